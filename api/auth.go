@@ -0,0 +1,135 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// BasicAuthUser is the basic auth user name of the APIs, as in grin
+	BasicAuthUser = "grin"
+
+	// secretSize is the length of the generated secret
+	secretSize = 20
+
+	secretAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// ReadSecret returns the API secret stored in file, the file with the new
+// random secret is created if it doesn't exist
+func ReadSecret(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buf := make([]byte, secretSize)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(secretAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = secretAlphabet[n.Int64()]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(file, buf, 0600); err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// SetSecrets enables the basic auth of the foreign & owner APIs, empty
+// secret leaves the API open
+func (s *Server) SetSecrets(foreign, owner string) {
+	s.foreignSecret = foreign
+	s.ownerSecret = owner
+}
+
+// authorized reports whether the Authorization header value carries secret
+func authorized(header, secret string) bool {
+	if secret == "" {
+		return true
+	}
+
+	const prefix = "Basic "
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+
+	want := base64.StdEncoding.EncodeToString([]byte(BasicAuthUser + ":" + secret))
+	return subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(want)) == 1
+}
+
+// requireAuth wraps handler to reject the requests not carrying *secret
+func requireAuth(handler http.Handler, secret *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r.Header.Get("Authorization"), *secret) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="GrinAPI"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// grpcAuthorized returns error if the request metadata doesn't carry the
+// foreign secret
+func (s *Server) grpcAuthorized(ctx context.Context) error {
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			header = values[0]
+		}
+	}
+
+	if !authorized(header, s.foreignSecret) {
+		return status.Error(codes.Unauthenticated, "invalid api secret")
+	}
+
+	return nil
+}
+
+// grpcAuth returns the interceptors checking the foreign secret
+func (s *Server) grpcAuth() []grpc.ServerOption {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := s.grpcAuthorized(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := s.grpcAuthorized(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+
+	return []grpc.ServerOption{grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream)}
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+// BackupHeightHeader is the response header of the backup height
+const BackupHeightHeader = "X-Backup-Height"
+
+// Restored is the result of the chain restore
+type Restored struct {
+	// Blocks is the count of the processed blocks of the backup
+	Blocks int    `json:"blocks"`
+	Height uint64 `json:"height"`
+}
+
+// SetBackup adds the owner endpoints of the chain backup: GET
+// /v1/chain/backup streams the snapshot of the main chain & POST
+// /v1/chain/restore processes the blocks of the backup body
+func (s *Server) SetBackup(c *chain.Chain) {
+	s.ownerMux.HandleFunc("/v1/chain/backup", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+			return
+		}
+
+		snapshot, err := c.Snapshot()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set(BackupHeightHeader, strconv.FormatUint(snapshot.Height(), 10))
+		if _, err := snapshot.WriteTo(w); err != nil {
+			logrus.Errorf("failed to write the chain backup: %v", err)
+		}
+	})
+
+	s.ownerMux.HandleFunc("/v1/chain/restore", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+			return
+		}
+
+		n, err := c.Restore(r.Context(), r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, Restored{Blocks: n, Height: c.Height()})
+	})
+}
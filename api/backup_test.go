@@ -0,0 +1,79 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/storage"
+	"github.com/dblokhin/gringo/testutil"
+	"net/http"
+	"testing"
+)
+
+// newBackupChain returns the chain of the generated blocks
+func newBackupChain() *chain.Chain {
+	c := chain.New(&chain.Testnet4, storage.NewMemStorage())
+	c.SetValidator(testutil.Validate)
+
+	return c
+}
+
+func TestBackupRestore(t *testing.T) {
+	source := newBackupChain()
+	blocks, err := testutil.New(&chain.Testnet4, 1).Chain(&chain.Testnet4, 3, testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range blocks {
+		if err := source.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	s := newTestServer()
+	s.SetBackup(source)
+
+	w := request(s.Owner(), http.MethodGet, "/v1/chain/backup", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if height := w.Header().Get(BackupHeightHeader); height != "3" {
+		t.Errorf("backup height was %s, want 3", height)
+	}
+
+	if w := request(s.Owner(), http.MethodPost, "/v1/chain/backup", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	restored := newBackupChain()
+	target := newTestServer()
+	target.SetBackup(restored)
+
+	w = request(target.Owner(), http.MethodPost, "/v1/chain/restore", w.Body.String())
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d: %s", w.Code, w.Body.String())
+	}
+
+	var result Restored
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Blocks != 3 || result.Height != 3 {
+		t.Errorf("restored %+v, want 3 blocks of height 3", result)
+	}
+
+	if head := restored.Head(); head.Hash() != blocks[2].Hash() {
+		t.Errorf("head was %d, want 3", head.Header.Height)
+	}
+
+	if w := request(target.Owner(), http.MethodPost, "/v1/chain/restore", "garbage"); w.Code != http.StatusBadRequest {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
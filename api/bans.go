@@ -0,0 +1,80 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// maxBansRequest is the max size of the pushed ban list
+const maxBansRequest = 1 << 20
+
+// BanList is the ban list shared by the nodes of the same operator: the
+// banned addrs & CIDR ranges
+type BanList struct {
+	Bans []string `json:"bans"`
+}
+
+// BansAdded is the result of the pushed ban list
+type BansAdded struct {
+	// Added is the count of the entries missing from the ban list
+	Added int `json:"added"`
+}
+
+// bans returns the ban list on GET & bans the entries of the pushed one on
+// POST: /v1/peers/bans
+func (s *Server) bans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, BanList{Bans: s.peers.Bans()})
+
+	case http.MethodPost:
+		var list BanList
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBansRequest)).Decode(&list); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		added, err := s.addBans(list.Bans)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, BansAdded{Added: added})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+	}
+}
+
+// addBans bans the entries missing from the ban list, nothing is banned if
+// any entry is invalid
+func (s *Server) addBans(entries []string) (int, error) {
+	for _, entry := range entries {
+		if err := validBan(entry); err != nil {
+			return 0, err
+		}
+	}
+
+	banned := make(map[string]bool)
+	for _, entry := range s.peers.Bans() {
+		banned[entry] = true
+	}
+
+	added := 0
+	for _, entry := range entries {
+		if banned[entry] {
+			continue
+		}
+
+		s.peers.Ban(entry)
+		banned[entry] = true
+		added++
+	}
+
+	return added, nil
+}
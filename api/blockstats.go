@@ -0,0 +1,51 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// BlockStats is the rolling statistics of the recent blocks: the wallets
+// suggest the fees by the median fee rate, the operators detect the stalled
+// network by the time since the last block
+type BlockStats struct {
+	Blocks int `json:"blocks"`
+	// AvgBlockInterval is the average block interval in seconds
+	AvgBlockInterval float64 `json:"avg_block_interval"`
+	// MedianFeeRate is the median fee per transaction weight of the blocks
+	// with the transactions
+	MedianFeeRate uint64 `json:"median_fee_rate"`
+	TxBlocks      int    `json:"tx_blocks"`
+	// Utilization is the average block weight to the max block weight ratio
+	Utilization    float64   `json:"utilization"`
+	LastBlock      time.Time `json:"last_block"`
+	SinceLastBlock int64     `json:"since_last_block"`
+	Stalled        bool      `json:"stalled"`
+}
+
+// blockStats returns the rolling statistics of the recent blocks:
+// /v1/chain/stats
+func (s *Server) blockStats(r *http.Request) (interface{}, error) {
+	return s.recentStats(), nil
+}
+
+// recentStats returns the rolling statistics of the chain now
+func (s *Server) recentStats() BlockStats {
+	now := time.Now()
+	stats := s.chain.BlockStats(now)
+
+	return BlockStats{
+		Blocks:           stats.Blocks,
+		AvgBlockInterval: stats.AvgInterval.Seconds(),
+		MedianFeeRate:    stats.MedianFeeRate,
+		TxBlocks:         stats.TxBlocks,
+		Utilization:      stats.Utilization,
+		LastBlock:        stats.LastBlock.UTC(),
+		SinceLastBlock:   int64(now.Sub(stats.LastBlock) / time.Second),
+		Stalled:          stats.Stalled,
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockStats(t *testing.T) {
+	last := time.Now().Add(-30 * time.Minute)
+	s := New(&testChain{genesis: chain.Testnet1, stats: chain.BlockStats{
+		Blocks:        60,
+		AvgInterval:   90 * time.Second,
+		MedianFeeRate: 500000,
+		TxBlocks:      12,
+		Utilization:   0.25,
+		LastBlock:     last,
+		Stalled:       true,
+	}}, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/chain/stats", "")
+	var stats BlockStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid stats response %s: %v", w.Body.String(), err)
+	}
+
+	if stats.Blocks != 60 || stats.AvgBlockInterval != 90 || stats.MedianFeeRate != 500000 || stats.TxBlocks != 12 ||
+		stats.Utilization != 0.25 || !stats.Stalled {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if stats.SinceLastBlock < 30*60 || stats.SinceLastBlock > 31*60 || !stats.LastBlock.Equal(last) {
+		t.Errorf("last block %v was %d seconds ago, want %v", stats.LastBlock, stats.SinceLastBlock, last)
+	}
+
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_block_stats", "params": []}`)
+	if !strings.Contains(w.Body.String(), `"median_fee_rate":500000`) {
+		t.Errorf("unexpected get_block_stats response: %s", w.Body.String())
+	}
+}
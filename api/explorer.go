@@ -0,0 +1,277 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPageSize is the max count of blocks in the explorer blocks page
+	MaxPageSize = 100
+
+	// SearchDepth is the count of the recent blocks searched by the partial
+	// hash, commitment or kernel excess
+	SearchDepth = 1000
+
+	// MinSearchLength is the min length of the hex prefix searched
+	MinSearchLength = 6
+
+	// MaxSearchResults is the max count of the search results
+	MaxSearchResults = 20
+
+	// defaultPageSize is the count of blocks in the page if limit is not set
+	defaultPageSize = 20
+)
+
+var errInvalidQuery = errors.New("invalid search query")
+
+// ExplorerBlock is the block with the fees & the reward of the miner
+type ExplorerBlock struct {
+	BlockPrintable
+	Fees          uint64 `json:"fees"`
+	Reward        uint64 `json:"reward"`
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// BlockSummary is the block in the explorer blocks page
+type BlockSummary struct {
+	Hash            string `json:"hash"`
+	Height          uint64 `json:"height"`
+	Timestamp       string `json:"timestamp"`
+	TotalDifficulty uint64 `json:"total_difficulty"`
+	Inputs          int    `json:"inputs"`
+	Outputs         int    `json:"outputs"`
+	Kernels         int    `json:"kernels"`
+	Fees            uint64 `json:"fees"`
+}
+
+// SearchResult is the block, output or kernel found by the explorer search
+type SearchResult struct {
+	// Type is one of block, output or kernel
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Height uint64 `json:"height"`
+}
+
+// ChainStats is the aggregate stats of the main chain
+type ChainStats struct {
+	Height          uint64 `json:"height"`
+	TotalDifficulty uint64 `json:"total_difficulty"`
+	// Emission is the coins mined in nanogrins
+	Emission       uint64 `json:"emission"`
+	Fees           uint64 `json:"fees"`
+	Kernels        uint64 `json:"kernels"`
+	Outputs        uint64 `json:"outputs"`
+	Inputs         uint64 `json:"inputs"`
+	UnspentOutputs uint64 `json:"unspent_outputs"`
+}
+
+// statsCache is the stats counted up to the block of hash, the stats are
+// counted again on reorg
+type statsCache struct {
+	sync.Mutex
+
+	stats ChainStats
+	hash  consensus.Hash
+}
+
+// blockFees returns the sum of the kernel fees
+func blockFees(block *consensus.Block) uint64 {
+	var fees uint64
+	for _, kernel := range block.Kernels {
+		fees += kernel.Fee
+	}
+
+	return fees
+}
+
+// explorerBlock returns the block with the fees:
+// /v1/explorer/blocks/{hash|height}
+func (s *Server) explorerBlock(r *http.Request) (interface{}, error) {
+	block, err := s.findBlock(strings.TrimPrefix(r.URL.Path, "/v1/explorer/blocks/"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := ExplorerBlock{
+		BlockPrintable: NewBlockPrintable(block, s.isSpent),
+		Fees:           blockFees(block),
+	}
+
+	// the genesis has no reward
+	if block.Header.Height > s.chain.Genesis().Header.Height {
+		result.Reward = consensus.Reward + result.Fees
+	}
+
+	if height := s.chain.Height(); height >= block.Header.Height {
+		result.Confirmations = height - block.Header.Height + 1
+	}
+
+	return result, nil
+}
+
+// recentBlocks returns the page of blocks from the head:
+// /v1/explorer/blocks?page=x&limit=y
+func (s *Server) recentBlocks(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	page, limit := uint64(0), uint64(defaultPageSize)
+	if value := query.Get("page"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errInvalidRange
+		}
+		page = n
+	}
+
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 || n > MaxPageSize {
+			return nil, errInvalidRange
+		}
+		limit = n
+	}
+
+	result := make([]BlockSummary, 0, limit)
+
+	height := s.chain.Height()
+	if page > height/limit {
+		return result, nil
+	}
+
+	for i := uint64(0); i < limit && page*limit+i <= height; i++ {
+		block, err := s.findBlock(strconv.FormatUint(height-page*limit-i, 10))
+		if err != nil {
+			continue
+		}
+
+		result = append(result, BlockSummary{
+			Hash:            block.Hash().String(),
+			Height:          block.Header.Height,
+			Timestamp:       block.Header.Timestamp.UTC().Format(time.RFC3339),
+			TotalDifficulty: uint64(block.Header.TotalDifficulty),
+			Inputs:          len(block.Inputs),
+			Outputs:         len(block.Outputs),
+			Kernels:         len(block.Kernels),
+			Fees:            blockFees(block),
+		})
+	}
+
+	return result, nil
+}
+
+// search returns the blocks, outputs & kernels of the recent SearchDepth
+// blocks by the hex prefix, the block height or the full commitment of the
+// unspent output: /v1/explorer/search?q=xxx
+func (s *Server) search(r *http.Request) (interface{}, error) {
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+
+	result := make([]SearchResult, 0)
+
+	if height, err := strconv.ParseUint(q, 10, 64); err == nil {
+		if block, err := s.findBlock(q); err == nil {
+			result = append(result, SearchResult{Type: "block", ID: block.Hash().String(), Height: height})
+		}
+	}
+
+	if len(q) < MinSearchLength || strings.Trim(q, "0123456789abcdef") != "" {
+		if len(result) > 0 {
+			return result, nil
+		}
+		return nil, errInvalidQuery
+	}
+
+	// the unspent outputs are found at any depth
+	if len(q) == 2*secp256k1zkp.PedersenCommitmentSize {
+		if id, err := s.outputBlock(q); err == nil && id.Height != nil {
+			return append(result, SearchResult{Type: "output", ID: q, Height: *id.Height}), nil
+		}
+	}
+
+	height := s.chain.Height()
+	for i := uint64(0); i < SearchDepth && i <= height && len(result) < MaxSearchResults; i++ {
+		block, err := s.findBlock(strconv.FormatUint(height-i, 10))
+		if err != nil {
+			continue
+		}
+
+		if id := block.Hash().String(); strings.HasPrefix(id, q) {
+			result = append(result, SearchResult{Type: "block", ID: id, Height: block.Header.Height})
+		}
+
+		for j := range block.Outputs {
+			if id := hex.EncodeToString(block.Outputs[j].Commit.Bytes()); strings.HasPrefix(id, q) {
+				result = append(result, SearchResult{Type: "output", ID: id, Height: block.Header.Height})
+			}
+		}
+
+		for j := range block.Kernels {
+			if id := hex.EncodeToString(block.Kernels[j].Excess.Bytes()); strings.HasPrefix(id, q) {
+				result = append(result, SearchResult{Type: "kernel", ID: id, Height: block.Header.Height})
+			}
+		}
+	}
+
+	if len(result) > MaxSearchResults {
+		result = result[:MaxSearchResults]
+	}
+
+	return result, nil
+}
+
+// chainStats returns the aggregate stats of the main chain: /v1/explorer/stats
+func (s *Server) chainStats(r *http.Request) (interface{}, error) {
+	s.stats.Lock()
+	defer s.stats.Unlock()
+
+	stats := &s.stats.stats
+	genesis := s.chain.Genesis().Header.Height
+
+	// the main chain is changed below the counted block on reorg
+	if !s.stats.hash.IsZero() {
+		block, err := s.findBlock(strconv.FormatUint(stats.Height, 10))
+		if err != nil || block.Hash() != s.stats.hash {
+			*stats = ChainStats{}
+			s.stats.hash = consensus.ZeroHash
+		}
+	}
+
+	from := genesis
+	if !s.stats.hash.IsZero() {
+		from = stats.Height + 1
+	}
+
+	for height := from; height <= s.chain.Height(); height++ {
+		block, err := s.findBlock(strconv.FormatUint(height, 10))
+		if err != nil {
+			return nil, err
+		}
+
+		if height > genesis {
+			stats.Emission += consensus.Reward
+		}
+
+		stats.Height = height
+		stats.TotalDifficulty = uint64(block.Header.TotalDifficulty)
+		stats.Fees += blockFees(block)
+		stats.Kernels += uint64(len(block.Kernels))
+		stats.Outputs += uint64(len(block.Outputs))
+		stats.Inputs += uint64(len(block.Inputs))
+		s.stats.hash = block.Hash()
+	}
+
+	stats.UnspentOutputs = stats.Outputs - stats.Inputs
+
+	return *stats, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// blocksChain is the chain of blocks by height on top of the genesis
+type blocksChain struct {
+	testChain
+	blocks []*consensus.Block
+}
+
+func (c *blocksChain) Head() consensus.Block { return *c.blocks[len(c.blocks)-1] }
+func (c *blocksChain) Height() uint64        { return uint64(len(c.blocks) - 1) }
+func (c *blocksChain) TotalDifficulty() consensus.Difficulty {
+	return c.blocks[len(c.blocks)-1].Header.TotalDifficulty
+}
+func (c *blocksChain) GetKernel(excess secp256k1zkp.Commitment, min, max uint64) *consensus.BlockID {
+	for _, block := range c.blocks {
+		for _, kernel := range block.Kernels {
+			if height := block.Header.Height; height >= min && height <= max && bytes.Equal(kernel.Excess.Bytes(), excess) {
+				return &consensus.BlockID{Hash: block.Hash(), Height: &height}
+			}
+		}
+	}
+	return nil
+}
+func (c *blocksChain) GetBlockID(id consensus.BlockID) *consensus.Block {
+	for _, block := range c.blocks {
+		if (id.Height == nil || block.Header.Height == *id.Height) &&
+			(id.Hash.IsZero() || block.Hash() == id.Hash) {
+			return block
+		}
+	}
+	return nil
+}
+
+// newBlocksChain returns the chain of n blocks after the genesis, the block
+// of height h has h kernels of the fee h
+func newBlocksChain(n int) *blocksChain {
+	c := &blocksChain{testChain: testChain{genesis: chain.Testnet4}}
+	c.blocks = append(c.blocks, &c.genesis)
+
+	for i := 1; i <= n; i++ {
+		parent := c.blocks[i-1]
+
+		header := parent.Header
+		header.Height = uint64(i)
+		header.Previous = parent.Hash()
+		header.Timestamp = parent.Header.Timestamp.Add(time.Minute)
+		header.TotalDifficulty = parent.Header.TotalDifficulty + 1
+		header.POW.Nonces = append([]uint32(nil), parent.Header.POW.Nonces...)
+		header.POW.Nonces[0] = uint32(i)
+
+		block := &consensus.Block{Header: header}
+		for j := 0; j < i; j++ {
+			excess := secp256k1zkp.CommitValue(big.NewInt(int64(i*10+j)), big.NewInt(0))
+			block.Kernels = append(block.Kernels, consensus.TxKernel{Fee: uint64(i), Excess: *excess})
+		}
+
+		c.blocks = append(c.blocks, block)
+	}
+
+	return c
+}
+
+func TestExplorerBlock(t *testing.T) {
+	c := newBlocksChain(3)
+	s := New(c, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/explorer/blocks/2", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var block ExplorerBlock
+	if err := json.NewDecoder(w.Body).Decode(&block); err != nil {
+		t.Fatal(err)
+	}
+
+	if block.Header.Height != 2 || len(block.Kernels) != 2 {
+		t.Errorf("block was %d with %d kernels, want 2 with 2", block.Header.Height, len(block.Kernels))
+	}
+
+	if block.Fees != 4 || block.Reward != consensus.Reward+4 {
+		t.Errorf("fees & reward were %d %d, want 4 %d", block.Fees, block.Reward, consensus.Reward+4)
+	}
+
+	if block.Confirmations != 2 {
+		t.Errorf("confirmations was %d, want 2", block.Confirmations)
+	}
+}
+
+func TestRecentBlocks(t *testing.T) {
+	s := New(newBlocksChain(5), &testPool{}, testPeers{})
+
+	for _, test := range []struct {
+		url     string
+		heights []uint64
+	}{
+		{"/v1/explorer/blocks?limit=2", []uint64{5, 4}},
+		{"/v1/explorer/blocks?limit=2&page=2", []uint64{1, 0}},
+		{"/v1/explorer/blocks?limit=4&page=1", []uint64{1, 0}},
+		{"/v1/explorer/blocks?limit=2&page=3", []uint64{}},
+	} {
+		w := request(s, http.MethodGet, test.url, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status code was %d, want %d", test.url, w.Code, http.StatusOK)
+			continue
+		}
+
+		var blocks []BlockSummary
+		if err := json.NewDecoder(w.Body).Decode(&blocks); err != nil {
+			t.Fatal(err)
+		}
+
+		heights := make([]uint64, 0)
+		for _, block := range blocks {
+			heights = append(heights, block.Height)
+		}
+
+		if len(heights) != len(test.heights) || (len(heights) > 0 && heights[0] != test.heights[0]) {
+			t.Errorf("%s: heights were %v, want %v", test.url, heights, test.heights)
+		}
+	}
+
+	if w := request(s, http.MethodGet, "/v1/explorer/blocks?limit=1000", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := newBlocksChain(3)
+	s := New(c, &testPool{}, testPeers{})
+
+	hash := c.blocks[2].Hash().String()
+	excess := hex.EncodeToString(c.blocks[3].Kernels[1].Excess.Bytes())
+
+	for _, test := range []struct {
+		q    string
+		typ  string
+		id   string
+		code int
+	}{
+		{q: hash[:8], typ: "block", id: hash, code: http.StatusOK},
+		{q: excess[:12], typ: "kernel", id: excess, code: http.StatusOK},
+		{q: "1", typ: "block", id: c.blocks[1].Hash().String(), code: http.StatusOK},
+		{q: "abc", code: http.StatusBadRequest},
+		{q: "xyz123", code: http.StatusBadRequest},
+	} {
+		w := request(s, http.MethodGet, "/v1/explorer/search?q="+test.q, "")
+		if w.Code != test.code {
+			t.Errorf("%s: status code was %d, want %d", test.q, w.Code, test.code)
+			continue
+		}
+
+		if test.code != http.StatusOK {
+			continue
+		}
+
+		var results []SearchResult
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) == 0 || results[0].Type != test.typ || results[0].ID != test.id {
+			t.Errorf("%s: results were %+v, want %s %s", test.q, results, test.typ, test.id)
+		}
+	}
+}
+
+func TestChainStats(t *testing.T) {
+	c := newBlocksChain(3)
+	s := New(c, &testPool{}, testPeers{})
+
+	stats := func() ChainStats {
+		w := request(s, http.MethodGet, "/v1/explorer/stats", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var stats ChainStats
+		if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+			t.Fatal(err)
+		}
+		return stats
+	}
+
+	// 1+2+3 kernels of the fees 1+4+9
+	if got := stats(); got.Height != 3 || got.Kernels != 6 || got.Fees != 14 || got.Emission != 3*consensus.Reward {
+		t.Errorf("stats were %+v", got)
+	}
+
+	// the stats are counted again after reorg to the block 3 of no kernels
+	c.blocks = newBlocksChain(4).blocks
+	c.blocks[3].Header.POW.Nonces[0] = 100
+	c.blocks[3].Kernels = nil
+	if got := stats(); got.Height != 4 || got.Kernels != 7 || got.Fees != 21 {
+		t.Errorf("stats after reorg were %+v", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"github.com/dblokhin/gringo/api/nodepb"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// streamBuffer is the count of the events buffered for the slow subscriber,
+// the events are skipped on overflow
+const streamBuffer = 16
+
+// BlockSource publishes the new head blocks
+type BlockSource interface {
+	Subscribe(ch chan<- *consensus.Block)
+	Unsubscribe(ch chan<- *consensus.Block)
+}
+
+// TxSource publishes the new pool transactions
+type TxSource interface {
+	Subscribe(ch chan<- *consensus.Transaction)
+	Unsubscribe(ch chan<- *consensus.Transaction)
+}
+
+// grpcServer implements nodepb.NodeServer
+type grpcServer struct {
+	api    *Server
+	blocks BlockSource
+	txs    TxSource
+}
+
+// NewGRPC returns gRPC server of the Node service (see nodepb/node.proto),
+// the streaming subscriptions are fed by blocks & txs. The server uses TLS
+// if it is enabled by SetTLS and requires the foreign API secret
+func (s *Server) NewGRPC(blocks BlockSource, txs TxSource) *grpc.Server {
+	opts := s.grpcAuth()
+	if s.tls != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tls)))
+	}
+
+	server := grpc.NewServer(opts...)
+	nodepb.RegisterNodeServer(server, &grpcServer{
+		api:    s,
+		blocks: blocks,
+		txs:    txs,
+	})
+
+	return server
+}
+
+// GetStatus returns the node status
+func (g *grpcServer) GetStatus(ctx context.Context, req *nodepb.StatusRequest) (*nodepb.Status, error) {
+	head := g.api.chain.Head()
+
+	return &nodepb.Status{
+		ProtocolVersion: consensus.ProtocolVersion,
+		UserAgent:       p2p.UserAgent,
+		Connections:     uint32(len(g.api.peers.Connected())),
+		Tip: &nodepb.Tip{
+			Height:          g.api.chain.Height(),
+			LastBlockPushed: head.Hash().Bytes(),
+			PrevBlockToLast: head.Header.Previous.Bytes(),
+			TotalDifficulty: uint64(g.api.chain.TotalDifficulty()),
+		},
+	}, nil
+}
+
+// GetBlock returns the block by hash or height
+func (g *grpcServer) GetBlock(ctx context.Context, req *nodepb.BlockRequest) (*nodepb.Block, error) {
+	block, err := g.block(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return pbBlock(block), nil
+}
+
+// GetHeader returns the block header by hash or height
+func (g *grpcServer) GetHeader(ctx context.Context, req *nodepb.BlockRequest) (*nodepb.BlockHeader, error) {
+	block, err := g.block(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return pbHeader(&block.Header), nil
+}
+
+func (g *grpcServer) block(req *nodepb.BlockRequest) (*consensus.Block, error) {
+	var id consensus.BlockID
+	if len(req.Hash) == 0 {
+		id.Height = &req.Height
+	} else {
+		hash, err := consensus.NewHash(req.Hash)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		id.Hash = hash
+	}
+
+	block, err := g.api.blockByID(id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return block, nil
+}
+
+// PushTransaction adds the transaction to the pool
+func (g *grpcServer) PushTransaction(ctx context.Context, req *nodepb.PushTransactionRequest) (*nodepb.PushTransactionResponse, error) {
+	var tx consensus.Transaction
+	if err := tx.Read(bytes.NewReader(req.Tx)); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := g.api.addTx(ctx, &tx, true); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &nodepb.PushTransactionResponse{Hash: tx.Hash().Bytes()}, nil
+}
+
+// SubscribeBlocks streams the new head blocks until the client cancels
+func (g *grpcServer) SubscribeBlocks(req *nodepb.SubscribeRequest, stream nodepb.Node_SubscribeBlocksServer) error {
+	ch := make(chan *consensus.Block, streamBuffer)
+	g.blocks.Subscribe(ch)
+	defer g.blocks.Unsubscribe(ch)
+
+	for {
+		select {
+		case block := <-ch:
+			if err := stream.Send(pbBlock(block)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// SubscribeTransactions streams the new pool transactions until the client
+// cancels
+func (g *grpcServer) SubscribeTransactions(req *nodepb.SubscribeRequest, stream nodepb.Node_SubscribeTransactionsServer) error {
+	ch := make(chan *consensus.Transaction, streamBuffer)
+	g.txs.Subscribe(ch)
+	defer g.txs.Unsubscribe(ch)
+
+	for {
+		select {
+		case tx := <-ch:
+			if err := stream.Send(pbTransaction(tx)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func pbHeader(h *consensus.BlockHeader) *nodepb.BlockHeader {
+	return &nodepb.BlockHeader{
+		Hash:              h.Hash().Bytes(),
+		Version:           uint32(h.Version),
+		Height:            h.Height,
+		Previous:          h.Previous.Bytes(),
+		PrevRoot:          h.PreviousRoot.Bytes(),
+		Timestamp:         h.Timestamp.Unix(),
+		OutputRoot:        h.UTXORoot.Bytes(),
+		RangeProofRoot:    h.RangeProofRoot.Bytes(),
+		KernelRoot:        h.KernelRoot.Bytes(),
+		Nonce:             h.Nonce,
+		EdgeBits:          uint32(h.POW.EdgeBits),
+		CuckooSolution:    h.POW.Nonces,
+		TotalDifficulty:   uint64(h.TotalDifficulty),
+		SecondaryScaling:  h.ScalingDifficulty,
+		TotalKernelOffset: h.TotalKernelOffset.Bytes(),
+	}
+}
+
+func pbBlock(b *consensus.Block) *nodepb.Block {
+	return &nodepb.Block{
+		Header:  pbHeader(&b.Header),
+		Inputs:  pbInputs(b.Inputs),
+		Outputs: pbOutputs(b.Outputs),
+		Kernels: pbKernels(b.Kernels),
+	}
+}
+
+func pbTransaction(tx *consensus.Transaction) *nodepb.Transaction {
+	return &nodepb.Transaction{
+		Hash:    tx.Hash().Bytes(),
+		Offset:  tx.KernelOffset[:],
+		Inputs:  pbInputs(tx.Inputs),
+		Outputs: pbOutputs(tx.Outputs),
+		Kernels: pbKernels(tx.Kernels),
+	}
+}
+
+func pbInputs(inputs consensus.InputList) []*nodepb.Input {
+	result := make([]*nodepb.Input, 0, len(inputs))
+	for _, input := range inputs {
+		result = append(result, &nodepb.Input{
+			Features: uint32(input.Features),
+			Commit:   input.Commit,
+		})
+	}
+
+	return result
+}
+
+func pbOutputs(outputs consensus.OutputList) []*nodepb.Output {
+	result := make([]*nodepb.Output, 0, len(outputs))
+	for _, output := range outputs {
+		result = append(result, &nodepb.Output{
+			Features: uint32(output.Features),
+			Commit:   output.Commit.Bytes(),
+			Proof:    rangeProof(&output),
+		})
+	}
+
+	return result
+}
+
+func pbKernels(kernels consensus.TxKernelList) []*nodepb.Kernel {
+	result := make([]*nodepb.Kernel, 0, len(kernels))
+	for _, kernel := range kernels {
+		result = append(result, &nodepb.Kernel{
+			Features:   uint32(kernel.Features),
+			Fee:        kernel.Fee,
+			LockHeight: kernel.LockHeight,
+			Excess:     kernel.Excess.Bytes(),
+			ExcessSig:  append([]byte(nil), kernel.ExcessSig[:]...),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"github.com/dblokhin/gringo/api/nodepb"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+type testBlocks struct {
+	subscribed chan chan<- *consensus.Block
+}
+
+func (b *testBlocks) Subscribe(ch chan<- *consensus.Block)   { b.subscribed <- ch }
+func (b *testBlocks) Unsubscribe(ch chan<- *consensus.Block) {}
+
+type testTxs struct{}
+
+func (testTxs) Subscribe(ch chan<- *consensus.Transaction)   {}
+func (testTxs) Unsubscribe(ch chan<- *consensus.Transaction) {}
+
+func TestGRPC(t *testing.T) {
+	blocks := &testBlocks{subscribed: make(chan chan<- *consensus.Block, 1)}
+	server := newTestServer().NewGRPC(blocks, testTxs{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := nodepb.NewNodeClient(conn)
+	genesis := chain.Testnet1.Hash().Bytes()
+
+	status, err := client.GetStatus(ctx, &nodepb.StatusRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(status.Tip.LastBlockPushed, genesis) {
+		t.Errorf("tip was %x, want genesis %x", status.Tip.LastBlockPushed, genesis)
+	}
+
+	header, err := client.GetHeader(ctx, &nodepb.BlockRequest{Hash: genesis})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(header.CuckooSolution) != len(chain.Testnet1.Header.POW.Nonces) {
+		t.Errorf("cuckoo solution size was %d", len(header.CuckooSolution))
+	}
+
+	if _, err := client.GetBlock(ctx, &nodepb.BlockRequest{Height: 10}); err == nil {
+		t.Error("unknown block was found")
+	}
+
+	stream, err := client.SubscribeBlocks(ctx, &nodepb.SubscribeRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	(<-blocks.subscribed) <- &chain.Testnet1
+
+	block, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(block.Header.Hash, genesis) {
+		t.Errorf("streamed block was %x, want %x", block.Header.Hash, genesis)
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	api := newTestServer()
+	api.SetSecrets("secret", "")
+	server := api.NewGRPC(&testBlocks{}, testTxs{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := nodepb.NewNodeClient(conn)
+	if _, err := client.GetStatus(ctx, &nodepb.StatusRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("request without secret: %v", err)
+	}
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(BasicAuthUser+":secret"))
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	if _, err := client.GetStatus(ctx, &nodepb.StatusRequest{}); err != nil {
+		t.Errorf("request with secret: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultHashrateWindow is the count of the recent blocks the network
+	// graph rate is estimated by, the difficulty adjustment window
+	DefaultHashrateWindow = 60
+	// MaxHashrateWindow is the max count of the estimated blocks, a day
+	MaxHashrateWindow = 1440
+)
+
+var errInvalidWindow = errors.New("invalid blocks window")
+
+// PowHashrate is the estimated graph rate of the proof of work
+type PowHashrate struct {
+	Blocks     int     `json:"blocks"`
+	EdgeBits   uint8   `json:"edge_bits"`
+	Difficulty uint64  `json:"difficulty"`
+	GraphRate  float64 `json:"graph_rate"`
+}
+
+// NetworkHashrate is the network graph rate estimated by the recent blocks,
+// the primary & the secondary proofs of work separately
+type NetworkHashrate struct {
+	Height uint64 `json:"height"`
+	Blocks int    `json:"blocks"`
+	// BlockTime is the average block interval in seconds
+	BlockTime float64     `json:"block_time"`
+	Primary   PowHashrate `json:"primary"`
+	Secondary PowHashrate `json:"secondary"`
+}
+
+// hashrate returns the network graph rate: /v1/chain/hashrate?blocks=N
+func (s *Server) hashrate(r *http.Request) (interface{}, error) {
+	blocks := DefaultHashrateWindow
+	if value := r.URL.Query().Get("blocks"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errInvalidWindow
+		}
+		blocks = n
+	}
+
+	return s.networkHashrate(blocks)
+}
+
+// networkHashrate estimates the graph rate of the blocks up to the head, the
+// window is shortened to the chain height
+func (s *Server) networkHashrate(blocks int) (*NetworkHashrate, error) {
+	if blocks <= 0 || blocks > MaxHashrateWindow {
+		return nil, errInvalidWindow
+	}
+
+	height := s.chain.Height()
+	genesis := s.chain.Genesis().Header.Height
+	from := genesis
+	if height-genesis > uint64(blocks) {
+		from = height - uint64(blocks)
+	}
+
+	headers := make([]consensus.BlockHeader, 0, height-from+1)
+	for h := from; h <= height; h++ {
+		block, err := s.blockByID(consensus.BlockID{Height: &h})
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, block.Header)
+	}
+
+	rate := consensus.EstimateRate(headers)
+	return &NetworkHashrate{
+		Height:    height,
+		Blocks:    rate.Blocks,
+		BlockTime: rate.BlockTime.Seconds(),
+		Primary:   powHashrate(rate.Primary),
+		Secondary: powHashrate(rate.Secondary),
+	}, nil
+}
+
+// powHashrate returns the json of the proof of work rate
+func powHashrate(rate consensus.PowRate) PowHashrate {
+	return PowHashrate{
+		Blocks:     rate.Blocks,
+		EdgeBits:   rate.EdgeBits,
+		Difficulty: uint64(rate.Difficulty),
+		GraphRate:  rate.GraphRate,
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHashrate(t *testing.T) {
+	s := New(newBlocksChain(5), &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/chain/hashrate?blocks=3", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("hashrate request failed: %d %s", w.Code, w.Body.String())
+	}
+
+	var rate NetworkHashrate
+	if err := json.Unmarshal(w.Body.Bytes(), &rate); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocks of the test chain are a minute apart & gain the difficulty
+	// of 1
+	if rate.Height != 5 || rate.Blocks != 3 || rate.BlockTime != 60 {
+		t.Errorf("unexpected height %d, blocks %d & block time %v", rate.Height, rate.Blocks, rate.BlockTime)
+	}
+
+	if rate.Primary.Blocks+rate.Secondary.Blocks != 3 {
+		t.Errorf("blocks of the proofs of work were %d & %d, want 3", rate.Primary.Blocks, rate.Secondary.Blocks)
+	}
+
+	// the window is shortened to the chain height
+	w = request(s, http.MethodGet, "/v1/chain/hashrate", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &rate); err != nil || rate.Blocks != 5 {
+		t.Errorf("default window was %d blocks, %v, want 5", rate.Blocks, err)
+	}
+
+	for _, query := range []string{"blocks=0", "blocks=x", "blocks=100000"} {
+		if w := request(s, http.MethodGet, "/v1/chain/hashrate?"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_network_hashrate", "params": [2]}`)
+	if !strings.Contains(w.Body.String(), `"blocks":2`) {
+		t.Errorf("unexpected get_network_hashrate response: %s", w.Body.String())
+	}
+
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_network_hashrate", "params": [-1]}`)
+	if !strings.Contains(w.Body.String(), `"error"`) {
+		t.Errorf("invalid window was accepted: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"sort"
+)
+
+// ReadinessCheck returns nil if the node is ready to serve the requests
+type ReadinessCheck func() error
+
+// Readiness is the /readyz response
+type Readiness struct {
+	Status string `json:"status"`
+	// Checks is the failed checks errors by name
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// AddReadinessCheck adds the named check to /readyz
+func (s *Server) AddReadinessCheck(name string, check ReadinessCheck) {
+	s.checks[name] = check
+}
+
+// healthz reports the process is alive
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Readiness{Status: "ok"})
+}
+
+// readyz runs the readiness checks, responds 503 if any check fails
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := Readiness{Status: "ok"}
+	for _, name := range names {
+		if err := s.checks[name](); err != nil {
+			if result.Checks == nil {
+				result.Checks = make(map[string]string)
+			}
+			result.Checks[name] = err.Error()
+		}
+	}
+
+	if len(result.Checks) > 0 {
+		result.Status = "unavailable"
+		writeJSON(w, http.StatusServiceUnavailable, result)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
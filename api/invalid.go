@@ -0,0 +1,73 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// Invalidator is the chain rejecting the blocks marked invalid by the operator
+type Invalidator interface {
+	InvalidateBlock(hash consensus.Hash) bool
+	ReconsiderBlock(hash consensus.Hash)
+	InvalidBlocks() []consensus.Hash
+}
+
+// InvalidBlock is the block hash marked invalid, Known is set if the block
+// is already in the chain & isn't rewound
+type InvalidBlock struct {
+	Hash  string `json:"hash"`
+	Known bool   `json:"known"`
+}
+
+// SetInvalidator adds the owner methods of the invalid blocks:
+// get_invalid_blocks, invalidate_block [hash] & reconsider_block [hash]
+func (s *Server) SetInvalidator(c Invalidator) {
+	s.RegisterOwnerMethod("get_invalid_blocks", func(params json.RawMessage) (interface{}, error) {
+		hashes := c.InvalidBlocks()
+
+		result := make([]string, 0, len(hashes))
+		for _, hash := range hashes {
+			result = append(result, hash.String())
+		}
+
+		return result, nil
+	})
+
+	s.RegisterOwnerMethod("invalidate_block", func(params json.RawMessage) (interface{}, error) {
+		hash, err := parseHashParam(params)
+		if err != nil {
+			return nil, err
+		}
+
+		return InvalidBlock{Hash: hash.String(), Known: c.InvalidateBlock(hash)}, nil
+	})
+
+	s.RegisterOwnerMethod("reconsider_block", func(params json.RawMessage) (interface{}, error) {
+		hash, err := parseHashParam(params)
+		if err != nil {
+			return nil, err
+		}
+
+		c.ReconsiderBlock(hash)
+		return nil, nil
+	})
+}
+
+// parseHashParam returns the block hash of the [hash] params
+func parseHashParam(params json.RawMessage) (consensus.Hash, error) {
+	var value string
+	if err := parseParams(params, &value); err != nil {
+		return consensus.ZeroHash, err
+	}
+
+	hash, err := consensus.ParseHash(value)
+	if err != nil {
+		return consensus.ZeroHash, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return hash, nil
+}
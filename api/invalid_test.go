@@ -0,0 +1,57 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// testInvalidator is the set of the invalid hashes, the genesis is known
+type testInvalidator map[consensus.Hash]bool
+
+func (v testInvalidator) InvalidateBlock(hash consensus.Hash) bool {
+	v[hash] = true
+	return hash == chain.Testnet1.Hash()
+}
+func (v testInvalidator) ReconsiderBlock(hash consensus.Hash) { delete(v, hash) }
+func (v testInvalidator) InvalidBlocks() []consensus.Hash {
+	result := make([]consensus.Hash, 0, len(v))
+	for hash := range v {
+		result = append(result, hash)
+	}
+	return result
+}
+
+func TestOwnerInvalidBlocks(t *testing.T) {
+	s := newTestServer()
+	invalid := make(testInvalidator)
+	s.SetInvalidator(invalid)
+
+	genesis := chain.Testnet1.Hash().String()
+	call := func(method, params string) string {
+		w := request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`)
+		return w.Body.String()
+	}
+
+	if body := call("invalidate_block", `["`+genesis+`"]`); !strings.Contains(body, `"known":true`) || !invalid[chain.Testnet1.Hash()] {
+		t.Errorf("invalidate_block returned %s", body)
+	}
+
+	if body := call("get_invalid_blocks", "[]"); !strings.Contains(body, genesis) {
+		t.Errorf("get_invalid_blocks returned %s", body)
+	}
+
+	if body := call("reconsider_block", `["`+genesis+`"]`); strings.Contains(body, `"error"`) || len(invalid) != 0 {
+		t.Errorf("reconsider_block returned %s", body)
+	}
+
+	if body := call("invalidate_block", `["xyz"]`); !strings.Contains(body, `"error"`) || len(invalid) != 0 {
+		t.Errorf("invalid hash was accepted: %s", body)
+	}
+}
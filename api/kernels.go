@@ -0,0 +1,91 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// kernel returns the kernel by excess:
+// /v1/chain/kernels/{excess}?min_height=x&max_height=y
+func (s *Server) kernel(r *http.Request) (interface{}, error) {
+	min, err := heightParam(r, "min_height")
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := heightParam(r, "max_height")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.locateKernel(strings.TrimPrefix(r.URL.Path, "/v1/chain/kernels/"), min, max)
+}
+
+// heightParam returns the optional height of the query param
+func heightParam(r *http.Request, name string) (*uint64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	height, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, errInvalidRange
+	}
+
+	return &height, nil
+}
+
+// locateKernel returns the main chain kernel by hex excess within the
+// optional height range
+func (s *Server) locateKernel(id string, min, max *uint64) (*LocatedTxKernel, error) {
+	excess, err := hex.DecodeString(id)
+	if err != nil || len(excess) != secp256k1zkp.PedersenCommitmentSize {
+		return nil, fmt.Errorf("invalid kernel excess: %s", id)
+	}
+
+	minHeight, maxHeight := uint64(0), uint64(math.MaxUint64)
+	if min != nil {
+		minHeight = *min
+	}
+	if max != nil {
+		maxHeight = *max
+	}
+
+	if minHeight > maxHeight {
+		return nil, errInvalidRange
+	}
+
+	blockID := s.chain.GetKernel(excess, minHeight, maxHeight)
+	if blockID == nil {
+		return nil, errNotFound
+	}
+
+	block, err := s.blockByID(*blockID)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := firstLeaf(block.Header.KernelMmrSize, len(block.Kernels))
+	for i := range block.Kernels {
+		if bytes.Equal(block.Kernels[i].Excess.Bytes(), excess) {
+			return &LocatedTxKernel{
+				TxKernel: newKernelPrintable(&block.Kernels[i]),
+				Height:   block.Header.Height,
+				MmrIndex: mmrLeafIndex(leaves + uint64(i)),
+			}, nil
+		}
+	}
+
+	return nil, errNotFound
+}
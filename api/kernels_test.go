@@ -0,0 +1,52 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestKernel(t *testing.T) {
+	c := newBlocksChain(3)
+
+	// the MMR of the 1+2+3 kernels after the block 3
+	c.blocks[3].Header.KernelMmrSize = 10
+	s := New(c, &testPool{}, testPeers{})
+
+	excess := hex.EncodeToString(c.blocks[3].Kernels[1].Excess.Bytes())
+
+	w := request(s, http.MethodGet, "/v1/chain/kernels/"+excess, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var kernel LocatedTxKernel
+	if err := json.NewDecoder(w.Body).Decode(&kernel); err != nil {
+		t.Fatal(err)
+	}
+
+	// the kernel is the leaf 4 of the position 8
+	if kernel.TxKernel.Excess != excess || kernel.Height != 3 || kernel.MmrIndex != 8 {
+		t.Errorf("kernel was %+v, want %s at 3 of mmr index 8", kernel, excess)
+	}
+
+	for _, test := range []struct {
+		url  string
+		code int
+	}{
+		{"/v1/chain/kernels/" + excess + "?min_height=2&max_height=3", http.StatusOK},
+		{"/v1/chain/kernels/" + excess + "?max_height=2", http.StatusNotFound},
+		{"/v1/chain/kernels/" + excess + "?min_height=3&max_height=2", http.StatusBadRequest},
+		{"/v1/chain/kernels/" + excess + "?min_height=x", http.StatusBadRequest},
+		{"/v1/chain/kernels/abcd", http.StatusBadRequest},
+	} {
+		if w := request(s, http.MethodGet, test.url, ""); w.Code != test.code {
+			t.Errorf("%s: status code was %d, want %d", test.url, w.Code, test.code)
+		}
+	}
+}
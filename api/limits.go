@@ -0,0 +1,197 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxPeersPage is the max count of peers in the peers page, the page
+	// is of this size if limit is not set
+	MaxPeersPage = 100
+
+	// MaxOutputIDs is the max count of commitments in the outputs request
+	MaxOutputIDs = 1000
+
+	// MaxBatchSize is the max count of requests in the JSON-RPC batch
+	MaxBatchSize = 100
+
+	// maxRateClients is the count of the client buckets kept, the least
+	// recently seen client is dropped for the new one
+	maxRateClients = 10000
+)
+
+var (
+	errInvalidPage  = errors.New("invalid page")
+	errTooManyIDs   = errors.New("too many output ids")
+	errRateLimited  = errors.New("rate limit exceeded")
+	errBatchTooLong = errors.New("batch too large")
+)
+
+// pageParams returns the offset & the limit of the page request:
+// ?offset=x&limit=y, the limit is max by default & up to max
+func pageParams(r *http.Request, max int) (offset, limit int, err error) {
+	query := r.URL.Query()
+
+	limit = max
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > max {
+			return 0, 0, errInvalidPage
+		}
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, errInvalidPage
+		}
+	}
+
+	return offset, limit, nil
+}
+
+// rpcPage returns the offset & the limit of the JSON-RPC page params, the
+// omitted limit is max
+func rpcPage(offset, limit, max int) (int, int, error) {
+	if limit == 0 {
+		limit = max
+	}
+
+	if offset < 0 || limit < 0 || limit > max {
+		return 0, 0, &rpcError{Code: codeInvalidParams, Message: errInvalidPage.Error()}
+	}
+
+	return offset, limit, nil
+}
+
+// rpcConnectedPeers returns the page of the connected peers:
+// get_connected_peers [offset, limit]
+func (s *Server) rpcConnectedPeers(params json.RawMessage) (interface{}, error) {
+	var offset, limit int
+	if err := parseParams(params, &offset, &limit); err != nil {
+		return nil, err
+	}
+
+	offset, limit, err := rpcPage(offset, limit, MaxPeersPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return peersPage(s.peerList(), offset, limit), nil
+}
+
+// peersPage returns the page of the peers
+func peersPage(peers []PeerInfo, offset, limit int) []PeerInfo {
+	if offset >= len(peers) {
+		return make([]PeerInfo, 0)
+	}
+
+	peers = peers[offset:]
+	if len(peers) > limit {
+		peers = peers[:limit]
+	}
+
+	return peers
+}
+
+// SetRateLimit limits the foreign API requests of the client addr to rate
+// per second with the burst of requests, zero rate disables the limit
+func (s *Server) SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		s.limiter = nil
+		return
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	s.limiter = &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		size:    maxRateClients,
+		order:   list.New(),
+		clients: make(map[string]*list.Element),
+	}
+}
+
+// rateLimiter is the LRU of the token buckets of the client addrs, up to size
+// clients
+type rateLimiter struct {
+	sync.Mutex
+	rate    float64
+	burst   float64
+	size    int
+	order   *list.List
+	clients map[string]*list.Element
+}
+
+// bucket is the tokens of the client left at last
+type bucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+// allow takes the token of the client at now, or returns the wait of the
+// next token
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	elem, ok := l.clients[client]
+	if ok {
+		l.order.MoveToFront(elem)
+	} else {
+		if l.order.Len() >= l.size {
+			last := l.order.Back()
+			l.order.Remove(last)
+			delete(l.clients, last.Value.(*bucket).client)
+		}
+
+		elem = l.order.PushFront(&bucket{client: client, tokens: l.burst, last: now})
+		l.clients[client] = elem
+	}
+
+	b := elem.Value.(*bucket)
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// limit answers 429 to the client over the rate limit, the health probes
+// aren't limited
+func (s *Server) limit(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limiter == nil || r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, wait := s.limiter.allow(s.clientIP(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, errRateLimited)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
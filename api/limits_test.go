@@ -0,0 +1,146 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/p2p"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	s := newTestServer()
+	s.SetRateLimit(2, 2)
+
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _ := s.limiter.allow("10.0.0.1", now); !ok {
+			t.Fatalf("request %d of the burst was limited", i)
+		}
+	}
+
+	ok, wait := s.limiter.allow("10.0.0.1", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("request over the burst was allowed: %v, wait %s", ok, wait)
+	}
+
+	if ok, _ := s.limiter.allow("10.0.0.2", now); !ok {
+		t.Errorf("request of the other client was limited")
+	}
+
+	if ok, _ := s.limiter.allow("10.0.0.1", now.Add(wait)); !ok {
+		t.Errorf("request after the wait was limited")
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	s := newTestServer()
+	s.SetRateLimit(1, 1)
+	s.limiter.size = 2
+
+	now := time.Now()
+	for _, client := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "10.0.0.3"} {
+		s.limiter.allow(client, now)
+	}
+
+	// the least recently seen client is dropped for the new one
+	if len(s.limiter.clients) != 2 || s.limiter.clients["10.0.0.2"] != nil || s.limiter.clients["10.0.0.1"] == nil {
+		t.Errorf("tracked clients were %v", s.limiter.clients)
+	}
+
+	if ok, _ := s.limiter.allow("10.0.0.1", now); ok {
+		t.Errorf("request of the tracked client over the limit was allowed")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newTestServer()
+	s.SetRateLimit(1, 1)
+	handler := s.limit(s)
+
+	if w := request(handler, http.MethodGet, "/v1/chain", ""); w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w := request(handler, http.MethodGet, "/v1/chain", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("status code was %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	if w := request(handler, http.MethodGet, "/healthz", ""); w.Code == http.StatusTooManyRequests {
+		t.Errorf("health probe was limited")
+	}
+
+	s.SetRateLimit(0, 0)
+	if w := request(handler, http.MethodGet, "/v1/chain", ""); w.Code != http.StatusOK {
+		t.Errorf("disabled limit answered %d", w.Code)
+	}
+}
+
+func TestPeersPage(t *testing.T) {
+	peers := make(testPeers, MaxPeersPage+5)
+	for i := range peers {
+		peers[i] = p2p.PeerStats{Addr: "127.0.0.1:13414"}
+	}
+	s := New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+
+	for url, want := range map[string]int{
+		"/v1/peers/connected":                  MaxPeersPage,
+		"/v1/peers/connected?offset=100":       5,
+		"/v1/peers/connected?offset=2&limit=3": 3,
+		"/v1/peers/connected?offset=200":       0,
+	} {
+		w := request(s, http.MethodGet, url, "")
+		var result []PeerInfo
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: invalid response %s", url, w.Body.String())
+		}
+
+		if len(result) != want {
+			t.Errorf("%s: count of peers was %d, want %d", url, len(result), want)
+		}
+	}
+
+	for _, url := range []string{"/v1/peers/connected?limit=0", "/v1/peers/connected?limit=101", "/v1/peers/connected?offset=-1"} {
+		if w := request(s, http.MethodGet, url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code was %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w := request(s.Owner(), http.MethodGet, "/v1/peers?state=all&limit=10", "")
+	if n := strings.Count(w.Body.String(), `"addr"`); n != 10 {
+		t.Errorf("count of owner peers was %d, want 10", n)
+	}
+
+	for params, want := range map[string]int{"[]": MaxPeersPage, "[100]": 5, "[2, 3]": 3} {
+		w := request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_connected_peers", "params": `+params+`}`)
+		if n := strings.Count(w.Body.String(), `"addr"`); n != want {
+			t.Errorf("get_connected_peers %s: count of peers was %d, want %d", params, n, want)
+		}
+	}
+
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_connected_peers", "params": [0, 101]}`)
+	if !strings.Contains(w.Body.String(), errInvalidPage.Error()) {
+		t.Errorf("page over the limit returned %s", w.Body.String())
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	s := newTestServer()
+
+	ids := strings.Repeat("08,", MaxOutputIDs) + "08"
+	if w := request(s, http.MethodGet, "/v1/chain/outputs/byids?id="+ids, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	batch := "[" + strings.TrimSuffix(strings.Repeat(`{"jsonrpc": "2.0", "id": 1, "method": "get_tip"},`, MaxBatchSize+1), ",") + "]"
+	if w := request(s, http.MethodPost, "/v2/foreign", batch); !strings.Contains(w.Body.String(), errBatchTooLong.Error()) {
+		t.Errorf("batch over the limit returned %s", w.Body.String())
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"net/http"
+)
+
+// maxMerkleProofRequest is the max body size of the merkle proof request
+const maxMerkleProofRequest = 64 << 10
+
+// MerkleProofRequest is the body of the merkle_proof/verify request: the
+// output of the header output root & its merkle proof supplied by the full
+// node
+type MerkleProofRequest struct {
+	// Header is the hash or height of the header
+	Header     string `json:"header"`
+	OutputType string `json:"output_type"`
+	Commit     string `json:"commit"`
+	MmrIndex   uint64 `json:"mmr_index"`
+	// MerkleProof is the hex of the serialized grin proof
+	MerkleProof string `json:"merkle_proof"`
+}
+
+// MerkleProofResult is the result of the merkle proof verification
+type MerkleProofResult struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// verifyMerkleProof verifies the output merkle proof against the output root
+// of the header: POST /v1/chain/merkle_proof/verify
+func (s *Server) verifyMerkleProof(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+		return
+	}
+
+	var req MerkleProofRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMerkleProofRequest)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %v", err))
+		return
+	}
+
+	result, err := s.merkleProof(&req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err == errNotFound {
+			status = http.StatusNotFound
+		}
+
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// merkleProof returns the verification result of the request proof, the
+// invalid request returns error
+func (s *Server) merkleProof(req *MerkleProofRequest) (*MerkleProofResult, error) {
+	block, err := s.findBlock(req.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := outputFeatures(req.OutputType)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := decodeHex("commit", req.Commit, secp256k1zkp.PedersenCommitmentSize)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeHex("merkle_proof", req.MerkleProof, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var proof consensus.MerkleProof
+	if err := proof.Read(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid merkle_proof: %v", err)
+	}
+
+	// the output MMR leaf is the output without the range proof
+	leaf := append([]byte{byte(features)}, commit...)
+	if err := proof.Verify(block.Header.UTXORoot, leaf, req.MmrIndex); err != nil {
+		return &MerkleProofResult{Error: err.Error()}, nil
+	}
+
+	return &MerkleProofResult{Valid: true}, nil
+}
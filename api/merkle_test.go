@@ -0,0 +1,85 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/dblokhin/gringo/consensus"
+	"net/http"
+	"testing"
+)
+
+func TestVerifyMerkleProof(t *testing.T) {
+	commits := [][]byte{bytes.Repeat([]byte{8}, 33), bytes.Repeat([]byte{9}, 33)}
+
+	// the output MMR of two plain outputs
+	var mmr consensus.MMR
+	for _, commit := range commits {
+		mmr.Append(append([]byte{byte(consensus.DefaultOutput)}, commit...))
+	}
+
+	c := newBlocksChain(2)
+	c.blocks[2].Header.UTXORoot = mmr.Root()
+	s := New(c, &testPool{}, testPeers{})
+
+	sibling, _ := consensus.ParseHash(leafHash(2, append([]byte{byte(consensus.DefaultOutput)}, commits[1]...)))
+	proof := consensus.MerkleProof{MmrSize: mmr.Size(), Path: []consensus.Hash{sibling}}
+
+	verify := func(req MerkleProofRequest) (int, MerkleProofResult) {
+		body, _ := json.Marshal(req)
+		w := request(s, http.MethodPost, "/v1/chain/merkle_proof/verify", string(body))
+
+		var result MerkleProofResult
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return w.Code, result
+	}
+
+	req := MerkleProofRequest{
+		Header:      "2",
+		OutputType:  "Plain",
+		Commit:      hex.EncodeToString(commits[0]),
+		MmrIndex:    1,
+		MerkleProof: hex.EncodeToString(proof.Bytes()),
+	}
+
+	if code, result := verify(req); code != http.StatusOK || !result.Valid {
+		t.Errorf("valid proof: %d %+v", code, result)
+	}
+
+	coinbase := req
+	coinbase.OutputType = "Coinbase"
+	if code, result := verify(coinbase); code != http.StatusOK || result.Valid {
+		t.Errorf("proof of other features: %d %+v", code, result)
+	}
+
+	other := req
+	other.Header = "1"
+	if code, result := verify(other); code != http.StatusOK || result.Valid {
+		t.Errorf("proof of other header: %d %+v", code, result)
+	}
+
+	unknown := req
+	unknown.Header = "3"
+	if code, _ := verify(unknown); code != http.StatusNotFound {
+		t.Errorf("unknown header: status code was %d, want %d", code, http.StatusNotFound)
+	}
+
+	malformed := req
+	malformed.MerkleProof = "00"
+	if code, _ := verify(malformed); code != http.StatusBadRequest {
+		t.Errorf("malformed proof: status code was %d, want %d", code, http.StatusBadRequest)
+	}
+
+	if w := request(s, http.MethodGet, "/v1/chain/merkle_proof/verify", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status code was %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/mining"
+	"time"
+)
+
+// SetMining adds the owner methods controlling the miner at runtime:
+// get_mining_status, pause_mining, resume_mining, set_mining_threads &
+// set_mining_window
+func (s *Server) SetMining(controller *mining.Controller) {
+	s.RegisterOwnerMethod("get_mining_status", func(params json.RawMessage) (interface{}, error) {
+		return controller.Status(time.Now()), nil
+	})
+
+	s.RegisterOwnerMethod("pause_mining", func(params json.RawMessage) (interface{}, error) {
+		controller.Pause()
+		return controller.Status(time.Now()), nil
+	})
+
+	s.RegisterOwnerMethod("resume_mining", func(params json.RawMessage) (interface{}, error) {
+		controller.Resume()
+		return controller.Status(time.Now()), nil
+	})
+
+	s.RegisterOwnerMethod("set_mining_threads", func(params json.RawMessage) (interface{}, error) {
+		var threads int
+		if err := parseParams(params, &threads); err != nil {
+			return nil, err
+		}
+
+		if err := controller.SetThreads(threads); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return controller.Status(time.Now()), nil
+	})
+
+	s.RegisterOwnerMethod("set_mining_window", func(params json.RawMessage) (interface{}, error) {
+		var window string
+		if err := parseParams(params, &window); err != nil {
+			return nil, err
+		}
+
+		w, err := mining.ParseWindow(window)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		controller.SetWindow(w)
+
+		return controller.Status(time.Now()), nil
+	})
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/mining"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOwnerMining(t *testing.T) {
+	s := newTestServer()
+	s.SetMining(mining.NewController(mining.Controls{Threads: 2}, nil))
+
+	status := func(method, params string) mining.Status {
+		t.Helper()
+
+		w := request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`)
+		var response struct {
+			Result struct {
+				Ok mining.Status `json:"Ok"`
+			} `json:"result"`
+			Error *rpcError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error != nil {
+			t.Fatalf("%s failed: %s", method, w.Body.String())
+		}
+
+		return response.Result.Ok
+	}
+
+	if st := status("get_mining_status", "[]"); !st.Mining || st.Threads != 2 {
+		t.Errorf("unexpected mining status: %+v", st)
+	}
+
+	if st := status("pause_mining", "[]"); st.Mining || st.Reason != mining.ReasonPaused {
+		t.Errorf("mining was not paused: %+v", st)
+	}
+
+	if st := status("resume_mining", "[]"); !st.Mining {
+		t.Errorf("mining was not resumed: %+v", st)
+	}
+
+	if st := status("set_mining_threads", "[4]"); st.Threads != 4 {
+		t.Errorf("threads were %d, want 4", st.Threads)
+	}
+
+	if st := status("set_mining_window", `["22:00-06:00"]`); st.RunWindow != "22:00-06:00" {
+		t.Errorf("run window was %q, want 22:00-06:00", st.RunWindow)
+	}
+
+	for _, body := range []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "set_mining_threads", "params": [-1]}`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "set_mining_window", "params": ["night"]}`,
+	} {
+		if w := request(s.Owner(), http.MethodPost, "/v2/owner", body); !strings.Contains(w.Body.String(), `"error"`) {
+			t.Errorf("invalid params were accepted: %s", w.Body.String())
+		}
+	}
+}
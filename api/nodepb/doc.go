@@ -0,0 +1,9 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package nodepb is the gRPC Node service generated from node.proto by
+// protoc-gen-go v1.2.0 with the grpc plugin
+package nodepb
+
+//go:generate protoc --go_out=plugins=grpc:. node.proto
@@ -0,0 +1,119 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"math/bits"
+	"net/http"
+	"strconv"
+)
+
+// MaxOutputsPage is the max count of outputs in the outputs/byheight page
+const MaxOutputsPage = 1000
+
+// mmrLeaves returns the count of leaves in the MMR of size
+func mmrLeaves(size uint64) uint64 {
+	var leaves uint64
+
+	// the MMR is the list of perfect trees of decreasing heights
+	for height := 62; height >= 0; height-- {
+		if peak := uint64(1)<<uint(height+1) - 1; size >= peak {
+			size -= peak
+			leaves += 1 << uint(height)
+		}
+	}
+
+	return leaves
+}
+
+// mmrLeafIndex returns the 1-based MMR position of the leaf n counted from 0
+func mmrLeafIndex(n uint64) uint64 {
+	return 2*n - uint64(bits.OnesCount64(n)) + 1
+}
+
+// firstLeaf returns the number of the first of n leaves added by the block,
+// the header keeps the MMR size after the block
+func firstLeaf(size uint64, n int) uint64 {
+	leaves := mmrLeaves(size)
+	if uint64(n) <= leaves {
+		return leaves - uint64(n)
+	}
+
+	return 0
+}
+
+// outputListing returns the page of the outputs created from the start
+// height for the wallet restore:
+// /v1/chain/outputs/byheight?start=x[&end=y][&limit=n]
+//
+// The blocks are never split, the page ends after the block reaching the
+// limit, or the empty page after MaxHeightRange blocks without outputs. The
+// next page starts at last_retrieved_height + 1.
+func (s *Server) outputListing(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	start, err := strconv.ParseUint(query.Get("start"), 10, 64)
+	if err != nil {
+		return nil, errInvalidRange
+	}
+
+	height := s.chain.Height()
+
+	end := height
+	if value := query.Get("end"); value != "" {
+		if end, err = strconv.ParseUint(value, 10, 64); err != nil || end < start {
+			return nil, errInvalidRange
+		}
+	}
+
+	limit := uint64(MaxOutputsPage)
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || n == 0 || n > MaxOutputsPage {
+			return nil, errInvalidRange
+		}
+		limit = n
+	}
+
+	if end > height {
+		end = height
+	}
+
+	result := OutputListing{
+		HighestHeight: height,
+		Outputs:       make([]OutputPrintable, 0),
+	}
+
+	if start > end {
+		result.LastRetrievedHeight = height
+		return result, nil
+	}
+
+	for h := start; h <= end && h-start < MaxHeightRange; h++ {
+		// the outputs of the next block would exceed the page
+		block, err := s.findBlock(strconv.FormatUint(h, 10))
+		if err == nil && len(result.Outputs) > 0 && uint64(len(result.Outputs)+len(block.Outputs)) > limit {
+			break
+		}
+
+		result.LastRetrievedHeight = h
+		if err != nil {
+			continue
+		}
+
+		leaves := firstLeaf(block.Header.OutputMmrSize, len(block.Outputs))
+		for i := range block.Outputs {
+			output := newOutputPrintable(&block.Outputs[i], h, s.isSpent(&block.Outputs[i]))
+			output.MmrIndex = mmrLeafIndex(leaves + uint64(i))
+			result.Outputs = append(result.Outputs, output)
+		}
+
+		if uint64(len(result.Outputs)) >= limit {
+			break
+		}
+	}
+
+	return result, nil
+}
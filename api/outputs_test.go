@@ -0,0 +1,68 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestMMRIndex(t *testing.T) {
+	// the MMR sizes of 0..7 leaves
+	sizes := []uint64{0, 1, 3, 4, 7, 8, 10, 11}
+	for leaves, size := range sizes {
+		if got := mmrLeaves(size); got != uint64(leaves) {
+			t.Errorf("leaves of the MMR size %d were %d, want %d", size, got, leaves)
+		}
+	}
+
+	// the leaf positions are 1, 2, 4, 5, 8, 9, 11, 12
+	for n, index := range []uint64{1, 2, 4, 5, 8, 9, 11, 12} {
+		if got := mmrLeafIndex(uint64(n)); got != index {
+			t.Errorf("index of the leaf %d was %d, want %d", n, got, index)
+		}
+	}
+}
+
+func TestOutputListing(t *testing.T) {
+	s := New(newBlocksChain(5), &testPool{}, testPeers{})
+
+	for _, test := range []struct {
+		url  string
+		last uint64
+	}{
+		{"/v1/chain/outputs/byheight?start=1&end=3", 3},
+		{"/v1/chain/outputs/byheight?start=2", 5},
+		{"/v1/chain/outputs/byheight?start=4&end=100&limit=10", 5},
+		{"/v1/chain/outputs/byheight?start=10", 5},
+	} {
+		w := request(s, http.MethodGet, test.url, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status code was %d, want %d", test.url, w.Code, http.StatusOK)
+			continue
+		}
+
+		var listing OutputListing
+		if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+			t.Fatal(err)
+		}
+
+		if listing.HighestHeight != 5 || listing.LastRetrievedHeight != test.last {
+			t.Errorf("%s: heights were %d %d, want 5 %d", test.url, listing.HighestHeight, listing.LastRetrievedHeight, test.last)
+		}
+	}
+
+	for _, url := range []string{
+		"/v1/chain/outputs/byheight?start=x",
+		"/v1/chain/outputs/byheight?start=3&end=2",
+		"/v1/chain/outputs/byheight?start=1&limit=0",
+		"/v1/chain/outputs/byheight?start=1&limit=1001",
+	} {
+		if w := request(s, http.MethodGet, url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code was %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
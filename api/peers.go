@@ -0,0 +1,156 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"fmt"
+	"github.com/dblokhin/gringo/p2p"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// peersByState returns printable peers of the state: all, connected or
+// banned
+func (s *Server) peersByState(state string) ([]PeerInfo, error) {
+	switch state {
+	case "", "all":
+	case "connected":
+		return s.peerList(), nil
+	case "banned":
+	default:
+		return nil, fmt.Errorf("unknown peers state: %s", state)
+	}
+
+	result := make([]PeerInfo, 0)
+	for _, stats := range s.peers.All() {
+		if state == "banned" && stats.Status != "banned" {
+			continue
+		}
+		result = append(result, newPeerInfo(stats))
+	}
+
+	return result, nil
+}
+
+// allPeers returns the page of the peers of the state, all by default:
+// /v1/peers?state=all|connected|banned&offset=x&limit=y
+func (s *Server) allPeers(r *http.Request) (interface{}, error) {
+	offset, limit, err := pageParams(r, MaxPeersPage)
+	if err != nil {
+		return nil, err
+	}
+
+	peers, err := s.peersByState(r.URL.Query().Get("state"))
+	if err != nil {
+		return nil, err
+	}
+
+	return peersPage(peers, offset, limit), nil
+}
+
+// peerAction bans or unbans the peer or the CIDR range:
+// POST /v1/peers/{addr|cidr}/ban|unban
+func (s *Server) peerAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/peers/")
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	addr, action := path[:i], path[i+1:]
+	if action != "ban" && action != "unban" {
+		writeError(w, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	if err := s.banPeer(addr, action == "ban"); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// validBan returns nil if addr is the peer addr or the CIDR range
+func validBan(addr string) error {
+	if _, _, err := net.ParseCIDR(addr); err != nil {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid peer addr or range: %s", addr)
+		}
+	}
+
+	return nil
+}
+
+// banPeer bans or unbans the peer addr or the CIDR range
+func (s *Server) banPeer(addr string, ban bool) error {
+	if err := validBan(addr); err != nil {
+		return err
+	}
+
+	if ban {
+		s.peers.Ban(addr)
+	} else {
+		s.peers.Unban(addr)
+	}
+
+	return nil
+}
+
+// connLog returns the connection events of the query, the latest first
+func (s *Server) connLog(addr, event, since string, limit int) ([]ConnEvent, error) {
+	query := p2p.ConnLogQuery{Addr: addr, Event: event, Limit: limit}
+
+	switch event {
+	case "", p2p.ConnConnect, p2p.ConnDisconnect, p2p.ConnBan:
+	default:
+		return nil, fmt.Errorf("unknown connection event: %s", event)
+	}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since time: %s", since)
+		}
+		query.Since = t
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	result := make([]ConnEvent, 0)
+	for _, event := range s.peers.ConnLog(query) {
+		result = append(result, newConnEvent(event))
+	}
+
+	return result, nil
+}
+
+// peersLog returns the connection audit log of the owner API:
+// /v1/peers/log?addr=x&event=connect|disconnect|ban&since=rfc3339&limit=n
+func (s *Server) peersLog(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit: %s", value)
+		}
+		limit = n
+	}
+
+	return s.connLog(query.Get("addr"), query.Get("event"), query.Get("since"), limit)
+}
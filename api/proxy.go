@@ -0,0 +1,161 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	corsMethods = "GET, POST, OPTIONS"
+	corsHeaders = "Authorization, Content-Type"
+	// corsMaxAge is the seconds the browser caches the preflight response
+	corsMaxAge = "600"
+)
+
+// SetCORS allows the browser requests of the foreign API from the origins,
+// "*" allows any origin. The owner API is never allowed
+func (s *Server) SetCORS(origins []string) {
+	s.corsOrigins = make(map[string]bool)
+	for _, origin := range origins {
+		s.corsOrigins[strings.TrimSuffix(origin, "/")] = true
+	}
+}
+
+// SetTrustedProxies sets the IPs or the CIDR ranges of the reverse proxies
+// the client addr is taken from the X-Forwarded-For header of
+func (s *Server) SetTrustedProxies(proxies []string) error {
+	s.trustedProxies = nil
+	for _, proxy := range proxies {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return err
+		}
+		s.trustedProxies = append(s.trustedProxies, network)
+	}
+
+	return nil
+}
+
+// SetBasePath serves the API under the path prefix, as /grin of the nginx
+// location proxying the API
+func (s *Server) SetBasePath(path string) {
+	s.basePath = strings.TrimSuffix(path, "/")
+}
+
+// parseNetwork returns the network of the CIDR range or the single IP
+func parseNetwork(value string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid proxy addr: %s", value)
+	}
+
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// trusted reports whether ip is of the trusted proxies
+func (s *Server) trusted(ip net.IP) bool {
+	for _, network := range s.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the addr of the client: the X-Forwarded-For addrs are
+// walked from the latest while they are of the trusted proxies
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !s.trusted(ip) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		next := net.ParseIP(addr)
+		if next == nil {
+			break
+		}
+
+		host = addr
+		if !s.trusted(next) {
+			break
+		}
+	}
+
+	return host
+}
+
+// cors adds the CORS headers of the allowed origin & answers the preflight
+// requests before the auth, the browsers send them without the credentials
+func (s *Server) cors(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(s.corsOrigins["*"] || s.corsOrigins[origin]) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+			w.Header().Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// frontend wraps the listener handler to strip the base path & log the
+// requests by the client addr
+func (s *Server) frontend(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logrus.WithFields(logrus.Fields{
+			"client": s.clientIP(r),
+			"method": r.Method,
+			"path":   r.URL.Path,
+		}).Debug("api request")
+
+		if s.basePath == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, s.basePath)
+		if len(path) == len(r.URL.Path) || !strings.HasPrefix(path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.StripPrefix(s.basePath, handler).ServeHTTP(w, r)
+	})
+}
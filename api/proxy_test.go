@@ -0,0 +1,91 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	s := newTestServer()
+	if err := s.SetTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetTrustedProxies([]string{"nginx"}); err == nil {
+		t.Errorf("invalid proxy was accepted")
+	}
+	s.SetTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8"})
+
+	for _, test := range []struct {
+		remote, forwarded, ip string
+	}{
+		{"192.0.2.1:1000", "203.0.113.5", "192.0.2.1"},
+		{"127.0.0.1:1000", "", "127.0.0.1"},
+		{"127.0.0.1:1000", "203.0.113.5", "203.0.113.5"},
+		// the spoofed addr before the untrusted one is ignored
+		{"127.0.0.1:1000", "198.51.100.7, 203.0.113.5, 10.0.0.2", "203.0.113.5"},
+		{"127.0.0.1:1000", "garbage, 10.0.0.2", "10.0.0.2"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+		r.RemoteAddr = test.remote
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if ip := s.clientIP(r); ip != test.ip {
+			t.Errorf("client of %s & %q was %s, want %s", test.remote, test.forwarded, ip, test.ip)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := newTestServer()
+	s.SetSecrets("secret", "")
+	s.SetCORS([]string{"https://wallet.example/"})
+	handler := s.frontend(s.cors(s))
+
+	// the preflight is answered without the credentials
+	r := httptest.NewRequest(http.MethodOptions, "/v2/foreign", nil)
+	r.Header.Set("Origin", "https://wallet.example")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://wallet.example" ||
+		w.Header().Get("Access-Control-Allow-Headers") != corsHeaders {
+		t.Errorf("unexpected preflight response %d: %v", w.Code, w.Header())
+	}
+
+	// the other origins get no CORS headers
+	r = httptest.NewRequest(http.MethodGet, "/v1/status", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("unexpected response %d: %v", w.Code, w.Header())
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	s := newTestServer()
+	s.SetBasePath("/grin/")
+	handler := s.frontend(s.cors(s))
+
+	for path, code := range map[string]int{
+		"/grin/v1/status": http.StatusOK,
+		"/grin/healthz":   http.StatusOK,
+		"/v1/status":      http.StatusNotFound,
+		"/grinv1/status":  http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != code {
+			t.Errorf("%s returned %d, want %d", path, w.Code, code)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+)
+
+// SetReload adds the owner method reload_config applying the runtime
+// settings of the config file as SIGHUP does
+func (s *Server) SetReload(reload func() error) {
+	s.RegisterOwnerMethod("reload_config", func(params json.RawMessage) (interface{}, error) {
+		return nil, reload()
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOwnerReload(t *testing.T) {
+	s := newTestServer()
+
+	var err error
+	reloads := 0
+	s.SetReload(func() error {
+		reloads++
+		return err
+	})
+
+	w := request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "reload_config", "params": []}`)
+	if strings.Contains(w.Body.String(), "error") || strings.Contains(w.Body.String(), "Err") || reloads != 1 {
+		t.Errorf("reload_config failed: %s", w.Body.String())
+	}
+
+	err = errors.New("invalid p2p.max_peers: 0")
+	w = request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "reload_config", "params": []}`)
+	if !strings.Contains(w.Body.String(), "invalid p2p.max_peers") {
+		t.Errorf("failed reload was reported as %s", w.Body.String())
+	}
+
+	if w := request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "reload_config", "params": []}`); !strings.Contains(w.Body.String(), "error") {
+		t.Errorf("reload_config is served by the foreign api: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"golang.org/x/net/websocket"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// reorgBuffer is the count of the reorgs queued to the websocket client
+const reorgBuffer = 16
+
+// Reorg is the switch of the main chain to the fork: the exchanges freeze the
+// deposits of the unconfirmed kernels
+type Reorg struct {
+	Time       time.Time `json:"time"`
+	ForkHeight uint64    `json:"fork_height"`
+	// Disconnected is the hashes of the removed main chain blocks, from
+	// the lowest
+	Disconnected []string `json:"disconnected_blocks"`
+	Connected    []string `json:"connected_blocks"`
+	// Kernels is the hashes of the kernels of the transactions lost the
+	// confirmations
+	Kernels []string `json:"unconfirmed_kernels"`
+}
+
+// newReorg returns the json of the reorg event
+func newReorg(event *chain.ReorgEvent) Reorg {
+	reorg := Reorg{
+		Time:         event.Time.UTC(),
+		ForkHeight:   event.ForkHeight,
+		Disconnected: make([]string, 0, len(event.Disconnected)),
+		Connected:    make([]string, 0, len(event.Connected)),
+		Kernels:      make([]string, 0, len(event.Kernels)),
+	}
+
+	for _, hash := range event.Disconnected {
+		reorg.Disconnected = append(reorg.Disconnected, hash.String())
+	}
+	for _, hash := range event.Connected {
+		reorg.Connected = append(reorg.Connected, hash.String())
+	}
+	for _, hash := range event.Kernels {
+		reorg.Kernels = append(reorg.Kernels, hash.String())
+	}
+
+	return reorg
+}
+
+// reorgs returns the recent reorgs, the latest last: /v1/chain/reorgs
+func (s *Server) reorgs(r *http.Request) (interface{}, error) {
+	events := s.chain.Reorgs()
+
+	result := make([]Reorg, 0, len(events))
+	for i := range events {
+		result = append(result, newReorg(&events[i]))
+	}
+
+	return result, nil
+}
+
+// reorgsWS streams the reorgs to the websocket client as the json messages:
+// /v1/chain/reorgs/ws. The reorgs are skipped while the client is behind by
+// reorgBuffer events
+func (s *Server) reorgsWS() http.Handler {
+	// the origin is not checked, the API secret authorizes the clients
+	return websocket.Server{Handler: func(ws *websocket.Conn) {
+		// the stream outlives the request timeouts of the listener
+		ws.SetDeadline(time.Time{})
+
+		events := make(chan *chain.ReorgEvent, reorgBuffer)
+		s.chain.SubscribeReorgs(events)
+		defer s.chain.UnsubscribeReorgs(events)
+
+		// the client messages are discarded, the read fails on close
+		closed := make(chan struct{})
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			close(closed)
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case event := <-events:
+				if err := websocket.JSON.Send(ws, newReorg(event)); err != nil {
+					return
+				}
+			}
+		}
+	}}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// reorgChain keeps the reorgs & signals the subscription
+type reorgChain struct {
+	testChain
+	events     []chain.ReorgEvent
+	subscribed chan chan<- *chain.ReorgEvent
+}
+
+func (c *reorgChain) Reorgs() []chain.ReorgEvent { return c.events }
+func (c *reorgChain) SubscribeReorgs(ch chan<- *chain.ReorgEvent) {
+	c.subscribed <- ch
+}
+
+func TestReorgs(t *testing.T) {
+	event := chain.ReorgEvent{
+		ForkHeight:   10,
+		Disconnected: []consensus.Hash{{0x01}, {0x02}},
+		Connected:    []consensus.Hash{{0x03}},
+		Kernels:      []consensus.Hash{{0x04}},
+	}
+	c := &reorgChain{
+		testChain:  testChain{genesis: chain.Testnet1},
+		events:     []chain.ReorgEvent{event},
+		subscribed: make(chan chan<- *chain.ReorgEvent, 1),
+	}
+	s := New(c, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/chain/reorgs", "")
+	var reorgs []Reorg
+	if err := json.Unmarshal(w.Body.Bytes(), &reorgs); err != nil {
+		t.Fatalf("invalid reorgs response %s: %v", w.Body.String(), err)
+	}
+
+	if len(reorgs) != 1 || reorgs[0].ForkHeight != 10 || len(reorgs[0].Disconnected) != 2 ||
+		reorgs[0].Kernels[0] != event.Kernels[0].String() {
+		t.Errorf("unexpected reorgs: %+v", reorgs)
+	}
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/chain/reorgs/ws"
+	ws, err := websocket.Dial(url, "", server.URL)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	defer ws.Close()
+
+	(<-c.subscribed) <- &event
+
+	var reorg Reorg
+	if err := websocket.JSON.Receive(ws, &reorg); err != nil {
+		t.Fatalf("reorg was not received: %v", err)
+	}
+
+	if reorg.ForkHeight != 10 || len(reorg.Connected) != 1 || reorg.Connected[0] != event.Connected[0].String() {
+		t.Errorf("unexpected reorg message: %+v", reorg)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"github.com/dblokhin/gringo/monitor"
+)
+
+// ResourceMonitor is the sampled resource usage of the node used by the API
+type ResourceMonitor interface {
+	Usage() monitor.Usage
+}
+
+// Resources is the resource usage of the node process, the bandwidth is of
+// the peer messages of the last hour
+type Resources struct {
+	RSS              uint64 `json:"rss"`
+	OpenFDs          int    `json:"open_fds"`
+	Goroutines       int    `json:"goroutines"`
+	StorageSize      uint64 `json:"storage_size"`
+	SentLastHour     uint64 `json:"sent_last_hour"`
+	ReceivedLastHour uint64 `json:"received_last_hour"`
+	// Sampled is the unix time of the sample
+	Sampled int64 `json:"sampled"`
+}
+
+// SetResources adds the resource usage of the monitor to the node status
+func (s *Server) SetResources(resources ResourceMonitor) {
+	s.resources = resources
+}
+
+// newResources returns the resources of the sampled usage, nil before the
+// first sample
+func newResources(usage monitor.Usage) *Resources {
+	if usage.Sampled.IsZero() {
+		return nil
+	}
+
+	return &Resources{
+		RSS:              usage.RSS,
+		OpenFDs:          usage.OpenFDs,
+		Goroutines:       usage.Goroutines,
+		StorageSize:      usage.StorageSize,
+		SentLastHour:     usage.Sent,
+		ReceivedLastHour: usage.Received,
+		Sampled:          usage.Sampled.Unix(),
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/p2p"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Method is the JSON-RPC method handler, params are the raw request params
+type Method func(params json.RawMessage) (interface{}, error)
+
+// rpcRequest is the JSON-RPC 2.0 request
+type rpcRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is the JSON-RPC 2.0 response
+type rpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the JSON-RPC 2.0 error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error interface
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// RegisterMethod adds the foreign JSON-RPC method, replaces the existing one
+func (s *Server) RegisterMethod(name string, method Method) {
+	s.methods[name] = method
+}
+
+// RegisterOwnerMethod adds the owner JSON-RPC method, replaces the existing
+// one
+func (s *Server) RegisterOwnerMethod(name string, method Method) {
+	s.ownerMethods[name] = method
+}
+
+// registerMethods adds the node foreign & owner API methods
+func (s *Server) registerMethods() {
+	s.methods = make(map[string]Method)
+	s.ownerMethods = make(map[string]Method)
+
+	s.RegisterMethod("get_status", func(params json.RawMessage) (interface{}, error) {
+		return s.status(nil)
+	})
+
+	s.RegisterMethod("get_tip", func(params json.RawMessage) (interface{}, error) {
+		return s.chainTip(), nil
+	})
+
+	s.RegisterMethod("get_chain_tips", func(params json.RawMessage) (interface{}, error) {
+		return s.chainTips(), nil
+	})
+
+	s.RegisterMethod("get_block_stats", func(params json.RawMessage) (interface{}, error) {
+		return s.recentStats(), nil
+	})
+
+	s.RegisterMethod("get_network_hashrate", func(params json.RawMessage) (interface{}, error) {
+		var blocks *int
+		if err := parseParams(params, &blocks); err != nil {
+			return nil, err
+		}
+
+		window := DefaultHashrateWindow
+		if blocks != nil {
+			window = *blocks
+		}
+
+		rate, err := s.networkHashrate(window)
+		if err == errInvalidWindow {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		} else if err != nil {
+			return nil, err
+		}
+
+		return rate, nil
+	})
+
+	s.RegisterMethod("get_version", func(params json.RawMessage) (interface{}, error) {
+		head := s.chain.Head()
+
+		return Version{
+			NodeVersion:        p2p.Version,
+			BlockHeaderVersion: head.Header.Version,
+		}, nil
+	})
+
+	s.RegisterMethod("get_block", func(params json.RawMessage) (interface{}, error) {
+		block, err := s.rpcBlock(params)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewBlockPrintable(block, s.isSpent), nil
+	})
+
+	s.RegisterMethod("get_header", func(params json.RawMessage) (interface{}, error) {
+		block, err := s.rpcBlock(params)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewHeaderPrintable(&block.Header), nil
+	})
+
+	s.RegisterMethod("get_outputs", func(params json.RawMessage) (interface{}, error) {
+		var (
+			ids                        []string
+			start, end                 *uint64
+			includeProof, merkleProofs *bool
+		)
+
+		if err := parseParams(params, &ids, &start, &end, &includeProof, &merkleProofs); err != nil {
+			return nil, err
+		}
+
+		return s.rpcOutputs(ids, start, end, includeProof != nil && *includeProof)
+	})
+
+	s.RegisterMethod("get_outputs_by_height", func(params json.RawMessage) (interface{}, error) {
+		var start, end uint64
+		if err := parseParams(params, &start, &end); err != nil {
+			return nil, err
+		}
+
+		return s.blockOutputs(start, end)
+	})
+
+	s.RegisterMethod("get_kernel", func(params json.RawMessage) (interface{}, error) {
+		var (
+			excess   string
+			min, max *uint64
+		)
+
+		if err := parseParams(params, &excess, &min, &max); err != nil {
+			return nil, err
+		}
+
+		return s.locateKernel(excess, min, max)
+	})
+
+	s.RegisterMethod("get_pool_size", func(params json.RawMessage) (interface{}, error) {
+		return s.pool.Size(), nil
+	})
+
+	s.RegisterMethod("push_transaction", func(params json.RawMessage) (interface{}, error) {
+		var (
+			raw   json.RawMessage
+			fluff *bool
+		)
+
+		if err := parseParams(params, &raw, &fluff); err != nil {
+			return nil, err
+		}
+
+		// the hex serialized transaction of the first api version
+		var txHex string
+		if err := json.Unmarshal(raw, &txHex); err == nil {
+			return nil, s.pushTxHex(context.Background(), txHex, fluff != nil && *fluff)
+		}
+
+		var txJSON TxJSON
+		if err := json.Unmarshal(raw, &txJSON); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid transaction: %v", err)}
+		}
+
+		tx, err := txJSON.Transaction()
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return nil, s.addTx(context.Background(), tx, fluff != nil && *fluff)
+	})
+
+	s.RegisterMethod("get_connected_peers", s.rpcConnectedPeers)
+
+	s.RegisterOwnerMethod("get_peers", func(params json.RawMessage) (interface{}, error) {
+		var (
+			state         string
+			offset, limit int
+		)
+		if err := parseParams(params, &state, &offset, &limit); err != nil {
+			return nil, err
+		}
+
+		offset, limit, err := rpcPage(offset, limit, MaxPeersPage)
+		if err != nil {
+			return nil, err
+		}
+
+		peers, err := s.peersByState(state)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return peersPage(peers, offset, limit), nil
+	})
+
+	s.RegisterOwnerMethod("get_connected_peers", s.rpcConnectedPeers)
+
+	for name, ban := range map[string]bool{"ban_peer": true, "unban_peer": false} {
+		ban := ban
+		s.RegisterOwnerMethod(name, func(params json.RawMessage) (interface{}, error) {
+			var addr string
+			if err := parseParams(params, &addr); err != nil {
+				return nil, err
+			}
+
+			if err := s.banPeer(addr, ban); err != nil {
+				return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+			}
+
+			return nil, nil
+		})
+	}
+
+	s.RegisterOwnerMethod("get_connection_log", func(params json.RawMessage) (interface{}, error) {
+		var (
+			addr, event, since string
+			limit              int
+		)
+		if err := parseParams(params, &addr, &event, &since, &limit); err != nil {
+			return nil, err
+		}
+
+		result, err := s.connLog(addr, event, since, limit)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return result, nil
+	})
+
+	s.RegisterOwnerMethod("get_log_levels", func(params json.RawMessage) (interface{}, error) {
+		return logging.Levels(), nil
+	})
+
+	s.RegisterOwnerMethod("set_log_level", func(params json.RawMessage) (interface{}, error) {
+		var module, level string
+		if err := parseParams(params, &module, &level); err != nil {
+			return nil, err
+		}
+
+		if err := logging.SetLevel(module, level); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return nil, nil
+	})
+}
+
+// rpcBlock returns block by params [height, hash, commit], where commit is
+// the unspent output of the block. Only one of them is required
+func (s *Server) rpcBlock(params json.RawMessage) (*consensus.Block, error) {
+	var (
+		height       *uint64
+		hash, commit *string
+	)
+
+	if err := parseParams(params, &height, &hash, &commit); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case hash != nil:
+		return s.findBlock(*hash)
+	case height != nil:
+		return s.findBlock(strconv.FormatUint(*height, 10))
+	case commit != nil:
+		id, err := s.outputBlock(*commit)
+		if err != nil {
+			return nil, err
+		}
+		return s.blockByID(*id)
+	}
+
+	return nil, &rpcError{Code: codeInvalidParams, Message: "height, hash or commit is required"}
+}
+
+// rpcOutputs returns the unspent outputs by commitments or the outputs of
+// the blocks from start to end height
+func (s *Server) rpcOutputs(ids []string, start, end *uint64, includeProof bool) ([]OutputPrintable, error) {
+	result := make([]OutputPrintable, 0)
+
+	if ids != nil {
+		if len(ids) > MaxOutputIDs {
+			return nil, &rpcError{Code: codeInvalidParams, Message: errTooManyIDs.Error()}
+		}
+
+		for _, id := range ids {
+			blockID, err := s.outputBlock(id)
+			if err == errNotFound {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			block, err := s.blockByID(*blockID)
+			if err != nil {
+				continue
+			}
+
+			for i := range block.Outputs {
+				if hex.EncodeToString(block.Outputs[i].Commit.Bytes()) == id {
+					result = append(result, newOutputPrintable(&block.Outputs[i], block.Header.Height, false))
+				}
+			}
+		}
+	} else {
+		if start == nil || end == nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "commits or height range is required"}
+		}
+
+		blocks, err := s.blockOutputs(*start, *end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, block := range blocks {
+			result = append(result, block.Outputs...)
+		}
+	}
+
+	if !includeProof {
+		for i := range result {
+			result[i].Proof = nil
+		}
+	}
+
+	return result, nil
+}
+
+// rpcHandler returns the handler of JSON-RPC 2.0 single & batch requests
+// to methods
+func rpcHandler(methods map[string]Method) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveRPC(methods, w, r)
+	}
+}
+
+// serveRPC handles JSON-RPC 2.0 single & batch requests
+func serveRPC(methods map[string]Method, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, int64(consensus.MaxMsgLen)))
+	if err != nil {
+		writeJSON(w, http.StatusOK, newRPCError(nil, codeParseError, err.Error()))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+
+	// batch request
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			writeJSON(w, http.StatusOK, newRPCError(nil, codeParseError, err.Error()))
+			return
+		}
+
+		if len(batch) == 0 {
+			writeJSON(w, http.StatusOK, newRPCError(nil, codeInvalidRequest, "empty batch"))
+			return
+		}
+
+		if len(batch) > MaxBatchSize {
+			writeJSON(w, http.StatusOK, newRPCError(nil, codeInvalidRequest, errBatchTooLong.Error()))
+			return
+		}
+
+		responses := make([]*rpcResponse, 0, len(batch))
+		for _, raw := range batch {
+			if resp := call(methods, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+
+		if len(responses) == 0 {
+			// batch of notifications only
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	resp := call(methods, body)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// call executes the single request, returns nil on notification
+func call(methods map[string]Method, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return newRPCError(nil, codeParseError, err.Error())
+	}
+
+	if req.Version != "2.0" || req.Method == "" {
+		return newRPCError(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	method, ok := methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return newRPCError(req.ID, codeMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+	}
+
+	result, err := method(req.Params)
+	if req.ID == nil {
+		return nil
+	}
+
+	if e, ok := err.(*rpcError); ok {
+		return newRPCError(req.ID, e.Code, e.Message)
+	}
+
+	return &rpcResponse{
+		Version: "2.0",
+		ID:      req.ID,
+		Result:  newResult(result, err),
+	}
+}
+
+// newResult returns the method result in the grin api format: {"Ok": result}
+// or {"Err": error}
+func newResult(result interface{}, err error) map[string]interface{} {
+	switch {
+	case err == errNotFound:
+		return map[string]interface{}{"Err": "NotFound"}
+	case err != nil:
+		return map[string]interface{}{"Err": map[string]string{"Internal": err.Error()}}
+	}
+
+	return map[string]interface{}{"Ok": result}
+}
+
+// newRPCError returns error response
+func newRPCError(id json.RawMessage, code int, message string) *rpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &rpcResponse{
+		Version: "2.0",
+		ID:      id,
+		Error: &rpcError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// parseParams decodes the positional params into args, missing & null
+// params leave args unchanged
+func parseParams(params json.RawMessage, args ...interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(params, &list); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: "params must be an array"}
+	}
+
+	if len(list) > len(args) {
+		return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("too many params: %d", len(list))}
+	}
+
+	for i, param := range list {
+		if string(param) == "null" {
+			continue
+		}
+
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid param %d: %v", i, err)}
+		}
+	}
+
+	return nil
+}
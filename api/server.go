@@ -0,0 +1,643 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package api implements the node HTTP API compatible with the grin
+// foreign node API: REST (/v1/...) and JSON-RPC 2.0 (/v2/foreign), and the
+// node owner API: JSON-RPC 2.0 (/v2/owner) & the peers management
+// (/v1/peers) served by the separate handler
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxHeightRange is the max count of blocks in the outputs/byheight request
+const MaxHeightRange = 1000
+
+const (
+	// readHeaderTimeout & idleTimeout close the slow & the idle connections
+	// of the API listeners
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+
+	// readTimeout & writeTimeout bound the foreign API request & response,
+	// the websocket streams clear them. The owner backup & restore aren't
+	// bounded
+	readTimeout  = 30 * time.Second
+	writeTimeout = 2 * time.Minute
+)
+
+var (
+	errNotFound      = errors.New("not found")
+	errInvalidRange  = errors.New("invalid height range")
+	errMethodAllowed = errors.New("method not allowed")
+)
+
+// Chain is the blockchain used by the API
+type Chain interface {
+	Genesis() consensus.Block
+	Head() consensus.Block
+	Height() uint64
+	TotalDifficulty() consensus.Difficulty
+	HeaderHead() consensus.BlockHeader
+	GetBlockID(id consensus.BlockID) *consensus.Block
+	GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID
+	GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID
+	BlockStats(now time.Time) chain.BlockStats
+	Reorgs() []chain.ReorgEvent
+	CompetingTips() []chain.CompetingTip
+	SubscribeReorgs(ch chan<- *chain.ReorgEvent)
+	UnsubscribeReorgs(ch chan<- *chain.ReorgEvent)
+}
+
+// Pool is the transaction pool used by the API
+type Pool interface {
+	Size() int
+	ProcessTx(ctx context.Context, transaction *consensus.Transaction) error
+}
+
+// Peers is the peers manager used by the API
+type Peers interface {
+	Connected() []p2p.PeerStats
+	All() []p2p.PeerStats
+	Ban(addr string)
+	Unban(addr string)
+	PropagateTx(tx *consensus.Transaction, fluff bool)
+	ConnLog(query p2p.ConnLogQuery) []p2p.ConnEvent
+	Bans() []string
+}
+
+// Sync is the sync progress & the node identity used by the API
+type Sync interface {
+	Status() p2p.SyncStatus
+	NodeID() p2p.NodeID
+}
+
+// Server is the node HTTP API
+type Server struct {
+	chain Chain
+	pool  Pool
+	peers Peers
+	sync  Sync
+
+	// network name & the start time of the node
+	network string
+	started time.Time
+
+	// JSON-RPC methods by name
+	methods      map[string]Method
+	ownerMethods map[string]Method
+
+	// readiness checks by name
+	checks map[string]ReadinessCheck
+
+	mux      *http.ServeMux
+	ownerMux *http.ServeMux
+
+	// the muxes requiring the basic auth of the secrets, empty secret
+	// disables the auth
+	foreign       http.Handler
+	owner         http.Handler
+	foreignSecret string
+	ownerSecret   string
+
+	// TLS settings of the listeners, nil means plain HTTP
+	tls *tls.Config
+
+	// wallet is the proxy of the wallet foreign API, nil if it's disabled
+	wallet http.Handler
+
+	// resources is the resource monitor of the status, nil if it's disabled
+	resources ResourceMonitor
+
+	// the reverse proxy settings: the browser origins allowed by CORS, the
+	// proxies trusted to forward the client addr & the path prefix of the
+	// listeners
+	corsOrigins    map[string]bool
+	trustedProxies []*net.IPNet
+	basePath       string
+
+	// limiter is the rate limit of the foreign API clients, nil if it's
+	// disabled
+	limiter *rateLimiter
+
+	// the explorer stats counted so far
+	stats statsCache
+}
+
+// New returns the API server
+func New(chain Chain, pool Pool, peers Peers) *Server {
+	s := &Server{
+		chain:    chain,
+		pool:     pool,
+		peers:    peers,
+		started:  time.Now(),
+		mux:      http.NewServeMux(),
+		ownerMux: http.NewServeMux(),
+		checks:   make(map[string]ReadinessCheck),
+	}
+
+	s.foreign = requireAuth(s.mux, &s.foreignSecret)
+	s.owner = requireAuth(s.ownerMux, &s.ownerSecret)
+
+	s.registerMethods()
+	s.mux.HandleFunc("/v2/foreign", rpcHandler(s.methods))
+	s.ownerMux.HandleFunc("/v2/owner", rpcHandler(s.ownerMethods))
+	s.ownerMux.HandleFunc("/v1/peers", s.get(s.allPeers))
+	s.ownerMux.HandleFunc("/v1/peers/", s.peerAction)
+	s.ownerMux.HandleFunc("/v1/peers/log", s.get(s.peersLog))
+	s.ownerMux.HandleFunc("/v1/peers/bans", s.bans)
+
+	s.mux.HandleFunc("/v1/status", s.get(s.status))
+	s.mux.HandleFunc("/v1/blocks/", s.get(s.block))
+	s.mux.HandleFunc("/v1/headers/", s.get(s.header))
+	s.mux.HandleFunc("/v1/chain", s.get(s.tip))
+	s.mux.HandleFunc("/v1/chain/hashrate", s.get(s.hashrate))
+	s.mux.HandleFunc("/v1/chain/stats", s.get(s.blockStats))
+	s.mux.HandleFunc("/v1/chain/reorgs", s.get(s.reorgs))
+	s.mux.HandleFunc("/v1/chain/tips", s.get(s.tips))
+	s.mux.Handle("/v1/chain/reorgs/ws", s.reorgsWS())
+	s.mux.HandleFunc("/v1/chain/outputs/byids", s.get(s.outputsByIDs))
+	s.mux.HandleFunc("/v1/chain/outputs/byheight", s.get(s.outputsByHeight))
+	s.mux.HandleFunc("/v1/chain/kernels/", s.get(s.kernel))
+	s.mux.HandleFunc("/v1/chain/merkle_proof/verify", s.verifyMerkleProof)
+	s.mux.HandleFunc("/v1/txhashset/roots", s.get(s.roots))
+	s.mux.HandleFunc("/v1/txhashset/lastoutputs", s.get(s.lastOutputs))
+	s.mux.HandleFunc("/v1/txhashset/lastkernels", s.get(s.lastKernels))
+	s.mux.HandleFunc("/v1/pool/size", s.get(s.poolSize))
+	s.mux.HandleFunc("/v1/pool/push_tx", s.pushTx)
+	s.mux.HandleFunc("/v1/peers/connected", s.get(s.connectedPeers))
+	s.mux.HandleFunc("/v1/explorer/blocks", s.get(s.recentBlocks))
+	s.mux.HandleFunc("/v1/explorer/blocks/", s.get(s.explorerBlock))
+	s.mux.HandleFunc("/v1/explorer/search", s.get(s.search))
+	s.mux.HandleFunc("/v1/explorer/stats", s.get(s.chainStats))
+
+	s.mux.HandleFunc("/healthz", s.healthz)
+	s.mux.HandleFunc("/readyz", s.readyz)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler interface, the health probes & the
+// wallet requests of the wallet proxy are served without the API secret
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz", "/readyz":
+		s.mux.ServeHTTP(w, r)
+	case "/v2/foreign":
+		if s.wallet != nil && walletRequest(w, r) {
+			s.wallet.ServeHTTP(w, r)
+			return
+		}
+		s.foreign.ServeHTTP(w, r)
+	default:
+		s.foreign.ServeHTTP(w, r)
+	}
+}
+
+// SetNode sets the network name & the sync progress of the node status
+func (s *Server) SetNode(network string, sync Sync) {
+	s.network = network
+	s.sync = sync
+}
+
+// SetTLS enables TLS of the API listeners
+func (s *Server) SetTLS(config *tls.Config) {
+	s.tls = config
+}
+
+// ListenAndServe serves the API on addr
+func (s *Server) ListenAndServe(addr string) error {
+	logrus.Infof("api listening on %s", addr)
+	return s.serve(&http.Server{
+		Addr:         addr,
+		Handler:      s.frontend(s.limit(s.cors(s))),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	})
+}
+
+// Owner returns the handler of the owner API
+func (s *Server) Owner() http.Handler {
+	return s.owner
+}
+
+// ListenAndServeOwner serves the owner API on addr
+func (s *Server) ListenAndServeOwner(addr string) error {
+	logrus.Infof("owner api listening on %s", addr)
+	return s.serve(&http.Server{Addr: addr, Handler: s.frontend(s.owner)})
+}
+
+// serve runs the server over TLS if it is enabled, the slow & the idle
+// connections are closed
+func (s *Server) serve(server *http.Server) error {
+	server.TLSConfig = s.tls
+	server.ReadHeaderTimeout = readHeaderTimeout
+	server.IdleTimeout = idleTimeout
+
+	if s.tls != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+
+	return server.ListenAndServe()
+}
+
+// get wraps the handler returning the result to be encoded to JSON
+func (s *Server) get(handler func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+			return
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			status := http.StatusBadRequest
+			if err == errNotFound {
+				status = http.StatusNotFound
+			}
+
+			writeError(w, status, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// status returns the node status
+func (s *Server) status(r *http.Request) (interface{}, error) {
+	header := s.chain.HeaderHead()
+
+	status := Status{
+		Version:         p2p.Version,
+		Commit:          p2p.Commit,
+		BuildDate:       p2p.BuildDate,
+		ProtocolVersion: consensus.ProtocolVersion,
+		UserAgent:       p2p.UserAgent,
+		Network:         s.network,
+		Connections:     len(s.peers.Connected()),
+		Tip:             s.chainTip(),
+		HeaderTip: Tip{
+			Height:          header.Height,
+			LastBlockPushed: header.Hash().String(),
+			PrevBlockToLast: header.Previous.String(),
+			TotalDifficulty: uint64(header.TotalDifficulty),
+		},
+		Uptime: int64(time.Since(s.started) / time.Second),
+	}
+
+	if s.resources != nil {
+		status.Resources = newResources(s.resources.Usage())
+	}
+
+	if s.sync != nil {
+		sync := s.sync.Status()
+		if id := s.sync.NodeID(); !id.IsZero() {
+			status.NodeID = id.String()
+		}
+
+		status.SyncStatus = sync.Stage
+		status.SyncPercent = sync.Percent
+		status.ForkAlarm = sync.ForkAlarm
+		status.SyncProgress = &SyncProgress{
+			Headers:          sync.Headers,
+			Blocks:           sync.Blocks,
+			Target:           sync.Target,
+			HeadersPerSecond: sync.HeadersPerSecond,
+			BlocksPerSecond:  sync.BlocksPerSecond,
+			ETA:              int64(sync.ETA / time.Second),
+		}
+	}
+
+	return status, nil
+}
+
+// tip returns the chain head
+func (s *Server) tip(r *http.Request) (interface{}, error) {
+	return s.chainTip(), nil
+}
+
+func (s *Server) chainTip() Tip {
+	head := s.chain.Head()
+
+	return Tip{
+		Height:          s.chain.Height(),
+		LastBlockPushed: head.Hash().String(),
+		PrevBlockToLast: head.Header.Previous.String(),
+		TotalDifficulty: uint64(s.chain.TotalDifficulty()),
+	}
+}
+
+// block returns block by hash or height: /v1/blocks/{hash|height}
+func (s *Server) block(r *http.Request) (interface{}, error) {
+	block, err := s.findBlock(strings.TrimPrefix(r.URL.Path, "/v1/blocks/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBlockPrintable(block, s.isSpent), nil
+}
+
+// header returns block header by hash or height: /v1/headers/{hash|height}
+func (s *Server) header(r *http.Request) (interface{}, error) {
+	block, err := s.findBlock(strings.TrimPrefix(r.URL.Path, "/v1/headers/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHeaderPrintable(&block.Header), nil
+}
+
+// findBlock returns block by the hex hash or height
+func (s *Server) findBlock(param string) (*consensus.Block, error) {
+	var id consensus.BlockID
+
+	if len(param) == 2*consensus.BlockHashSize {
+		hash, err := consensus.ParseHash(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash: %v", err)
+		}
+		id.Hash = hash
+	} else {
+		height, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block height: %s", param)
+		}
+		id.Height = &height
+	}
+
+	return s.blockByID(id)
+}
+
+// blockByID returns block by hash or height
+func (s *Server) blockByID(id consensus.BlockID) (*consensus.Block, error) {
+	// genesis is not stored in the storage
+	genesis := s.chain.Genesis()
+	if (id.Height != nil && *id.Height == genesis.Header.Height) ||
+		(!id.Hash.IsZero() && id.Hash == genesis.Hash()) {
+		return &genesis, nil
+	}
+
+	block := s.chain.GetBlockID(id)
+	if block == nil {
+		return nil, errNotFound
+	}
+
+	return block, nil
+}
+
+// outputsByIDs returns unspent outputs by commitments:
+// /v1/chain/outputs/byids?id=xxx&id=yyy or ?id=xxx,yyy
+func (s *Server) outputsByIDs(r *http.Request) (interface{}, error) {
+	var ids []string
+	for _, param := range r.URL.Query()["id"] {
+		ids = append(ids, strings.Split(param, ",")...)
+	}
+
+	if len(ids) > MaxOutputIDs {
+		return nil, errTooManyIDs
+	}
+
+	return s.unspentOutputs(ids)
+}
+
+// unspentOutputs returns unspent outputs by hex commitments
+func (s *Server) unspentOutputs(ids []string) ([]Output, error) {
+	result := make([]Output, 0)
+
+	for _, id := range ids {
+		blockID, err := s.outputBlock(id)
+		if err == errNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		output := Output{
+			Commit: id,
+		}
+
+		if blockID.Height != nil {
+			output.Height = *blockID.Height
+		}
+
+		result = append(result, output)
+	}
+
+	return result, nil
+}
+
+// outputBlock returns id of the block with the unspent output by hex
+// commitment
+func (s *Server) outputBlock(id string) (*consensus.BlockID, error) {
+	commit, err := hex.DecodeString(id)
+	if err != nil || len(commit) != secp256k1zkp.PedersenCommitmentSize {
+		return nil, fmt.Errorf("invalid commitment: %s", id)
+	}
+
+	blockID := s.chain.GetUnspentOutput(commit)
+	if blockID == nil {
+		return nil, errNotFound
+	}
+
+	return blockID, nil
+}
+
+// outputsByHeight returns outputs of the blocks in the height range:
+// /v1/chain/outputs/byheight?start_height=x&end_height=y, or the page of the
+// outputs with ?start=x
+func (s *Server) outputsByHeight(r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+	if _, ok := query["start"]; ok {
+		return s.outputListing(r)
+	}
+
+	start, err := strconv.ParseUint(query.Get("start_height"), 10, 64)
+	if err != nil {
+		return nil, errInvalidRange
+	}
+
+	end, err := strconv.ParseUint(query.Get("end_height"), 10, 64)
+	if err != nil {
+		return nil, errInvalidRange
+	}
+
+	return s.blockOutputs(start, end)
+}
+
+// blockOutputs returns outputs of the blocks from start to end height
+func (s *Server) blockOutputs(start, end uint64) ([]BlockOutputs, error) {
+	if end < start || end-start >= MaxHeightRange {
+		return nil, errInvalidRange
+	}
+
+	if height := s.chain.Height(); end > height {
+		end = height
+	}
+
+	result := make([]BlockOutputs, 0)
+	for height := start; height <= end; height++ {
+		block, err := s.findBlock(strconv.FormatUint(height, 10))
+		if err != nil {
+			continue
+		}
+
+		outputs := BlockOutputs{
+			Header: BlockHeaderInfo{
+				Hash:     block.Hash().String(),
+				Height:   block.Header.Height,
+				Previous: block.Header.Previous.String(),
+			},
+			Outputs: make([]OutputPrintable, 0, len(block.Outputs)),
+		}
+
+		leaves := firstLeaf(block.Header.OutputMmrSize, len(block.Outputs))
+		for i := range block.Outputs {
+			output := newOutputPrintable(&block.Outputs[i], height, s.isSpent(&block.Outputs[i]))
+			output.MmrIndex = mmrLeafIndex(leaves + uint64(i))
+			outputs.Outputs = append(outputs.Outputs, output)
+		}
+
+		result = append(result, outputs)
+	}
+
+	return result, nil
+}
+
+// isSpent returns true if the output is missing in the utxo set
+func (s *Server) isSpent(o *consensus.Output) bool {
+	return s.chain.GetUnspentOutput(o.Commit.Bytes()) == nil
+}
+
+// poolSize returns count of the transactions in the pool
+func (s *Server) poolSize(r *http.Request) (interface{}, error) {
+	return PoolInfo{PoolSize: s.pool.Size()}, nil
+}
+
+// pushTx adds the transaction to the pool: POST /v1/pool/push_tx[?fluff].
+// The body is the binary transaction of the application/octet-stream
+// content type, or {"tx_hex": "...", "fluff": true}
+func (s *Server) pushTx(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errMethodAllowed)
+		return
+	}
+
+	var req PushTx
+	if value, ok := r.URL.Query()["fluff"]; ok {
+		// ?fluff without the value as in grin
+		req.Fluff = value[0] == "" || value[0] == "true" || value[0] == "1"
+	}
+
+	body := http.MaxBytesReader(w, r.Body, int64(consensus.MaxMsgLen))
+
+	var (
+		tx  consensus.Transaction
+		err error
+	)
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		err = tx.Read(body)
+	} else if err = json.NewDecoder(body).Decode(&req); err == nil {
+		err = decodeTxHex(req.TxHex, &tx)
+	}
+
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, TxError{Error: err.Error(), Reason: ReasonMalformed})
+		return
+	}
+
+	if err := s.addTx(r.Context(), &tx, req.Fluff); err != nil {
+		status, reason := txErrorReason(err)
+		writeJSON(w, status, TxError{Error: err.Error(), Reason: reason})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct{}{})
+}
+
+// pushTxHex decodes the hex transaction & adds it to the pool
+func (s *Server) pushTxHex(ctx context.Context, txHex string, fluff bool) error {
+	var tx consensus.Transaction
+	if err := decodeTxHex(txHex, &tx); err != nil {
+		return err
+	}
+
+	return s.addTx(ctx, &tx, fluff)
+}
+
+// decodeTxHex decodes the hex serialized transaction
+func decodeTxHex(txHex string, tx *consensus.Transaction) error {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return err
+	}
+
+	return tx.Read(bytes.NewReader(raw))
+}
+
+// addTx adds the transaction to the pool & relays it to the peers, fluff
+// skips the stem phase. The validation is cancelled with ctx
+func (s *Server) addTx(ctx context.Context, tx *consensus.Transaction, fluff bool) error {
+	if err := s.pool.ProcessTx(ctx, tx); err != nil {
+		return err
+	}
+
+	s.peers.PropagateTx(tx, fluff)
+
+	logrus.Infof("api: pushed tx %s, fluff: %t", tx.Hash(), fluff)
+	return nil
+}
+
+// connectedPeers returns the page of the connected peers:
+// /v1/peers/connected?offset=x&limit=y
+func (s *Server) connectedPeers(r *http.Request) (interface{}, error) {
+	offset, limit, err := pageParams(r, MaxPeersPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return peersPage(s.peerList(), offset, limit), nil
+}
+
+// peerList returns printable connected peers
+func (s *Server) peerList() []PeerInfo {
+	result := make([]PeerInfo, 0)
+	for _, stats := range s.peers.Connected() {
+		result = append(result, newPeerInfo(stats))
+	}
+
+	return result
+}
+
+// writeJSON writes v as JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// writeError writes error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,732 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/monitor"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"golang.org/x/crypto/blake2b"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testChain struct {
+	genesis consensus.Block
+	stats   chain.BlockStats
+	tips    []chain.CompetingTip
+}
+
+func (c *testChain) Genesis() consensus.Block                      { return c.genesis }
+func (c *testChain) Head() consensus.Block                         { return c.genesis }
+func (c *testChain) Height() uint64                                { return c.genesis.Header.Height }
+func (c *testChain) TotalDifficulty() consensus.Difficulty         { return c.genesis.Header.TotalDifficulty }
+func (c *testChain) HeaderHead() consensus.BlockHeader             { return c.genesis.Header }
+func (c *testChain) GetBlockID(consensus.BlockID) *consensus.Block { return nil }
+func (c *testChain) GetUnspentOutput(secp256k1zkp.Commitment) *consensus.BlockID {
+	return nil
+}
+func (c *testChain) GetKernel(secp256k1zkp.Commitment, uint64, uint64) *consensus.BlockID {
+	return nil
+}
+func (c *testChain) BlockStats(time.Time) chain.BlockStats      { return c.stats }
+func (c *testChain) Reorgs() []chain.ReorgEvent                 { return nil }
+func (c *testChain) CompetingTips() []chain.CompetingTip        { return c.tips }
+func (c *testChain) SubscribeReorgs(chan<- *chain.ReorgEvent)   {}
+func (c *testChain) UnsubscribeReorgs(chan<- *chain.ReorgEvent) {}
+
+type testPool struct {
+	txs []*consensus.Transaction
+}
+
+func (p *testPool) Size() int { return len(p.txs) }
+func (p *testPool) ProcessTx(ctx context.Context, tx *consensus.Transaction) error {
+	p.txs = append(p.txs, tx)
+	return nil
+}
+
+type testPeers []p2p.PeerStats
+
+func (p testPeers) Connected() []p2p.PeerStats                        { return p }
+func (p testPeers) All() []p2p.PeerStats                              { return p }
+func (p testPeers) Ban(addr string)                                   {}
+func (p testPeers) Unban(addr string)                                 {}
+func (p testPeers) PropagateTx(tx *consensus.Transaction, fluff bool) {}
+func (p testPeers) ConnLog(p2p.ConnLogQuery) []p2p.ConnEvent          { return nil }
+func (p testPeers) Bans() []string                                    { return nil }
+
+type testSync p2p.SyncStatus
+
+func (s testSync) Status() p2p.SyncStatus { return p2p.SyncStatus(s) }
+func (s testSync) NodeID() p2p.NodeID     { return p2p.NodeID{0x02, 0x01} }
+
+func newTestServer() *Server {
+	peers := testPeers{{Addr: "127.0.0.1:13414", Inbound: true}}
+	return New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+}
+
+func request(h http.Handler, method, url, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+	return w
+}
+
+func TestStatus(t *testing.T) {
+	s := newTestServer()
+	s.SetNode("testnet1", testSync{Stage: p2p.SyncBlocks, Percent: 42, Blocks: 42, Target: 100, BlocksPerSecond: 2, ETA: 29 * time.Second})
+
+	w := request(s, http.MethodGet, "/v1/status", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var status Status
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Connections != 1 {
+		t.Errorf("connections was %d, want 1", status.Connections)
+	}
+
+	if status.Tip.LastBlockPushed != chain.Testnet1.Hash().String() {
+		t.Errorf("tip was %s, want genesis", status.Tip.LastBlockPushed)
+	}
+
+	if status.HeaderTip.LastBlockPushed != chain.Testnet1.Hash().String() {
+		t.Errorf("header tip was %s, want genesis", status.HeaderTip.LastBlockPushed)
+	}
+
+	if status.Version != p2p.Version || status.Network != "testnet1" {
+		t.Errorf("version & network were %s %s, want %s testnet1", status.Version, status.Network, p2p.Version)
+	}
+
+	if status.NodeID != (p2p.NodeID{0x02, 0x01}).String() {
+		t.Errorf("node id was %s", status.NodeID)
+	}
+
+	if status.SyncStatus != p2p.SyncBlocks || status.SyncPercent != 42 {
+		t.Errorf("sync was %s %d%%, want %s 42%%", status.SyncStatus, status.SyncPercent, p2p.SyncBlocks)
+	}
+
+	want := SyncProgress{Blocks: 42, Target: 100, BlocksPerSecond: 2, ETA: 29}
+	if status.SyncProgress == nil || *status.SyncProgress != want {
+		t.Errorf("sync progress was %+v, want %+v", status.SyncProgress, want)
+	}
+
+	if status.Resources != nil {
+		t.Errorf("resources were %+v without the monitor", status.Resources)
+	}
+
+	resources := monitor.New(monitor.Interval, func() (uint64, uint64) { return 10, 20 }, func() uint64 { return 1 << 20 })
+	resources.Sample(time.Now())
+	s.SetResources(resources)
+
+	status = Status{}
+	if err := json.NewDecoder(request(s, http.MethodGet, "/v1/status", "").Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Resources == nil || status.Resources.StorageSize != 1<<20 || status.Resources.Goroutines == 0 {
+		t.Errorf("resources were %+v", status.Resources)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	s := newTestServer()
+	genesis := chain.Testnet1.Hash().String()
+
+	for _, url := range []string{"/v1/blocks/0", "/v1/blocks/" + genesis, "/v1/headers/0"} {
+		w := request(s, http.MethodGet, url, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status code was %d, want %d", url, w.Code, http.StatusOK)
+			continue
+		}
+
+		if !strings.Contains(w.Body.String(), genesis) {
+			t.Errorf("%s: genesis hash is not in the response", url)
+		}
+	}
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"/v1/blocks/10", http.StatusNotFound},
+		{"/v1/blocks/abc", http.StatusBadRequest},
+		{"/v1/chain/outputs/byheight?start_height=10&end_height=1", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		if w := request(s, http.MethodGet, test.url, ""); w.Code != test.code {
+			t.Errorf("%s: status code was %d, want %d", test.url, w.Code, test.code)
+		}
+	}
+}
+
+func TestOutputPrintable(t *testing.T) {
+	output := consensus.Output{
+		Features: consensus.CoinbaseOutput,
+		Commit:   secp256k1zkp.CommitValue(big.NewInt(3), big.NewInt(60)),
+	}
+
+	data, err := json.Marshal(newOutputPrintable(&output, 5, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fields of the grin api OutputPrintable
+	for _, name := range []string{"output_type", "commit", "spent", "proof_hash", "block_height", "merkle_proof", "mmr_index"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("field %s is missing in %s", name, data)
+		}
+	}
+
+	if fields["output_type"] != "Coinbase" || fields["spent"] != true || fields["merkle_proof"] != nil {
+		t.Errorf("unexpected output %s", data)
+	}
+
+	// the output without the range proof has no proof & the hash of nothing
+	emptyHash := blake2b.Sum256(nil)
+	if fields["proof"] != nil || fields["proof_hash"] != hex.EncodeToString(emptyHash[:]) {
+		t.Errorf("unexpected proof of the output %s", data)
+	}
+}
+
+func TestPushTx(t *testing.T) {
+	s := newTestServer()
+
+	if w := request(s, http.MethodGet, "/v1/pool/push_tx", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	w := request(s, http.MethodPost, "/v1/pool/push_tx", `{"tx_hex": "zz"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status code was %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	var txErr TxError
+	if err := json.NewDecoder(w.Body).Decode(&txErr); err != nil || txErr.Reason != ReasonMalformed {
+		t.Errorf("rejection reason was %q, want %s", txErr.Reason, ReasonMalformed)
+	}
+
+	w = request(s, http.MethodGet, "/v1/pool/size", "")
+	if strings.TrimSpace(w.Body.String()) != `{"pool_size":0}` {
+		t.Errorf("pool size was %s", w.Body.String())
+	}
+}
+
+// txPeers records the propagated transactions
+type txPeers struct {
+	testPeers
+	fluff []bool
+}
+
+func (p *txPeers) PropagateTx(tx *consensus.Transaction, fluff bool) {
+	p.fluff = append(p.fluff, fluff)
+}
+
+// rejectPool rejects the transactions with err
+type rejectPool struct {
+	testPool
+	err error
+}
+
+func (p *rejectPool) ProcessTx(ctx context.Context, tx *consensus.Transaction) error {
+	return p.err
+}
+
+func TestPushTxFluff(t *testing.T) {
+	peers := new(txPeers)
+	s := New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+
+	excess := secp256k1zkp.CommitValue(big.NewInt(1), big.NewInt(0))
+	tx := consensus.Transaction{Kernels: consensus.TxKernelList{{Fee: 1, Excess: *excess}}}
+	txHex := hex.EncodeToString(tx.Bytes())
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/pool/push_tx?fluff", bytes.NewReader(tx.Bytes()))
+	r.Header.Set("Content-Type", "application/octet-stream")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("binary push status code was %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := request(s, http.MethodPost, "/v1/pool/push_tx", `{"tx_hex": "`+txHex+`"}`); w.Code != http.StatusOK {
+		t.Fatalf("hex push status code was %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(peers.fluff) != 2 || !peers.fluff[0] || peers.fluff[1] {
+		t.Errorf("fluff flags were %v, want [true false]", peers.fluff)
+	}
+
+	// the pool rejection reasons
+	for _, test := range []struct {
+		err    error
+		code   int
+		reason string
+	}{
+		{mempool.ErrDuplicateTx, http.StatusConflict, ReasonDuplicate},
+		{mempool.ErrPoolFull, http.StatusServiceUnavailable, ReasonPoolFull},
+		{errors.New("invalid kernel signature"), http.StatusBadRequest, ReasonInvalid},
+	} {
+		s := New(&testChain{genesis: chain.Testnet1}, &rejectPool{err: test.err}, peers)
+
+		w := request(s, http.MethodPost, "/v1/pool/push_tx", `{"tx_hex": "`+txHex+`"}`)
+		if w.Code != test.code {
+			t.Errorf("%v: status code was %d, want %d", test.err, w.Code, test.code)
+		}
+
+		var txErr TxError
+		if err := json.NewDecoder(w.Body).Decode(&txErr); err != nil || txErr.Reason != test.reason {
+			t.Errorf("%v: reason was %q, want %s", test.err, txErr.Reason, test.reason)
+		}
+	}
+}
+
+func TestRPC(t *testing.T) {
+	s := newTestServer()
+	genesis := chain.Testnet1.Hash().String()
+
+	w := request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_header", "params": [0, null]}`)
+	if !strings.Contains(w.Body.String(), genesis) {
+		t.Errorf("genesis hash is not in the response: %s", w.Body.String())
+	}
+
+	batch := `[
+		{"jsonrpc": "2.0", "id": 1, "method": "get_pool_size"},
+		{"jsonrpc": "2.0", "id": 2, "method": "unknown"},
+		{"jsonrpc": "2.0", "method": "get_tip"},
+		{"jsonrpc": "2.0", "id": 3, "method": "get_block", "params": ["x", 1]}
+	]`
+
+	var responses []rpcResponse
+	w = request(s, http.MethodPost, "/v2/foreign", batch)
+	if err := json.NewDecoder(w.Body).Decode(&responses); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(responses) != 3 {
+		t.Fatalf("batch responses count was %d, want 3", len(responses))
+	}
+
+	if result, ok := responses[0].Result.(map[string]interface{}); !ok || result["Ok"] != 0.0 {
+		t.Errorf("get_pool_size response was %+v", responses[0])
+	}
+
+	if responses[1].Error == nil || responses[1].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method response was %+v", responses[1])
+	}
+
+	if responses[2].Error == nil || responses[2].Error.Code != codeInvalidParams {
+		t.Errorf("invalid params response was %+v", responses[2])
+	}
+}
+
+// TestRPCWallet checks the requests of grin-wallet
+func TestRPCWallet(t *testing.T) {
+	pool := &testPool{}
+	s := New(&testChain{genesis: chain.Testnet1}, pool, testPeers{})
+
+	tests := []struct {
+		request  string
+		response string
+	}{
+		{
+			`{"jsonrpc":"2.0","method":"get_version","params":[],"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Ok":{"node_version":"` + p2p.Version + `","block_header_version":1}}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"get_outputs","params":[["0832a3990cd8a497ad280394afeaa5fcbdf02d6e8c86eb7fc47ba6bb25cd8973fd"],null,null,false,false],"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Ok":[]}}`,
+		},
+		{
+			`{"jsonrpc":"2.0","method":"get_header","params":[null,null,"0832a3990cd8a497ad280394afeaa5fcbdf02d6e8c86eb7fc47ba6bb25cd8973fd"],"id":1}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"Err":"NotFound"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		w := request(s, http.MethodPost, "/v2/foreign", test.request)
+		if strings.TrimSpace(w.Body.String()) != test.response {
+			t.Errorf("response was %s, want %s", w.Body.String(), test.response)
+		}
+	}
+
+	payload, err := ioutil.ReadFile("testdata/push_transaction.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := request(s, http.MethodPost, "/v2/foreign", string(payload))
+	if response := strings.TrimSpace(w.Body.String()); response != `{"jsonrpc":"2.0","id":1,"result":{"Ok":null}}` {
+		t.Fatalf("push_transaction response was %s", response)
+	}
+
+	if len(pool.txs) != 1 {
+		t.Fatalf("pool size was %d, want 1", len(pool.txs))
+	}
+
+	// the kernel sums & signature hold only if the json is decoded right
+	if err := pool.txs[0].Validate(context.Background()); err != nil {
+		t.Errorf("pushed transaction is not valid: %v", err)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := newTestServer()
+
+	if w := request(s, http.MethodGet, "/healthz", ""); w.Code != http.StatusOK {
+		t.Errorf("healthz status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if w := request(s, http.MethodGet, "/readyz", ""); w.Code != http.StatusOK {
+		t.Errorf("readyz status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	s.AddReadinessCheck("peers", func() error { return errors.New("no peers") })
+
+	w := request(s, http.MethodGet, "/readyz", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz status code was %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	if !strings.Contains(w.Body.String(), "no peers") {
+		t.Errorf("failed check is not in the response: %s", w.Body.String())
+	}
+}
+
+func TestOwnerSetLogLevel(t *testing.T) {
+	s := newTestServer()
+	logger := logging.Module(logging.P2P)
+	defer logger.SetLevel(logger.GetLevel())
+
+	w := request(s, http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "get_log_levels", "params": []}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("owner api is served on the foreign listener: %d", w.Code)
+	}
+
+	w = request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "set_log_level", "params": ["p2p", "debug"]}`)
+	if strings.Contains(w.Body.String(), "error") {
+		t.Fatalf("set_log_level failed: %s", w.Body.String())
+	}
+
+	if level := logging.Levels()[logging.P2P]; level != "debug" {
+		t.Errorf("p2p log level was %s, want debug", level)
+	}
+
+	w = request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "set_log_level", "params": ["unknown", "debug"]}`)
+	if !strings.Contains(w.Body.String(), "unknown log module") {
+		t.Errorf("unknown module was accepted: %s", w.Body.String())
+	}
+}
+
+// banPeers records the banned peers
+type banPeers struct {
+	testPeers
+	banned map[string]bool
+}
+
+func (p *banPeers) All() []p2p.PeerStats {
+	result := append([]p2p.PeerStats(nil), p.testPeers...)
+	for addr := range p.banned {
+		result = append(result, p2p.PeerStats{Addr: addr, Status: "banned"})
+	}
+	return result
+}
+func (p *banPeers) Ban(addr string)   { p.banned[addr] = true }
+func (p *banPeers) Unban(addr string) { delete(p.banned, addr) }
+func (p *banPeers) Bans() []string {
+	result := make([]string, 0, len(p.banned))
+	for addr := range p.banned {
+		result = append(result, addr)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// logPeers returns the connection events & records the query
+type logPeers struct {
+	testPeers
+	events []p2p.ConnEvent
+	query  p2p.ConnLogQuery
+}
+
+func (p *logPeers) ConnLog(query p2p.ConnLogQuery) []p2p.ConnEvent {
+	p.query = query
+	return p.events
+}
+
+func TestOwnerConnLog(t *testing.T) {
+	now := time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC)
+	peers := &logPeers{events: []p2p.ConnEvent{
+		{Time: now, Event: p2p.ConnDisconnect, Addr: "10.0.0.1:13414", Inbound: true, UserAgent: "grin", Duration: 90 * time.Second, BytesSent: 10, BytesReceived: 20, Reason: "banned"},
+		{Time: now, Event: p2p.ConnBan, Addr: "10.0.0.2:13414"},
+	}}
+	s := New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+
+	if w := request(s, http.MethodGet, "/v1/peers/log", ""); w.Code != http.StatusNotFound {
+		t.Errorf("connection log is served on the foreign listener: %d", w.Code)
+	}
+
+	w := request(s.Owner(), http.MethodGet, "/v1/peers/log?addr=10.0.0.0/24&event=disconnect&since=2018-10-17T19:00:00Z&limit=5", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d: %s", w.Code, w.Body.String())
+	}
+
+	since := now.Add(-time.Hour)
+	if q := peers.query; q.Addr != "10.0.0.0/24" || q.Event != p2p.ConnDisconnect || !q.Since.Equal(since) || q.Limit != 5 {
+		t.Errorf("query was %+v", q)
+	}
+
+	var result []ConnEvent
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ConnEvent{
+		{Time: "2018-10-17T20:00:00Z", Event: "disconnect", Addr: "10.0.0.1:13414", Direction: "Inbound", UserAgent: "grin", Duration: 90, BytesSent: 10, BytesReceived: 20, Reason: "banned"},
+		{Time: "2018-10-17T20:00:00Z", Event: "ban", Addr: "10.0.0.2:13414"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("connection log was %+v", result)
+	}
+
+	for _, query := range []string{"event=kick", "since=yesterday", "limit=-1"} {
+		if w := request(s.Owner(), http.MethodGet, "/v1/peers/log?"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code was %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	w = request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "get_connection_log", "params": ["10.0.0.1", "ban"]}`)
+	if strings.Contains(w.Body.String(), "error") || peers.query.Addr != "10.0.0.1" || peers.query.Event != p2p.ConnBan {
+		t.Errorf("get_connection_log failed: %s", w.Body.String())
+	}
+}
+
+func TestOwnerPeers(t *testing.T) {
+	peers := &banPeers{
+		testPeers: testPeers{{Addr: "127.0.0.1:13414", Status: "connected"}},
+		banned:    make(map[string]bool),
+	}
+	s := New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+
+	if w := request(s, http.MethodPost, "/v1/peers/10.0.0.1:13414/ban", ""); w.Code != http.StatusNotFound {
+		t.Errorf("peers are managed on the foreign listener: %d", w.Code)
+	}
+
+	if w := request(s.Owner(), http.MethodPost, "/v1/peers/10.0.0.1:13414/ban", ""); w.Code != http.StatusOK {
+		t.Fatalf("ban status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	for _, test := range []struct {
+		state string
+		count int
+	}{
+		{"all", 2},
+		{"connected", 1},
+		{"banned", 1},
+	} {
+		w := request(s.Owner(), http.MethodGet, "/v1/peers?state="+test.state, "")
+
+		var result []PeerInfo
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result) != test.count {
+			t.Errorf("%s peers count was %d, want %d", test.state, len(result), test.count)
+		}
+	}
+
+	w := request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "unban_peer", "params": ["10.0.0.1:13414"]}`)
+	if strings.Contains(w.Body.String(), "error") || peers.banned["10.0.0.1:13414"] {
+		t.Errorf("unban_peer failed: %s", w.Body.String())
+	}
+
+	if w := request(s.Owner(), http.MethodPost, "/v1/peers/10.0.0.0/24/ban", ""); w.Code != http.StatusOK || !peers.banned["10.0.0.0/24"] {
+		t.Errorf("range ban status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	for _, url := range []string{"/v1/peers/invalid/ban", "/v1/peers/10.0.0.0/33/ban", "/v1/peers?state=unknown"} {
+		method := http.MethodPost
+		if strings.Contains(url, "?") {
+			method = http.MethodGet
+		}
+
+		if w := request(s.Owner(), method, url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code was %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestOwnerBans(t *testing.T) {
+	peers := &banPeers{banned: map[string]bool{"10.0.0.1:13414": true}}
+	s := New(&testChain{genesis: chain.Testnet1}, &testPool{}, peers)
+
+	w := request(s.Owner(), http.MethodPost, "/v1/peers/bans", `{"bans": ["10.0.0.1:13414", "10.0.1.0/24", "10.0.0.2:13414"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("push status code was %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var added BansAdded
+	if err := json.NewDecoder(w.Body).Decode(&added); err != nil {
+		t.Fatal(err)
+	}
+	if added.Added != 2 {
+		t.Errorf("added bans count was %d, want 2", added.Added)
+	}
+
+	w = request(s.Owner(), http.MethodGet, "/v1/peers/bans", "")
+
+	var list BanList
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Bans) != 3 || list.Bans[0] != "10.0.0.1:13414" || list.Bans[2] != "10.0.1.0/24" {
+		t.Errorf("ban list was %v", list.Bans)
+	}
+
+	// the invalid entry rejects the whole list
+	w = request(s.Owner(), http.MethodPost, "/v1/peers/bans", `{"bans": ["10.0.0.3:13414", "invalid"]}`)
+	if w.Code != http.StatusBadRequest || peers.banned["10.0.0.3:13414"] {
+		t.Errorf("invalid ban list status code was %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	if w := request(s, http.MethodGet, "/v1/peers/bans", ""); w.Code != http.StatusNotFound {
+		t.Errorf("ban list is served on the foreign listener: %d", w.Code)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "api.crt")
+	keyFile := filepath.Join(dir, "api.key")
+
+	if _, err := TLSConfig(certFile, keyFile, false, nil); err == nil {
+		t.Errorf("missing certificate was accepted")
+	}
+
+	config, err := TLSConfig(certFile, keyFile, true, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to generate certificate: %v", err)
+	}
+
+	// the existing certificate is reused
+	reloaded, err := TLSConfig(certFile, keyFile, true, []string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(config.Certificates[0].Certificate[0], reloaded.Certificates[0].Certificate[0]) {
+		t.Errorf("certificate was regenerated")
+	}
+
+	server := httptest.NewUnstartedServer(newTestServer())
+	server.TLS = config
+	server.StartTLS()
+	defer server.Close()
+
+	pem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		t.Fatal("failed to parse certificate")
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("tls request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status was %d", resp.StatusCode)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, ".api_secret")
+	secret, err := ReadSecret(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(secret) != secretSize {
+		t.Errorf("secret was %q", secret)
+	}
+
+	if reread, err := ReadSecret(file); err != nil || reread != secret {
+		t.Errorf("secret was regenerated: %q, %v", reread, err)
+	}
+
+	s := newTestServer()
+	s.SetSecrets(secret, "owner")
+
+	get := func(h http.Handler, url, password string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		if password != "" {
+			r.SetBasicAuth(BasicAuthUser, password)
+		}
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := get(s, "/v1/status", ""); code != http.StatusUnauthorized {
+		t.Errorf("request without secret: status %d", code)
+	}
+	if code := get(s, "/v1/status", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("request with wrong secret: status %d", code)
+	}
+	if code := get(s, "/v1/status", secret); code != http.StatusOK {
+		t.Errorf("request with secret: status %d", code)
+	}
+	if code := get(s, "/healthz", ""); code != http.StatusOK {
+		t.Errorf("health probe: status %d", code)
+	}
+	if code := get(s.Owner(), "/v2/owner", secret); code != http.StatusUnauthorized {
+		t.Errorf("owner request with foreign secret: status %d", code)
+	}
+}
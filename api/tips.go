@@ -0,0 +1,48 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// CompetingTip is the block of the head height & total difficulty received
+// after the head, the chain switches to it once it's extended
+type CompetingTip struct {
+	Hash            string    `json:"hash"`
+	Height          uint64    `json:"height"`
+	TotalDifficulty uint64    `json:"total_difficulty"`
+	Received        time.Time `json:"received"`
+}
+
+// ChainTips is the head won the tip race by the first seen & the competing
+// tips, the first seen first
+type ChainTips struct {
+	Head      Tip            `json:"head"`
+	Competing []CompetingTip `json:"competing"`
+}
+
+// chainTips returns the head & the competing tips
+func (s *Server) chainTips() ChainTips {
+	tips := s.chain.CompetingTips()
+
+	result := ChainTips{Head: s.chainTip(), Competing: make([]CompetingTip, 0, len(tips))}
+	for _, tip := range tips {
+		result.Competing = append(result.Competing, CompetingTip{
+			Hash:            tip.Hash.String(),
+			Height:          tip.Height,
+			TotalDifficulty: uint64(tip.TotalDifficulty),
+			Received:        tip.Received.UTC(),
+		})
+	}
+
+	return result
+}
+
+// tips returns the head & the competing tips: /v1/chain/tips
+func (s *Server) tips(r *http.Request) (interface{}, error) {
+	return s.chainTips(), nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChainTips(t *testing.T) {
+	tip := chain.CompetingTip{Hash: consensus.Hash{0x01}, Height: 0, TotalDifficulty: 10, Received: time.Now()}
+	s := New(&testChain{genesis: chain.Testnet1, tips: []chain.CompetingTip{tip}}, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/chain/tips", "")
+	var tips ChainTips
+	if err := json.Unmarshal(w.Body.Bytes(), &tips); err != nil {
+		t.Fatalf("invalid tips response %s: %v", w.Body.String(), err)
+	}
+
+	if tips.Head.LastBlockPushed != chain.Testnet1.Hash().String() {
+		t.Errorf("head was %+v, want genesis", tips.Head)
+	}
+
+	if len(tips.Competing) != 1 || tips.Competing[0].Hash != tip.Hash.String() || tips.Competing[0].TotalDifficulty != 10 {
+		t.Errorf("competing tips were %+v", tips.Competing)
+	}
+
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "get_chain_tips", "params": []}`)
+	var response struct {
+		Result struct {
+			Ok ChainTips `json:"Ok"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || len(response.Result.Ok.Competing) != 1 {
+		t.Errorf("get_chain_tips returned %s", w.Body.String())
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"net/http"
+)
+
+// The reasons of the transaction rejection
+const (
+	ReasonMalformed    = "malformed"
+	ReasonInvalid      = "invalid"
+	ReasonNoKernels    = "no_kernels"
+	ReasonDuplicate    = "duplicate"
+	ReasonPoolFull     = "pool_full"
+	ReasonUnknownInput = "unknown_input"
+	ReasonDoubleSpend  = "double_spend"
+	ReasonCancelled    = "cancelled"
+)
+
+// TxError is the push_tx response of the rejected transaction
+type TxError struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
+// txErrorReason returns the status code & the reason of the pool error, the
+// transactions failing the consensus rules are invalid
+func txErrorReason(err error) (int, string) {
+	switch err {
+	case mempool.ErrNoKernels:
+		return http.StatusBadRequest, ReasonNoKernels
+	case mempool.ErrDuplicateTx:
+		return http.StatusConflict, ReasonDuplicate
+	case mempool.ErrPoolFull:
+		return http.StatusServiceUnavailable, ReasonPoolFull
+	case mempool.ErrUnknownInput:
+		return http.StatusBadRequest, ReasonUnknownInput
+	case mempool.ErrDoubleSpend:
+		return http.StatusConflict, ReasonDoubleSpend
+	case context.Canceled, context.DeadlineExceeded:
+		return http.StatusServiceUnavailable, ReasonCancelled
+	}
+
+	return http.StatusBadRequest, ReasonInvalid
+}
+
+// TxJSON is the transaction in the grin json format, as pushed by grin-wallet
+type TxJSON struct {
+	Offset string     `json:"offset"`
+	Body   TxBodyJSON `json:"body"`
+}
+
+// TxBodyJSON is the inputs, outputs & kernels of the transaction
+type TxBodyJSON struct {
+	Inputs  []InputJSON    `json:"inputs"`
+	Outputs []OutputJSON   `json:"outputs"`
+	Kernels []TxKernelJSON `json:"kernels"`
+}
+
+// InputJSON is the transaction input
+type InputJSON struct {
+	Features string `json:"features"`
+	Commit   string `json:"commit"`
+}
+
+// UnmarshalJSON accepts the input object & the bare commitment of the later
+// grin versions
+func (i *InputJSON) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &i.Commit)
+	}
+
+	type input InputJSON
+	return json.Unmarshal(data, (*input)(i))
+}
+
+// OutputJSON is the transaction output
+type OutputJSON struct {
+	Features string `json:"features"`
+	Commit   string `json:"commit"`
+	Proof    string `json:"proof"`
+}
+
+// TxKernelJSON is the transaction kernel. The features are either the name
+// with fee & lock_height fields, or the {"Plain": {"fee": 1}} object
+type TxKernelJSON struct {
+	Features   json.RawMessage `json:"features"`
+	Fee        uint64          `json:"fee"`
+	LockHeight uint64          `json:"lock_height"`
+	Excess     string          `json:"excess"`
+	ExcessSig  string          `json:"excess_sig"`
+}
+
+// Transaction returns the consensus transaction
+func (t *TxJSON) Transaction() (*consensus.Transaction, error) {
+	var tx consensus.Transaction
+
+	offset, err := decodeHex("offset", t.Offset, len(tx.KernelOffset))
+	if err != nil {
+		return nil, err
+	}
+	copy(tx.KernelOffset[:], offset)
+
+	for _, input := range t.Body.Inputs {
+		features, err := outputFeatures(input.Features)
+		if err != nil {
+			return nil, err
+		}
+
+		commit, err := decodeHex("input commit", input.Commit, secp256k1zkp.PedersenCommitmentSize)
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Inputs = append(tx.Inputs, consensus.Input{
+			Features: features,
+			Commit:   commit,
+		})
+	}
+
+	for _, o := range t.Body.Outputs {
+		output, err := o.output()
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Outputs = append(tx.Outputs, *output)
+	}
+
+	for _, k := range t.Body.Kernels {
+		kernel, err := k.kernel()
+		if err != nil {
+			return nil, err
+		}
+
+		tx.Kernels = append(tx.Kernels, *kernel)
+	}
+
+	return &tx, nil
+}
+
+// output returns the consensus output
+func (o *OutputJSON) output() (*consensus.Output, error) {
+	features, err := outputFeatures(o.Features)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := decodePoint("output commit", o.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := decodeHex("proof", o.Proof, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var rangeProof bulletproofs.BulletProof
+	if err := rangeProof.Read(bytes.NewReader(proof)); err != nil {
+		return nil, fmt.Errorf("invalid proof: %v", err)
+	}
+
+	return &consensus.Output{
+		Features:   features,
+		Commit:     commit,
+		RangeProof: rangeProof,
+	}, nil
+}
+
+// kernel returns the consensus kernel
+func (k *TxKernelJSON) kernel() (*consensus.TxKernel, error) {
+	kernel := consensus.TxKernel{
+		Fee:        k.Fee,
+		LockHeight: k.LockHeight,
+	}
+
+	var name string
+	if err := json.Unmarshal(k.Features, &name); err != nil {
+		var variant map[string]struct {
+			Fee        uint64 `json:"fee"`
+			LockHeight uint64 `json:"lock_height"`
+		}
+		if err := json.Unmarshal(k.Features, &variant); err != nil || len(variant) != 1 {
+			return nil, fmt.Errorf("invalid kernel features: %s", k.Features)
+		}
+
+		for key, value := range variant {
+			name = key
+			kernel.Fee = value.Fee
+			kernel.LockHeight = value.LockHeight
+		}
+	}
+
+	switch name {
+	case "Plain", "HeightLocked":
+		kernel.Features = consensus.DefaultKernel
+	case "Coinbase":
+		kernel.Features = consensus.CoinbaseKernel
+	default:
+		return nil, fmt.Errorf("unsupported kernel features: %s", name)
+	}
+
+	excess, err := decodePoint("excess", k.Excess)
+	if err != nil {
+		return nil, err
+	}
+	kernel.Excess = *excess
+
+	sig, err := decodeHex("excess_sig", k.ExcessSig, len(kernel.ExcessSig))
+	if err != nil {
+		return nil, err
+	}
+	copy(kernel.ExcessSig[:], sig)
+
+	return &kernel, nil
+}
+
+// outputFeatures returns the output features by name
+func outputFeatures(name string) (consensus.OutputFeatures, error) {
+	switch name {
+	case "", "Plain":
+		return consensus.DefaultOutput, nil
+	case "Coinbase":
+		return consensus.CoinbaseOutput, nil
+	}
+
+	return 0, fmt.Errorf("unsupported output features: %s", name)
+}
+
+// decodeHex decodes the hex field of the size bytes, any size if size < 0
+func decodeHex(field, value string, size int) ([]byte, error) {
+	data, err := hex.DecodeString(value)
+	if err != nil || (size >= 0 && len(data) != size) {
+		return nil, fmt.Errorf("invalid %s: %s", field, value)
+	}
+
+	return data, nil
+}
+
+// decodePoint decodes the hex commitment
+func decodePoint(field, value string) (*bulletproofs.Point, error) {
+	data, err := decodeHex(field, value, secp256k1zkp.PedersenCommitmentSize)
+	if err != nil {
+		return nil, err
+	}
+
+	point := new(bulletproofs.Point)
+	if err := point.Read(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", field, value)
+	}
+
+	return point, nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/dblokhin/gringo/consensus"
+	"golang.org/x/crypto/blake2b"
+	"net/http"
+	"strconv"
+)
+
+// MaxLastNodes is the max count of the last outputs or kernels
+const MaxLastNodes = 1000
+
+// defaultLastNodes is the count of the last outputs or kernels if n is not set
+const defaultLastNodes = 10
+
+// TxHashSetRoots is the MMR roots & sizes of the head. gringo doesn't keep
+// the MMRs, so the roots are the ones committed by the head header
+type TxHashSetRoots struct {
+	Height             uint64 `json:"height"`
+	OutputRootHash     string `json:"output_root_hash"`
+	RangeProofRootHash string `json:"range_proof_root_hash"`
+	KernelRootHash     string `json:"kernel_root_hash"`
+	OutputMmrSize      uint64 `json:"output_mmr_size"`
+	KernelMmrSize      uint64 `json:"kernel_mmr_size"`
+}
+
+// TxHashSetNode is the MMR leaf of the output or kernel
+type TxHashSetNode struct {
+	// Hash is the leaf hash of the MMR
+	Hash string `json:"hash"`
+	// ID is the output commitment or the kernel excess
+	ID       string `json:"id"`
+	Height   uint64 `json:"height"`
+	MmrIndex uint64 `json:"mmr_index"`
+}
+
+// leafHash returns the MMR hash of the leaf at the 1-based position
+func leafHash(index uint64, data []byte) string {
+	buf := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(buf, index-1)
+
+	hash := blake2b.Sum256(append(buf, data...))
+	return hex.EncodeToString(hash[:])
+}
+
+// roots returns the MMR roots & sizes of the head: /v1/txhashset/roots
+func (s *Server) roots(r *http.Request) (interface{}, error) {
+	head := s.chain.Head()
+
+	return TxHashSetRoots{
+		Height:             head.Header.Height,
+		OutputRootHash:     head.Header.UTXORoot.String(),
+		RangeProofRootHash: head.Header.RangeProofRoot.String(),
+		KernelRootHash:     head.Header.KernelRoot.String(),
+		OutputMmrSize:      head.Header.OutputMmrSize,
+		KernelMmrSize:      head.Header.KernelMmrSize,
+	}, nil
+}
+
+// lastOutputs returns the last n outputs, the newest first:
+// /v1/txhashset/lastoutputs?n=x
+func (s *Server) lastOutputs(r *http.Request) (interface{}, error) {
+	return s.lastNodes(r, func(block *consensus.Block) []TxHashSetNode {
+		leaves := firstLeaf(block.Header.OutputMmrSize, len(block.Outputs))
+
+		nodes := make([]TxHashSetNode, 0, len(block.Outputs))
+		for i := range block.Outputs {
+			index := mmrLeafIndex(leaves + uint64(i))
+			nodes = append(nodes, TxHashSetNode{
+				Hash:     leafHash(index, block.Outputs[i].BytesWithoutProof()),
+				ID:       hex.EncodeToString(block.Outputs[i].Commit.Bytes()),
+				Height:   block.Header.Height,
+				MmrIndex: index,
+			})
+		}
+
+		return nodes
+	})
+}
+
+// lastKernels returns the last n kernels, the newest first:
+// /v1/txhashset/lastkernels?n=x
+func (s *Server) lastKernels(r *http.Request) (interface{}, error) {
+	return s.lastNodes(r, func(block *consensus.Block) []TxHashSetNode {
+		leaves := firstLeaf(block.Header.KernelMmrSize, len(block.Kernels))
+
+		nodes := make([]TxHashSetNode, 0, len(block.Kernels))
+		for i := range block.Kernels {
+			index := mmrLeafIndex(leaves + uint64(i))
+			nodes = append(nodes, TxHashSetNode{
+				Hash:     leafHash(index, block.Kernels[i].Bytes()),
+				ID:       hex.EncodeToString(block.Kernels[i].Excess.Bytes()),
+				Height:   block.Header.Height,
+				MmrIndex: index,
+			})
+		}
+
+		return nodes
+	})
+}
+
+// lastNodes returns the last n leaves of the recent MaxHeightRange blocks,
+// the leaves of the block are returned by nodes
+func (s *Server) lastNodes(r *http.Request, nodes func(block *consensus.Block) []TxHashSetNode) (interface{}, error) {
+	n := uint64(defaultLastNodes)
+	if value := r.URL.Query().Get("n"); value != "" {
+		var err error
+		if n, err = strconv.ParseUint(value, 10, 64); err != nil || n == 0 || n > MaxLastNodes {
+			return nil, errInvalidRange
+		}
+	}
+
+	result := make([]TxHashSetNode, 0, n)
+
+	height := s.chain.Height()
+	for i := uint64(0); i < MaxHeightRange && i <= height && uint64(len(result)) < n; i++ {
+		block, err := s.findBlock(strconv.FormatUint(height-i, 10))
+		if err != nil {
+			continue
+		}
+
+		leaves := nodes(block)
+		for j := len(leaves) - 1; j >= 0 && uint64(len(result)) < n; j-- {
+			result = append(result, leaves[j])
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRoots(t *testing.T) {
+	c := newBlocksChain(2)
+	c.blocks[2].Header.KernelMmrSize = 4
+	s := New(c, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/txhashset/roots", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var roots TxHashSetRoots
+	if err := json.NewDecoder(w.Body).Decode(&roots); err != nil {
+		t.Fatal(err)
+	}
+
+	if roots.Height != 2 || roots.KernelMmrSize != 4 || roots.KernelRootHash != c.blocks[2].Header.KernelRoot.String() {
+		t.Errorf("roots were %+v", roots)
+	}
+}
+
+func TestLastKernels(t *testing.T) {
+	c := newBlocksChain(3)
+	for i, size := range []uint64{0, 1, 4, 10} {
+		c.blocks[i].Header.KernelMmrSize = size
+	}
+	s := New(c, &testPool{}, testPeers{})
+
+	w := request(s, http.MethodGet, "/v1/txhashset/lastkernels?n=4", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code was %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var nodes []TxHashSetNode
+	if err := json.NewDecoder(w.Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	// the leaves 5, 4, 3 of the block 3 & the leaf 2 of the block 2
+	indexes := []uint64{9, 8, 5, 4}
+	if len(nodes) != len(indexes) {
+		t.Fatalf("%d kernels were returned, want %d", len(nodes), len(indexes))
+	}
+
+	for i, node := range nodes {
+		if node.MmrIndex != indexes[i] {
+			t.Errorf("kernel %d mmr index was %d, want %d", i, node.MmrIndex, indexes[i])
+		}
+	}
+
+	kernel := c.blocks[3].Kernels[2]
+	if nodes[0].ID != hex.EncodeToString(kernel.Excess.Bytes()) || nodes[0].Hash != leafHash(9, kernel.Bytes()) || nodes[3].Height != 2 {
+		t.Errorf("kernels were %+v", nodes)
+	}
+
+	for _, url := range []string{"/v1/txhashset/lastkernels?n=0", "/v1/txhashset/lastoutputs?n=1001"} {
+		if w := request(s, http.MethodGet, url, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code was %d, want %d", url, w.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"golang.org/x/crypto/blake2b"
+	"time"
+)
+
+// Tip is the state of the chain head
+type Tip struct {
+	Height          uint64 `json:"height"`
+	LastBlockPushed string `json:"last_block_pushed"`
+	PrevBlockToLast string `json:"prev_block_to_last"`
+	TotalDifficulty uint64 `json:"total_difficulty"`
+}
+
+// Status is the node status
+type Status struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit,omitempty"`
+	BuildDate       string `json:"build_date,omitempty"`
+	ProtocolVersion uint32 `json:"protocol_version"`
+	UserAgent       string `json:"user_agent"`
+	Network         string `json:"network,omitempty"`
+	// NodeID is the hex of the node identity public key, empty if the node
+	// has none
+	NodeID      string `json:"node_id,omitempty"`
+	Connections int    `json:"connections"`
+	Tip         Tip    `json:"tip"`
+	// HeaderTip is the best header, ahead of the tip while syncing
+	HeaderTip   Tip    `json:"header_tip"`
+	SyncStatus  string `json:"sync_status,omitempty"`
+	SyncPercent int    `json:"sync_percent"`
+	// SyncProgress is the detailed sync progress, nil without the syncer
+	SyncProgress *SyncProgress `json:"sync_progress,omitempty"`
+	// ForkAlarm is set if the most peers are ahead of the synced chain: the
+	// chain may be on a minority fork or stalled
+	ForkAlarm bool `json:"fork_alarm"`
+	// Uptime is the node uptime in seconds
+	Uptime int64 `json:"uptime"`
+	// Resources is the resource usage of the node, nil without the monitor
+	Resources *Resources `json:"resources,omitempty"`
+}
+
+// SyncProgress is the synced headers & blocks of the target height, the
+// rates of the last minute & the estimated seconds to complete the stage
+type SyncProgress struct {
+	Headers          uint64  `json:"headers"`
+	Blocks           uint64  `json:"blocks"`
+	Target           uint64  `json:"target"`
+	HeadersPerSecond float64 `json:"headers_per_sec"`
+	BlocksPerSecond  float64 `json:"blocks_per_sec"`
+	// ETA is 0 if unknown
+	ETA int64 `json:"eta"`
+}
+
+// BlockHeaderPrintable is the block header in the grin api format
+type BlockHeaderPrintable struct {
+	Hash              string   `json:"hash"`
+	Version           uint16   `json:"version"`
+	Height            uint64   `json:"height"`
+	Previous          string   `json:"previous"`
+	PrevRoot          string   `json:"prev_root"`
+	Timestamp         string   `json:"timestamp"`
+	OutputRoot        string   `json:"output_root"`
+	RangeProofRoot    string   `json:"range_proof_root"`
+	KernelRoot        string   `json:"kernel_root"`
+	Nonce             uint64   `json:"nonce"`
+	EdgeBits          uint8    `json:"edge_bits"`
+	CuckooSolution    []uint32 `json:"cuckoo_solution"`
+	TotalDifficulty   uint64   `json:"total_difficulty"`
+	SecondaryScaling  uint32   `json:"secondary_scaling"`
+	TotalKernelOffset string   `json:"total_kernel_offset"`
+}
+
+// OutputPrintable is the output in the grin api format. gringo doesn't keep
+// the output MMR, so merkle_proof is always null, mmr_index is counted from
+// the output MMR size of the block header
+type OutputPrintable struct {
+	OutputType  string  `json:"output_type"`
+	Commit      string  `json:"commit"`
+	Spent       bool    `json:"spent"`
+	Proof       *string `json:"proof"`
+	ProofHash   string  `json:"proof_hash"`
+	BlockHeight uint64  `json:"block_height"`
+	MerkleProof *string `json:"merkle_proof"`
+	MmrIndex    uint64  `json:"mmr_index"`
+}
+
+// TxKernelPrintable is the kernel in the grin api format
+type TxKernelPrintable struct {
+	Features   string `json:"features"`
+	Fee        uint64 `json:"fee"`
+	LockHeight uint64 `json:"lock_height"`
+	Excess     string `json:"excess"`
+	ExcessSig  string `json:"excess_sig"`
+}
+
+// LocatedTxKernel is the kernel with its block height & MMR position in the
+// grin api format
+type LocatedTxKernel struct {
+	TxKernel TxKernelPrintable `json:"tx_kernel"`
+	Height   uint64            `json:"height"`
+	MmrIndex uint64            `json:"mmr_index"`
+}
+
+// BlockPrintable is the block in the grin api format
+type BlockPrintable struct {
+	Header  BlockHeaderPrintable `json:"header"`
+	Inputs  []string             `json:"inputs"`
+	Outputs []OutputPrintable    `json:"outputs"`
+	Kernels []TxKernelPrintable  `json:"kernels"`
+}
+
+// BlockHeaderInfo is the short block header info
+type BlockHeaderInfo struct {
+	Hash     string `json:"hash"`
+	Height   uint64 `json:"height"`
+	Previous string `json:"previous"`
+}
+
+// BlockOutputs is the outputs of the block
+type BlockOutputs struct {
+	Header  BlockHeaderInfo   `json:"header"`
+	Outputs []OutputPrintable `json:"outputs"`
+}
+
+// Output is the unspent output location in the grin api format, mmr_index is
+// always 0
+type Output struct {
+	Commit   string `json:"commit"`
+	Height   uint64 `json:"height"`
+	MmrIndex uint64 `json:"mmr_index"`
+}
+
+// OutputListing is the page of the outputs scanned by the wallet restore
+type OutputListing struct {
+	HighestHeight       uint64            `json:"highest_height"`
+	LastRetrievedHeight uint64            `json:"last_retrieved_height"`
+	Outputs             []OutputPrintable `json:"outputs"`
+}
+
+// Version is the node version in the grin api format
+type Version struct {
+	NodeVersion        string `json:"node_version"`
+	BlockHeaderVersion uint16 `json:"block_header_version"`
+}
+
+// PoolInfo is the transaction pool info
+type PoolInfo struct {
+	PoolSize int `json:"pool_size"`
+}
+
+// PushTx is the body of the push_tx request
+type PushTx struct {
+	TxHex string `json:"tx_hex"`
+	// Fluff skips the Dandelion stem phase
+	Fluff bool `json:"fluff"`
+}
+
+// PeerInfo is the connected peer info
+type PeerInfo struct {
+	Addr            string `json:"addr"`
+	Status          string `json:"status"`
+	Version         uint32 `json:"version"`
+	UserAgent       string `json:"user_agent"`
+	Capabilities    uint32 `json:"capabilities"`
+	TotalDifficulty uint64 `json:"total_difficulty"`
+	Height          uint64 `json:"height"`
+	Direction       string `json:"direction"`
+	NodeID          string `json:"node_id,omitempty"`
+}
+
+// ConnEvent is the connection audit record of the peer
+type ConnEvent struct {
+	Time  string `json:"time"`
+	Event string `json:"event"`
+	Addr  string `json:"addr"`
+	// Direction, UserAgent, Duration & the bytes are empty if the peer
+	// wasn't connected, the Duration is in seconds
+	Direction     string  `json:"direction,omitempty"`
+	UserAgent     string  `json:"user_agent,omitempty"`
+	Duration      float64 `json:"duration"`
+	BytesSent     uint64  `json:"bytes_sent"`
+	BytesReceived uint64  `json:"bytes_received"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// NewHeaderPrintable returns printable header
+func NewHeaderPrintable(h *consensus.BlockHeader) BlockHeaderPrintable {
+	return BlockHeaderPrintable{
+		Hash:              h.Hash().String(),
+		Version:           h.Version,
+		Height:            h.Height,
+		Previous:          h.Previous.String(),
+		PrevRoot:          h.PreviousRoot.String(),
+		Timestamp:         h.Timestamp.UTC().Format(time.RFC3339),
+		OutputRoot:        h.UTXORoot.String(),
+		RangeProofRoot:    h.RangeProofRoot.String(),
+		KernelRoot:        h.KernelRoot.String(),
+		Nonce:             h.Nonce,
+		EdgeBits:          h.POW.EdgeBits,
+		CuckooSolution:    h.POW.Nonces,
+		TotalDifficulty:   uint64(h.TotalDifficulty),
+		SecondaryScaling:  h.ScalingDifficulty,
+		TotalKernelOffset: h.TotalKernelOffset.String(),
+	}
+}
+
+// newOutputPrintable returns printable output of the block at height
+func newOutputPrintable(o *consensus.Output, height uint64, spent bool) OutputPrintable {
+	outputType := "Transaction"
+	if o.Features&consensus.CoinbaseOutput == consensus.CoinbaseOutput {
+		outputType = "Coinbase"
+	}
+
+	result := OutputPrintable{
+		OutputType:  outputType,
+		Commit:      hex.EncodeToString(o.Commit.Bytes()),
+		Spent:       spent,
+		BlockHeight: height,
+	}
+
+	proof := rangeProof(o)
+	if proof != nil {
+		value := hex.EncodeToString(proof)
+		result.Proof = &value
+	}
+
+	proofHash := blake2b.Sum256(proof)
+	result.ProofHash = hex.EncodeToString(proofHash[:])
+
+	return result
+}
+
+// rangeProof returns the serialized range proof of the output, nil if the
+// output has no proof
+func rangeProof(o *consensus.Output) []byte {
+	if o.RangeProof.A == nil {
+		return nil
+	}
+
+	return o.RangeProof.Bytes()
+}
+
+// NewBlockPrintable returns printable block, isSpent reports the spent outputs
+func NewBlockPrintable(b *consensus.Block, isSpent func(o *consensus.Output) bool) BlockPrintable {
+	result := BlockPrintable{
+		Header:  NewHeaderPrintable(&b.Header),
+		Inputs:  make([]string, 0, len(b.Inputs)),
+		Outputs: make([]OutputPrintable, 0, len(b.Outputs)),
+		Kernels: make([]TxKernelPrintable, 0, len(b.Kernels)),
+	}
+
+	for _, input := range b.Inputs {
+		result.Inputs = append(result.Inputs, hex.EncodeToString(input.Commit))
+	}
+
+	leaves := firstLeaf(b.Header.OutputMmrSize, len(b.Outputs))
+	for i := range b.Outputs {
+		output := newOutputPrintable(&b.Outputs[i], b.Header.Height, isSpent(&b.Outputs[i]))
+		output.MmrIndex = mmrLeafIndex(leaves + uint64(i))
+		result.Outputs = append(result.Outputs, output)
+	}
+
+	for i := range b.Kernels {
+		result.Kernels = append(result.Kernels, newKernelPrintable(&b.Kernels[i]))
+	}
+
+	return result
+}
+
+// newKernelPrintable returns printable kernel
+func newKernelPrintable(kernel *consensus.TxKernel) TxKernelPrintable {
+	features := "Plain"
+	if kernel.Features&consensus.CoinbaseKernel == consensus.CoinbaseKernel {
+		features = "Coinbase"
+	}
+
+	return TxKernelPrintable{
+		Features:   features,
+		Fee:        kernel.Fee,
+		LockHeight: kernel.LockHeight,
+		Excess:     hex.EncodeToString(kernel.Excess.Bytes()),
+		ExcessSig:  hex.EncodeToString(kernel.ExcessSig[:]),
+	}
+}
+
+// newPeerInfo returns printable peer stats
+func newPeerInfo(s p2p.PeerStats) PeerInfo {
+	direction := "Outbound"
+	if s.Inbound {
+		direction = "Inbound"
+	}
+
+	info := PeerInfo{
+		Addr:            s.Addr,
+		Status:          s.Status,
+		Version:         s.Version,
+		UserAgent:       s.UserAgent,
+		Capabilities:    uint32(s.Capabilities),
+		TotalDifficulty: uint64(s.TotalDifficulty),
+		Height:          s.Height,
+		Direction:       direction,
+	}
+
+	if !s.NodeID.IsZero() {
+		info.NodeID = s.NodeID.String()
+	}
+
+	return info
+}
+
+// newConnEvent returns printable connection event
+func newConnEvent(e p2p.ConnEvent) ConnEvent {
+	event := ConnEvent{
+		Time:          e.Time.UTC().Format(time.RFC3339Nano),
+		Event:         e.Event,
+		Addr:          e.Addr,
+		UserAgent:     e.UserAgent,
+		Duration:      e.Duration.Seconds(),
+		BytesSent:     e.BytesSent,
+		BytesReceived: e.BytesReceived,
+		Reason:        e.Reason,
+	}
+
+	// the connected peer event has the connection duration
+	if e.Duration > 0 {
+		event.Direction = "Outbound"
+		if e.Inbound {
+			event.Direction = "Inbound"
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/mining"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// walletTimeout is the deadline of the wallet listener request
+	walletTimeout = 30 * time.Second
+	// maxWalletResponse is the max size of the wallet listener response
+	maxWalletResponse = 1 << 20
+)
+
+// WalletClient is the client of the foreign API of the grin wallet listener,
+// the coinbase of the mined blocks is built by the wallet so the rewards are
+// spendable by the operator wallet
+type WalletClient struct {
+	url    string
+	client *http.Client
+}
+
+// NewWalletClient returns the client of the wallet listener url, e.g.
+// http://127.0.0.1:3415
+func NewWalletClient(url string) *WalletClient {
+	return &WalletClient{
+		url:    strings.TrimSuffix(url, "/") + "/v2/foreign",
+		client: &http.Client{Timeout: walletTimeout},
+	}
+}
+
+// blockFeesJSON is the block_fees param of build_coinbase
+type blockFeesJSON struct {
+	Fees   uint64  `json:"fees"`
+	Height uint64  `json:"height"`
+	KeyID  *string `json:"key_id"`
+}
+
+// coinbaseJSON is the build_coinbase result of the wallet
+type coinbaseJSON struct {
+	Output OutputJSON   `json:"output"`
+	Kernel TxKernelJSON `json:"kernel"`
+	KeyID  string       `json:"key_id"`
+}
+
+// BuildCoinbase requests the coinbase of the block by the build_coinbase
+// method of the wallet foreign JSON-RPC API
+func (c *WalletClient) BuildCoinbase(ctx context.Context, fees mining.BlockFees) (*mining.Coinbase, error) {
+	blockFees := blockFeesJSON{Fees: fees.Fees, Height: fees.Height}
+	if fees.KeyID != "" {
+		blockFees.KeyID = &fees.KeyID
+	}
+
+	params, err := json.Marshal(map[string]blockFeesJSON{"block_fees": blockFees})
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(rpcRequest{
+		Version: "2.0",
+		ID:      json.RawMessage("1"),
+		Method:  "build_coinbase",
+		Params:  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wallet listener request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wallet listener responded %s", resp.Status)
+	}
+
+	// the wallet result is {"Ok": ...} or {"Err": ...}
+	var response struct {
+		Result struct {
+			Ok  *coinbaseJSON   `json:"Ok"`
+			Err json.RawMessage `json:"Err"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWalletResponse)).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid wallet listener response: %v", err)
+	}
+
+	if response.Error != nil {
+		return nil, fmt.Errorf("wallet listener error: %v", response.Error)
+	}
+
+	if response.Result.Ok == nil {
+		if len(response.Result.Err) == 0 {
+			return nil, errors.New("wallet listener returned no coinbase")
+		}
+		return nil, fmt.Errorf("wallet listener error: %s", response.Result.Err)
+	}
+
+	return response.Result.Ok.coinbase()
+}
+
+// coinbase returns the coinbase of the wallet result
+func (c *coinbaseJSON) coinbase() (*mining.Coinbase, error) {
+	output, err := c.Output.output()
+	if err != nil {
+		return nil, err
+	}
+
+	kernel, err := c.Kernel.kernel()
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Features != consensus.CoinbaseOutput || kernel.Features != consensus.CoinbaseKernel {
+		return nil, errors.New("wallet listener built no coinbase")
+	}
+
+	return &mining.Coinbase{Output: *output, Kernel: *kernel, KeyID: c.KeyID}, nil
+}
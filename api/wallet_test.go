@@ -0,0 +1,101 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/dblokhin/gringo/mining"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testWallet returns the wallet listener responding the result of the
+// build_coinbase request, the requests are recorded
+func testWallet(t *testing.T, result interface{}, requests *[]rpcRequest) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v2/foreign" {
+			t.Errorf("invalid request to %s: %v", r.URL.Path, err)
+		}
+		*requests = append(*requests, req)
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+}
+
+func TestWalletBuildCoinbase(t *testing.T) {
+	expected, err := mining.NewKeyCoinbase(big.NewInt(1)).BuildCoinbase(context.Background(), mining.BlockFees{Height: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := map[string]interface{}{"Ok": map[string]interface{}{
+		"key_id": "0300000000000000000000000400000000",
+		"output": map[string]interface{}{
+			"features": "Coinbase",
+			"commit":   hex.EncodeToString(expected.Output.Commit.Bytes()),
+			"proof":    hex.EncodeToString(expected.Output.RangeProof.Bytes()),
+		},
+		"kernel": map[string]interface{}{
+			"features":    "Coinbase",
+			"fee":         0,
+			"lock_height": 0,
+			"excess":      hex.EncodeToString(expected.Kernel.Excess.Bytes()),
+			"excess_sig":  hex.EncodeToString(expected.Kernel.ExcessSig[:]),
+		},
+	}}
+
+	var requests []rpcRequest
+	server := testWallet(t, result, &requests)
+	defer server.Close()
+
+	coinbase, err := NewWalletClient(server.URL+"/").BuildCoinbase(context.Background(), mining.BlockFees{Fees: 2, Height: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if coinbase.KeyID != "0300000000000000000000000400000000" || coinbase.Kernel.ExcessSig != expected.Kernel.ExcessSig ||
+		coinbase.Output.Commit.X.Cmp(expected.Output.Commit.X) != 0 {
+		t.Errorf("coinbase was %+v", coinbase)
+	}
+
+	if len(requests) != 1 || requests[0].Method != "build_coinbase" ||
+		string(requests[0].Params) != `{"block_fees":{"fees":2,"height":5,"key_id":null}}` {
+		t.Errorf("requests were %+v", requests)
+	}
+}
+
+func TestWalletBuildCoinbaseError(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		result interface{}
+		err    string
+	}{
+		{name: "wallet error", result: map[string]interface{}{"Err": map[string]string{"GenericError": "locked"}}, err: "wallet listener error"},
+		{name: "no result", err: "returned no coinbase"},
+		{name: "plain output", result: map[string]interface{}{"Ok": map[string]interface{}{
+			"output": map[string]string{"features": "Plain", "commit": "zz"},
+		}}, err: "invalid output commit"},
+	} {
+		var requests []rpcRequest
+		server := testWallet(t, test.result, &requests)
+
+		_, err := NewWalletClient(server.URL).BuildCoinbase(context.Background(), mining.BlockFees{Height: 1, KeyID: "03"})
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error was %v, want %s", test.name, err, test.err)
+		}
+
+		if len(requests) != 1 || !strings.Contains(string(requests[0].Params), `"key_id":"03"`) {
+			t.Errorf("%s: requests were %+v", test.name, requests)
+		}
+
+		server.Close()
+	}
+}
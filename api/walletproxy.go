@@ -0,0 +1,89 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+// errWalletUnreachable is the failed request of the wallet listener
+var errWalletUnreachable = errors.New("wallet unreachable")
+
+// walletMethods are the wallet foreign API methods forwarded to the wallet
+// listener. build_coinbase is left to the miner of the wallet owner, the
+// public callers would bloat the wallet with the outputs
+var walletMethods = map[string]bool{
+	"check_version":         true,
+	"verify_slate_messages": true,
+	"receive_tx":            true,
+	"finalize_invoice_tx":   true,
+	"finalize_tx":           true,
+}
+
+// SetWalletProxy forwards the wallet foreign API requests of /v2/foreign to
+// the wallet listener target, so the wallet receives the transactions over
+// the public node endpoint & stays private. The wallet requests bypass the
+// foreign secret, secret is the basic auth of the wallet foreign API & nil
+// config is the system roots of the TLS listener
+func (s *Server) SetWalletProxy(target *url.URL, secret string, config *tls.Config) {
+	proxy := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			r.URL.Path = strings.TrimSuffix(target.Path, "/") + "/v2/foreign"
+			r.URL.RawPath = ""
+			r.URL.RawQuery = ""
+			r.Host = target.Host
+
+			r.Header.Del("Authorization")
+			if secret != "" {
+				r.SetBasicAuth(BasicAuthUser, secret)
+			}
+		},
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: config,
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logrus.Warnf("wallet proxy: %v", err)
+			writeError(w, http.StatusBadGateway, errWalletUnreachable)
+		},
+	}
+
+	s.wallet = proxy
+}
+
+// walletRequest returns true if the foreign JSON-RPC request is of the
+// wallet methods, the body is kept for the handler
+func walletRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, int64(consensus.MaxMsgLen)))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	if err != nil {
+		return false
+	}
+
+	// the batches are the node requests
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return false
+	}
+
+	return walletMethods[req.Method]
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestWalletProxy(t *testing.T) {
+	var requests []rpcRequest
+	var auth []string
+	wallet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v2/foreign" {
+			t.Errorf("invalid request to %s: %v", r.URL.Path, err)
+		}
+		requests = append(requests, req)
+		auth = append(auth, r.Header.Get("Authorization"))
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": map[string]string{"Ok": "slate"}})
+	}))
+	defer wallet.Close()
+
+	target, err := url.Parse(wallet.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestServer()
+	s.SetSecrets("foreign", "")
+	s.SetWalletProxy(target, "wallet", nil)
+
+	// the wallet methods bypass the node secret
+	w := request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "receive_tx", "params": [{}, null, null]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "slate") {
+		t.Fatalf("receive_tx was not forwarded: %d %s", w.Code, w.Body.String())
+	}
+
+	if len(requests) != 1 || requests[0].Method != "receive_tx" {
+		t.Errorf("wallet requests were %+v", requests)
+	}
+
+	if len(auth) != 1 || !authorized(auth[0], "wallet") {
+		t.Errorf("wallet request auth was %v", auth)
+	}
+
+	// the node methods & the coinbase requests are the node ones
+	for _, method := range []string{"get_tip", "build_coinbase"} {
+		w := request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": []}`)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s status code was %d, want %d", method, w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if len(requests) != 1 {
+		t.Errorf("node requests were forwarded: %+v", requests)
+	}
+
+	wallet.Close()
+	w = request(s, http.MethodPost, "/v2/foreign", `{"jsonrpc": "2.0", "id": 1, "method": "check_version", "params": []}`)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("unreachable wallet status code was %d, want %d", w.Code, http.StatusBadGateway)
+	}
+}
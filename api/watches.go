@@ -0,0 +1,131 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"golang.org/x/net/websocket"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// watchBuffer is the count of the watch events queued to the websocket client
+const watchBuffer = 64
+
+// Watch is the kernel excess or the output commitment tracked to the
+// confirmations
+type Watch struct {
+	Kind          string `json:"kind"`
+	Commit        string `json:"commit"`
+	Confirmations uint64 `json:"confirmations"`
+	// Height is the block of the commitment, 0 if it's not mined
+	Height    uint64 `json:"height"`
+	Confirmed bool   `json:"confirmed"`
+}
+
+// WatchEvent is the change of the watch: mined, confirmed or reorged
+type WatchEvent struct {
+	Watch
+	Event string `json:"event"`
+	Depth uint64 `json:"depth"`
+}
+
+// newWatch returns the json of the watch
+func newWatch(watch chain.Watch) Watch {
+	return Watch{
+		Kind:          watch.Kind,
+		Commit:        hex.EncodeToString(watch.Commit),
+		Confirmations: watch.Confirmations,
+		Height:        watch.Height,
+		Confirmed:     watch.Confirmed,
+	}
+}
+
+// SetWatcher adds the owner methods of the confirmation tracking: add_watch,
+// get_watches & remove_watch, and the websocket of the watch events
+// /v1/watches/ws
+func (s *Server) SetWatcher(watcher *chain.Watcher) {
+	s.RegisterOwnerMethod("add_watch", func(params json.RawMessage) (interface{}, error) {
+		var kind, commit string
+		var confirmations uint64
+		if err := parseParams(params, &kind, &commit, &confirmations); err != nil {
+			return nil, err
+		}
+
+		c, err := hex.DecodeString(commit)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid commitment: %s", commit)}
+		}
+
+		watch, err := watcher.Add(kind, c, confirmations)
+		if err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+
+		return newWatch(watch), nil
+	})
+
+	s.RegisterOwnerMethod("get_watches", func(params json.RawMessage) (interface{}, error) {
+		watches := watcher.Watches()
+
+		result := make([]Watch, 0, len(watches))
+		for _, watch := range watches {
+			result = append(result, newWatch(watch))
+		}
+
+		return result, nil
+	})
+
+	s.RegisterOwnerMethod("remove_watch", func(params json.RawMessage) (interface{}, error) {
+		var kind, commit string
+		if err := parseParams(params, &kind, &commit); err != nil {
+			return nil, err
+		}
+
+		c, err := hex.DecodeString(commit)
+		if err != nil || !watcher.Remove(kind, c) {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown watch: %s %s", kind, commit)}
+		}
+
+		return nil, nil
+	})
+
+	s.ownerMux.Handle("/v1/watches/ws", watchesWS(watcher))
+}
+
+// watchesWS streams the watch events to the websocket client as the json
+// messages. The events are skipped while the client is behind by watchBuffer
+// events
+func watchesWS(watcher *chain.Watcher) http.Handler {
+	// the origin is not checked, the API secret authorizes the clients
+	return websocket.Server{Handler: func(ws *websocket.Conn) {
+		events := make(chan *chain.WatchEvent, watchBuffer)
+		watcher.Subscribe(events)
+		defer watcher.Unsubscribe(events)
+
+		// the client messages are discarded, the read fails on close
+		closed := make(chan struct{})
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			close(closed)
+		}()
+
+		for {
+			select {
+			case <-closed:
+				return
+			case event := <-events:
+				message := WatchEvent{Watch: newWatch(event.Watch), Event: event.Event, Depth: event.Depth}
+				if err := websocket.JSON.Send(ws, message); err != nil {
+					return
+				}
+			}
+		}
+	}}
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"encoding/json"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// watchTestChain is the test chain of the watcher
+type watchTestChain struct {
+	*testChain
+}
+
+func (c watchTestChain) Subscribe(chan<- *consensus.Block)   {}
+func (c watchTestChain) Unsubscribe(chan<- *consensus.Block) {}
+
+func TestOwnerWatches(t *testing.T) {
+	s := newTestServer()
+	s.SetWatcher(chain.NewWatcher(watchTestChain{&testChain{}}))
+
+	commit := strings.Repeat("08", 33)
+	call := func(method, params string, result interface{}) {
+		t.Helper()
+
+		w := request(s.Owner(), http.MethodPost, "/v2/owner", `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`)
+		var response struct {
+			Result struct {
+				Ok json.RawMessage `json:"Ok"`
+			} `json:"result"`
+			Error *rpcError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Error != nil {
+			t.Fatalf("%s failed: %s", method, w.Body.String())
+		}
+
+		if result != nil {
+			if err := json.Unmarshal(response.Result.Ok, result); err != nil {
+				t.Fatalf("%s result: %v", method, err)
+			}
+		}
+	}
+
+	var watch Watch
+	call("add_watch", `["kernel", "`+commit+`", 3]`, &watch)
+	if watch.Kind != chain.WatchKernel || watch.Commit != commit || watch.Confirmations != 3 || watch.Height != 0 {
+		t.Errorf("unexpected watch: %+v", watch)
+	}
+
+	var watches []Watch
+	call("get_watches", `[]`, &watches)
+	if len(watches) != 1 || watches[0] != watch {
+		t.Errorf("watches were %+v", watches)
+	}
+
+	call("remove_watch", `["kernel", "`+commit+`"]`, nil)
+	call("get_watches", `[]`, &watches)
+	if len(watches) != 0 {
+		t.Errorf("watch was not removed: %+v", watches)
+	}
+
+	for _, body := range []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "add_watch", "params": ["tx", "` + commit + `"]}`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "add_watch", "params": ["output", "zz"]}`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "remove_watch", "params": ["kernel", "` + commit + `"]}`,
+	} {
+		if w := request(s.Owner(), http.MethodPost, "/v2/owner", body); !strings.Contains(w.Body.String(), `"error"`) {
+			t.Errorf("invalid request succeeded: %s", body)
+		}
+	}
+}
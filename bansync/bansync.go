@@ -0,0 +1,168 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package bansync shares the ban lists between the trusted nodes of the same
+// operator over the owner API, so the attacker banned by one node is banned
+// by all of them
+package bansync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/logging"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// syncTimeout is the deadline of the owner API request
+	syncTimeout = 30 * time.Second
+
+	// bansPath is the ban list of the owner API
+	bansPath = "/v1/peers/bans"
+)
+
+// Peers is the ban list of the node
+type Peers interface {
+	Bans() []string
+	Ban(addr string)
+}
+
+// Syncer pulls the ban lists of the trusted nodes & pushes the entries they
+// miss every interval, the unbans are not shared
+type Syncer struct {
+	nodes    []string
+	secret   string
+	interval time.Duration
+	peers    Peers
+
+	client *http.Client
+	log    logging.Logger
+}
+
+// NewSyncer returns the syncer of the ban list of peers with the owner APIs
+// of nodes, secret is the owner API secret of the nodes
+func NewSyncer(nodes []string, secret string, interval time.Duration, peers Peers) *Syncer {
+	return &Syncer{
+		nodes:    nodes,
+		secret:   secret,
+		interval: interval,
+		peers:    peers,
+		client:   &http.Client{Timeout: syncTimeout},
+		log:      logging.Default("bansync"),
+	}
+}
+
+// SetLogger sets the logger of the failed syncs
+func (s *Syncer) SetLogger(logger logging.Logger) {
+	s.log = logger
+}
+
+// Sync bans the entries of the node ban list missing locally & pushes the
+// local entries the node misses, returns the counts of the pulled & the
+// pushed entries
+func (s *Syncer) Sync(ctx context.Context, node string) (int, int, error) {
+	var remote api.BanList
+	if err := s.do(ctx, http.MethodGet, node, nil, &remote); err != nil {
+		return 0, 0, err
+	}
+
+	known := make(map[string]bool)
+	for _, entry := range remote.Bans {
+		known[entry] = true
+	}
+
+	local := make(map[string]bool)
+	var missing []string
+	for _, entry := range s.peers.Bans() {
+		local[entry] = true
+		if !known[entry] {
+			missing = append(missing, entry)
+		}
+	}
+
+	pulled := 0
+	for _, entry := range remote.Bans {
+		if !local[entry] {
+			s.peers.Ban(entry)
+			pulled++
+		}
+	}
+
+	if len(missing) == 0 {
+		return pulled, 0, nil
+	}
+
+	var added api.BansAdded
+	if err := s.do(ctx, http.MethodPost, node, &api.BanList{Bans: missing}, &added); err != nil {
+		return pulled, 0, err
+	}
+
+	return pulled, added.Added, nil
+}
+
+// Run syncs the ban list with the nodes every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, node := range s.nodes {
+			pulled, pushed, err := s.Sync(ctx, node)
+			if err != nil {
+				s.log.Warnf("ban list sync with %s failed: %v", node, err)
+				continue
+			}
+
+			if pulled > 0 || pushed > 0 {
+				s.log.Infof("ban list synced with %s: %d banned, %d pushed", node, pulled, pushed)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// do sends the json of req to the ban list of the node owner API & decodes
+// the response to resp
+func (s *Syncer) do(ctx context.Context, method, node string, req, resp interface{}) error {
+	var body io.Reader
+	if req != nil {
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(node, "/")+bansPath, body)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		r.SetBasicAuth(api.BasicAuthUser, s.secret)
+	}
+
+	res, err := s.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("owner api responded %s", res.Status)
+	}
+
+	return json.NewDecoder(res.Body).Decode(resp)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package bansync
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+// banList is the ban list of the node
+type banList map[string]bool
+
+func (l banList) Bans() []string {
+	result := make([]string, 0, len(l))
+	for entry := range l {
+		result = append(result, entry)
+	}
+	sort.Strings(result)
+	return result
+}
+func (l banList) Ban(addr string)                                   { l[addr] = true }
+func (l banList) Unban(addr string)                                 { delete(l, addr) }
+func (l banList) Connected() []p2p.PeerStats                        { return nil }
+func (l banList) All() []p2p.PeerStats                              { return nil }
+func (l banList) PropagateTx(tx *consensus.Transaction, fluff bool) {}
+func (l banList) ConnLog(p2p.ConnLogQuery) []p2p.ConnEvent          { return nil }
+
+func TestSync(t *testing.T) {
+	remote := banList{"10.0.0.1:13414": true, "10.0.1.0/24": true}
+	server := api.New(nil, nil, remote)
+	server.SetSecrets("", "secret")
+
+	owner := httptest.NewServer(server.Owner())
+	defer owner.Close()
+
+	local := banList{"10.0.0.1:13414": true, "10.0.0.2:13414": true}
+	s := NewSyncer([]string{owner.URL}, "secret", time.Hour, local)
+
+	pulled, pushed, err := s.Sync(context.Background(), owner.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pulled != 1 || pushed != 1 {
+		t.Errorf("pulled %d & pushed %d entries, want 1 & 1", pulled, pushed)
+	}
+
+	for _, entry := range []string{"10.0.0.1:13414", "10.0.0.2:13414", "10.0.1.0/24"} {
+		if !local[entry] || !remote[entry] {
+			t.Errorf("%s is not banned by both nodes", entry)
+		}
+	}
+
+	// the synced lists are left as is
+	if pulled, pushed, err := s.Sync(context.Background(), owner.URL); err != nil || pulled != 0 || pushed != 0 {
+		t.Errorf("resync pulled %d & pushed %d entries: %v", pulled, pushed, err)
+	}
+
+	s = NewSyncer([]string{owner.URL}, "invalid", time.Hour, local)
+	if _, _, err := s.Sync(context.Background(), owner.URL); err == nil {
+		t.Errorf("sync with the invalid secret returned no error")
+	}
+}
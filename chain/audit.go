@@ -0,0 +1,141 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+)
+
+// ErrInflation is the UTXO set commitments not matching the kernel excesses,
+// the kernel offset & the emission: the coins were created or destroyed
+var ErrInflation = errors.New("utxo set doesn't match the kernels & the emission")
+
+// Supply is the supply audit of the chain
+type Supply struct {
+	Height uint64
+	Head   consensus.Hash
+	// Outputs is the count of the unspent outputs, Kernels is the count of
+	// all the kernels
+	Outputs int
+	Kernels int
+	// Emission is the expected emission at Height, the genesis is rewarded
+	// if it has the coinbase output
+	Emission uint64
+	// UTXOSum is the sum of the unspent output commitments, KernelSum is the
+	// sum of the kernel excesses, the kernel offset & the emission, both are
+	// nil if there is nothing to sum
+	UTXOSum   *bulletproofs.Point
+	KernelSum *bulletproofs.Point
+}
+
+// Valid returns true if no coins were created or destroyed
+func (s *Supply) Valid() bool {
+	return equalPoints(s.UTXOSum, s.KernelSum)
+}
+
+// Audit walks the main chain from the genesis to the head building the UTXO
+// set, the utxo commitments must sum to the kernel excesses, the total kernel
+// offset & the expected emission:
+// sum(utxo) = sum(excesses) + offset*G + emission*H.
+// The failed sum returns the supply & ErrInflation
+func (c *Chain) Audit(ctx context.Context) (*Supply, error) {
+	if c.headersOnly {
+		return nil, errors.New("the headers only chain has no blocks to audit")
+	}
+
+	head := c.Head()
+
+	supply := Supply{
+		Height: head.Header.Height,
+		Head:   head.Hash(),
+	}
+
+	utxo := make(map[string]*bulletproofs.Point)
+	var excesses *bulletproofs.Point
+
+	for height := c.genesis.Header.Height; height <= head.Header.Height; height++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		block := c.genesis
+		if height != c.genesis.Header.Height {
+			h := height
+			if block = c.storage.GetBlock(consensus.BlockID{Height: &h}); block == nil {
+				return nil, fmt.Errorf("block %d is missing", height)
+			}
+		}
+
+		if height != c.genesis.Header.Height || len(block.Outputs) > 0 {
+			supply.Emission += consensus.Reward
+		}
+
+		for _, input := range block.Inputs {
+			key := string(input.Commit)
+			if _, ok := utxo[key]; !ok {
+				return nil, fmt.Errorf("block %d spends unknown output %x", height, []byte(input.Commit))
+			}
+			delete(utxo, key)
+		}
+
+		for i := range block.Outputs {
+			key := string(block.Outputs[i].Commit.Bytes())
+			if _, ok := utxo[key]; ok {
+				return nil, fmt.Errorf("block %d duplicates output %x", height, []byte(key))
+			}
+			utxo[key] = block.Outputs[i].Commit
+		}
+
+		for i := range block.Kernels {
+			excesses = addPoints(excesses, &block.Kernels[i].Excess)
+		}
+		supply.Kernels += len(block.Kernels)
+	}
+
+	supply.Outputs = len(utxo)
+	for _, commit := range utxo {
+		supply.UTXOSum = addPoints(supply.UTXOSum, commit)
+	}
+
+	supply.KernelSum = excesses
+	if offset := new(big.Int).SetBytes(head.Header.TotalKernelOffset[:]); offset.Sign() != 0 {
+		supply.KernelSum = addPoints(supply.KernelSum, bulletproofs.ScalarMulPoint(&secp256k1zkp.G, offset))
+	}
+
+	if supply.Emission > 0 {
+		emission := new(big.Int).SetUint64(supply.Emission)
+		supply.KernelSum = addPoints(supply.KernelSum, bulletproofs.ScalarMulPoint(&secp256k1zkp.H, emission))
+	}
+
+	if !supply.Valid() {
+		return &supply, ErrInflation
+	}
+
+	return &supply, nil
+}
+
+// addPoints returns a + b, a may be nil
+func addPoints(a, b *bulletproofs.Point) *bulletproofs.Point {
+	if a == nil {
+		return b
+	}
+
+	return bulletproofs.SumPoints(a, b)
+}
+
+// equalPoints returns true if the points are equal or both nil
+func equalPoints(a, b *bulletproofs.Point) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/testutil"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+	"testing"
+)
+
+// commitOutput returns the output committing to value by blind
+func commitOutput(features consensus.OutputFeatures, blind, value int64) consensus.Output {
+	return consensus.Output{
+		Features: features,
+		Commit:   secp256k1zkp.CommitValue(big.NewInt(blind), big.NewInt(value)),
+	}
+}
+
+// excessKernel returns the kernel of the excess blind*G
+func excessKernel(fee uint64, blind int64) consensus.TxKernel {
+	return consensus.TxKernel{
+		Fee:    fee,
+		Excess: *bulletproofs.ScalarMulPoint(&secp256k1zkp.G, big.NewInt(blind)),
+	}
+}
+
+// auditChain returns the chain of the coinbase block & the block spending
+// its output, the second coinbase is of the extra value
+func auditChain(t *testing.T, extra int64) *Chain {
+	chain, _ := newTestChain()
+
+	reward := int64(consensus.Reward)
+	const fee = 8
+
+	first := child(&Testnet4, 1)
+	first.Outputs = consensus.OutputList{commitOutput(consensus.CoinbaseOutput, 3, reward)}
+	first.Kernels = consensus.TxKernelList{excessKernel(0, 3)}
+
+	second := child(first, 1)
+	second.Inputs = consensus.InputList{{Features: consensus.CoinbaseOutput, Commit: first.Outputs[0].Commit.Bytes()}}
+	second.Outputs = consensus.OutputList{
+		commitOutput(consensus.DefaultOutput, 10, reward-fee),
+		commitOutput(consensus.CoinbaseOutput, 7, reward+fee+extra),
+	}
+	second.Kernels = consensus.TxKernelList{excessKernel(fee, 10-3), excessKernel(0, 7)}
+
+	for _, block := range []*consensus.Block{first, second} {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	return chain
+}
+
+func TestAudit(t *testing.T) {
+	supply, err := auditChain(t, 0).Audit(context.Background())
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+
+	if supply.Height != 2 || supply.Outputs != 2 || supply.Kernels != 3 || supply.Emission != 2*consensus.Reward {
+		t.Errorf("supply was %+v", supply)
+	}
+
+	if !supply.Valid() {
+		t.Error("supply isn't valid")
+	}
+}
+
+func TestAuditGeneratedChain(t *testing.T) {
+	chain, _ := newTestChain()
+
+	g := testutil.New(&Testnet4, 1)
+	blocks, err := g.Chain(&Testnet4, 2, testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spend, err := g.Block(blocks[1], testutil.Options{Spends: 2, Fee: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range append(blocks, spend) {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	supply, err := chain.Audit(context.Background())
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+
+	if supply.Outputs != len(g.Unspent(spend)) || supply.Kernels != 4 || !supply.Valid() {
+		t.Errorf("supply was %+v", supply)
+	}
+}
+
+func TestAuditInflation(t *testing.T) {
+	supply, err := auditChain(t, 1).Audit(context.Background())
+	if !errors.Is(err, ErrInflation) {
+		t.Fatalf("error was %v, want %v", err, ErrInflation)
+	}
+
+	if supply == nil || supply.Valid() {
+		t.Error("inflated supply is valid")
+	}
+}
+
+func TestAuditMissingBlock(t *testing.T) {
+	chain := auditChain(t, 0)
+	chain.storage = newMemStorage()
+
+	if _, err := chain.Audit(context.Background()); err == nil {
+		t.Error("chain of the missing blocks was audited")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/wire"
+	"io"
+)
+
+// backupMagic starts the chain backup, backupVersion is the format of the
+// blocks following it
+var backupMagic = [8]byte{'g', 'r', 'i', 'n', 'g', 'o', 'b', 'k'}
+
+const backupVersion = 1
+
+// Snapshot is the main chain of the head at the time of the snapshot. The
+// stored blocks are immutable, so the snapshot is written while the chain
+// grows
+type Snapshot struct {
+	genesis consensus.Hash
+	// blocks is the main chain after the genesis, from the lowest
+	blocks []*consensus.Block
+}
+
+// Snapshot returns the snapshot of the main chain, the chain is locked while
+// the blocks are collected only
+func (c *Chain) Snapshot() (*Snapshot, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	snapshot := &Snapshot{
+		genesis: c.genesis.Hash(),
+		blocks:  make([]*consensus.Block, c.head.Header.Height-c.genesis.Header.Height),
+	}
+
+	block := c.head
+	for i := len(snapshot.blocks) - 1; i >= 0; i-- {
+		snapshot.blocks[i] = block
+
+		height := block.Header.Height - 1
+		if i > 0 {
+			block = c.storage.GetBlock(consensus.BlockID{Hash: block.Header.Previous, Height: &height})
+			if block == nil {
+				return nil, fmt.Errorf("block %d of the main chain is missing", height)
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// Height returns the height of the snapshot head
+func (s *Snapshot) Height() uint64 {
+	if len(s.blocks) == 0 {
+		return 0
+	}
+
+	return s.blocks[len(s.blocks)-1].Header.Height
+}
+
+// WriteTo writes the backup of the snapshot: the magic, the version, the
+// genesis hash & the blocks
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	n, err := wire.WriteTo(w, func(buf *bytes.Buffer) {
+		buf.Write(backupMagic[:])
+		wire.WriteUint16(buf, backupVersion)
+		buf.Write(s.genesis[:])
+		wire.WriteUint64(buf, uint64(len(s.blocks)))
+	})
+	if err != nil {
+		return n, err
+	}
+
+	for _, block := range s.blocks {
+		written, err := block.WriteTo(w)
+		n += written
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Restore processes the blocks of the backup of the chain of the same
+// genesis, the blocks are validated as the blocks of the peers & the known
+// ones are skipped. Returns the count of the processed blocks
+func (c *Chain) Restore(ctx context.Context, r io.Reader) (int, error) {
+	var magic [len(backupMagic)]byte
+	if err := wire.ReadBytes(r, magic[:]); err != nil {
+		return 0, err
+	}
+
+	if magic != backupMagic {
+		return 0, errors.New("not a chain backup")
+	}
+
+	version, err := wire.ReadUint16(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if version != backupVersion {
+		return 0, fmt.Errorf("unsupported backup version %d", version)
+	}
+
+	var genesis consensus.Hash
+	if err := wire.ReadBytes(r, genesis[:]); err != nil {
+		return 0, err
+	}
+
+	if genesis != c.genesis.Hash() {
+		return 0, fmt.Errorf("backup of the other genesis %s", genesis)
+	}
+
+	count, err := wire.ReadUint64(r)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := uint64(0); i < count; i++ {
+		var block consensus.Block
+		if err := block.Read(r); err != nil {
+			return processed, err
+		}
+
+		if c.storage.GetBlock(consensus.BlockID{Hash: block.Hash()}) != nil {
+			continue
+		}
+
+		if err := c.ProcessBlock(ctx, &block); err != nil {
+			return processed, fmt.Errorf("block %d: %w", block.Header.Height, err)
+		}
+		processed++
+	}
+
+	return processed, nil
+}
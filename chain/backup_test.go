@@ -0,0 +1,87 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	chain, _ := newTestChain()
+
+	parent := &Testnet4
+	for i := 0; i < 5; i++ {
+		block := child(parent, 1)
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+		parent = block
+	}
+
+	snapshot, err := chain.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	if snapshot.Height() != 5 {
+		t.Errorf("snapshot height was %d, want 5", snapshot.Height())
+	}
+
+	// the blocks processed after the snapshot are not backed up
+	if err := chain.ProcessBlock(context.Background(), child(parent, 1)); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := snapshot.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	backup := buf.Bytes()
+
+	restored, _ := newTestChain()
+	n, err := restored.Restore(context.Background(), bytes.NewReader(backup))
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if head := restored.Head(); n != 5 || head.Hash() != parent.Hash() {
+		t.Errorf("restored %d blocks of head %d, want 5 of head 5", n, head.Header.Height)
+	}
+
+	// the known blocks are skipped
+	if n, err := restored.Restore(context.Background(), bytes.NewReader(backup)); err != nil || n != 0 {
+		t.Errorf("restored %d known blocks: %v", n, err)
+	}
+
+	if restored.Height() != 5 {
+		t.Errorf("height was %d, want 5", restored.Height())
+	}
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	chain, _ := newTestChain()
+
+	snapshot, err := chain.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := snapshot.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	other := New(&Testnet1, newMemStorage())
+	if _, err := other.Restore(context.Background(), bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("backup of the other genesis was restored")
+	}
+
+	garbage := make([]byte, buf.Len())
+	if _, err := chain.Restore(context.Background(), bytes.NewReader(garbage)); err == nil {
+		t.Error("garbage was restored")
+	}
+}
@@ -6,9 +6,13 @@ package chain
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"io"
+	"runtime"
 	"sync"
 	"time"
 )
@@ -18,13 +22,13 @@ var Testnet1 = consensus.Block{
 	Header: consensus.BlockHeader{
 		Version:         1,
 		Height:          0,
-		Previous:        bytes.Repeat([]byte{0xff}, consensus.BlockHashSize),
+		Previous:        consensus.MaxHash,
 		Timestamp:       time.Date(2017, 11, 16, 20, 0, 0, 0, time.UTC),
 		TotalDifficulty: 10,
 
-		UTXORoot:       bytes.Repeat([]byte{0x00}, 32),
-		RangeProofRoot: bytes.Repeat([]byte{0x00}, 32),
-		KernelRoot:     bytes.Repeat([]byte{0x00}, 32),
+		UTXORoot:       consensus.ZeroHash,
+		RangeProofRoot: consensus.ZeroHash,
+		KernelRoot:     consensus.ZeroHash,
 
 		Nonce: 28205,
 		POW: consensus.Proof{
@@ -46,14 +50,14 @@ var Testnet2 = consensus.Block{
 	Header: consensus.BlockHeader{
 		Version:   1,
 		Height:    0,
-		Previous:  bytes.Repeat([]byte{0xff}, consensus.BlockHashSize),
+		Previous:  consensus.MaxHash,
 		Timestamp: time.Date(2017, 11, 16, 20, 0, 0, 0, time.UTC),
 		//Difficulty:      10,
 		//TotalDifficulty: 10,
 
-		UTXORoot:       bytes.Repeat([]byte{0x00}, 32),
-		RangeProofRoot: bytes.Repeat([]byte{0x00}, 32),
-		KernelRoot:     bytes.Repeat([]byte{0x00}, 32),
+		UTXORoot:       consensus.ZeroHash,
+		RangeProofRoot: consensus.ZeroHash,
+		KernelRoot:     consensus.ZeroHash,
 
 		Nonce: 70081,
 		POW: consensus.Proof{
@@ -78,15 +82,15 @@ var Testnet3 = consensus.Block{
 	Header: consensus.BlockHeader{
 		Version:         1,
 		Height:          0,
-		Previous:        bytes.Repeat([]byte{0xff}, consensus.BlockHashSize),
+		Previous:        consensus.MaxHash,
 		Timestamp:       time.Date(2018, 7, 8, 18, 0, 0, 0, time.UTC),
 		TotalDifficulty: TESTNET3_INITIAL_DIFFICULTY,
 
-		UTXORoot:       bytes.Repeat([]byte{0x00}, 32),
-		RangeProofRoot: bytes.Repeat([]byte{0x00}, 32),
-		KernelRoot:     bytes.Repeat([]byte{0x00}, 32),
+		UTXORoot:       consensus.ZeroHash,
+		RangeProofRoot: consensus.ZeroHash,
+		KernelRoot:     consensus.ZeroHash,
 
-		TotalKernelOffset: bytes.Repeat([]byte{0x00}, 32),
+		TotalKernelOffset: consensus.ZeroHash,
 		TotalKernelSum:    bytes.Repeat([]byte{0x00}, 33),
 
 		Nonce: 4956988373127691,
@@ -114,15 +118,15 @@ var Testnet4 = consensus.Block{
 	Header: consensus.BlockHeader{
 		Version:         1,
 		Height:          0,
-		Previous:        bytes.Repeat([]byte{0xff}, consensus.BlockHashSize),
+		Previous:        consensus.MaxHash,
 		Timestamp:       time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC),
 		TotalDifficulty: testnet4InitialDifficulty,
 
-		UTXORoot:       bytes.Repeat([]byte{0x00}, 32),
-		RangeProofRoot: bytes.Repeat([]byte{0x00}, 32),
-		KernelRoot:     bytes.Repeat([]byte{0x00}, 32),
+		UTXORoot:       consensus.ZeroHash,
+		RangeProofRoot: consensus.ZeroHash,
+		KernelRoot:     consensus.ZeroHash,
 
-		TotalKernelOffset: bytes.Repeat([]byte{0x00}, 32),
+		TotalKernelOffset: consensus.ZeroHash,
 		TotalKernelSum:    bytes.Repeat([]byte{0x00}, 33),
 
 		Nonce: 8612241555342799290,
@@ -145,13 +149,13 @@ var Mainnet = consensus.Block{
 	Header: consensus.BlockHeader{
 		Version:         1,
 		Height:          0,
-		Previous:        bytes.Repeat([]byte{0xff}, consensus.BlockHashSize),
+		Previous:        consensus.MaxHash,
 		Timestamp:       time.Date(2018, 8, 14, 0, 0, 0, 0, time.UTC),
 		TotalDifficulty: 1000,
 
-		UTXORoot:       bytes.Repeat([]byte{0x00}, 32),
-		RangeProofRoot: bytes.Repeat([]byte{0x00}, 32),
-		KernelRoot:     bytes.Repeat([]byte{0x00}, 32),
+		UTXORoot:       consensus.ZeroHash,
+		RangeProofRoot: consensus.ZeroHash,
+		KernelRoot:     consensus.ZeroHash,
 
 		Nonce: 28205,
 		POW: consensus.Proof{
@@ -168,6 +172,28 @@ var Mainnet = consensus.Block{
 	},
 }
 
+var (
+	// ErrOrphan is the block of the unknown previous block, the block is
+	// not added
+	ErrOrphan = errors.New("orphan block")
+
+	// ErrInvalidTotalDifficulty is the block total difficulty not matching
+	// the previous block
+	ErrInvalidTotalDifficulty = errors.New("wrong block total difficulty")
+
+	// ErrDifficultyTooLow is the block difficulty below the next difficulty
+	// of the previous blocks
+	ErrDifficultyTooLow = errors.New("difficulty is too low")
+
+	// ErrInvalidPrevious is the header not following the previous header by
+	// the hash or height
+	ErrInvalidPrevious = errors.New("header doesn't follow the previous header")
+
+	// ErrInvalidHeaderRoot is the header previous root not matching the
+	// header MMR of the previous headers
+	ErrInvalidHeaderRoot = errors.New("header previous root doesn't match the header mmr")
+)
+
 type Chain struct {
 	sync.RWMutex
 
@@ -182,28 +208,219 @@ type Chain struct {
 	height uint64
 	// current total difficulty
 	totalDifficulty consensus.Difficulty
+	// the valid header of the most total difficulty seen
+	headerHead consensus.BlockHeader
+
+	// params is the consensus parameters of the network
+	params *consensus.Params
+	// validate checks the block by consensus rules
+	validate func(ctx context.Context, block *consensus.Block) error
+	// streaming is set if the consensus rules are checked by ReadBlock,
+	// the replaced validation is left to ProcessBlock
+	streaming bool
+	// validateHeader checks the header & its proof of work
+	validateHeader func(header *consensus.BlockHeader) error
+	// headerWorkers is the count of the proof of work verifiers of the
+	// headers batch
+	headerWorkers int
+
+	// intentFile keeps the intent of the storage change being applied, empty
+	// if the intents aren't recorded
+	intentFile string
+
+	// headersOnly is set if the chain keeps the headers without the block
+	// bodies, the headers extend the head
+	headersOnly bool
+	// headerMMR is the MMR of the head & previous header hashes
+	headerMMR consensus.MMR
+	// window is the difficulty window of the head, it saves the storage
+	// reads of the next difficulty
+	window difficultyWindow
+	// stats is the rolling statistics window of the head
+	stats statsWindow
+	// progress of the head since the last summary line
+	progress progress
+
+	// process is processBlock wrapped by the middlewares
+	process BlockHandler
+
+	// invalid is the block hashes marked invalid by the operator
+	imu     sync.RWMutex
+	invalid map[consensus.Hash]bool
+
+	// competing is the siblings of the head of its total difficulty, the
+	// first seen first
+	competing []competingTip
+
+	// subscribers of the new head blocks & the reorgs, the recent reorgs
+	smu              sync.Mutex
+	subscribers      map[chan<- *consensus.Block]struct{}
+	reorgSubscribers map[chan<- *ReorgEvent]struct{}
+	reorgs           []*ReorgEvent
+
+	// chain statistics
+	metrics *Metrics
+
+	log logging.Logger
 }
 
 func New(genesis *consensus.Block, storage Storage) *Chain {
 	chain := Chain{
-		storage:         storage,
-		genesis:         genesis,
-		head:            genesis,
-		height:          genesis.Header.Height,
-		totalDifficulty: genesis.Header.TotalDifficulty,
+		storage:          storage,
+		genesis:          genesis,
+		head:             genesis,
+		height:           genesis.Header.Height,
+		totalDifficulty:  genesis.Header.TotalDifficulty,
+		params:           &consensus.TestnetParams,
+		subscribers:      make(map[chan<- *consensus.Block]struct{}),
+		reorgSubscribers: make(map[chan<- *ReorgEvent]struct{}),
+		log:              logging.Default(logging.Chain),
 	}
+	chain.validate = chain.validateBlock
+	chain.process = chain.processBlock
+	chain.streaming = true
+	chain.validateHeader = chain.validateHeaderPOW
+	chain.headerWorkers = runtime.GOMAXPROCS(0)
+	chain.metrics = newMetrics(&chain)
 
 	// init state from storage
-	// setting up currents: height, total diff & blockHashChain
-	if lastBlock := storage.GetLastBlock(); lastBlock != nil {
-		chain.head = lastBlock
-		chain.totalDifficulty = lastBlock.Header.TotalDifficulty
-		chain.height = lastBlock.Header.Height
-	}
+	chain.loadHead()
 
 	return &chain
 }
 
+// loadHead sets the head, height & total difficulty to the last block of
+// storage, the genesis of the empty storage
+func (c *Chain) loadHead() {
+	c.head = c.genesis
+	if lastBlock := c.storage.GetLastBlock(); lastBlock != nil {
+		c.head = lastBlock
+	}
+
+	c.height = c.head.Header.Height
+	c.totalDifficulty = c.head.Header.TotalDifficulty
+	c.headerHead = c.head.Header
+}
+
+// SetLogger replaces the chain logger, logging.Nop disables the logging
+func (c *Chain) SetLogger(logger logging.Logger) {
+	c.log = logger
+}
+
+// SetParams replaces the consensus parameters, New uses the testnet ones
+func (c *Chain) SetParams(params *consensus.Params) {
+	c.params = params
+	c.window.reset()
+}
+
+// Params returns the consensus parameters of the chain
+func (c *Chain) Params() *consensus.Params {
+	return c.params
+}
+
+// validateBlock checks the block by the consensus rules of the chain params
+func (c *Chain) validateBlock(ctx context.Context, block *consensus.Block) error {
+	return block.Validate(ctx, c.params)
+}
+
+// validateHeaderPOW checks the header & its proof of work by the consensus
+// params of the chain, the cuckoo cycle is verified after the cheap checks
+// only
+func (c *Chain) validateHeaderPOW(header *consensus.BlockHeader) error {
+	if err := header.PreValidate(c.params); err != nil {
+		c.metrics.rejectHeader("precheck")
+		return err
+	}
+
+	if err := header.ValidatePOW(); err != nil {
+		c.metrics.rejectHeader("pow")
+		return err
+	}
+
+	return nil
+}
+
+// SetValidator replaces the block-scope consensus validation, simnet uses it
+// to accept blocks without the proof of work
+func (c *Chain) SetValidator(validate func(ctx context.Context, block *consensus.Block) error) {
+	c.validate = validate
+	c.streaming = false
+}
+
+// SetHeaderValidator replaces the header & proof of work validation, simnet
+// uses it to accept the announced headers without the proof of work
+func (c *Chain) SetHeaderValidator(validate func(header *consensus.BlockHeader) error) {
+	c.validateHeader = validate
+}
+
+// SetHeaderWorkers sets the count of the proof of work verifiers of the
+// headers batch, GOMAXPROCS if n isn't positive
+func (c *Chain) SetHeaderWorkers(n int) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	c.headerWorkers = n
+}
+
+// SetHeadersOnly switches the chain to keep the headers only: the headers
+// extending the head are stored as the blocks without bodies & the block
+// bodies of the peers are skipped. The header MMR is rebuilt from storage
+func (c *Chain) SetHeadersOnly() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.headersOnly = true
+	c.headerMMR = consensus.MMR{}
+	c.headerMMR.Append(c.genesis.Hash().Bytes())
+
+	for height := c.genesis.Header.Height + 1; height <= c.height; height++ {
+		h := height
+		block := c.storage.GetBlock(consensus.BlockID{Height: &h})
+		if block == nil {
+			c.log.Errorf("header %d is missing, the header mmr is incomplete", height)
+			return
+		}
+
+		c.headerMMR.Append(block.Hash().Bytes())
+	}
+}
+
+// HeadersOnly returns true if the chain keeps the headers only
+func (c *Chain) HeadersOnly() bool {
+	return c.headersOnly
+}
+
+// HeaderRoot returns the root of the header MMR of the head & previous
+// headers, nil unless the chain keeps the headers only
+func (c *Chain) HeaderRoot() consensus.Hash {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.headerMMR.Root()
+}
+
+// ReadBlock reads the block received from the peer. The block is validated
+// by the consensus rules while decoded, the decoding is stopped on the first
+// violation & ProcessBlock doesn't validate the block again
+func (c *Chain) ReadBlock(ctx context.Context, r io.Reader, block *consensus.Block) error {
+	// the body isn't read at all
+	if c.headersOnly {
+		return block.Header.Read(r)
+	}
+
+	if !c.streaming {
+		return block.Read(r)
+	}
+
+	return block.ReadValidate(ctx, r, c.params)
+}
+
+// Metrics returns the prometheus collector of the chain statistics
+func (c *Chain) Metrics() *Metrics {
+	return c.metrics
+}
+
 // Genesis returns genesis block
 func (c *Chain) Genesis() consensus.Block {
 	return *c.genesis
@@ -211,11 +428,17 @@ func (c *Chain) Genesis() consensus.Block {
 
 // TotalDifficulty returns current total difficulty
 func (c *Chain) TotalDifficulty() consensus.Difficulty {
+	c.RLock()
+	defer c.RUnlock()
+
 	return c.totalDifficulty
 }
 
 // Height returns current height
 func (c *Chain) Height() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+
 	return c.height
 }
 
@@ -223,7 +446,7 @@ func (c *Chain) Height() uint64 {
 func (c *Chain) GetBlockHeaders(loc consensus.Locator) []consensus.BlockHeader {
 	// for safety
 	if len(loc.Hashes) > consensus.MaxLocators {
-		logrus.Error("locator hashes object is too big")
+		c.log.Error("locator hashes object is too big")
 		loc.Hashes = loc.Hashes[:consensus.MaxLocators]
 	}
 
@@ -234,7 +457,7 @@ func (c *Chain) GetBlockHeaders(loc consensus.Locator) []consensus.BlockHeader {
 	for _, hash := range loc.Hashes {
 
 		// if hash is head of current chain, return empty result
-		if bytes.Compare(hash, c.head.Hash()) == 0 {
+		if hash == c.head.Hash() {
 			return result
 		}
 
@@ -261,9 +484,34 @@ func (c *Chain) GetBlockHeaders(loc consensus.Locator) []consensus.BlockHeader {
 	return result
 }
 
-// GetBlock returns block by hash, if not found returns nil, nil
+// Locator returns the hashes of the head & the headers back from it by the
+// doubling distances, the genesis hash is the last. The peer returns the
+// headers after the first hash known to it
+func (c *Chain) Locator() consensus.Locator {
+	c.RLock()
+	defer c.RUnlock()
+
+	loc := consensus.Locator{Hashes: []consensus.Hash{c.head.Hash()}}
+	if c.height == c.genesis.Header.Height {
+		return loc
+	}
+
+	for offset := uint64(1); offset < c.height-c.genesis.Header.Height && len(loc.Hashes) < consensus.MaxLocators-1; offset *= 2 {
+		height := c.height - offset
+		if block := c.storage.GetBlock(consensus.BlockID{Height: &height}); block != nil {
+			loc.Hashes = append(loc.Hashes, block.Hash())
+		}
+	}
+
+	loc.Hashes = append(loc.Hashes, c.genesis.Hash())
+
+	return loc
+}
+
+// GetBlock returns block by hash, if not found returns nil, nil. The chain
+// keeping the headers only has no blocks
 func (c *Chain) GetBlock(hash consensus.Hash) *consensus.Block {
-	if hash == nil {
+	if hash.IsZero() || c.headersOnly {
 		return nil
 	}
 
@@ -278,86 +526,449 @@ func (c *Chain) GetBlockID(b consensus.BlockID) *consensus.Block {
 	return c.storage.GetBlock(b)
 }
 
-func (c *Chain) ProcessHeaders(headers []consensus.BlockHeader) error {
-	for _, header := range headers {
-		if err := header.Validate(); err != nil {
+// GetUnspentOutput returns id of the block with the unspent output by
+// commitment, if not found returns nil
+func (c *Chain) GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID {
+	return c.storage.GetUnspentOutput(commit)
+}
+
+// GetKernel returns id of the block with the kernel by excess within the
+// height range, if not found returns nil
+func (c *Chain) GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID {
+	return c.storage.GetKernel(excess, minHeight, maxHeight)
+}
+
+// ProcessHeaders validates the headers & updates the header head. The proofs
+// of work of the batch are verified by the headerWorkers, the linkage & total
+// difficulty of the headers sequentially. The first header of the unknown
+// previous header returns ErrOrphan, the validation is stopped on the ctx
+// cancellation
+func (c *Chain) ProcessHeaders(ctx context.Context, headers []consensus.BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	result := "rejected"
+	start := time.Now()
+	defer c.metrics.observeHeaders(&result, len(headers), start)
+
+	// the links are checked before the proofs of work: the total difficulty
+	// of the header of the forged proof doesn't match the proof hash
+	for i := 1; i < len(headers); i++ {
+		if err := verifyLink(&headers[i-1], &headers[i]); err != nil {
+			c.metrics.rejectHeader("link")
+			return err
+		}
+	}
+
+	if err := c.checkInvalidHeaders(headers); err != nil {
+		c.metrics.rejectHeader("invalidated")
+		return err
+	}
+
+	if err := c.verifyHeaders(ctx, headers); err != nil {
+		return err
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	prev := c.previousHeader(&headers[0])
+	if prev == nil {
+		result = "orphan"
+		return ErrOrphan
+	}
+
+	if err := verifyLink(prev, &headers[0]); err != nil {
+		return err
+	}
+
+	if c.headersOnly {
+		if err := c.extendHeaders(ctx, headers); err != nil {
+			return err
+		}
+	}
+
+	if last := headers[len(headers)-1]; last.TotalDifficulty >= c.headerHead.TotalDifficulty {
+		c.headerHead = last
+	}
+	result = "accepted"
+
+	last := &headers[len(headers)-1]
+	c.log.WithFields(logging.Fields{
+		"height":   last.Height,
+		"hash":     last.Hash().String(),
+		"count":    len(headers),
+		"duration": time.Since(start),
+	}).Debug("headers accepted")
+	c.logProgress(0, len(headers))
+
+	return nil
+}
+
+// extendHeaders adds the linked headers extending the head to the chain of
+// the headers only, the known headers are skipped. The difficulty & the
+// previous root of the header MMR are checked
+func (c *Chain) extendHeaders(ctx context.Context, headers []consensus.BlockHeader) error {
+	intended := false
+	for i := range headers {
+		header := headers[i]
+
+		if header.Height <= c.height {
+			// the header is known or of the fork-chain
+			// TODO: process the headers of the fork-chains
+			continue
+		}
+
+		if c.head.Hash() != header.Previous {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.verifyDifficulty(&header); err != nil {
+			return err
+		}
+
+		if header.PreviousRoot != c.headerMMR.Root() {
+			return ErrInvalidHeaderRoot
+		}
+
+		// the intent of the first header rolls back the batch
+		block := &consensus.Block{Header: header}
+		if !intended {
+			if err := c.beginIntent(opAddBlocks, block); err != nil {
+				return err
+			}
+			intended = true
+			defer c.endIntent()
+		}
+
+		c.storage.AddBlock(block)
+		c.headerMMR.Append(block.Hash().Bytes())
+		c.window.push(&header, c.params.DifficultyAdjustWindow+consensus.MedianTimeWindow)
+		c.head = block
+		c.height = header.Height
+		c.totalDifficulty = header.TotalDifficulty
+		if c.totalDifficulty >= c.headerHead.TotalDifficulty {
+			c.headerHead = header
+		}
+		c.notify(block)
+	}
+
+	return nil
+}
+
+// verifyDifficulty checks the header difficulty is not less than the next
+// difficulty of the previous blocks, the header difficulty is set to the one
+// of its proof
+func (c *Chain) verifyDifficulty(header *consensus.BlockHeader) error {
+	// the difficulty is not serialized, it is the difficulty of the proof
+	header.Difficulty = header.POW.ToDifficulty()
+
+	diffAvg := c.params.NextDifficulty(c.difficultyIter(header))
+	if header.Difficulty < diffAvg {
+		return ErrDifficultyTooLow
+	}
+
+	return nil
+}
+
+// verifyHeaders validates the headers & their proofs of work by the pool of
+// headerWorkers, the first failure stops the pool
+func (c *Chain) verifyHeaders(ctx context.Context, headers []consensus.BlockHeader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := c.headerWorkers
+	if workers > len(headers) {
+		workers = len(headers)
+	}
+
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				if err := c.validateHeader(&headers[j]); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range headers {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+// previousHeader returns the known header previous to the header: the header
+// head, the head or the stored block header, nil if it's unknown
+func (c *Chain) previousHeader(header *consensus.BlockHeader) *consensus.BlockHeader {
+	if c.headerHead.Hash() == header.Previous {
+		return &c.headerHead
+	}
+
+	if c.head.Hash() == header.Previous {
+		return &c.head.Header
+	}
+
+	if header.Height == 0 {
+		return nil
+	}
+
+	height := header.Height - 1
+	if block := c.storage.GetBlock(consensus.BlockID{Hash: header.Previous, Height: &height}); block != nil {
+		return &block.Header
+	}
+
+	return nil
+}
+
+// ValidateHeader checks the header of the peer & its proof of work, the
+// header must follow prev unless prev is nil, the link is checked before the
+// proof of work. The headers of the peer are
+// validated one by one while decoded, so the invalid batch is dropped on the
+// first violation before the rest is read
+func (c *Chain) ValidateHeader(prev, header *consensus.BlockHeader) error {
+	if prev != nil {
+		if err := verifyLink(prev, header); err != nil {
+			c.metrics.rejectHeader("link")
 			return err
 		}
 	}
+
+	return c.validateHeader(header)
+}
+
+// verifyLink checks the header follows prev by the hash, height, timestamp &
+// total difficulty
+func verifyLink(prev, header *consensus.BlockHeader) error {
+	if header.Previous != prev.Hash() || header.Height != prev.Height+1 {
+		return ErrInvalidPrevious
+	}
+
+	// - previous Timestamp MUST BE less block.Header.Timestamp
+	if !header.Timestamp.After(prev.Timestamp) {
+		return consensus.ErrInvalidBlockTime
+	}
+
+	// - block.TotalDiff MUST BE == previous.TotalDiff + previous.POW.ToDifficulty()
+	if header.TotalDifficulty != prev.TotalDifficulty.Add(prev.POW.ToDifficulty()) {
+		return ErrInvalidTotalDifficulty
+	}
+
 	return nil
 }
 
-func (c *Chain) ProcessBlock(block *consensus.Block) error {
+// HeaderHead returns the valid header of the most total difficulty seen, the
+// header chain is ahead of the blocks while syncing
+func (c *Chain) HeaderHead() consensus.BlockHeader {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.headerHead
+}
+
+// ProcessBlock validates the block & adds it on top of the chain, the block
+// is not added if ctx is cancelled before the validation is done. The block
+// of the unknown previous block returns ErrOrphan, the sibling of the head is
+// kept as the competing tip & its child switches the chain to it. The chain
+// of the headers only processes the block header. The block passes the
+// middlewares first
+func (c *Chain) ProcessBlock(ctx context.Context, block *consensus.Block) error {
+	return c.process(ctx, block)
+}
+
+// processBlock is the innermost block handler of ProcessBlock
+func (c *Chain) processBlock(ctx context.Context, block *consensus.Block) error {
+	if c.headersOnly {
+		return c.ProcessHeaders(ctx, []consensus.BlockHeader{block.Header})
+	}
+
 	// before locking storage on change MUST lock the Chain
 	// Checking existing block
 	c.Lock()
 	defer c.Unlock()
-	logrus.Infof("processing block (height: %d, totalDiff: %d)", block.Header.Height, block.Header.TotalDifficulty)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	log := c.log.WithFields(logging.Fields{
+		"height": block.Header.Height,
+		"hash":   block.Hash().String(),
+	})
+	log.Debug("processing block")
+
+	result := "rejected"
+	start := time.Now()
+	defer c.metrics.observeBlock(&result, start)
 
 	// quick check is it current tip
-	if bytes.Compare(c.head.Hash(), block.Hash()) == 0 {
+	if c.head.Hash() == block.Hash() {
 		// the block is exists
+		result = "known"
 		return nil
 	}
 
+	if err := c.checkInvalid(block.Hash()); err != nil {
+		log.Warn("block marked invalid rejected")
+		return err
+	}
+
 	// verify block by consensus rules
-	if err := block.Validate(); err != nil {
+	if err := c.validate(ctx, block); err != nil {
 		return err
 	}
 
-	logrus.Info("getting the previous blocks")
 	// Get the previous block
 	prevHeight := block.Header.Height - 1
 	prevBlockID := consensus.BlockID{
 		Hash:   block.Header.Previous,
 		Height: &prevHeight,
 	}
-	prevBlock := c.storage.GetBlock(prevBlockID)
+	prevBlock := c.head
+	if c.head.Hash() != block.Header.Previous {
+		prevBlock = c.storage.GetBlock(prevBlockID)
+	}
+	if prevBlock == nil {
+		prevBlock = c.competingBlock(block.Header.Previous)
+	}
+
 	if prevBlock == nil {
-		logrus.Info("no previous blocks")
+		log.Debug("orphan block")
 		// No previous block at the current chain
 		// It may be unknown fork-chain
 		// TODO: process that
-		return nil
+		result = "orphan"
+		return ErrOrphan
 	}
 
-	logrus.Info("validating with the previous blocks")
 	// Previous block exists
 
 	// Checks with the previous block
-	// - previous Timestamp MUST BE less block.Header.Timestamp
-	if !block.Header.Timestamp.After(prevBlock.Header.Timestamp) {
-		return errors.New("invalid block time")
-	}
-	// - block.TotalDiff MUST BE == previous.TotalDiff + previous.POW.ToDifficulty()
-	if block.Header.TotalDifficulty != prevBlock.Header.TotalDifficulty+prevBlock.Header.POW.ToDifficulty() {
-		return errors.New("wrong block total difficulty")
+	if err := verifyLink(&prevBlock.Header, &block.Header); err != nil {
+		return err
 	}
 	// - check that the difficulty is not less than that calculated by the
 	//    	difficulty average based on the previous blocks
-	limit := int(consensus.DifficultyAdjustWindow + consensus.MedianTimeWindow)
-	fromHeight := uint64(0)
-	if block.Header.Height > uint64(limit) {
-		fromHeight = block.Header.Height - uint64(limit)
+	if err := c.verifyDifficulty(&block.Header); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	blockID := consensus.BlockID{
-		Hash:   nil,
-		Height: &fromHeight,
+	// the sibling of the head loses the tip race to the first seen head &
+	// is kept, the block extending it switches the chain to it
+	if block.Header.Previous == c.head.Header.Previous && block.Header.TotalDifficulty == c.totalDifficulty {
+		c.retainTip(block)
+		result = "competing"
+		log.Debug("competing tip retained")
+		return nil
 	}
 
-	diffAvg := consensus.NextDifficulty(c.storage.From(blockID, limit))
-	if block.Header.Difficulty < diffAvg {
-		return errors.New("difficulty is too low")
+	if tip := c.competingBlock(block.Header.Previous); tip != nil && block.Header.TotalDifficulty > c.totalDifficulty {
+		if err := c.switchTip(tip, block); err != nil {
+			return err
+		}
+		result = "reorg"
+		return nil
+	}
+
+	// TODO: process blocks of the deeper fork-chains, the switch to the
+	// fork notifies the reorg by notifyReorg
+	if c.head.Hash() != block.Header.Previous {
+		result = "fork"
+		return nil
+	}
+
+	// the block extends the current chain, the intent rolls back the block
+	// partially stored by the crash
+	if err := c.beginIntent(opAddBlocks, block); err != nil {
+		return err
+	}
+	c.storage.AddBlock(block)
+	c.endIntent()
+
+	c.window.push(&block.Header, c.params.DifficultyAdjustWindow+consensus.MedianTimeWindow)
+	c.stats.push(block)
+	c.head = block
+	c.height = block.Header.Height
+	c.totalDifficulty = block.Header.TotalDifficulty
+	if c.totalDifficulty >= c.headerHead.TotalDifficulty {
+		c.headerHead = block.Header
 	}
+	c.pruneTips()
+	c.notify(block)
+	result = "accepted"
 
-	// TODO: applying block
+	log.WithFields(logging.Fields{"duration": time.Since(start)}).Debug("block accepted")
+	c.logProgress(1, 0)
 
 	return nil
 }
 
+// Subscribe registers ch to receive the new head blocks, the block is
+// skipped if ch is not ready to receive it
+func (c *Chain) Subscribe(ch chan<- *consensus.Block) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	c.subscribers[ch] = struct{}{}
+}
+
+// Unsubscribe removes ch from the subscribers
+func (c *Chain) Unsubscribe(ch chan<- *consensus.Block) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	delete(c.subscribers, ch)
+}
+
+// notify sends the new head block to subscribers
+func (c *Chain) notify(block *consensus.Block) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	for ch := range c.subscribers {
+		select {
+		case ch <- block:
+		default:
+		}
+	}
+}
+
 // Head returns lastest block in blockchain
 func (c *Chain) Head() consensus.Block {
+	c.RLock()
+	defer c.RUnlock()
+
 	return *c.head
 }
 
@@ -370,8 +981,8 @@ func (c *Chain) Validate() error {
 
 	// go from head to genesis
 	// TODO: MUST check all consensus rules
-	for bytes.Compare(block.Header.Previous, c.genesis.Hash()) != 0 {
-		err := block.Validate()
+	for block.Header.Previous != c.genesis.Hash() {
+		err := block.Validate(context.Background(), c.params)
 		if err == nil {
 			return err
 		}
@@ -1,17 +1,552 @@
 package chain
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/testutil"
+	"runtime"
 	"testing"
+	"time"
 )
 
 func TestGenesisHash(t *testing.T) {
 	hash := Testnet4.Hash()
-	expected, _ := hex.DecodeString("0644cedb1acfdde4ee9e135ae61de3cbeb301b5f27a40a2c366da8e724292f20")
+	expected, _ := consensus.ParseHash("0644cedb1acfdde4ee9e135ae61de3cbeb301b5f27a40a2c366da8e724292f20")
 
-	if bytes.Compare(hash, expected) != 0 {
-		t.Errorf("Genesis hash was %v wanted %x. Content:\n%x\n",
+	if hash != expected {
+		t.Errorf("Genesis hash was %v wanted %v. Content:\n%x\n",
 			hash, expected, Testnet4.Bytes())
 	}
 }
+
+// memStorage keeps the blocks by hash, froms counts the From calls
+type memStorage struct {
+	blocks map[consensus.Hash]*consensus.Block
+	froms  int
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{blocks: make(map[consensus.Hash]*consensus.Block)}
+}
+
+func (s *memStorage) AddBlock(block *consensus.Block) {
+	s.blocks[block.Hash()] = block
+}
+
+func (s *memStorage) DelBlock(id consensus.BlockID) {}
+
+func (s *memStorage) GetBlock(id consensus.BlockID) *consensus.Block {
+	if id.Hash.IsZero() && id.Height != nil {
+		for _, block := range s.blocks {
+			if block.Header.Height == *id.Height {
+				return block
+			}
+		}
+		return nil
+	}
+
+	return s.blocks[id.Hash]
+}
+
+func (s *memStorage) GetLastBlock() *consensus.Block                  { return nil }
+func (s *memStorage) From(consensus.BlockID, int) consensus.BlockList { s.froms++; return nil }
+func (s *memStorage) GetUnspentOutput(secp256k1zkp.Commitment) *consensus.BlockID {
+	return nil
+}
+func (s *memStorage) GetKernel(secp256k1zkp.Commitment, uint64, uint64) *consensus.BlockID {
+	return nil
+}
+
+// newTestChain returns chain skipping the consensus validation of blocks
+func newTestChain() (*Chain, *memStorage) {
+	storage := newMemStorage()
+	chain := New(&Testnet4, storage)
+	chain.validate = func(ctx context.Context, block *consensus.Block) error { return nil }
+
+	return chain, storage
+}
+
+// child returns the next block after parent, the block hash is the hash of
+// the proof, so nonce makes the siblings different. The proof is searched for
+// the minimum difficulty
+func child(parent *consensus.Block, nonce uint32) *consensus.Block {
+	header := parent.Header
+	header.Height = parent.Header.Height + 1
+	header.Previous = parent.Hash()
+	header.Timestamp = parent.Header.Timestamp.Add(time.Minute)
+	header.TotalDifficulty = parent.Header.TotalDifficulty.Add(parent.Header.POW.ToDifficulty())
+
+	header.POW.Nonces = append([]uint32(nil), parent.Header.POW.Nonces...)
+	header.POW.Nonces[0] = uint32(header.Height)<<8 | nonce
+	for header.POW.ToDifficulty() < consensus.MinimumDifficulty {
+		header.POW.Nonces[1]++
+	}
+
+	return &consensus.Block{Header: header}
+}
+
+func TestProcessBlockExtend(t *testing.T) {
+	chain, storage := newTestChain()
+
+	block := child(&Testnet4, 1)
+	if err := chain.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	head := chain.Head()
+	if head.Hash() != block.Hash() {
+		t.Errorf("head was %s, want %s", head.Hash(), block.Hash())
+	}
+
+	if chain.Height() != block.Header.Height {
+		t.Errorf("height was %d, want %d", chain.Height(), block.Header.Height)
+	}
+
+	if chain.TotalDifficulty() != block.Header.TotalDifficulty {
+		t.Errorf("total difficulty was %d, want %d", chain.TotalDifficulty(), block.Header.TotalDifficulty)
+	}
+
+	if header := chain.HeaderHead(); header.Height != block.Header.Height {
+		t.Errorf("header head height was %d, want %d", header.Height, block.Header.Height)
+	}
+
+	if storage.GetBlock(consensus.BlockID{Hash: block.Hash()}) == nil {
+		t.Error("block is not stored")
+	}
+}
+
+// TestChainConcurrentReads reads the chain tip while it grows, run with -race
+func TestChainConcurrentReads(t *testing.T) {
+	chain, _ := newTestChain()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+
+			head := chain.Head()
+			if head.Header.Height > chain.Height() {
+				t.Errorf("head height %d is above the chain height", head.Header.Height)
+			}
+			chain.TotalDifficulty()
+			runtime.Gosched()
+		}
+	}()
+
+	parent := &Testnet4
+	for i := 0; i < 20; i++ {
+		block := child(parent, 1)
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+		parent = block
+		runtime.Gosched()
+	}
+
+	close(stop)
+	<-done
+
+	if chain.Height() != parent.Header.Height {
+		t.Errorf("height was %d, want %d", chain.Height(), parent.Header.Height)
+	}
+}
+
+func TestProcessGeneratedChain(t *testing.T) {
+	chain := New(&Testnet4, newMemStorage())
+	chain.SetValidator(testutil.Validate)
+
+	g := testutil.New(&Testnet4, 1)
+	blocks, err := g.Chain(&Testnet4, 3, testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spends, err := g.Chain(blocks[2], 2, testutil.Options{Spends: 2, Fee: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fork, err := g.Block(blocks[2], testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range append(append(blocks, spends...), fork) {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("block %d: ProcessBlock failed: %v", block.Header.Height, err)
+		}
+	}
+
+	if head := chain.Head(); head.Hash() != spends[1].Hash() {
+		t.Errorf("head was %d %s, want %d %s", head.Header.Height, head.Hash(), spends[1].Header.Height, spends[1].Hash())
+	}
+}
+
+func BenchmarkProcessBlock(b *testing.B) {
+	g := testutil.New(&Testnet4, 1)
+	blocks, err := g.Chain(&Testnet4, 10, testutil.Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chain := New(&Testnet4, newMemStorage())
+		chain.SetValidator(testutil.Validate)
+
+		for _, block := range blocks {
+			if err := chain.ProcessBlock(context.Background(), block); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestProcessBlockKnown(t *testing.T) {
+	chain, storage := newTestChain()
+	chain.validate = func(ctx context.Context, block *consensus.Block) error {
+		return errors.New("known block must not be validated")
+	}
+
+	genesis := Testnet4
+	if err := chain.ProcessBlock(context.Background(), &genesis); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	if chain.Height() != Testnet4.Header.Height || len(storage.blocks) != 0 {
+		t.Error("known block changed the chain")
+	}
+}
+
+func TestProcessBlockOrphan(t *testing.T) {
+	chain, storage := newTestChain()
+
+	// the parent is unknown to the chain
+	orphan := child(child(&Testnet4, 1), 2)
+	if err := chain.ProcessBlock(context.Background(), orphan); !errors.Is(err, ErrOrphan) {
+		t.Fatalf("error was %v, want %v", err, ErrOrphan)
+	}
+
+	head := chain.Head()
+	if head.Hash() != Testnet4.Hash() || len(storage.blocks) != 0 {
+		t.Error("orphan block changed the chain")
+	}
+}
+
+func TestProcessBlockFork(t *testing.T) {
+	chain, storage := newTestChain()
+
+	first := child(&Testnet4, 1)
+	second := child(first, 2)
+	for _, block := range []*consensus.Block{first, second} {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	// sibling of the head
+	fork := child(first, 3)
+	if err := chain.ProcessBlock(context.Background(), fork); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	head := chain.Head()
+	if head.Hash() != second.Hash() {
+		t.Errorf("head was %s, want %s", head.Hash(), second.Hash())
+	}
+
+	if len(storage.blocks) != 2 {
+		t.Errorf("stored %d blocks, want 2", len(storage.blocks))
+	}
+}
+
+func TestProcessBlockInvalid(t *testing.T) {
+	chain, _ := newTestChain()
+
+	block := child(&Testnet4, 1)
+	block.Header.TotalDifficulty++
+
+	if err := chain.ProcessBlock(context.Background(), block); !errors.Is(err, ErrInvalidTotalDifficulty) {
+		t.Errorf("error was %v, want %v", err, ErrInvalidTotalDifficulty)
+	}
+
+	if chain.Height() != Testnet4.Header.Height {
+		t.Error("invalid block changed the chain")
+	}
+
+	chain.validate = func(ctx context.Context, block *consensus.Block) error { return errors.New("invalid") }
+	if err := chain.ProcessBlock(context.Background(), child(&Testnet4, 2)); err == nil {
+		t.Error("block failed the consensus validation was accepted")
+	}
+}
+
+func TestProcessBlockCancelled(t *testing.T) {
+	chain, _ := newTestChain()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the context is cancelled while the block is validated
+	chain.validate = func(ctx context.Context, block *consensus.Block) error {
+		cancel()
+		return nil
+	}
+
+	if err := chain.ProcessBlock(ctx, child(&Testnet4, 1)); err != context.Canceled {
+		t.Errorf("error was %v, want %v", err, context.Canceled)
+	}
+
+	if chain.Height() != Testnet4.Header.Height {
+		t.Error("cancelled block changed the chain")
+	}
+}
+
+// headers returns the headers of n blocks after parent
+func TestDifficultyWindow(t *testing.T) {
+	chain, storage := newTestChain()
+
+	parent := &Testnet4
+	var first *consensus.Block
+	for i := 0; i < 20; i++ {
+		parent = child(parent, 1)
+		if first == nil {
+			first = parent
+		}
+		if err := chain.ProcessBlock(context.Background(), parent); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	if storage.froms != 1 {
+		t.Errorf("storage was read %d times, want the cold window only", storage.froms)
+	}
+
+	if chain.window.tip != parent.Hash() || len(chain.window.infos) != 20 {
+		t.Fatalf("window of %d blocks, tip %s, want 20 blocks of %s", len(chain.window.infos), chain.window.tip, parent.Hash())
+	}
+
+	if info, ok := chain.window.iter().Next(); !ok || !info.Timestamp.Equal(parent.Header.Timestamp) {
+		t.Errorf("window iterator didn't start from the head: %v", info.Timestamp)
+	}
+
+	// the fork of the window doesn't replace it
+	fork := child(first, 2)
+	if err := chain.ProcessBlock(context.Background(), fork); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+	if chain.window.tip != parent.Hash() || storage.froms != 2 {
+		t.Errorf("fork replaced the window of the head")
+	}
+
+	chain.SetParams(&consensus.TestnetParams)
+	if !chain.window.tip.IsZero() {
+		t.Error("window wasn't reset by the params")
+	}
+}
+
+func headers(parent *consensus.Block, n int) []consensus.BlockHeader {
+	result := make([]consensus.BlockHeader, 0, n)
+	for i := 0; i < n; i++ {
+		parent = child(parent, 1)
+		result = append(result, parent.Header)
+	}
+
+	return result
+}
+
+func TestProcessHeaders(t *testing.T) {
+	chain, _ := newTestChain()
+	chain.SetHeaderWorkers(4)
+	chain.validateHeader = func(header *consensus.BlockHeader) error {
+		if header.Height == 100 {
+			return consensus.ErrInvalidPow
+		}
+		return nil
+	}
+
+	batch := headers(&Testnet4, 10)
+	if err := chain.ProcessHeaders(context.Background(), batch); err != nil {
+		t.Fatalf("ProcessHeaders failed: %v", err)
+	}
+
+	if head := chain.HeaderHead(); head.Hash() != batch[9].Hash() {
+		t.Errorf("header head was %d, want %d", head.Height, batch[9].Height)
+	}
+
+	// the next batch follows the header head
+	next := headers(&consensus.Block{Header: batch[9]}, 100)
+	if err := chain.ProcessHeaders(context.Background(), next[:50]); err != nil {
+		t.Fatalf("ProcessHeaders failed: %v", err)
+	}
+
+	if err := chain.ProcessHeaders(context.Background(), next[60:80]); !errors.Is(err, ErrOrphan) {
+		t.Errorf("error was %v, want %v", err, ErrOrphan)
+	}
+
+	broken := append([]consensus.BlockHeader(nil), next[50:60]...)
+	broken[3], broken[4] = broken[4], broken[3]
+	if err := chain.ProcessHeaders(context.Background(), broken); !errors.Is(err, ErrInvalidPrevious) {
+		t.Errorf("error was %v, want %v", err, ErrInvalidPrevious)
+	}
+
+	// the header of height 100 fails the proof of work
+	if err := chain.ProcessHeaders(context.Background(), next[50:]); !errors.Is(err, consensus.ErrInvalidPow) {
+		t.Errorf("error was %v, want %v", err, consensus.ErrInvalidPow)
+	}
+
+	if head := chain.HeaderHead(); head.Height != 60 {
+		t.Errorf("header head was %d, want 60", head.Height)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := chain.ProcessHeaders(ctx, next[50:60]); err != context.Canceled {
+		t.Errorf("error was %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestValidateHeader(t *testing.T) {
+	chain, _ := newTestChain()
+	chain.validateHeader = func(header *consensus.BlockHeader) error {
+		if header.Height == 3 {
+			return consensus.ErrInvalidPow
+		}
+		return nil
+	}
+
+	batch := headers(&Testnet4, 3)
+	tests := []struct {
+		prev, header *consensus.BlockHeader
+		err          error
+	}{
+		{nil, &batch[0], nil},
+		{&batch[0], &batch[1], nil},
+		{&batch[1], &batch[0], ErrInvalidPrevious},
+		{&batch[1], &batch[2], consensus.ErrInvalidPow},
+	}
+
+	for i, test := range tests {
+		if err := chain.ValidateHeader(test.prev, test.header); !errors.Is(err, test.err) {
+			t.Errorf("%d: error was %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+// rootedHeaders returns the headers of n blocks after parent committing to
+// the header mmr, the mmr is extended by the headers
+func rootedHeaders(parent *consensus.Block, n int, mmr *consensus.MMR) []consensus.BlockHeader {
+	result := make([]consensus.BlockHeader, 0, n)
+	for i := 0; i < n; i++ {
+		parent = child(parent, 1)
+		parent.Header.PreviousRoot = mmr.Root()
+		mmr.Append(parent.Hash().Bytes())
+		result = append(result, parent.Header)
+	}
+
+	return result
+}
+
+func TestHeadersOnly(t *testing.T) {
+	chain, storage := newTestChain()
+	chain.validateHeader = func(header *consensus.BlockHeader) error { return nil }
+	chain.validate = func(ctx context.Context, block *consensus.Block) error {
+		return errors.New("block must not be validated")
+	}
+	chain.SetHeadersOnly()
+
+	var mmr consensus.MMR
+	mmr.Append(Testnet4.Hash().Bytes())
+
+	batch := rootedHeaders(&Testnet4, 10, &mmr)
+	if err := chain.ProcessHeaders(context.Background(), batch); err != nil {
+		t.Fatalf("ProcessHeaders failed: %v", err)
+	}
+
+	if head := chain.Head(); head.Hash() != batch[9].Hash() || chain.Height() != 10 {
+		t.Errorf("head was %d, want 10", head.Header.Height)
+	}
+
+	if chain.TotalDifficulty() != batch[9].TotalDifficulty {
+		t.Errorf("total difficulty was %d, want %d", chain.TotalDifficulty(), batch[9].TotalDifficulty)
+	}
+
+	if len(storage.blocks) != 10 {
+		t.Errorf("stored %d headers, want 10", len(storage.blocks))
+	}
+
+	if chain.HeaderRoot() != mmr.Root() {
+		t.Errorf("header root was %s, want %s", chain.HeaderRoot(), mmr.Root())
+	}
+
+	// the known headers are skipped
+	if err := chain.ProcessHeaders(context.Background(), batch[5:]); err != nil || chain.Height() != 10 {
+		t.Errorf("known headers: %v, height %d", err, chain.Height())
+	}
+
+	// the rebuilt mmr is the same
+	chain.SetHeadersOnly()
+	if chain.HeaderRoot() != mmr.Root() {
+		t.Errorf("rebuilt header root was %s, want %s", chain.HeaderRoot(), mmr.Root())
+	}
+
+	next := rootedHeaders(&consensus.Block{Header: batch[9]}, 2, &mmr)
+	invalid := next[0]
+	invalid.PreviousRoot = batch[9].PreviousRoot
+	if err := chain.ProcessHeaders(context.Background(), []consensus.BlockHeader{invalid}); !errors.Is(err, ErrInvalidHeaderRoot) {
+		t.Errorf("error was %v, want %v", err, ErrInvalidHeaderRoot)
+	}
+
+	if chain.Height() != 10 {
+		t.Error("header of the invalid root changed the chain")
+	}
+
+	// the block body is skipped
+	for _, header := range next {
+		if err := chain.ProcessBlock(context.Background(), &consensus.Block{Header: header}); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	if chain.Height() != 12 || chain.GetBlock(next[1].Hash()) != nil {
+		t.Errorf("height was %d, want 12 & no blocks", chain.Height())
+	}
+}
+
+func TestLocator(t *testing.T) {
+	chain, _ := newTestChain()
+
+	if loc := chain.Locator(); len(loc.Hashes) != 1 || loc.Hashes[0] != Testnet4.Hash() {
+		t.Errorf("locator of the genesis was %v", loc.Hashes)
+	}
+
+	parent := &Testnet4
+	blocks := []*consensus.Block{parent}
+	for i := 0; i < 10; i++ {
+		parent = child(parent, 1)
+		if err := chain.ProcessBlock(context.Background(), parent); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+		blocks = append(blocks, parent)
+	}
+
+	loc := chain.Locator()
+	heights := []int{10, 9, 8, 6, 2, 0}
+	if len(loc.Hashes) != len(heights) {
+		t.Fatalf("locator of %d hashes, want %d", len(loc.Hashes), len(heights))
+	}
+
+	for i, height := range heights {
+		if loc.Hashes[i] != blocks[height].Hash() {
+			t.Errorf("locator hash %d isn't of height %d", i, height)
+		}
+	}
+}
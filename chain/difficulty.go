@@ -0,0 +1,102 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// difficultyWindow is the cache of the timestamps & the difficulties of the
+// recent main chain blocks, the latest last. The window of the tip is the
+// input of the next difficulty of its child
+type difficultyWindow struct {
+	// tip is the hash of the latest block, zero if the window is unknown
+	tip   consensus.Hash
+	infos []consensus.HeaderInfo
+}
+
+// push adds the header extending the window tip, the oldest blocks past size
+// are dropped. The header not extending the tip resets the window
+func (w *difficultyWindow) push(header *consensus.BlockHeader, size int) {
+	if w.tip != header.Previous {
+		w.reset()
+		return
+	}
+
+	w.infos = append(w.infos, consensus.HeaderInfo{Timestamp: header.Timestamp, Difficulty: header.Difficulty})
+	if len(w.infos) > size {
+		w.infos = append(w.infos[:0], w.infos[len(w.infos)-size:]...)
+	}
+	w.tip = header.Hash()
+}
+
+// reset drops the window, the next one is read from the storage
+func (w *difficultyWindow) reset() {
+	w.tip = consensus.ZeroHash
+	w.infos = nil
+}
+
+// iter returns the iterator of the window from the latest block
+func (w *difficultyWindow) iter() consensus.DifficultyIter {
+	return &windowIter{infos: w.infos, next: len(w.infos)}
+}
+
+// windowIter iterates the header infos backwards
+type windowIter struct {
+	infos []consensus.HeaderInfo
+	next  int
+}
+
+// Next implements consensus.DifficultyIter
+func (it *windowIter) Next() (consensus.HeaderInfo, bool) {
+	if it.next == 0 {
+		return consensus.HeaderInfo{}, false
+	}
+
+	it.next--
+	return it.infos[it.next], true
+}
+
+// difficultyIter returns the difficulty window previous to the header: the
+// cached one if the header extends its tip, otherwise the window is read from
+// the storage & cached if the header extends the head
+func (c *Chain) difficultyIter(header *consensus.BlockHeader) consensus.DifficultyIter {
+	if !c.window.tip.IsZero() && c.window.tip == header.Previous {
+		return c.window.iter()
+	}
+
+	// the block extending the competing tip follows the tip, not the head
+	limit := c.params.DifficultyAdjustWindow + consensus.MedianTimeWindow
+	if tip := c.competingBlock(header.Previous); tip != nil {
+		w := c.storageWindow(&tip.Header)
+		w.push(&tip.Header, limit)
+		return w.iter()
+	}
+
+	w := c.storageWindow(header)
+	if header.Previous == c.head.Hash() {
+		c.window = w
+	}
+
+	return w.iter()
+}
+
+// storageWindow reads the difficulty window previous to the header from the
+// main chain blocks of the storage
+func (c *Chain) storageWindow(header *consensus.BlockHeader) difficultyWindow {
+	limit := c.params.DifficultyAdjustWindow + consensus.MedianTimeWindow
+	fromHeight := uint64(0)
+	if header.Height > uint64(limit) {
+		fromHeight = header.Height - uint64(limit)
+	}
+
+	var w difficultyWindow
+	for _, block := range c.storage.From(consensus.BlockID{Height: &fromHeight}, limit) {
+		w.infos = append(w.infos, consensus.HeaderInfo{Timestamp: block.Header.Timestamp, Difficulty: block.Header.Difficulty})
+	}
+	w.tip = header.Previous
+
+	return w
+}
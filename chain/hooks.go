@@ -0,0 +1,25 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// BlockHandler processes the block like ProcessBlock
+type BlockHandler func(ctx context.Context, block *consensus.Block) error
+
+// BlockMiddleware wraps the next block handler: the code before calling next
+// runs before the chain processes the block & its error rejects the block,
+// the code after next gets the processing result. The middlewares run
+// without the chain lock, so they may read the chain
+type BlockMiddleware func(next BlockHandler) BlockHandler
+
+// Use wraps ProcessBlock by the middleware, the last added runs first. Must
+// be called before the blocks are processed
+func (c *Chain) Use(middleware BlockMiddleware) {
+	c.process = middleware(c.process)
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+)
+
+func TestBlockMiddleware(t *testing.T) {
+	chain, _ := newTestChain()
+
+	var calls []string
+	errFiltered := errors.New("filtered")
+
+	// the filter runs first & the stats see the result of the chain
+	chain.Use(func(next BlockHandler) BlockHandler {
+		return func(ctx context.Context, block *consensus.Block) error {
+			err := next(ctx, block)
+			head := chain.Head()
+			calls = append(calls, "stats", head.Hash().String())
+			return err
+		}
+	})
+	chain.Use(func(next BlockHandler) BlockHandler {
+		return func(ctx context.Context, block *consensus.Block) error {
+			calls = append(calls, "filter")
+			if block.Header.Nonce == 1 {
+				return errFiltered
+			}
+			return next(ctx, block)
+		}
+	})
+
+	filtered := child(&Testnet4, 1)
+	filtered.Header.Nonce = 1
+	if err := chain.ProcessBlock(context.Background(), filtered); err != errFiltered {
+		t.Errorf("filtered block error was %v", err)
+	}
+
+	if chain.Height() != Testnet4.Header.Height {
+		t.Errorf("filtered block was added")
+	}
+
+	block := child(&Testnet4, 2)
+	if err := chain.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	want := []string{"filter", "filter", "stats", block.Hash().String()}
+	if len(calls) != len(want) {
+		t.Fatalf("calls were %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls were %v, want %v", calls, want)
+			break
+		}
+	}
+}
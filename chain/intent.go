@@ -0,0 +1,169 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/wire"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// intentOp is the storage operation of the intent
+type intentOp uint8
+
+const (
+	// opAddBlocks adds the intent block & the blocks on top of it, the
+	// partially applied blocks are rolled back by deleting them
+	opAddBlocks intentOp = 1
+)
+
+// intent is the storage operation started on the block, the intent file
+// keeps it until the operation is committed
+type intent struct {
+	op     intentOp
+	hash   consensus.Hash
+	height uint64
+}
+
+// Bytes returns the serialized intent
+func (in *intent) Bytes() []byte {
+	return wire.Bytes(func(buf *bytes.Buffer) {
+		wire.WriteUint8(buf, uint8(in.op))
+		buf.Write(in.hash[:])
+		wire.WriteUint64(buf, in.height)
+	})
+}
+
+// Read reads the serialized intent
+func (in *intent) Read(r io.Reader) error {
+	op, err := wire.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	in.op = intentOp(op)
+
+	if err := wire.ReadBytes(r, in.hash[:]); err != nil {
+		return err
+	}
+
+	in.height, err = wire.ReadUint64(r)
+	return err
+}
+
+// SetIntentFile records the intent of every storage change to the file
+// before it is applied & clears it after: the intent left by the crash is of
+// the partially applied blocks, they are rolled back & the head is reloaded
+// from storage. Must be called before the blocks are processed
+func (c *Chain) SetIntentFile(file string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	c.intentFile = file
+	if err != nil {
+		return nil
+	}
+
+	var in intent
+	r := bytes.NewReader(data)
+	if err := in.Read(r); err != nil || r.Len() > 0 {
+		return fmt.Errorf("invalid intent file %s", file)
+	}
+
+	return c.recoverIntent(&in)
+}
+
+// recoverIntent completes the storage operation interrupted by the crash
+// & clears its intent, must be called with the lock held
+func (c *Chain) recoverIntent(in *intent) error {
+	log := c.log.WithFields(logging.Fields{
+		"height": in.height,
+		"hash":   in.hash.String(),
+	})
+
+	switch in.op {
+	case opAddBlocks:
+		log.Warn("rolling back the partially applied blocks")
+
+		height := in.height
+		c.storage.DelBlock(consensus.BlockID{Hash: in.hash, Height: &height})
+		c.loadHead()
+		c.window.reset()
+
+	default:
+		return fmt.Errorf("unknown intent operation %d", in.op)
+	}
+
+	return os.Remove(c.intentFile)
+}
+
+// beginIntent persists the intent of the operation on the block before the
+// storage is changed, the chain without the intent file records nothing.
+// Must be called with the lock held
+func (c *Chain) beginIntent(op intentOp, block *consensus.Block) error {
+	if c.intentFile == "" {
+		return nil
+	}
+
+	in := intent{op: op, hash: block.Hash(), height: block.Header.Height}
+	if err := writeFileSync(c.intentFile, in.Bytes()); err != nil {
+		return fmt.Errorf("failed to record the intent: %v", err)
+	}
+
+	return nil
+}
+
+// endIntent clears the intent of the committed operation, the intent left
+// by the failure only rolls back the applied blocks on the next start. Must
+// be called with the lock held
+func (c *Chain) endIntent() {
+	if c.intentFile == "" {
+		return
+	}
+
+	if err := os.Remove(c.intentFile); err != nil {
+		c.log.Errorf("failed to clear the intent: %v", err)
+	}
+}
+
+// writeFileSync replaces the file by the data synced to the disk, the file
+// is never left partially written
+func writeFileSync(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIntentCommitted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-intent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "chain_intent")
+
+	chain, _ := newTestChain()
+	if err := chain.SetIntentFile(file); err != nil {
+		t.Fatalf("SetIntentFile failed: %v", err)
+	}
+
+	block := child(&Testnet4, 1)
+	if err := chain.ProcessBlock(context.Background(), block); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("intent of the committed block was left: %v", err)
+	}
+}
+
+func TestIntentRollback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-intent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "chain_intent")
+
+	store := storage.NewMemStorage()
+	store.AddBlock(&Testnet4)
+	first := child(&Testnet4, 1)
+	store.AddBlock(first)
+
+	// the crash after the second block is stored leaves its intent
+	second := child(first, 1)
+	in := intent{op: opAddBlocks, hash: second.Hash(), height: second.Header.Height}
+	if err := writeFileSync(file, in.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	store.AddBlock(second)
+
+	chain := New(&Testnet4, store)
+	chain.SetLogger(logging.Nop)
+	chain.validate = func(ctx context.Context, block *consensus.Block) error { return nil }
+	if chain.Height() != 2 {
+		t.Fatalf("height was %d, want 2", chain.Height())
+	}
+
+	if err := chain.SetIntentFile(file); err != nil {
+		t.Fatalf("SetIntentFile failed: %v", err)
+	}
+
+	if head := chain.Head(); head.Hash() != first.Hash() || chain.Height() != 1 {
+		t.Errorf("head was %d, want 1", head.Header.Height)
+	}
+
+	if store.GetBlock(consensus.BlockID{Hash: second.Hash()}) != nil {
+		t.Error("partially applied block was not rolled back")
+	}
+
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("recovered intent was left: %v", err)
+	}
+
+	// the rolled back block is applied again
+	if err := chain.ProcessBlock(context.Background(), second); err != nil {
+		t.Errorf("ProcessBlock failed: %v", err)
+	}
+}
+
+func TestIntentInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-intent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "chain_intent")
+
+	if err := ioutil.WriteFile(file, []byte{1, 2, 3}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	chain, _ := newTestChain()
+	if err := chain.SetIntentFile(file); err == nil {
+		t.Error("truncated intent was read")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"sort"
+)
+
+// ErrInvalidBlock is the block or the header marked invalid by the operator,
+// it's rejected even if it's valid by the consensus rules
+var ErrInvalidBlock = errors.New("block is marked invalid")
+
+// InvalidateBlock marks the block hash invalid: the block & its header are
+// rejected, so the chain doesn't follow it. The block already in the chain
+// isn't rewound, it returns true then
+func (c *Chain) InvalidateBlock(hash consensus.Hash) bool {
+	c.imu.Lock()
+	if c.invalid == nil {
+		c.invalid = make(map[consensus.Hash]bool)
+	}
+	c.invalid[hash] = true
+	c.imu.Unlock()
+
+	known := c.GetBlock(hash) != nil
+	c.log.WithFields(logging.Fields{"hash": hash.String(), "known": known}).Warn("block marked invalid")
+
+	return known
+}
+
+// ReconsiderBlock removes the invalid mark of the block hash, the block is
+// processed again when it's received
+func (c *Chain) ReconsiderBlock(hash consensus.Hash) {
+	c.imu.Lock()
+	defer c.imu.Unlock()
+
+	delete(c.invalid, hash)
+}
+
+// InvalidBlocks returns the block hashes marked invalid, sorted
+func (c *Chain) InvalidBlocks() []consensus.Hash {
+	c.imu.RLock()
+	defer c.imu.RUnlock()
+
+	result := make([]consensus.Hash, 0, len(c.invalid))
+	for hash := range c.invalid {
+		result = append(result, hash)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Compare(result[j]) < 0
+	})
+
+	return result
+}
+
+// checkInvalid returns ErrInvalidBlock if the hash is marked invalid
+func (c *Chain) checkInvalid(hash consensus.Hash) error {
+	c.imu.RLock()
+	defer c.imu.RUnlock()
+
+	if c.invalid[hash] {
+		return ErrInvalidBlock
+	}
+
+	return nil
+}
+
+// checkInvalidHeaders returns ErrInvalidBlock if any of the headers is marked
+// invalid, the hashes are skipped while nothing is marked
+func (c *Chain) checkInvalidHeaders(headers []consensus.BlockHeader) error {
+	c.imu.RLock()
+	marked := len(c.invalid) > 0
+	c.imu.RUnlock()
+
+	if !marked {
+		return nil
+	}
+
+	for i := range headers {
+		if err := c.checkInvalid(headers[i].Hash()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+)
+
+func TestInvalidateBlock(t *testing.T) {
+	chain, _ := newTestChain()
+	chain.validateHeader = func(header *consensus.BlockHeader) error { return nil }
+
+	block := child(&Testnet4, 1)
+	if known := chain.InvalidateBlock(block.Hash()); known {
+		t.Errorf("unknown block was reported known")
+	}
+
+	if err := chain.ProcessBlock(context.Background(), block); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("error was %v, want %v", err, ErrInvalidBlock)
+	}
+
+	if err := chain.ProcessHeaders(context.Background(), []consensus.BlockHeader{block.Header}); !errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("header error was %v, want %v", err, ErrInvalidBlock)
+	}
+
+	if chain.Height() != Testnet4.Header.Height || chain.HeaderHead().Height != Testnet4.Header.Height {
+		t.Errorf("invalid block was added")
+	}
+
+	// the sibling is valid
+	sibling := child(&Testnet4, 2)
+	if err := chain.ProcessBlock(context.Background(), sibling); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	if known := chain.InvalidateBlock(sibling.Hash()); !known {
+		t.Errorf("block of the chain was not reported known")
+	}
+
+	if hashes := chain.InvalidBlocks(); len(hashes) != 2 {
+		t.Errorf("invalid blocks were %v", hashes)
+	}
+
+	chain.ReconsiderBlock(block.Hash())
+	chain.ReconsiderBlock(sibling.Hash())
+	if hashes := chain.InvalidBlocks(); len(hashes) != 0 {
+		t.Errorf("reconsidered blocks were %v", hashes)
+	}
+
+	if err := chain.ProcessBlock(context.Background(), block); errors.Is(err, ErrInvalidBlock) {
+		t.Errorf("reconsidered block was rejected: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Metrics is a prometheus collector of the chain statistics
+type Metrics struct {
+	// height & total difficulty of the chain head
+	height          prometheus.GaugeFunc
+	totalDifficulty prometheus.GaugeFunc
+
+	// latency of the block processing
+	processLatency prometheus.Histogram
+
+	// processed blocks by result (accepted, known, orphan, competing, reorg,
+	// fork, rejected)
+	blocks *prometheus.CounterVec
+
+	// latency of the headers batch processing
+	headersLatency prometheus.Histogram
+
+	// processed headers by result (accepted, orphan, rejected), the rate is
+	// the header sync throughput
+	headers *prometheus.CounterVec
+
+	// rejected headers by the validation stage (precheck, link, invalidated,
+	// pow), the cheap stages run before the cuckoo cycle verification
+	headersRejected *prometheus.CounterVec
+}
+
+// newMetrics returns metrics of the chain
+func newMetrics(c *Chain) *Metrics {
+	return &Metrics{
+		height: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "height",
+			Help:      "Height of the chain head.",
+		}, func() float64 {
+			c.RLock()
+			defer c.RUnlock()
+
+			return float64(c.Height())
+		}),
+
+		totalDifficulty: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "total_difficulty",
+			Help:      "Total difficulty of the chain head.",
+		}, func() float64 {
+			c.RLock()
+			defer c.RUnlock()
+
+			return float64(c.TotalDifficulty())
+		}),
+
+		processLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "block_process_duration_seconds",
+			Help:      "Latency of the block processing.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}),
+
+		blocks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "blocks_processed_total",
+			Help:      "Processed blocks by result.",
+		}, []string{"result"}),
+
+		headersLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "headers_process_duration_seconds",
+			Help:      "Latency of the headers batch processing.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}),
+
+		headers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "headers_processed_total",
+			Help:      "Processed headers by result.",
+		}, []string{"result"}),
+
+		headersRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gringo",
+			Subsystem: "chain",
+			Name:      "headers_rejected_total",
+			Help:      "Rejected headers by validation stage.",
+		}, []string{"stage"}),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.height.Describe(ch)
+	m.totalDifficulty.Describe(ch)
+	m.processLatency.Describe(ch)
+	m.blocks.Describe(ch)
+	m.headersLatency.Describe(ch)
+	m.headers.Describe(ch)
+	m.headersRejected.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.height.Collect(ch)
+	m.totalDifficulty.Collect(ch)
+	m.processLatency.Collect(ch)
+	m.blocks.Collect(ch)
+	m.headersLatency.Collect(ch)
+	m.headers.Collect(ch)
+	m.headersRejected.Collect(ch)
+}
+
+// observeBlock records the block processing started at start, used with defer
+func (m *Metrics) observeBlock(result *string, start time.Time) {
+	m.processLatency.Observe(time.Since(start).Seconds())
+	m.blocks.WithLabelValues(*result).Inc()
+}
+
+// observeHeaders records the batch of n headers processing started at start,
+// used with defer
+func (m *Metrics) observeHeaders(result *string, n int, start time.Time) {
+	m.headersLatency.Observe(time.Since(start).Seconds())
+	m.headers.WithLabelValues(*result).Add(float64(n))
+}
+
+// rejectHeader records the header rejected at the validation stage
+func (m *Metrics) rejectHeader(stage string) {
+	m.headersRejected.WithLabelValues(stage).Inc()
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+// nilStorage is the empty storage
+type nilStorage struct{}
+
+func (nilStorage) AddBlock(block *consensus.Block)                 {}
+func (nilStorage) DelBlock(id consensus.BlockID)                   {}
+func (nilStorage) GetBlock(id consensus.BlockID) *consensus.Block  { return nil }
+func (nilStorage) GetLastBlock() *consensus.Block                  { return nil }
+func (nilStorage) From(consensus.BlockID, int) consensus.BlockList { return nil }
+func (nilStorage) GetUnspentOutput(secp256k1zkp.Commitment) *consensus.BlockID {
+	return nil
+}
+func (nilStorage) GetKernel(secp256k1zkp.Commitment, uint64, uint64) *consensus.BlockID {
+	return nil
+}
+
+func TestChainMetrics(t *testing.T) {
+	chain := New(&Testnet4, nilStorage{})
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(chain.Metrics()); err != nil {
+		t.Fatalf("failed to register chain metrics: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "gringo_chain_total_difficulty" {
+			value := family.GetMetric()[0].GetGauge().GetValue()
+			if value != float64(Testnet4.Header.TotalDifficulty) {
+				t.Errorf("total difficulty was %v, want %v", value, Testnet4.Header.TotalDifficulty)
+			}
+			return
+		}
+	}
+
+	t.Error("total difficulty is not exposed")
+}
+
+func TestHeadersRejectedMetrics(t *testing.T) {
+	chain, _ := newTestChain()
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(chain.Metrics()); err != nil {
+		t.Fatalf("failed to register chain metrics: %v", err)
+	}
+
+	batch := headers(&Testnet4, 2)
+
+	// the ascending nonces of the unsolved graph pass the precheck only
+	for i := range batch[1].POW.Nonces {
+		batch[1].POW.Nonces[i] = uint32(i)
+	}
+
+	unsorted := batch[1]
+	unsorted.POW.Nonces = append([]uint32(nil), batch[1].POW.Nonces...)
+	unsorted.POW.Nonces[0], unsorted.POW.Nonces[1] = 1, 0
+
+	chain.ValidateHeader(&batch[1], &batch[0])
+	chain.ValidateHeader(nil, &unsorted)
+	chain.ValidateHeader(nil, &batch[1])
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	rejected := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() == "gringo_chain_headers_rejected_total" {
+			for _, metric := range family.GetMetric() {
+				rejected[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	for _, stage := range []string{"link", "precheck", "pow"} {
+		if rejected[stage] != 1 {
+			t.Errorf("%s rejected headers were %v, want 1", stage, rejected[stage])
+		}
+	}
+}
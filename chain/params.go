@@ -0,0 +1,53 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import "github.com/dblokhin/gringo/consensus"
+
+// Params is the network parameters
+type Params struct {
+	Genesis *consensus.Block
+
+	// Consensus is the consensus parameters of the network
+	Consensus *consensus.Params
+
+	// Port is the default p2p port of the network
+	Port uint16
+
+	// Seeds is the default initial peers
+	Seeds []string
+
+	// DNSSeeds is the host:port list of the hosts resolving to the initial
+	// peers listening on the port
+	DNSSeeds []string
+}
+
+// Networks is the network parameters by name, the old testnets have no seeds.
+// The usernet is the private network on top of the testnet4 genesis
+var Networks = map[string]Params{
+	"mainnet": {
+		Genesis:   &Mainnet,
+		Consensus: &consensus.MainnetParams,
+		Port:      3414,
+		DNSSeeds: []string{
+			"mainnet.seed.grin-tech.org:3414",
+			"mainnet.seed.grin.icu:3414",
+			"mainnet.seed.713.mw:3414",
+			"mainnet.seed.grin.lesceller.com:3414",
+			"mainnet.seed.grin.prokapi.com:3414",
+			"grinseed.yeastplume.org:3414",
+		},
+	},
+	"testnet1": {Genesis: &Testnet1, Consensus: &consensus.TestnetParams, Port: 13414},
+	"testnet2": {Genesis: &Testnet2, Consensus: &consensus.TestnetParams, Port: 13414},
+	"testnet3": {Genesis: &Testnet3, Consensus: &consensus.TestnetParams, Port: 13414},
+	"testnet4": {
+		Genesis:   &Testnet4,
+		Consensus: &consensus.TestnetParams,
+		Port:      13414,
+		DNSSeeds:  []string{"t4.seed.grin-tech.org:13414"},
+	},
+	"usernet": {Genesis: &Testnet4, Consensus: &consensus.UsernetParams, Port: 13414},
+}
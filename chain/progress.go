@@ -0,0 +1,46 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"time"
+)
+
+// progressInterval is the interval of the chain head summary lines, the
+// single blocks & headers are logged at the debug level
+const progressInterval = 10 * time.Second
+
+// progress is the count of the blocks & the headers accepted since the last
+// summary line
+type progress struct {
+	last    time.Time
+	blocks  int
+	headers int
+}
+
+// logProgress counts the accepted blocks & headers, the summary of the head is
+// logged at the info level once per progressInterval. Must be called with the
+// chain locked
+func (c *Chain) logProgress(blocks, headers int) {
+	c.progress.blocks += blocks
+	c.progress.headers += headers
+
+	now := time.Now()
+	if now.Sub(c.progress.last) < progressInterval {
+		return
+	}
+
+	c.log.WithFields(logging.Fields{
+		"height":           c.height,
+		"hash":             c.head.Hash().String(),
+		"total_difficulty": uint64(c.totalDifficulty),
+		"header_height":    c.headerHead.Height,
+		"blocks":           c.progress.blocks,
+		"headers":          c.progress.headers,
+	}).Info("chain head")
+
+	c.progress = progress{last: now}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"bytes"
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogProgress(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.InfoLevel)
+
+	chain, _ := newTestChain()
+	chain.SetLogger(logging.NewLogrus(logger))
+	chain.validateHeader = func(header *consensus.BlockHeader) error { return nil }
+
+	block := &Testnet4
+	for i := 0; i < 3; i++ {
+		block = child(block, 1)
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	// the first block is summarized at once, the others are counted
+	if n := strings.Count(buf.String(), "chain head"); n != 1 {
+		t.Fatalf("%d summary lines were logged, want 1: %s", n, buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "height=1 ") || !strings.Contains(buf.String(), "blocks=1 ") {
+		t.Errorf("first summary is wrong: %s", buf.String())
+	}
+
+	buf.Reset()
+	chain.progress.last = chain.progress.last.Add(-progressInterval)
+
+	batch := headers(block, 2)
+	if err := chain.ProcessHeaders(context.Background(), batch); err != nil {
+		t.Fatalf("ProcessHeaders failed: %v", err)
+	}
+
+	line := buf.String()
+	if !strings.Contains(line, "blocks=2 ") || !strings.Contains(line, "headers=2 ") ||
+		!strings.Contains(line, "header_height=5 ") || !strings.Contains(line, "height=3 ") {
+		t.Errorf("summary of the counted blocks & headers is wrong: %s", line)
+	}
+
+	if strings.Contains(line, "block accepted") || time.Since(chain.progress.last) > progressInterval {
+		t.Errorf("progress wasn't reset or blocks are logged at info level: %s", line)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"time"
+)
+
+// maxReorgEvents is the count of the recent reorgs kept by the chain
+const maxReorgEvents = 100
+
+// ReorgEvent is the switch of the main chain to the fork of the more total
+// difficulty
+type ReorgEvent struct {
+	Time time.Time
+	// ForkHeight is the height of the common ancestor of the chains
+	ForkHeight uint64
+	// Disconnected is the blocks removed from the main chain & Connected
+	// the fork blocks replacing them, from the lowest
+	Disconnected []consensus.Hash
+	Connected    []consensus.Hash
+	// Kernels is the kernels of the disconnected blocks missing in the
+	// connected ones: their transactions lost the confirmations
+	Kernels []consensus.Hash
+}
+
+// NewReorgEvent returns the reorg of the disconnected main chain blocks by
+// the connected fork blocks, both from the lowest. The coinbase kernels are
+// not listed
+func NewReorgEvent(disconnected, connected []*consensus.Block) *ReorgEvent {
+	event := &ReorgEvent{Time: time.Now()}
+	if len(disconnected) > 0 {
+		event.ForkHeight = disconnected[0].Header.Height - 1
+	}
+
+	confirmed := make(map[consensus.Hash]bool)
+	for _, block := range connected {
+		event.Connected = append(event.Connected, block.Hash())
+		for i := range block.Kernels {
+			confirmed[block.Kernels[i].Hash()] = true
+		}
+	}
+
+	for _, block := range disconnected {
+		event.Disconnected = append(event.Disconnected, block.Hash())
+		for i := range block.Kernels {
+			kernel := &block.Kernels[i]
+			if kernel.Features&consensus.CoinbaseKernel == consensus.CoinbaseKernel {
+				continue
+			}
+
+			if hash := kernel.Hash(); !confirmed[hash] {
+				event.Kernels = append(event.Kernels, hash)
+			}
+		}
+	}
+
+	return event
+}
+
+// SubscribeReorgs registers ch to receive the reorgs, the event is skipped
+// if ch is not ready to receive it
+func (c *Chain) SubscribeReorgs(ch chan<- *ReorgEvent) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	c.reorgSubscribers[ch] = struct{}{}
+}
+
+// UnsubscribeReorgs removes ch from the reorg subscribers
+func (c *Chain) UnsubscribeReorgs(ch chan<- *ReorgEvent) {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	delete(c.reorgSubscribers, ch)
+}
+
+// Reorgs returns the recent reorgs, the latest last
+func (c *Chain) Reorgs() []ReorgEvent {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	result := make([]ReorgEvent, 0, len(c.reorgs))
+	for _, event := range c.reorgs {
+		result = append(result, *event)
+	}
+
+	return result
+}
+
+// notifyReorg records the reorg & sends it to the subscribers, the fork
+// switch of the chain calls it after the fork blocks are connected
+func (c *Chain) notifyReorg(event *ReorgEvent) {
+	c.log.WithFields(logging.Fields{
+		"height":       event.ForkHeight,
+		"disconnected": len(event.Disconnected),
+		"kernels":      len(event.Kernels),
+	}).Warn("chain reorganized")
+
+	c.smu.Lock()
+	defer c.smu.Unlock()
+
+	c.reorgs = append(c.reorgs, event)
+	if len(c.reorgs) > maxReorgEvents {
+		c.reorgs = append(c.reorgs[:0], c.reorgs[len(c.reorgs)-maxReorgEvents:]...)
+	}
+
+	for ch := range c.reorgSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+)
+
+func TestReorgEvent(t *testing.T) {
+	chain, _ := newTestChain()
+
+	// the fork confirms the transaction of the fee 2 again
+	first := withTxs(child(&Testnet4, 1), 1, 2)
+	second := withTxs(child(first, 1), 3)
+	fork := withTxs(child(&Testnet4, 2), 2)
+
+	ch := make(chan *ReorgEvent, 1)
+	chain.SubscribeReorgs(ch)
+	chain.notifyReorg(NewReorgEvent([]*consensus.Block{first, second}, []*consensus.Block{fork}))
+
+	event := <-ch
+	if event.ForkHeight != 0 || len(event.Disconnected) != 2 || event.Disconnected[1] != second.Hash() ||
+		len(event.Connected) != 1 || event.Connected[0] != fork.Hash() {
+		t.Errorf("unexpected reorg: %+v", event)
+	}
+
+	if len(event.Kernels) != 2 || event.Kernels[0] != first.Kernels[1].Hash() || event.Kernels[1] != second.Kernels[1].Hash() {
+		t.Errorf("unconfirmed kernels were %v, want the fees 1 & 3", event.Kernels)
+	}
+
+	if reorgs := chain.Reorgs(); len(reorgs) != 1 || reorgs[0].ForkHeight != 0 {
+		t.Errorf("recent reorgs were %+v", reorgs)
+	}
+
+	// the full subscriber skips the event, the recent reorgs are bounded
+	chain.UnsubscribeReorgs(ch)
+	for i := 0; i < maxReorgEvents+1; i++ {
+		chain.notifyReorg(NewReorgEvent([]*consensus.Block{second}, nil))
+	}
+
+	if len(ch) != 0 {
+		t.Errorf("unsubscribed channel received the reorg")
+	}
+
+	if reorgs := chain.Reorgs(); len(reorgs) != maxReorgEvents || reorgs[0].ForkHeight != 1 {
+		t.Errorf("kept %d reorgs, want the latest %d", len(reorgs), maxReorgEvents)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"sort"
+	"time"
+)
+
+const (
+	// StatsWindow is the count of the recent blocks of the rolling
+	// statistics
+	StatsWindow = 60
+
+	// stallFactor is the count of the block times without a new block the
+	// chain is stalled after
+	stallFactor = 10
+)
+
+// BlockStats is the rolling statistics of the recent main chain blocks
+type BlockStats struct {
+	// Blocks is the count of the blocks, up to StatsWindow
+	Blocks int
+	// AvgInterval is the average interval of the blocks
+	AvgInterval time.Duration
+	// MedianFeeRate is the median fee per transaction weight of the blocks
+	// with the transactions, the weight of consensus.TxWeight
+	MedianFeeRate uint64
+	// TxBlocks is the count of the blocks with the transactions
+	TxBlocks int
+	// Utilization is the average block weight to consensus.MaxBlockWeight
+	// ratio
+	Utilization float64
+	// LastBlock is the timestamp of the head, Stalled is set if there is no
+	// new block for stallFactor block times
+	LastBlock time.Time
+	Stalled   bool
+}
+
+// blockStat is the interval, the fee rate & the weight of the block
+type blockStat struct {
+	timestamp time.Time
+	// feeRate is the fee per weight of the block transactions, txs is false
+	// if the block has no transactions
+	feeRate uint64
+	txs     bool
+	weight  uint64
+}
+
+// newBlockStat returns the stat of the block, the block transactions are of
+// the kernels & outputs besides the coinbase ones
+func newBlockStat(block *consensus.Block) blockStat {
+	stat := blockStat{
+		timestamp: block.Header.Timestamp,
+		weight:    consensus.BlockWeight(len(block.Inputs), len(block.Outputs), len(block.Kernels)),
+	}
+
+	var fee uint64
+	kernels := 0
+	for i := range block.Kernels {
+		if block.Kernels[i].Features&consensus.CoinbaseKernel != consensus.CoinbaseKernel {
+			fee += block.Kernels[i].Fee
+			kernels++
+		}
+	}
+
+	outputs := 0
+	for i := range block.Outputs {
+		if block.Outputs[i].Features&consensus.CoinbaseOutput != consensus.CoinbaseOutput {
+			outputs++
+		}
+	}
+
+	if kernels > 0 {
+		stat.txs = true
+		stat.feeRate = fee / consensus.TxWeight(len(block.Inputs), outputs, kernels)
+	}
+
+	return stat
+}
+
+// statsWindow is the stats of the recent main chain blocks, the latest last
+type statsWindow struct {
+	// tip is the hash of the latest block, zero if the window is unknown
+	tip    consensus.Hash
+	blocks []blockStat
+}
+
+// push adds the block extending the window tip, the oldest blocks past
+// StatsWindow are dropped. The block not extending the tip resets the window
+func (w *statsWindow) push(block *consensus.Block) {
+	if w.tip != block.Header.Previous {
+		w.tip = consensus.ZeroHash
+		w.blocks = nil
+		return
+	}
+
+	w.blocks = append(w.blocks, newBlockStat(block))
+	if len(w.blocks) > StatsWindow+1 {
+		w.blocks = append(w.blocks[:0], w.blocks[len(w.blocks)-StatsWindow-1:]...)
+	}
+	w.tip = block.Hash()
+}
+
+// BlockStats returns the rolling statistics of the recent blocks at now, the
+// window is read from the storage if it's not of the head
+func (c *Chain) BlockStats(now time.Time) BlockStats {
+	c.Lock()
+	defer c.Unlock()
+
+	head := c.head.Hash()
+	if c.stats.tip != head {
+		// the window base is the block before the first of StatsWindow
+		fromHeight := c.genesis.Header.Height
+		if c.height > fromHeight+StatsWindow {
+			fromHeight = c.height - StatsWindow
+		}
+
+		c.stats.blocks = c.stats.blocks[:0]
+		if fromHeight == c.genesis.Header.Height {
+			c.stats.blocks = append(c.stats.blocks, newBlockStat(c.genesis))
+			fromHeight++
+		}
+		blocks := c.storage.From(consensus.BlockID{Height: &fromHeight}, StatsWindow+1)
+		for i := range blocks {
+			c.stats.blocks = append(c.stats.blocks, newBlockStat(&blocks[i]))
+		}
+		c.stats.tip = head
+	}
+
+	return c.stats.summary(now, c.params.BlockTime)
+}
+
+// summary returns the statistics of the window, the first block is the base
+// of the intervals & is not counted
+func (w *statsWindow) summary(now time.Time, blockTime time.Duration) BlockStats {
+	var stats BlockStats
+	if len(w.blocks) == 0 {
+		return stats
+	}
+
+	last := w.blocks[len(w.blocks)-1]
+	stats.LastBlock = last.timestamp
+	stats.Stalled = blockTime > 0 && now.Sub(last.timestamp) > stallFactor*blockTime
+
+	blocks := w.blocks[1:]
+	if len(blocks) == 0 {
+		return stats
+	}
+
+	stats.Blocks = len(blocks)
+	stats.AvgInterval = last.timestamp.Sub(w.blocks[0].timestamp) / time.Duration(len(blocks))
+
+	var weight uint64
+	var rates []uint64
+	for _, stat := range blocks {
+		weight += stat.weight
+		if stat.txs {
+			rates = append(rates, stat.feeRate)
+		}
+	}
+	stats.Utilization = float64(weight) / float64(len(blocks)) / float64(consensus.MaxBlockWeight)
+
+	stats.TxBlocks = len(rates)
+	if len(rates) > 0 {
+		sort.Slice(rates, func(i, j int) bool { return rates[i] < rates[j] })
+		stats.MedianFeeRate = rates[len(rates)/2]
+	}
+
+	return stats
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// withTxs adds the coinbase & the transaction kernels of the fees, an input &
+// an output per kernel to the block. The kernel excess is the commitment of
+// the fee
+func withTxs(block *consensus.Block, fees ...uint64) *consensus.Block {
+	block.Outputs = append(block.Outputs, consensus.Output{Features: consensus.CoinbaseOutput})
+	block.Kernels = append(block.Kernels, consensus.TxKernel{Features: consensus.CoinbaseKernel, Excess: *secp256k1zkp.CommitValue(big.NewInt(1), big.NewInt(0))})
+
+	for _, fee := range fees {
+		block.Inputs = append(block.Inputs, consensus.Input{})
+		block.Outputs = append(block.Outputs, consensus.Output{})
+		block.Kernels = append(block.Kernels, consensus.TxKernel{Fee: fee, Excess: *secp256k1zkp.CommitValue(new(big.Int).SetUint64(fee+1), big.NewInt(0))})
+	}
+
+	return block
+}
+
+func TestBlockStats(t *testing.T) {
+	chain, _ := newTestChain()
+
+	// the window of the genesis has no blocks
+	if stats := chain.BlockStats(Testnet4.Header.Timestamp); stats.Blocks != 0 || !stats.LastBlock.Equal(Testnet4.Header.Timestamp) {
+		t.Errorf("unexpected stats of the genesis: %+v", stats)
+	}
+
+	// the fee rates are 400/8 & 90/4, the second block has no transactions
+	blocks := []*consensus.Block{withTxs(child(&Testnet4, 1), 100, 300)}
+	blocks = append(blocks, withTxs(child(blocks[0], 1)))
+	blocks = append(blocks, withTxs(child(blocks[1], 1), 90))
+
+	var weight uint64
+	for _, block := range blocks {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+		weight += consensus.BlockWeight(len(block.Inputs), len(block.Outputs), len(block.Kernels))
+	}
+
+	last := blocks[2].Header.Timestamp
+	stats := chain.BlockStats(last.Add(5 * time.Minute))
+	if stats.Blocks != 3 || stats.AvgInterval != time.Minute || stats.TxBlocks != 2 || stats.Stalled {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if stats.MedianFeeRate != 50 {
+		t.Errorf("median fee rate was %d, want 50", stats.MedianFeeRate)
+	}
+
+	if want := float64(weight) / 3 / float64(consensus.MaxBlockWeight); stats.Utilization != want {
+		t.Errorf("utilization was %v, want %v", stats.Utilization, want)
+	}
+
+	if stats := chain.BlockStats(last.Add(time.Hour)); !stats.Stalled {
+		t.Errorf("chain without blocks for an hour was not stalled")
+	}
+}
@@ -4,7 +4,10 @@
 
 package chain
 
-import "github.com/dblokhin/gringo/consensus"
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+)
 
 // Storage represents storage methods for backends
 // Storage doesnt check consensus rules!
@@ -21,4 +24,10 @@ type Storage interface {
 	GetLastBlock() *consensus.Block
 	// Returns list of blocks from id
 	From(id consensus.BlockID, limit int) consensus.BlockList
+	// Returns id of the block with the unspent output by commitment
+	// if not found return nil
+	GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID
+	// Returns id of the main chain block with the kernel by excess within
+	// the height range, if not found return nil
+	GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID
 }
@@ -0,0 +1,122 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"time"
+)
+
+// maxCompetingTips is the count of the competing tips kept by the chain, the
+// first seen are kept
+const maxCompetingTips = 16
+
+// CompetingTip is the block of the head height & total difficulty received
+// after the head. The tip race is won by the first seen block: the head stays
+// until the block extending the competing tip switches the chain to it
+type CompetingTip struct {
+	Hash            consensus.Hash
+	Height          uint64
+	TotalDifficulty consensus.Difficulty
+	Received        time.Time
+}
+
+// competingTip is the retained block of the competing tip
+type competingTip struct {
+	block    *consensus.Block
+	received time.Time
+}
+
+// CompetingTips returns the competing tips of the head, the first seen first
+func (c *Chain) CompetingTips() []CompetingTip {
+	c.RLock()
+	defer c.RUnlock()
+
+	result := make([]CompetingTip, 0, len(c.competing))
+	for _, tip := range c.competing {
+		result = append(result, CompetingTip{
+			Hash:            tip.block.Hash(),
+			Height:          tip.block.Header.Height,
+			TotalDifficulty: tip.block.Header.TotalDifficulty,
+			Received:        tip.received,
+		})
+	}
+
+	return result
+}
+
+// competingBlock returns the block of the competing tip by hash, nil if it's
+// not retained. Must be called with the lock held
+func (c *Chain) competingBlock(hash consensus.Hash) *consensus.Block {
+	for _, tip := range c.competing {
+		if tip.block.Hash() == hash {
+			return tip.block
+		}
+	}
+
+	return nil
+}
+
+// retainTip keeps the sibling of the head as the competing tip, the known
+// one keeps its first seen time. Must be called with the lock held
+func (c *Chain) retainTip(block *consensus.Block) {
+	if c.competingBlock(block.Hash()) != nil || len(c.competing) >= maxCompetingTips {
+		return
+	}
+
+	c.competing = append(c.competing, competingTip{block: block, received: time.Now()})
+}
+
+// pruneTips drops the competing tips not of the head height after the head
+// change. Must be called with the lock held
+func (c *Chain) pruneTips() {
+	tips := c.competing[:0]
+	for _, tip := range c.competing {
+		if tip.block.Header.Previous == c.head.Header.Previous && tip.block.Hash() != c.head.Hash() {
+			tips = append(tips, tip)
+		}
+	}
+	c.competing = tips
+}
+
+// switchTip replaces the head by the competing tip & the block extending it,
+// the replaced head is disconnected. The head is deleted before the intent,
+// so the crash leaves the chain at the common ancestor. Must be called with
+// the lock held
+func (c *Chain) switchTip(tip, block *consensus.Block) error {
+	disconnected := c.head
+	height := disconnected.Header.Height
+	c.storage.DelBlock(consensus.BlockID{Hash: disconnected.Hash(), Height: &height})
+
+	if err := c.beginIntent(opAddBlocks, tip); err != nil {
+		c.loadHead()
+		c.window.reset()
+		return err
+	}
+	c.storage.AddBlock(tip)
+	c.storage.AddBlock(block)
+	c.endIntent()
+
+	c.window.reset()
+	c.head = block
+	c.height = block.Header.Height
+	c.totalDifficulty = block.Header.TotalDifficulty
+	if c.totalDifficulty >= c.headerHead.TotalDifficulty {
+		c.headerHead = block.Header
+	}
+	c.pruneTips()
+
+	c.log.WithFields(logging.Fields{
+		"height":   block.Header.Height,
+		"hash":     block.Hash().String(),
+		"replaced": disconnected.Hash().String(),
+	}).Info("competing tip extended, switched to it")
+
+	c.notifyReorg(NewReorgEvent([]*consensus.Block{disconnected}, []*consensus.Block{tip, block}))
+	c.notify(block)
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+)
+
+func TestTipRace(t *testing.T) {
+	chain, storage := newTestChain()
+
+	first := child(&Testnet4, 1)
+	head := child(first, 2)
+	for _, block := range []*consensus.Block{first, head} {
+		if err := chain.ProcessBlock(context.Background(), block); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	// the first seen block stays the head, the competing one is kept
+	competing := child(first, 3)
+	for i := 0; i < 2; i++ {
+		if err := chain.ProcessBlock(context.Background(), competing); err != nil {
+			t.Fatalf("ProcessBlock failed: %v", err)
+		}
+	}
+
+	if h := chain.Head(); h.Hash() != head.Hash() {
+		t.Errorf("head was %s, want the first seen %s", h.Hash(), head.Hash())
+	}
+
+	tips := chain.CompetingTips()
+	if len(tips) != 1 || tips[0].Hash != competing.Hash() || tips[0].Height != head.Header.Height {
+		t.Fatalf("competing tips were %+v", tips)
+	}
+
+	// the block extending the competing tip switches the chain to it
+	next := child(competing, 1)
+	if err := chain.ProcessBlock(context.Background(), next); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	if h := chain.Head(); h.Hash() != next.Hash() || chain.Height() != next.Header.Height {
+		t.Errorf("head was %s at %d, want %s", h.Hash(), chain.Height(), next.Hash())
+	}
+
+	if header := chain.HeaderHead(); chain.TotalDifficulty() != next.Header.TotalDifficulty || header.Hash() != next.Hash() {
+		t.Errorf("total difficulty was %d, want %d", chain.TotalDifficulty(), next.Header.TotalDifficulty)
+	}
+
+	if storage.blocks[competing.Hash()] == nil || storage.blocks[next.Hash()] == nil {
+		t.Errorf("competing blocks were not stored")
+	}
+
+	reorgs := chain.Reorgs()
+	if len(reorgs) != 1 || reorgs[0].Disconnected[0] != head.Hash() || len(reorgs[0].Connected) != 2 {
+		t.Errorf("reorgs were %+v", reorgs)
+	}
+
+	if tips := chain.CompetingTips(); len(tips) != 0 {
+		t.Errorf("competing tips of the replaced head were %+v", tips)
+	}
+
+	// the replaced head isn't a competing tip of the new head
+	if err := chain.ProcessBlock(context.Background(), child(head, 4)); err != nil {
+		t.Fatalf("ProcessBlock failed: %v", err)
+	}
+
+	if h := chain.Head(); h.Hash() != next.Hash() {
+		t.Errorf("block of the replaced head switched the chain")
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"sort"
+	"sync"
+)
+
+const (
+	// DefaultConfirmations is the confirmations of the watch registered
+	// without them, as the grin wallet
+	DefaultConfirmations = 10
+
+	// MaxWatches is the max count of the registered watches, every watch
+	// is looked up on the new blocks until it's mined
+	MaxWatches = 1000
+
+	// watchBuffer is the count of the new blocks & the reorgs queued to the
+	// watcher
+	watchBuffer = 64
+)
+
+// The kinds of the watched commitments
+const (
+	WatchKernel = "kernel"
+	WatchOutput = "output"
+)
+
+// The watch events
+const (
+	// WatchMined is the commitment found in the main chain
+	WatchMined = "mined"
+	// WatchConfirmed is the commitment reached the watch confirmations
+	WatchConfirmed = "confirmed"
+	// WatchReorged is the mined commitment removed by the reorg
+	WatchReorged = "reorged"
+)
+
+// ErrTooManyWatches is returned by Add if MaxWatches are registered
+var ErrTooManyWatches = errors.New("too many watches")
+
+// Watch is the kernel excess or the output commitment of interest
+type Watch struct {
+	Kind   string
+	Commit secp256k1zkp.Commitment
+	// Confirmations is the count of the blocks the event of confirmed is
+	// emitted at, the block of the commitment included
+	Confirmations uint64
+	// Height is the block height of the commitment, 0 if it's not mined
+	Height    uint64
+	Confirmed bool
+}
+
+// WatchEvent is the change of the watch
+type WatchEvent struct {
+	Watch
+	Event string
+	// Depth is the confirmations of the commitment at the event
+	Depth uint64
+}
+
+// WatchChain is the chain of the watched commitments
+type WatchChain interface {
+	Height() uint64
+	GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID
+	GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID
+	Subscribe(ch chan<- *consensus.Block)
+	Unsubscribe(ch chan<- *consensus.Block)
+	SubscribeReorgs(ch chan<- *ReorgEvent)
+	UnsubscribeReorgs(ch chan<- *ReorgEvent)
+}
+
+// Watcher tracks the confirmations of the registered kernels & outputs, the
+// clients are notified when the commitment is mined, reaches the watch
+// confirmations or is reorged out instead of polling block by block
+type Watcher struct {
+	sync.Mutex
+	chain   WatchChain
+	watches map[string]*Watch
+
+	subscribers map[chan<- *WatchEvent]struct{}
+}
+
+// NewWatcher returns the watcher of the chain
+func NewWatcher(chain WatchChain) *Watcher {
+	return &Watcher{
+		chain:       chain,
+		watches:     make(map[string]*Watch),
+		subscribers: make(map[chan<- *WatchEvent]struct{}),
+	}
+}
+
+// watchKey returns the key of the watch of the commitment
+func watchKey(kind string, commit secp256k1zkp.Commitment) string {
+	return kind + ":" + hex.EncodeToString(commit)
+}
+
+// Add registers the watch of the kernel excess or the output commitment,
+// zero confirmations are DefaultConfirmations. The registered watch is
+// replaced. The commitment already mined is looked up at once
+func (w *Watcher) Add(kind string, commit secp256k1zkp.Commitment, confirmations uint64) (Watch, error) {
+	if kind != WatchKernel && kind != WatchOutput {
+		return Watch{}, fmt.Errorf("unknown watch kind: %s", kind)
+	}
+
+	if len(commit) != secp256k1zkp.PedersenCommitmentSize {
+		return Watch{}, fmt.Errorf("invalid commitment size: %d", len(commit))
+	}
+
+	if confirmations == 0 {
+		confirmations = DefaultConfirmations
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	key := watchKey(kind, commit)
+	if _, ok := w.watches[key]; !ok && len(w.watches) >= MaxWatches {
+		return Watch{}, ErrTooManyWatches
+	}
+
+	watch := &Watch{Kind: kind, Commit: commit, Confirmations: confirmations}
+	w.watches[key] = watch
+	w.update(watch, w.chain.Height())
+
+	return *watch, nil
+}
+
+// Remove unregisters the watch, returns false if it's not registered
+func (w *Watcher) Remove(kind string, commit secp256k1zkp.Commitment) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	key := watchKey(kind, commit)
+	if _, ok := w.watches[key]; !ok {
+		return false
+	}
+
+	delete(w.watches, key)
+	return true
+}
+
+// Watches returns the registered watches by kind & commitment
+func (w *Watcher) Watches() []Watch {
+	w.Lock()
+	defer w.Unlock()
+
+	keys := make([]string, 0, len(w.watches))
+	for key := range w.watches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]Watch, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *w.watches[key])
+	}
+
+	return result
+}
+
+// Subscribe registers ch to receive the watch events, the event is skipped
+// if ch is not ready to receive it
+func (w *Watcher) Subscribe(ch chan<- *WatchEvent) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.subscribers[ch] = struct{}{}
+}
+
+// Unsubscribe removes ch from the subscribers
+func (w *Watcher) Unsubscribe(ch chan<- *WatchEvent) {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.subscribers, ch)
+}
+
+// Run updates the watches on the new blocks & the reorgs of the chain until
+// ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	blocks := make(chan *consensus.Block, watchBuffer)
+	reorgs := make(chan *ReorgEvent, watchBuffer)
+	w.chain.Subscribe(blocks)
+	w.chain.SubscribeReorgs(reorgs)
+	defer w.chain.Unsubscribe(blocks)
+	defer w.chain.UnsubscribeReorgs(reorgs)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event := <-reorgs:
+			w.reorg(event)
+
+		case block := <-blocks:
+			w.connect(block.Header.Height)
+		}
+	}
+}
+
+// connect updates the watches on the new head of the height
+func (w *Watcher) connect(height uint64) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, watch := range w.watches {
+		w.update(watch, height)
+	}
+}
+
+// update looks up the commitment of the watch not mined yet & emits the
+// events of the head height. Must be called with the watcher locked
+func (w *Watcher) update(watch *Watch, height uint64) {
+	if watch.Height == 0 {
+		var id *consensus.BlockID
+		if watch.Kind == WatchKernel {
+			id = w.chain.GetKernel(watch.Commit, 0, height)
+		} else {
+			id = w.chain.GetUnspentOutput(watch.Commit)
+		}
+
+		if id == nil || id.Height == nil || *id.Height > height {
+			return
+		}
+
+		watch.Height = *id.Height
+		w.emit(watch, WatchMined, height)
+	}
+
+	if !watch.Confirmed && height+1-watch.Height >= watch.Confirmations {
+		watch.Confirmed = true
+		w.emit(watch, WatchConfirmed, height)
+	}
+}
+
+// reorg resets the watches mined above the fork height
+func (w *Watcher) reorg(event *ReorgEvent) {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, watch := range w.watches {
+		if watch.Height > event.ForkHeight {
+			w.emit(watch, WatchReorged, 0)
+			watch.Height = 0
+			watch.Confirmed = false
+		}
+	}
+}
+
+// emit sends the event of the watch at the head height to the subscribers.
+// Must be called with the watcher locked
+func (w *Watcher) emit(watch *Watch, event string, height uint64) {
+	e := &WatchEvent{Watch: *watch, Event: event}
+	if height >= watch.Height && watch.Height > 0 {
+		e.Depth = height + 1 - watch.Height
+	}
+
+	for ch := range w.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"testing"
+)
+
+// watchChain is the chain of the kernels & outputs by the hex commitment
+type watchChain struct {
+	height  uint64
+	kernels map[string]uint64
+	outputs map[string]uint64
+}
+
+func (c *watchChain) Height() uint64 { return c.height }
+
+func (c *watchChain) GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID {
+	return c.lookup(c.outputs, commit)
+}
+
+func (c *watchChain) GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID {
+	return c.lookup(c.kernels, excess)
+}
+
+func (c *watchChain) lookup(index map[string]uint64, commit secp256k1zkp.Commitment) *consensus.BlockID {
+	height, ok := index[string(commit)]
+	if !ok {
+		return nil
+	}
+
+	return &consensus.BlockID{Height: &height}
+}
+
+func (c *watchChain) Subscribe(ch chan<- *consensus.Block)    {}
+func (c *watchChain) Unsubscribe(ch chan<- *consensus.Block)  {}
+func (c *watchChain) SubscribeReorgs(ch chan<- *ReorgEvent)   {}
+func (c *watchChain) UnsubscribeReorgs(ch chan<- *ReorgEvent) {}
+
+func TestWatcher(t *testing.T) {
+	c := &watchChain{height: 5, kernels: make(map[string]uint64), outputs: make(map[string]uint64)}
+	w := NewWatcher(c)
+
+	events := make(chan *WatchEvent, 8)
+	w.Subscribe(events)
+
+	excess := secp256k1zkp.Commitment(make([]byte, secp256k1zkp.PedersenCommitmentSize))
+	if watch, err := w.Add(WatchKernel, excess, 3); err != nil || watch.Height != 0 {
+		t.Fatalf("unexpected watch: %+v, %v", watch, err)
+	}
+
+	// mined at 6, confirmed by the head 8
+	c.height, c.kernels[string(excess)] = 6, 6
+	w.connect(6)
+	if e := <-events; e.Event != WatchMined || e.Height != 6 || e.Depth != 1 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	w.connect(7)
+	w.connect(8)
+	if e := <-events; e.Event != WatchConfirmed || e.Depth != 3 || !e.Confirmed {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	// the reorg above the fork height resets the watch
+	delete(c.kernels, string(excess))
+	w.reorg(&ReorgEvent{ForkHeight: 5})
+	if e := <-events; e.Event != WatchReorged || e.Height != 6 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if watches := w.Watches(); len(watches) != 1 || watches[0].Height != 0 || watches[0].Confirmed {
+		t.Errorf("watch was not reset: %+v", watches)
+	}
+
+	// the output mined deep enough is confirmed at once
+	commit := secp256k1zkp.Commitment(make([]byte, secp256k1zkp.PedersenCommitmentSize))
+	commit[0] = 1
+	c.outputs[string(commit)] = 1
+	if watch, err := w.Add(WatchOutput, commit, 0); err != nil || watch.Confirmations != DefaultConfirmations || watch.Confirmed {
+		t.Errorf("unexpected watch: %+v, %v", watch, err)
+	}
+	if watch, _ := w.Add(WatchOutput, commit, 2); !watch.Confirmed {
+		t.Errorf("output was not confirmed: %+v", watch)
+	}
+
+	if _, err := w.Add("tx", commit, 0); err == nil {
+		t.Errorf("unknown kind was added")
+	}
+	if _, err := w.Add(WatchOutput, commit[1:], 0); err == nil {
+		t.Errorf("short commitment was added")
+	}
+
+	if !w.Remove(WatchOutput, commit) || w.Remove(WatchOutput, commit) {
+		t.Errorf("output watch was not removed once")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package client is the Go client of the node API: the foreign & the owner
+// JSON-RPC methods with the typed results of the api package, and the
+// websocket subscriptions of the node events
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"golang.org/x/net/websocket"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the deadline of the request without the context one
+	DefaultTimeout = 30 * time.Second
+
+	// maxResponse is the max size of the API response
+	maxResponse = 64 << 20
+
+	// subscriptionBuffer is the count of the events queued to the
+	// subscription channel
+	subscriptionBuffer = 16
+)
+
+// ErrNotFound is returned if the node has no requested block, output or
+// kernel
+var ErrNotFound = errors.New("not found")
+
+// Error is the error of the node API: the JSON-RPC error object, the error
+// result of the method or the REST error
+type Error struct {
+	// Code is the JSON-RPC error code or the HTTP status of the REST error,
+	// 0 is the error result of the method
+	Code    int
+	Message string
+}
+
+// Error implements error interface
+func (e *Error) Error() string {
+	if e.Code == 0 {
+		return "node api error: " + e.Message
+	}
+
+	return fmt.Sprintf("node api error %d: %s", e.Code, e.Message)
+}
+
+// Client is the client of the node API listener: the foreign one of
+// NewForeign or the owner one of NewOwner
+type Client struct {
+	url      string
+	endpoint string
+	secret   string
+	tls      *tls.Config
+	http     *http.Client
+
+	id uint64
+}
+
+// newClient returns the client of the API url serving the JSON-RPC endpoint
+func newClient(url, endpoint, secret string) *Client {
+	return &Client{
+		url:      strings.TrimSuffix(url, "/"),
+		endpoint: endpoint,
+		secret:   secret,
+		http:     &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// NewForeign returns the client of the foreign API of the url, e.g.
+// http://127.0.0.1:13413 or the url of the reverse proxy location with the
+// base path. Empty secret is the API without the auth
+func NewForeign(url, secret string) *Foreign {
+	return &Foreign{newClient(url, "/v2/foreign", secret)}
+}
+
+// NewOwner returns the client of the owner API of the url, e.g.
+// http://127.0.0.1:13420
+func NewOwner(url, secret string) *Owner {
+	return &Owner{newClient(url, "/v2/owner", secret)}
+}
+
+// SetTLS sets the TLS settings of the https & wss urls, as the CA roots
+// trusting the self-signed certificate of the node
+func (c *Client) SetTLS(config *tls.Config) {
+	c.tls = config
+	c.http = &http.Client{
+		Timeout:   c.http.Timeout,
+		Transport: &http.Transport{TLSClientConfig: config},
+	}
+}
+
+// SetHTTPClient replaces the http client of the requests
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.http = client
+}
+
+// Call requests the JSON-RPC method of the positional params & decodes the
+// Ok result to result, nil result discards it
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      atomic.AddUint64(&c.id, 1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Result *struct {
+			Ok  json.RawMessage `json:"Ok"`
+			Err json.RawMessage `json:"Err"`
+		} `json:"result"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body), &response); err != nil {
+		return err
+	}
+
+	switch {
+	case response.Error != nil:
+		return &Error{Code: response.Error.Code, Message: response.Error.Message}
+	case response.Result == nil:
+		return fmt.Errorf("no result of %s", method)
+	case len(response.Result.Err) > 0:
+		return resultError(response.Result.Err)
+	}
+
+	if result == nil || len(response.Result.Ok) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(response.Result.Ok, result)
+}
+
+// resultError returns the error of the {"Err": ...} result
+func resultError(raw json.RawMessage) error {
+	var kind string
+	if err := json.Unmarshal(raw, &kind); err == nil {
+		if kind == "NotFound" {
+			return ErrNotFound
+		}
+		return &Error{Message: kind}
+	}
+
+	var internal struct {
+		Internal string `json:"Internal"`
+	}
+	if err := json.Unmarshal(raw, &internal); err == nil && internal.Internal != "" {
+		return &Error{Message: internal.Internal}
+	}
+
+	return &Error{Message: string(raw)}
+}
+
+// get requests the REST path & decodes the json response to result
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, result)
+}
+
+// do requests the path of the API & decodes the json response to result
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.secret != "" {
+		req.SetBasicAuth(api.BasicAuthUser, c.secret)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = string(bytes.TrimSpace(data))
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			return ErrNotFound
+		}
+		return &Error{Code: resp.StatusCode, Message: e.Error}
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+// subscribe opens the websocket of the path & calls receive for the messages
+// until it fails or ctx is done, done is called then
+func (c *Client) subscribe(ctx context.Context, path string, receive func(ws *websocket.Conn) error, done func()) error {
+	url := c.url + path
+	switch {
+	case strings.HasPrefix(url, "https://"):
+		url = "wss://" + strings.TrimPrefix(url, "https://")
+	case strings.HasPrefix(url, "http://"):
+		url = "ws://" + strings.TrimPrefix(url, "http://")
+	}
+
+	config, err := websocket.NewConfig(url, c.url)
+	if err != nil {
+		return err
+	}
+	config.TlsConfig = c.tls
+	if c.secret != "" {
+		req := http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(api.BasicAuthUser, c.secret)
+		config.Header = req.Header
+	}
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		ws.Close()
+	}()
+
+	go func() {
+		defer done()
+		defer close(stop)
+
+		for receive(ws) == nil {
+		}
+	}()
+
+	return nil
+}
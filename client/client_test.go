@@ -0,0 +1,110 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dblokhin/gringo/api"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// rpcServer returns the JSON-RPC server of the results by method, checking
+// the basic auth of the secret
+func rpcServer(t *testing.T, secret string, results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != api.BasicAuthUser || pass != secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []interface{}   `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v2/foreign" {
+			t.Errorf("invalid request %s: %v", r.URL.Path, err)
+		}
+
+		result, ok := results[req.Method]
+		if !ok {
+			result = `"error": {"code": -32601, "message": "method not found"}`
+		}
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": ` + string(req.ID) + `, ` + result + `}`))
+	}))
+}
+
+func TestCall(t *testing.T) {
+	server := rpcServer(t, "secret", map[string]string{
+		"get_tip":       `"result": {"Ok": {"height": 7, "last_block_pushed": "aa"}}`,
+		"get_kernel":    `"result": {"Err": "NotFound"}`,
+		"get_pool_size": `"result": {"Ok": 3}`,
+		"get_block":     `"result": {"Err": {"Internal": "storage failed"}}`,
+	})
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewForeign(server.URL+"/", "secret")
+
+	if tip, err := c.Tip(ctx); err != nil || tip.Height != 7 || tip.LastBlockPushed != "aa" {
+		t.Errorf("unexpected tip: %+v, %v", tip, err)
+	}
+
+	if size, err := c.PoolSize(ctx); err != nil || size != 3 {
+		t.Errorf("pool size was %d, %v", size, err)
+	}
+
+	if _, err := c.Kernel(ctx, "08", nil, nil); err != ErrNotFound {
+		t.Errorf("missing kernel error was %v", err)
+	}
+
+	if _, err := c.Block(ctx, 1); err == nil || err.Error() != "node api error: storage failed" {
+		t.Errorf("internal error was %v", err)
+	}
+
+	if err := c.Call(ctx, "get_nothing", nil); err == nil || err.(*Error).Code != -32601 {
+		t.Errorf("rpc error was %v", err)
+	}
+
+	if _, err := NewForeign(server.URL, "wrong").Tip(ctx); err == nil || err.(*Error).Code != http.StatusUnauthorized {
+		t.Errorf("auth error was %v", err)
+	}
+}
+
+func TestSubscribeReorgs(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		if r := ws.Request(); r.URL.Path != "/grin/v1/chain/reorgs/ws" || r.Header.Get("Authorization") == "" {
+			t.Errorf("unexpected websocket request %s", r.URL.Path)
+			return
+		}
+
+		for height := uint64(1); height <= 2; height++ {
+			websocket.JSON.Send(ws, api.Reorg{ForkHeight: height})
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reorgs, err := NewForeign(server.URL+"/grin", "secret").SubscribeReorgs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var heights []uint64
+	for reorg := range reorgs {
+		heights = append(heights, reorg.ForkHeight)
+	}
+
+	// the channel is closed on the server close of the connection
+	if len(heights) != 2 || heights[0] != 1 || heights[1] != 2 {
+		t.Errorf("fork heights were %v", heights)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/api"
+	"golang.org/x/net/websocket"
+)
+
+// Foreign is the client of the node foreign API
+type Foreign struct {
+	*Client
+}
+
+// Status returns the node status & the sync progress
+func (f *Foreign) Status(ctx context.Context) (*api.Status, error) {
+	var status api.Status
+	if err := f.Call(ctx, "get_status", &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// Tip returns the head of the chain
+func (f *Foreign) Tip(ctx context.Context) (*api.Tip, error) {
+	var tip api.Tip
+	if err := f.Call(ctx, "get_tip", &tip); err != nil {
+		return nil, err
+	}
+
+	return &tip, nil
+}
+
+// Block returns the block of the height
+func (f *Foreign) Block(ctx context.Context, height uint64) (*api.BlockPrintable, error) {
+	var block api.BlockPrintable
+	if err := f.Call(ctx, "get_block", &block, height); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// BlockByHash returns the block of the hex hash
+func (f *Foreign) BlockByHash(ctx context.Context, hash string) (*api.BlockPrintable, error) {
+	var block api.BlockPrintable
+	if err := f.Call(ctx, "get_block", &block, nil, hash); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// Header returns the block header of the height
+func (f *Foreign) Header(ctx context.Context, height uint64) (*api.BlockHeaderPrintable, error) {
+	var header api.BlockHeaderPrintable
+	if err := f.Call(ctx, "get_header", &header, height); err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
+
+// Kernel returns the main chain kernel of the hex excess within the optional
+// heights
+func (f *Foreign) Kernel(ctx context.Context, excess string, minHeight, maxHeight *uint64) (*api.LocatedTxKernel, error) {
+	var kernel api.LocatedTxKernel
+	if err := f.Call(ctx, "get_kernel", &kernel, excess, minHeight, maxHeight); err != nil {
+		return nil, err
+	}
+
+	return &kernel, nil
+}
+
+// Outputs returns the unspent outputs of the hex commitments, the spent &
+// unknown ones are skipped
+func (f *Foreign) Outputs(ctx context.Context, commits []string) ([]api.OutputPrintable, error) {
+	var outputs []api.OutputPrintable
+	if err := f.Call(ctx, "get_outputs", &outputs, commits); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// PoolSize returns the count of the pool transactions
+func (f *Foreign) PoolSize(ctx context.Context) (int, error) {
+	var size int
+	err := f.Call(ctx, "get_pool_size", &size)
+
+	return size, err
+}
+
+// PushTx adds the hex serialized transaction to the node pool, fluff skips
+// the Dandelion stem phase
+func (f *Foreign) PushTx(ctx context.Context, txHex string, fluff bool) error {
+	return f.Call(ctx, "push_transaction", nil, txHex, fluff)
+}
+
+// BlockStats returns the rolling statistics of the recent blocks
+func (f *Foreign) BlockStats(ctx context.Context) (*api.BlockStats, error) {
+	var stats api.BlockStats
+	if err := f.Call(ctx, "get_block_stats", &stats); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// NetworkHashrate returns the network graph rate estimated by the recent
+// blocks, 0 is api.DefaultHashrateWindow
+func (f *Foreign) NetworkHashrate(ctx context.Context, blocks int) (*api.NetworkHashrate, error) {
+	var params []interface{}
+	if blocks > 0 {
+		params = append(params, blocks)
+	}
+
+	var rate api.NetworkHashrate
+	if err := f.Call(ctx, "get_network_hashrate", &rate, params...); err != nil {
+		return nil, err
+	}
+
+	return &rate, nil
+}
+
+// ChainTips returns the head & the competing tips of its height
+func (f *Foreign) ChainTips(ctx context.Context) (*api.ChainTips, error) {
+	var tips api.ChainTips
+	if err := f.Call(ctx, "get_chain_tips", &tips); err != nil {
+		return nil, err
+	}
+
+	return &tips, nil
+}
+
+// Reorgs returns the recent reorgs of the chain, the latest last
+func (f *Foreign) Reorgs(ctx context.Context) ([]api.Reorg, error) {
+	var reorgs []api.Reorg
+	if err := f.get(ctx, "/v1/chain/reorgs", &reorgs); err != nil {
+		return nil, err
+	}
+
+	return reorgs, nil
+}
+
+// SubscribeReorgs streams the reorgs of the chain until ctx is done or the
+// connection fails, the channel is closed then
+func (f *Foreign) SubscribeReorgs(ctx context.Context) (<-chan api.Reorg, error) {
+	ch := make(chan api.Reorg, subscriptionBuffer)
+
+	receive := func(ws *websocket.Conn) error {
+		var reorg api.Reorg
+		if err := websocket.JSON.Receive(ws, &reorg); err != nil {
+			return err
+		}
+
+		select {
+		case ch <- reorg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := f.subscribe(ctx, "/v1/chain/reorgs/ws", receive, func() { close(ch) }); err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/mining"
+	"golang.org/x/net/websocket"
+)
+
+// Owner is the client of the node owner API
+type Owner struct {
+	*Client
+}
+
+// Peers returns the peers of the state: all, connected or banned
+func (o *Owner) Peers(ctx context.Context, state string) ([]api.PeerInfo, error) {
+	var peers []api.PeerInfo
+	if err := o.Call(ctx, "get_peers", &peers, state); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+// BanPeer bans the peer host:port or the CIDR range
+func (o *Owner) BanPeer(ctx context.Context, addr string) error {
+	return o.Call(ctx, "ban_peer", nil, addr)
+}
+
+// UnbanPeer unbans the peer host:port or the CIDR range
+func (o *Owner) UnbanPeer(ctx context.Context, addr string) error {
+	return o.Call(ctx, "unban_peer", nil, addr)
+}
+
+// MiningStatus returns the status of the miner controls
+func (o *Owner) MiningStatus(ctx context.Context) (*mining.Status, error) {
+	var status mining.Status
+	if err := o.Call(ctx, "get_mining_status", &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// PauseMining pauses the miner until ResumeMining
+func (o *Owner) PauseMining(ctx context.Context) error {
+	return o.Call(ctx, "pause_mining", nil)
+}
+
+// ResumeMining resumes the miner paused by PauseMining
+func (o *Owner) ResumeMining(ctx context.Context) error {
+	return o.Call(ctx, "resume_mining", nil)
+}
+
+// AddWatch registers the watch of the hex commitment of the kind, the
+// kernel excess or the output commitment, until the confirmations. Zero
+// confirmations are the node default
+func (o *Owner) AddWatch(ctx context.Context, kind, commit string, confirmations uint64) (*api.Watch, error) {
+	var watch api.Watch
+	if err := o.Call(ctx, "add_watch", &watch, kind, commit, confirmations); err != nil {
+		return nil, err
+	}
+
+	return &watch, nil
+}
+
+// Watches returns the registered watches
+func (o *Owner) Watches(ctx context.Context) ([]api.Watch, error) {
+	var watches []api.Watch
+	if err := o.Call(ctx, "get_watches", &watches); err != nil {
+		return nil, err
+	}
+
+	return watches, nil
+}
+
+// RemoveWatch unregisters the watch of the hex commitment of the kind
+func (o *Owner) RemoveWatch(ctx context.Context, kind, commit string) error {
+	return o.Call(ctx, "remove_watch", nil, kind, commit)
+}
+
+// SubscribeWatches streams the events of the watches until ctx is done or
+// the connection fails, the channel is closed then
+func (o *Owner) SubscribeWatches(ctx context.Context) (<-chan api.WatchEvent, error) {
+	ch := make(chan api.WatchEvent, subscriptionBuffer)
+
+	receive := func(ws *websocket.Conn) error {
+		var event api.WatchEvent
+		if err := websocket.JSON.Receive(ws, &event); err != nil {
+			return err
+		}
+
+		select {
+		case ch <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if err := o.subscribe(ctx, "/v1/watches/ws", receive, func() { close(ch) }); err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
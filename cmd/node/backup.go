@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/config"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// chainBackup writes the snapshot of the running node chain to the file, the
+// file is replaced once the snapshot is complete
+func chainBackup(args []string) error {
+	cfg, path, err := backupArgs("backup", args)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ownerDo(cfg, http.MethodGet, "/v1/chain/backup", nil, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	n, err := io.Copy(f, resp.Body)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("chain of height %s backed up to %s (%d bytes)\n", resp.Header.Get(api.BackupHeightHeader), path, n)
+
+	return nil
+}
+
+// chainRestore sends the blocks of the backup file to the running node, the
+// node validates & processes them as the blocks of the peers
+func chainRestore(args []string) error {
+	cfg, path, err := backupArgs("restore", args)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	resp, err := ownerDo(cfg, http.MethodPost, "/v1/chain/restore", f, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(body))
+
+	return nil
+}
+
+// backupArgs returns the config & the backup file of the chain backup or
+// restore args
+func backupArgs(kind string, args []string) (*config.Config, string, error) {
+	var opts options
+	fs := newFlagSet("chain "+kind, &opts)
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() != 1 {
+		return nil, "", fmt.Errorf("usage: chain %s [flags] <path>", kind)
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cfg.API.OwnerListenAddr == "" {
+		return nil, "", errors.New("owner api is disabled: api.owner_listen_addr is not set")
+	}
+
+	return cfg, fs.Arg(0), nil
+}
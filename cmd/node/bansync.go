@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/bansync"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// syncBans shares the ban list of the pool with the trusted nodes of the
+// ban_sync settings, the secret defaults to the owner API one
+func syncBans(cfg *config.Config, pool p2p.PeersPool) error {
+	path := cfg.BanSync.SecretPath
+	if path == "" {
+		path = cfg.API.SecretPath
+	}
+
+	secret, err := apiSecret(cfg, path)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("ban list syncing with %d nodes every %ds", len(cfg.BanSync.Nodes), cfg.BanSync.Interval)
+	go bansync.NewSyncer(cfg.BanSync.Nodes, secret, time.Duration(cfg.BanSync.Interval)*time.Second, pool).Run(context.Background())
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"golang.org/x/crypto/blake2b"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// auditKeyFile is the default signing key of the audit reports, relative to
+// the chain dir
+const auditKeyFile = "audit_key"
+
+// chainCommand runs chain subcommands
+func chainCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: chain info|audit|block|header|backup|restore [flags]")
+	}
+
+	switch args[0] {
+	case "info":
+		return chainInfo(args[1:])
+	case "audit":
+		return chainAudit(args[1:])
+	case "block":
+		return chainBlock("block", args[1:])
+	case "header":
+		return chainBlock("header", args[1:])
+	case "backup":
+		return chainBackup(args[1:])
+	case "restore":
+		return chainRestore(args[1:])
+	default:
+		return fmt.Errorf("unknown chain command: %s", args[0])
+	}
+}
+
+// chainInfo prints the head of the chain from the data directory
+func chainInfo(args []string) error {
+	var opts options
+	if err := newFlagSet("chain info", &opts).Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	chain, _, err := openChain(cfg)
+	if err != nil {
+		return err
+	}
+
+	head := chain.Head()
+	genesis := chain.Genesis()
+
+	fmt.Printf("network:          %s\n", cfg.Network)
+	fmt.Printf("genesis:          %s\n", genesis.Hash())
+	fmt.Printf("height:           %d\n", chain.Height())
+	fmt.Printf("head:             %s\n", head.Hash())
+	fmt.Printf("total difficulty: %d\n", chain.TotalDifficulty())
+
+	return nil
+}
+
+// chainBlock prints the json of the block or the header of the hash or
+// height: from the running node, or from the data directory if offline
+func chainBlock(kind string, args []string) error {
+	var opts options
+	fs := newFlagSet("chain "+kind, &opts)
+	offline := fs.Bool("offline", false, "read the data directory instead of the running node")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: chain %s [flags] <hash|height>", kind)
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	var result interface{}
+	if *offline {
+		result, err = localBlock(cfg, kind, fs.Arg(0))
+	} else {
+		var raw json.RawMessage
+		err = foreignGet(cfg, "/v1/"+kind+"s/"+fs.Arg(0), &raw)
+		result = raw
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// localBlock returns the printable block or header of the hex hash or
+// height from the data directory
+func localBlock(cfg *config.Config, kind, param string) (interface{}, error) {
+	var id consensus.BlockID
+	if len(param) == 2*consensus.BlockHashSize {
+		hash, err := consensus.ParseHash(param)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block hash: %v", err)
+		}
+		id.Hash = hash
+	} else {
+		height, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block height: %s", param)
+		}
+		id.Height = &height
+	}
+
+	c, _, err := openChain(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// genesis is not stored in the storage
+	block := c.GetBlockID(id)
+	if genesis := c.Genesis(); (id.Height != nil && *id.Height == genesis.Header.Height) ||
+		(!id.Hash.IsZero() && id.Hash == genesis.Hash()) {
+		block = &genesis
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", param)
+	}
+
+	if kind == "header" {
+		return api.NewHeaderPrintable(&block.Header), nil
+	}
+
+	return api.NewBlockPrintable(block, func(o *consensus.Output) bool {
+		return c.GetUnspentOutput(o.Commit.Bytes()) == nil
+	}), nil
+}
+
+// AuditReport is the supply audit of the chain
+type AuditReport struct {
+	Network string `json:"network"`
+	Height  uint64 `json:"height"`
+	Head    string `json:"head"`
+	Outputs int    `json:"utxo_count"`
+	Kernels int    `json:"kernel_count"`
+	// Emission is the expected emission in nanogrins
+	Emission  uint64 `json:"expected_emission"`
+	UTXOSum   string `json:"utxo_sum"`
+	KernelSum string `json:"kernel_sum"`
+	Valid     bool   `json:"valid"`
+}
+
+// SignedAuditReport is the report signed by the node key: the schnorr
+// signature of the blake2b-256 hash of the report json
+type SignedAuditReport struct {
+	Report    json.RawMessage `json:"report"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+// chainAudit prints the signed supply audit of the chain from the data
+// directory, the inflated supply returns error after the report is printed
+func chainAudit(args []string) error {
+	var opts options
+	fs := newFlagSet("chain audit", &opts)
+	keyFile := fs.String("key", "", "signing key file of the report (default: <datadir>/"+auditKeyFile+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	path := *keyFile
+	if path == "" {
+		path = filepath.Join(cfg.ChainDir(), auditKeyFile)
+	}
+
+	key, err := readKey(path)
+	if err != nil {
+		return err
+	}
+
+	c, _, err := openChain(cfg)
+	if err != nil {
+		return err
+	}
+
+	supply, auditErr := c.Audit(context.Background())
+	if supply == nil {
+		return auditErr
+	}
+
+	signed, err := signAuditReport(AuditReport{
+		Network:   cfg.Network,
+		Height:    supply.Height,
+		Head:      supply.Head.String(),
+		Outputs:   supply.Outputs,
+		Kernels:   supply.Kernels,
+		Emission:  supply.Emission,
+		UTXOSum:   pointHex(supply.UTXOSum),
+		KernelSum: pointHex(supply.KernelSum),
+		Valid:     supply.Valid(),
+	}, key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+
+	return auditErr
+}
+
+// signAuditReport returns the report signed by key
+func signAuditReport(report AuditReport, key *big.Int) (*SignedAuditReport, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := bulletproofs.ScalarMulPoint(&secp256k1zkp.G, key)
+	compressed := secp256k1zkp.CompressPubkey(*publicKey)
+	signature := secp256k1zkp.SignMessage(*publicKey, *key, blake2b.Sum256(data)).Bytes()
+
+	return &SignedAuditReport{
+		Report:    data,
+		PublicKey: hex.EncodeToString(compressed[:]),
+		Signature: hex.EncodeToString(signature[:]),
+	}, nil
+}
+
+// readKey returns the hex secret key of the file: the audit or the node
+// identity key, the random key is generated if the file is missing
+func readKey(file string) (*big.Int, error) {
+	data, err := ioutil.ReadFile(file)
+	if err == nil {
+		key, ok := new(big.Int).SetString(strings.TrimSpace(string(data)), 16)
+		if !ok || key.Sign() == 0 {
+			return nil, fmt.Errorf("invalid secret key in %s", file)
+		}
+
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := secp256k1zkp.RandomInt()
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(file, []byte(fmt.Sprintf("%064x\n", key)), 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// pointHex returns the hex of the commitment, empty for nil
+func pointHex(p *bulletproofs.Point) string {
+	if p == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(p.Bytes())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+)
+
+// crawlCommand handshakes the seeds of the network & the addrs they advertise
+// and dumps the json snapshot of the network topology
+func crawlCommand(args []string) error {
+	var opts options
+	fs := newFlagSet("crawl", &opts)
+	timeout := fs.Duration("timeout", p2p.DefaultCrawlTimeout, "deadline of the probe of the node")
+	workers := fs.Int("workers", p2p.DefaultCrawlWorkers, "count of the nodes probed at once")
+	limit := fs.Int("limit", p2p.DefaultCrawlLimit, "max count of the probed nodes")
+	out := fs.String("out", "", "file of the snapshot (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	if *workers <= 0 || *limit <= 0 || *timeout <= 0 {
+		return fmt.Errorf("invalid crawl settings: workers %d, limit %d, timeout %s", *workers, *limit, *timeout)
+	}
+
+	params := chain.Networks[cfg.Network]
+	netParams := consensusParams(cfg.Consensus, *params.Consensus)
+	consensus.SetLimits(netParams.Limits)
+
+	crawler := p2p.NewCrawler(netParams, params.Genesis.Hash())
+	crawler.Timeout, crawler.Workers, crawler.Limit = *timeout, *workers, *limit
+
+	seeds, dnsSeeds := networkSeeds(cfg)
+	seeds = append(seeds, resolveSeeds(dnsSeeds)...)
+	if len(seeds) == 0 {
+		return fmt.Errorf("no seeds of %s to crawl", cfg.Network)
+	}
+
+	// the interrupt stops the crawl & the probed nodes are dumped
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	snapshot := crawler.Crawl(ctx, seeds)
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+
+	if err := ioutil.WriteFile(*out, data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("crawled %d nodes, %d reachable in %s\n", len(snapshot.Nodes), snapshot.Reachable, snapshot.Duration)
+	return nil
+}
+
+// resolveSeeds returns the addrs of the DNS seeds, the unresolved seeds are
+// skipped
+func resolveSeeds(dnsSeeds []string) []string {
+	var addrs []string
+	for _, seed := range dnsSeeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			continue
+		}
+
+		ips, err := net.LookupHost(host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to resolve dns seed %s: %v\n", host, err)
+			continue
+		}
+
+		for _, ip := range ips {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+
+	return addrs
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/dblokhin/gringo/config"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+)
+
+// the files of the node in the chain dir
+const (
+	nodeKeyFile = "node_key"
+	banFile     = "banned_peers"
+	intentFile  = "chain_intent"
+	apiCertFile = "api_tls.crt"
+	apiKeyFile  = "api_tls.key"
+)
+
+// dataFiles returns the files of the node relative to the chain dir
+func dataFiles(cfg *config.Config) []string {
+	files := []string{nodeKeyFile, banFile, intentFile, apiCertFile, apiKeyFile, coinbaseKeyFile, auditKeyFile}
+	for _, path := range []string{cfg.API.SecretPath, cfg.API.ForeignSecretPath} {
+		if path != "" && !filepath.IsAbs(path) {
+			files = append(files, path)
+		}
+	}
+
+	return files
+}
+
+// prepareDataDir creates the chain dir of the network. The files of the
+// flat layout are moved to the chain dir created first, so the network the
+// node was run on before keeps its state
+func prepareDataDir(cfg *config.Config) error {
+	dir := cfg.ChainDir()
+	if dir == cfg.DataDir {
+		return os.MkdirAll(dir, 0700)
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	for _, file := range dataFiles(cfg) {
+		old := filepath.Join(cfg.DataDir, file)
+		if _, err := os.Stat(old); err != nil {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0700); err != nil {
+			return err
+		}
+
+		if err := os.Rename(old, filepath.Join(dir, file)); err != nil {
+			return err
+		}
+		logrus.Infof("moved %s to %s", old, dir)
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/yoss22/bulletproofs"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+)
+
+var (
+	pointType         = reflect.TypeOf(bulletproofs.Point{})
+	rangeProofType    = reflect.TypeOf(bulletproofs.BulletProof{})
+	addrType          = reflect.TypeOf(net.TCPAddr{})
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// decodeCommand prints the json of the wire message of the file or stdin, the
+// hex or the binary of the header & the body, or of the body of --type
+func decodeCommand(args []string) error {
+	var opts options
+	fs := newFlagSet("decode", &opts)
+	msgType := fs.String("type", "", "type of the message body without the header: "+strings.Join(p2p.MessageNames(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: decode [flags] [file]")
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	params := chain.Networks[cfg.Network]
+	netParams := consensusParams(cfg.Consensus, *params.Consensus)
+	consensus.SetLimits(netParams.Limits)
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 && fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	data, err := readCapture(in)
+	if err != nil {
+		return err
+	}
+
+	var msg p2p.Message
+	if *msgType != "" {
+		t, err := p2p.ParseMessageType(*msgType)
+		if err != nil {
+			return err
+		}
+		msg, err = p2p.DecodeBody(t, data)
+		return printMessage(os.Stdout, msg, err)
+	}
+
+	header, msg, err := p2p.DecodeMessage(data, netParams)
+	if header == nil {
+		return err
+	}
+	fmt.Printf("%s message of %d bytes\n", p2p.MessageTypeName(header.Type), header.Len)
+
+	return printMessage(os.Stdout, msg, err)
+}
+
+// readCapture returns the bytes of the capture: the hex, the whitespace is
+// ignored, or the binary
+func readCapture(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.Join(strings.Fields(string(data)), "")
+	if decoded, err := hex.DecodeString(text); err == nil && text != "" {
+		return decoded, nil
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("nothing to decode")
+	}
+
+	return data, nil
+}
+
+// printMessage prints the json of the decoded message, the message partly
+// decoded is printed before the decoding error is returned
+func printMessage(w io.Writer, msg p2p.Message, decodeErr error) error {
+	if msg == nil {
+		return decodeErr
+	}
+
+	var buf bytes.Buffer
+	if err := dumpValue(&buf, reflect.ValueOf(msg)); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, out.String())
+
+	return decodeErr
+}
+
+// dumpValue writes the json of the value: the exported struct fields in the
+// wire order, the embedded structs flattened, the bytes, the points & the
+// range proofs as hex and the addrs as host:port
+func dumpValue(buf *bytes.Buffer, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return dumpValue(buf, v.Elem())
+	}
+
+	if v.CanAddr() {
+		switch v.Type() {
+		case pointType:
+			return writeJSON(buf, hex.EncodeToString(v.Addr().Interface().(*bulletproofs.Point).Bytes()))
+		case rangeProofType:
+			return writeJSON(buf, hex.EncodeToString(v.Addr().Interface().(*bulletproofs.BulletProof).Bytes()))
+		case addrType:
+			return writeJSON(buf, v.Addr().Interface().(*net.TCPAddr).String())
+		}
+
+		ptr := reflect.PtrTo(v.Type())
+		if v.CanInterface() && (ptr.Implements(marshalerType) || ptr.Implements(textMarshalerType)) {
+			return writeJSON(buf, v.Addr().Interface())
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		if err := dumpFields(buf, v, &first); err != nil {
+			return err
+		}
+		buf.WriteByte('}')
+
+	case reflect.Array, reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return writeJSON(buf, hex.EncodeToString(data))
+		}
+
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := dumpValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	default:
+		return writeJSON(buf, v.Interface())
+	}
+
+	return nil
+}
+
+// dumpFields writes the exported fields of the struct, the fields of the
+// embedded structs are written in place
+func dumpFields(buf *bytes.Buffer, v reflect.Value, first *bool) error {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := dumpFields(buf, v.Field(i), first); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+
+		writeJSON(buf, field.Name)
+		buf.WriteByte(':')
+		if err := dumpValue(buf, v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeJSON writes the json of the value
+func writeJSON(buf *bytes.Buffer, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(data)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"io"
+	"os"
+	"strconv"
+)
+
+// hashrateCommand prints the network graph rate of the primary & the
+// secondary proofs of work estimated by the running node
+func hashrateCommand(args []string) error {
+	var opts options
+	fs := newFlagSet("hashrate", &opts)
+	blocks := fs.Int("blocks", api.DefaultHashrateWindow, "count of the recent blocks to estimate by")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	if !cfg.API.Enabled {
+		return errors.New("api is disabled: api.enabled is false")
+	}
+
+	var rate api.NetworkHashrate
+	if err := foreignGet(cfg, "/v1/chain/hashrate?blocks="+strconv.Itoa(*blocks), &rate); err != nil {
+		return err
+	}
+
+	printHashrate(os.Stdout, &rate)
+	return nil
+}
+
+// printHashrate prints the graph rates of the proofs of work
+func printHashrate(w io.Writer, rate *api.NetworkHashrate) {
+	fmt.Fprintf(w, "height:     %d\n", rate.Height)
+	fmt.Fprintf(w, "blocks:     %d\n", rate.Blocks)
+	fmt.Fprintf(w, "block time: %.1fs\n", rate.BlockTime)
+
+	for _, pow := range []struct {
+		name string
+		rate api.PowHashrate
+	}{{"primary", rate.Primary}, {"secondary", rate.Secondary}} {
+		if pow.rate.Blocks == 0 {
+			fmt.Fprintf(w, "%-10s  no blocks\n", pow.name+":")
+			continue
+		}
+
+		fmt.Fprintf(w, "%-10s  C%d %.2f gps, %d blocks of difficulty %d\n", pow.name+":", pow.rate.EdgeBits,
+			pow.rate.GraphRate, pow.rate.Blocks, pow.rate.Difficulty)
+	}
+}
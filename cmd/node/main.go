@@ -1,27 +1,115 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/dblokhin/gringo/p2p"
 	"github.com/sirupsen/logrus"
-	"github.com/dblokhin/gringo/chain"
-	"github.com/dblokhin/gringo/storage"
 	"os"
+	"sort"
 )
 
+// command is a cli subcommand
+type command struct {
+	// Usage is the one line description
+	Usage string
+	// Run runs the command with the args after the command name
+	Run func(args []string) error
+}
+
+var commands = map[string]command{
+	"run": {
+		Usage: "run the node (default command)",
+		Run:   runNode,
+	},
+	"peers": {
+		Usage: "peers of the running node: connected, all, banned, ban, unban",
+		Run:   peersCommand,
+	},
+	"chain": {
+		Usage: "chain commands: info, audit, block, header, backup, restore",
+		Run:   chainCommand,
+	},
+	"crawl": {
+		Usage: "crawl the network peers & dump the topology snapshot",
+		Run:   crawlCommand,
+	},
+	"decode": {
+		Usage: "decode the hex or binary wire message of the file or stdin",
+		Run:   decodeCommand,
+	},
+	"hashrate": {
+		Usage: "network graph rate estimated by the running node",
+		Run:   hashrateCommand,
+	},
+	"sync": {
+		Usage: "sync progress of the running node",
+		Run:   syncCommand,
+	},
+	"version": {
+		Usage: "print the node version",
+		Run:   printVersion,
+	},
+}
+
 func init() {
 	// Output to stdout instead of the default stderr
 	// Can be any io.Writer, see below for File example
 	logrus.SetOutput(os.Stdout)
-
-	// Only log the warning severity or above.
-	logrus.SetLevel(logrus.DebugLevel)
 }
 
 func main() {
-	logrus.Info("Starting")
-	chain := chain.New(&chain.Testnet1, storage.NewSqlStorage(nil))
+	args := os.Args[1:]
+
+	// run the node by default
+	name := "run"
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.Run(args); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
 
-	sync := p2p.NewSyncer([]string{"127.0.0.1:13414"}, chain, nil)
-	sync.Pool.Run()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
+// usage prints the list of commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].Usage)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the command flags.\n", os.Args[0])
+}
+
+// printVersion prints the node version & the build info
+func printVersion(args []string) error {
+	fmt.Println(p2p.UserAgent)
+
+	if p2p.Commit != "" {
+		fmt.Println("commit:", p2p.Commit)
+	}
+	if p2p.BuildDate != "" {
+		fmt.Println("built:", p2p.BuildDate)
+	}
+
+	return nil
+}
@@ -0,0 +1,695 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/mining"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"golang.org/x/crypto/blake2b"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsPort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		listenAddr string
+		expected   string
+	}{
+		{"", "0.0.0.0:13415"},
+		{"127.0.0.1:13414", "127.0.0.1:13415"},
+		{"127.0.0.1", "127.0.0.1:13415"},
+		{"::1", "[::1]:13415"},
+	} {
+		path := filepath.Join(dir, config.FileName)
+		data := []byte("[p2p]\nlisten_addr = \"" + test.listenAddr + "\"\n")
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := options{config: path, port: 13415}
+		cfg, err := opts.load()
+		if err != nil {
+			t.Errorf("listen addr %q: %v", test.listenAddr, err)
+			continue
+		}
+
+		if cfg.P2P.ListenAddr != test.expected {
+			t.Errorf("listen addr was %s, want %s", cfg.P2P.ListenAddr, test.expected)
+		}
+	}
+
+	// nolisten wins over the port
+	opts := options{config: filepath.Join(dir, config.FileName), port: 13415, noListen: true}
+	cfg, err := opts.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if addr := p2pListenAddr(cfg); !cfg.P2P.NoListen || addr != "" {
+		t.Errorf("nolisten: listen addr was %q", addr)
+	}
+}
+
+func TestReadinessChecks(t *testing.T) {
+	cfg := config.Health{MaxSyncLag: 5, MinPeers: 2}
+
+	tests := []struct {
+		lag    uint64
+		peers  int
+		ping   error
+		failed []string
+	}{
+		{5, 2, nil, nil},
+		{6, 2, nil, []string{"sync"}},
+		{0, 1, nil, []string{"peers"}},
+		{6, 0, errors.New("no database"), []string{"peers", "storage", "sync"}},
+	}
+
+	for _, test := range tests {
+		server := api.New(nil, nil, nil)
+		addReadinessChecks(server, cfg,
+			func() uint64 { return test.lag },
+			func() int { return test.peers },
+			func() error { return test.ping })
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var readiness api.Readiness
+		if err := json.NewDecoder(w.Body).Decode(&readiness); err != nil {
+			t.Fatal(err)
+		}
+
+		failed := make([]string, 0)
+		for name := range readiness.Checks {
+			failed = append(failed, name)
+		}
+		sort.Strings(failed)
+
+		if len(test.failed) == 0 && w.Code != http.StatusOK {
+			t.Errorf("lag %d, %d peers: status code was %d, want %d", test.lag, test.peers, w.Code, http.StatusOK)
+		}
+
+		if len(test.failed) > 0 && !reflect.DeepEqual(failed, test.failed) {
+			t.Errorf("lag %d, %d peers: failed checks were %v, want %v", test.lag, test.peers, failed, test.failed)
+		}
+	}
+}
+
+func TestPrepareDataDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the flat layout of the node run before
+	for _, file := range []string{nodeKeyFile, banFile, ".api_secret"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.Network = "mainnet"
+	if err := prepareDataDir(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ChainDir() != filepath.Join(dir, "main") {
+		t.Errorf("chain dir was %s", cfg.ChainDir())
+	}
+
+	for _, file := range []string{nodeKeyFile, banFile, ".api_secret"} {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, "main", file)); err != nil || string(data) != file {
+			t.Errorf("%s was not moved: %v", file, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s was left in the data dir", file)
+		}
+	}
+
+	// the other network starts with the empty dir
+	cfg.Network = "floonet"
+	if err := prepareDataDir(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, err := ioutil.ReadDir(filepath.Join(dir, "floo")); err != nil || len(files) != 0 {
+		t.Errorf("floonet dir was %v, %v", files, err)
+	}
+
+	// the flat layout is kept as is
+	cfg.ChainDirs = false
+	if err := prepareDataDir(cfg); err != nil || cfg.ChainDir() != dir {
+		t.Errorf("flat chain dir was %s, %v", cfg.ChainDir(), err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer logging.SetLevels("info", nil)
+
+	syncer := p2p.NewSyncer(nil, nil, nil)
+	syncer.SetLogger(logging.Nop)
+	miner := mining.NewController(mining.Controls{Threads: 1}, nil)
+	r := &reloader{opts: options{datadir: dir}, syncer: syncer, pool: mempool.New(nil), miner: miner}
+
+	data := []byte("[logging]\nlevel = \"warning\"\n[logging.modules]\np2p = \"debug\"\n[mining]\nenabled = false\nthreads = 3\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if levels := logging.Levels(); levels[logging.P2P] != "debug" || levels[logging.Chain] != "warning" {
+		t.Errorf("reloaded log levels were %v", levels)
+	}
+
+	if status := miner.Status(time.Now()); !status.Paused || status.Threads != 3 {
+		t.Errorf("reloaded mining status was %+v", status)
+	}
+
+	// the invalid config is not applied
+	data = []byte("[logging]\nlevel = \"info\"\n[p2p]\nmax_peers = 0\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.reload(); err == nil || logging.Levels()[logging.Chain] != "warning" {
+		t.Errorf("invalid config was reloaded: %v", err)
+	}
+}
+
+func TestAPIClientTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.API.TLSSelfSigned = true
+
+	tlsConfig, err := apiTLS(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(api.New(nil, nil, nil))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	cfg.API.ListenAddr = server.Listener.Addr().String()
+	client, url, err := apiClient(cfg, cfg.API.ListenAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(url + "/healthz")
+	if err != nil {
+		t.Fatalf("tls request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("healthz status was %d", resp.StatusCode)
+	}
+}
+
+func TestNetworkSeeds(t *testing.T) {
+	cfg := config.Default()
+	cfg.Network = "mainnet"
+	cfg.P2P.Seeds = []string{"10.0.0.1:3414"}
+
+	seeds, dnsSeeds := networkSeeds(cfg)
+	if !reflect.DeepEqual(seeds, []string{"10.0.0.1:3414"}) {
+		t.Errorf("seeds were %v, want the configured one", seeds)
+	}
+	if !reflect.DeepEqual(dnsSeeds, chain.Networks["mainnet"].DNSSeeds) {
+		t.Errorf("dns seeds were %v, want the mainnet ones", dnsSeeds)
+	}
+
+	// the configured seeds replace the network ones
+	cfg.P2P.DefaultSeeds = false
+	if seeds, dnsSeeds := networkSeeds(cfg); len(seeds) != 1 || len(dnsSeeds) != 0 {
+		t.Errorf("seeds were %v & %v, want the configured one only", seeds, dnsSeeds)
+	}
+}
+
+func TestConsensusParams(t *testing.T) {
+	cfg := config.Consensus{MagicCode: "4a2b", BlockTime: "30s", DampFactor: 3, MinEdgeBits: 12, MaxBlockHeaders: 64}
+	preset := consensus.UsernetParams
+
+	params := consensusParams(cfg, consensus.UsernetParams)
+	if params.MagicCode != [2]byte{0x4a, 0x2b} || params.BlockTime != 30*time.Second || params.DampFactor != 3 || params.MinEdgeBits != 12 {
+		t.Errorf("params were %+v, want the overridden ones", params)
+	}
+
+	if params.Limits.MaxBlockHeaders != 64 || params.Limits.MaxMsgLen != consensus.DefaultLimits.MaxMsgLen {
+		t.Errorf("limits were %+v, want the overridden headers count only", params.Limits)
+	}
+
+	if params.HardForkV2Height != consensus.UsernetParams.HardForkV2Height {
+		t.Errorf("hard fork height was %d, want the usernet one", params.HardForkV2Height)
+	}
+
+	// the presets are copied
+	if consensus.UsernetParams != preset {
+		t.Errorf("usernet params were changed to %+v", consensus.UsernetParams)
+	}
+}
+
+// recordPeers records the banned peers & the connection log queries of the
+// owner api
+type recordPeers struct {
+	banned  []string
+	queries []p2p.ConnLogQuery
+}
+
+func (p *recordPeers) Connected() []p2p.PeerStats                        { return nil }
+func (p *recordPeers) All() []p2p.PeerStats                              { return nil }
+func (p *recordPeers) Ban(addr string)                                   { p.banned = append(p.banned, addr) }
+func (p *recordPeers) Unban(addr string)                                 {}
+func (p *recordPeers) PropagateTx(tx *consensus.Transaction, fluff bool) {}
+func (p *recordPeers) Bans() []string                                    { return p.banned }
+func (p *recordPeers) ConnLog(query p2p.ConnLogQuery) []p2p.ConnEvent {
+	p.queries = append(p.queries, query)
+	return nil
+}
+
+func TestPeersCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	peers := new(recordPeers)
+	server := httptest.NewServer(api.New(nil, nil, peers).Owner())
+	defer server.Close()
+
+	data := []byte("[api]\nowner_listen_addr = \"" + server.Listener.Addr().String() + "\"\napi_secret_path = \"\"\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peersCommand([]string{"ban", "10.0.0.1:13414", "--datadir", dir}); err != nil {
+		t.Fatalf("peers ban failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(peers.banned, []string{"10.0.0.1:13414"}) {
+		t.Errorf("banned peers were %v", peers.banned)
+	}
+
+	for _, args := range [][]string{{"log", "--datadir", dir}, {"log", "10.0.0.0/24", "--datadir", dir}} {
+		if err := peersCommand(args); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+
+	if len(peers.queries) != 2 || peers.queries[0].Addr != "" || peers.queries[1].Addr != "10.0.0.0/24" {
+		t.Errorf("connection log queries were %+v", peers.queries)
+	}
+
+	for _, args := range [][]string{{"ban"}, {"ban", "--datadir", dir}, {"unknown"}} {
+		if err := peersCommand(args); err == nil {
+			t.Errorf("%v: invalid command was accepted", args)
+		}
+	}
+}
+
+func TestSignAuditReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, auditKeyFile)
+	key, err := readKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the generated key is kept
+	if again, err := readKey(path); err != nil || again.Cmp(key) != 0 {
+		t.Fatalf("key was not kept: %v", err)
+	}
+
+	signed, err := signAuditReport(AuditReport{Network: "usernet", Height: 10, Valid: true}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey := bulletproofs.ScalarMulPoint(&secp256k1zkp.G, key)
+	if compressed := secp256k1zkp.CompressPubkey(*publicKey); signed.PublicKey != hex.EncodeToString(compressed[:]) {
+		t.Errorf("public key was %s", signed.PublicKey)
+	}
+
+	var sig [64]byte
+	data, _ := hex.DecodeString(signed.Signature)
+	copy(sig[:], data)
+
+	if !secp256k1zkp.VerifySignature(*publicKey, blake2b.Sum256(signed.Report), secp256k1zkp.DecodeSignature(sig)) {
+		t.Error("report signature isn't verified")
+	}
+
+	var report AuditReport
+	if err := json.Unmarshal(signed.Report, &report); err != nil || report.Height != 10 || !report.Valid {
+		t.Errorf("report was %+v (%v)", report, err)
+	}
+}
+
+func TestP2PListenAddr(t *testing.T) {
+	for _, test := range []struct {
+		network, listenAddr string
+		noListen            bool
+		expected            string
+	}{
+		{"mainnet", "0.0.0.0", false, "0.0.0.0:3414"},
+		{"testnet4", "0.0.0.0", false, "0.0.0.0:13414"},
+		{"mainnet", "[::]", false, "[::]:3414"},
+		{"mainnet", "127.0.0.1:5000", false, "127.0.0.1:5000"},
+		{"mainnet", "", false, ""},
+		{"mainnet", "127.0.0.1:5000", true, ""},
+	} {
+		cfg := config.Default()
+		cfg.Network, cfg.P2P.ListenAddr, cfg.P2P.NoListen = test.network, test.listenAddr, test.noListen
+
+		if addr := p2pListenAddr(cfg); addr != test.expected {
+			t.Errorf("%s %q: listen addr was %q, want %q", test.network, test.listenAddr, addr, test.expected)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	status := &api.Status{
+		SyncStatus:  p2p.SyncBlocks,
+		SyncPercent: 50,
+		SyncProgress: &api.SyncProgress{
+			Headers:         200,
+			Blocks:          100,
+			Target:          200,
+			BlocksPerSecond: 2.5,
+			ETA:             40,
+		},
+	}
+
+	want := "[###############---------------]  50% body_sync headers 200/200 blocks 100/200 2.5 blocks/s eta 40s"
+	if line := progressLine(status); line != want {
+		t.Errorf("line was %q, want %q", line, want)
+	}
+
+	status = &api.Status{SyncStatus: p2p.SyncDone, SyncPercent: 100}
+	if line, want := progressLine(status), "[##############################] 100% no_sync"; line != want {
+		t.Errorf("line was %q, want %q", line, want)
+	}
+}
+
+func TestSyncCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(interval time.Duration) { syncPollInterval = interval }(syncPollInterval)
+	syncPollInterval = time.Millisecond
+
+	// the node syncs the headers on the first request
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := api.Status{SyncStatus: p2p.SyncDone, SyncPercent: 100}
+		if requests++; requests == 1 {
+			status = api.Status{SyncStatus: p2p.SyncHeaders, SyncProgress: &api.SyncProgress{Headers: 10, Target: 20}}
+		}
+		json.NewEncoder(w).Encode(status)
+	}))
+	defer server.Close()
+
+	data := []byte("[api]\nenabled = true\nlisten_addr = \"" + server.Listener.Addr().String() + "\"\nforeign_api_secret_path = \"\"\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := syncCommand([]string{"--datadir", dir}); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("status was requested %d times, want 2", requests)
+	}
+}
+
+func TestCoinbaseBuilder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := config.Default()
+	cfg.DataDir = dir
+	cfg.Mining.WalletListenerURL = "http://127.0.0.1:3415"
+
+	if builder, err := coinbaseBuilder(cfg); err != nil || reflect.TypeOf(builder) != reflect.TypeOf(&api.WalletClient{}) {
+		t.Errorf("%s builder was %T, %v, want the wallet", cfg.Network, builder, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.ChainDir(), coinbaseKeyFile)); !os.IsNotExist(err) {
+		t.Errorf("coinbase key of %s was generated", cfg.Network)
+	}
+
+	// usernet falls back to the internal key
+	cfg.Network = "usernet"
+	if builder, err := coinbaseBuilder(cfg); err != nil || reflect.TypeOf(builder) == reflect.TypeOf(&api.WalletClient{}) {
+		t.Errorf("usernet builder was %T, %v, want the fallback", builder, err)
+	}
+
+	cfg.Mining.WalletListenerURL = ""
+	if builder, err := coinbaseBuilder(cfg); err != nil || reflect.TypeOf(builder) != reflect.TypeOf(&mining.KeyCoinbase{}) {
+		t.Errorf("usernet builder without wallet was %T, %v, want the internal key", builder, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.ChainDir(), coinbaseKeyFile)); err != nil {
+		t.Errorf("coinbase key was not generated: %v", err)
+	}
+}
+
+func TestPauseOnFork(t *testing.T) {
+	controller := mining.NewController(mining.Controls{Threads: 1}, nil)
+	tip := chain.Testnet1
+	head := func() consensus.Block { return tip }
+
+	var reason string
+	process := pauseOnFork(head, controller)(func(ctx context.Context, block *consensus.Block) error {
+		_, reason = controller.Mining(time.Now())
+		return nil
+	})
+
+	next := consensus.Block{Header: consensus.BlockHeader{Height: 1, Previous: tip.Hash()}}
+	process(context.Background(), &next)
+	if reason != "" {
+		t.Errorf("block extending the head paused mining: %s", reason)
+	}
+
+	fork := consensus.Block{Header: consensus.BlockHeader{Height: 1}}
+	process(context.Background(), &fork)
+	if reason != mining.PauseReorg {
+		t.Errorf("fork block paused mining by %q, want %s", reason, mining.PauseReorg)
+	}
+
+	if mining, _ := controller.Mining(time.Now()); !mining {
+		t.Errorf("mining was not resumed after the fork block")
+	}
+}
+
+func TestHashrateCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RequestURI()
+		json.NewEncoder(w).Encode(api.NetworkHashrate{Height: 100, Blocks: 10})
+	}))
+	defer server.Close()
+
+	data := []byte("[api]\nenabled = true\nlisten_addr = \"" + server.Listener.Addr().String() + "\"\nforeign_api_secret_path = \"\"\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := hashrateCommand([]string{"--datadir", dir, "--blocks", "10"}); err != nil {
+		t.Fatalf("hashrate failed: %v", err)
+	}
+
+	if query != "/v1/chain/hashrate?blocks=10" {
+		t.Errorf("requested %s", query)
+	}
+
+	var buf bytes.Buffer
+	printHashrate(&buf, &api.NetworkHashrate{
+		Blocks:    2,
+		BlockTime: 60,
+		Primary:   api.PowHashrate{Blocks: 2, EdgeBits: 31, Difficulty: 1000, GraphRate: 1.5},
+	})
+	for _, line := range []string{"primary:    C31 1.50 gps, 2 blocks of difficulty 1000", "secondary:  no blocks", "block time: 60.0s"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("%q is missing in the output:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestChainBlockCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		json.NewEncoder(w).Encode(api.BlockHeaderPrintable{Height: 5})
+	}))
+	defer server.Close()
+
+	data := []byte("[api]\nenabled = true\nlisten_addr = \"" + server.Listener.Addr().String() + "\"\nforeign_api_secret_path = \"\"\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kind := range []string{"block", "header"} {
+		if err := chainCommand([]string{kind, "--datadir", dir, "5"}); err != nil {
+			t.Errorf("chain %s failed: %v", kind, err)
+		}
+	}
+
+	if len(paths) != 2 || paths[0] != "/v1/blocks/5" || paths[1] != "/v1/headers/5" {
+		t.Errorf("requested %v", paths)
+	}
+
+	if err := chainCommand([]string{"block", "--datadir", dir}); err == nil {
+		t.Error("chain block without the hash or height succeeded")
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pong := p2p.Pong{Ping: p2p.Ping{TotalDifficulty: 1000, Height: 7}}
+	file := filepath.Join(dir, "pong.hex")
+	if err := ioutil.WriteFile(file, []byte(hex.EncodeToString(pong.Bytes())+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := decodeCommand([]string{"--datadir", dir, "--type", "pong", file}); err != nil {
+		t.Errorf("decode failed: %v", err)
+	}
+	if err := decodeCommand([]string{"--datadir", dir, "--type", "txhashset", file}); err == nil {
+		t.Error("decode of the unknown type succeeded")
+	}
+
+	var buf bytes.Buffer
+	if err := printMessage(&buf, &pong, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"TotalDifficulty\": 1000,\n  \"Height\": 7\n}\n"; buf.String() != want {
+		t.Errorf("printed %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := printMessage(&buf, &p2p.GetBlock{Hash: consensus.Hash{0xab}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\"ab000000") {
+		t.Errorf("hash isn't hex: %s", buf.String())
+	}
+}
+
+func TestCrawlCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := []byte("[p2p]\ndefault_seeds = false\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, config.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the closed listener addr is the unreachable seed
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis.Close()
+
+	out := filepath.Join(dir, "snapshot.json")
+	if err := crawlCommand([]string{"--datadir", dir, "--seed", lis.Addr().String(), "--timeout", "1s", "--out", out}); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	data, err = ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot p2p.NetworkSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshot.Nodes) != 1 || snapshot.Nodes[0].Addr != lis.Addr().String() || snapshot.Nodes[0].Reachable {
+		t.Errorf("unexpected snapshot: %+v", snapshot)
+	}
+
+	if err := crawlCommand([]string{"--datadir", dir, "--workers", "0"}); err == nil {
+		t.Errorf("zero workers were accepted")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/dblokhin/gringo/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serveMetrics registers the collectors and serves them on /metrics,
+// pushes them to the push gateway if it's configured
+func serveMetrics(cfg config.Metrics, collectors ...prometheus.Collector) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGoCollector())
+	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	for _, collector := range collectors {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	logrus.Infof("metrics listening on %s", cfg.ListenAddr)
+	go func() {
+		if err := http.Serve(lis, mux); err != nil {
+			logrus.Fatal(err)
+		}
+	}()
+
+	if cfg.PushURL != "" {
+		go pushMetrics(push.New(cfg.PushURL, "gringo").Gatherer(registry), time.Duration(cfg.PushInterval)*time.Second)
+	}
+
+	return nil
+}
+
+// pushMetrics pushes the metrics to the push gateway every interval
+func pushMetrics(pusher *push.Pusher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := pusher.Push(); err != nil {
+			logrus.Warnf("metrics push failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/mining"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/sirupsen/logrus"
+	"path/filepath"
+	"time"
+)
+
+// coinbaseKeyFile is the internal key of the usernet coinbase in the chain dir
+const coinbaseKeyFile = "coinbase_key"
+
+// coinbaseBuilder returns the builder of the mined blocks coinbase: the
+// wallet listener, usernet falls back to the internal key of the chain dir
+func coinbaseBuilder(cfg *config.Config) (mining.CoinbaseBuilder, error) {
+	var wallet mining.CoinbaseBuilder
+	if cfg.Mining.WalletListenerURL != "" {
+		wallet = api.NewWalletClient(cfg.Mining.WalletListenerURL)
+	}
+
+	if cfg.Network != "usernet" {
+		return wallet, nil
+	}
+
+	key, err := readKey(filepath.Join(cfg.ChainDir(), coinbaseKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	internal := mining.NewKeyCoinbase(key)
+	if wallet == nil {
+		return internal, nil
+	}
+
+	return mining.Fallback(wallet, internal), nil
+}
+
+// checkCoinbase builds the coinbase of the block at height, so the wallet
+// not paying the rewards is reported on start instead of the mined block
+func checkCoinbase(builder mining.CoinbaseBuilder, height uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	coinbase, err := builder.BuildCoinbase(ctx, mining.BlockFees{Height: height})
+	if err != nil {
+		logrus.Errorf("Mining rewards can't be built: %v", err)
+		return
+	}
+
+	if coinbase.KeyID == "" {
+		logrus.Infof("Mining rewards are paid to the internal key of %s", coinbaseKeyFile)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{"key_id": coinbase.KeyID}).Info("Mining rewards are paid to the wallet")
+}
+
+// miningController returns the throttling & the schedule of the miner, the
+// fork blocks processed by the chain pause the mining
+func miningController(cfg *config.Config, c *chain.Chain, syncer *p2p.Syncer) (*mining.Controller, error) {
+	window, err := mining.ParseWindow(cfg.Mining.RunWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	synced := func() bool {
+		return !syncer.InitialBlockDownload() && syncer.SyncLag() == 0
+	}
+
+	controller := mining.NewController(mining.Controls{
+		SyncedOnly: cfg.Mining.SyncedOnly,
+		Threads:    cfg.Mining.Threads,
+		Window:     window,
+	}, synced)
+	c.Use(pauseOnFork(c.Head, controller))
+
+	return controller, nil
+}
+
+// pauseOnFork pauses the mining while the block not extending the head is
+// processed, the blocks mined meanwhile may be of the reorged chain
+func pauseOnFork(head func() consensus.Block, controller *mining.Controller) chain.BlockMiddleware {
+	return func(next chain.BlockHandler) chain.BlockHandler {
+		return func(ctx context.Context, block *consensus.Block) error {
+			tip := head()
+			if block.Header.Previous != tip.Hash() {
+				controller.Begin(mining.PauseReorg)
+				defer controller.End(mining.PauseReorg)
+			}
+
+			return next(ctx, block)
+		}
+	}
+}
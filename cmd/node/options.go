@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"github.com/dblokhin/gringo/config"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stringList is a flag.Value collecting repeated flags
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// options is the flags common to all commands, they override the config file
+type options struct {
+	config   string
+	chain    string
+	datadir  string
+	loglevel string
+	port     int
+	noListen bool
+	seeds    stringList
+}
+
+// newFlagSet returns flag set of the command with the common flags
+func newFlagSet(name string, opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	fs.StringVar(&opts.config, "config", "", "path to the config file (default: <datadir>/"+config.FileName+")")
+	fs.StringVar(&opts.chain, "chain", "", "network to run on (mainnet, testnet1..testnet4)")
+	fs.StringVar(&opts.datadir, "datadir", "", "directory for the node data")
+	fs.StringVar(&opts.loglevel, "loglevel", "", "log level (debug, info, warning, error)")
+	fs.IntVar(&opts.port, "port", 0, "p2p listen port")
+	fs.BoolVar(&opts.noListen, "nolisten", false, "disable the inbound p2p connections")
+	fs.Var(&opts.seeds, "seed", "seed peer addr, may be repeated")
+
+	return fs
+}
+
+// load returns the config overridden by the flags
+func (o *options) load() (*config.Config, error) {
+	path := o.config
+	if path == "" {
+		dataDir := o.datadir
+		if dataDir == "" {
+			dataDir = config.Default().DataDir
+		}
+		path = filepath.Join(dataDir, config.FileName)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.chain != "" {
+		cfg.Network = o.chain
+	}
+
+	if o.datadir != "" {
+		cfg.DataDir = o.datadir
+	}
+
+	if o.loglevel != "" {
+		cfg.Logging.Level = o.loglevel
+	}
+
+	if len(o.seeds) > 0 {
+		cfg.P2P.Seeds = o.seeds
+	}
+
+	if o.port != 0 {
+		// the port enables listening if the listen addr is not set
+		host := "0.0.0.0"
+		if cfg.P2P.ListenAddr != "" {
+			host = listenHost(cfg.P2P.ListenAddr)
+		}
+		cfg.P2P.ListenAddr = net.JoinHostPort(host, strconv.Itoa(o.port))
+	}
+
+	if o.noListen {
+		cfg.P2P.NoListen = true
+	}
+
+	return cfg, prepareDataDir(cfg)
+}
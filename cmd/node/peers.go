@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/config"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// peersUsage is the usage of the peers command
+const peersUsage = "usage: peers [connected|all|banned] [flags], peers ban|unban <addr|cidr> [flags], peers log [addr|host|cidr] [flags]"
+
+// peersCommand lists, bans & unbans the peers of the running node & prints
+// its connection log over the owner API
+func peersCommand(args []string) error {
+	action := "connected"
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		action, args = args[0], args[1:]
+	}
+
+	var addr string
+	switch action {
+	case "connected", "all", "banned":
+	case "ban", "unban":
+		if len(args) == 0 || len(args[0]) == 0 || args[0][0] == '-' {
+			return errors.New(peersUsage)
+		}
+		addr, args = args[0], args[1:]
+	case "log":
+		if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+			addr, args = args[0], args[1:]
+		}
+	default:
+		return fmt.Errorf("unknown peers command: %s\n%s", action, peersUsage)
+	}
+
+	var opts options
+	if err := newFlagSet("peers "+action, &opts).Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.API.OwnerListenAddr == "" {
+		return errors.New("owner api is disabled: api.owner_listen_addr is not set")
+	}
+
+	if action == "log" {
+		return ownerRequest(cfg, http.MethodGet, "/v1/peers/log?addr="+url.QueryEscape(addr))
+	}
+
+	if addr != "" {
+		return ownerRequest(cfg, http.MethodPost, "/v1/peers/"+addr+"/"+action)
+	}
+
+	return ownerRequest(cfg, http.MethodGet, "/v1/peers?state="+action)
+}
+
+// ownerRequest requests the node owner API & prints the indented json
+// response
+func ownerRequest(cfg *config.Config, method, path string) error {
+	resp, err := ownerDo(cfg, method, path, nil, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
+
+// ownerDo sends the request of the body to the node owner API & returns the
+// ok response, stream disables the client timeout of the long transfers
+func ownerDo(cfg *config.Config, method, path string, body io.Reader, stream bool) (*http.Response, error) {
+	client, url, err := apiClient(cfg, cfg.API.OwnerListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	if stream {
+		client.Timeout = 0
+	}
+
+	req, err := http.NewRequest(method, url+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := apiSecret(cfg, cfg.API.SecretPath)
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		req.SetBasicAuth(api.BasicAuthUser, secret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("is the node running? %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		message, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("node api error: %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+
+	return resp, nil
+}
+
+// apiClient returns the client & the base url of the node API listening on
+// addr, the client trusts the API certificate if TLS is enabled
+func apiClient(cfg *config.Config, addr string) (*http.Client, string, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	// the node listening on all the interfaces is reached by localhost
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); host == "" || ip.IsUnspecified() {
+			addr = net.JoinHostPort("localhost", port)
+		}
+	}
+
+	certFile, _ := tlsFiles(cfg)
+	if certFile == "" {
+		return client, "http://" + addr, nil
+	}
+
+	pem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, "", errors.New("invalid api certificate: " + certFile)
+	}
+
+	client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	return client, "https://" + addr, nil
+}
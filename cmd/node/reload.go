@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/mining"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// reloader applies the runtime settings of the config file to the running
+// node: the log levels, the max peers, the ban list, the mempool policies &
+// the mining controls. The other settings need the restart
+type reloader struct {
+	sync.Mutex
+
+	opts   options
+	syncer *p2p.Syncer
+	pool   *mempool.Pool
+	miner  *mining.Controller
+}
+
+// reload reads the config file & applies its runtime settings, nothing is
+// applied if the config is invalid
+func (r *reloader) reload() error {
+	r.Lock()
+	defer r.Unlock()
+
+	cfg, err := r.opts.load()
+	if err != nil {
+		return err
+	}
+
+	window, err := mining.ParseWindow(cfg.Mining.RunWindow)
+	if err != nil {
+		return err
+	}
+
+	if err := logging.SetLevels(cfg.Logging.Level, cfg.Logging.Modules); err != nil {
+		return err
+	}
+
+	r.syncer.SetMaxPeers(cfg.P2P.MaxPeers)
+
+	setPoolPolicies(r.pool, cfg.Mempool)
+
+	switch {
+	case r.miner == nil && cfg.Mining.Enabled:
+		logrus.Warn("Mining is enabled by the restart only")
+	case r.miner != nil:
+		if cfg.Mining.Enabled {
+			r.miner.Resume()
+		} else {
+			r.miner.Pause()
+		}
+
+		if err := r.miner.SetThreads(cfg.Mining.Threads); err != nil {
+			return err
+		}
+		r.miner.SetWindow(window)
+	}
+
+	if err := r.syncer.ReloadBans(); err != nil {
+		return err
+	}
+
+	logrus.Info("Config reloaded")
+	return nil
+}
+
+// watch reloads the config on SIGHUP
+func (r *reloader) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if err := r.reload(); err != nil {
+			logrus.Errorf("Config reload failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/mining"
+	"github.com/dblokhin/gringo/monitor"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/storage"
+	"github.com/sirupsen/logrus"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runNode runs the node
+func runNode(args []string) error {
+	var opts options
+	if err := newFlagSet("run", &opts).Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	logs := cfg.Logging
+	if logs.File != "" && !filepath.IsAbs(logs.File) {
+		logs.File = filepath.Join(cfg.ChainDir(), logs.File)
+	}
+
+	if err := setupLogging(logs); err != nil {
+		return err
+	}
+
+	logrus.Info("Starting")
+	chain, store, err := openChain(cfg)
+	if err != nil {
+		return err
+	}
+
+	pool := mempool.New(chain)
+	setPoolPolicies(pool, cfg.Mempool)
+
+	if cfg.Mining.Enabled {
+		coinbase, err := coinbaseBuilder(cfg)
+		if err != nil {
+			return err
+		}
+		go checkCoinbase(coinbase, chain.Height()+1)
+	}
+
+	p2p.SetMaxOnlineConnections(cfg.P2P.MaxPeers)
+	p2p.SetUserAgent(cfg.P2P.UserAgent)
+	seeds, dnsSeeds := networkSeeds(cfg)
+	sync := p2p.NewSyncer(seeds, chain, pool)
+	sync.SetDNSSeeds(dnsSeeds)
+	sync.SetParams(chain.Params())
+	sync.SetIBDDistance(uint64(cfg.P2P.IBDDistance))
+	sync.SetForkAlarm(p2p.ForkAlarmConfig{
+		PeersPercent: cfg.ForkAlarm.PeersPercent,
+		Blocks:       uint64(cfg.ForkAlarm.Blocks),
+		Duration:     time.Duration(cfg.ForkAlarm.DurationSecs) * time.Second,
+	})
+	sync.SetDandelion(p2p.DandelionConfig{
+		Epoch:           time.Duration(cfg.Dandelion.EpochSecs) * time.Second,
+		StemProbability: cfg.Dandelion.StemProbability,
+		Aggregation:     time.Duration(cfg.Dandelion.AggregationSecs) * time.Second,
+		Embargo:         time.Duration(cfg.Dandelion.EmbargoSecs) * time.Second,
+	})
+	if err := sync.SetBanFile(filepath.Join(cfg.ChainDir(), banFile)); err != nil {
+		return err
+	}
+	nodeKey, err := readKey(filepath.Join(cfg.ChainDir(), nodeKeyFile))
+	if err != nil {
+		return err
+	}
+	sync.SetIdentity(p2p.NewIdentity(nodeKey))
+	logrus.WithFields(logrus.Fields{"node_id": sync.NodeID().String()}).Info("Node identity")
+	if cfg.Mode == config.ModeHeaders {
+		logrus.Info("Syncing the headers only")
+		chain.SetHeadersOnly()
+		sync.SetHeadersOnly()
+	}
+	if cfg.ArchiveMode {
+		logrus.Info("Keeping all the blocks")
+		sync.SetArchive()
+	}
+	sync.OnProgress(10*time.Second, logProgress)
+
+	var miner *mining.Controller
+	if cfg.Mining.Enabled {
+		if miner, err = miningController(cfg, chain, sync); err != nil {
+			return err
+		}
+	}
+	listenAddr := p2pListenAddr(cfg)
+	if cfg.P2P.MDNS {
+		sync.SetMDNS(listenPort(listenAddr))
+	}
+	if listenAddr != "" {
+		if err := sync.Pool.Listen(listenAddr); err != nil {
+			return err
+		}
+	} else {
+		logrus.Info("Not listening, outbound connections only")
+	}
+
+	reloader := &reloader{opts: opts, syncer: sync, pool: pool, miner: miner}
+	go reloader.watch()
+
+	resources := monitor.New(monitor.Interval, sync.Traffic, store.DiskSize)
+	go resources.Run(context.Background())
+
+	if cfg.API.Enabled {
+		server := api.New(chain, pool, sync.Pool)
+		server.SetReload(reloader.reload)
+		server.SetNode(cfg.Network, sync)
+		server.SetResources(resources)
+		if miner != nil {
+			server.SetMining(miner)
+		}
+		server.SetWatcher(runWatcher(chain))
+		server.SetBackup(chain)
+		server.SetInvalidator(chain)
+		tlsConfig, err := apiTLS(cfg)
+		if err != nil {
+			return err
+		}
+		server.SetTLS(tlsConfig)
+		server.SetCORS(cfg.API.CORSOrigins)
+		server.SetBasePath(cfg.API.BasePath)
+		server.SetRateLimit(float64(cfg.API.RateLimit), cfg.API.RateBurst)
+		if err := server.SetTrustedProxies(cfg.API.TrustedProxies); err != nil {
+			return err
+		}
+
+		foreignSecret, err := apiSecret(cfg, cfg.API.ForeignSecretPath)
+		if err != nil {
+			return err
+		}
+		ownerSecret, err := apiSecret(cfg, cfg.API.SecretPath)
+		if err != nil {
+			return err
+		}
+		server.SetSecrets(foreignSecret, ownerSecret)
+
+		if cfg.API.WalletProxyURL != "" {
+			if err := setWalletProxy(cfg, server); err != nil {
+				return err
+			}
+		}
+
+		peers := func() int { return len(sync.Pool.Connected()) }
+		addReadinessChecks(server, cfg.Health, sync.SyncLag, peers, store.Ping)
+		go func() {
+			if err := server.ListenAndServe(cfg.API.ListenAddr); err != nil {
+				logrus.Fatal(err)
+			}
+		}()
+
+		if cfg.API.OwnerListenAddr != "" {
+			go func() {
+				if err := server.ListenAndServeOwner(cfg.API.OwnerListenAddr); err != nil {
+					logrus.Fatal(err)
+				}
+			}()
+		}
+
+		if cfg.API.GRPCListenAddr != "" {
+			lis, err := net.Listen("tcp", cfg.API.GRPCListenAddr)
+			if err != nil {
+				return err
+			}
+
+			logrus.Infof("grpc api listening on %s", cfg.API.GRPCListenAddr)
+			go server.NewGRPC(chain, pool).Serve(lis)
+		}
+	}
+
+	if cfg.Telemetry.Enabled {
+		reportTelemetry(cfg.Telemetry, cfg.Network, chain, sync)
+	}
+
+	if len(cfg.BanSync.Nodes) > 0 {
+		if err := syncBans(cfg, sync.Pool); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Metrics.Enabled {
+		if err := serveMetrics(cfg.Metrics, chain.Metrics(), sync.Metrics(), pool.Metrics(), store.Metrics(), resources.Metrics()); err != nil {
+			return err
+		}
+	}
+
+	sync.Run()
+	return nil
+}
+
+// setPoolPolicies sets the conflict & the relay policies of the mempool
+func setPoolPolicies(pool *mempool.Pool, cfg config.Mempool) {
+	pool.SetConflictPolicy(mempool.ConflictPolicy{
+		Replace:         cfg.ReplaceByFee,
+		FeeRateIncrease: uint64(cfg.ReplaceFeeRateIncrease),
+	})
+	pool.SetRelayPolicy(mempool.RelayPolicy{
+		MaxWeight:  uint64(cfg.MaxTxWeight),
+		MaxKernels: cfg.MaxTxKernels,
+		MaxOutputs: cfg.MaxTxOutputs,
+	})
+}
+
+// logProgress logs the progress of the headers & blocks sync
+func logProgress(status p2p.SyncStatus) {
+	switch status.Stage {
+	case p2p.SyncHeaders:
+		logrus.WithFields(logrus.Fields{
+			"height": status.Headers,
+			"target": status.Target,
+			"rate":   fmt.Sprintf("%.1f/s", status.HeadersPerSecond),
+			"eta":    status.ETA.Round(time.Second),
+		}).Info("syncing headers")
+	case p2p.SyncBlocks:
+		logrus.WithFields(logrus.Fields{
+			"height": status.Blocks,
+			"target": status.Target,
+			"rate":   fmt.Sprintf("%.1f/s", status.BlocksPerSecond),
+			"eta":    status.ETA.Round(time.Second),
+		}).Info("syncing blocks")
+	}
+}
+
+// p2pListenAddr returns the p2p listen addr of the config, the addr without
+// the port listens on the network default port. Empty if it isn't listening or
+// no_listen is set
+func p2pListenAddr(cfg *config.Config) string {
+	addr := cfg.P2P.ListenAddr
+	if addr == "" || cfg.P2P.NoListen {
+		return ""
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+
+	return net.JoinHostPort(listenHost(addr), strconv.Itoa(int(chain.Networks[cfg.Network].Port)))
+}
+
+// listenHost returns the host of the listen addr with or without the port
+func listenHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// listenPort returns the port of the listen addr, 0 if it isn't listening
+func listenPort(addr string) uint16 {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+
+	n, _ := strconv.ParseUint(port, 10, 16)
+	return uint16(n)
+}
+
+// runWatcher returns the confirmation tracking of the chain, the watches are
+// updated for the node lifetime
+func runWatcher(c *chain.Chain) *chain.Watcher {
+	watcher := chain.NewWatcher(c)
+	go watcher.Run(context.Background())
+
+	return watcher
+}
+
+// openChain returns the chain of the configured network & its storage
+func openChain(cfg *config.Config) (*chain.Chain, *storage.SqlStorage, error) {
+	params, ok := chain.Networks[cfg.Network]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown network: %s", cfg.Network)
+	}
+
+	var db *sql.DB
+	if cfg.Storage.DSN != "" {
+		var err error
+		if db, err = sql.Open("mysql", cfg.Storage.DSN); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	store := storage.NewSqlStorage(db)
+	if cfg.ArchiveMode {
+		store.SetArchive()
+	}
+
+	c := chain.New(params.Genesis, store)
+	c.SetParams(consensusParams(cfg.Consensus, *params.Consensus))
+	if err := c.SetIntentFile(filepath.Join(cfg.ChainDir(), intentFile)); err != nil {
+		return nil, nil, err
+	}
+
+	consensus.SetWorkers(cfg.Workers)
+	consensus.SetLimits(c.Params().Limits)
+	headerWorkers := cfg.P2P.HeaderWorkers
+	if headerWorkers == 0 {
+		headerWorkers = cfg.Workers
+	}
+	c.SetHeaderWorkers(headerWorkers)
+
+	for _, value := range cfg.InvalidBlocks {
+		hash, err := consensus.ParseHash(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.InvalidateBlock(hash)
+	}
+
+	return c, store, nil
+}
+
+// consensusParams returns the network params overridden by the config, the
+// config must be valid
+func consensusParams(cfg config.Consensus, params consensus.Params) *consensus.Params {
+	if magic, err := hex.DecodeString(cfg.MagicCode); err == nil && len(magic) == 2 {
+		copy(params.MagicCode[:], magic)
+	}
+
+	if d, err := time.ParseDuration(cfg.BlockTime); err == nil {
+		params.BlockTime = d
+	}
+
+	if cfg.HardForkV2Height != 0 {
+		params.HardForkV2Height = cfg.HardForkV2Height
+	}
+
+	if cfg.HardForkInterval != 0 {
+		params.HardForkInterval = cfg.HardForkInterval
+	}
+
+	if cfg.DifficultyAdjustWindow != 0 {
+		params.DifficultyAdjustWindow = cfg.DifficultyAdjustWindow
+	}
+
+	if cfg.DampFactor != 0 {
+		params.DampFactor = cfg.DampFactor
+	}
+
+	if cfg.MinEdgeBits != 0 {
+		params.MinEdgeBits = uint8(cfg.MinEdgeBits)
+	}
+
+	if cfg.MaxMsgLen != 0 {
+		params.Limits.MaxMsgLen = cfg.MaxMsgLen
+	}
+
+	if cfg.MaxBlockHeaders != 0 {
+		params.Limits.MaxBlockHeaders = cfg.MaxBlockHeaders
+	}
+
+	if cfg.MaxPeerAddrs != 0 {
+		params.Limits.MaxPeerAddrs = cfg.MaxPeerAddrs
+	}
+
+	if cfg.MaxLocators != 0 {
+		params.Limits.MaxLocators = cfg.MaxLocators
+	}
+
+	if cfg.MaxListLen != 0 {
+		params.Limits.MaxListLen = cfg.MaxListLen
+	}
+
+	return &params
+}
+
+// networkSeeds returns the initial peers & the DNS seeds, the configured
+// seeds are added to the network ones unless p2p.default_seeds is off
+func networkSeeds(cfg *config.Config) (seeds, dnsSeeds []string) {
+	if !cfg.P2P.DefaultSeeds {
+		return cfg.P2P.Seeds, nil
+	}
+
+	params := chain.Networks[cfg.Network]
+	seeds = append(append(seeds, params.Seeds...), cfg.P2P.Seeds...)
+
+	return seeds, params.DNSSeeds
+}
+
+// tlsFiles returns the certificate & key files of the API, empty if TLS is
+// disabled
+func tlsFiles(cfg *config.Config) (certFile, keyFile string) {
+	if cfg.API.TLSCertFile == "" && cfg.API.TLSSelfSigned {
+		return filepath.Join(cfg.ChainDir(), apiCertFile), filepath.Join(cfg.ChainDir(), apiKeyFile)
+	}
+
+	return cfg.API.TLSCertFile, cfg.API.TLSKeyFile
+}
+
+// apiTLS returns the TLS settings of the API listeners, nil if TLS is disabled
+func apiTLS(cfg *config.Config) (*tls.Config, error) {
+	certFile, keyFile := tlsFiles(cfg)
+	if certFile == "" {
+		return nil, nil
+	}
+
+	// the certificate is valid for localhost & the hosts the APIs listen on
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	for _, addr := range []string{cfg.API.ListenAddr, cfg.API.OwnerListenAddr, cfg.API.GRPCListenAddr} {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil || host == "" || host == "localhost" {
+			continue
+		}
+
+		if ip := net.ParseIP(host); ip.IsUnspecified() || ip.IsLoopback() {
+			continue
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	return api.TLSConfig(certFile, keyFile, cfg.API.TLSSelfSigned, hosts)
+}
+
+// apiSecret returns the API secret of the file relative to the chain dir,
+// empty if path is not set
+func apiSecret(cfg *config.Config, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.ChainDir(), path)
+	}
+
+	return api.ReadSecret(path)
+}
+
+// addReadinessChecks adds the sync, peers & storage checks to /readyz,
+// syncLag is the count of blocks behind the best peer & peers is the count
+// of the connected peers
+func addReadinessChecks(server *api.Server, cfg config.Health, syncLag func() uint64, peers func() int, ping func() error) {
+	server.AddReadinessCheck("sync", func() error {
+		if lag := syncLag(); lag > uint64(cfg.MaxSyncLag) {
+			return fmt.Errorf("chain is %d blocks behind the best peer", lag)
+		}
+		return nil
+	})
+
+	server.AddReadinessCheck("peers", func() error {
+		if n := peers(); n < cfg.MinPeers {
+			return fmt.Errorf("%d peers connected, want %d", n, cfg.MinPeers)
+		}
+		return nil
+	})
+
+	server.AddReadinessCheck("storage", ping)
+}
+
+// setupLogging configures the log output & the module levels
+func setupLogging(cfg config.Logging) error {
+	var rotateEvery time.Duration
+	if cfg.RotateInterval != "" {
+		var err error
+		if rotateEvery, err = time.ParseDuration(cfg.RotateInterval); err != nil {
+			return err
+		}
+	}
+
+	return logging.Setup(logging.Options{
+		Level:       cfg.Level,
+		Modules:     cfg.Modules,
+		Format:      cfg.Format,
+		File:        cfg.File,
+		MaxSize:     cfg.MaxSize,
+		MaxBackups:  cfg.MaxBackups,
+		MaxAge:      cfg.MaxAge,
+		RotateEvery: rotateEvery,
+	})
+}
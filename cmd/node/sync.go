@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/p2p"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// syncPollInterval is the interval of the status requests of the sync
+// command
+var syncPollInterval = time.Second
+
+// progressWidth is the width of the progress bar
+const progressWidth = 30
+
+// syncCommand shows the sync progress bar of the running node until it's
+// synced, the status is requested from the node API
+func syncCommand(args []string) error {
+	var opts options
+	if err := newFlagSet("sync", &opts).Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := opts.load()
+	if err != nil {
+		return err
+	}
+
+	if !cfg.API.Enabled {
+		return errors.New("api is disabled: api.enabled is false")
+	}
+
+	for {
+		status, err := nodeStatus(cfg)
+		if err != nil {
+			return err
+		}
+
+		// the line is redrawn & cleared to the end
+		fmt.Fprint(os.Stdout, "\r"+progressLine(status)+"\x1b[K")
+
+		if status.SyncStatus == p2p.SyncDone {
+			fmt.Fprintln(os.Stdout)
+			return nil
+		}
+
+		time.Sleep(syncPollInterval)
+	}
+}
+
+// nodeStatus requests the status of the node API
+func nodeStatus(cfg *config.Config) (*api.Status, error) {
+	var status api.Status
+	if err := foreignGet(cfg, "/v1/status", &status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// foreignGet requests the path of the node foreign API & decodes the json
+// response to result
+func foreignGet(cfg *config.Config, path string, result interface{}) error {
+	client, url, err := apiClient(cfg, cfg.API.ListenAddr)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url+path, nil)
+	if err != nil {
+		return err
+	}
+
+	secret, err := apiSecret(cfg, cfg.API.ForeignSecretPath)
+	if err != nil {
+		return err
+	}
+	if secret != "" {
+		req.SetBasicAuth(api.BasicAuthUser, secret)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("is the node running? %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("node api error: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+// progressLine returns the progress bar of the sync status, the headers &
+// blocks, the rate & the estimated time of the stage
+func progressLine(status *api.Status) string {
+	percent := status.SyncPercent
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	filled := percent * progressWidth / 100
+	line := fmt.Sprintf("[%s%s] %3d%% %s", strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled),
+		percent, status.SyncStatus)
+
+	progress := status.SyncProgress
+	if progress == nil {
+		return line
+	}
+
+	line += fmt.Sprintf(" headers %d/%d blocks %d/%d", progress.Headers, progress.Target, progress.Blocks, progress.Target)
+
+	switch status.SyncStatus {
+	case p2p.SyncHeaders:
+		line += fmt.Sprintf(" %.1f headers/s", progress.HeadersPerSecond)
+	case p2p.SyncBlocks:
+		line += fmt.Sprintf(" %.1f blocks/s", progress.BlocksPerSecond)
+	}
+
+	if progress.ETA > 0 {
+		line += " eta " + (time.Duration(progress.ETA) * time.Second).String()
+	}
+
+	return line
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/config"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/telemetry"
+	"github.com/sirupsen/logrus"
+	"time"
+)
+
+// reportTelemetry starts the opt-in reports of the node stats to the
+// telemetry endpoint
+func reportTelemetry(cfg config.Telemetry, network string, c *chain.Chain, sync *p2p.Syncer) {
+	stats := func() telemetry.Report {
+		return telemetry.Report{
+			Version:   p2p.Version,
+			UserAgent: p2p.UserAgent,
+			Network:   network,
+			Height:    c.Height(),
+			Peers:     len(sync.Pool.Connected()),
+			Syncing:   sync.Status().Stage != p2p.SyncDone,
+		}
+	}
+
+	logrus.Infof("telemetry reporting to %s every %ds", cfg.URL, cfg.Interval)
+	go telemetry.NewReporter(cfg.URL, time.Duration(cfg.Interval)*time.Second, stats).Run(context.Background())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/dblokhin/gringo/api"
+	"github.com/dblokhin/gringo/config"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// setWalletProxy forwards the wallet foreign API requests of the server to
+// the wallet listener of the api settings
+func setWalletProxy(cfg *config.Config, server *api.Server) error {
+	target, err := url.Parse(cfg.API.WalletProxyURL)
+	if err != nil {
+		return err
+	}
+
+	var secret string
+	if path := cfg.API.WalletProxySecretPath; path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.ChainDir(), path)
+		}
+
+		// the wallet secret is never generated, the wallet wouldn't know it
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+
+	var tlsConfig *tls.Config
+	if file := cfg.API.WalletProxyCAFile; file != "" {
+		pem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+
+		if !roots.AppendCertsFromPEM(pem) {
+			return errors.New("invalid wallet certificate: " + file)
+		}
+		tlsConfig = &tls.Config{RootCAs: roots}
+	}
+
+	logrus.Infof("wallet foreign api proxied to %s", cfg.API.WalletProxyURL)
+	server.SetWalletProxy(target, secret, tlsConfig)
+	return nil
+}
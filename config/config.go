@@ -0,0 +1,710 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package config loads the node configuration (gringo.toml)
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the default name of the config file
+const FileName = "gringo.toml"
+
+// The node modes
+const (
+	// ModeFull syncs & validates the full blocks
+	ModeFull = "full"
+	// ModeHeaders syncs & validates the headers only, the block bodies &
+	// the transactions are skipped
+	ModeHeaders = "headers"
+)
+
+// Config is the node configuration
+type Config struct {
+	// DataDir is the directory for the node data
+	DataDir string `toml:"data_dir"`
+	// ChainDirs keeps the data of every network in its own directory of
+	// DataDir: main, floo, test4 ... False is the flat layout of DataDir
+	ChainDirs bool `toml:"chain_dirs"`
+	// Network is the chain the node runs on
+	Network string `toml:"network"`
+	// Mode is the node mode: ModeFull or ModeHeaders
+	Mode string `toml:"mode"`
+	// ArchiveMode keeps all the blocks & announces the full history to the
+	// peers, otherwise the recently used blocks are kept
+	ArchiveMode bool `toml:"archive_mode"`
+	// Workers is the count of the validation workers: the range proofs
+	// verifiers of the block or transaction & the proof of work verifiers of
+	// the synced headers, 0 is GOMAXPROCS
+	Workers int `toml:"workers"`
+	// InvalidBlocks is the hex hashes of the blocks rejected even if they
+	// are valid, the emergency tool of the consensus incidents
+	InvalidBlocks []string `toml:"invalid_blocks"`
+
+	P2P       P2P       `toml:"p2p"`
+	API       API       `toml:"api"`
+	Mempool   Mempool   `toml:"mempool"`
+	Dandelion Dandelion `toml:"dandelion"`
+	Mining    Mining    `toml:"mining"`
+	Logging   Logging   `toml:"logging"`
+	Storage   Storage   `toml:"storage"`
+	Metrics   Metrics   `toml:"metrics"`
+	Health    Health    `toml:"health"`
+	ForkAlarm ForkAlarm `toml:"fork_alarm"`
+	Telemetry Telemetry `toml:"telemetry"`
+	BanSync   BanSync   `toml:"ban_sync"`
+	Consensus Consensus `toml:"consensus"`
+}
+
+// P2P is the p2p network settings
+type P2P struct {
+	// ListenAddr is the addr for inbound connections, the port is the
+	// network default if omitted
+	ListenAddr string `toml:"listen_addr"`
+	// NoListen disables the inbound connections whatever ListenAddr is: the
+	// node only dials the peers & advertises no port
+	NoListen bool `toml:"no_listen"`
+	// UserAgent is sent by the handshakes, empty is the name & version
+	UserAgent string `toml:"user_agent"`
+	// Seeds is the list of the initial peers
+	Seeds []string `toml:"seeds"`
+	// DefaultSeeds adds the seeds shipped with the network to Seeds, false
+	// makes Seeds replace them
+	DefaultSeeds bool `toml:"default_seeds"`
+	// MaxPeers is the max count of the connected peers
+	MaxPeers int `toml:"max_peers"`
+	// HeaderWorkers is the count of the proof of work verifiers of the
+	// synced headers, 0 is Workers
+	HeaderWorkers int `toml:"header_workers"`
+	// MDNS discovers the peers of the local network & advertises the node
+	// by mDNS
+	MDNS bool `toml:"mdns"`
+	// IBDDistance is the count of blocks behind the best peer the initial
+	// block download ends at
+	IBDDistance int `toml:"ibd_distance"`
+}
+
+// API is the node API settings
+type API struct {
+	Enabled    bool   `toml:"enabled"`
+	ListenAddr string `toml:"listen_addr"`
+	// GRPCListenAddr is the addr of the gRPC API, empty means disabled
+	GRPCListenAddr string `toml:"grpc_listen_addr"`
+	// OwnerListenAddr is the addr of the owner API, empty means disabled
+	OwnerListenAddr string `toml:"owner_listen_addr"`
+
+	// TLSCertFile & TLSKeyFile enable TLS of the API listeners
+	TLSCertFile string `toml:"tls_cert_file"`
+	TLSKeyFile  string `toml:"tls_key_file"`
+	// TLSSelfSigned enables TLS with the self-signed certificate generated
+	// in the chain dir, or in the cert & key files if they are set
+	TLSSelfSigned bool `toml:"tls_self_signed"`
+
+	// SecretPath & ForeignSecretPath are the files of the owner & foreign
+	// API basic auth secrets, relative to the chain dir. The files are
+	// generated if missing, empty path disables the auth
+	SecretPath        string `toml:"api_secret_path"`
+	ForeignSecretPath string `toml:"foreign_api_secret_path"`
+
+	// CORSOrigins is the browser origins allowed to request the foreign
+	// API, "*" allows any
+	CORSOrigins []string `toml:"cors_origins"`
+	// TrustedProxies is the IPs or CIDR ranges of the reverse proxies the
+	// client addr is taken from X-Forwarded-For of
+	TrustedProxies []string `toml:"trusted_proxies"`
+	// BasePath is the path prefix the API is served under, as the location
+	// of the reverse proxy
+	BasePath string `toml:"base_path"`
+
+	// RateLimit is the requests per second of the client addr to the
+	// foreign API, over the RateBurst requests. Zero disables the limit
+	RateLimit int `toml:"rate_limit"`
+	RateBurst int `toml:"rate_burst"`
+
+	// WalletProxyURL is the wallet listener the wallet foreign API requests
+	// of /v2/foreign are forwarded to, empty disables the proxy
+	WalletProxyURL string `toml:"wallet_proxy_url"`
+	// WalletProxySecretPath is the file of the wallet foreign API secret,
+	// relative to the chain dir. Empty sends no auth
+	WalletProxySecretPath string `toml:"wallet_proxy_secret_path"`
+	// WalletProxyCAFile is the certificate trusted by the https wallet
+	// listener besides the system roots
+	WalletProxyCAFile string `toml:"wallet_proxy_ca_file"`
+}
+
+// Mempool is the transaction pool settings
+type Mempool struct {
+	// ReplaceByFee replaces the pool transactions by the transaction
+	// spending their inputs if its fee rate is ReplaceFeeRateIncrease
+	// percents higher than theirs, otherwise the spending one is rejected
+	ReplaceByFee           bool `toml:"replace_by_fee"`
+	ReplaceFeeRateIncrease int  `toml:"replace_fee_rate_increase"`
+
+	// MaxTxWeight, MaxTxKernels & MaxTxOutputs are the limits of the pool &
+	// relayed transactions stricter than the consensus ones, 0 is not
+	// limited. The weight is the block weight
+	MaxTxWeight  int `toml:"max_tx_weight"`
+	MaxTxKernels int `toml:"max_tx_kernels"`
+	MaxTxOutputs int `toml:"max_tx_outputs"`
+}
+
+// Dandelion is the relay settings of the stem transactions, the node is the
+// stem relay or the fluff point by the epoch. The intervals are in seconds
+type Dandelion struct {
+	// EpochSecs is the interval the node keeps the stem or the fluff mode for
+	EpochSecs int `toml:"epoch_secs"`
+	// StemProbability is the percent probability of the stem epoch
+	StemProbability int `toml:"stem_probability"`
+	// AggregationSecs is the patience interval the stem transactions of the
+	// fluff epoch are collected for, then aggregated into one & fluffed
+	AggregationSecs int `toml:"aggregation_secs"`
+	// EmbargoSecs is the max stem time, the forwarded stem transaction not
+	// seen fluffed after it is fluffed by the node
+	EmbargoSecs int `toml:"embargo_secs"`
+}
+
+// Mining is the miner settings
+type Mining struct {
+	Enabled bool `toml:"enabled"`
+	// Threads is the count of the solver threads
+	Threads int `toml:"threads"`
+	// WalletListenerURL is the foreign API of the wallet building the
+	// coinbase of the mined blocks, usernet falls back to the internal key
+	// of the chain dir if it's empty or unreachable
+	WalletListenerURL string `toml:"wallet_listener_url"`
+	// SyncedOnly mines only when the chain is synced to the peers
+	SyncedOnly bool `toml:"synced_only"`
+	// RunWindow is the local time of day the miner runs at, "HH:MM-HH:MM"
+	// (e.g. "22:00-06:00" of the off-peak hours), empty is the whole day
+	RunWindow string `toml:"run_window"`
+}
+
+// Logging is the log settings
+type Logging struct {
+	// Level is the logrus level name (debug, info, warning, error)
+	Level string `toml:"level"`
+	// Modules is the levels of the p2p, chain, mempool, storage & mining
+	// modules overriding Level
+	Modules map[string]string `toml:"modules"`
+	// Format is the output format: text or json
+	Format string `toml:"format"`
+
+	// File is the log file, relative to the chain dir. Empty means stdout
+	File string `toml:"file"`
+	// MaxSize is the size in megabytes of the file to be rotated
+	MaxSize int `toml:"max_size"`
+	// MaxBackups is the count of the rotated files to keep, 0 keeps all
+	MaxBackups int `toml:"max_backups"`
+	// MaxAge is the count of days to keep the rotated files, 0 keeps all
+	MaxAge int `toml:"max_age"`
+	// RotateInterval rotates the file by time (e.g. "24h"), empty means
+	// size rotation only
+	RotateInterval string `toml:"rotate_interval"`
+}
+
+// Storage is the blockchain storage settings
+type Storage struct {
+	// DSN is the mysql data source name, empty means no database
+	DSN string `toml:"dsn"`
+}
+
+// Metrics is the prometheus metrics settings
+type Metrics struct {
+	Enabled bool `toml:"enabled"`
+	// ListenAddr is the addr of the /metrics endpoint
+	ListenAddr string `toml:"listen_addr"`
+	// PushURL is the push gateway url, empty means no pushing
+	PushURL string `toml:"push_url"`
+	// PushInterval is the period of pushing in seconds
+	PushInterval int `toml:"push_interval"`
+}
+
+// Telemetry is the opt-in reporting of the anonymized node stats
+type Telemetry struct {
+	Enabled bool `toml:"enabled"`
+	// URL is the endpoint the reports are posted to
+	URL string `toml:"url"`
+	// Interval is the period of the reports in seconds
+	Interval int `toml:"interval"`
+}
+
+// BanSync is the sharing of the ban list with the trusted nodes of the same
+// operator over their owner APIs
+type BanSync struct {
+	// Nodes are the owner API urls of the trusted nodes, empty disables the
+	// sync
+	Nodes []string `toml:"nodes"`
+	// SecretPath is the file of the owner API secret of the nodes, relative
+	// to the chain dir. Empty is the owner API secret of this node
+	SecretPath string `toml:"secret_path"`
+	// Interval is the period of the sync in seconds
+	Interval int `toml:"interval"`
+}
+
+// Health is the readiness probe settings
+type Health struct {
+	// MaxSyncLag is the max count of blocks behind the best peer
+	MaxSyncLag int `toml:"max_sync_lag"`
+	// MinPeers is the min count of the connected peers
+	MinPeers int `toml:"min_peers"`
+}
+
+// ForkAlarm is the alarm of the most peers ahead of the synced chain, the
+// chain is likely on a minority fork or stalled
+type ForkAlarm struct {
+	// PeersPercent is the percent of the connected peers ahead raising the
+	// alarm, 0 disables it
+	PeersPercent int `toml:"peers_percent"`
+	// Blocks is the lead of the peers total difficulty in the blocks of the
+	// head difficulty
+	Blocks int `toml:"blocks"`
+	// DurationSecs is how long the peers are ahead before the alarm
+	DurationSecs int `toml:"duration_secs"`
+}
+
+// Consensus overrides the consensus parameters of the network, the empty
+// values keep the network ones
+type Consensus struct {
+	// MagicCode is the 2 bytes hex of the message header
+	MagicCode string `toml:"magic_code"`
+	// BlockTime is the block interval (e.g. "30s")
+	BlockTime              string `toml:"block_time"`
+	HardForkV2Height       uint64 `toml:"hard_fork_v2_height"`
+	HardForkInterval       uint64 `toml:"hard_fork_interval"`
+	DifficultyAdjustWindow int    `toml:"difficulty_adjust_window"`
+	DampFactor             int    `toml:"damp_factor"`
+	MinEdgeBits            int    `toml:"min_edge_bits"`
+	// the DoS protection limits of the messages
+	MaxMsgLen       uint64 `toml:"max_msg_len"`
+	MaxBlockHeaders int    `toml:"max_block_headers"`
+	MaxPeerAddrs    int    `toml:"max_peer_addrs"`
+	MaxLocators     int    `toml:"max_locators"`
+	MaxListLen      uint64 `toml:"max_list_len"`
+}
+
+// Default returns config with the sane defaults
+func Default() *Config {
+	return &Config{
+		DataDir:   defaultDataDir(),
+		ChainDirs: true,
+		Network:   "testnet4",
+		Mode:      ModeFull,
+		P2P: P2P{
+			ListenAddr:   "0.0.0.0",
+			Seeds:        []string{"127.0.0.1:13414"},
+			DefaultSeeds: true,
+			MaxPeers:     15,
+			IBDDistance:  5,
+		},
+		API: API{
+			Enabled:           true,
+			ListenAddr:        "127.0.0.1:13413",
+			OwnerListenAddr:   "127.0.0.1:13420",
+			SecretPath:        ".api_secret",
+			ForeignSecretPath: ".foreign_api_secret",
+			RateLimit:         20,
+			RateBurst:         100,
+		},
+		Mempool: Mempool{
+			ReplaceFeeRateIncrease: 25,
+			MaxTxWeight:            8000,
+			MaxTxKernels:           50,
+			MaxTxOutputs:           500,
+		},
+		Dandelion: Dandelion{
+			EpochSecs:       600,
+			StemProbability: 90,
+			AggregationSecs: 30,
+			EmbargoSecs:     180,
+		},
+		Mining: Mining{
+			Enabled:    false,
+			Threads:    1,
+			SyncedOnly: true,
+		},
+		Logging: Logging{
+			Level:   "info",
+			Format:  "text",
+			MaxSize: 100,
+		},
+		Metrics: Metrics{
+			Enabled:      false,
+			ListenAddr:   "127.0.0.1:13416",
+			PushInterval: 15,
+		},
+		Telemetry: Telemetry{
+			Enabled:  false,
+			Interval: 3600,
+		},
+		BanSync: BanSync{
+			Interval: 60,
+		},
+		Health: Health{
+			MaxSyncLag: 5,
+			MinPeers:   1,
+		},
+		ForkAlarm: ForkAlarm{
+			PeersPercent: 60,
+			Blocks:       5,
+			DurationSecs: 600,
+		},
+	}
+}
+
+// Load returns the defaults overridden by the config file at path (if it
+// exists) and then by the environment variables.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if _, err := os.Stat(path); err == nil {
+			if _, err := toml.DecodeFile(path, cfg); err != nil {
+				return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Validate returns error if config has invalid values
+func (c *Config) Validate() error {
+	if c.DataDir == "" {
+		return errors.New("data_dir is not set")
+	}
+
+	if c.Mode != ModeFull && c.Mode != ModeHeaders {
+		return fmt.Errorf("invalid mode: %s", c.Mode)
+	}
+
+	if c.Mode == ModeHeaders && c.Mining.Enabled {
+		return errors.New("mining is not supported by the headers mode")
+	}
+
+	if c.Mode == ModeHeaders && c.ArchiveMode {
+		return errors.New("archive_mode is not supported by the headers mode")
+	}
+
+	if c.P2P.MaxPeers <= 0 {
+		return fmt.Errorf("invalid p2p.max_peers: %d", c.P2P.MaxPeers)
+	}
+
+	if c.Workers < 0 {
+		return fmt.Errorf("invalid workers: %d", c.Workers)
+	}
+
+	for _, hash := range c.InvalidBlocks {
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+			return fmt.Errorf("invalid invalid_blocks hash: %s", hash)
+		}
+	}
+
+	if c.P2P.HeaderWorkers < 0 {
+		return fmt.Errorf("invalid p2p.header_workers: %d", c.P2P.HeaderWorkers)
+	}
+
+	if c.P2P.IBDDistance < 0 {
+		return fmt.Errorf("invalid p2p.ibd_distance: %d", c.P2P.IBDDistance)
+	}
+
+	if c.Mempool.ReplaceFeeRateIncrease < 0 {
+		return fmt.Errorf("invalid mempool.replace_fee_rate_increase: %d", c.Mempool.ReplaceFeeRateIncrease)
+	}
+
+	if c.Mempool.MaxTxWeight < 0 || c.Mempool.MaxTxKernels < 0 || c.Mempool.MaxTxOutputs < 0 {
+		return fmt.Errorf("invalid mempool settings: %+v", c.Mempool)
+	}
+
+	if c.Dandelion.EpochSecs <= 0 || c.Dandelion.AggregationSecs <= 0 || c.Dandelion.EmbargoSecs <= 0 ||
+		c.Dandelion.StemProbability < 0 || c.Dandelion.StemProbability > 100 {
+		return fmt.Errorf("invalid dandelion settings: %+v", c.Dandelion)
+	}
+
+	if c.Mining.Threads <= 0 {
+		return fmt.Errorf("invalid mining.threads: %d", c.Mining.Threads)
+	}
+
+	if c.Mining.WalletListenerURL != "" {
+		if u, err := url.Parse(c.Mining.WalletListenerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid mining.wallet_listener_url: %s", c.Mining.WalletListenerURL)
+		}
+	} else if c.Mining.Enabled && c.Network != "usernet" {
+		return errors.New("mining requires mining.wallet_listener_url")
+	}
+
+	if !validWindow(c.Mining.RunWindow) {
+		return fmt.Errorf("invalid mining.run_window: %s", c.Mining.RunWindow)
+	}
+
+	if c.Logging.RotateInterval != "" {
+		if _, err := time.ParseDuration(c.Logging.RotateInterval); err != nil {
+			return fmt.Errorf("invalid logging.rotate_interval: %v", err)
+		}
+	}
+
+	if (c.API.TLSCertFile == "") != (c.API.TLSKeyFile == "") {
+		return errors.New("api.tls_cert_file & api.tls_key_file must be set together")
+	}
+
+	if c.API.WalletProxyURL != "" {
+		if u, err := url.Parse(c.API.WalletProxyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid api.wallet_proxy_url: %s", c.API.WalletProxyURL)
+		}
+	}
+
+	for _, proxy := range c.API.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid api.trusted_proxies: %s", proxy)
+		}
+	}
+
+	if c.API.BasePath != "" && (!strings.HasPrefix(c.API.BasePath, "/") || c.API.BasePath == "/") {
+		return fmt.Errorf("invalid api.base_path: %s", c.API.BasePath)
+	}
+
+	if c.API.RateLimit < 0 || c.API.RateBurst < 0 {
+		return fmt.Errorf("invalid api rate limit: %d, burst %d", c.API.RateLimit, c.API.RateBurst)
+	}
+
+	if c.Health.MaxSyncLag < 0 || c.Health.MinPeers < 0 {
+		return fmt.Errorf("invalid health settings: %+v", c.Health)
+	}
+
+	if c.ForkAlarm.PeersPercent < 0 || c.ForkAlarm.PeersPercent > 100 || c.ForkAlarm.Blocks < 0 || c.ForkAlarm.DurationSecs < 0 {
+		return fmt.Errorf("invalid fork_alarm settings: %+v", c.ForkAlarm)
+	}
+
+	if c.Metrics.PushURL != "" && c.Metrics.PushInterval <= 0 {
+		return fmt.Errorf("invalid metrics.push_interval: %d", c.Metrics.PushInterval)
+	}
+
+	if c.Telemetry.Enabled {
+		if u, err := url.Parse(c.Telemetry.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid telemetry.url: %s", c.Telemetry.URL)
+		}
+	}
+
+	if c.Telemetry.Interval <= 0 {
+		return fmt.Errorf("invalid telemetry.interval: %d", c.Telemetry.Interval)
+	}
+
+	for _, node := range c.BanSync.Nodes {
+		if u, err := url.Parse(node); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid ban_sync.nodes url: %s", node)
+		}
+	}
+
+	if c.BanSync.Interval <= 0 {
+		return fmt.Errorf("invalid ban_sync.interval: %d", c.BanSync.Interval)
+	}
+
+	return c.Consensus.validate()
+}
+
+// validate returns error if the overrides are invalid
+func (c *Consensus) validate() error {
+	if c.MagicCode != "" {
+		if magic, err := hex.DecodeString(c.MagicCode); err != nil || len(magic) != 2 {
+			return fmt.Errorf("invalid consensus.magic_code: %s", c.MagicCode)
+		}
+	}
+
+	if c.BlockTime != "" {
+		if d, err := time.ParseDuration(c.BlockTime); err != nil || d < time.Second {
+			return fmt.Errorf("invalid consensus.block_time: %s", c.BlockTime)
+		}
+	}
+
+	if c.DifficultyAdjustWindow < 0 || c.DampFactor < 0 || c.MinEdgeBits < 0 || c.MinEdgeBits > 63 {
+		return fmt.Errorf("invalid consensus settings: %+v", *c)
+	}
+
+	// the headers count is uint16 on the wire, the locator has the head &
+	// the genesis at least
+	if c.MaxBlockHeaders < 0 || c.MaxBlockHeaders > math.MaxUint16 || c.MaxPeerAddrs < 0 || c.MaxLocators < 0 || c.MaxLocators == 1 {
+		return fmt.Errorf("invalid consensus limits: %+v", *c)
+	}
+
+	return nil
+}
+
+// applyEnv overrides settings by GRINGO_* environment variables
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	str := map[string]*string{
+		"GRINGO_DATA_DIR":                     &c.DataDir,
+		"GRINGO_NETWORK":                      &c.Network,
+		"GRINGO_MODE":                         &c.Mode,
+		"GRINGO_P2P_LISTEN_ADDR":              &c.P2P.ListenAddr,
+		"GRINGO_P2P_USER_AGENT":               &c.P2P.UserAgent,
+		"GRINGO_API_LISTEN_ADDR":              &c.API.ListenAddr,
+		"GRINGO_API_GRPC_LISTEN_ADDR":         &c.API.GRPCListenAddr,
+		"GRINGO_API_OWNER_LISTEN_ADDR":        &c.API.OwnerListenAddr,
+		"GRINGO_API_TLS_CERT_FILE":            &c.API.TLSCertFile,
+		"GRINGO_API_TLS_KEY_FILE":             &c.API.TLSKeyFile,
+		"GRINGO_API_SECRET_PATH":              &c.API.SecretPath,
+		"GRINGO_API_FOREIGN_SECRET_PATH":      &c.API.ForeignSecretPath,
+		"GRINGO_API_BASE_PATH":                &c.API.BasePath,
+		"GRINGO_API_WALLET_PROXY_URL":         &c.API.WalletProxyURL,
+		"GRINGO_API_WALLET_PROXY_SECRET_PATH": &c.API.WalletProxySecretPath,
+		"GRINGO_API_WALLET_PROXY_CA_FILE":     &c.API.WalletProxyCAFile,
+		"GRINGO_LOG_LEVEL":                    &c.Logging.Level,
+		"GRINGO_STORAGE_DSN":                  &c.Storage.DSN,
+		"GRINGO_MINING_WALLET_LISTENER_URL":   &c.Mining.WalletListenerURL,
+		"GRINGO_MINING_RUN_WINDOW":            &c.Mining.RunWindow,
+		"GRINGO_TELEMETRY_URL":                &c.Telemetry.URL,
+		"GRINGO_BAN_SYNC_SECRET_PATH":         &c.BanSync.SecretPath,
+	}
+
+	for name, field := range str {
+		if value, ok := lookup(name); ok {
+			*field = value
+		}
+	}
+
+	num := map[string]*int{
+		"GRINGO_WORKERS":                           &c.Workers,
+		"GRINGO_P2P_MAX_PEERS":                     &c.P2P.MaxPeers,
+		"GRINGO_P2P_HEADER_WORKERS":                &c.P2P.HeaderWorkers,
+		"GRINGO_P2P_IBD_DISTANCE":                  &c.P2P.IBDDistance,
+		"GRINGO_MEMPOOL_REPLACE_FEE_RATE_INCREASE": &c.Mempool.ReplaceFeeRateIncrease,
+		"GRINGO_MEMPOOL_MAX_TX_WEIGHT":             &c.Mempool.MaxTxWeight,
+		"GRINGO_MEMPOOL_MAX_TX_KERNELS":            &c.Mempool.MaxTxKernels,
+		"GRINGO_MEMPOOL_MAX_TX_OUTPUTS":            &c.Mempool.MaxTxOutputs,
+		"GRINGO_DANDELION_EPOCH_SECS":              &c.Dandelion.EpochSecs,
+		"GRINGO_DANDELION_STEM_PROBABILITY":        &c.Dandelion.StemProbability,
+		"GRINGO_DANDELION_AGGREGATION_SECS":        &c.Dandelion.AggregationSecs,
+		"GRINGO_DANDELION_EMBARGO_SECS":            &c.Dandelion.EmbargoSecs,
+		"GRINGO_MINING_THREADS":                    &c.Mining.Threads,
+		"GRINGO_API_RATE_LIMIT":                    &c.API.RateLimit,
+		"GRINGO_API_RATE_BURST":                    &c.API.RateBurst,
+		"GRINGO_METRICS_PUSH_INTERVAL":             &c.Metrics.PushInterval,
+		"GRINGO_HEALTH_MAX_SYNC_LAG":               &c.Health.MaxSyncLag,
+		"GRINGO_HEALTH_MIN_PEERS":                  &c.Health.MinPeers,
+		"GRINGO_FORK_ALARM_PEERS_PERCENT":          &c.ForkAlarm.PeersPercent,
+		"GRINGO_FORK_ALARM_BLOCKS":                 &c.ForkAlarm.Blocks,
+		"GRINGO_FORK_ALARM_DURATION_SECS":          &c.ForkAlarm.DurationSecs,
+		"GRINGO_TELEMETRY_INTERVAL":                &c.Telemetry.Interval,
+		"GRINGO_BAN_SYNC_INTERVAL":                 &c.BanSync.Interval,
+	}
+
+	for name, field := range num {
+		if value, ok := lookup(name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*field = n
+		}
+	}
+
+	flags := map[string]*bool{
+		"GRINGO_ARCHIVE_MODE":           &c.ArchiveMode,
+		"GRINGO_CHAIN_DIRS":             &c.ChainDirs,
+		"GRINGO_P2P_DEFAULT_SEEDS":      &c.P2P.DefaultSeeds,
+		"GRINGO_P2P_MDNS":               &c.P2P.MDNS,
+		"GRINGO_P2P_NO_LISTEN":          &c.P2P.NoListen,
+		"GRINGO_API_ENABLED":            &c.API.Enabled,
+		"GRINGO_API_TLS_SELF_SIGNED":    &c.API.TLSSelfSigned,
+		"GRINGO_MEMPOOL_REPLACE_BY_FEE": &c.Mempool.ReplaceByFee,
+		"GRINGO_MINING_ENABLED":         &c.Mining.Enabled,
+		"GRINGO_MINING_SYNCED_ONLY":     &c.Mining.SyncedOnly,
+		"GRINGO_METRICS_ENABLED":        &c.Metrics.Enabled,
+		"GRINGO_TELEMETRY_ENABLED":      &c.Telemetry.Enabled,
+	}
+
+	for name, field := range flags {
+		if value, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*field = b
+		}
+	}
+
+	lists := map[string]*[]string{
+		"GRINGO_P2P_SEEDS":           &c.P2P.Seeds,
+		"GRINGO_API_CORS_ORIGINS":    &c.API.CORSOrigins,
+		"GRINGO_API_TRUSTED_PROXIES": &c.API.TrustedProxies,
+		"GRINGO_BAN_SYNC_NODES":      &c.BanSync.Nodes,
+		"GRINGO_INVALID_BLOCKS":      &c.InvalidBlocks,
+	}
+
+	for name, field := range lists {
+		if value, ok := lookup(name); ok {
+			*field = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*field = append(*field, item)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ChainDir returns the directory of the network data: the node key, the ban
+// list, the secrets & the logs
+func (c *Config) ChainDir() string {
+	if !c.ChainDirs {
+		return c.DataDir
+	}
+
+	return filepath.Join(c.DataDir, ChainDirName(c.Network))
+}
+
+// ChainDirName returns the directory name of the network as in grin: the
+// network name without "net", main for mainnet & floo for floonet
+func ChainDirName(network string) string {
+	return strings.Replace(network, "net", "", 1)
+}
+
+// defaultDataDir returns ~/.gringo
+func defaultDataDir() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ".gringo"
+	}
+
+	return filepath.Join(home, ".gringo")
+}
+
+// validWindow returns true if s is empty or the "HH:MM-HH:MM" window
+func validWindow(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	bounds := strings.Split(s, "-")
+	if len(bounds) != 2 {
+		return false
+	}
+
+	for _, bound := range bounds {
+		if _, err := time.Parse("15:04", strings.TrimSpace(bound)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,402 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, FileName)
+	data := []byte(`
+data_dir = "/var/lib/gringo"
+network = "mainnet"
+workers = 2
+
+[p2p]
+seeds = ["10.0.0.1:3414", "10.0.0.2:3414"]
+default_seeds = false
+max_peers = 8
+
+[mempool]
+replace_by_fee = true
+
+[dandelion]
+stem_probability = 50
+
+[logging]
+level = "debug"
+`)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("GRINGO_P2P_MAX_PEERS", "20")
+	defer os.Unsetenv("GRINGO_P2P_MAX_PEERS")
+	os.Setenv("GRINGO_P2P_NO_LISTEN", "true")
+	defer os.Unsetenv("GRINGO_P2P_NO_LISTEN")
+	os.Setenv("GRINGO_MEMPOOL_REPLACE_FEE_RATE_INCREASE", "50")
+	defer os.Unsetenv("GRINGO_MEMPOOL_REPLACE_FEE_RATE_INCREASE")
+	os.Setenv("GRINGO_DANDELION_EMBARGO_SECS", "60")
+	defer os.Unsetenv("GRINGO_DANDELION_EMBARGO_SECS")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.DataDir != "/var/lib/gringo" || cfg.Network != "mainnet" || cfg.Workers != 2 {
+		t.Errorf("file settings were not applied: %+v", cfg)
+	}
+
+	if !reflect.DeepEqual(cfg.P2P.Seeds, []string{"10.0.0.1:3414", "10.0.0.2:3414"}) {
+		t.Errorf("seeds were %v", cfg.P2P.Seeds)
+	}
+
+	if cfg.P2P.DefaultSeeds {
+		t.Errorf("default seeds were not disabled")
+	}
+
+	// env overrides the file
+	if cfg.P2P.MaxPeers != 20 {
+		t.Errorf("max peers was %d, want 20", cfg.P2P.MaxPeers)
+	}
+
+	if !cfg.P2P.NoListen {
+		t.Errorf("no listen was not enabled")
+	}
+
+	if !cfg.Mempool.ReplaceByFee || cfg.Mempool.ReplaceFeeRateIncrease != 50 {
+		t.Errorf("mempool settings were %+v", cfg.Mempool)
+	}
+
+	if cfg.Dandelion != (Dandelion{EpochSecs: 600, StemProbability: 50, AggregationSecs: 30, EmbargoSecs: 60}) {
+		t.Errorf("dandelion settings were %+v", cfg.Dandelion)
+	}
+
+	// defaults are kept for missing settings
+	if cfg.API.ListenAddr != Default().API.ListenAddr {
+		t.Errorf("api listen addr was %s", cfg.API.ListenAddr)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(os.TempDir(), "not-exists", FileName))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("expected defaults, got %+v", cfg)
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	cfg := Default()
+	env := map[string]string{"GRINGO_P2P_MAX_PEERS": "many"}
+
+	err := cfg.applyEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+
+	if err == nil {
+		t.Errorf("expected error on invalid env value")
+	}
+}
+
+func TestValidateConsensus(t *testing.T) {
+	for _, c := range []Consensus{
+		{MagicCode: "54"},
+		{MagicCode: "xyz0"},
+		{BlockTime: "10ms"},
+		{MinEdgeBits: 64},
+		{DampFactor: -1},
+	} {
+		cfg := Default()
+		cfg.Consensus = c
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("invalid consensus settings %+v were accepted", c)
+		}
+	}
+
+	cfg := Default()
+	cfg.Consensus = Consensus{MagicCode: "4a2b", BlockTime: "30s", DampFactor: 1, MinEdgeBits: 15}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid consensus settings were rejected: %v", err)
+	}
+}
+
+func TestValidateMempool(t *testing.T) {
+	for _, m := range []Mempool{
+		{ReplaceFeeRateIncrease: -1},
+		{MaxTxWeight: -1},
+		{MaxTxKernels: -1},
+		{MaxTxOutputs: -1},
+	} {
+		cfg := Default()
+		cfg.Mempool = m
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("invalid mempool settings %+v were accepted", m)
+		}
+	}
+
+	cfg := Default()
+	cfg.Mempool = Mempool{}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unlimited mempool settings were rejected: %v", err)
+	}
+}
+
+func TestValidateDandelion(t *testing.T) {
+	for _, update := range []func(d *Dandelion){
+		func(d *Dandelion) { d.EpochSecs = 0 },
+		func(d *Dandelion) { d.AggregationSecs = -1 },
+		func(d *Dandelion) { d.EmbargoSecs = 0 },
+		func(d *Dandelion) { d.StemProbability = 101 },
+	} {
+		cfg := Default()
+		update(&cfg.Dandelion)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("invalid dandelion settings %+v were accepted", cfg.Dandelion)
+		}
+	}
+
+	cfg := Default()
+	cfg.Dandelion.StemProbability = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("dandelion settings of no stem epochs were rejected: %v", err)
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	cfg := Default()
+	cfg.API.TLSCertFile = "api.crt"
+
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("cert file without key file was accepted")
+	}
+
+	cfg.API.TLSKeyFile = "api.key"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid tls settings were rejected: %v", err)
+	}
+}
+
+func TestValidateProxy(t *testing.T) {
+	for _, api := range []API{
+		{TrustedProxies: []string{"nginx"}},
+		{TrustedProxies: []string{"10.0.0.0/33"}},
+		{BasePath: "grin"},
+		{BasePath: "/"},
+		{WalletProxyURL: "127.0.0.1:3415"},
+	} {
+		cfg := Default()
+		cfg.API.TrustedProxies, cfg.API.BasePath, cfg.API.WalletProxyURL = api.TrustedProxies, api.BasePath, api.WalletProxyURL
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("invalid proxy settings %+v were accepted", api)
+		}
+	}
+
+	cfg := Default()
+	env := map[string]string{
+		"GRINGO_API_CORS_ORIGINS":     "https://wallet.example, *",
+		"GRINGO_API_TRUSTED_PROXIES":  "127.0.0.1,10.0.0.0/8",
+		"GRINGO_API_BASE_PATH":        "/grin",
+		"GRINGO_API_WALLET_PROXY_URL": "https://127.0.0.1:3415",
+	}
+	if err := cfg.applyEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}); err != nil {
+		t.Fatalf("applyEnv failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(cfg.API.CORSOrigins, []string{"https://wallet.example", "*"}) ||
+		!reflect.DeepEqual(cfg.API.TrustedProxies, []string{"127.0.0.1", "10.0.0.0/8"}) || cfg.API.BasePath != "/grin" ||
+		cfg.API.WalletProxyURL != "https://127.0.0.1:3415" {
+		t.Errorf("proxy settings were %+v", cfg.API)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid proxy settings were rejected: %v", err)
+	}
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	cfg := Default()
+	cfg.API.RateBurst = -1
+
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("negative rate burst was accepted")
+	}
+
+	cfg = Default()
+	if err := cfg.applyEnv(func(name string) (string, bool) {
+		return "0", name == "GRINGO_API_RATE_LIMIT"
+	}); err != nil {
+		t.Fatalf("applyEnv failed: %v", err)
+	}
+
+	if cfg.API.RateLimit != 0 || cfg.API.RateBurst != Default().API.RateBurst {
+		t.Errorf("rate limit settings were %d, %d", cfg.API.RateLimit, cfg.API.RateBurst)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled rate limit was rejected: %v", err)
+	}
+}
+
+func TestValidateTelemetry(t *testing.T) {
+	cfg := Default()
+	if cfg.Telemetry.Enabled {
+		t.Errorf("telemetry is enabled by default")
+	}
+
+	cfg.Telemetry.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("telemetry without url was accepted")
+	}
+
+	cfg.Telemetry.URL = "https://telemetry.example/report"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid telemetry settings were rejected: %v", err)
+	}
+
+	cfg.Telemetry.Interval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("zero telemetry interval was accepted")
+	}
+}
+
+func TestChainDir(t *testing.T) {
+	cfg := Default()
+	cfg.DataDir = "/var/lib/gringo"
+
+	for network, dir := range map[string]string{
+		"mainnet":  "/var/lib/gringo/main",
+		"floonet":  "/var/lib/gringo/floo",
+		"testnet4": "/var/lib/gringo/test4",
+		"usernet":  "/var/lib/gringo/user",
+	} {
+		cfg.Network = network
+		if cfg.ChainDir() != dir {
+			t.Errorf("%s chain dir was %s, want %s", network, cfg.ChainDir(), dir)
+		}
+	}
+
+	cfg.ChainDirs = false
+	if cfg.ChainDir() != cfg.DataDir {
+		t.Errorf("flat chain dir was %s", cfg.ChainDir())
+	}
+}
+
+func TestValidateBanSync(t *testing.T) {
+	cfg := Default()
+	if len(cfg.BanSync.Nodes) != 0 {
+		t.Errorf("ban sync is enabled by default")
+	}
+
+	cfg.BanSync.Nodes = []string{"http://10.0.0.2:13420", "10.0.0.3:13420"}
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("node without the url scheme was accepted")
+	}
+
+	cfg.BanSync.Nodes = cfg.BanSync.Nodes[:1]
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid ban sync settings were rejected: %v", err)
+	}
+
+	cfg.BanSync.Interval = 0
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("zero ban sync interval was accepted")
+	}
+}
+
+func TestValidateInvalidBlocks(t *testing.T) {
+	cfg := Default()
+	cfg.InvalidBlocks = []string{strings.Repeat("ab", 32)}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("valid invalid block hash was rejected: %v", err)
+	}
+
+	for _, hash := range []string{"abcd", strings.Repeat("xy", 32)} {
+		cfg.InvalidBlocks = []string{hash}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("invalid block hash %s was accepted", hash)
+		}
+	}
+}
+
+func TestValidateMining(t *testing.T) {
+	for _, test := range []struct {
+		network, url string
+		valid        bool
+	}{
+		{network: "testnet4", url: "http://127.0.0.1:3415", valid: true},
+		{network: "testnet4"},
+		{network: "usernet", valid: true},
+		{network: "usernet", url: "127.0.0.1:3415"},
+		{network: "usernet", url: "ftp://127.0.0.1:3415"},
+	} {
+		cfg := Default()
+		cfg.Network = test.network
+		cfg.Mining.Enabled = true
+		cfg.Mining.WalletListenerURL = test.url
+
+		if err := cfg.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s mining of wallet %q: %v, want valid %v", test.network, test.url, err, test.valid)
+		}
+	}
+
+	for window, valid := range map[string]bool{"": true, "22:00-06:00": true, "9:00 - 17:30": true, "22:00": false, "22:00-24:00": false} {
+		cfg := Default()
+		cfg.Mining.RunWindow = window
+
+		if err := cfg.Validate(); (err == nil) != valid {
+			t.Errorf("run window %q: %v, want valid %v", window, err, valid)
+		}
+	}
+}
+
+func TestValidateMode(t *testing.T) {
+	cfg := Default()
+	cfg.Mode = "light"
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("unknown mode was accepted")
+	}
+
+	cfg.Mode = ModeHeaders
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("headers mode was rejected: %v", err)
+	}
+
+	cfg.Mining.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("mining in headers mode was accepted")
+	}
+
+	cfg.Mining.Enabled = false
+	cfg.ArchiveMode = true
+	if err := cfg.Validate(); err == nil {
+		t.Errorf("archive mode in headers mode was accepted")
+	}
+
+	cfg.Mode = ModeFull
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("archive mode was rejected: %v", err)
+	}
+}
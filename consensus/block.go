@@ -6,11 +6,12 @@ package consensus
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/dblokhin/gringo/secp256k1zkp"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/wire"
 	"github.com/yoss22/bulletproofs"
 	"golang.org/x/crypto/blake2b"
 	"io"
@@ -63,7 +64,7 @@ func (f KernelFeatures) String() string {
 
 // BlockID identify block by Hash or/and Height (if not nill)
 type BlockID struct {
-	// Block hash, if nil - use the height
+	// Block hash, if zero - use the height
 	Hash Hash
 	// Block height, if nil - use the hash
 	Height *uint64
@@ -76,27 +77,19 @@ type Block struct {
 	Inputs  InputList
 	Outputs OutputList
 	Kernels TxKernelList
+
+	// validated is the params the block was validated with by ReadValidate
+	validated *Params
 }
 
-// Bytes implements p2p Message interface
-func (b *Block) Bytes() []byte {
-	buff := new(bytes.Buffer)
-	if _, err := buff.Write(b.Header.Bytes()); err != nil {
-		logrus.Fatal(err)
-	}
+// write writes the block to buf
+func (b *Block) write(buf *bytes.Buffer) {
+	b.Header.write(buf)
 
 	// Write counts: inputs, outputs, kernels
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(b.Inputs))); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(b.Outputs))); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(b.Kernels))); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint64(buf, uint64(len(b.Inputs)))
+	wire.WriteUint64(buf, uint64(len(b.Outputs)))
+	wire.WriteUint64(buf, uint64(len(b.Kernels)))
 
 	// consensus rule: input, output, kernels MUST BE sorted!
 	sort.Sort(b.Inputs)
@@ -105,26 +98,28 @@ func (b *Block) Bytes() []byte {
 
 	// Write inputs
 	for _, input := range b.Inputs {
-		if _, err := buff.Write(input.Bytes()); err != nil {
-			logrus.Fatal(err)
-		}
+		buf.Write(input.Bytes())
 	}
 
 	// Write outputs
 	for _, output := range b.Outputs {
-		if _, err := buff.Write(output.Bytes()); err != nil {
-			logrus.Fatal(err)
-		}
+		buf.Write(output.Bytes())
 	}
 
 	// Write kernels
 	for _, txKernel := range b.Kernels {
-		if _, err := buff.Write(txKernel.Bytes()); err != nil {
-			logrus.Fatal(err)
-		}
+		buf.Write(txKernel.Bytes())
 	}
+}
 
-	return buff.Bytes()
+// Bytes implements p2p Message interface
+func (b *Block) Bytes() []byte {
+	return wire.Bytes(b.write)
+}
+
+// WriteTo implements io.WriterTo
+func (b *Block) WriteTo(w io.Writer) (int64, error) {
+	return wire.WriteTo(w, b.write)
 }
 
 // Type implements p2p Message interface
@@ -134,36 +129,18 @@ func (b *Block) Type() uint8 {
 
 // Read implements p2p Message interface
 func (b *Block) Read(r io.Reader) error {
+	b.validated = nil
+
 	// Read block header
 	if err := b.Header.Read(r); err != nil {
 		return err
 	}
 
-	// Read counts
-	var inputs, outputs, kernels uint64
-	if err := binary.Read(r, binary.BigEndian, &inputs); err != nil {
-		return err
-	}
-
-	if err := binary.Read(r, binary.BigEndian, &outputs); err != nil {
-		return err
-	}
-
-	if err := binary.Read(r, binary.BigEndian, &kernels); err != nil {
+	inputs, outputs, kernels, err := readCounts(r)
+	if err != nil {
 		return err
 	}
 
-	// Sanity check the lengths.
-	if inputs > 1000000 {
-		return errors.New("transaction contains too many inputs")
-	}
-	if outputs > 1000000 {
-		return errors.New("transaction contains too many outputs")
-	}
-	if kernels > 1000000 {
-		return errors.New("transaction contains too many kernels")
-	}
-
 	// Read inputs
 	b.Inputs = make([]Input, inputs)
 	for i := uint64(0); i < inputs; i++ {
@@ -191,6 +168,25 @@ func (b *Block) Read(r io.Reader) error {
 	return nil
 }
 
+// readCounts reads the counts of inputs, outputs & kernels, the counts are
+// limited by the block weight before the lists are allocated
+func readCounts(r io.Reader) (inputs, outputs, kernels uint64, err error) {
+	if inputs, err = wire.ReadLen(r, "inputs count", maxListLen); err != nil {
+		return
+	}
+
+	if outputs, err = wire.ReadLen(r, "outputs count", maxListLen); err != nil {
+		return
+	}
+
+	if kernels, err = wire.ReadLen(r, "kernels count", maxListLen); err != nil {
+		return
+	}
+
+	err = verifyWeight(int(inputs), int(outputs), int(kernels))
+	return
+}
+
 // String implements String() interface
 func (p Block) String() string {
 	return fmt.Sprintf("%#v", p)
@@ -202,129 +198,293 @@ func (b *Block) Hash() Hash {
 }
 
 // Validate returns nil if block successfully passed BLOCK-SCOPE consensus rules
-func (b *Block) Validate() error {
-	logrus.Info("block scope validate")
-	/*
-		TODO: implement it:
+// of the network params, the range proofs verification is stopped on the ctx
+// cancellation. The block validated by ReadValidate with the same params
+// isn't validated again
+func (b *Block) Validate(ctx context.Context, params *Params) error {
+	if b.validated != nil && b.validated == params {
+		return nil
+	}
 
-		verify_weight()
-		verify_sorted()
-		verify_coinbase()
-		verify_kernels()
+	// Validate header and proof-of-work.
+	if err := b.Header.Validate(params); err != nil {
+		return err
+	}
 
-	*/
+	return b.ValidateBody(ctx)
+}
 
-	// Validate header and proof-of-work.
-	if err := b.Header.Validate(); err != nil {
+// ValidateBody returns nil if the inputs, outputs & kernels of the block
+// passed the BLOCK-SCOPE consensus rules, the header isn't validated
+func (b *Block) ValidateBody(ctx context.Context) error {
+	v, err := newBodyValidator(ctx, len(b.Inputs), len(b.Outputs), len(b.Kernels))
+	if err != nil {
 		return err
 	}
+	defer v.close()
 
-	// Check that consensus rule MaxBlockCoinbaseOutputs & MaxBlockCoinbaseKernels
-	if len(b.Outputs) == 0 || len(b.Kernels) == 0 {
-		return errors.New("invalid nocoinbase block")
+	for i := range b.Inputs {
+		if err := v.input(&b.Inputs[i]); err != nil {
+			return err
+		}
 	}
 
-	// Check sorted inputs, outputs, kernels
-	if err := b.verifySorted(); err != nil {
+	for i := range b.Outputs {
+		if err := v.output(&b.Outputs[i]); err != nil {
+			return err
+		}
+	}
+
+	for i := range b.Kernels {
+		if err := v.kernel(&b.Kernels[i]); err != nil {
+			return err
+		}
+	}
+
+	return v.wait()
+}
+
+// ReadValidate reads the block validating it while decoding, as Read &
+// Validate: the header & the weight of the counts are checked before the body
+// is read, the order, coinbase & range proof of every input, output & kernel
+// as it's decoded. The decoding is stopped on the first violation, so the
+// invalid block is never materialized in full
+func (b *Block) ReadValidate(ctx context.Context, r io.Reader, params *Params) error {
+	b.validated = nil
+
+	// Read & validate block header
+	if err := b.Header.Read(r); err != nil {
 		return err
 	}
 
-	if err := b.verifyCoinbase(); err != nil {
+	if err := b.Header.Validate(params); err != nil {
 		return err
 	}
 
-	// Verify all output values are within the correct range.
-	if err := b.verifyRangeProofs(); err != nil {
+	inputs, outputs, kernels, err := readCounts(r)
+	if err != nil {
 		return err
 	}
 
-	if err := b.verifyKernels(); err != nil {
+	v, err := newBodyValidator(ctx, int(inputs), int(outputs), int(kernels))
+	if err != nil {
 		return err
 	}
+	defer v.close()
 
-	return nil
-}
+	// the lists grow as the items are read & validated, the counts are not
+	// trusted before
+	b.Inputs, b.Outputs, b.Kernels = nil, nil, nil
+
+	for i := uint64(0); i < inputs; i++ {
+		var input Input
+		if err := input.Read(r); err != nil {
+			return err
+		}
 
-func (b *Block) verifyCoinbase() error {
-	coinbase := 0
+		if err := v.input(&input); err != nil {
+			return err
+		}
 
-	for _, output := range b.Outputs {
-		if output.Features&CoinbaseOutput == CoinbaseOutput {
-			coinbase++
+		b.Inputs = append(b.Inputs, input)
+	}
+
+	for i := uint64(0); i < outputs; i++ {
+		var output Output
+		if err := output.Read(r); err != nil {
+			return err
+		}
 
-			if coinbase > MaxBlockCoinbaseOutputs {
-				return errors.New("invalid block with few coinbase outputs")
-			}
+		if err := v.output(&output); err != nil {
+			return err
+		}
+
+		b.Outputs = append(b.Outputs, output)
+	}
+
+	for i := uint64(0); i < kernels; i++ {
+		var kernel TxKernel
+		if err := kernel.Read(r); err != nil {
+			return err
+		}
 
-			// Validate output
-			if err := output.Validate(); err != nil {
-				return err
-			}
+		if err := v.kernel(&kernel); err != nil {
+			return err
 		}
+
+		b.Kernels = append(b.Kernels, kernel)
+	}
+
+	if err := v.wait(); err != nil {
+		return err
 	}
 
-	// Check the roots
-	// TODO: do that
+	b.validated = params
+
+	return nil
+}
+
+// BlockWeight returns the weight of the inputs, outputs & kernels counted
+// against MaxBlockWeight
+func BlockWeight(inputs, outputs, kernels int) uint64 {
+	return uint64(inputs)*uint64(BlockInputWeight) +
+		uint64(outputs)*uint64(BlockOutputWeight) +
+		uint64(kernels)*uint64(BlockKernelWeight)
+}
+
+// verifyWeight checks the weight of the inputs, outputs & kernels doesn't
+// exceed MaxBlockWeight
+func verifyWeight(inputs, outputs, kernels int) error {
+	if weight := BlockWeight(inputs, outputs, kernels); weight > uint64(MaxBlockWeight) {
+		return fmt.Errorf("%w: %d", ErrTooHeavy, weight)
+	}
 
 	return nil
 }
 
-func (b *Block) verifyKernels() error {
-	coinbase := 0
+// bodyValidator checks the BLOCK-SCOPE rules of the body item by item, the
+// inputs, outputs & kernels are passed in the serialization order
+type bodyValidator struct {
+	ctx    context.Context
+	proofs *rangeVerifier
 
-	for _, kernel := range b.Kernels {
-		if kernel.Features&CoinbaseKernel == CoinbaseKernel {
-			coinbase++
+	// list & last are the list & the hash of the previous item
+	list string
+	last Hash
 
-			if coinbase > MaxBlockCoinbaseKernels {
-				return errors.New("invalid block with few coinbase kernels")
-			}
+	// the seen input & output commitments & kernel hashes
+	inputs  map[string]struct{}
+	outputs map[string]struct{}
+	kernels map[string]struct{}
 
-			// Validate kernel
-			if err := kernel.Validate(); err != nil {
-				return err
-			}
-		}
+	coinbaseOutputs int
+	coinbaseKernels int
+}
+
+// newBodyValidator returns the validator of the body of the counts, the
+// weight & the coinbase presence are checked by the counts. The range proofs
+// are verified by the workers, wait returns their result & close must be
+// called on the failure
+func newBodyValidator(ctx context.Context, inputs, outputs, kernels int) (*bodyValidator, error) {
+	if err := verifyWeight(inputs, outputs, kernels); err != nil {
+		return nil, err
 	}
 
-	// TODO: Verify that the kernel sums are correct.
+	// Check that consensus rule MaxBlockCoinbaseOutputs & MaxBlockCoinbaseKernels
+	if outputs == 0 || kernels == 0 {
+		return nil, fmt.Errorf("%w: no coinbase in block", ErrInvalidCoinbase)
+	}
 
-	// Check the roots
-	// TODO: do that
+	return &bodyValidator{
+		ctx:     ctx,
+		proofs:  newRangeVerifier(ctx),
+		inputs:  make(map[string]struct{}, inputs),
+		outputs: make(map[string]struct{}, outputs),
+		kernels: make(map[string]struct{}, kernels),
+	}, nil
+}
+
+// unique checks the key isn't seen in the set of the list & adds it
+func unique(set map[string]struct{}, list string, key []byte) error {
+	if _, ok := set[string(key)]; ok {
+		return fmt.Errorf("%w: %s %x", ErrDuplicate, list, key)
+	}
 
+	set[string(key)] = struct{}{}
 	return nil
 }
 
-// verifySorted checks sorted inputs, outputs, kernels
-func (b *Block) verifySorted() error {
-	if !sort.IsSorted(b.Inputs) {
-		return errors.New("block inputs are not sorted")
+// sorted checks the item hash isn't less than the previous item of the list
+func (v *bodyValidator) sorted(list string, hash Hash) error {
+	if v.list == list && hash.Compare(v.last) < 0 {
+		return fmt.Errorf("%w: block %s", ErrNotSorted, list)
+	}
+
+	v.list, v.last = list, hash
+	return nil
+}
+
+// input checks the order & the uniqueness of the input
+func (v *bodyValidator) input(input *Input) error {
+	if err := v.sorted("inputs", input.Hash()); err != nil {
+		return err
 	}
 
-	if !sort.IsSorted(b.Outputs) {
-		return errors.New("block outputs are not sorted")
+	return unique(v.inputs, "input", input.Commit)
+}
+
+// output checks the order, the uniqueness, the coinbase count & the range
+// proof of the output
+func (v *bodyValidator) output(output *Output) error {
+	if err := v.sorted("outputs", output.Hash()); err != nil {
+		return err
 	}
 
-	if !sort.IsSorted(b.Kernels) {
-		return errors.New("block kernels are not sorted")
+	if err := unique(v.outputs, "output", output.Commit.Bytes()); err != nil {
+		return err
 	}
 
-	return nil
+	if output.Features&CoinbaseOutput == CoinbaseOutput {
+		v.coinbaseOutputs++
+
+		if v.coinbaseOutputs > MaxBlockCoinbaseOutputs {
+			return fmt.Errorf("%w: too many coinbase outputs", ErrInvalidCoinbase)
+		}
+
+		// Validate output
+		if err := output.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := v.ctx.Err(); err != nil {
+		return err
+	}
+
+	// Verify the output value is within the correct range.
+	return v.proofs.verify(output)
 }
 
-// verifyRangeProofs returns nil if all outputs have valid range proofs.
-func (b *Block) verifyRangeProofs() error {
-	// TODO(yoss22): Batch verify these.
-	prover := bulletproofs.NewProver(64)
-	for _, output := range b.Outputs {
-		if !prover.Verify(output.Commit, output.RangeProof) {
-			return fmt.Errorf("proof verification failed for %v %v",
-				output.Commit, output.RangeProof)
+// kernel checks the order, the uniqueness & the coinbase count of the
+// kernel, the coinbase kernel signature is verified
+func (v *bodyValidator) kernel(kernel *TxKernel) error {
+	hash := kernel.Hash()
+	if err := v.sorted("kernels", hash); err != nil {
+		return err
+	}
+
+	if err := unique(v.kernels, "kernel", hash[:]); err != nil {
+		return err
+	}
+
+	if kernel.Features&CoinbaseKernel == CoinbaseKernel {
+		v.coinbaseKernels++
+
+		if v.coinbaseKernels > MaxBlockCoinbaseKernels {
+			return fmt.Errorf("%w: too many coinbase kernels", ErrInvalidCoinbase)
+		}
+
+		// Validate kernel
+		if err := kernel.Validate(); err != nil {
+			return err
 		}
 	}
+
+	// TODO: Verify that the kernel sums are correct.
+
 	return nil
 }
 
+// wait returns the result of the range proofs verification
+func (v *bodyValidator) wait() error {
+	return v.proofs.wait()
+}
+
+// close cancels the range proofs verification
+func (v *bodyValidator) close() {
+	v.proofs.close()
+}
+
 // CompactBlock compact version of grin block
 // Compact representation of a full block.
 // Each input/output/kernel is represented as a short_id.
@@ -345,21 +505,10 @@ type CompactBlock struct {
 // Bytes implements p2p Message interface
 func (b *CompactBlock) Bytes() []byte {
 	buff := new(bytes.Buffer)
-	if _, err := buff.Write(b.Header.Bytes()); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint8(len(b.Outputs))); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint8(len(b.Kernels))); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(b.KernelIDs))); err != nil {
-		logrus.Fatal(err)
-	}
+	b.Header.write(buff)
+	wire.WriteUint8(buff, uint8(len(b.Outputs)))
+	wire.WriteUint8(buff, uint8(len(b.Kernels)))
+	wire.WriteUint64(buff, uint64(len(b.KernelIDs)))
 
 	// consensus rule: input, output, kernels MUST BE sorted!
 	sort.Sort(b.Outputs)
@@ -369,21 +518,21 @@ func (b *CompactBlock) Bytes() []byte {
 	// Write outputs
 	for _, output := range b.Outputs {
 		if _, err := buff.Write(output.Bytes()); err != nil {
-			logrus.Fatal(err)
+			panic(err)
 		}
 	}
 
 	// Write kernels
 	for _, txKernel := range b.Kernels {
 		if _, err := buff.Write(txKernel.Bytes()); err != nil {
-			logrus.Fatal(err)
+			panic(err)
 		}
 	}
 
 	// Write kernels ids
 	for _, id := range b.KernelIDs {
 		if _, err := buff.Write(id); err != nil {
-			logrus.Fatal(err)
+			panic(err)
 		}
 	}
 
@@ -403,20 +552,22 @@ func (b *CompactBlock) Read(r io.Reader) error {
 	}
 
 	// Read counts
-	var (
-		outputs, kernels uint8
-		kernelIDs        uint64
-	)
+	outputs, err := wire.ReadUint8(r)
+	if err != nil {
+		return err
+	}
 
-	if err := binary.Read(r, binary.BigEndian, &outputs); err != nil {
+	kernels, err := wire.ReadUint8(r)
+	if err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &kernels); err != nil {
+	kernelIDs, err := wire.ReadLen(r, "kernel ids count", maxListLen)
+	if err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &kernelIDs); err != nil {
+	if err := verifyWeight(0, int(outputs), int(kernels)+int(kernelIDs)); err != nil {
 		return err
 	}
 
@@ -442,7 +593,7 @@ func (b *CompactBlock) Read(r io.Reader) error {
 	for i := uint64(0); i < kernelIDs; i++ {
 
 		shortID := make(ShortID, ShortIDSize)
-		if _, err := io.ReadFull(r, shortID); err != nil {
+		if err := wire.ReadBytes(r, shortID); err != nil {
 			return err
 		}
 
@@ -457,6 +608,29 @@ func (p CompactBlock) String() string {
 	return fmt.Sprintf("%#v", p)
 }
 
+// Compact returns the compact block of the block: the coinbase outputs &
+// kernels in full, the other kernels by the short ids of the block hash
+func (b *Block) Compact() *CompactBlock {
+	hash := b.Hash()
+	compact := CompactBlock{Header: b.Header}
+
+	for _, output := range b.Outputs {
+		if output.Features&CoinbaseOutput == CoinbaseOutput {
+			compact.Outputs = append(compact.Outputs, output)
+		}
+	}
+
+	for i := range b.Kernels {
+		if b.Kernels[i].Features&CoinbaseKernel == CoinbaseKernel {
+			compact.Kernels = append(compact.Kernels, b.Kernels[i])
+		} else {
+			compact.KernelIDs = append(compact.KernelIDs, b.Kernels[i].Hash().ShortID(hash))
+		}
+	}
+
+	return &compact
+}
+
 // Hash returns hash of block
 func (b *CompactBlock) Hash() Hash {
 	return b.Header.Hash()
@@ -471,25 +645,21 @@ type Input struct {
 
 func (input *Input) Bytes() []byte {
 	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, uint8(input.Features)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(input.Commit); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint8(buff, uint8(input.Features))
+	buff.Write(input.Commit)
 
 	return buff.Bytes()
 }
 
 func (input *Input) Read(r io.Reader) error {
-	if err := binary.Read(r, binary.BigEndian, &input.Features); err != nil {
+	features, err := wire.ReadUint8(r)
+	if err != nil {
 		return err
 	}
+	input.Features = OutputFeatures(features)
 
 	commitment := make([]byte, secp256k1zkp.PedersenCommitmentSize)
-	if _, err := io.ReadFull(r, commitment); err != nil {
+	if err := wire.ReadBytes(r, commitment); err != nil {
 		return err
 	}
 
@@ -499,9 +669,8 @@ func (input *Input) Read(r io.Reader) error {
 }
 
 // Hash returns a hash of the serialised input.
-func (input *Input) Hash() []byte {
-	hashed := blake2b.Sum256(input.Bytes())
-	return hashed[:]
+func (input *Input) Hash() Hash {
+	return blake2b.Sum256(input.Bytes())
 }
 
 // InputList sortable list of inputs
@@ -513,7 +682,7 @@ func (m InputList) Len() int {
 
 // Less is used to order inputs by their hash.
 func (m InputList) Less(i, j int) bool {
-	return bytes.Compare(m[i].Hash(), m[j].Hash()) < 0
+	return m[i].Hash().Compare(m[j].Hash()) < 0
 }
 
 func (m InputList) Swap(i, j int) {
@@ -543,14 +712,8 @@ type Output struct {
 func (o *Output) BytesWithoutProof() []byte {
 	buff := new(bytes.Buffer)
 
-	// Write features
-	if err := binary.Write(buff, binary.BigEndian, uint8(o.Features)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(o.Commit.Bytes()); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint8(buff, uint8(o.Features))
+	buff.Write(o.Commit.Bytes())
 
 	return buff.Bytes()
 }
@@ -558,20 +721,8 @@ func (o *Output) BytesWithoutProof() []byte {
 // Bytes implements p2p Message interface
 func (o *Output) Bytes() []byte {
 	buff := new(bytes.Buffer)
-
-	if _, err := buff.Write(o.BytesWithoutProof()); err != nil {
-		logrus.Fatal(err)
-	}
-
-	proof := o.RangeProof.Bytes()
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(proof))); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(proof); err != nil {
-		logrus.Fatal(err)
-	}
+	buff.Write(o.BytesWithoutProof())
+	wire.WriteBytesWithLen(buff, o.RangeProof.Bytes())
 
 	return buff.Bytes()
 }
@@ -579,9 +730,11 @@ func (o *Output) Bytes() []byte {
 // Read implements p2p Message interface
 func (o *Output) Read(r io.Reader) error {
 	// Read features
-	if err := binary.Read(r, binary.BigEndian, (*uint8)(&o.Features)); err != nil {
+	features, err := wire.ReadUint8(r)
+	if err != nil {
 		return err
 	}
+	o.Features = OutputFeatures(features)
 
 	// Read commitment
 	o.Commit = new(bulletproofs.Point)
@@ -590,17 +743,13 @@ func (o *Output) Read(r io.Reader) error {
 	}
 
 	// Read range proof
-	var proofLen uint64 // tha max is MaxProofSize (5134), but in message field it is uint64
-	if err := binary.Read(r, binary.BigEndian, &proofLen); err != nil {
+	proofLen, err := wire.ReadLen(r, "range proof len", uint64(secp256k1zkp.MaxProofSize))
+	if err != nil {
 		return err
 	}
 
-	if proofLen > uint64(secp256k1zkp.MaxProofSize) {
-		return fmt.Errorf("invalid range proof length: %d", proofLen)
-	}
-
 	proof := new(bulletproofs.BulletProof)
-	err := proof.Read(io.LimitReader(r, int64(proofLen)))
+	err = proof.Read(io.LimitReader(r, int64(proofLen)))
 	if err != nil {
 		return errors.New("failed to deserialize range proof")
 	}
@@ -620,9 +769,8 @@ func (p Output) String() string {
 }
 
 // Hash returns a hash of the serialised output.
-func (o *Output) Hash() []byte {
-	hashed := blake2b.Sum256(o.BytesWithoutProof())
-	return hashed[:]
+func (o *Output) Hash() Hash {
+	return blake2b.Sum256(o.BytesWithoutProof())
 }
 
 // OutputList sortable list of outputs
@@ -634,7 +782,7 @@ func (m OutputList) Len() int {
 
 // Less is used to order outputs by their hash.
 func (m OutputList) Less(i, j int) bool {
-	return bytes.Compare(m[i].Hash(), m[j].Hash()) < 0
+	return m[i].Hash().Compare(m[j].Hash()) < 0
 }
 
 func (m OutputList) Swap(i, j int) {
@@ -667,9 +815,8 @@ type TxKernel struct {
 }
 
 // Hash returns a hash of the serialised kernel.
-func (k *TxKernel) Hash() []byte {
-	hashed := blake2b.Sum256(k.Bytes())
-	return hashed[:]
+func (k *TxKernel) Hash() Hash {
+	return blake2b.Sum256(k.Bytes())
 }
 
 // Read implements p2p Message interface
@@ -677,27 +824,13 @@ func (k *TxKernel) Bytes() []byte {
 	buff := new(bytes.Buffer)
 
 	// Write features, fee & lock
-	if err := binary.Write(buff, binary.BigEndian, uint8(k.Features)); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint8(buff, uint8(k.Features))
+	wire.WriteUint64(buff, k.Fee)
+	wire.WriteUint64(buff, k.LockHeight)
 
-	if err := binary.Write(buff, binary.BigEndian, k.Fee); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, k.LockHeight); err != nil {
-		logrus.Fatal(err)
-	}
-
-	// Write Excess
-	if _, err := buff.Write(k.Excess.Bytes()); err != nil {
-		logrus.Fatal(err)
-	}
-
-	// Write ExcessSig
-	if _, err := buff.Write(k.ExcessSig[:]); err != nil {
-		logrus.Fatal(err)
-	}
+	// Write Excess & ExcessSig
+	buff.Write(k.Excess.Bytes())
+	buff.Write(k.ExcessSig[:])
 
 	return buff.Bytes()
 }
@@ -705,15 +838,17 @@ func (k *TxKernel) Bytes() []byte {
 // Read implements p2p Message interface
 func (k *TxKernel) Read(r io.Reader) error {
 	// Read features, fee & lock
-	if err := binary.Read(r, binary.BigEndian, (*uint8)(&k.Features)); err != nil {
+	features, err := wire.ReadUint8(r)
+	if err != nil {
 		return err
 	}
+	k.Features = KernelFeatures(features)
 
-	if err := binary.Read(r, binary.BigEndian, &k.Fee); err != nil {
+	if k.Fee, err = wire.ReadUint64(r); err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &k.LockHeight); err != nil {
+	if k.LockHeight, err = wire.ReadUint64(r); err != nil {
 		return err
 	}
 
@@ -722,15 +857,9 @@ func (k *TxKernel) Read(r io.Reader) error {
 		return err
 	}
 
-	if _, err := io.ReadFull(r, k.ExcessSig[:]); err != nil {
-		return err
-	}
-
-	return nil
+	return wire.ReadBytes(r, k.ExcessSig[:])
 }
 
-var ErrInvalidSignature = errors.New("signature isn't valid")
-
 // Validate returns nil if kernel successfully passed consensus rules.
 func (o *TxKernel) Validate() error {
 	// The spender signs the fee and lock height using the private key for P. If
@@ -764,7 +893,7 @@ func (m TxKernelList) Len() int {
 
 // Less is used to order kernels by their hash.
 func (m TxKernelList) Less(i, j int) bool {
-	return bytes.Compare(m[i].Hash(), m[j].Hash()) < 0
+	return m[i].Hash().Compare(m[j].Hash()) < 0
 }
 
 func (m TxKernelList) Swap(i, j int) {
@@ -813,197 +942,164 @@ type BlockHeader struct {
 
 // Hash is a hash based on the blocks proof of work.
 func (b *BlockHeader) Hash() Hash {
-	hash := blake2b.Sum256(b.POW.ProofBytes())
+	buf := wire.GetBuffer()
+	defer wire.PutBuffer(buf)
 
-	return hash[:]
+	b.POW.writeProof(buf)
+
+	return blake2b.Sum256(buf.Bytes())
 }
 
+// headerLenWithoutPOW is the size of the serialized header without the proof
+// of work: version, height, timestamp, the hashes & roots, kernel offset, MMR
+// sizes, total & scaling difficulty and nonce
+const headerLenWithoutPOW = 2 + 8 + 8 + 5*BlockHashSize + secp256k1zkp.SecretKeySize + 8 + 8 + 8 + 4 + 8
+
+// MaxBlockHeaderLen is the size of the serialized header of the max cuckoo
+// graph
+const MaxBlockHeaderLen = headerLenWithoutPOW + 1 + maxProofBytes
+
 // bytesWithoutPOW used in Hash() method, where doesnt need POW data
 func (b *BlockHeader) bytesWithoutPOW() []byte {
-	buff := new(bytes.Buffer)
+	return wire.Bytes(b.writeWithoutPOW)
+}
 
+// writeWithoutPOW writes the header without the proof of work to buf
+func (b *BlockHeader) writeWithoutPOW(buf *bytes.Buffer) {
 	// Write version, height of block
-	if err := binary.Write(buff, binary.BigEndian, b.Version); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, b.Height); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint16(buf, b.Version)
+	wire.WriteUint64(buf, b.Height)
 
 	// Write timestamp
-	if err := binary.Write(buff, binary.BigEndian, b.Timestamp.Unix()); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint64(buf, uint64(b.Timestamp.Unix()))
 
-	// Write prev blockhash
-	if len(b.Previous) != BlockHashSize {
-		logrus.Fatal(errors.New("invalid previous block hash len"))
-	}
-
-	if _, err := buff.Write(b.Previous); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if len(b.PreviousRoot) != BlockHashSize {
-		logrus.Fatal(errors.New("invalid previous root hash len"))
-	}
-
-	if _, err := buff.Write(b.PreviousRoot); err != nil {
-		logrus.Fatal(err)
-	}
+	// Write prev blockhash & roots, the fixed size hashes are never short
+	buf.Write(b.Previous[:])
+	buf.Write(b.PreviousRoot[:])
 
 	// Write UTXORoot, RangeProofRoot, KernelRoot
-	if len(b.UTXORoot) != BlockHashSize ||
-		len(b.RangeProofRoot) != BlockHashSize ||
-		len(b.KernelRoot) != BlockHashSize {
-		logrus.Fatal(errors.New("invalid UTXORoot/RangeProofRoot/KernelRoot len"))
-	}
+	buf.Write(b.UTXORoot[:])
+	buf.Write(b.RangeProofRoot[:])
+	buf.Write(b.KernelRoot[:])
+	buf.Write(b.TotalKernelOffset[:])
 
-	if _, err := buff.Write(b.UTXORoot); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(b.RangeProofRoot); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(b.KernelRoot); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if _, err := buff.Write(b.TotalKernelOffset); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, b.OutputMmrSize); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, b.KernelMmrSize); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(b.TotalDifficulty)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, b.ScalingDifficulty); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint64(buf, b.OutputMmrSize)
+	wire.WriteUint64(buf, b.KernelMmrSize)
+	wire.WriteUint64(buf, uint64(b.TotalDifficulty))
+	wire.WriteUint32(buf, b.ScalingDifficulty)
 
 	// Write nonce
-	if err := binary.Write(buff, binary.BigEndian, b.Nonce); err != nil {
-		logrus.Fatal(err)
-	}
-
-	return buff.Bytes()
+	wire.WriteUint64(buf, b.Nonce)
 }
 
-func (b *BlockHeader) bytesPOW() []byte {
-	return b.POW.Bytes()
+// write writes the header to buf
+func (b *BlockHeader) write(buf *bytes.Buffer) {
+	b.writeWithoutPOW(buf)
+	b.POW.write(buf)
 }
 
 // Bytes implements p2p Message interface
 func (b *BlockHeader) Bytes() []byte {
-	var buff bytes.Buffer
-	buff.Write(b.bytesWithoutPOW())
-	buff.Write(b.bytesPOW())
+	return wire.Bytes(b.write)
+}
 
-	return buff.Bytes()
+// WriteTo implements io.WriterTo
+func (b *BlockHeader) WriteTo(w io.Writer) (int64, error) {
+	return wire.WriteTo(w, b.write)
 }
 
 // Read implements p2p Message interface
 func (b *BlockHeader) Read(r io.Reader) error {
-	// Read version, height of block
-	if err := binary.Read(r, binary.BigEndian, &b.Version); err != nil {
+	// the fixed size fields & the edge bits of the proof are read at once,
+	// the hashes share the read buffer
+	data := make([]byte, headerLenWithoutPOW+1)
+	if _, err := io.ReadFull(r, data); err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &b.Height); err != nil {
-		return err
+	next := func(n int) []byte {
+		field := data[:n:n]
+		data = data[n:]
+		return field
 	}
 
-	// Read timestamp
-	var ts int64
-	if err := binary.Read(r, binary.BigEndian, &ts); err != nil {
-		return err
+	hash := func(h *Hash) {
+		copy(h[:], next(BlockHashSize))
 	}
 
+	// Read version, height of block
+	b.Version = binary.BigEndian.Uint16(next(2))
+	b.Height = binary.BigEndian.Uint64(next(8))
+
+	// Read timestamp
 	// FIXME: Check timestamp is in correct range.
-	b.Timestamp = time.Unix(ts, 0).UTC()
+	b.Timestamp = time.Unix(int64(binary.BigEndian.Uint64(next(8))), 0).UTC()
 
 	// Read prev blockhash
-	b.Previous = make([]byte, BlockHashSize)
-	if _, err := io.ReadFull(r, b.Previous); err != nil {
-		return err
-	}
-
-	b.PreviousRoot = make([]byte, BlockHashSize)
-	if _, err := io.ReadFull(r, b.PreviousRoot); err != nil {
-		return err
-	}
+	hash(&b.Previous)
+	hash(&b.PreviousRoot)
 
 	// Read UTXORoot, RangeProofRoot, KernelRoot
-	b.UTXORoot = make([]byte, BlockHashSize)
-	if _, err := io.ReadFull(r, b.UTXORoot); err != nil {
-		return err
-	}
-
-	b.RangeProofRoot = make([]byte, BlockHashSize)
-	if _, err := io.ReadFull(r, b.RangeProofRoot); err != nil {
+	hash(&b.UTXORoot)
+	hash(&b.RangeProofRoot)
+	hash(&b.KernelRoot)
+	hash(&b.TotalKernelOffset)
+
+	b.OutputMmrSize = binary.BigEndian.Uint64(next(8))
+	b.KernelMmrSize = binary.BigEndian.Uint64(next(8))
+	b.TotalDifficulty = Difficulty(binary.BigEndian.Uint64(next(8)))
+	b.ScalingDifficulty = binary.BigEndian.Uint32(next(4))
+	b.Nonce = binary.BigEndian.Uint64(next(8))
+
+	b.POW.EdgeBits = data[0]
+	if err := b.POW.readNonces(r); err != nil {
 		return err
 	}
 
-	b.KernelRoot = make([]byte, BlockHashSize)
-	if _, err := io.ReadFull(r, b.KernelRoot); err != nil {
-		return err
-	}
-
-	b.TotalKernelOffset = make([]byte, secp256k1zkp.SecretKeySize)
-	if _, err := io.ReadFull(r, b.TotalKernelOffset); err != nil {
-		return err
-	}
+	// the difficulty is not serialized, it is the difficulty of the proof
+	b.Difficulty = b.POW.ToDifficulty()
 
-	if err := binary.Read(r, binary.BigEndian, &b.OutputMmrSize); err != nil {
-		return err
-	}
-
-	if err := binary.Read(r, binary.BigEndian, &b.KernelMmrSize); err != nil {
-		return err
-	}
+	return nil
+}
 
-	if err := binary.Read(r, binary.BigEndian, &b.TotalDifficulty); err != nil {
+// Validate returns nil if header successfully passed consensus rules of the
+// network params
+func (b *BlockHeader) Validate(params *Params) error {
+	if err := b.PreValidate(params); err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &b.ScalingDifficulty); err != nil {
-		return err
-	}
+	return b.ValidatePOW()
+}
 
-	if err := binary.Read(r, binary.BigEndian, &b.Nonce); err != nil {
-		return err
+// ValidatePOW verifies the cuckoo cycle of the header, the recently verified
+// headers aren't verified again: the header announced & then received within
+// the block is verified once
+func (b *BlockHeader) ValidatePOW() error {
+	if verifiedPOW.verified(b) {
+		return nil
 	}
 
-	if err := b.POW.Read(r); err != nil {
+	if err := b.POW.Validate(b, b.POW.EdgeBits); err != nil {
 		return err
 	}
 
+	verifiedPOW.add(b)
 	return nil
 }
 
-// Validate returns nil if header successfully passed consensus rules
-func (b *BlockHeader) Validate() error {
-	logrus.Info("block header validate")
-
+// PreValidate checks the header & its proof of work without the cuckoo cycle
+// verification: the version, the time, the edge bits & the nonces. It's cheap
+// enough to reject the flood of the invalid headers before the verification
+func (b *BlockHeader) PreValidate(params *Params) error {
 	// Check block header version
-	if !ValidateBlockVersion(b.Height, b.Version) {
-		return fmt.Errorf("invalid block version %d on height %d, maybe update Gringo?", b.Version, b.Height)
+	if !params.ValidateBlockVersion(b.Height, b.Version) {
+		return fmt.Errorf("%w %d on height %d, maybe update Gringo?", ErrInvalidBlockVersion, b.Version, b.Height)
 	}
 
 	// refuse blocks more than 12 blocks intervals in future (as in bitcoin)
-	if b.Timestamp.Sub(time.Now().UTC()) > time.Second*12*BlockTimeSec {
-		return fmt.Errorf("invalid block time (%s)", b.Timestamp)
+	if b.Timestamp.Sub(time.Now().UTC()) > 12*params.BlockTime {
+		return fmt.Errorf("%w (%s)", ErrInvalidBlockTime, b.Timestamp)
 	}
 
 	// TODO: Check difficulty.
@@ -1013,33 +1109,31 @@ func (b *BlockHeader) Validate() error {
 
 	// Either the size shift must be a valid primary POW (greater than the
 	// minimum size shift) or equal to the secondary POW size shift.
-	if b.POW.EdgeBits < DefaultMinEdgeBits && isPrimaryPow {
-		return fmt.Errorf("cuckoo size too small: %d", b.POW.EdgeBits)
+	if b.POW.EdgeBits < params.MinEdgeBits && isPrimaryPow {
+		return fmt.Errorf("%w: cuckoo size too small: %d", ErrInvalidPow, b.POW.EdgeBits)
 	}
 
 	// The primary POW must have a scaling factor of 1.
 	if isPrimaryPow && b.ScalingDifficulty != 1 {
-		return fmt.Errorf("invalid scaling difficulty: %d", b.ScalingDifficulty)
+		return fmt.Errorf("%w: invalid scaling difficulty: %d", ErrInvalidPow, b.ScalingDifficulty)
 	}
 
-	if err := b.POW.Validate(b, b.POW.EdgeBits); err != nil {
-		return err
+	// the nonces of the cycle are the ascending edges of the graph
+	if len(b.POW.Nonces) != ProofSize {
+		return fmt.Errorf("%w: %d nonces", ErrInvalidPow, len(b.POW.Nonces))
 	}
 
-	return nil
-}
+	for i, nonce := range b.POW.Nonces {
+		if b.POW.EdgeBits < 32 && nonce >= 1<<b.POW.EdgeBits {
+			return fmt.Errorf("%w: nonce %d out of the graph", ErrInvalidPow, i)
+		}
 
-// ValidateBlockVersion helper for validation block header version
-func ValidateBlockVersion(height uint64, version uint16) bool {
-	if height < HardForkV2Height {
-		return version == 1
-	} else if height < HardForkInterval {
-		return version == 2
-	} else if height < 2*HardForkInterval {
-		return version == 3
-	} else {
-		return false
+		if i > 0 && nonce <= b.POW.Nonces[i-1] {
+			return fmt.Errorf("%w: nonce %d isn't ascending", ErrInvalidPow, i)
+		}
 	}
+
+	return nil
 }
 
 // String implements String() interface
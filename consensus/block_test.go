@@ -2,7 +2,13 @@ package consensus
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
+	"github.com/dblokhin/gringo/wire"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
 	"testing"
 )
 
@@ -18,7 +24,7 @@ func TestBlockValidate(t *testing.T) {
 		t.Errorf("failed to deserialize block: %v", err)
 	}
 
-	if err := block.Validate(); err != nil {
+	if err := block.Validate(context.Background(), &TestnetParams); err != nil {
 		t.Errorf("TestBlockValidate failed: %v", err)
 	}
 }
@@ -38,3 +44,407 @@ func TestBlockSerialize(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockValidateCancelled(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Errorf("failed to deserialize block: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := block.Validate(ctx, &TestnetParams); err != context.Canceled {
+		t.Errorf("error was %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestVerifyWeight(t *testing.T) {
+	if err := verifyWeight(1, 2, 1); err != nil {
+		t.Errorf("verifyWeight failed: %v", err)
+	}
+
+	if err := verifyWeight(0, int(MaxBlockWeight/BlockOutputWeight)+1, 1); !errors.Is(err, ErrTooHeavy) {
+		t.Errorf("error was %v, want %v", err, ErrTooHeavy)
+	}
+}
+
+func TestBlockHeaderWriteTo(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Fatalf("failed to deserialize block: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := block.Header.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	data := block.Header.Bytes()
+	if !bytes.Equal(buf.Bytes(), data) || !bytes.Equal(data, serialisedBlock[:len(data)]) {
+		t.Errorf("WriteTo & Bytes differ from the serialized header")
+	}
+
+	var header BlockHeader
+	if err := header.Read(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(header.Bytes(), data) || header.Hash() != block.Header.Hash() {
+		t.Errorf("header changed by the round trip")
+	}
+
+	blockData := block.Bytes()
+	buf.Reset()
+	if _, err := block.WriteTo(&buf); err != nil || !bytes.Equal(buf.Bytes(), blockData) {
+		t.Errorf("block WriteTo differs from Bytes: %v", err)
+	}
+}
+
+func BenchmarkBlockHeaderBytes(b *testing.B) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		block.Header.Bytes()
+	}
+}
+
+func BenchmarkBlockHeaderWriteTo(b *testing.B) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		b.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		block.Header.WriteTo(&buf)
+	}
+}
+
+func BenchmarkBlockHeaderRead(b *testing.B) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		b.Fatal(err)
+	}
+
+	data := block.Header.Bytes()
+	r := bytes.NewReader(data)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+
+		var header BlockHeader
+		if err := header.Read(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBlockHeaderHash(b *testing.B) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		block.Header.Hash()
+	}
+}
+
+func TestBlockReadValidate(t *testing.T) {
+	block := &Block{}
+	if err := block.ReadValidate(context.Background(), bytes.NewReader(serialisedBlock), &TestnetParams); err != nil {
+		t.Fatalf("ReadValidate failed: %v", err)
+	}
+
+	if !bytes.Equal(block.Bytes(), serialisedBlock) {
+		t.Errorf("ReadValidate block differs from the serialized block")
+	}
+
+	header := block.Header.Bytes()
+
+	// the counts of the too heavy block are rejected before the body is read
+	heavy := append([]byte(nil), header...)
+	heavy = append(heavy, make([]byte, 24)...)
+	binary.BigEndian.PutUint64(heavy[len(header)+8:], uint64(MaxBlockWeight/BlockOutputWeight)+1)
+	binary.BigEndian.PutUint64(heavy[len(header)+16:], 1)
+
+	if err := new(Block).ReadValidate(context.Background(), bytes.NewReader(heavy), &TestnetParams); !errors.Is(err, ErrTooHeavy) {
+		t.Errorf("error was %v, want %v", err, ErrTooHeavy)
+	}
+
+	// the unsorted kernels are rejected
+	var unsorted bytes.Buffer
+	unsorted.Write(header)
+	wire.WriteUint64(&unsorted, uint64(len(block.Inputs)))
+	wire.WriteUint64(&unsorted, uint64(len(block.Outputs)))
+	wire.WriteUint64(&unsorted, uint64(len(block.Kernels)))
+	for i := range block.Inputs {
+		unsorted.Write(block.Inputs[i].Bytes())
+	}
+	for i := range block.Outputs {
+		unsorted.Write(block.Outputs[i].Bytes())
+	}
+	for i := len(block.Kernels) - 1; i >= 0; i-- {
+		unsorted.Write(block.Kernels[i].Bytes())
+	}
+
+	if err := new(Block).ReadValidate(context.Background(), &unsorted, &TestnetParams); !errors.Is(err, ErrNotSorted) {
+		t.Errorf("error was %v, want %v", err, ErrNotSorted)
+	}
+}
+
+func FuzzBlockRead(f *testing.F) {
+	f.Add(serialisedBlock)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		block := &Block{}
+		if err := block.Read(bytes.NewReader(data)); err == nil {
+			block.Bytes()
+		}
+
+		block.ReadValidate(context.Background(), bytes.NewReader(data), &TestnetParams)
+	})
+}
+
+func FuzzBlockHeaderRead(f *testing.F) {
+	f.Add(serialisedBlock)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var header BlockHeader
+		if err := header.Read(bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		encoded := header.Bytes()
+
+		var decoded BlockHeader
+		if err := decoded.Read(bytes.NewReader(encoded)); err != nil {
+			t.Fatalf("failed to read the encoded header %x: %v", encoded, err)
+		}
+
+		if again := decoded.Bytes(); !bytes.Equal(again, encoded) {
+			t.Errorf("header was encoded to %x, want %x", again, encoded)
+		}
+	})
+}
+
+func FuzzProofRead(f *testing.F) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add(block.Header.POW.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var proof Proof
+		if err := proof.Read(bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		encoded := proof.Bytes()
+
+		var decoded Proof
+		if err := decoded.Read(bytes.NewReader(encoded)); err != nil {
+			t.Fatalf("failed to read the encoded proof %x: %v", encoded, err)
+		}
+
+		if again := decoded.Bytes(); !bytes.Equal(again, encoded) {
+			t.Errorf("proof was encoded to %x, want %x", again, encoded)
+		}
+	})
+}
+
+func FuzzLocatorRead(f *testing.F) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		f.Fatal(err)
+	}
+
+	locator := Locator{Hashes: []Hash{block.Hash(), block.Header.Previous}}
+	f.Add(locator.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := bytes.NewReader(data)
+
+		var locator Locator
+		if err := locator.Read(r); err != nil {
+			return
+		}
+
+		if encoded := locator.Bytes(); !bytes.Equal(encoded, data[:len(data)-r.Len()]) {
+			t.Errorf("locator was encoded to %x, want %x", encoded, data[:len(data)-r.Len()])
+		}
+	})
+}
+
+// quietLog discards the validation logs for the benchmark
+func quietLog(b *testing.B) {
+	out := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	b.Cleanup(func() { logrus.SetOutput(out) })
+}
+
+// testBlock returns the captured block
+func testBlock(b *testing.B) *Block {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		b.Fatal(err)
+	}
+
+	return block
+}
+
+func BenchmarkBlockHeaderValidate(b *testing.B) {
+	quietLog(b)
+	block := testBlock(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// the cuckoo cycle is verified every time
+		verifiedPOW.purge()
+
+		if err := block.Header.Validate(&TestnetParams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBlockValidate(b *testing.B) {
+	quietLog(b)
+	block := testBlock(b)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := block.Validate(context.Background(), &TestnetParams); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkOutputValidate measures the range proof verification of the
+// output, the cost of the block validation grows by it per output
+func BenchmarkOutputValidate(b *testing.B) {
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+	tx := &Transaction{}
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v, err := newBodyValidator(context.Background(), 0, 1, 1)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if err := v.output(&tx.Outputs[0]); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := v.wait(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBlockValidateDuplicate(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Fatalf("failed to deserialize block: %v", err)
+	}
+
+	// the duplicates are sorted next to the originals
+	inputs := append(InputList{block.Inputs[0]}, block.Inputs...)
+	outputs := append(OutputList{block.Outputs[0]}, block.Outputs...)
+	kernels := append(TxKernelList{block.Kernels[0]}, block.Kernels...)
+
+	for _, duplicate := range []*Block{
+		{Header: block.Header, Inputs: inputs, Outputs: block.Outputs, Kernels: block.Kernels},
+		{Header: block.Header, Inputs: block.Inputs, Outputs: outputs, Kernels: block.Kernels},
+		{Header: block.Header, Inputs: block.Inputs, Outputs: block.Outputs, Kernels: kernels},
+	} {
+		if err := duplicate.Validate(context.Background(), &TestnetParams); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("error was %v, want %v", err, ErrDuplicate)
+		}
+
+		var buf bytes.Buffer
+		buf.Write(duplicate.Bytes())
+		if err := new(Block).ReadValidate(context.Background(), &buf, &TestnetParams); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("ReadValidate error was %v, want %v", err, ErrDuplicate)
+		}
+	}
+}
+
+func TestBlockCompact(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Fatalf("failed to deserialize block: %v", err)
+	}
+
+	compact := block.Compact()
+	if len(compact.Outputs) != 1 || len(compact.Kernels) != 1 || len(compact.KernelIDs) != len(block.Kernels)-1 {
+		t.Fatalf("compact block of %d outputs, %d kernels & %d kernel ids", len(compact.Outputs), len(compact.Kernels), len(compact.KernelIDs))
+	}
+
+	if compact.Outputs[0].Features != CoinbaseOutput || compact.Kernels[0].Features != CoinbaseKernel {
+		t.Errorf("full output & kernel are not the coinbase ones")
+	}
+
+	for i := range block.Kernels {
+		if block.Kernels[i].Features == CoinbaseKernel {
+			continue
+		}
+
+		id := Hash(block.Kernels[i].Hash()).ShortID(block.Hash())
+		if !bytes.Equal(compact.KernelIDs[0], id) {
+			t.Errorf("kernel id was %s, want %s", compact.KernelIDs[0], id)
+		}
+	}
+
+	var read CompactBlock
+	if err := read.Read(bytes.NewReader(compact.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Hash() != block.Hash() || !bytes.Equal(read.Bytes(), compact.Bytes()) {
+		t.Errorf("read compact block differs")
+	}
+}
+
+func TestBlockHeaderPreValidate(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Fatalf("failed to deserialize block: %v", err)
+	}
+
+	if err := block.Header.PreValidate(&TestnetParams); err != nil {
+		t.Errorf("PreValidate failed: %v", err)
+	}
+
+	tests := []func(header *BlockHeader){
+		func(header *BlockHeader) { header.POW.EdgeBits = SecondPowEdgeBits - 1 },
+		func(header *BlockHeader) { header.POW.Nonces = header.POW.Nonces[1:] },
+		func(header *BlockHeader) { header.POW.Nonces[1] = header.POW.Nonces[0] },
+		func(header *BlockHeader) { header.POW.Nonces[ProofSize-1] = 1 << header.POW.EdgeBits },
+	}
+
+	for i, test := range tests {
+		header := block.Header
+		header.POW.Nonces = append([]uint32(nil), block.Header.POW.Nonces...)
+		test(&header)
+
+		if err := header.PreValidate(&TestnetParams); !errors.Is(err, ErrInvalidPow) {
+			t.Errorf("%d: error was %v, want %v", i, err, ErrInvalidPow)
+		}
+	}
+}
@@ -4,12 +4,11 @@
 
 package consensus
 
-import "time"
-
 // Consensus rule that everything is sorted in lexicographical order on the wire.
 
 // MAXTarget The target is the 32-bytes hash block hashes must be lower than.
-var MAXTarget = []byte{0xf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+var MAXTarget = Hash{0xf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
 
 const (
 	// BlockHashSize size of block hash
@@ -43,19 +42,9 @@ const (
 	// But we do techincally support blocks with multiple coinbase outputs/kernels.
 	MaxBlockCoinbaseKernels int = 1
 
-	// BlockTimeSec Block interval, in seconds, the network will tune its next_target for. Note
-	// that we may reduce this value in the future as we get more data on mining
-	// with Cuckoo Cycle, networks improve and block propagation is optimized
-	// (adjusting the reward accordingly).
-	BlockTimeSec time.Duration = 60
-
 	// ProofSize Cuckoo-cycle proof size (cycle length)
 	ProofSize int = 42
 
-	// DefaultMinEdgeBits is the default Cuckatoo Cycle size used for mining and
-	// validating.
-	DefaultMinEdgeBits uint8 = 30
-
 	/// Secondary proof-of-work size, meant to be ASIC resistant.
 	SecondPowEdgeBits uint8 = 29
 
@@ -71,8 +60,7 @@ const (
 	// behind the value is the longest bitcoin fork was about 30 blocks, so 5h. We
 	// add an order of magnitude to be safe and round to 48h of blocks to make it
 	// easier to reason about.
-	// TODO: check that on bug with size BlockTimeSec
-	CutThroughHorizon uint32 = 48 * 3600 / uint32(BlockTimeSec)
+	CutThroughHorizon uint32 = 48 * 60
 
 	// Weight of an input when counted against the max block weigth capacity
 	BlockInputWeight uint32 = 1
@@ -86,28 +74,9 @@ const (
 	// Total maximum block weight
 	MaxBlockWeight uint32 = 80000
 
-	// Fork every 250,000 blocks for first 2 years, simple number and just a
-	// little less than 6 months.
-	HardForkInterval uint64 = 250000
-
-	// The block height at which version 2 blocks are valid
-	HardForkV2Height uint64 = 95000
-
 	// Time window in blocks to calculate block time median
 	MedianTimeWindow int = 11
 
 	// Index at half the desired median
 	MedianTimeIndex = MedianTimeWindow / 2
-
-	// Number of blocks used to calculate difficulty adjustments
-	DifficultyAdjustWindow int = 60
-
-	// Average time span of the difficulty adjustment window
-	BlockTimeWindow time.Duration = time.Duration(DifficultyAdjustWindow) * BlockTimeSec
-
-	// Maximum size time window used for difficulty adjustments
-	UpperTimeBound time.Duration = BlockTimeWindow * 2
-
-	// Minimum size time window used for difficulty adjustments
-	LowerTimeBound time.Duration = BlockTimeWindow * 2
 )
@@ -8,32 +8,196 @@ import (
 	"bytes"
 	"encoding/hex"
 	"testing"
+	"time"
 )
 
 func TestHash_ShortID(t *testing.T) {
-	var hash Hash
 	var expected ShortID
-	otherHash := make(Hash, BlockHashSize)
+	var otherHash Hash
 
-	hash, _ = hex.DecodeString("81e47a19e6b29b0a65b9591762ce5143ed30d0261e5d24a3201752506b20f15c")
+	hash, _ := ParseHash("81e47a19e6b29b0a65b9591762ce5143ed30d0261e5d24a3201752506b20f15c")
 	expected, _ = hex.DecodeString("e973960ba690")
 
 	if bytes.Compare(hash.ShortID(otherHash), expected) != 0 {
 		t.Errorf("ShortID was incorrect, want: %s", expected.String())
 	}
 
-	hash, _ = hex.DecodeString("3a42e66e46dd7633b57d1f921780a1ac715e6b93c19ee52ab714178eb3a9f673")
+	hash, _ = ParseHash("3a42e66e46dd7633b57d1f921780a1ac715e6b93c19ee52ab714178eb3a9f673")
 	expected, _ = hex.DecodeString("f0c06e838e59")
 
 	if bytes.Compare(hash.ShortID(otherHash), expected) != 0 {
 		t.Errorf("ShortID was incorrect, want: %s", expected.String())
 	}
 
-	hash, _ = hex.DecodeString("3a42e66e46dd7633b57d1f921780a1ac715e6b93c19ee52ab714178eb3a9f673")
+	hash, _ = ParseHash("3a42e66e46dd7633b57d1f921780a1ac715e6b93c19ee52ab714178eb3a9f673")
 	expected, _ = hex.DecodeString("95bf0ca12d5b")
-	otherHash, _ = hex.DecodeString("81e47a19e6b29b0a65b9591762ce5143ed30d0261e5d24a3201752506b20f15c")
+	otherHash, _ = ParseHash("81e47a19e6b29b0a65b9591762ce5143ed30d0261e5d24a3201752506b20f15c")
 
 	if bytes.Compare(hash.ShortID(otherHash), expected) != 0 {
 		t.Errorf("ShortID was incorrect, got: %s", hash.ShortID(otherHash).String())
 	}
 }
+
+func TestParseHash(t *testing.T) {
+	const s = "0644cedb1acfdde4ee9e135ae61de3cbeb301b5f27a40a2c366da8e724292f20"
+
+	hash, err := ParseHash(s)
+	if err != nil {
+		t.Fatalf("ParseHash failed: %v", err)
+	}
+	if hash.String() != s || hash.IsZero() {
+		t.Errorf("hash was %s, want %s", hash, s)
+	}
+
+	if other, _ := NewHash(hash.Bytes()); other != hash || other.Compare(hash) != 0 {
+		t.Errorf("hash of the bytes was %s, want %s", other, hash)
+	}
+	if hash.Compare(MaxHash) >= 0 || hash.Compare(ZeroHash) <= 0 {
+		t.Error("hash isn't between the zero & the max hashes")
+	}
+
+	for _, invalid := range []string{"", "00", s[:62], s + "00", "zz" + s[2:]} {
+		if _, err := ParseHash(invalid); err == nil {
+			t.Errorf("invalid hash %q was parsed", invalid)
+		}
+	}
+}
+
+func TestValidateBlockVersion(t *testing.T) {
+	for _, test := range []struct {
+		params  *Params
+		height  uint64
+		version uint16
+		valid   bool
+	}{
+		{&TestnetParams, 94999, 1, true},
+		{&TestnetParams, 95000, 1, false},
+		{&TestnetParams, 95000, 2, true},
+		{&TestnetParams, 250000, 3, true},
+		{&TestnetParams, 500000, 3, false},
+		{&MainnetParams, 95000, 1, true},
+		{&MainnetParams, 262080, 2, true},
+		{&MainnetParams, 524160, 3, true},
+	} {
+		if valid := test.params.ValidateBlockVersion(test.height, test.version); valid != test.valid {
+			t.Errorf("version %d at %d was valid %v, want %v", test.version, test.height, valid, test.valid)
+		}
+	}
+}
+
+func TestDifficultyFromHash(t *testing.T) {
+	// the quotients of the grin max target 0x0fff..ff
+	for _, test := range []struct {
+		hash string
+		diff Difficulty
+	}{
+		{"0fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 1},
+		{"0000000000000001000000000000000000000000000000000000000000000000", 1152921504606846975},
+		{"000000ffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 1048576},
+		{"1f00000000000000000000000000000000000000000000000000000000000000", 0},
+		{"0000000000000000000000000000000000000000000000000000000000000001", MaxDifficulty},
+		{"0000000000000000000000000000000000000000000000000000000000000000", MaxDifficulty},
+	} {
+		hash, _ := ParseHash(test.hash)
+		if diff := MinimumDifficulty.FromHash(hash); diff != test.diff {
+			t.Errorf("difficulty of %s was %d, want %d", test.hash, diff, test.diff)
+		}
+	}
+
+	if target := Difficulty(2).Target(); target.String() != "07ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff" {
+		t.Errorf("target of 2 was %s", target)
+	}
+	if ZeroDifficulty.Target() != MAXTarget || MinimumDifficulty.Target() != MAXTarget {
+		t.Error("target of the minimum difficulty isn't the max target")
+	}
+}
+
+func TestDifficultyArithmetic(t *testing.T) {
+	if d := Difficulty(10).Add(100); d != 110 {
+		t.Errorf("10 + 100 was %d", d)
+	}
+	if d := (MaxDifficulty - 1).Add(2); d != MaxDifficulty {
+		t.Errorf("overflowing sum was %d, want %d", d, MaxDifficulty)
+	}
+
+	if d := (MaxDifficulty / 2).MulDiv(4, 2); d != MaxDifficulty-1 {
+		t.Errorf("product was truncated: %d", d)
+	}
+	if d := MaxDifficulty.MulDiv(3, 2); d != MaxDifficulty {
+		t.Errorf("overflowing product was %d, want %d", d, MaxDifficulty)
+	}
+	if d := Difficulty(10).MulDiv(1, 0); d != MaxDifficulty {
+		t.Errorf("division by zero was %d, want %d", d, MaxDifficulty)
+	}
+}
+
+// headerInfos iterates the infos from the first
+type headerInfos []HeaderInfo
+
+func (h *headerInfos) Next() (HeaderInfo, bool) {
+	if len(*h) == 0 {
+		return HeaderInfo{}, false
+	}
+
+	info := (*h)[0]
+	*h = (*h)[1:]
+	return info, true
+}
+
+func TestNextDifficulty(t *testing.T) {
+	params := TestnetParams
+	size := params.DifficultyAdjustWindow + MedianTimeWindow
+
+	// the blocks of the difficulty 1000 from the latest, one extra block
+	// past the window
+	history := func(n int) *headerInfos {
+		infos := make(headerInfos, n)
+		now := time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC)
+		for i := range infos {
+			infos[i] = HeaderInfo{Timestamp: now.Add(-time.Duration(i) * params.BlockTime), Difficulty: 1000}
+		}
+		return &infos
+	}
+
+	if d := params.NextDifficulty(history(0)); d != ZeroDifficulty {
+		t.Errorf("difficulty of no blocks was %d", d)
+	}
+	if d := params.NextDifficulty(history(size - 1)); d != MinimumDifficulty {
+		t.Errorf("difficulty of the short window was %d, want %d", d, MinimumDifficulty)
+	}
+
+	iter := history(size + 1)
+	if d := params.NextDifficulty(iter); d <= MinimumDifficulty || d > 1000 {
+		t.Errorf("difficulty of the window was %d", d)
+	}
+	if len(*iter) != 1 {
+		t.Errorf("iterator was read past the window: %d left", len(*iter))
+	}
+
+	// the blocks twice as fast as the target raise the dampened difficulty
+	// by 8/7, the usernet one is doubled at once
+	fast := func(params Params) *headerInfos {
+		infos := make(headerInfos, params.DifficultyAdjustWindow+MedianTimeWindow)
+		now := time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC)
+		for i := range infos {
+			infos[i] = HeaderInfo{Timestamp: now.Add(-time.Duration(i) * params.BlockTime / 2), Difficulty: 1000}
+		}
+		return &infos
+	}
+
+	if d := params.NextDifficulty(fast(params)); d != 1142 {
+		t.Errorf("dampened difficulty of the fast blocks was %d, want 1142", d)
+	}
+	if d := UsernetParams.NextDifficulty(fast(UsernetParams)); d != 2000 {
+		t.Errorf("usernet difficulty of the fast blocks was %d, want 2000", d)
+	}
+
+	// the time span is bounded to the double of the target one
+	slow := history(size)
+	for i := range *slow {
+		(*slow)[i].Timestamp = (*slow)[i].Timestamp.Add(-time.Duration(i) * 9 * params.BlockTime)
+	}
+	if d := (&Params{BlockTime: params.BlockTime, DifficultyAdjustWindow: params.DifficultyAdjustWindow}).NextDifficulty(slow); d != 500 {
+		t.Errorf("difficulty of the slow blocks was %d, want 500", d)
+	}
+}
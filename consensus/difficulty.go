@@ -5,7 +5,8 @@
 package consensus
 
 import (
-	"encoding/binary"
+	"math"
+	"math/big"
 	"sort"
 	"time"
 )
@@ -15,6 +16,9 @@ const (
 
 	// The minimum mining difficulty we'll allow
 	MinimumDifficulty Difficulty = 1
+
+	// MaxDifficulty is the difficulty the overflowing arithmetic saturates to
+	MaxDifficulty Difficulty = math.MaxUint64
 )
 
 // Difficulty is defined as the maximum target divided by the block hash.
@@ -24,21 +28,81 @@ func (d Difficulty) FromNum(num uint64) Difficulty {
 	return Difficulty(num)
 }
 
-// FromHash computes the difficulty from a hash. Divides the maximum target by the
-// provided hash.
+// FromHash computes the difficulty from a hash. Divides the 256-bit maximum
+// target by the 256-bit hash, the zero hash & the quotient overflowing 64 bits
+// are MaxDifficulty
 func (d Difficulty) FromHash(hash Hash) Difficulty {
-	maxTarget := binary.BigEndian.Uint64(MAXTarget)
+	num := new(big.Int).SetBytes(hash[:])
+	if num.Sign() == 0 {
+		return MaxDifficulty
+	}
+
+	return saturate(num.Quo(new(big.Int).SetBytes(MAXTarget[:]), num))
+}
+
+// Target returns the 256-bit target of the difficulty: the maximum target
+// divided by the difficulty, the zero difficulty is of the maximum target
+func (d Difficulty) Target() Hash {
+	if d == ZeroDifficulty {
+		return MAXTarget
+	}
+
+	num := new(big.Int).SetBytes(MAXTarget[:])
+	num.Quo(num, new(big.Int).SetUint64(uint64(d)))
+
+	var target Hash
+	num.FillBytes(target[:])
+	return target
+}
+
+// Add returns d + o saturating to MaxDifficulty
+func (d Difficulty) Add(o Difficulty) Difficulty {
+	if d > MaxDifficulty-o {
+		return MaxDifficulty
+	}
+
+	return d + o
+}
+
+// MulDiv returns d * m / n saturating to MaxDifficulty, the product is not
+// truncated. The zero n is MaxDifficulty
+func (d Difficulty) MulDiv(m, n uint64) Difficulty {
+	if n == 0 {
+		return MaxDifficulty
+	}
+
+	num := new(big.Int).SetUint64(uint64(d))
+	num.Mul(num, new(big.Int).SetUint64(m))
+
+	return saturate(num.Quo(num, new(big.Int).SetUint64(n)))
+}
 
-	// Use the first 64 bits of the given hash
-	num := binary.BigEndian.Uint64(hash[:8])
+// saturate returns the difficulty of num, MaxDifficulty if num overflows
+func saturate(num *big.Int) Difficulty {
+	if !num.IsUint64() {
+		return MaxDifficulty
+	}
 
-	return Difficulty(maxTarget / num)
+	return Difficulty(num.Uint64())
 }
 
 func (d Difficulty) IntoNum() uint64 {
 	return uint64(d)
 }
 
+// HeaderInfo is the timestamp & the difficulty of the past block, the
+// difficulty adjustment input
+type HeaderInfo struct {
+	Timestamp  time.Time
+	Difficulty Difficulty
+}
+
+// DifficultyIter iterates the past blocks from the latest to the oldest
+type DifficultyIter interface {
+	// Next returns the next older block, false if there are no more blocks
+	Next() (HeaderInfo, bool)
+}
+
 // NextDifficulty computes the proof-of-work difficulty that the next block should comply
 // with. Takes an iterator over past blocks, from latest (highest height) to
 // oldest (lowest height). The iterator produces pairs of timestamp and
@@ -49,15 +113,9 @@ func (d Difficulty) IntoNum() uint64 {
 // The refence difficulty is an average of the difficulty over a window of
 // DIFFICULTY_ADJUST_WINDOW blocks. The corresponding timespan is calculated by using the
 // difference between the median timestamps at the beginning and the end
-// of the window.
-
-func NextDifficulty(blist BlockList) Difficulty {
-
-	blen := len(blist)
-	if blen == 0 {
-		return ZeroDifficulty
-	}
-
+// of the window, dampened by DampFactor & bounded to the half & the double of
+// the target time span. The iterator is not read past the window
+func (p *Params) NextDifficulty(iter DifficultyIter) Difficulty {
 	// Sum of difficulties in the window, used to calculate the average later.
 	sumDiff := ZeroDifficulty
 
@@ -66,22 +124,28 @@ func NextDifficulty(blist BlockList) Difficulty {
 	windowBegin := make([]time.Time, 0)
 	windowEnd := make([]time.Time, 0)
 
-	for i := blen - 1; i >= 0; i-- {
-		if i < DifficultyAdjustWindow {
-			sumDiff += blist[i].Header.Difficulty
+	i := 0
+	for ; i < p.DifficultyAdjustWindow+MedianTimeWindow; i++ {
+		info, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		if i < p.DifficultyAdjustWindow {
+			sumDiff = sumDiff.Add(info.Difficulty)
 
 			if i < MedianTimeWindow {
-				windowBegin = append(windowBegin, blist[i].Header.Timestamp)
+				windowBegin = append(windowBegin, info.Timestamp)
 			}
 		} else {
-			if i < DifficultyAdjustWindow+MedianTimeWindow {
-				windowEnd = append(windowEnd, blist[i].Header.Timestamp)
-			} else {
-				break
-			}
+			windowEnd = append(windowEnd, info.Timestamp)
 		}
 	}
 
+	if i == 0 {
+		return ZeroDifficulty
+	}
+
 	// Check we have enough blocks
 	if len(windowEnd) < MedianTimeWindow {
 		return MinimumDifficulty
@@ -99,19 +163,25 @@ func NextDifficulty(blist BlockList) Difficulty {
 	endTime := windowEnd[len(windowEnd)/2]
 
 	// Average difficulty and dampened average time
-	diffAvg := sumDiff / MinimumDifficulty.FromNum(uint64(DifficultyAdjustWindow))
-	ts := (3*BlockTimeWindow + beginTime.Sub(endTime)) / 4
+	window := p.BlockTimeWindow()
+	diffAvg := sumDiff.MulDiv(1, uint64(p.DifficultyAdjustWindow))
+
+	damp := time.Duration(1)
+	if p.DampFactor > 1 {
+		damp = time.Duration(p.DampFactor)
+	}
+	ts := ((damp-1)*window + beginTime.Sub(endTime)) / damp
 
 	// Apply time bounds
-	if ts < LowerTimeBound {
-		ts = LowerTimeBound
+	if lower := window / 2; ts < lower {
+		ts = lower
 	}
-	if ts > UpperTimeBound {
-		ts = UpperTimeBound
+	if upper := 2 * window; ts > upper {
+		ts = upper
 	}
 
 	//Result
-	diff := diffAvg * MinimumDifficulty.FromNum(uint64(BlockTimeWindow)) / MinimumDifficulty.FromNum(uint64(ts))
+	diff := diffAvg.MulDiv(uint64(window/time.Second), uint64(ts/time.Second))
 	if diff > MinimumDifficulty {
 		return diff
 	}
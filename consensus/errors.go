@@ -0,0 +1,57 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"errors"
+	"github.com/dblokhin/gringo/wire"
+)
+
+// The consensus failures of the blocks, headers & transactions. The returned
+// errors wrap them with the details, match them by errors.Is
+var (
+	// ErrInvalidBlockVersion is the header version not valid at its height
+	ErrInvalidBlockVersion = errors.New("invalid block version")
+
+	// ErrInvalidBlockTime is the header timestamp too far in the future or
+	// not after the previous block
+	ErrInvalidBlockTime = errors.New("invalid block time")
+
+	// ErrInvalidPow is the proof of work not verified or of the invalid size
+	ErrInvalidPow = errors.New("invalid proof of work")
+
+	// ErrTooHeavy is the block or transaction exceeding MaxBlockWeight
+	ErrTooHeavy = errors.New("block weight is too heavy")
+
+	// ErrNotSorted is the inputs, outputs or kernels not sorted
+	ErrNotSorted = errors.New("inputs, outputs or kernels are not sorted")
+
+	// ErrInvalidCoinbase is the block of the missing or too many coinbase
+	// outputs & kernels, or the transaction having them
+	ErrInvalidCoinbase = errors.New("invalid coinbase")
+
+	// ErrNoKernels is the transaction without kernels
+	ErrNoKernels = errors.New("transaction has no kernels")
+
+	// ErrDuplicate is the block or transaction of the duplicate input or
+	// output commitments or kernels
+	ErrDuplicate = errors.New("duplicate inputs, outputs or kernels")
+
+	// ErrCutThrough is the transaction spending its own output
+	ErrCutThrough = errors.New("transaction spends its own output")
+
+	// ErrInvalidSignature is the kernel excess signature not verified
+	ErrInvalidSignature = errors.New("signature isn't valid")
+
+	// ErrInvalidKernelSum is the transaction creating or destroying value
+	ErrInvalidKernelSum = errors.New("kernel sums do not match")
+
+	// ErrInvalidRangeProof is the output range proof not verified
+	ErrInvalidRangeProof = errors.New("range proof verification failed")
+)
+
+// ErrTooLargeRead is the message, string or list of the peer exceeding its
+// maximum, it's returned before the buffer of the size is allocated
+var ErrTooLargeRead = wire.ErrTooLargeRead
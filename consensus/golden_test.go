@@ -0,0 +1,78 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// wireMessage is the message read & written by the golden tests
+type wireMessage interface {
+	Read(r io.Reader) error
+	Bytes() []byte
+}
+
+// golden is the serialized data captured from the grin node
+type golden struct {
+	name string
+	data []byte
+	// msg returns the empty message the data is read to
+	msg func() wireMessage
+}
+
+// goldens returns the block, header, proof & transaction captured from the
+// grin testnet4 nodes
+func goldens(t *testing.T) []golden {
+	// the header is the prefix of the block
+	r := bytes.NewReader(serialisedBlock)
+	if err := new(BlockHeader).Read(r); err != nil {
+		t.Fatal(err)
+	}
+	header := serialisedBlock[:len(serialisedBlock)-r.Len()]
+
+	transaction, err := hex.DecodeString(testTransaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []golden{
+		{"block", serialisedBlock, func() wireMessage { return new(Block) }},
+		{"header", header, func() wireMessage { return new(BlockHeader) }},
+		{"proof", header[headerLenWithoutPOW:], func() wireMessage { return new(Proof) }},
+		{"transaction", transaction, func() wireMessage { return new(Transaction) }},
+	}
+}
+
+// TestGolden ensures the captured data is read entirely & written to the
+// identical bytes
+func TestGolden(t *testing.T) {
+	for _, test := range goldens(t) {
+		msg := test.msg()
+
+		r := bytes.NewReader(test.data)
+		if err := msg.Read(r); err != nil {
+			t.Errorf("%s: failed to read: %v", test.name, err)
+			continue
+		}
+
+		if r.Len() != 0 {
+			t.Errorf("%s: read %d of %d bytes", test.name, len(test.data)-r.Len(), len(test.data))
+		}
+
+		if data := msg.Bytes(); !bytes.Equal(data, test.data) {
+			t.Errorf("%s: written\n%x\nwant\n%x", test.name, data, test.data)
+		}
+
+		if w, ok := msg.(io.WriterTo); ok {
+			var buf bytes.Buffer
+			if _, err := w.WriteTo(&buf); err != nil || !bytes.Equal(buf.Bytes(), test.data) {
+				t.Errorf("%s: WriteTo written\n%x\nwant\n%x (%v)", test.name, buf.Bytes(), test.data, err)
+			}
+		}
+	}
+}
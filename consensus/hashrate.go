@@ -0,0 +1,95 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"math"
+	"time"
+)
+
+// GraphWeight returns the relative cost of solving the cuckoo graph of the
+// edge bits, as grin: the double graph size times the edge bits per the base
+// graph
+func GraphWeight(edgeBits uint8) float64 {
+	return math.Ldexp(float64(edgeBits), int(edgeBits)-int(BaseEdgeBits)+1)
+}
+
+// PowRate is the estimated graph rate of the miners of the proof of work
+type PowRate struct {
+	// Blocks is the count of the blocks of the proof of work
+	Blocks int
+	// EdgeBits is the graph size of the latest block of the proof of work
+	EdgeBits uint8
+	// Difficulty is the average difficulty of the blocks
+	Difficulty Difficulty
+	// GraphRate is the estimated count of the graphs per second solved by
+	// the network
+	GraphRate float64
+}
+
+// NetworkRate is the estimated graph rate of the network by the recent blocks
+type NetworkRate struct {
+	// Blocks is the count of the estimated blocks, Span is their time
+	Blocks int
+	Span   time.Duration
+	// BlockTime is the average block interval
+	BlockTime time.Duration
+
+	Primary   PowRate
+	Secondary PowRate
+}
+
+// EstimateRate returns the network graph rate of the headers from the oldest
+// to the latest, the first header is the base of the time span. The
+// difficulty of the block is the total difficulty gained by it, the graphs
+// of the primary proof of work are of its graph weight & the ones of the
+// secondary are scaled by the header scaling difficulty
+func EstimateRate(headers []BlockHeader) NetworkRate {
+	var rate NetworkRate
+	if len(headers) < 2 {
+		return rate
+	}
+
+	rate.Blocks = len(headers) - 1
+	rate.Span = headers[len(headers)-1].Timestamp.Sub(headers[0].Timestamp)
+	rate.BlockTime = rate.Span / time.Duration(rate.Blocks)
+
+	var graphs [2]float64
+	var difficulty [2]Difficulty
+	for i := 1; i < len(headers); i++ {
+		header := &headers[i]
+		work := header.TotalDifficulty - headers[i-1].TotalDifficulty
+		if header.TotalDifficulty < headers[i-1].TotalDifficulty {
+			work = ZeroDifficulty
+		}
+
+		pow, weight := &rate.Primary, GraphWeight(header.POW.EdgeBits)
+		k := 0
+		if header.POW.EdgeBits == SecondPowEdgeBits {
+			pow, weight, k = &rate.Secondary, float64(header.ScalingDifficulty), 1
+		}
+		if weight < 1 {
+			weight = 1
+		}
+
+		pow.Blocks++
+		pow.EdgeBits = header.POW.EdgeBits
+		difficulty[k] = difficulty[k].Add(work)
+		graphs[k] += float64(ProofSize) * float64(work) / weight
+	}
+
+	for k, pow := range []*PowRate{&rate.Primary, &rate.Secondary} {
+		if pow.Blocks == 0 {
+			continue
+		}
+
+		pow.Difficulty = difficulty[k].MulDiv(1, uint64(pow.Blocks))
+		if rate.Span > 0 {
+			pow.GraphRate = graphs[k] / rate.Span.Seconds()
+		}
+	}
+
+	return rate
+}
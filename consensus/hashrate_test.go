@@ -0,0 +1,59 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGraphWeight(t *testing.T) {
+	for edgeBits, weight := range map[uint8]float64{24: 48, 29: 1856, 31: 7936, 23: 23} {
+		if w := GraphWeight(edgeBits); w != weight {
+			t.Errorf("graph weight of %d was %v, want %v", edgeBits, w, weight)
+		}
+	}
+}
+
+func TestEstimateRate(t *testing.T) {
+	if rate := EstimateRate(nil); rate.Blocks != 0 || rate.Primary.GraphRate != 0 {
+		t.Errorf("rate of no headers was %+v", rate)
+	}
+
+	// the primary & the secondary blocks alternate every minute, each gains
+	// the difficulty of 100
+	start := time.Date(2018, 10, 17, 0, 0, 0, 0, time.UTC)
+	headers := make([]BlockHeader, 11)
+	for i := range headers {
+		headers[i].Timestamp = start.Add(time.Duration(i) * time.Minute)
+		headers[i].TotalDifficulty = Difficulty(1000 + 100*i)
+		headers[i].POW.EdgeBits = 31
+		headers[i].ScalingDifficulty = 1
+		if i%2 == 0 {
+			headers[i].POW.EdgeBits = SecondPowEdgeBits
+			headers[i].ScalingDifficulty = 2
+		}
+	}
+
+	rate := EstimateRate(headers)
+	if rate.Blocks != 10 || rate.Span != 10*time.Minute || rate.BlockTime != time.Minute {
+		t.Errorf("unexpected blocks %d, span %v & block time %v", rate.Blocks, rate.Span, rate.BlockTime)
+	}
+
+	if rate.Primary.Blocks != 5 || rate.Primary.EdgeBits != 31 || rate.Primary.Difficulty != 100 {
+		t.Errorf("unexpected primary rate: %+v", rate.Primary)
+	}
+	if want := 5 * 42 * 100 / 7936.0 / 600; math.Abs(rate.Primary.GraphRate-want) > 1e-9 {
+		t.Errorf("primary graph rate was %v, want %v", rate.Primary.GraphRate, want)
+	}
+
+	if rate.Secondary.Blocks != 5 || rate.Secondary.EdgeBits != SecondPowEdgeBits || rate.Secondary.Difficulty != 100 {
+		t.Errorf("unexpected secondary rate: %+v", rate.Secondary)
+	}
+	if want := 5 * 42 * 100 / 2.0 / 600; math.Abs(rate.Secondary.GraphRate-want) > 1e-9 {
+		t.Errorf("secondary graph rate was %v, want %v", rate.Secondary.GraphRate, want)
+	}
+}
@@ -17,12 +17,57 @@ const (
 	ShortIDSize = 6
 )
 
-// Hash is hashes (block hash, commitments and so on)
-type Hash []byte
+// Hash is the blake2b-256 hash of the blocks, headers, kernels & the MMR
+// nodes, comparable & usable as the map key. The zero Hash is the missing one
+type Hash [BlockHashSize]byte
+
+// ZeroHash is the zero hash, MaxHash is of all the bits set: the previous
+// hash of the genesis
+var (
+	ZeroHash Hash
+	MaxHash  = Hash{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+// NewHash returns the hash of the bytes, the length must be BlockHashSize
+func NewHash(b []byte) (Hash, error) {
+	var h Hash
+	if len(b) != BlockHashSize {
+		return h, fmt.Errorf("invalid hash length: %d", len(b))
+	}
+
+	copy(h[:], b)
+	return h, nil
+}
+
+// ParseHash returns the hash of the hex string
+func ParseHash(s string) (Hash, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return ZeroHash, fmt.Errorf("invalid hash: %v", err)
+	}
+
+	return NewHash(b)
+}
 
 // String prints the hexadecimal encoding of the hash.
 func (h Hash) String() string {
-	return fmt.Sprintf("%032x", []byte(h))
+	return hex.EncodeToString(h[:])
+}
+
+// Bytes returns the copy of the hash bytes
+func (h Hash) Bytes() []byte {
+	return append([]byte(nil), h[:]...)
+}
+
+// IsZero returns true if the hash is the zero hash
+func (h Hash) IsZero() bool {
+	return h == ZeroHash
+}
+
+// Compare returns -1, 0 or 1 comparing the hash bytes with o
+func (h Hash) Compare(o Hash) int {
+	return bytes.Compare(h[:], o[:])
 }
 
 // ShortID returns shortID from Hash
@@ -32,7 +77,7 @@ func (h Hash) ShortID(blockHash Hash) ShortID {
 	k0 := binary.LittleEndian.Uint64(blockHash[:8])
 	k1 := binary.LittleEndian.Uint64(blockHash[8:16])
 
-	hash := siphash.Hash(k0, k1, h)
+	hash := siphash.Hash(k0, k1, h[:])
 	binary.LittleEndian.PutUint64(result, hash)
 
 	// returned size is ShortIDSize
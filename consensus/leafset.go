@@ -0,0 +1,374 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/bits"
+	"sort"
+)
+
+// the roaring bitmap portable format of the grin leaf set, pmmr_leaf.bin of
+// the txhashset archive: the cookie of the run containers keeps the count of
+// the containers in the high 16 bits
+const (
+	roaringCookie      = 12347
+	roaringCookieNoRun = 12346
+
+	// roaringArrayMax is the max cardinality of the array container, the
+	// bigger ones are the bitmaps
+	roaringArrayMax = 4096
+	// roaringBitmapWords is the size of the bitmap container in uint64
+	roaringBitmapWords = 1 << 16 / 64
+	// roaringNoOffsets is the container count the offsets of the run
+	// containers format start from
+	roaringNoOffsets = 4
+)
+
+// errInvalidLeafSet is the malformed serialized leaf set
+var errInvalidLeafSet = errors.New("invalid leaf set")
+
+// LeafSet is the bitmap of the unspent leaves of the output MMR as in grin:
+// the 1-based MMR positions of the unspent outputs. The positions are split
+// into the containers of 2^16 positions by the high 16 bits
+type LeafSet struct {
+	containers map[uint16]*[roaringBitmapWords]uint64
+}
+
+// NewLeafSet returns the empty leaf set
+func NewLeafSet() *LeafSet {
+	return &LeafSet{containers: make(map[uint16]*[roaringBitmapWords]uint64)}
+}
+
+// Add marks the leaf at pos unspent
+func (s *LeafSet) Add(pos uint32) {
+	c := s.containers[uint16(pos>>16)]
+	if c == nil {
+		c = new([roaringBitmapWords]uint64)
+		s.containers[uint16(pos>>16)] = c
+	}
+
+	low := uint16(pos)
+	c[low/64] |= 1 << (low % 64)
+}
+
+// Remove marks the leaf at pos spent
+func (s *LeafSet) Remove(pos uint32) {
+	c := s.containers[uint16(pos>>16)]
+	if c == nil {
+		return
+	}
+
+	low := uint16(pos)
+	c[low/64] &^= 1 << (low % 64)
+
+	if cardinality(c) == 0 {
+		delete(s.containers, uint16(pos>>16))
+	}
+}
+
+// Includes returns true if the leaf at pos is unspent
+func (s *LeafSet) Includes(pos uint32) bool {
+	c := s.containers[uint16(pos>>16)]
+	if c == nil {
+		return false
+	}
+
+	low := uint16(pos)
+	return c[low/64]&(1<<(low%64)) != 0
+}
+
+// Len returns the count of the unspent leaves
+func (s *LeafSet) Len() int {
+	n := 0
+	for _, c := range s.containers {
+		n += cardinality(c)
+	}
+
+	return n
+}
+
+// Positions returns the positions of the unspent leaves, the lowest first
+func (s *LeafSet) Positions() []uint32 {
+	positions := make([]uint32, 0, s.Len())
+	for _, key := range s.keys() {
+		c := s.containers[key]
+		for i, word := range c {
+			for ; word != 0; word &= word - 1 {
+				low := uint32(i*64 + bits.TrailingZeros64(word))
+				positions = append(positions, uint32(key)<<16|low)
+			}
+		}
+	}
+
+	return positions
+}
+
+// Validate returns nil if the unspent positions are the leaves of the MMR of
+// size
+func (s *LeafSet) Validate(size uint64) error {
+	for _, pos := range s.Positions() {
+		if pos == 0 || uint64(pos) > size {
+			return fmt.Errorf("leaf %d beyond the mmr of size %d", pos, size)
+		}
+
+		if _, height := peakMapHeight(uint64(pos) - 1); height != 0 {
+			return fmt.Errorf("position %d is not a leaf", pos)
+		}
+	}
+
+	return nil
+}
+
+// Bytes returns the portable roaring bitmap of the leaf set, the containers
+// are the arrays & the bitmaps
+func (s *LeafSet) Bytes() []byte {
+	keys := s.keys()
+	buf := new(bytes.Buffer)
+
+	writeUint32LE(buf, roaringCookieNoRun)
+	writeUint32LE(buf, uint32(len(keys)))
+
+	for _, key := range keys {
+		writeUint16LE(buf, key)
+		writeUint16LE(buf, uint16(cardinality(s.containers[key])-1))
+	}
+
+	// the offsets of the containers follow the header
+	offset := uint32(8 + 8*len(keys))
+	for _, key := range keys {
+		writeUint32LE(buf, offset)
+		offset += uint32(containerSize(cardinality(s.containers[key])))
+	}
+
+	for _, key := range keys {
+		c := s.containers[key]
+		if cardinality(c) > roaringArrayMax {
+			for _, word := range c {
+				writeUint64LE(buf, word)
+			}
+			continue
+		}
+
+		for i, word := range c {
+			for ; word != 0; word &= word - 1 {
+				writeUint16LE(buf, uint16(i*64+bits.TrailingZeros64(word)))
+			}
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// Read reads the portable roaring bitmap of the leaf set, the run containers
+// of grin are read as well
+func (s *LeafSet) Read(r io.Reader) error {
+	s.containers = make(map[uint16]*[roaringBitmapWords]uint64)
+
+	cookie, err := readUint32LE(r)
+	if err != nil {
+		return err
+	}
+
+	var count uint32
+	var runs []byte
+
+	switch {
+	case cookie == roaringCookieNoRun:
+		if count, err = readUint32LE(r); err != nil {
+			return err
+		}
+
+	case cookie&0xffff == roaringCookie:
+		count = cookie>>16 + 1
+		runs = make([]byte, (count+7)/8)
+		if _, err := io.ReadFull(r, runs); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%w: cookie %d", errInvalidLeafSet, cookie)
+	}
+
+	if count > 1<<16 {
+		return fmt.Errorf("%w: %d containers", errInvalidLeafSet, count)
+	}
+
+	keys := make([]uint16, count)
+	cards := make([]int, count)
+	for i := range keys {
+		if keys[i], err = readUint16LE(r); err != nil {
+			return err
+		}
+
+		card, err := readUint16LE(r)
+		if err != nil {
+			return err
+		}
+		cards[i] = int(card) + 1
+
+		if i > 0 && keys[i] <= keys[i-1] {
+			return fmt.Errorf("%w: unsorted container %d", errInvalidLeafSet, keys[i])
+		}
+	}
+
+	// the offsets are skipped, the containers are read sequentially
+	if runs == nil || count >= roaringNoOffsets {
+		if _, err := io.CopyN(ioutil.Discard, r, 4*int64(count)); err != nil {
+			return err
+		}
+	}
+
+	for i, key := range keys {
+		c := new([roaringBitmapWords]uint64)
+
+		switch {
+		case runs != nil && runs[i/8]&(1<<(uint(i)%8)) != 0:
+			err = readRunContainer(r, c)
+		case cards[i] > roaringArrayMax:
+			for j := range c {
+				if c[j], err = readUint64LE(r); err != nil {
+					break
+				}
+			}
+		default:
+			err = readArrayContainer(r, c, cards[i])
+		}
+		if err != nil {
+			return err
+		}
+
+		if cardinality(c) != cards[i] {
+			return fmt.Errorf("%w: container %d cardinality", errInvalidLeafSet, key)
+		}
+
+		s.containers[key] = c
+	}
+
+	return nil
+}
+
+// keys returns the sorted keys of the containers
+func (s *LeafSet) keys() []uint16 {
+	keys := make([]uint16, 0, len(s.containers))
+	for key := range s.containers {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// readArrayContainer reads the sorted values of the array container
+func readArrayContainer(r io.Reader, c *[roaringBitmapWords]uint64, card int) error {
+	for i := 0; i < card; i++ {
+		low, err := readUint16LE(r)
+		if err != nil {
+			return err
+		}
+		c[low/64] |= 1 << (low % 64)
+	}
+
+	return nil
+}
+
+// readRunContainer reads the start & length-1 runs of the run container
+func readRunContainer(r io.Reader, c *[roaringBitmapWords]uint64) error {
+	n, err := readUint16LE(r)
+	if err != nil {
+		return err
+	}
+
+	for i := uint16(0); i < n; i++ {
+		start, err := readUint16LE(r)
+		if err != nil {
+			return err
+		}
+
+		length, err := readUint16LE(r)
+		if err != nil {
+			return err
+		}
+
+		if int(start)+int(length) >= 1<<16 {
+			return fmt.Errorf("%w: run %d+%d", errInvalidLeafSet, start, length)
+		}
+
+		for low := int(start); low <= int(start)+int(length); low++ {
+			c[low/64] |= 1 << (uint(low) % 64)
+		}
+	}
+
+	return nil
+}
+
+// cardinality returns the count of the set bits of the container
+func cardinality(c *[roaringBitmapWords]uint64) int {
+	n := 0
+	for _, word := range c {
+		n += bits.OnesCount64(word)
+	}
+
+	return n
+}
+
+// containerSize returns the serialized size of the container of card values
+func containerSize(card int) int {
+	if card > roaringArrayMax {
+		return 8 * roaringBitmapWords
+	}
+
+	return 2 * card
+}
+
+func writeUint16LE(buf *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint32LE(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64LE(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+func readUint16LE(r io.Reader) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint16(b[:]), nil
+}
+
+func readUint32LE(r io.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func readUint64LE(r io.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestLeafSetRoundTrip(t *testing.T) {
+	set := NewLeafSet()
+
+	// the array container & the bitmap container of the next 2^16 positions
+	want := []uint32{1, 2, 4, 1000}
+	for pos := uint32(1 << 16); pos < 1<<16+5000; pos++ {
+		want = append(want, pos)
+	}
+	for _, pos := range want {
+		set.Add(pos)
+	}
+
+	set.Add(5)
+	set.Remove(5)
+	set.Remove(7)
+
+	if set.Len() != len(want) || !set.Includes(1000) || set.Includes(5) {
+		t.Errorf("leaf set of %d leaves, want %d", set.Len(), len(want))
+	}
+
+	var read LeafSet
+	if err := read.Read(bytes.NewReader(set.Bytes())); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(read.Positions(), want) {
+		t.Errorf("read positions weren't the written ones")
+	}
+
+	// the empty leaf set
+	if err := read.Read(bytes.NewReader(NewLeafSet().Bytes())); err != nil || read.Len() != 0 {
+		t.Errorf("read empty leaf set of %d leaves: %v", read.Len(), err)
+	}
+}
+
+func TestLeafSetReadRuns(t *testing.T) {
+	// the run optimized bitmap of grin: the single run container of 3..7
+	data := []byte{
+		0x3b, 0x30, 0x00, 0x00, // cookie of the single container
+		0x01,                   // the run containers
+		0x00, 0x00, 0x04, 0x00, // key 0, 5 values
+		0x01, 0x00, 0x03, 0x00, 0x04, 0x00, // run 3 of 5
+	}
+
+	var set LeafSet
+	if err := set.Read(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if got := set.Positions(); !reflect.DeepEqual(got, []uint32{3, 4, 5, 6, 7}) {
+		t.Errorf("positions were %v, want 3..7", got)
+	}
+
+	// the cardinality doesn't match the run
+	data[7] = 0x05
+	if err := set.Read(bytes.NewReader(data)); err == nil {
+		t.Error("leaf set of the invalid cardinality was read")
+	}
+
+	if err := set.Read(bytes.NewReader([]byte{1, 2, 3, 4})); err == nil {
+		t.Error("leaf set of the invalid cookie was read")
+	}
+}
+
+func TestLeafSetValidate(t *testing.T) {
+	var mmr MMR
+	for i := 0; i < 4; i++ {
+		mmr.Append([]byte{byte(i)})
+	}
+
+	// the leaves of the MMR of 7 nodes
+	set := NewLeafSet()
+	for _, pos := range []uint32{1, 2, 4, 5} {
+		set.Add(pos)
+	}
+
+	if err := set.Validate(mmr.Size()); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	set.Add(3)
+	if err := set.Validate(mmr.Size()); err == nil {
+		t.Error("parent position was validated")
+	}
+
+	set.Remove(3)
+	set.Add(8)
+	if err := set.Validate(mmr.Size()); err == nil {
+		t.Error("position beyond the mmr was validated")
+	}
+}
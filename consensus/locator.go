@@ -6,9 +6,8 @@ package consensus
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/wire"
 	"io"
 )
 
@@ -22,17 +21,12 @@ func (h *Locator) Bytes() []byte {
 
 	// check the bounds & set the limits
 	if len(h.Hashes) > MaxLocators {
-		logrus.Fatal(errors.New("invalid hashes len in locator"))
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint8(len(h.Hashes))); err != nil {
-		logrus.Fatal(err)
+		panic(errors.New("invalid hashes len in locator"))
 	}
 
+	wire.WriteUint8(buff, uint8(len(h.Hashes)))
 	for _, hash := range h.Hashes {
-		if _, err := buff.Write(hash); err != nil {
-			logrus.Fatal(err)
-		}
+		buff.Write(hash[:])
 	}
 
 	return buff.Bytes()
@@ -40,21 +34,18 @@ func (h *Locator) Bytes() []byte {
 
 // Read implements Message interface
 func (h *Locator) Read(r io.Reader) error {
-
-	var count uint8
-	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+	count, err := wire.ReadUint8(r)
+	if err != nil {
 		return err
 	}
 
-	if int(count) > MaxLocators {
-		return errors.New("too big locator len from peer")
+	if err := wire.CheckLen("locator len", uint64(count), uint64(MaxLocators)); err != nil {
+		return err
 	}
 
 	h.Hashes = make([]Hash, count)
-	for i := 0; i < int(count); i++ {
-		h.Hashes[i] = make([]byte, BlockHashSize)
-
-		if _, err := io.ReadFull(r, h.Hashes[i]); err != nil {
+	for i := range h.Hashes {
+		if err := wire.ReadBytes(r, h.Hashes[i][:]); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,257 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/wire"
+	"golang.org/x/crypto/blake2b"
+	"io"
+	"math/bits"
+)
+
+// MaxMerklePath is the max count of hashes in the merkle proof, the path of
+// the MMR of 2^64 nodes is shorter
+const MaxMerklePath = 128
+
+// ErrInvalidMerkleProof is the merkle proof not leading to the root
+var ErrInvalidMerkleProof = errors.New("invalid merkle proof")
+
+// hashWithIndex returns the MMR hash of the node at the 0-based index
+func hashWithIndex(index uint64, data ...[]byte) Hash {
+	h, _ := blake2b.New256(nil)
+
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], index)
+	h.Write(buf[:])
+
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	var hash Hash
+	h.Sum(hash[:0])
+
+	return hash
+}
+
+// peakMapHeight returns the bitmap of the peaks of the MMR of size & the
+// height of the next node
+func peakMapHeight(size uint64) (peakMap, height uint64) {
+	if size == 0 {
+		return 0, 0
+	}
+
+	peakSize := ^uint64(0) >> uint(bits.LeadingZeros64(size))
+	for ; peakSize != 0; peakSize >>= 1 {
+		peakMap <<= 1
+		if size >= peakSize {
+			size -= peakSize
+			peakMap |= 1
+		}
+	}
+
+	return peakMap, size
+}
+
+// mmrPeaks returns the 1-based positions of the peaks of the MMR of size, nil
+// if the size isn't valid
+func mmrPeaks(size uint64) []uint64 {
+	if size == 0 {
+		return nil
+	}
+
+	var peaks []uint64
+	var prev uint64
+
+	left := size
+	for peakSize := ^uint64(0) >> uint(bits.LeadingZeros64(size)); peakSize != 0; peakSize >>= 1 {
+		if left >= peakSize {
+			peaks = append(peaks, prev+peakSize)
+			prev += peakSize
+			left -= peakSize
+		}
+	}
+
+	if left > 0 {
+		return nil
+	}
+
+	return peaks
+}
+
+// mmrFamily returns the parent & sibling positions of the node at pos
+func mmrFamily(pos uint64) (parent, sibling uint64) {
+	peakMap, height := peakMapHeight(pos - 1)
+	peak := uint64(1) << height
+
+	if peakMap&peak != 0 {
+		return pos + 1, pos + 1 - 2*peak
+	}
+
+	return pos + 2*peak, pos + 2*peak - 1
+}
+
+// mmrLeftSibling returns true if the node at pos is the left child
+func mmrLeftSibling(pos uint64) bool {
+	peakMap, height := peakMapHeight(pos - 1)
+	return peakMap&(uint64(1)<<height) == 0
+}
+
+// MMR is the Merkle Mountain Range keeping the peaks only, enough to append
+// the leaves & to get the root
+type MMR struct {
+	size  uint64
+	peaks []Hash
+}
+
+// Size returns the count of the MMR nodes
+func (m *MMR) Size() uint64 {
+	return m.size
+}
+
+// Copy returns the copy of the MMR, appended independently of m
+func (m *MMR) Copy() MMR {
+	return MMR{size: m.size, peaks: append([]Hash(nil), m.peaks...)}
+}
+
+// Append adds the leaf of the data, the data is hashed with its position
+func (m *MMR) Append(data []byte) {
+	pos := m.size + 1
+	hash := hashWithIndex(pos-1, data)
+
+	// the leaf is merged with the preceding peaks of the same height
+	peakMap, _ := peakMapHeight(pos - 1)
+	for peak := uint64(1); peakMap&peak != 0; peak <<= 1 {
+		left := m.peaks[len(m.peaks)-1]
+		m.peaks = m.peaks[:len(m.peaks)-1]
+
+		pos++
+		hash = hashWithIndex(pos-1, left[:], hash[:])
+	}
+
+	m.peaks = append(m.peaks, hash)
+	m.size = pos
+}
+
+// Root returns the root of the MMR, the peaks are bagged from the right. The
+// root of the empty MMR is the zero hash
+func (m *MMR) Root() Hash {
+	var root Hash
+	for i := len(m.peaks) - 1; i >= 0; i-- {
+		if i == len(m.peaks)-1 {
+			root = m.peaks[i]
+		} else {
+			root = hashWithIndex(m.size, m.peaks[i][:], root[:])
+		}
+	}
+
+	return root
+}
+
+// MerkleProof is the path of the MMR leaf to the root as in grin: the
+// siblings up to the peak, the bagged peaks to the right of the peak & the
+// peaks to the left of it
+type MerkleProof struct {
+	// MmrSize is the size of the MMR of the root
+	MmrSize uint64
+	Path    []Hash
+}
+
+// Bytes returns the binary proof
+func (p *MerkleProof) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	wire.WriteUint64(buf, p.MmrSize)
+	wire.WriteUint64(buf, uint64(len(p.Path)))
+
+	for _, hash := range p.Path {
+		buf.Write(hash[:])
+	}
+
+	return buf.Bytes()
+}
+
+// Read reads the binary proof
+func (p *MerkleProof) Read(r io.Reader) error {
+	var err error
+	if p.MmrSize, err = wire.ReadUint64(r); err != nil {
+		return err
+	}
+
+	n, err := wire.ReadLen(r, "merkle path len", MaxMerklePath)
+	if err != nil {
+		return err
+	}
+
+	p.Path = make([]Hash, n)
+	for i := range p.Path {
+		if err := wire.ReadBytes(r, p.Path[i][:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Verify returns nil if the leaf of the data at the 1-based pos leads to the
+// root by the proof
+func (p *MerkleProof) Verify(root Hash, data []byte, pos uint64) error {
+	if pos == 0 {
+		return fmt.Errorf("%w: zero position", ErrInvalidMerkleProof)
+	}
+
+	peaks := mmrPeaks(p.MmrSize)
+	if peaks == nil {
+		return fmt.Errorf("%w: invalid mmr size %d", ErrInvalidMerkleProof, p.MmrSize)
+	}
+
+	// hash returns the hash of the node at pos, the nodes beyond the MMR
+	// are the bagged peaks
+	hash := func(pos uint64, data ...[]byte) Hash {
+		if pos > p.MmrSize {
+			return hashWithIndex(p.MmrSize, data...)
+		}
+		return hashWithIndex(pos-1, data...)
+	}
+
+	node := hash(pos, data)
+	for _, sibling := range p.Path {
+		parent, siblingPos := mmrFamily(pos)
+
+		left, right := node, sibling
+		if peak := peakIndex(peaks, pos); peak >= 0 {
+			// the last peak is bagged with the peak to the left
+			if peak == len(peaks)-1 {
+				left, right = sibling, node
+			}
+		} else if parent > p.MmrSize || mmrLeftSibling(siblingPos) {
+			left, right = sibling, node
+		}
+
+		pos = parent
+		node = hash(pos, left[:], right[:])
+	}
+
+	if node != root {
+		return ErrInvalidMerkleProof
+	}
+
+	return nil
+}
+
+// peakIndex returns the index of pos in the sorted peaks, -1 if it isn't a
+// peak
+func peakIndex(peaks []uint64, pos uint64) int {
+	for i, peak := range peaks {
+		if peak == pos {
+			return i
+		}
+	}
+
+	return -1
+}
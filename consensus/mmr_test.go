@@ -0,0 +1,149 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// fullMMR is the MMR keeping all the nodes to build the proofs as grin does
+type fullMMR struct {
+	nodes  []Hash
+	leaves []uint64
+}
+
+func (m *fullMMR) append(data []byte) {
+	pos := uint64(len(m.nodes)) + 1
+	m.nodes = append(m.nodes, hashWithIndex(pos-1, data))
+	m.leaves = append(m.leaves, pos)
+
+	for !mmrLeftSibling(pos) {
+		parent, sibling := mmrFamily(pos)
+		m.nodes = append(m.nodes, hashWithIndex(parent-1, m.nodes[sibling-1][:], m.nodes[pos-1][:]))
+		pos = parent
+	}
+}
+
+// bag returns the bagged peaks from the right
+func (m *fullMMR) bag(peaks []uint64) Hash {
+	var root Hash
+	for i := len(peaks) - 1; i >= 0; i-- {
+		if i == len(peaks)-1 {
+			root = m.nodes[peaks[i]-1]
+		} else {
+			root = hashWithIndex(uint64(len(m.nodes)), m.nodes[peaks[i]-1][:], root[:])
+		}
+	}
+
+	return root
+}
+
+func (m *fullMMR) root() Hash {
+	return m.bag(mmrPeaks(uint64(len(m.nodes))))
+}
+
+// proof returns the siblings up to the peak, the bagged peaks to the right
+// & the peaks to the left reversed
+func (m *fullMMR) proof(pos uint64) MerkleProof {
+	size := uint64(len(m.nodes))
+	peaks := mmrPeaks(size)
+
+	proof := MerkleProof{MmrSize: size}
+	for peakIndex(peaks, pos) < 0 {
+		parent, sibling := mmrFamily(pos)
+		proof.Path = append(proof.Path, m.nodes[sibling-1])
+		pos = parent
+	}
+
+	i := peakIndex(peaks, pos)
+	if i+1 < len(peaks) {
+		rhs := m.bag(peaks[i+1:])
+		proof.Path = append(proof.Path, rhs)
+	}
+
+	for j := i - 1; j >= 0; j-- {
+		proof.Path = append(proof.Path, m.nodes[peaks[j]-1])
+	}
+
+	return proof
+}
+
+func TestMMRRoot(t *testing.T) {
+	var mmr MMR
+	var full fullMMR
+
+	if !mmr.Root().IsZero() {
+		t.Errorf("root of the empty mmr: %x", mmr.Root())
+	}
+
+	for i := 0; i < 100; i++ {
+		mmr.Append([]byte{byte(i)})
+		full.append([]byte{byte(i)})
+
+		if mmr.Size() != uint64(len(full.nodes)) {
+			t.Fatalf("%d leaves: size %d, want %d", i+1, mmr.Size(), len(full.nodes))
+		}
+
+		if mmr.Root() != full.root() {
+			t.Fatalf("%d leaves: root %x, want %x", i+1, mmr.Root(), full.root())
+		}
+	}
+}
+
+func TestMerkleProofVerify(t *testing.T) {
+	var full fullMMR
+	for i := 0; i < 50; i++ {
+		full.append([]byte{byte(i)})
+
+		root := full.root()
+		for j, pos := range full.leaves {
+			proof := full.proof(pos)
+			if err := proof.Verify(root, []byte{byte(j)}, pos); err != nil {
+				t.Fatalf("%d leaves: leaf %d at %d: %v", i+1, j, pos, err)
+			}
+
+			if err := proof.Verify(root, []byte{byte(j + 1)}, pos); !errors.Is(err, ErrInvalidMerkleProof) {
+				t.Fatalf("%d leaves: other data of leaf %d verified: %v", i+1, j, err)
+			}
+		}
+	}
+}
+
+func TestMerkleProofRead(t *testing.T) {
+	var full fullMMR
+	for i := 0; i < 11; i++ {
+		full.append([]byte{byte(i)})
+	}
+
+	proof := full.proof(full.leaves[3])
+
+	var read MerkleProof
+	if err := read.Read(bytes.NewReader(proof.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(read.Bytes(), proof.Bytes()) {
+		t.Errorf("read proof %x, want %x", read.Bytes(), proof.Bytes())
+	}
+
+	if err := read.Verify(full.root(), []byte{3}, full.leaves[3]); err != nil {
+		t.Error(err)
+	}
+
+	tooLong := MerkleProof{Path: make([]Hash, MaxMerklePath+1)}
+	data := tooLong.Bytes()
+	if err := read.Read(bytes.NewReader(data[:16])); !errors.Is(err, ErrTooLargeRead) {
+		t.Errorf("path of %d hashes: %v, want ErrTooLargeRead", MaxMerklePath+1, err)
+	}
+
+	for _, size := range []uint64{0, 2, 5} {
+		proof := MerkleProof{MmrSize: size}
+		if err := proof.Verify(ZeroHash, []byte{0}, 1); !errors.Is(err, ErrInvalidMerkleProof) {
+			t.Errorf("mmr size %d: %v, want ErrInvalidMerkleProof", size, err)
+		}
+	}
+}
@@ -4,8 +4,7 @@
 
 package consensus
 
-// MagicCode is expected in the header of every message
-var MagicCode = [2]byte{0x54, 0x34}
+import "github.com/dblokhin/gringo/wire"
 
 const (
 	// protocolVersion version of grin p2p protocol
@@ -13,10 +12,6 @@ const (
 
 	// size in bytes of a message header
 	HeaderLen uint64 = 11
-
-	// MaxMsgLen is the maximum size we're willing to accept for any message. Enforced by the
-	// peer-to-peer networking layer only for DoS protection.
-	MaxMsgLen uint64 = 20000000
 )
 
 // Types of p2p messages
@@ -44,6 +39,16 @@ const (
 	MsgTypeTransactionKernel
 )
 
+// The gringo extension messages out of the grin message types, sent to the
+// peers of the matching capability only
+const (
+	// MsgTypePoolKernels is the kernel short ids of the pool transactions
+	MsgTypePoolKernels uint8 = 128 + iota
+	// MsgTypeGetPoolTxs requests the pool transactions by the kernel short
+	// ids
+	MsgTypeGetPoolTxs
+)
+
 // Capabilities of node
 type Capabilities uint32
 
@@ -58,6 +63,13 @@ const (
 	CapPeerList     = 1 << 2
 	CapFastSyncNode = CapUtxoHist | CapPeerList
 	CapFullNode     = CapFullHist | CapUtxoHist | CapPeerList
+
+	// CapNodeID is the gringo extension out of the grin capabilities: the
+	// handshake carries the node identity key
+	CapNodeID Capabilities = 1 << 16
+	// CapTxPool is the gringo extension: the peer exchanges the kernel short
+	// ids of its transaction pool on connect
+	CapTxPool Capabilities = 1 << 17
 )
 
 // Network error codes
@@ -65,15 +77,61 @@ const (
 	NetUnsupportedVersion int = 100
 )
 
-const (
-	// Maximum number of hashes in a block header locator request
-	MaxLocators int = 14
+// Limits is the DoS protection limits of the peer-to-peer protocol, the
+// research networks & the usernets tune them by the params
+type Limits struct {
+	// MaxMsgLen is the maximum size we're willing to accept for any message
+	MaxMsgLen uint64
+	// MaxBlockHeaders is the maximum number of block headers a peer should
+	// ever send
+	MaxBlockHeaders int
+	// MaxPeerAddrs is the maximum number of peer addresses a peer should
+	// ever send
+	MaxPeerAddrs int
+	// MaxLocators is the maximum number of hashes in a block header locator
+	// request
+	MaxLocators int
+	// MaxListLen is the sanity limit of the inputs, outputs & kernels counts
+	// read before the block weight is checked
+	MaxListLen uint64
+}
 
-	// Maximum number of block headers a peer should ever send
-	MaxBlockHeaders = 512
+// DefaultLimits is the limits of the grin networks
+var DefaultLimits = Limits{
+	MaxMsgLen:       20000000,
+	MaxBlockHeaders: 512,
+	MaxPeerAddrs:    256,
+	MaxLocators:     14,
+	MaxListLen:      1000000,
+}
+
+// The limits in force, the messages are decoded without the params so the
+// limits are set for the process by SetLimits
+var (
+	MaxMsgLen       = DefaultLimits.MaxMsgLen
+	MaxBlockHeaders = DefaultLimits.MaxBlockHeaders
+	MaxPeerAddrs    = DefaultLimits.MaxPeerAddrs
+	MaxLocators     = DefaultLimits.MaxLocators
+	maxListLen      = DefaultLimits.MaxListLen
+)
+
+// SetLimits sets the limits in force of the process. Must be called before
+// the messages are decoded
+func SetLimits(limits Limits) {
+	MaxMsgLen = limits.MaxMsgLen
+	MaxBlockHeaders = limits.MaxBlockHeaders
+	MaxPeerAddrs = limits.MaxPeerAddrs
+	MaxLocators = limits.MaxLocators
+	maxListLen = limits.MaxListLen
+}
+
+const (
+	// Maximum number of the pool kernel short ids a peer should ever send
+	MaxPoolKernels = 100000
 
-	// Maximum number of peer addresses a peer should ever send
-	MaxPeerAddrs = 256
+	// Maximum length of the user agent or error message a peer should ever
+	// send
+	MaxStringLen = wire.MaxStringLen
 )
 
 // Protocol defines grin-node network communicates
@@ -0,0 +1,109 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import "time"
+
+// Params is the consensus parameters of the network
+type Params struct {
+	// MagicCode is expected in the header of every message
+	MagicCode [2]byte
+
+	// BlockTime is the block interval the network tunes the difficulty for
+	BlockTime time.Duration
+
+	// HardForkV2Height is the height the version 2 blocks are valid from
+	HardForkV2Height uint64
+
+	// HardForkInterval is the height the version 3 blocks are valid from,
+	// the blocks are not valid from the double interval
+	HardForkInterval uint64
+
+	// DifficultyAdjustWindow is the count of blocks used to calculate the
+	// difficulty adjustments
+	DifficultyAdjustWindow int
+
+	// DampFactor dampens the difficulty adjustment: the time span of the
+	// window is averaged with DampFactor-1 target spans, 1 adjusts the
+	// difficulty to the time span at once
+	DampFactor int
+
+	// MinEdgeBits is the min Cuckatoo Cycle size of the primary proof of work
+	MinEdgeBits uint8
+
+	// Limits is the DoS protection limits of the messages
+	Limits Limits
+}
+
+var (
+	// MainnetParams is the parameters of the grin mainnet
+	MainnetParams = Params{
+		MagicCode:              [2]byte{97, 61},
+		BlockTime:              60 * time.Second,
+		HardForkV2Height:       262080,
+		HardForkInterval:       524160,
+		DifficultyAdjustWindow: 60,
+		DampFactor:             4,
+		MinEdgeBits:            31,
+		Limits:                 DefaultLimits,
+	}
+
+	// FloonetParams is the parameters of the grin floonet
+	FloonetParams = Params{
+		MagicCode:              [2]byte{83, 59},
+		BlockTime:              60 * time.Second,
+		HardForkV2Height:       185040,
+		HardForkInterval:       298080,
+		DifficultyAdjustWindow: 60,
+		DampFactor:             4,
+		MinEdgeBits:            31,
+		Limits:                 DefaultLimits,
+	}
+
+	// TestnetParams is the parameters of the grin testnets
+	TestnetParams = Params{
+		MagicCode:              [2]byte{0x54, 0x34},
+		BlockTime:              60 * time.Second,
+		HardForkV2Height:       95000,
+		HardForkInterval:       250000,
+		DifficultyAdjustWindow: 60,
+		DampFactor:             4,
+		MinEdgeBits:            30,
+		Limits:                 DefaultLimits,
+	}
+
+	// UsernetParams is the parameters of the private network for the local
+	// tests: the small cuckoo cycles are mined by CPU in seconds, the
+	// difficulty starts at the minimum & follows the hash rate at once
+	UsernetParams = Params{
+		MagicCode:              [2]byte{73, 43},
+		BlockTime:              10 * time.Second,
+		HardForkV2Height:       95000,
+		HardForkInterval:       250000,
+		DifficultyAdjustWindow: 11,
+		DampFactor:             1,
+		MinEdgeBits:            15,
+		Limits:                 DefaultLimits,
+	}
+)
+
+// ValidateBlockVersion returns true if the block version is valid at height
+func (p *Params) ValidateBlockVersion(height uint64, version uint16) bool {
+	if height < p.HardForkV2Height {
+		return version == 1
+	} else if height < p.HardForkInterval {
+		return version == 2
+	} else if height < 2*p.HardForkInterval {
+		return version == 3
+	} else {
+		return false
+	}
+}
+
+// BlockTimeWindow returns the average time span of the difficulty
+// adjustment window
+func (p *Params) BlockTimeWindow() time.Duration {
+	return time.Duration(p.DifficultyAdjustWindow) * p.BlockTime
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"container/list"
+	"golang.org/x/crypto/blake2b"
+	"sync"
+)
+
+// powCacheSize is the number of recently verified headers kept in memory,
+// enough for the headers announced & then received within the blocks
+const powCacheSize = 4096
+
+// verifiedPOW is the cache of the verified cuckoo cycles of the headers
+var verifiedPOW = newPOWCache(powCacheSize)
+
+// powEntry is the verified header: the header hash is the hash of the proof
+// only, so the hash of the pre-proof header & the edge bits binds the proof
+// to the header it's verified for
+type powEntry struct {
+	hash   Hash
+	header Hash
+}
+
+// powCache is LRU cache of the verified proofs of work by header hash
+type powCache struct {
+	sync.Mutex
+
+	size  int
+	order *list.List
+	items map[Hash]*list.Element
+}
+
+// newPOWCache returns cache holding up to size verified headers
+func newPOWCache(size int) *powCache {
+	return &powCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[Hash]*list.Element),
+	}
+}
+
+// powHeaderHash returns the hash of the header without the proof of work &
+// the edge bits of the proof
+func powHeaderHash(b *BlockHeader) Hash {
+	return blake2b.Sum256(append(b.bytesWithoutPOW(), b.POW.EdgeBits))
+}
+
+// verified returns true if the proof of work of the header was verified
+func (c *powCache) verified(b *BlockHeader) bool {
+	hash := b.Hash()
+
+	c.Lock()
+	defer c.Unlock()
+
+	elem, ok := c.items[hash]
+	if !ok || elem.Value.(powEntry).header != powHeaderHash(b) {
+		return false
+	}
+
+	c.order.MoveToFront(elem)
+	return true
+}
+
+// add adds the header of the verified proof of work evicting the least
+// recently used one
+func (c *powCache) add(b *BlockHeader) {
+	entry := powEntry{hash: b.Hash(), header: powHeaderHash(b)}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if elem, ok := c.items[entry.hash]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[entry.hash] = c.order.PushFront(entry)
+
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(powEntry).hash)
+	}
+}
+
+// purge drops all the cached headers
+func (c *powCache) purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.order.Init()
+	c.items = make(map[Hash]*list.Element)
+}
+
+// len returns number of cached headers
+func (c *powCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.order.Len()
+}
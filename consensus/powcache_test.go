@@ -0,0 +1,56 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidatePOWCache(t *testing.T) {
+	block := &Block{}
+	if err := block.Read(bytes.NewReader(serialisedBlock)); err != nil {
+		t.Fatalf("failed to deserialize block: %v", err)
+	}
+
+	verifiedPOW.purge()
+	defer verifiedPOW.purge()
+
+	if err := block.Header.ValidatePOW(); err != nil {
+		t.Fatalf("ValidatePOW failed: %v", err)
+	}
+
+	if !verifiedPOW.verified(&block.Header) {
+		t.Error("verified header wasn't cached")
+	}
+
+	// the header of the same proof & hash is verified again
+	forged := block.Header
+	forged.Timestamp = forged.Timestamp.Add(-time.Second)
+	if err := forged.ValidatePOW(); !errors.Is(err, ErrInvalidPow) {
+		t.Errorf("error was %v, want %v", err, ErrInvalidPow)
+	}
+
+	if verifiedPOW.len() != 1 {
+		t.Errorf("cache of %d headers, want 1", verifiedPOW.len())
+	}
+}
+
+func TestPOWCacheEviction(t *testing.T) {
+	cache := newPOWCache(2)
+
+	headers := make([]BlockHeader, 3)
+	for i := range headers {
+		headers[i].POW.EdgeBits = SecondPowEdgeBits
+		headers[i].POW.Nonces = []uint32{uint32(i)}
+		cache.add(&headers[i])
+	}
+
+	if cache.len() != 2 || cache.verified(&headers[0]) || !cache.verified(&headers[2]) {
+		t.Errorf("cache of %d headers kept the least recently used one", cache.len())
+	}
+}
@@ -6,11 +6,9 @@ package consensus
 
 import (
 	"bytes"
-	"encoding/binary"
-	"errors"
 	"fmt"
 	"github.com/dblokhin/gringo/cuckoo"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/wire"
 	"golang.org/x/crypto/blake2b"
 	"io"
 )
@@ -24,20 +22,14 @@ type Proof struct {
 	Nonces []uint32
 }
 
-var (
-	errInvalidPow = errors.New("invalid pow verify")
-)
-
 // Validate validates the pow
 func (p *Proof) Validate(header *BlockHeader, cuckooSize uint8) error {
-	logrus.Infof("block POW validate for size %d", cuckooSize)
-
 	cuckoo := cuckoo.NewCuckaroo(header.bytesWithoutPOW())
 	if cuckoo.Verify(header.POW.Nonces, header.POW.EdgeBits) {
 		return nil
 	}
 
-	return errInvalidPow
+	return ErrInvalidPow
 }
 
 // ToDifficulty converts the proof to a proof-of-work Target so they can be compared.
@@ -47,23 +39,29 @@ func (p *Proof) ToDifficulty() Difficulty {
 }
 
 // Hash returns hash of content pow
-func (p *Proof) Hash() []byte {
-	hash := blake2b.Sum256(p.Bytes())
-	return hash[:]
+func (p *Proof) Hash() Hash {
+	buf := wire.GetBuffer()
+	defer wire.PutBuffer(buf)
+
+	p.write(buf)
+
+	return blake2b.Sum256(buf.Bytes())
 }
 
-// ProofBytes returns the serialised proof of work nonces.
-func (p *Proof) ProofBytes() []byte {
-	buff := new(bytes.Buffer)
+// maxProofBytes is the size of the packed nonces of the max cuckoo graph
+const maxProofBytes = (64*ProofSize + 7) / 8
 
+// proofLen returns the size of the packed nonces
+func (p *Proof) proofLen() int {
 	// The solution we serialise depends on the size of the cuckoo graph. The
 	// cycle is always of length 42, but each vertex takes up more bits on
 	// larger graphs, nonceLengthBits is this number of bits.
-	nonceLengthBits := uint(p.EdgeBits)
+	return (int(p.EdgeBits)*ProofSize + 7) / 8
+}
 
-	// Make a slice just large enough to fit all of the POW bits.
-	bitvecLengthBits := nonceLengthBits * uint(ProofSize)
-	bitvec := make([]uint8, (bitvecLengthBits+7)/8)
+// pack packs the nonces to the zeroed bitvec of proofLen
+func (p *Proof) pack(bitvec []byte) {
+	nonceLengthBits := uint(p.EdgeBits)
 
 	for n, nonce := range p.Nonces {
 		// Pack this nonce into the bit stream.
@@ -76,34 +74,53 @@ func (p *Proof) ProofBytes() []byte {
 			}
 		}
 	}
+}
 
-	if _, err := buff.Write(bitvec); err != nil {
-		logrus.Fatal(err)
-	}
+// ProofBytes returns the serialised proof of work nonces.
+func (p *Proof) ProofBytes() []byte {
+	// Make a slice just large enough to fit all of the POW bits.
+	bitvec := make([]uint8, p.proofLen())
+	p.pack(bitvec)
 
-	return buff.Bytes()
+	return bitvec
 }
 
-// Bytes returns binary []byte
-func (p *Proof) Bytes() []byte {
-	buff := new(bytes.Buffer)
-
-	// Write size of cuckoo graph.
-	if err := binary.Write(buff, binary.BigEndian, p.EdgeBits); err != nil {
-		logrus.Fatal(err)
+// writeProof writes the packed nonces to buf
+func (p *Proof) writeProof(buf *bytes.Buffer) {
+	n := p.proofLen()
+	if n > maxProofBytes {
+		buf.Write(p.ProofBytes())
+		return
 	}
 
-	buff.Write(p.ProofBytes())
+	var bitvec [maxProofBytes]byte
+	p.pack(bitvec[:n])
+	buf.Write(bitvec[:n])
+}
+
+// write writes the size of cuckoo graph & the packed nonces to buf
+func (p *Proof) write(buf *bytes.Buffer) {
+	buf.WriteByte(p.EdgeBits)
+	p.writeProof(buf)
+}
 
-	return buff.Bytes()
+// Bytes returns binary []byte
+func (p *Proof) Bytes() []byte {
+	return wire.Bytes(p.write)
 }
 
 // Read deserializes a Proof.
 func (p *Proof) Read(r io.Reader) error {
-	if err := binary.Read(r, binary.BigEndian, &p.EdgeBits); err != nil {
+	var err error
+	if p.EdgeBits, err = wire.ReadUint8(r); err != nil {
 		return err
 	}
 
+	return p.readNonces(r)
+}
+
+// readNonces reads the packed nonces of the cuckoo graph size
+func (p *Proof) readNonces(r io.Reader) error {
 	if p.EdgeBits == 0 || p.EdgeBits > 64 {
 		return fmt.Errorf("invalid cuckoo graph size: %d", p.EdgeBits)
 	}
@@ -112,10 +129,13 @@ func (p *Proof) Read(r io.Reader) error {
 
 	nonceLengthBits := uint(p.EdgeBits)
 
-	// Make a slice just large enough to fit all of the POW bits.
-	bitvecLengthBits := nonceLengthBits * uint(ProofSize)
-	bitvec := make([]uint8, (bitvecLengthBits+7)/8)
-	if _, err := io.ReadFull(r, bitvec); err != nil {
+	// the packed bits are read to the pooled buffer
+	buf := wire.GetBuffer()
+	defer wire.PutBuffer(buf)
+
+	buf.Grow(p.proofLen())
+	bitvec := buf.Bytes()[:p.proofLen()]
+	if err := wire.ReadBytes(r, bitvec); err != nil {
 		return err
 	}
 
@@ -0,0 +1,129 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"github.com/yoss22/bulletproofs"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// workers is the count of the concurrent range proof verifiers of the block
+// or the transaction
+var workers = int32(runtime.GOMAXPROCS(0))
+
+// SetWorkers sets the count of the concurrent range proof verifiers of the
+// block or the transaction, n < 1 is GOMAXPROCS
+func SetWorkers(n int) {
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	atomic.StoreInt32(&workers, int32(n))
+}
+
+// rangeVerifier verifies the range proofs of the outputs by the workers, the
+// first failure cancels the verification
+type rangeVerifier struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	prover  *bulletproofs.Prover
+	outputs chan *Output
+	wg      sync.WaitGroup
+	once    sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+// newRangeVerifier starts the workers of the verifier, the outputs are
+// verified until wait or close is called
+func newRangeVerifier(ctx context.Context) *rangeVerifier {
+	n := int(atomic.LoadInt32(&workers))
+
+	r := &rangeVerifier{
+		prover:  bulletproofs.NewProver(64),
+		outputs: make(chan *Output, n),
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+
+	r.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go r.work()
+	}
+
+	return r
+}
+
+// work verifies the queued outputs, the outputs queued after the failure are
+// skipped
+func (r *rangeVerifier) work() {
+	defer r.wg.Done()
+
+	for output := range r.outputs {
+		if r.ctx.Err() != nil {
+			continue
+		}
+
+		if !r.prover.Verify(output.Commit, output.RangeProof) {
+			r.fail(fmt.Errorf("%w: %v", ErrInvalidRangeProof, output.Commit))
+		}
+	}
+}
+
+// fail records the first failure & cancels the verification
+func (r *rangeVerifier) fail(err error) {
+	r.mu.Lock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+
+	r.cancel()
+}
+
+// result returns the failure, the ctx error if the verification is cancelled
+// or nil
+func (r *rangeVerifier) result() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	return r.ctx.Err()
+}
+
+// verify queues the output, the failure of the queued outputs is returned
+func (r *rangeVerifier) verify(output *Output) error {
+	select {
+	case r.outputs <- output:
+		return nil
+	case <-r.ctx.Done():
+		return r.result()
+	}
+}
+
+// close cancels the verification without waiting for the workers
+func (r *rangeVerifier) close() {
+	r.cancel()
+	r.once.Do(func() { close(r.outputs) })
+}
+
+// wait returns the result of the queued outputs verification
+func (r *rangeVerifier) wait() error {
+	r.once.Do(func() { close(r.outputs) })
+	r.wg.Wait()
+
+	err := r.result()
+	r.cancel()
+
+	return err
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// The round trip tests are of the external package, testutil imports
+// consensus
+package consensus_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/testutil"
+	"io"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// wireType is the type of the binary serialization
+type wireType interface {
+	Bytes() []byte
+	Read(r io.Reader) error
+}
+
+// checkRoundTrip checks the value read from the bytes of the value reads the
+// bytes entirely, equals the value & is written to the same bytes
+func checkRoundTrip(value, read wireType) error {
+	data := value.Bytes()
+
+	r := bytes.NewReader(data)
+	if err := read.Read(r); err != nil {
+		return fmt.Errorf("failed to read %x: %v", data, err)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("%d of %d bytes are not read", r.Len(), len(data))
+	}
+
+	if written := read.Bytes(); !bytes.Equal(written, data) {
+		return fmt.Errorf("read value is written to\n%x, want\n%x", written, data)
+	}
+
+	if !reflect.DeepEqual(read, value) {
+		return fmt.Errorf("read %+v, want %+v", read, value)
+	}
+
+	return nil
+}
+
+// testRoundTrip checks the round trip of the random values of the random
+// seeds, random returns the value & the empty value to read
+func testRoundTrip(t *testing.T, random func(r *rand.Rand) (value, read wireType)) {
+	property := func(seed int64) bool {
+		value, read := random(rand.New(rand.NewSource(seed)))
+		if err := checkRoundTrip(value, read); err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 100}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProofRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		proof := testutil.RandomProof(r)
+		return &proof, new(consensus.Proof)
+	})
+}
+
+func TestBlockHeaderRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		header := testutil.RandomHeader(r)
+		return &header, new(consensus.BlockHeader)
+	})
+}
+
+func TestInputRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		input := testutil.RandomInput(r)
+		return &input, new(consensus.Input)
+	})
+}
+
+func TestOutputRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		output := testutil.RandomOutput(r)
+		return &output, new(consensus.Output)
+	})
+}
+
+func TestTxKernelRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		kernel := testutil.RandomKernel(r)
+		return &kernel, new(consensus.TxKernel)
+	})
+}
+
+func TestTransactionRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		return testutil.RandomTransaction(r), new(consensus.Transaction)
+	})
+}
+
+func TestBlockRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		return testutil.RandomBlock(r), new(consensus.Block)
+	})
+}
+
+func TestCompactBlockRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		block := testutil.RandomBlock(r)
+		compact := &consensus.CompactBlock{
+			Header:    block.Header,
+			Outputs:   block.Outputs,
+			Kernels:   block.Kernels,
+			KernelIDs: testutil.RandomShortIDs(r),
+		}
+
+		return compact, new(consensus.CompactBlock)
+	})
+}
+
+func TestLocatorRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		locator := &consensus.Locator{Hashes: testutil.RandomHashes(r, consensus.MaxLocators)}
+		return locator, new(consensus.Locator)
+	})
+}
+
+func TestMerkleProofRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireType, wireType) {
+		proof := &consensus.MerkleProof{
+			MmrSize: r.Uint64(),
+			Path:    testutil.RandomHashes(r, consensus.MaxMerklePath),
+		}
+
+		return proof, new(consensus.MerkleProof)
+	})
+}
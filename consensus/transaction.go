@@ -6,11 +6,15 @@ package consensus
 
 import (
 	"bytes"
-	"encoding/binary"
-	"errors"
+	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/wire"
+	"github.com/yoss22/bulletproofs"
+	"golang.org/x/crypto/blake2b"
 	"io"
+	"math/big"
 	"sort"
 )
 
@@ -26,27 +30,43 @@ type Transaction struct {
 	Kernels TxKernelList
 }
 
-// Bytes implements p2p Message interface
-func (t *Transaction) Bytes() []byte {
-	buff := new(bytes.Buffer)
-
-	if _, err := buff.Write(t.KernelOffset[:]); err != nil {
-		logrus.Fatal(err)
+// TxWeight returns the weight of the transaction body the fee is paid for:
+// 4 per output & 1 per kernel less 1 per input, at least 1. The inputs are
+// discounted as they shrink the UTXO set
+func TxWeight(inputs, outputs, kernels int) uint64 {
+	weight := 4*int64(outputs) + int64(kernels) - int64(inputs)
+	if weight < 1 {
+		return 1
 	}
 
-	// Inputs & outputs lens
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(t.Inputs))); err != nil {
-		logrus.Fatal(err)
-	}
+	return uint64(weight)
+}
 
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(t.Outputs))); err != nil {
-		logrus.Fatal(err)
-	}
+// Weight returns the weight of the transaction
+func (t *Transaction) Weight() uint64 {
+	return TxWeight(len(t.Inputs), len(t.Outputs), len(t.Kernels))
+}
 
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(t.Kernels))); err != nil {
-		logrus.Fatal(err)
+// Fee returns the sum of the kernel fees
+func (t *Transaction) Fee() uint64 {
+	var fee uint64
+	for i := range t.Kernels {
+		fee += t.Kernels[i].Fee
 	}
 
+	return fee
+}
+
+// Bytes implements p2p Message interface
+func (t *Transaction) Bytes() []byte {
+	buff := new(bytes.Buffer)
+	buff.Write(t.KernelOffset[:])
+
+	// Inputs & outputs lens
+	wire.WriteUint64(buff, uint64(len(t.Inputs)))
+	wire.WriteUint64(buff, uint64(len(t.Outputs)))
+	wire.WriteUint64(buff, uint64(len(t.Kernels)))
+
 	// Consensus rule that everything is sorted in lexicographical order on the wire
 	// consensus rule: input, output, kernels MUST BE sorted!
 	sort.Sort(t.Inputs)
@@ -55,22 +75,22 @@ func (t *Transaction) Bytes() []byte {
 
 	// Write inputs
 	for _, input := range t.Inputs {
-		if _, err := buff.Write(input.Commit); err != nil {
-			logrus.Fatal(err)
+		if _, err := buff.Write(input.Bytes()); err != nil {
+			panic(err)
 		}
 	}
 
 	// Write outputs
 	for _, output := range t.Outputs {
 		if _, err := buff.Write(output.Bytes()); err != nil {
-			logrus.Fatal(err)
+			panic(err)
 		}
 	}
 
 	// Write kernels
 	for _, kernel := range t.Kernels {
 		if _, err := buff.Write(kernel.Bytes()); err != nil {
-			logrus.Fatal(err)
+			panic(err)
 		}
 	}
 
@@ -84,35 +104,16 @@ func (t *Transaction) Type() uint8 {
 
 // Read implements p2p Message interface
 func (t *Transaction) Read(r io.Reader) error {
-	if _, err := io.ReadFull(r, t.KernelOffset[:]); err != nil {
+	if err := wire.ReadBytes(r, t.KernelOffset[:]); err != nil {
 		return err
 	}
 
 	// Read the lengths of the subsequent fields.
-	var inputs, outputs, kernels uint64
-	if err := binary.Read(r, binary.BigEndian, &inputs); err != nil {
+	inputs, outputs, kernels, err := readCounts(r)
+	if err != nil {
 		return err
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &outputs); err != nil {
-		return err
-	}
-
-	if err := binary.Read(r, binary.BigEndian, &kernels); err != nil {
-		return err
-	}
-
-	// Sanity check the lengths.
-	if inputs > 1000000 {
-		return errors.New("transaction contains too many inputs")
-	}
-	if outputs > 1000000 {
-		return errors.New("transaction contains too many outputs")
-	}
-	if kernels > 1000000 {
-		return errors.New("transaction contains too many kernels")
-	}
-
 	t.Inputs = make([]Input, inputs)
 	for i := uint64(0); i < inputs; i++ {
 		if err := t.Inputs[i].Read(r); err != nil {
@@ -134,24 +135,234 @@ func (t *Transaction) Read(r io.Reader) error {
 		}
 	}
 
-	// TODO: Check block weight.
-
 	// Check sorted input, output requiring consensus rule!
 	if !sort.IsSorted(t.Inputs) {
-		return errors.New("consensus error: inputs are not sorted")
+		return fmt.Errorf("%w: inputs", ErrNotSorted)
 	}
 
 	if !sort.IsSorted(t.Outputs) {
-		return errors.New("consensus error: outputs are not sorted")
+		return fmt.Errorf("%w: outputs", ErrNotSorted)
 	}
 
 	if !sort.IsSorted(t.Kernels) {
-		return errors.New("consensus error: kernels are not sorted")
+		return fmt.Errorf("%w: kernels", ErrNotSorted)
 	}
 
 	return nil
 }
 
+// Validate returns nil if transaction successfully passed TX-SCOPE consensus
+// rules, the inputs are checked against the utxo set by the caller. The range
+// proofs verification is stopped on the ctx cancellation
+func (t *Transaction) Validate(ctx context.Context) error {
+	if len(t.Kernels) == 0 {
+		return ErrNoKernels
+	}
+
+	if err := verifyWeight(len(t.Inputs), len(t.Outputs), len(t.Kernels)); err != nil {
+		return err
+	}
+
+	if !sort.IsSorted(t.Inputs) || !sort.IsSorted(t.Outputs) || !sort.IsSorted(t.Kernels) {
+		return ErrNotSorted
+	}
+
+	if err := t.verifyUnique(); err != nil {
+		return err
+	}
+
+	if err := t.verifyCutThrough(); err != nil {
+		return err
+	}
+
+	for _, output := range t.Outputs {
+		if output.Features&CoinbaseOutput == CoinbaseOutput {
+			return fmt.Errorf("%w: transaction has coinbase output", ErrInvalidCoinbase)
+		}
+	}
+
+	for _, kernel := range t.Kernels {
+		if kernel.Features&CoinbaseKernel == CoinbaseKernel {
+			return fmt.Errorf("%w: transaction has coinbase kernel", ErrInvalidCoinbase)
+		}
+	}
+
+	for i := range t.Kernels {
+		if err := t.Kernels[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := t.verifyKernelSums(); err != nil {
+		return err
+	}
+
+	// Verify all output values are within the correct range.
+	proofs := newRangeVerifier(ctx)
+	defer proofs.close()
+
+	for i := range t.Outputs {
+		if err := proofs.verify(&t.Outputs[i]); err != nil {
+			return err
+		}
+	}
+
+	return proofs.wait()
+}
+
+// verifyUnique checks that no input or output commitment or kernel is
+// duplicated
+func (t *Transaction) verifyUnique() error {
+	inputs := make(map[string]struct{}, len(t.Inputs))
+	for _, input := range t.Inputs {
+		if err := unique(inputs, "input", input.Commit); err != nil {
+			return err
+		}
+	}
+
+	outputs := make(map[string]struct{}, len(t.Outputs))
+	for _, output := range t.Outputs {
+		if err := unique(outputs, "output", output.Commit.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	kernels := make(map[string]struct{}, len(t.Kernels))
+	for i := range t.Kernels {
+		if err := unique(kernels, "kernel", t.Kernels[i].Hash().Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyCutThrough checks that no output is spent by the same transaction
+func (t *Transaction) verifyCutThrough() error {
+	outputs := make(map[string]struct{}, len(t.Outputs))
+	for _, output := range t.Outputs {
+		outputs[string(output.Commit.Bytes())] = struct{}{}
+	}
+
+	for _, input := range t.Inputs {
+		if _, ok := outputs[string(input.Commit)]; ok {
+			return ErrCutThrough
+		}
+	}
+
+	return nil
+}
+
+// verifyKernelSums checks that no value is created or destroyed:
+// sum(outputs) + fee*H = sum(inputs) + sum(excesses) + offset*G
+func (t *Transaction) verifyKernelSums() error {
+	var excesses *bulletproofs.Point
+	for i := range t.Kernels {
+		excesses = sumPoints(excesses, &t.Kernels[i].Excess)
+	}
+
+	var lhs *bulletproofs.Point
+	if fee := t.Fee(); fee > 0 {
+		lhs = bulletproofs.ScalarMulPoint(&secp256k1zkp.H, new(big.Int).SetUint64(fee))
+	}
+	for _, output := range t.Outputs {
+		lhs = sumPoints(lhs, output.Commit)
+	}
+
+	rhs := excesses
+	for _, input := range t.Inputs {
+		commit := new(bulletproofs.Point)
+		if err := commit.Read(bytes.NewReader(input.Commit)); err != nil {
+			return fmt.Errorf("%w: invalid input commitment %s: %v", ErrInvalidKernelSum, input.Commit, err)
+		}
+		rhs = sumPoints(rhs, commit)
+	}
+
+	offset := new(big.Int).SetBytes(t.KernelOffset[:])
+	if offset.Sign() != 0 {
+		rhs = sumPoints(rhs, bulletproofs.ScalarMulPoint(&secp256k1zkp.G, offset))
+	}
+
+	if lhs == nil || lhs.X == nil || rhs.X == nil || lhs.X.Cmp(rhs.X) != 0 || lhs.Y.Cmp(rhs.Y) != 0 {
+		return ErrInvalidKernelSum
+	}
+
+	return nil
+}
+
+// sumPoints returns a + b, nil a is the start of the sum
+func sumPoints(a, b *bulletproofs.Point) *bulletproofs.Point {
+	if a == nil {
+		return b
+	}
+
+	return bulletproofs.SumPoints(a, b)
+}
+
+// curveOrder is the order of the secp256k1 group the offsets are summed by
+var curveOrder = btcec.S256().N
+
+// Aggregate returns the transaction of the inputs, outputs & kernels of txs &
+// the sum of their offsets, the outputs spent by the inputs of txs are cut
+// through. The aggregated transaction of the valid ones is valid unless they
+// spend the same input
+func Aggregate(txs ...*Transaction) *Transaction {
+	result := new(Transaction)
+
+	offset := new(big.Int)
+	for _, tx := range txs {
+		result.Inputs = append(result.Inputs, tx.Inputs...)
+		result.Outputs = append(result.Outputs, tx.Outputs...)
+		result.Kernels = append(result.Kernels, tx.Kernels...)
+		offset.Add(offset, new(big.Int).SetBytes(tx.KernelOffset[:]))
+	}
+	offset.Mod(offset, curveOrder).FillBytes(result.KernelOffset[:])
+
+	result.cutThrough()
+	sort.Sort(result.Inputs)
+	sort.Sort(result.Outputs)
+	sort.Sort(result.Kernels)
+
+	return result
+}
+
+// cutThrough removes the outputs spent by the inputs of the transaction &
+// the inputs spending them
+func (t *Transaction) cutThrough() {
+	spent := make(map[string]int, len(t.Inputs))
+	for _, input := range t.Inputs {
+		spent[string(input.Commit)]++
+	}
+
+	cut := make(map[string]int)
+	outputs := t.Outputs[:0]
+	for _, output := range t.Outputs {
+		commit := string(output.Commit.Bytes())
+		if spent[commit] > 0 {
+			spent[commit]--
+			cut[commit]++
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+
+	inputs := t.Inputs[:0]
+	for _, input := range t.Inputs {
+		if cut[string(input.Commit)] > 0 {
+			cut[string(input.Commit)]--
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+
+	t.Inputs, t.Outputs = inputs, outputs
+}
+
+// Hash returns a hash of the serialised transaction.
+func (t *Transaction) Hash() Hash {
+	return blake2b.Sum256(t.Bytes())
+}
+
 // String implements String() interface
 func (t Transaction) String() string {
 	return fmt.Sprintf("%#v", t)
@@ -2,14 +2,20 @@ package consensus
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
+	"errors"
 	. "github.com/yoss22/bulletproofs"
+	"math/big"
+	"sort"
 	"testing"
 )
 
+// testTransaction is a serialized transaction of 1 input, 2 outputs & 1 kernel
+const testTransaction = "d29efa3aa282679fba8353ac370bc9994394d1b1964fb4830c58bf4a402d4f800000000000000001000000000000000200000000000000010009de9aceb09ff7cc422a3704dddf9373a7bdcc8805b2f81a9ee05786f49238a7660008bc127c31911faf56ed4b3bcc9819dc34b47e104de991ce32519ec333c6638e0600000000000002a34199a825fc69cd4030d11924f3011bba3322fecf866bfd44b05d84ddf4c5fada39284bb90e3594386bb8b116825f90ab2c9ea7e3bbec047a3c0b2586bb58dc520f9687a3f713f6921109ea6474f8219fdbd2aa2b37620b5f2b1da736f6ee43e9d8216ebbc921a8355db6952210d625a89155d60f32ed3deb8dabbc8a8f059bbfa1f1803a367a404121824db8111202311641d61b5c03e0c694b326e4124c3dc9c48a7d9b745bc0e1f1db6cf746d7d183300e3f212d6e4bc4c69b4275644993bd1766d732862ff77ebb1667a8ff9e318338f91cbb0b494e5b934721f24818acfaf27ab2cf5ca78aefa6510bb032666e198000f7b81499fc50fa4ba40b8e866721fa69e08940968769459ae60c22bef2c1ffb6f472243a04ffa049af2cb0ab206349c03cad3c3e40d46f0a5d1999825df1d5af75caa726ec78eb312a716468e8e455071c109a01086c5531fce7d1dad145ceba46a55a32096c23ab867a4842e650f8630027a5206192d55bfae463a340cd95143139af48fa175f158ee0be66715409c1d2db2630a2e85e414cc123bb4a67dfd0b9ea05e22dea7b2fc5f2c28462cb1a74f1e63a513826d9403f38067a68cf5e3172b4023c541d97480ed2421179d7e2abee1d5e11524971adc95682845bea7303427423ec84adf7c4bf99a1f03d6fc02ca6a6328d81c4b23c1c7230a9e0d42b000b885dbd0681ada4ddef22386c97ee1a9dad87c39234ceefc3b5ec41c744f546924a5de250c7f5e1bfe8ee631dc049ec748c0f3702d8b2f2e650af38c5287a64ebe51b43c527978a16226a0eb4b632a11ec51040627e92ce2529c30e3e1b34170d490d5f50abe57fed3e6c2fcde6c39115e002edb5eae0b9a006434ca8e8985e4bf93cb20ee52342b4698865d870258e8ac9a15c55853eabef06f32b12ba28bbec31ecbac8a8bfe12e5c0ccfa21c1a5120000832a3990cd8a497ad280394afeaa5fcbdf02d6e8c86eb7fc47ba6bb25cd8973fd00000000000002a38bfaaaeebbba7ff4b614c75390729666e2ce1cfa0d5fa9d33c7e8327dd468711b1cd2e6a72108735e183ab232114969adf1bda21b78d524ac8d76ab1f68b8c5700c09e2ec7874e6948d6367f8af295b54806dcfe46021ed115f74ba0679509e1b650def8083790f8e26745fa3141d69fd350c7c726bc9453d3e1598cae27f8c131f233de12d947bb4b0c0b0d12fb147d4780eca856b380f9a8a952031e201d12bfc7815082fa7710a0e57feb0a514b8dddcf98c277cc16b6c5347805afc095ad304dbada87330648c6ef0a18f21ad6dde0460c416811f1276e8ea335491b8e297be2f27e8e827f112ef66a3f86e978c39ea770c084d4dbaa96dcfab6966c822a3ef3af42435602689ed3c3b4578097e06a8f609b442ee309a5e5348dcbad74086c9ebb72fa6588db3316dd9e41262cd807b8565d3b1e1a71e7400e90aeffb841ace29355ebd07521672f8b8c1d32f055b8d794bc8ad46150efb595e171cad20f0d0700594fa5d850eb688f4b871c43c8ef039679b2b282ab968a91e4e78d5f223a4acac2484240496912b875c87c5fca8490a78fe78fc18c8f17b87ce8300d8a7360b3e2878302c57747ceb107556640c620c64b196b5a94079b188086d456554fae8cc33e0dab35618e3a4c7645fd16112443f649f2e0b679d63dea8d5da2ed783af2d068b1d830c821af27b954daab77405736258d25c005b9b98634a816d266264d1097824f479ab9addec644e744c579a8b4c0b814ee147a241098de6fb739c88e32828130b62435d7b7836cbd213f7b364e156ee42ed69aa8d83dd5d97af3e4ab2af17e14fab5276bbc0c1243996fed445648a882e5e1a53509ecd5225bbce19f82937ff680f476619242096cff5cf2e19712f62bdeac1488824db6945583bfea1762f26f76dccbd9b1c970e242632480b1abbdb709ce1fbfaf7a092051bdb3dc0000000000007a1200000000000001011908b3e6f62be2a299e1c96627822f1228aeb977a79a7074872e91cb6d0c2f239ab9752e5a56dd5d0d16bfa7c19defe154b1185b3d40acb34d75e73d3de288c5c9dc6326369b0d216ac21ef5e2240f3578e7503aa71a4405ce8f2ee6a0696ed98de7"
+
 func TestTransactionDeserialization(t *testing.T) {
-	transactionMsg, _ := hex.DecodeString(
-		"d29efa3aa282679fba8353ac370bc9994394d1b1964fb4830c58bf4a402d4f800000000000000001000000000000000200000000000000010009de9aceb09ff7cc422a3704dddf9373a7bdcc8805b2f81a9ee05786f49238a7660008bc127c31911faf56ed4b3bcc9819dc34b47e104de991ce32519ec333c6638e0600000000000002a34199a825fc69cd4030d11924f3011bba3322fecf866bfd44b05d84ddf4c5fada39284bb90e3594386bb8b116825f90ab2c9ea7e3bbec047a3c0b2586bb58dc520f9687a3f713f6921109ea6474f8219fdbd2aa2b37620b5f2b1da736f6ee43e9d8216ebbc921a8355db6952210d625a89155d60f32ed3deb8dabbc8a8f059bbfa1f1803a367a404121824db8111202311641d61b5c03e0c694b326e4124c3dc9c48a7d9b745bc0e1f1db6cf746d7d183300e3f212d6e4bc4c69b4275644993bd1766d732862ff77ebb1667a8ff9e318338f91cbb0b494e5b934721f24818acfaf27ab2cf5ca78aefa6510bb032666e198000f7b81499fc50fa4ba40b8e866721fa69e08940968769459ae60c22bef2c1ffb6f472243a04ffa049af2cb0ab206349c03cad3c3e40d46f0a5d1999825df1d5af75caa726ec78eb312a716468e8e455071c109a01086c5531fce7d1dad145ceba46a55a32096c23ab867a4842e650f8630027a5206192d55bfae463a340cd95143139af48fa175f158ee0be66715409c1d2db2630a2e85e414cc123bb4a67dfd0b9ea05e22dea7b2fc5f2c28462cb1a74f1e63a513826d9403f38067a68cf5e3172b4023c541d97480ed2421179d7e2abee1d5e11524971adc95682845bea7303427423ec84adf7c4bf99a1f03d6fc02ca6a6328d81c4b23c1c7230a9e0d42b000b885dbd0681ada4ddef22386c97ee1a9dad87c39234ceefc3b5ec41c744f546924a5de250c7f5e1bfe8ee631dc049ec748c0f3702d8b2f2e650af38c5287a64ebe51b43c527978a16226a0eb4b632a11ec51040627e92ce2529c30e3e1b34170d490d5f50abe57fed3e6c2fcde6c39115e002edb5eae0b9a006434ca8e8985e4bf93cb20ee52342b4698865d870258e8ac9a15c55853eabef06f32b12ba28bbec31ecbac8a8bfe12e5c0ccfa21c1a5120000832a3990cd8a497ad280394afeaa5fcbdf02d6e8c86eb7fc47ba6bb25cd8973fd00000000000002a38bfaaaeebbba7ff4b614c75390729666e2ce1cfa0d5fa9d33c7e8327dd468711b1cd2e6a72108735e183ab232114969adf1bda21b78d524ac8d76ab1f68b8c5700c09e2ec7874e6948d6367f8af295b54806dcfe46021ed115f74ba0679509e1b650def8083790f8e26745fa3141d69fd350c7c726bc9453d3e1598cae27f8c131f233de12d947bb4b0c0b0d12fb147d4780eca856b380f9a8a952031e201d12bfc7815082fa7710a0e57feb0a514b8dddcf98c277cc16b6c5347805afc095ad304dbada87330648c6ef0a18f21ad6dde0460c416811f1276e8ea335491b8e297be2f27e8e827f112ef66a3f86e978c39ea770c084d4dbaa96dcfab6966c822a3ef3af42435602689ed3c3b4578097e06a8f609b442ee309a5e5348dcbad74086c9ebb72fa6588db3316dd9e41262cd807b8565d3b1e1a71e7400e90aeffb841ace29355ebd07521672f8b8c1d32f055b8d794bc8ad46150efb595e171cad20f0d0700594fa5d850eb688f4b871c43c8ef039679b2b282ab968a91e4e78d5f223a4acac2484240496912b875c87c5fca8490a78fe78fc18c8f17b87ce8300d8a7360b3e2878302c57747ceb107556640c620c64b196b5a94079b188086d456554fae8cc33e0dab35618e3a4c7645fd16112443f649f2e0b679d63dea8d5da2ed783af2d068b1d830c821af27b954daab77405736258d25c005b9b98634a816d266264d1097824f479ab9addec644e744c579a8b4c0b814ee147a241098de6fb739c88e32828130b62435d7b7836cbd213f7b364e156ee42ed69aa8d83dd5d97af3e4ab2af17e14fab5276bbc0c1243996fed445648a882e5e1a53509ecd5225bbce19f82937ff680f476619242096cff5cf2e19712f62bdeac1488824db6945583bfea1762f26f76dccbd9b1c970e242632480b1abbdb709ce1fbfaf7a092051bdb3dc0000000000007a1200000000000001011908b3e6f62be2a299e1c96627822f1228aeb977a79a7074872e91cb6d0c2f239ab9752e5a56dd5d0d16bfa7c19defe154b1185b3d40acb34d75e73d3de288c5c9dc6326369b0d216ac21ef5e2240f3578e7503aa71a4405ce8f2ee6a0696ed98de7")
+	transactionMsg, _ := hex.DecodeString(testTransaction)
 
 	r := bytes.NewReader(transactionMsg)
 
@@ -34,3 +40,183 @@ func TestTransactionOutputSerialize(t *testing.T) {
 			actual, serialized)
 	}
 }
+
+func TestTransactionValidate(t *testing.T) {
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+
+	tx := &Transaction{}
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		t.Fatalf("failed to parse transaction: %v", err)
+	}
+
+	if err := tx.Validate(context.Background()); err != nil {
+		t.Errorf("valid transaction failed validation: %v", err)
+	}
+
+	tx.Kernels[0].Fee++
+	if err := tx.verifyKernelSums(); err == nil {
+		t.Errorf("kernel sums of the changed fee are valid")
+	}
+}
+
+func TestTransactionValidateDuplicate(t *testing.T) {
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+
+	for _, duplicate := range []func(tx *Transaction){
+		func(tx *Transaction) { tx.Inputs = append(tx.Inputs, tx.Inputs[0]) },
+		func(tx *Transaction) { tx.Outputs = append(tx.Outputs, tx.Outputs[0]) },
+		func(tx *Transaction) { tx.Kernels = append(tx.Kernels, tx.Kernels[0]) },
+	} {
+		tx := &Transaction{}
+		if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+			t.Fatalf("failed to parse transaction: %v", err)
+		}
+
+		duplicate(tx)
+		sort.Sort(tx.Inputs)
+		sort.Sort(tx.Outputs)
+		sort.Sort(tx.Kernels)
+
+		if err := tx.Validate(context.Background()); !errors.Is(err, ErrDuplicate) {
+			t.Errorf("error was %v, want %v", err, ErrDuplicate)
+		}
+	}
+}
+
+func TestTxWeight(t *testing.T) {
+	for _, test := range []struct {
+		inputs, outputs, kernels int
+		weight                   uint64
+	}{
+		{0, 0, 1, 1},
+		{1, 1, 1, 4},
+		{1, 2, 1, 8},
+		{2, 2, 1, 7},
+		{10, 1, 1, 1},
+		{0, 2, 2, 10},
+	} {
+		if weight := TxWeight(test.inputs, test.outputs, test.kernels); weight != test.weight {
+			t.Errorf("%d/%d/%d: weight was %d, want %d", test.inputs, test.outputs, test.kernels, weight, test.weight)
+		}
+	}
+
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+
+	tx := &Transaction{}
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		t.Fatalf("failed to parse transaction: %v", err)
+	}
+
+	if tx.Weight() != 8 || tx.Fee() != 8000000 {
+		t.Errorf("weight & fee were %d, %d, want 8, 8000000", tx.Weight(), tx.Fee())
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+
+	tx := &Transaction{}
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		t.Fatalf("failed to parse transaction: %v", err)
+	}
+
+	// the offset is split as (offset + 1) + (order - 1), the second
+	// transaction spends & recreates the output of the first one
+	first := *tx
+	new(big.Int).Add(new(big.Int).SetBytes(tx.KernelOffset[:]), big.NewInt(1)).FillBytes(first.KernelOffset[:])
+
+	second := &Transaction{
+		Inputs:  InputList{{Commit: tx.Outputs[0].Commit.Bytes()}},
+		Outputs: OutputList{tx.Outputs[0]},
+	}
+	new(big.Int).Sub(curveOrder, big.NewInt(1)).FillBytes(second.KernelOffset[:])
+
+	aggregated := Aggregate(&first, second)
+	if aggregated.KernelOffset != tx.KernelOffset {
+		t.Errorf("offset was %x, want %x", aggregated.KernelOffset, tx.KernelOffset)
+	}
+
+	if len(aggregated.Inputs) != 1 || len(aggregated.Outputs) != 2 || len(aggregated.Kernels) != 1 {
+		t.Errorf("aggregated %d inputs, %d outputs & %d kernels, want 1, 2 & 1", len(aggregated.Inputs), len(aggregated.Outputs), len(aggregated.Kernels))
+	}
+
+	if err := aggregated.Validate(context.Background()); err != nil {
+		t.Errorf("aggregated transaction failed validation: %v", err)
+	}
+
+	if len(tx.Outputs) != 2 || len(second.Inputs) != 1 {
+		t.Errorf("aggregated transactions were changed")
+	}
+}
+
+func FuzzTransactionRead(f *testing.F) {
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+	f.Add(transactionMsg)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tx := &Transaction{}
+		if err := tx.Read(bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		tx.Bytes()
+		tx.Validate(context.Background())
+	})
+}
+
+func BenchmarkTransactionValidate(b *testing.B) {
+	quietLog(b)
+
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+	tx := &Transaction{}
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := tx.Validate(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRangeVerifier(t *testing.T) {
+	defer SetWorkers(0)
+	SetWorkers(3)
+
+	transactionMsg, _ := hex.DecodeString(testTransaction)
+
+	var tx Transaction
+	if err := tx.Read(bytes.NewReader(transactionMsg)); err != nil {
+		t.Fatal(err)
+	}
+
+	proofs := newRangeVerifier(context.Background())
+	for i := 0; i < 10; i++ {
+		if err := proofs.verify(&tx.Outputs[i%len(tx.Outputs)]); err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+	}
+	if err := proofs.wait(); err != nil {
+		t.Errorf("wait failed: %v", err)
+	}
+
+	// the failure is returned by the next verify & wait
+	proofs = newRangeVerifier(context.Background())
+	proofs.fail(ErrInvalidRangeProof)
+	if err := proofs.verify(&tx.Outputs[0]); err != nil && !errors.Is(err, ErrInvalidRangeProof) {
+		t.Errorf("verify error was %v, want %v", err, ErrInvalidRangeProof)
+	}
+	if err := proofs.wait(); !errors.Is(err, ErrInvalidRangeProof) {
+		t.Errorf("wait error was %v, want %v", err, ErrInvalidRangeProof)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	proofs = newRangeVerifier(ctx)
+	proofs.verify(&tx.Outputs[0])
+	if err := proofs.wait(); err != context.Canceled {
+		t.Errorf("wait error was %v, want %v", err, context.Canceled)
+	}
+}
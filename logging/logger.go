@@ -0,0 +1,73 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the leveled logger used by the node packages, applications
+// embedding the node may provide their own implementation
+type Logger interface {
+	Debug(args ...interface{})
+	Debugf(format string, args ...interface{})
+	Info(args ...interface{})
+	Infof(format string, args ...interface{})
+	Warn(args ...interface{})
+	Warnf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+
+	// WithFields returns the logger adding the fields to the messages
+	WithFields(fields Fields) Logger
+}
+
+// Fields is the structured fields of the message: height, hash, peer,
+// duration & so on
+type Fields map[string]interface{}
+
+// Nop is the logger discarding all messages
+var Nop Logger = nop{}
+
+// NewLogrus returns the Logger writing to the logrus logger
+func NewLogrus(l logrus.FieldLogger) Logger {
+	return &logrusLogger{l}
+}
+
+// Default returns the logrus Logger of module configured by Setup
+func Default(module string) Logger {
+	return NewLogrus(Module(module))
+}
+
+// logrusLogger is the logrus adapter
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+func (l *logrusLogger) Debug(args ...interface{})                 { l.logger.Debug(args...) }
+func (l *logrusLogger) Debugf(format string, args ...interface{}) { l.logger.Debugf(format, args...) }
+func (l *logrusLogger) Info(args ...interface{})                  { l.logger.Info(args...) }
+func (l *logrusLogger) Infof(format string, args ...interface{})  { l.logger.Infof(format, args...) }
+func (l *logrusLogger) Warn(args ...interface{})                  { l.logger.Warn(args...) }
+func (l *logrusLogger) Warnf(format string, args ...interface{})  { l.logger.Warnf(format, args...) }
+func (l *logrusLogger) Error(args ...interface{})                 { l.logger.Error(args...) }
+func (l *logrusLogger) Errorf(format string, args ...interface{}) { l.logger.Errorf(format, args...) }
+
+func (l *logrusLogger) WithFields(fields Fields) Logger {
+	return &logrusLogger{l.logger.WithFields(logrus.Fields(fields))}
+}
+
+// nop is the no-op logger
+type nop struct{}
+
+func (nop) Debug(args ...interface{})                 {}
+func (nop) Debugf(format string, args ...interface{}) {}
+func (nop) Info(args ...interface{})                  {}
+func (nop) Infof(format string, args ...interface{})  {}
+func (nop) Warn(args ...interface{})                  {}
+func (nop) Warnf(format string, args ...interface{})  {}
+func (nop) Error(args ...interface{})                 {}
+func (nop) Errorf(format string, args ...interface{}) {}
+func (n nop) WithFields(fields Fields) Logger         { return n }
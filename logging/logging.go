@@ -0,0 +1,266 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package logging sets up the node log output and the per module loggers
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Modules having own log level
+const (
+	P2P     = "p2p"
+	Chain   = "chain"
+	Mempool = "mempool"
+	Storage = "storage"
+	Mining  = "mining"
+)
+
+// modules is the list of the known modules
+var modules = []string{P2P, Chain, Mempool, Storage, Mining}
+
+// Options is the log output settings
+type Options struct {
+	// Level is the default level of the modules
+	Level string
+	// Modules is the levels by module name overriding the default level
+	Modules map[string]string
+
+	// Format is the output format: text or json
+	Format string
+
+	// File is the log file path, empty means stdout
+	File string
+	// MaxSize is the size in megabytes of the file to be rotated
+	MaxSize int
+	// MaxBackups is the count of the rotated files to keep, 0 keeps all
+	MaxBackups int
+	// MaxAge is the count of days to keep the rotated files, 0 keeps all
+	MaxAge int
+	// RotateEvery rotates the file by time, 0 means size rotation only
+	RotateEvery time.Duration
+}
+
+var (
+	mu sync.Mutex
+
+	// loggers by module
+	loggers = map[string]*logrus.Logger{}
+
+	// current log file & its rotation stopper
+	file *lumberjack.Logger
+	stop chan struct{}
+)
+
+// Module returns the logrus logger of module, loggers of the unknown modules
+// are created with the standard logger settings
+func Module(module string) *logrus.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if logger, ok := loggers[module]; ok {
+		return logger
+	}
+
+	std := logrus.StandardLogger()
+	logger := logrus.New()
+	logger.SetOutput(std.Out)
+	logger.SetFormatter(std.Formatter)
+	logger.SetLevel(std.GetLevel())
+
+	loggers[module] = logger
+	return logger
+}
+
+// parseLevels returns the default level & the levels of the modules
+func parseLevels(level string, modules map[string]string) (logrus.Level, map[string]logrus.Level, error) {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	levels := make(map[string]logrus.Level, len(modules))
+	for module, name := range modules {
+		if !isModule(module) {
+			return 0, nil, errors.New("unknown log module: " + module)
+		}
+
+		if levels[module], err = logrus.ParseLevel(name); err != nil {
+			return 0, nil, fmt.Errorf("invalid %s log level: %v", module, err)
+		}
+	}
+
+	return l, levels, nil
+}
+
+// Setup applies opts to the standard logger and the module loggers
+func Setup(opts Options) error {
+	level, levels, err := parseLevels(opts.Level, opts.Modules)
+	if err != nil {
+		return err
+	}
+
+	var formatter logrus.Formatter
+	switch opts.Format {
+	case "", "text":
+		formatter = &logrus.TextFormatter{}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return fmt.Errorf("invalid log format: %s", opts.Format)
+	}
+
+	// register the known modules, so they are listed by Levels
+	for _, module := range modules {
+		Module(module)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	closeFile()
+
+	var out io.Writer = os.Stdout
+	if opts.File != "" {
+		file = &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSize,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAge,
+		}
+		out = file
+
+		if opts.RotateEvery > 0 {
+			stop = make(chan struct{})
+			go rotate(file, opts.RotateEvery, stop)
+		}
+	}
+
+	std := logrus.StandardLogger()
+	std.SetOutput(out)
+	std.SetFormatter(formatter)
+	std.SetLevel(level)
+
+	for module, logger := range loggers {
+		logger.SetOutput(out)
+		logger.SetFormatter(formatter)
+
+		if l, ok := levels[module]; ok {
+			logger.SetLevel(l)
+		} else {
+			logger.SetLevel(level)
+		}
+	}
+
+	return nil
+}
+
+// SetLevels changes the default level & the levels of the modules at
+// runtime, the output is kept
+func SetLevels(level string, moduleLevels map[string]string) error {
+	l, levels, err := parseLevels(level, moduleLevels)
+	if err != nil {
+		return err
+	}
+
+	// register the known modules, so they are listed by Levels
+	for _, module := range modules {
+		Module(module)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	logrus.StandardLogger().SetLevel(l)
+	for module, logger := range loggers {
+		if ml, ok := levels[module]; ok {
+			logger.SetLevel(ml)
+		} else {
+			logger.SetLevel(l)
+		}
+	}
+
+	return nil
+}
+
+// SetLevel changes the level of the module logger at runtime
+func SetLevel(module, level string) error {
+	l, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	logger, ok := loggers[module]
+	if !ok {
+		return errors.New("unknown log module: " + module)
+	}
+
+	logger.SetLevel(l)
+	return nil
+}
+
+// Levels returns the levels of the module loggers
+func Levels() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make(map[string]string, len(loggers))
+	for module, logger := range loggers {
+		result[module] = logger.GetLevel().String()
+	}
+
+	return result
+}
+
+// isModule reports whether module is the known module
+func isModule(module string) bool {
+	for _, m := range modules {
+		if m == module {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closeFile stops the time rotation & closes the current log file
+func closeFile() {
+	if stop != nil {
+		close(stop)
+		stop = nil
+	}
+
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
+
+// rotate rotates the log file every interval until stop is closed
+func rotate(file *lumberjack.Logger, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := file.Rotate(); err != nil {
+				logrus.Errorf("log rotation failed: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
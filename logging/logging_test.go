@@ -0,0 +1,116 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"bytes"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gringo-logging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "node.log")
+	err = Setup(Options{
+		Level:   "info",
+		Modules: map[string]string{P2P: "debug"},
+		Format:  "json",
+		File:    file,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(Options{Level: "info"})
+
+	if level := Levels()[P2P]; level != "debug" {
+		t.Errorf("p2p level was %s, want debug", level)
+	}
+
+	if level := Levels()[Chain]; level != "info" {
+		t.Errorf("chain level was %s, want info", level)
+	}
+
+	Module(P2P).Debug("p2p debug message")
+	Module(Chain).Debug("chain debug message")
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"msg":"p2p debug message"`) {
+		t.Errorf("p2p debug message is not logged: %s", data)
+	}
+
+	if strings.Contains(string(data), "chain debug message") {
+		t.Errorf("chain debug message is logged: %s", data)
+	}
+
+	// the reloaded levels keep the output
+	if err := SetLevels("warning", map[string]string{Chain: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if levels := Levels(); levels[P2P] != "warning" || levels[Chain] != "debug" {
+		t.Errorf("reloaded levels were %v", levels)
+	}
+
+	Module(Chain).Debug("chain reloaded message")
+	if data, err := ioutil.ReadFile(file); err != nil || !strings.Contains(string(data), "chain reloaded message") {
+		t.Errorf("chain debug message is not logged after the reload: %s", data)
+	}
+
+	if err := SetLevels("info", map[string]string{"p2p2": "debug"}); err == nil {
+		t.Error("unknown module was reloaded")
+	}
+
+	if err := Setup(Options{Level: "info", Format: "xml"}); err == nil {
+		t.Error("invalid format was accepted")
+	}
+
+	err = Setup(Options{Level: "info", Modules: map[string]string{"p2p2": "debug"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown log module") {
+		t.Errorf("unknown module was accepted: %v", err)
+	}
+}
+
+func TestNewLogrus(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.InfoLevel)
+
+	log := NewLogrus(logger)
+	log.Infof("block %d", 1)
+	log.Debug("hidden")
+
+	if !strings.Contains(buf.String(), "block 1") {
+		t.Errorf("info message is not logged: %s", buf.String())
+	}
+
+	if strings.Contains(buf.String(), "hidden") {
+		t.Errorf("debug message is logged: %s", buf.String())
+	}
+
+	buf.Reset()
+	log.WithFields(Fields{"height": 2, "peer": "10.0.0.1:3414"}).Info("block accepted")
+	if !strings.Contains(buf.String(), "height=2") || !strings.Contains(buf.String(), "peer=\"10.0.0.1:3414\"") {
+		t.Errorf("fields are not logged: %s", buf.String())
+	}
+
+	// must not panic
+	Nop.Errorf("error %d", 1)
+	Nop.WithFields(Fields{"height": 1}).Info("block accepted")
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"math/big"
+)
+
+// DefaultFeeRateIncrease is the default min increase in percents of the
+// replacing transaction fee rate
+const DefaultFeeRateIncrease = 25
+
+// ConflictPolicy is the handling of the transaction spending an input of the
+// pool transactions: the transaction is rejected with ErrDoubleSpend unless
+// Replace is set & its fee rate exceeds the fee rate of every conflicting
+// transaction by FeeRateIncrease percents, then the conflicting ones are
+// replaced
+type ConflictPolicy struct {
+	Replace         bool
+	FeeRateIncrease uint64
+}
+
+// The events of the conflicting transactions
+const (
+	// EventReplaced is the transaction replaced the conflicting ones
+	EventReplaced = "replaced"
+	// EventRejected is the transaction rejected by the conflicting ones
+	EventRejected = "rejected"
+)
+
+// Event is the conflict of the transaction with the pool transactions
+type Event struct {
+	// Type is EventReplaced or EventRejected
+	Type string
+	Tx   *consensus.Transaction
+	// Conflicts are the pool transactions spending the inputs of Tx: the
+	// replaced ones or the ones Tx is rejected by
+	Conflicts []*consensus.Transaction
+}
+
+// SetConflictPolicy sets the handling of the conflicting transactions, the
+// default policy rejects them
+func (p *Pool) SetConflictPolicy(policy ConflictPolicy) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.policy = policy
+}
+
+// SubscribeEvents registers ch to receive the conflict events, the event is
+// skipped if ch is not ready to receive it
+func (p *Pool) SubscribeEvents(ch chan<- Event) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.eventSubscribers[ch] = struct{}{}
+}
+
+// UnsubscribeEvents removes ch from the event subscribers
+func (p *Pool) UnsubscribeEvents(ch chan<- Event) {
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.eventSubscribers, ch)
+}
+
+// conflicts returns the pool transactions spending the inputs of tx, must be
+// called with the pool locked
+func (p *Pool) conflicts(tx *consensus.Transaction) []*consensus.Transaction {
+	var result []*consensus.Transaction
+	seen := make(map[*consensus.Transaction]bool)
+
+	for _, input := range tx.Inputs {
+		if spender, ok := p.spent[string(input.Commit)]; ok && !seen[spender] {
+			seen[spender] = true
+			result = append(result, spender)
+		}
+	}
+
+	return result
+}
+
+// replaces returns true if the policy replaces the conflicts by tx, must be
+// called with the pool locked
+func (p *Pool) replaces(tx *consensus.Transaction, conflicts []*consensus.Transaction) bool {
+	if !p.policy.Replace {
+		return false
+	}
+
+	// fee * conflict weight * 100 > conflict fee * weight * (100 + increase)
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(tx.Fee()), big.NewInt(100))
+	threshold := new(big.Int).SetUint64(100 + p.policy.FeeRateIncrease)
+	weight := new(big.Int).SetUint64(tx.Weight())
+
+	for _, conflict := range conflicts {
+		a := new(big.Int).Mul(fee, new(big.Int).SetUint64(conflict.Weight()))
+		b := new(big.Int).Mul(new(big.Int).SetUint64(conflict.Fee()), weight)
+		if a.Cmp(b.Mul(b, threshold)) <= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// emit sends the event to the subscribers, must be called with the pool
+// locked
+func (p *Pool) emit(event Event) {
+	for ch := range p.eventSubscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// TxHandler processes the transaction like ProcessTx
+type TxHandler func(ctx context.Context, tx *consensus.Transaction) error
+
+// TxMiddleware wraps the next transaction handler: the code before calling
+// next runs before the pool processes the transaction & its error rejects
+// the transaction, the code after next gets the processing result
+type TxMiddleware func(next TxHandler) TxHandler
+
+// Use wraps ProcessTx by the middleware, the last added runs first. Must be
+// called before the transactions are processed
+func (p *Pool) Use(middleware TxMiddleware) {
+	p.process = middleware(p.process)
+}
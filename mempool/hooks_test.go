@@ -0,0 +1,49 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+)
+
+func TestTxMiddleware(t *testing.T) {
+	pool := newTestPool(nil)
+
+	errFiltered := errors.New("filtered")
+	var results []error
+
+	pool.Use(func(next TxHandler) TxHandler {
+		return func(ctx context.Context, tx *consensus.Transaction) error {
+			err := next(ctx, tx)
+			results = append(results, err)
+			return err
+		}
+	})
+	pool.Use(func(next TxHandler) TxHandler {
+		return func(ctx context.Context, tx *consensus.Transaction) error {
+			if tx.Fee() > 100 {
+				return errFiltered
+			}
+			return next(ctx, tx)
+		}
+	})
+
+	filtered := &consensus.Transaction{Kernels: consensus.TxKernelList{newKernel(7, 200)}}
+	if err := pool.ProcessTx(context.Background(), filtered); err != errFiltered || pool.Size() != 0 {
+		t.Errorf("filtered tx error was %v, pool size %d", err, pool.Size())
+	}
+
+	tx := &consensus.Transaction{Kernels: consensus.TxKernelList{newKernel(7, 8)}}
+	for i := 0; i < 2; i++ {
+		pool.ProcessTx(context.Background(), tx)
+	}
+
+	if len(results) != 2 || results[0] != nil || results[1] != ErrDuplicateTx || pool.Size() != 1 {
+		t.Errorf("results were %v, pool size %d", results, pool.Size())
+	}
+}
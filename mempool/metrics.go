@@ -0,0 +1,60 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a prometheus collector of the pool statistics
+type Metrics struct {
+	// count of the pool transactions
+	size prometheus.GaugeFunc
+
+	// processed transactions by result (accepted, rejected)
+	txs *prometheus.CounterVec
+}
+
+// newMetrics returns metrics of the pool
+func newMetrics(p *Pool) *Metrics {
+	return &Metrics{
+		size: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "mempool",
+			Name:      "size",
+			Help:      "Count of the pool transactions.",
+		}, func() float64 {
+			return float64(p.Size())
+		}),
+
+		txs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gringo",
+			Subsystem: "mempool",
+			Name:      "txs_processed_total",
+			Help:      "Processed transactions by result.",
+		}, []string{"result"}),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.size.Describe(ch)
+	m.txs.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.size.Collect(ch)
+	m.txs.Collect(ch)
+}
+
+// observeTx records the transaction processing result
+func (m *Metrics) observeTx(err error) {
+	if err != nil {
+		m.txs.WithLabelValues("rejected").Inc()
+	} else {
+		m.txs.WithLabelValues("accepted").Inc()
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package mempool keeps the transactions waiting to be mined
+package mempool
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/timecache"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDuplicateTx the transaction is already in the pool
+	ErrDuplicateTx = errors.New("transaction is already in the pool")
+
+	// ErrNoKernels the transaction has no kernels
+	ErrNoKernels = consensus.ErrNoKernels
+
+	// ErrPoolFull the pool has reached its max size & the transaction doesn't
+	// pay the higher fee rate than the pool transactions
+	ErrPoolFull = errors.New("transaction pool is full")
+
+	// ErrUnknownInput the transaction spends an output missing in the utxo set
+	ErrUnknownInput = errors.New("transaction input is not an unspent output")
+
+	// ErrDoubleSpend the transaction spends an input of a pool transaction &
+	// doesn't replace it
+	ErrDoubleSpend = errors.New("transaction input is spent by a pool transaction")
+
+	// ErrRejectedTx the transaction failed the consensus validation recently
+	ErrRejectedTx = errors.New("transaction was rejected recently")
+
+	// ErrNonStandard the transaction exceeds the relay policy limits
+	ErrNonStandard = errors.New("transaction exceeds the relay policy")
+)
+
+const (
+	// DefaultMaxSize is the default max count of the pool transactions
+	DefaultMaxSize = 50000
+
+	// rejectTTL is the time the invalid transaction is remembered, it isn't
+	// validated again when relayed by the other peers
+	rejectTTL = 10 * time.Minute
+	// rejectCacheSize is the max count of the remembered invalid transactions
+	rejectCacheSize = 10000
+)
+
+// Chain is the utxo set the transaction inputs are checked against
+type Chain interface {
+	// GetUnspentOutput returns id of the block with the unspent output by
+	// commitment, if not found returns nil
+	GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID
+}
+
+// Pool is a pool of the valid transactions
+type Pool struct {
+	sync.RWMutex
+
+	// utxo set
+	chain Chain
+
+	// validate checks the transaction by consensus rules
+	validate func(ctx context.Context, tx *consensus.Transaction) error
+
+	// process is processTx wrapped by the middlewares
+	process TxHandler
+
+	// max count of the transactions
+	maxSize int
+
+	// transactions by hash
+	txs map[string]*consensus.Transaction
+
+	// pool transactions by the spent inputs
+	spent map[string]*consensus.Transaction
+
+	// policy of the transactions spending the inputs of the pool ones
+	policy ConflictPolicy
+
+	// standardness limits of the pool transactions
+	relay RelayPolicy
+
+	// hashes of the transactions failed the consensus validation
+	rejected *timecache.Cache
+
+	// subscribers of the new transactions
+	subscribers map[chan<- *consensus.Transaction]struct{}
+
+	// subscribers of the conflict events
+	eventSubscribers map[chan<- Event]struct{}
+
+	// pool statistics
+	metrics *Metrics
+
+	log logging.Logger
+}
+
+// New returns empty transaction pool spending the chain outputs
+func New(chain Chain) *Pool {
+	p := &Pool{
+		chain:            chain,
+		validate:         validate,
+		maxSize:          DefaultMaxSize,
+		txs:              make(map[string]*consensus.Transaction),
+		spent:            make(map[string]*consensus.Transaction),
+		policy:           ConflictPolicy{FeeRateIncrease: DefaultFeeRateIncrease},
+		relay:            DefaultRelayPolicy,
+		rejected:         timecache.New(rejectTTL, rejectCacheSize),
+		subscribers:      make(map[chan<- *consensus.Transaction]struct{}),
+		eventSubscribers: make(map[chan<- Event]struct{}),
+		log:              logging.Default(logging.Mempool),
+	}
+	p.process = p.processTx
+	p.metrics = newMetrics(p)
+
+	return p
+}
+
+// SetLogger replaces the pool logger, logging.Nop disables the logging
+func (p *Pool) SetLogger(logger logging.Logger) {
+	p.log = logger
+}
+
+// SetMaxSize sets the max count of the pool transactions
+func (p *Pool) SetMaxSize(n int) {
+	p.Lock()
+	defer p.Unlock()
+
+	if n > 0 {
+		p.maxSize = n
+	}
+}
+
+// Metrics returns the prometheus collector of the pool statistics
+func (p *Pool) Metrics() *Metrics {
+	return p.metrics
+}
+
+// validate checks the transaction by the consensus rules
+func validate(ctx context.Context, tx *consensus.Transaction) error {
+	return tx.Validate(ctx)
+}
+
+// ProcessTx validates transaction & adds it to the pool, the transaction is
+// not added if ctx is cancelled before the validation is done. The
+// transaction passes the middlewares first
+func (p *Pool) ProcessTx(ctx context.Context, tx *consensus.Transaction) error {
+	return p.process(ctx, tx)
+}
+
+// processTx is the innermost transaction handler of ProcessTx
+func (p *Pool) processTx(ctx context.Context, tx *consensus.Transaction) (err error) {
+	defer func() { p.metrics.observeTx(err) }()
+
+	if len(tx.Kernels) == 0 {
+		return ErrNoKernels
+	}
+
+	if err := p.standard(tx); err != nil {
+		return err
+	}
+
+	hash := tx.Hash()
+	if p.rejected.Has(hash) {
+		return ErrRejectedTx
+	}
+
+	if err := p.validate(ctx, tx); err != nil {
+		if ctx.Err() == nil {
+			p.rejected.Add(hash)
+		}
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, input := range tx.Inputs {
+		if p.chain.GetUnspentOutput(input.Commit) == nil {
+			return ErrUnknownInput
+		}
+	}
+
+	key := hash.String()
+
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.txs[key]; ok {
+		return ErrDuplicateTx
+	}
+
+	// the conflicting transactions are replaced by the higher fee rate or
+	// reject tx by the policy
+	conflicts := p.conflicts(tx)
+	if len(conflicts) > 0 && !p.replaces(tx, conflicts) {
+		p.emit(Event{Type: EventRejected, Tx: tx, Conflicts: conflicts})
+		return ErrDoubleSpend
+	}
+
+	for _, conflict := range conflicts {
+		p.remove(conflict)
+		p.log.Debugf("tx %s replaced by %s", conflict.Hash(), key)
+	}
+
+	// the full pool evicts the transaction of the lowest fee rate
+	if len(p.txs) >= p.maxSize {
+		lowest := p.lowestFeeRate()
+		if lowest == nil || !higherFeeRate(tx, lowest) {
+			return ErrPoolFull
+		}
+
+		p.remove(lowest)
+		p.log.Debugf("tx %s evicted by the higher fee rate", lowest.Hash())
+	}
+
+	for _, input := range tx.Inputs {
+		p.spent[string(input.Commit)] = tx
+	}
+
+	p.txs[key] = tx
+	p.log.Debugf("tx %s added to the pool", key)
+
+	if len(conflicts) > 0 {
+		p.emit(Event{Type: EventReplaced, Tx: tx, Conflicts: conflicts})
+	}
+
+	for ch := range p.subscribers {
+		select {
+		case ch <- tx:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// higherFeeRate returns true if a pays the higher fee per weight than b
+func higherFeeRate(a, b *consensus.Transaction) bool {
+	return new(big.Int).Mul(new(big.Int).SetUint64(a.Fee()), new(big.Int).SetUint64(b.Weight())).Cmp(
+		new(big.Int).Mul(new(big.Int).SetUint64(b.Fee()), new(big.Int).SetUint64(a.Weight()))) > 0
+}
+
+// lowestFeeRate returns the pool transaction of the lowest fee rate, nil if
+// the pool is empty. Must be called with the pool locked
+func (p *Pool) lowestFeeRate() *consensus.Transaction {
+	var lowest *consensus.Transaction
+	for _, tx := range p.txs {
+		if lowest == nil || higherFeeRate(lowest, tx) {
+			lowest = tx
+		}
+	}
+
+	return lowest
+}
+
+// remove removes the transaction & its spent inputs, must be called with
+// the pool locked
+func (p *Pool) remove(tx *consensus.Transaction) {
+	for _, input := range tx.Inputs {
+		delete(p.spent, string(input.Commit))
+	}
+
+	delete(p.txs, tx.Hash().String())
+}
+
+// Subscribe registers ch to receive the new pool transactions, the
+// transaction is skipped if ch is not ready to receive it
+func (p *Pool) Subscribe(ch chan<- *consensus.Transaction) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.subscribers[ch] = struct{}{}
+}
+
+// Unsubscribe removes ch from the subscribers
+func (p *Pool) Unsubscribe(ch chan<- *consensus.Transaction) {
+	p.Lock()
+	defer p.Unlock()
+
+	delete(p.subscribers, ch)
+}
+
+// Size returns count of the transactions in the pool
+func (p *Pool) Size() int {
+	p.RLock()
+	defer p.RUnlock()
+
+	return len(p.txs)
+}
+
+// Transactions returns all transactions of the pool, the higher fee rate
+// first as they are included in the block
+func (p *Pool) Transactions() []*consensus.Transaction {
+	p.RLock()
+	defer p.RUnlock()
+
+	result := make([]*consensus.Transaction, 0, len(p.txs))
+	for _, tx := range p.txs {
+		result = append(result, tx)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return higherFeeRate(result[i], result[j])
+	})
+
+	return result
+}
@@ -0,0 +1,346 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/testutil"
+	"math/big"
+	"testing"
+)
+
+// newKernel returns kernel signed by the key
+func newKernel(key int64, fee uint64) consensus.TxKernel {
+	x := big.NewInt(key)
+	P := secp256k1zkp.CommitValue(x, big.NewInt(0))
+
+	msg := secp256k1zkp.ComputeMessage(fee, 0)
+	sig := secp256k1zkp.SignMessage(*P, *x, msg)
+
+	return consensus.TxKernel{
+		Fee:       fee,
+		Excess:    *P,
+		ExcessSig: sig.Bytes(),
+	}
+}
+
+// testChain is the utxo set of the unspent commitments
+type testChain map[string]bool
+
+func (c testChain) GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID {
+	if !c[string(commit)] {
+		return nil
+	}
+
+	return &consensus.BlockID{}
+}
+
+// newTestPool returns pool checking only the kernel signatures, range proofs
+// of the test transactions are not valid
+func newTestPool(chain testChain) *Pool {
+	pool := New(chain)
+	pool.validate = func(ctx context.Context, tx *consensus.Transaction) error {
+		for i := range tx.Kernels {
+			if err := tx.Kernels[i].Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return pool
+}
+
+func TestPoolProcessTx(t *testing.T) {
+	pool := newTestPool(nil)
+
+	tx := &consensus.Transaction{
+		Kernels: consensus.TxKernelList{newKernel(7, 8)},
+	}
+
+	if err := pool.ProcessTx(context.Background(), tx); err != nil {
+		t.Fatalf("ProcessTx failed: %v", err)
+	}
+
+	if err := pool.ProcessTx(context.Background(), tx); err != ErrDuplicateTx {
+		t.Errorf("expected ErrDuplicateTx, got %v", err)
+	}
+
+	if err := pool.ProcessTx(context.Background(), &consensus.Transaction{}); err != ErrNoKernels {
+		t.Errorf("expected ErrNoKernels, got %v", err)
+	}
+
+	invalid := newKernel(9, 8)
+	invalid.Fee = 10
+	if err := pool.ProcessTx(context.Background(), &consensus.Transaction{Kernels: consensus.TxKernelList{invalid}}); err == nil {
+		t.Errorf("expected error on invalid kernel signature")
+	}
+
+	if err := pool.ProcessTx(context.Background(), &consensus.Transaction{Kernels: consensus.TxKernelList{invalid}}); err != ErrRejectedTx {
+		t.Errorf("expected ErrRejectedTx, got %v", err)
+	}
+
+	if pool.Size() != 1 {
+		t.Errorf("pool size was %d, want 1", pool.Size())
+	}
+}
+
+func TestPoolGeneratedTx(t *testing.T) {
+	g := testutil.New(&chain.Testnet4, 1)
+	blocks, err := g.Chain(&chain.Testnet4, 2, testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pool of the full validation, the generated proofs are valid
+	coins := g.Unspent(blocks[1])
+	utxo := testChain{string(coins[0].Output.Commit.Bytes()): true}
+	pool := New(utxo)
+
+	tx, err := g.Transaction(coins[:1], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.ProcessTx(context.Background(), tx); err != nil {
+		t.Errorf("ProcessTx failed: %v", err)
+	}
+
+	tx, err = g.Transaction(coins[1:], 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.ProcessTx(context.Background(), tx); !errors.Is(err, ErrUnknownInput) {
+		t.Errorf("error was %v, want %v", err, ErrUnknownInput)
+	}
+}
+
+func TestPoolInputs(t *testing.T) {
+	spent := secp256k1zkp.Commitment{8, 1}
+	unknown := secp256k1zkp.Commitment{8, 2}
+	pool := newTestPool(testChain{string(spent): true})
+
+	newTx := func(key int64, commit secp256k1zkp.Commitment) *consensus.Transaction {
+		return &consensus.Transaction{
+			Inputs:  consensus.InputList{{Commit: commit}},
+			Kernels: consensus.TxKernelList{newKernel(key, 1)},
+		}
+	}
+
+	if err := pool.ProcessTx(context.Background(), newTx(3, unknown)); err != ErrUnknownInput {
+		t.Errorf("expected ErrUnknownInput, got %v", err)
+	}
+
+	if err := pool.ProcessTx(context.Background(), newTx(3, spent)); err != nil {
+		t.Fatalf("ProcessTx failed: %v", err)
+	}
+
+	if err := pool.ProcessTx(context.Background(), newTx(4, spent)); err != ErrDoubleSpend {
+		t.Errorf("expected ErrDoubleSpend, got %v", err)
+	}
+}
+
+func TestPoolMaxSize(t *testing.T) {
+	pool := newTestPool(nil)
+	pool.SetMaxSize(1)
+
+	for key, expected := range []error{nil, ErrPoolFull} {
+		tx := &consensus.Transaction{
+			Kernels: consensus.TxKernelList{newKernel(int64(key+1), 1)},
+		}
+
+		if err := pool.ProcessTx(context.Background(), tx); err != expected {
+			t.Errorf("expected %v, got %v", expected, err)
+		}
+	}
+}
+
+func TestPoolFeeRate(t *testing.T) {
+	pool := newTestPool(nil)
+	pool.SetMaxSize(2)
+
+	newTx := func(key int64, fee uint64) *consensus.Transaction {
+		return &consensus.Transaction{Kernels: consensus.TxKernelList{newKernel(key, fee)}}
+	}
+
+	for key, test := range []struct {
+		fee      uint64
+		expected error
+	}{
+		{5, nil},
+		{1, nil},
+		{1, ErrPoolFull},
+		{3, nil},
+	} {
+		if err := pool.ProcessTx(context.Background(), newTx(int64(key+1), test.fee)); err != test.expected {
+			t.Errorf("fee %d: expected %v, got %v", test.fee, test.expected, err)
+		}
+	}
+
+	// the fee 1 transaction is evicted
+	var fees []uint64
+	for _, tx := range pool.Transactions() {
+		fees = append(fees, tx.Fee())
+	}
+
+	if len(fees) != 2 || fees[0] != 5 || fees[1] != 3 {
+		t.Errorf("fees of the pool transactions were %v, want [5 3]", fees)
+	}
+}
+
+func TestPoolSubscribe(t *testing.T) {
+	pool := newTestPool(nil)
+
+	ch := make(chan *consensus.Transaction, 1)
+	pool.Subscribe(ch)
+
+	tx := &consensus.Transaction{
+		Kernels: consensus.TxKernelList{newKernel(9, 1)},
+	}
+
+	if err := pool.ProcessTx(context.Background(), tx); err != nil {
+		t.Fatalf("ProcessTx failed: %v", err)
+	}
+
+	if got := <-ch; got != tx {
+		t.Errorf("subscriber got %v, want %v", got, tx)
+	}
+
+	pool.Unsubscribe(ch)
+	if len(pool.subscribers) != 0 {
+		t.Error("subscriber was not removed")
+	}
+}
+
+func TestPoolProcessTxCancelled(t *testing.T) {
+	pool := newTestPool(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tx := &consensus.Transaction{
+		Kernels: consensus.TxKernelList{newKernel(7, 8)},
+	}
+
+	if err := pool.ProcessTx(ctx, tx); err != context.Canceled {
+		t.Errorf("error was %v, want %v", err, context.Canceled)
+	}
+
+	if pool.Size() != 0 {
+		t.Errorf("pool had %d txs, want 0", pool.Size())
+	}
+
+	// the cancelled validation doesn't reject the transaction
+	if err := pool.ProcessTx(context.Background(), tx); err != nil {
+		t.Errorf("ProcessTx failed: %v", err)
+	}
+}
+
+func TestPoolReplaceByFee(t *testing.T) {
+	a, b := secp256k1zkp.Commitment{8, 1}, secp256k1zkp.Commitment{8, 2}
+	pool := newTestPool(testChain{string(a): true, string(b): true})
+
+	events := make(chan Event, 4)
+	pool.SubscribeEvents(events)
+
+	newTx := func(key int64, fee uint64, commits ...secp256k1zkp.Commitment) *consensus.Transaction {
+		tx := &consensus.Transaction{Kernels: consensus.TxKernelList{newKernel(key, fee)}}
+		for _, commit := range commits {
+			tx.Inputs = append(tx.Inputs, consensus.Input{Commit: commit})
+		}
+		return tx
+	}
+
+	first, second := newTx(1, 4, a), newTx(2, 4, b)
+	for _, tx := range []*consensus.Transaction{first, second} {
+		if err := pool.ProcessTx(context.Background(), tx); err != nil {
+			t.Fatalf("ProcessTx failed: %v", err)
+		}
+	}
+
+	// the default policy rejects the conflicting transaction of any fee
+	if err := pool.ProcessTx(context.Background(), newTx(3, 100, a)); err != ErrDoubleSpend {
+		t.Errorf("expected ErrDoubleSpend, got %v", err)
+	}
+
+	if event := <-events; event.Type != EventRejected || len(event.Conflicts) != 1 || event.Conflicts[0] != first {
+		t.Errorf("rejection event was %+v", event)
+	}
+
+	pool.SetConflictPolicy(ConflictPolicy{Replace: true, FeeRateIncrease: 25})
+
+	// the fee rate of the 25% increase exactly doesn't replace
+	if err := pool.ProcessTx(context.Background(), newTx(4, 5, a)); err != ErrDoubleSpend {
+		t.Errorf("expected ErrDoubleSpend, got %v", err)
+	}
+	<-events
+
+	// the heavier transaction spending both replaces both
+	replacing := newTx(5, 13, a, b)
+	if err := pool.ProcessTx(context.Background(), replacing); err != nil {
+		t.Fatalf("ProcessTx failed: %v", err)
+	}
+
+	if event := <-events; event.Type != EventReplaced || event.Tx != replacing || len(event.Conflicts) != 2 {
+		t.Errorf("replacement event was %+v", event)
+	}
+
+	if txs := pool.Transactions(); len(txs) != 1 || txs[0] != replacing {
+		t.Errorf("pool transactions were %v", txs)
+	}
+
+	// the inputs of the replaced transactions are spent by the replacing one
+	if err := pool.ProcessTx(context.Background(), newTx(6, 1, b)); err != ErrDoubleSpend {
+		t.Errorf("expected ErrDoubleSpend, got %v", err)
+	}
+
+	pool.UnsubscribeEvents(events)
+	if len(pool.eventSubscribers) != 0 {
+		t.Error("event subscriber was not removed")
+	}
+}
+
+func TestPoolRelayPolicy(t *testing.T) {
+	pool := newTestPool(nil)
+	pool.SetRelayPolicy(RelayPolicy{MaxWeight: 30, MaxKernels: 10, MaxOutputs: 2})
+
+	newTx := func(kernels, outputs int) *consensus.Transaction {
+		tx := &consensus.Transaction{Outputs: make(consensus.OutputList, outputs)}
+		for i := 0; i < kernels; i++ {
+			tx.Kernels = append(tx.Kernels, newKernel(int64(10*kernels+outputs+i), 1))
+		}
+		return tx
+	}
+
+	for _, test := range []struct {
+		kernels, outputs int
+		standard         bool
+	}{
+		{1, 0, true},
+		{2, 2, true},
+		{11, 0, false},
+		{1, 3, false},
+		// the weight of 2 outputs & 6 kernels is 32
+		{6, 2, false},
+	} {
+		err := pool.standard(newTx(test.kernels, test.outputs))
+		if standard := err == nil; standard != test.standard || (err != nil && !errors.Is(err, ErrNonStandard)) {
+			t.Errorf("%d kernels & %d outputs: %v", test.kernels, test.outputs, err)
+		}
+	}
+
+	if err := pool.ProcessTx(context.Background(), newTx(11, 0)); !errors.Is(err, ErrNonStandard) {
+		t.Errorf("expected ErrNonStandard, got %v", err)
+	}
+
+	// zero is not limited
+	pool.SetRelayPolicy(RelayPolicy{})
+	if err := pool.standard(newTx(11, 3)); err != nil {
+		t.Errorf("unlimited policy: %v", err)
+	}
+}
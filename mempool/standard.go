@@ -0,0 +1,58 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// RelayPolicy is the standardness limits of the pool transactions, stricter
+// than the consensus ones: the transaction exceeding them is valid in the
+// block, but isn't accepted to the pool nor relayed. Zero is not limited
+type RelayPolicy struct {
+	// MaxWeight is the max block weight of the transaction
+	MaxWeight  uint64
+	MaxKernels int
+	MaxOutputs int
+}
+
+// DefaultRelayPolicy is the tenth of the block weight, the kernels of the
+// aggregated transactions & the outputs of the weight
+var DefaultRelayPolicy = RelayPolicy{
+	MaxWeight:  uint64(consensus.MaxBlockWeight) / 10,
+	MaxKernels: 50,
+	MaxOutputs: 500,
+}
+
+// SetRelayPolicy sets the standardness limits of the pool transactions
+func (p *Pool) SetRelayPolicy(policy RelayPolicy) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.relay = policy
+}
+
+// standard returns ErrNonStandard if tx exceeds the relay policy limits
+func (p *Pool) standard(tx *consensus.Transaction) error {
+	p.RLock()
+	policy := p.relay
+	p.RUnlock()
+
+	if policy.MaxKernels > 0 && len(tx.Kernels) > policy.MaxKernels {
+		return fmt.Errorf("%w: %d kernels, the max is %d", ErrNonStandard, len(tx.Kernels), policy.MaxKernels)
+	}
+
+	if policy.MaxOutputs > 0 && len(tx.Outputs) > policy.MaxOutputs {
+		return fmt.Errorf("%w: %d outputs, the max is %d", ErrNonStandard, len(tx.Outputs), policy.MaxOutputs)
+	}
+
+	weight := consensus.BlockWeight(len(tx.Inputs), len(tx.Outputs), len(tx.Kernels))
+	if policy.MaxWeight > 0 && weight > policy.MaxWeight {
+		return fmt.Errorf("%w: weight %d, the max is %d", ErrNonStandard, weight, policy.MaxWeight)
+	}
+
+	return nil
+}
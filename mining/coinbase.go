@@ -0,0 +1,141 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package mining builds the reward of the blocks mined by the node
+package mining
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+	"sync"
+)
+
+// BlockFees is the coinbase request of the block, as the block_fees of the
+// grin wallet
+type BlockFees struct {
+	// Fees is the sum of the fees of the block transactions
+	Fees   uint64
+	Height uint64
+	// KeyID is the wallet key of the coinbase built for the block before,
+	// empty makes the wallet derive the next key
+	KeyID string
+}
+
+// Coinbase is the reward output & kernel of the block
+type Coinbase struct {
+	Output consensus.Output
+	Kernel consensus.TxKernel
+	// KeyID is the wallet key of the output, empty for the internal key
+	KeyID string
+}
+
+// CoinbaseBuilder builds the coinbase of the block paying the reward & the
+// fees to the operator
+type CoinbaseBuilder interface {
+	BuildCoinbase(ctx context.Context, fees BlockFees) (*Coinbase, error)
+}
+
+// KeyCoinbase builds the coinbase of the internal key: the blinding factor of
+// the block is derived from the key & the height, so the reward is spendable
+// by the key holder only
+type KeyCoinbase struct {
+	key *big.Int
+
+	// prover is created once, the generators are expensive
+	once   sync.Once
+	prover *bulletproofs.Prover
+}
+
+// NewKeyCoinbase returns the coinbase builder of the secret key
+func NewKeyCoinbase(key *big.Int) *KeyCoinbase {
+	return &KeyCoinbase{key: key}
+}
+
+// BuildCoinbase returns the coinbase of the reward & fees of the block, the
+// output is committed to the blinding factor of the height
+func (c *KeyCoinbase) BuildCoinbase(ctx context.Context, fees BlockFees) (*Coinbase, error) {
+	c.once.Do(func() { c.prover = bulletproofs.NewProver(64) })
+
+	value := new(big.Int).SetUint64(consensus.Reward + fees.Fees)
+	blind := c.blind(fees.Height)
+	commit := secp256k1zkp.CommitValue(blind, value)
+
+	// the proof nonce is of the blinding factor, so the key holder rewinds
+	// the proof
+	nonce := secp256k1zkp.ComputeHash(blind.Bytes())
+	proof, err := c.prover.CreateRangeProof(commit, value, blind, nonce, [16]byte{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create range proof: %v", err)
+	}
+
+	excess := bulletproofs.ScalarMulPoint(&secp256k1zkp.G, blind)
+	signature := secp256k1zkp.SignMessage(*excess, *blind, secp256k1zkp.ComputeMessage(0, 0))
+
+	return &Coinbase{
+		Output: consensus.Output{
+			Features:   consensus.CoinbaseOutput,
+			Commit:     commit,
+			RangeProof: proof,
+		},
+		Kernel: consensus.TxKernel{
+			Features:  consensus.CoinbaseKernel,
+			Excess:    *excess,
+			ExcessSig: signature.Bytes(),
+		},
+	}, nil
+}
+
+// blind returns the blinding factor of the block height: the hash of the
+// key & the height
+func (c *KeyCoinbase) blind(height uint64) *big.Int {
+	var key [32]byte
+	c.key.FillBytes(key[:])
+
+	var h [8]byte
+	binary.BigEndian.PutUint64(h[:], height)
+
+	hash := secp256k1zkp.ComputeHash(key[:], h[:])
+	blind := new(big.Int).SetBytes(hash[:])
+	blind.Mod(blind, btcec.S256().N)
+	if blind.Sign() == 0 {
+		blind.SetInt64(1)
+	}
+
+	return blind
+}
+
+// fallbackCoinbase is the builder of the coinbase of primary, the coinbase
+// of fallback is built if primary fails
+type fallbackCoinbase struct {
+	primary, fallback CoinbaseBuilder
+	log               logging.Logger
+}
+
+// Fallback returns the builder of the coinbase of primary, or of fallback
+// if primary fails: the internal key coinbase of the unreachable wallet
+func Fallback(primary, fallback CoinbaseBuilder) CoinbaseBuilder {
+	return &fallbackCoinbase{
+		primary:  primary,
+		fallback: fallback,
+		log:      logging.Default(logging.Mining),
+	}
+}
+
+// BuildCoinbase implements CoinbaseBuilder
+func (c *fallbackCoinbase) BuildCoinbase(ctx context.Context, fees BlockFees) (*Coinbase, error) {
+	coinbase, err := c.primary.BuildCoinbase(ctx, fees)
+	if err == nil {
+		return coinbase, nil
+	}
+
+	c.log.Warnf("failed to build coinbase of block %d, falling back: %v", fees.Height, err)
+	return c.fallback.BuildCoinbase(ctx, BlockFees{Fees: fees.Fees, Height: fees.Height})
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+	"testing"
+)
+
+func TestKeyCoinbase(t *testing.T) {
+	builder := NewKeyCoinbase(big.NewInt(12345))
+
+	coinbase, err := builder.BuildCoinbase(context.Background(), BlockFees{Fees: 7, Height: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if coinbase.Output.Features != consensus.CoinbaseOutput || coinbase.Kernel.Features != consensus.CoinbaseKernel {
+		t.Errorf("features were %v & %v", coinbase.Output.Features, coinbase.Kernel.Features)
+	}
+
+	if err := coinbase.Kernel.Validate(); err != nil {
+		t.Errorf("kernel failed validation: %v", err)
+	}
+
+	if !bulletproofs.NewProver(64).Verify(coinbase.Output.Commit, coinbase.Output.RangeProof) {
+		t.Errorf("range proof failed verification")
+	}
+
+	// the output commits to the reward & the fees over the kernel excess
+	value := new(big.Int).SetUint64(consensus.Reward + 7)
+	expected := bulletproofs.SumPoints(&coinbase.Kernel.Excess, bulletproofs.ScalarMulPoint(&secp256k1zkp.H, value))
+	if expected.X.Cmp(coinbase.Output.Commit.X) != 0 || expected.Y.Cmp(coinbase.Output.Commit.Y) != 0 {
+		t.Errorf("output doesn't commit to the reward & the fees")
+	}
+
+	// the blinding factor is of the height
+	same, err := builder.BuildCoinbase(context.Background(), BlockFees{Fees: 7, Height: 10})
+	if err != nil || same.Output.Commit.X.Cmp(coinbase.Output.Commit.X) != 0 {
+		t.Errorf("coinbase of the same height was changed: %v", err)
+	}
+
+	next, err := builder.BuildCoinbase(context.Background(), BlockFees{Fees: 7, Height: 11})
+	if err != nil || next.Output.Commit.X.Cmp(coinbase.Output.Commit.X) == 0 {
+		t.Errorf("coinbase of the next height was the same: %v", err)
+	}
+}
+
+// coinbaseFunc is the coinbase builder of the func
+type coinbaseFunc func(ctx context.Context, fees BlockFees) (*Coinbase, error)
+
+func (f coinbaseFunc) BuildCoinbase(ctx context.Context, fees BlockFees) (*Coinbase, error) {
+	return f(ctx, fees)
+}
+
+func TestFallback(t *testing.T) {
+	primary := &Coinbase{KeyID: "0300000000000000000000000400000000"}
+	fallback := &Coinbase{}
+
+	var requested BlockFees
+	builder := Fallback(
+		coinbaseFunc(func(context.Context, BlockFees) (*Coinbase, error) { return primary, nil }),
+		coinbaseFunc(func(_ context.Context, fees BlockFees) (*Coinbase, error) {
+			requested = fees
+			return fallback, nil
+		}),
+	)
+
+	if coinbase, err := builder.BuildCoinbase(context.Background(), BlockFees{Height: 1}); err != nil || coinbase != primary {
+		t.Errorf("coinbase was %v, %v, want the primary one", coinbase, err)
+	}
+
+	builder.(*fallbackCoinbase).primary = coinbaseFunc(func(context.Context, BlockFees) (*Coinbase, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	fees := BlockFees{Fees: 3, Height: 2, KeyID: primary.KeyID}
+	if coinbase, err := builder.BuildCoinbase(context.Background(), fees); err != nil || coinbase != fallback {
+		t.Errorf("coinbase was %v, %v, want the fallback one", coinbase, err)
+	}
+
+	// the wallet key isn't passed to the fallback
+	if requested != (BlockFees{Fees: 3, Height: 2}) {
+		t.Errorf("fallback was requested %+v", requested)
+	}
+}
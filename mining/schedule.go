@@ -0,0 +1,265 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The node activities pausing the mining, the mined blocks of them would be
+// of the stale head
+const (
+	// PauseTxHashSet is the txhashset install of the fast sync
+	PauseTxHashSet = "txhashset"
+	// PauseReorg is the processing of the fork blocks
+	PauseReorg = "reorg"
+)
+
+// The reasons of the stopped mining besides the pausing activities
+const (
+	ReasonPaused   = "paused"
+	ReasonSyncing  = "syncing"
+	ReasonWindow   = "outside run window"
+	ReasonDisabled = "no threads"
+)
+
+// Window is the daily run window of the miner, the local time of day from
+// Start to End. The window of End before Start wraps midnight, the one of
+// the equal bounds is the whole day
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses the window of "HH:MM-HH:MM", empty is the whole day
+func ParseWindow(s string) (Window, error) {
+	if s == "" {
+		return Window{}, nil
+	}
+
+	bounds := strings.Split(s, "-")
+	if len(bounds) != 2 {
+		return Window{}, fmt.Errorf("invalid run window %q, expected HH:MM-HH:MM", s)
+	}
+
+	var w Window
+	for i, field := range []*time.Duration{&w.Start, &w.End} {
+		t, err := time.Parse("15:04", strings.TrimSpace(bounds[i]))
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid run window %q, expected HH:MM-HH:MM", s)
+		}
+		*field = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	return w, nil
+}
+
+// Contains returns true if the local time of day of t is within the window
+func (w Window) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true
+	}
+
+	day := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start < w.End {
+		return day >= w.Start && day < w.End
+	}
+
+	return day >= w.Start || day < w.End
+}
+
+// String implements fmt.Stringer, the whole day is empty
+func (w Window) String() string {
+	if w.Start == w.End {
+		return ""
+	}
+
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+	}
+
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// Controls is the throttling & the schedule of the miner
+type Controls struct {
+	// SyncedOnly stops the mining until the chain is synced to the peers
+	SyncedOnly bool
+	// Threads is the count of the solver threads
+	Threads int
+	Window  Window
+}
+
+// Status is the mining state of the controller
+type Status struct {
+	Mining bool `json:"mining"`
+	// Reason is why the miner is stopped, empty if it's mining
+	Reason     string `json:"reason,omitempty"`
+	Paused     bool   `json:"paused"`
+	SyncedOnly bool   `json:"synced_only"`
+	Threads    int    `json:"threads"`
+	RunWindow  string `json:"run_window"`
+	// Activities is the node activities pausing the mining
+	Activities []string `json:"activities"`
+}
+
+// Controller decides whether the miner runs & by how many threads: by the
+// operator pause, the sync state, the node activities & the run window. The
+// controls are changed at runtime by the owner API
+type Controller struct {
+	sync.Mutex
+	controls Controls
+	paused   bool
+
+	// activities is the count of the running node activities pausing the
+	// mining by name
+	activities map[string]int
+
+	// synced returns true if the chain is synced to the peers
+	synced func() bool
+}
+
+// NewController returns the controller of the controls, synced reports the
+// sync state of the chain
+func NewController(controls Controls, synced func() bool) *Controller {
+	return &Controller{
+		controls:   controls,
+		activities: make(map[string]int),
+		synced:     synced,
+	}
+}
+
+// Pause stops the mining until Resume
+func (c *Controller) Pause() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.paused = true
+}
+
+// Resume resumes the mining paused by Pause
+func (c *Controller) Resume() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.paused = false
+}
+
+// Begin pauses the mining by the node activity until the matching End, the
+// activities may overlap
+func (c *Controller) Begin(activity string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.activities[activity]++
+}
+
+// End ends the node activity started by Begin
+func (c *Controller) End(activity string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.activities[activity] <= 1 {
+		delete(c.activities, activity)
+		return
+	}
+	c.activities[activity]--
+}
+
+// SetThreads sets the count of the solver threads, 0 stops the mining
+func (c *Controller) SetThreads(threads int) error {
+	if threads < 0 {
+		return fmt.Errorf("invalid threads: %d", threads)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.controls.Threads = threads
+	return nil
+}
+
+// SetWindow sets the run window of the miner
+func (c *Controller) SetWindow(window Window) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.controls.Window = window
+}
+
+// Threads returns the count of the solver threads to run at now, 0 if the
+// miner is stopped
+func (c *Controller) Threads(now time.Time) int {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.reason(now) != "" {
+		return 0
+	}
+
+	return c.controls.Threads
+}
+
+// Mining returns true if the miner runs at now, otherwise the reason it's
+// stopped
+func (c *Controller) Mining(now time.Time) (bool, string) {
+	c.Lock()
+	defer c.Unlock()
+
+	reason := c.reason(now)
+	return reason == "", reason
+}
+
+// reason returns why the miner is stopped at now, empty if it runs. Must be
+// called with the controller locked
+func (c *Controller) reason(now time.Time) string {
+	switch {
+	case c.paused:
+		return ReasonPaused
+	case c.controls.Threads == 0:
+		return ReasonDisabled
+	case len(c.activities) > 0:
+		return c.activityNames()[0]
+	case !c.controls.Window.Contains(now):
+		return ReasonWindow
+	case c.controls.SyncedOnly && c.synced != nil && !c.synced():
+		return ReasonSyncing
+	}
+
+	return ""
+}
+
+// activityNames returns the sorted running activities. Must be called with
+// the controller locked
+func (c *Controller) activityNames() []string {
+	names := make([]string, 0, len(c.activities))
+	for name := range c.activities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Status returns the mining state at now
+func (c *Controller) Status(now time.Time) Status {
+	c.Lock()
+	defer c.Unlock()
+
+	reason := c.reason(now)
+	return Status{
+		Mining:     reason == "",
+		Reason:     reason,
+		Paused:     c.paused,
+		SyncedOnly: c.controls.SyncedOnly,
+		Threads:    c.controls.Threads,
+		RunWindow:  c.controls.Window.String(),
+		Activities: c.activityNames(),
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	at := func(clock string) time.Time {
+		tm, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		window string
+		in     []string
+		out    []string
+	}{
+		{"", []string{"00:00", "12:00", "23:59"}, nil},
+		{"09:00-17:00", []string{"09:00", "16:59"}, []string{"08:59", "17:00", "23:00"}},
+		{"22:00-06:00", []string{"22:00", "23:59", "00:00", "05:59"}, []string{"06:00", "12:00", "21:59"}},
+	}
+
+	for _, test := range tests {
+		w, err := ParseWindow(test.window)
+		if err != nil {
+			t.Fatalf("window %q: %v", test.window, err)
+		}
+
+		if w.String() != test.window {
+			t.Errorf("window %q was printed as %q", test.window, w.String())
+		}
+
+		for _, clock := range test.in {
+			if !w.Contains(at(clock)) {
+				t.Errorf("window %q doesn't contain %s", test.window, clock)
+			}
+		}
+		for _, clock := range test.out {
+			if w.Contains(at(clock)) {
+				t.Errorf("window %q contains %s", test.window, clock)
+			}
+		}
+	}
+
+	for _, s := range []string{"22:00", "22:00-25:00", "night-day", "1-2-3"} {
+		if _, err := ParseWindow(s); err == nil {
+			t.Errorf("invalid window %q was parsed", s)
+		}
+	}
+}
+
+func TestController(t *testing.T) {
+	synced := false
+	c := NewController(Controls{SyncedOnly: true, Threads: 2}, func() bool { return synced })
+	now := time.Date(2018, 10, 17, 12, 0, 0, 0, time.Local)
+
+	expect := func(reason string) {
+		t.Helper()
+
+		mining, got := c.Mining(now)
+		if mining != (reason == "") || got != reason {
+			t.Errorf("mining was %v %q, want %q", mining, got, reason)
+		}
+	}
+
+	expect(ReasonSyncing)
+	synced = true
+	expect("")
+	if threads := c.Threads(now); threads != 2 {
+		t.Errorf("threads were %d, want 2", threads)
+	}
+
+	c.Begin(PauseReorg)
+	c.Begin(PauseReorg)
+	c.End(PauseReorg)
+	expect(PauseReorg)
+	if threads := c.Threads(now); threads != 0 {
+		t.Errorf("threads of the paused miner were %d", threads)
+	}
+	c.End(PauseReorg)
+	expect("")
+
+	c.Pause()
+	expect(ReasonPaused)
+	c.Resume()
+	expect("")
+
+	window, _ := ParseWindow("22:00-06:00")
+	c.SetWindow(window)
+	expect(ReasonWindow)
+	c.SetWindow(Window{})
+
+	if err := c.SetThreads(-1); err == nil {
+		t.Errorf("negative threads were accepted")
+	}
+	c.SetThreads(0)
+	expect(ReasonDisabled)
+
+	c.SetThreads(4)
+	c.Begin(PauseTxHashSet)
+	status := c.Status(now)
+	if status.Mining || status.Threads != 4 || len(status.Activities) != 1 || status.Activities[0] != PauseTxHashSet {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// VardiffConfig is the variable share difficulty of the stratum workers: the
+// difficulty of the worker is retargeted to TargetTime between the shares
+// every RetargetTime, within MinDifficulty & MaxDifficulty
+type VardiffConfig struct {
+	MinDifficulty uint64
+	MaxDifficulty uint64
+	// StartDifficulty is the difficulty of the new worker
+	StartDifficulty uint64
+	TargetTime      time.Duration
+	RetargetTime    time.Duration
+}
+
+// DefaultVardiff is the vardiff of the CPU rigs to the GPU farms
+var DefaultVardiff = VardiffConfig{
+	MinDifficulty:   1,
+	MaxDifficulty:   1 << 32,
+	StartDifficulty: 1,
+	TargetTime:      10 * time.Second,
+	RetargetTime:    time.Minute,
+}
+
+// maxRetargetFactor bounds the change of the difficulty by the retarget
+const maxRetargetFactor = 4
+
+// The results of the worker shares
+const (
+	ShareAccepted ShareResult = iota
+	// ShareRejected is the share of the invalid solution or below the
+	// worker difficulty
+	ShareRejected
+	// ShareStale is the share of the outdated job
+	ShareStale
+)
+
+// ShareResult is the result of the worker share
+type ShareResult int
+
+// WorkerStats is the share stats of the worker login
+type WorkerStats struct {
+	Login      string
+	Difficulty uint64
+	Accepted   uint64
+	Rejected   uint64
+	Stale      uint64
+	// Retargets is the count of the difficulty changes
+	Retargets uint64
+	LastShare time.Time
+	LoggedIn  time.Time
+}
+
+// worker is the stats & the retarget window of the login
+type worker struct {
+	WorkerStats
+	// shares is the count of the accepted & the stale shares since
+	// windowStart
+	shares      int
+	windowStart time.Time
+}
+
+// Vardiff is the share difficulty of the stratum workers by their share
+// rate, the weak workers get the lower difficulty & the fast ones the
+// higher one so every worker submits a share about every TargetTime
+type Vardiff struct {
+	sync.Mutex
+	config  VardiffConfig
+	workers map[string]*worker
+}
+
+// NewVardiff returns the vardiff of the config
+func NewVardiff(config VardiffConfig) *Vardiff {
+	return &Vardiff{
+		config:  config,
+		workers: make(map[string]*worker),
+	}
+}
+
+// Login registers the worker login & returns its difficulty, the relogged
+// worker keeps its difficulty & stats
+func (v *Vardiff) Login(login string, now time.Time) uint64 {
+	v.Lock()
+	defer v.Unlock()
+
+	w, ok := v.workers[login]
+	if !ok {
+		w = &worker{WorkerStats: WorkerStats{
+			Login:      login,
+			Difficulty: v.clamp(v.config.StartDifficulty),
+			LoggedIn:   now,
+		}}
+		v.workers[login] = w
+	}
+	w.windowStart = now
+	w.shares = 0
+
+	return w.Difficulty
+}
+
+// Logout removes the worker login & its stats
+func (v *Vardiff) Logout(login string) {
+	v.Lock()
+	defer v.Unlock()
+
+	delete(v.workers, login)
+}
+
+// Difficulty returns the share difficulty of the worker, 0 if it's not
+// logged in
+func (v *Vardiff) Difficulty(login string) uint64 {
+	v.Lock()
+	defer v.Unlock()
+
+	if w, ok := v.workers[login]; ok {
+		return w.Difficulty
+	}
+
+	return 0
+}
+
+// Share records the share of the worker & returns the difficulty of its
+// next job, retarget is true if the difficulty is changed. The rejected
+// shares don't count to the share rate
+func (v *Vardiff) Share(login string, result ShareResult, now time.Time) (difficulty uint64, retarget bool) {
+	v.Lock()
+	defer v.Unlock()
+
+	w, ok := v.workers[login]
+	if !ok {
+		return 0, false
+	}
+
+	switch result {
+	case ShareAccepted:
+		w.Accepted++
+		w.shares++
+	case ShareStale:
+		w.Stale++
+		w.shares++
+	default:
+		w.Rejected++
+	}
+	w.LastShare = now
+
+	retarget = v.retarget(w, now)
+	return w.Difficulty, retarget
+}
+
+// Retarget retargets the workers of the passed window, the workers without
+// the shares get the lower difficulty. Returns the retargeted logins & their
+// difficulty, to send them the new jobs
+func (v *Vardiff) Retarget(now time.Time) map[string]uint64 {
+	v.Lock()
+	defer v.Unlock()
+
+	result := make(map[string]uint64)
+	for login, w := range v.workers {
+		if v.retarget(w, now) {
+			result[login] = w.Difficulty
+		}
+	}
+
+	return result
+}
+
+// retarget adjusts the difficulty of the worker by the share rate of the
+// passed window, returns true if the difficulty is changed. Must be called
+// with the vardiff locked
+func (v *Vardiff) retarget(w *worker, now time.Time) bool {
+	elapsed := now.Sub(w.windowStart)
+	if elapsed < v.config.RetargetTime || elapsed <= 0 {
+		return false
+	}
+
+	// difficulty * shares * target / elapsed, the change is bounded by the
+	// factor
+	var difficulty uint64
+	if target := v.config.TargetTime * time.Duration(w.shares); w.shares == 0 || target < elapsed/maxRetargetFactor {
+		difficulty = w.Difficulty / maxRetargetFactor
+	} else if target > elapsed*maxRetargetFactor {
+		difficulty = w.Difficulty * maxRetargetFactor
+	} else {
+		difficulty = uint64(float64(w.Difficulty) * float64(target) / float64(elapsed))
+	}
+	difficulty = v.clamp(difficulty)
+
+	w.windowStart = now
+	w.shares = 0
+
+	if difficulty == w.Difficulty {
+		return false
+	}
+
+	w.Difficulty = difficulty
+	w.Retargets++
+	return true
+}
+
+// clamp returns the difficulty within the bounds of the config
+func (v *Vardiff) clamp(difficulty uint64) uint64 {
+	if difficulty < v.config.MinDifficulty {
+		difficulty = v.config.MinDifficulty
+	}
+
+	if v.config.MaxDifficulty > 0 && difficulty > v.config.MaxDifficulty {
+		difficulty = v.config.MaxDifficulty
+	}
+
+	if difficulty == 0 {
+		return 1
+	}
+
+	return difficulty
+}
+
+// Stats returns the share stats of the logged in workers by login
+func (v *Vardiff) Stats() []WorkerStats {
+	v.Lock()
+	defer v.Unlock()
+
+	result := make([]WorkerStats, 0, len(v.workers))
+	for _, w := range v.workers {
+		result = append(result, w.WorkerStats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Login < result[j].Login
+	})
+
+	return result
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVardiff(t *testing.T) {
+	config := VardiffConfig{
+		MinDifficulty:   2,
+		MaxDifficulty:   64,
+		StartDifficulty: 8,
+		TargetTime:      10 * time.Second,
+		RetargetTime:    time.Minute,
+	}
+
+	for _, test := range []struct {
+		name string
+		// shares is the count of the accepted shares of the minute
+		shares     int
+		difficulty uint64
+	}{
+		{name: "on target", shares: 6, difficulty: 8},
+		{name: "twice the target rate", shares: 12, difficulty: 16},
+		{name: "half the target rate", shares: 3, difficulty: 4},
+		{name: "gpu farm", shares: 600, difficulty: 32},
+		{name: "weak rig", shares: 1, difficulty: 2},
+	} {
+		v := NewVardiff(config)
+		start := time.Now()
+		if difficulty := v.Login("worker", start); difficulty != 8 {
+			t.Fatalf("%s: start difficulty was %d", test.name, difficulty)
+		}
+
+		var difficulty uint64
+		var retarget bool
+		for i := 1; i <= test.shares; i++ {
+			difficulty, retarget = v.Share("worker", ShareAccepted, start.Add(time.Minute*time.Duration(i)/time.Duration(test.shares)))
+			if retarget != (i == test.shares && test.difficulty != 8) {
+				t.Errorf("%s: share %d retarget was %v", test.name, i, retarget)
+			}
+		}
+
+		if difficulty != test.difficulty || v.Difficulty("worker") != test.difficulty {
+			t.Errorf("%s: difficulty was %d, want %d", test.name, difficulty, test.difficulty)
+		}
+	}
+}
+
+func TestVardiffBounds(t *testing.T) {
+	v := NewVardiff(VardiffConfig{MinDifficulty: 2, MaxDifficulty: 16, StartDifficulty: 8, TargetTime: time.Second, RetargetTime: time.Second})
+	start := time.Now()
+	v.Login("farm", start)
+	v.Login("rig", start)
+
+	for i := 0; i < 100; i++ {
+		v.Share("farm", ShareAccepted, start.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	// the worker without the shares is retargeted by the ticks
+	now := start.Add(time.Second)
+	if retargeted := v.Retarget(now); len(retargeted) != 2 || retargeted["farm"] != 16 || retargeted["rig"] != 2 {
+		t.Errorf("retargeted %v", retargeted)
+	}
+
+	for i := 0; i < 100; i++ {
+		v.Share("farm", ShareAccepted, now.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	if retargeted := v.Retarget(now.Add(time.Second)); len(retargeted) != 0 {
+		t.Errorf("retargeted %v at the bounds", retargeted)
+	}
+}
+
+func TestVardiffStats(t *testing.T) {
+	v := NewVardiff(DefaultVardiff)
+	start := time.Now()
+
+	if _, retarget := v.Share("unknown", ShareAccepted, start); retarget || v.Difficulty("unknown") != 0 {
+		t.Errorf("share of the unknown worker was recorded")
+	}
+
+	v.Login("b", start)
+	v.Login("a", start)
+	v.Share("a", ShareAccepted, start)
+	v.Share("a", ShareRejected, start)
+	v.Share("a", ShareStale, start.Add(time.Second))
+
+	// the relogged worker keeps the stats
+	v.Login("a", start.Add(2*time.Second))
+
+	stats := v.Stats()
+	if len(stats) != 2 || stats[0].Login != "a" || stats[1].Login != "b" {
+		t.Fatalf("stats were %+v", stats)
+	}
+
+	a := stats[0]
+	if a.Accepted != 1 || a.Rejected != 1 || a.Stale != 1 || !a.LastShare.Equal(start.Add(time.Second)) || !a.LoggedIn.Equal(start) {
+		t.Errorf("stats of a were %+v", a)
+	}
+
+	v.Logout("a")
+	if stats := v.Stats(); len(stats) != 1 {
+		t.Errorf("stats were %+v after the logout", stats)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package monitor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// the bandwidth gauge of the last hour labeled by direction
+const (
+	bandwidth     = "bandwidth_last_hour_bytes"
+	bandwidthHelp = "Bytes of the peer messages of the last hour by direction."
+)
+
+// Metrics is a prometheus collector of the sampled resource usage
+type Metrics struct {
+	rss         prometheus.GaugeFunc
+	openFDs     prometheus.GaugeFunc
+	goroutines  prometheus.GaugeFunc
+	storageSize prometheus.GaugeFunc
+
+	// bytes of the peer messages of the last hour by direction
+	sent     prometheus.GaugeFunc
+	received prometheus.GaugeFunc
+}
+
+// Metrics returns the collector of the last sampled usage
+func (m *Monitor) Metrics() *Metrics {
+	gauge := func(name, help string, labels prometheus.Labels, value func(u Usage) float64) prometheus.GaugeFunc {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "gringo",
+			Subsystem:   "resources",
+			Name:        name,
+			Help:        help,
+			ConstLabels: labels,
+		}, func() float64 {
+			return value(m.Usage())
+		})
+	}
+
+	return &Metrics{
+		rss: gauge("rss_bytes", "Resident memory of the node process.", nil, func(u Usage) float64 {
+			return float64(u.RSS)
+		}),
+		openFDs: gauge("open_fds", "Count of the open file descriptors.", nil, func(u Usage) float64 {
+			return float64(u.OpenFDs)
+		}),
+		goroutines: gauge("goroutines", "Count of the goroutines.", nil, func(u Usage) float64 {
+			return float64(u.Goroutines)
+		}),
+		storageSize: gauge("storage_size_bytes", "Size of the chain storage.", nil, func(u Usage) float64 {
+			return float64(u.StorageSize)
+		}),
+
+		sent: gauge(bandwidth, bandwidthHelp, prometheus.Labels{"direction": "sent"}, func(u Usage) float64 {
+			return float64(u.Sent)
+		}),
+		received: gauge(bandwidth, bandwidthHelp, prometheus.Labels{"direction": "received"}, func(u Usage) float64 {
+			return float64(u.Received)
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.rss.Describe(ch)
+	m.openFDs.Describe(ch)
+	m.goroutines.Describe(ch)
+	m.storageSize.Describe(ch)
+	m.sent.Describe(ch)
+	m.received.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.rss.Collect(ch)
+	m.openFDs.Collect(ch)
+	m.goroutines.Collect(ch)
+	m.storageSize.Collect(ch)
+	m.sent.Collect(ch)
+	m.received.Collect(ch)
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package monitor samples the resource usage of the node: the process memory
+// & file descriptors, the goroutines, the storage size and the p2p bandwidth
+// of the last hour, so the small deployments are sized by the real usage
+package monitor
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Interval is the default sampling interval of the monitor
+	Interval = time.Minute
+	// bandwidthWindow is the period of the bandwidth usage
+	bandwidthWindow = time.Hour
+)
+
+// Usage is the sampled resource usage of the node
+type Usage struct {
+	// RSS is the resident memory of the process in bytes, 0 if the OS
+	// doesn't report it
+	RSS uint64
+	// OpenFDs is the count of the open file descriptors, 0 if the OS doesn't
+	// report it
+	OpenFDs int
+	// Goroutines is the count of the goroutines
+	Goroutines int
+	// StorageSize is the size of the chain storage in bytes
+	StorageSize uint64
+	// Sent & Received are the bytes of the peer messages of the last hour,
+	// since the start if the node runs less
+	Sent     uint64
+	Received uint64
+	// Sampled is the time of the sample, zero before the first one
+	Sampled time.Time
+}
+
+// Traffic returns the bytes sent & received by the peers since the start
+type Traffic func() (sent, received uint64)
+
+// traffic is the traffic sample of the bandwidth window
+type traffic struct {
+	at             time.Time
+	sent, received uint64
+}
+
+// Monitor samples the usage every interval
+type Monitor struct {
+	interval    time.Duration
+	traffic     Traffic
+	storageSize func() uint64
+
+	mu    sync.RWMutex
+	usage Usage
+	// samples are the traffic samples of the bandwidth window, oldest first
+	samples []traffic
+}
+
+// New returns the monitor of the peers traffic & the storage size sampled
+// every interval
+func New(interval time.Duration, traffic Traffic, storageSize func() uint64) *Monitor {
+	return &Monitor{
+		interval:    interval,
+		traffic:     traffic,
+		storageSize: storageSize,
+	}
+}
+
+// Usage returns the last sampled usage
+func (m *Monitor) Usage() Usage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.usage
+}
+
+// Sample samples the usage at now, the storage is sampled out of the lock as
+// its size is queried from the database
+func (m *Monitor) Sample(now time.Time) Usage {
+	usage := Usage{
+		RSS:         rss(),
+		OpenFDs:     openFDs(),
+		Goroutines:  runtime.NumGoroutine(),
+		StorageSize: m.storageSize(),
+		Sampled:     now,
+	}
+	sent, received := m.traffic()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// the oldest sample is kept at the window start, so the usage covers the
+	// whole window
+	m.samples = append(m.samples, traffic{at: now, sent: sent, received: received})
+	for len(m.samples) > 1 && !m.samples[1].at.After(now.Add(-bandwidthWindow)) {
+		m.samples = m.samples[1:]
+	}
+
+	usage.Sent = sent - m.samples[0].sent
+	usage.Received = received - m.samples[0].received
+	m.usage = usage
+
+	return usage
+}
+
+// Run samples the usage every interval until ctx is done, the first sample
+// is taken on start
+func (m *Monitor) Run(ctx context.Context) {
+	m.Sample(time.Now())
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.Sample(now)
+		}
+	}
+}
+
+// rss returns the resident memory of the process by /proc, 0 if it's not
+// available
+func rss() uint64 {
+	data, err := ioutil.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+
+	// the pages of the total program size & the resident set
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return pages * uint64(os.Getpagesize())
+}
+
+// openFDs returns the count of the open file descriptors by /proc, 0 if it's
+// not available
+func openFDs() int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0
+	}
+
+	return len(fds)
+}
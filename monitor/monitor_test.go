@@ -0,0 +1,76 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package monitor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSample(t *testing.T) {
+	var sent, received uint64
+	m := New(Interval, func() (uint64, uint64) {
+		return sent, received
+	}, func() uint64 {
+		return 1 << 30
+	})
+
+	start := time.Now()
+	if usage := m.Usage(); !usage.Sampled.IsZero() {
+		t.Errorf("usage was sampled before the first sample: %+v", usage)
+	}
+
+	sent, received = 100, 1000
+	usage := m.Sample(start)
+	if usage.Sent != 0 || usage.Received != 0 || usage.StorageSize != 1<<30 || usage.Goroutines == 0 {
+		t.Errorf("first usage was %+v", usage)
+	}
+
+	if runtime.GOOS == "linux" && (usage.RSS == 0 || usage.OpenFDs == 0) {
+		t.Errorf("process usage was not sampled: %+v", usage)
+	}
+
+	// the bandwidth is of the last hour
+	for i := 1; i <= 90; i++ {
+		sent += 10
+		received += 20
+		usage = m.Sample(start.Add(time.Duration(i) * time.Minute))
+	}
+
+	if usage.Sent != 600 || usage.Received != 1200 {
+		t.Errorf("bandwidth of the last hour was %d & %d, want 600 & 1200", usage.Sent, usage.Received)
+	}
+
+	if m.Usage() != usage {
+		t.Errorf("last usage was %+v, want %+v", m.Usage(), usage)
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(m.Metrics()); err != nil {
+		t.Fatalf("failed to register resources metrics: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	bandwidth := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "gringo_resources_bandwidth_last_hour_bytes" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			bandwidth[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+
+	if bandwidth["sent"] != 600 || bandwidth["received"] != 1200 {
+		t.Errorf("bandwidth metrics were %v", bandwidth)
+	}
+}
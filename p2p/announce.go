@@ -0,0 +1,48 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+)
+
+// announceQueue is the count of the new head blocks waiting for the
+// announcement, the chain skips the blocks of the full queue
+const announceQueue = 64
+
+// subscribe registers the queue of the new head blocks of the chain
+func (s *Syncer) subscribe() chan *consensus.Block {
+	ch := make(chan *consensus.Block, announceQueue)
+	s.Chain.Subscribe(ch)
+
+	return ch
+}
+
+// announce propagates the new head blocks of ch until Stop: the blocks of the
+// peers & of the local miner alike. The block superseded by the next head
+// isn't propagated, nor the blocks of the headers only chain & of the initial
+// block download
+func (s *Syncer) announce(ch chan *consensus.Block) {
+	defer s.Chain.Unsubscribe(ch)
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case block := <-ch:
+			if s.headersOnly || block.Header.Height != s.Chain.Height() || s.InitialBlockDownload() {
+				continue
+			}
+
+			s.log.WithFields(logging.Fields{
+				"height": block.Header.Height,
+				"hash":   block.Hash().String(),
+			}).Debug("announcing block")
+			s.Pool.PropagateBlock(block)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"testing"
+)
+
+func TestAnnounce(t *testing.T) {
+	tests := []struct {
+		name        string
+		height      uint64
+		headersOnly bool
+		ibd         bool
+		propagated  int
+	}{
+		{name: "head", height: 12, propagated: 1},
+		{name: "stale head", height: 11},
+		{name: "headers only", height: 12, headersOnly: true},
+		{name: "initial block download", height: 12, ibd: true},
+	}
+
+	for _, test := range tests {
+		c := &mockChain{height: 12}
+		pool := &mockPool{}
+
+		s := NewSyncer(nil, c, &mockMempool{})
+		s.SetLogger(logging.Nop)
+		s.Pool = pool
+		s.headersOnly = test.headersOnly
+		s.ibd.done = !test.ibd
+
+		if s.subscribe(); c.subscribed == nil {
+			t.Fatalf("%s: syncer didn't subscribe to the chain", test.name)
+		}
+
+		// the unbuffered queue is read after the previous block is announced
+		ch := make(chan *consensus.Block)
+		c.subscribed = ch
+
+		done := make(chan struct{})
+		go func() {
+			s.announce(ch)
+			close(done)
+		}()
+
+		ch <- &consensus.Block{Header: consensus.BlockHeader{Height: test.height}}
+		ch <- &consensus.Block{Header: consensus.BlockHeader{Height: 0}}
+		s.Stop()
+		<-done
+
+		if len(pool.blocks) != test.propagated {
+			t.Errorf("%s: %d blocks propagated, want %d", test.name, len(pool.blocks), test.propagated)
+		}
+
+		if c.subscribed != nil {
+			t.Errorf("%s: syncer didn't unsubscribe on stop", test.name)
+		}
+	}
+}
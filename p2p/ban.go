@@ -0,0 +1,236 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// parseBan returns the normalized ban list entry: the host:port addr or the
+// CIDR range, the range is returned with its network
+func parseBan(entry string) (string, *net.IPNet, error) {
+	if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+		return ipNet.String(), ipNet, nil
+	}
+
+	if _, _, err := net.SplitHostPort(entry); err != nil {
+		return "", nil, fmt.Errorf("invalid ban entry: %s", entry)
+	}
+
+	return entry, nil, nil
+}
+
+// hostIP returns the IP of the host:port addr, nil if the host isn't IP
+func hostIP(addr string) net.IP {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+
+	return net.ParseIP(host)
+}
+
+// banAddr returns the ban entry of the misbehaving peer: the inbound peer
+// reconnects from another port, so its host is banned as the single IP range
+func (p *Peer) banAddr() string {
+	addr := p.conn.RemoteAddr().String()
+	ip := hostIP(addr)
+	if !p.Inbound || ip == nil {
+		return addr
+	}
+
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}).String()
+}
+
+// readBans returns the normalized entries of the ban file, one addr or CIDR
+// range per line, the empty lines & the # comments are skipped. The missing
+// file is the empty list
+func readBans(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		entry, _, err := parseBan(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// loadBans reads the ban list of the file
+func (pp *peersPool) loadBans(file string) error {
+	entries, err := readBans(file)
+	if err != nil {
+		return err
+	}
+
+	pp.bnmu.Lock()
+	defer pp.bnmu.Unlock()
+
+	for _, entry := range entries {
+		if _, ipNet, _ := parseBan(entry); ipNet != nil {
+			pp.BannedNets[entry] = ipNet
+		} else {
+			pp.BannedPeers[entry] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// reloadBans applies the edited ban file: its new entries are banned & the
+// missing ones unbanned. The file is left as is if it's invalid
+func (pp *peersPool) reloadBans() error {
+	if pp.banFile == "" {
+		return nil
+	}
+
+	entries, err := readBans(pp.banFile)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	for _, entry := range pp.Bans() {
+		current[entry] = true
+	}
+
+	// the bans first, so the file being rewritten keeps the new entries
+	wanted := make(map[string]bool)
+	for _, entry := range entries {
+		wanted[entry] = true
+		if !current[entry] {
+			pp.Ban(entry)
+		}
+	}
+
+	for entry := range current {
+		if !wanted[entry] {
+			pp.Unban(entry)
+		}
+	}
+
+	return nil
+}
+
+// Bans returns the banned addrs & CIDR ranges sorted
+func (pp *peersPool) Bans() []string {
+	pp.bnmu.Lock()
+	defer pp.bnmu.Unlock()
+
+	return pp.banEntries()
+}
+
+// banEntries returns the sorted ban list, must be called with bnmu locked
+func (pp *peersPool) banEntries() []string {
+	entries := make([]string, 0, len(pp.BannedPeers)+len(pp.BannedNets))
+	for addr := range pp.BannedPeers {
+		entries = append(entries, addr)
+	}
+	for cidr := range pp.BannedNets {
+		entries = append(entries, cidr)
+	}
+	sort.Strings(entries)
+
+	return entries
+}
+
+// saveBans writes the ban list to the ban file replacing it, must be called
+// with bnmu locked
+func (pp *peersPool) saveBans() {
+	if pp.banFile == "" {
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range pp.banEntries() {
+		buf.WriteString(entry)
+		buf.WriteByte('\n')
+	}
+
+	if err := writeFileAtomic(pp.banFile, buf.Bytes()); err != nil {
+		pp.log.Errorf("failed to save the ban list: %v", err)
+	}
+}
+
+// writeFileAtomic replaces the file by the data, the file is never left
+// partially written
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+// localNets are the private, the shared address space & the link local
+// ranges, not routable over the internet
+var localNets = []*net.IPNet{
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("169.254.0.0/16"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("fc00::/7"),
+	mustParseCIDR("fe80::/10"),
+}
+
+// mustParseCIDR returns the range of the valid CIDR
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return ipNet
+}
+
+// isLocal returns true if the ip is the loopback or of the local networks
+func isLocal(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+
+	for _, ipNet := range localNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
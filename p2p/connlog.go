@@ -0,0 +1,154 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connLogSize is the count of the latest connection events kept by the pool
+const connLogSize = 1000
+
+// the connection events
+const (
+	ConnConnect    = "connect"
+	ConnDisconnect = "disconnect"
+	ConnBan        = "ban"
+)
+
+// ConnEvent is the connection audit record: the peer connected, disconnected
+// or was banned
+type ConnEvent struct {
+	Time time.Time
+	// Event is one of connect, disconnect or ban
+	Event string
+	// Addr is the peer addr, the CIDR range of the range ban
+	Addr      string
+	Inbound   bool
+	UserAgent string
+	// Duration & the bytes are counted from the connect to the event, zero
+	// if the peer wasn't connected
+	Duration      time.Duration
+	BytesSent     uint64
+	BytesReceived uint64
+	// Reason is the disconnect reason, empty for the other events
+	Reason string
+}
+
+// ConnLogQuery selects the connection events, the zero fields match all
+type ConnLogQuery struct {
+	// Addr is the peer addr, the host or the CIDR range
+	Addr  string
+	Event string
+	Since time.Time
+	// Limit is the max count of the latest events
+	Limit int
+}
+
+// match returns true if the event is selected by the query
+func (q *ConnLogQuery) match(event *ConnEvent) bool {
+	if q.Event != "" && q.Event != event.Event {
+		return false
+	}
+
+	if !q.Since.IsZero() && event.Time.Before(q.Since) {
+		return false
+	}
+
+	if q.Addr == "" || q.Addr == event.Addr {
+		return true
+	}
+
+	if _, ipNet, err := net.ParseCIDR(q.Addr); err == nil {
+		ip := hostIP(event.Addr)
+		return ip != nil && ipNet.Contains(ip)
+	}
+
+	host, _, err := net.SplitHostPort(event.Addr)
+	return err == nil && host == q.Addr
+}
+
+// connLog is the ring buffer of the latest connection events
+type connLog struct {
+	sync.Mutex
+
+	events []ConnEvent
+	// next is the index of the next event once the buffer is full
+	next int
+}
+
+// add records the event, the oldest one is dropped if the log is full
+func (l *connLog) add(event ConnEvent) {
+	l.Lock()
+	defer l.Unlock()
+
+	if len(l.events) < connLogSize {
+		l.events = append(l.events, event)
+		return
+	}
+
+	l.events[l.next] = event
+	l.next = (l.next + 1) % connLogSize
+}
+
+// query returns the selected events, the latest first
+func (l *connLog) query(q ConnLogQuery) []ConnEvent {
+	l.Lock()
+	defer l.Unlock()
+
+	result := make([]ConnEvent, 0)
+	for i := 0; i < len(l.events); i++ {
+		if q.Limit > 0 && len(result) == q.Limit {
+			break
+		}
+
+		// the latest event precedes next
+		event := &l.events[(l.next-1-i+2*len(l.events))%len(l.events)]
+		if q.match(event) {
+			result = append(result, *event)
+		}
+	}
+
+	return result
+}
+
+// connEvent returns the event of the peer connection
+func (p *Peer) connEvent(event string) ConnEvent {
+	return ConnEvent{
+		Time:          time.Now(),
+		Event:         event,
+		Addr:          p.Addr,
+		Inbound:       p.Inbound,
+		UserAgent:     p.Info.UserAgent,
+		Duration:      time.Since(p.connected),
+		BytesSent:     atomic.LoadUint64(&p.bytesSent),
+		BytesReceived: atomic.LoadUint64(&p.bytesReceived),
+	}
+}
+
+// ConnLog returns the recorded connection events selected by the query, the
+// latest first
+func (pp *peersPool) ConnLog(query ConnLogQuery) []ConnEvent {
+	return pp.connLog.query(query)
+}
+
+// logConnect records the connected peer
+func (pp *peersPool) logConnect(peer *Peer) {
+	pp.connLog.add(peer.connEvent(ConnConnect))
+}
+
+// logDisconnect records the disconnected peer, must be called after
+// WaitForDisconnect
+func (pp *peersPool) logDisconnect(peer *Peer) {
+	event := peer.connEvent(ConnDisconnect)
+	if peer.reason != nil {
+		event.Reason = peer.reason.Error()
+	}
+
+	pp.connLog.add(event)
+}
@@ -0,0 +1,108 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnLogQuery(t *testing.T) {
+	start := time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC)
+
+	var log connLog
+	for i := 0; i < connLogSize+10; i++ {
+		event := ConnConnect
+		if i%2 == 1 {
+			event = ConnDisconnect
+		}
+
+		log.add(ConnEvent{
+			Time:  start.Add(time.Duration(i) * time.Second),
+			Event: event,
+			Addr:  fmt.Sprintf("10.0.%d.%d:13414", i%3, i%250),
+		})
+	}
+	log.add(ConnEvent{Time: start.Add(time.Hour), Event: ConnBan, Addr: "10.0.0.0/24"})
+
+	all := log.query(ConnLogQuery{})
+	if len(all) != connLogSize || all[0].Event != ConnBan || !all[len(all)-1].Time.Equal(start.Add(11*time.Second)) {
+		t.Fatalf("log of %d events was %v ... %v", len(all), all[0], all[len(all)-1])
+	}
+
+	for _, test := range []struct {
+		query    ConnLogQuery
+		expected int
+	}{
+		{ConnLogQuery{Limit: 5}, 5},
+		{ConnLogQuery{Event: ConnBan}, 1},
+		{ConnLogQuery{Event: ConnDisconnect, Limit: 1000}, 500},
+		{ConnLogQuery{Since: start.Add(time.Duration(connLogSize) * time.Second)}, 11},
+		{ConnLogQuery{Addr: "10.0.0.0/24"}, 334},
+		{ConnLogQuery{Addr: "10.0.1.0/24"}, 333},
+		{ConnLogQuery{Addr: "10.0.1.1"}, 1},
+		{ConnLogQuery{Addr: "10.0.1.1:13414"}, 1},
+		{ConnLogQuery{Addr: "10.0.1.1:1"}, 0},
+	} {
+		if events := log.query(test.query); len(events) != test.expected {
+			t.Errorf("%+v: %d events, want %d", test.query, len(events), test.expected)
+		}
+	}
+}
+
+func TestConnLogPool(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client := dialInbound(t, ln, consensus.ProtocolVersion)
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pp.acceptPeer(conn); err != nil {
+		t.Fatalf("failed to accept peer: %v", err)
+	}
+
+	addr := conn.RemoteAddr().String()
+	pp.Ban(addr)
+	pp.Ban("10.0.0.1:13414")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pp.ConnLog(ConnLogQuery{Addr: "127.0.0.1"})) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("connection events were %+v", pp.ConnLog(ConnLogQuery{}))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	events := pp.ConnLog(ConnLogQuery{Addr: "127.0.0.1"})
+	for i, event := range []string{ConnDisconnect, ConnBan, ConnConnect} {
+		if events[i].Event != event || events[i].Addr != addr || !events[i].Inbound || events[i].UserAgent != "test" {
+			t.Errorf("event %d was %+v, want %s", i, events[i], event)
+		}
+	}
+
+	if events[0].Reason != "banned" || events[0].BytesSent == 0 {
+		t.Errorf("disconnect event was %+v", events[0])
+	}
+
+	if events := pp.ConnLog(ConnLogQuery{Addr: "10.0.0.1:13414"}); len(events) != 1 || events[0].Event != ConnBan || events[0].Duration != 0 {
+		t.Errorf("ban of the unknown peer was %+v", events)
+	}
+}
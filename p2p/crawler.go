@@ -0,0 +1,283 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCrawlTimeout is the deadline of the probe of the node
+	DefaultCrawlTimeout = 10 * time.Second
+	// DefaultCrawlWorkers is the count of the nodes probed at once
+	DefaultCrawlWorkers = 32
+	// DefaultCrawlLimit is the max count of the probed nodes
+	DefaultCrawlLimit = 10000
+)
+
+var errOtherChain = errors.New("peer is on the other chain")
+
+// CrawledNode is the probe result of the node addr: the handshake info, the
+// height & the ping latency of the reachable node and the addrs it knows
+type CrawledNode struct {
+	Addr      string    `json:"addr"`
+	Reachable bool      `json:"reachable"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+
+	Version         uint32                 `json:"version,omitempty"`
+	Capabilities    consensus.Capabilities `json:"capabilities,omitempty"`
+	UserAgent       string                 `json:"user_agent,omitempty"`
+	TotalDifficulty consensus.Difficulty   `json:"total_difficulty,omitempty"`
+	Height          uint64                 `json:"height,omitempty"`
+	// Latency is the ping round trip, zero if the node didn't answer
+	Latency time.Duration `json:"latency,omitempty"`
+	// Peers is the addrs advertised by the node, the edges of the topology
+	Peers []string `json:"peers,omitempty"`
+}
+
+// NetworkSnapshot is the topology of the crawled network
+type NetworkSnapshot struct {
+	Time      time.Time     `json:"time"`
+	Duration  time.Duration `json:"duration"`
+	Reachable int           `json:"reachable"`
+	// Nodes is the probed addrs by addr
+	Nodes []CrawledNode `json:"nodes"`
+}
+
+// Crawler handshakes the known addrs of the network & asks them for their
+// peers without staying connected, the advertised addrs are crawled in turn
+type Crawler struct {
+	genesis consensus.Hash
+
+	// Timeout is the deadline of the probe, Workers the count of the nodes
+	// probed at once & Limit the max count of the probed nodes
+	Timeout time.Duration
+	Workers int
+	Limit   int
+}
+
+// NewCrawler returns the crawler of the network of the params & the genesis
+func NewCrawler(params *consensus.Params, genesis consensus.Hash) *Crawler {
+	magicCode = params.MagicCode
+
+	return &Crawler{
+		genesis: genesis,
+		Timeout: DefaultCrawlTimeout,
+		Workers: DefaultCrawlWorkers,
+		Limit:   DefaultCrawlLimit,
+	}
+}
+
+// Crawl probes the seeds & the addrs they advertise until all known addrs
+// are probed, the Limit is reached or ctx is done
+func (c *Crawler) Crawl(ctx context.Context, seeds []string) *NetworkSnapshot {
+	snapshot := &NetworkSnapshot{Time: time.Now()}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		nodes   []CrawledNode
+		queue   []string
+		seen    = make(map[string]bool)
+		running int
+	)
+
+	// push queues the addrs not seen yet up to the limit
+	push := func(addrs []string) {
+		for _, addr := range addrs {
+			if !seen[addr] && len(seen) < c.Limit {
+				seen[addr] = true
+				queue = append(queue, addr)
+			}
+		}
+	}
+	push(seeds)
+
+	sem := make(chan struct{}, c.Workers)
+	ready := make(chan struct{}, 1)
+	for {
+		mu.Lock()
+		if ctx.Err() != nil || (len(queue) == 0 && running == 0) {
+			mu.Unlock()
+			break
+		}
+
+		if len(queue) == 0 {
+			mu.Unlock()
+			<-ready
+			continue
+		}
+
+		addr := queue[0]
+		queue = queue[1:]
+		running++
+		mu.Unlock()
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			running--
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			node := c.probe(ctx, addr)
+			<-sem
+
+			mu.Lock()
+			nodes = append(nodes, node)
+			push(node.Peers)
+			running--
+			mu.Unlock()
+
+			select {
+			case ready <- struct{}{}:
+			default:
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Addr < nodes[j].Addr })
+	for i := range nodes {
+		if nodes[i].Reachable {
+			snapshot.Reachable++
+		}
+	}
+	snapshot.Nodes = nodes
+	snapshot.Duration = time.Since(snapshot.Time)
+
+	return snapshot
+}
+
+// probe handshakes the addr, pings it & requests its peers, the connection
+// is closed then
+func (c *Crawler) probe(ctx context.Context, addr string) CrawledNode {
+	node := CrawledNode{Addr: addr, Time: time.Now()}
+
+	dialer := net.Dialer{Timeout: c.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		node.Error = err.Error()
+		return node
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+
+	if err := c.handshake(conn, &node); err != nil {
+		node.Error = err.Error()
+		return node
+	}
+	node.Reachable = true
+
+	// the failed ping or peer request leaves the handshake info
+	if err := c.request(conn, &node); err != nil {
+		node.Error = err.Error()
+	}
+
+	return node
+}
+
+// handshake sends the hand of no listen port, so the node doesn't add the
+// crawler to its peers, & reads the shake
+func (c *Crawler) handshake(conn net.Conn, node *CrawledNode) error {
+	receiver, _ := conn.RemoteAddr().(*net.TCPAddr)
+	if receiver == nil {
+		receiver = &net.TCPAddr{IP: net.IPv4zero}
+	}
+
+	msg := hand{
+		Version:         consensus.ProtocolVersion,
+		Capabilities:    consensus.CapUnknown,
+		Nonce:           rand.Uint64(),
+		TotalDifficulty: consensus.Difficulty(1),
+		SenderAddr:      &net.TCPAddr{IP: net.IPv4zero},
+		ReceiverAddr:    receiver,
+		UserAgent:       UserAgent,
+		Genesis:         c.genesis,
+	}
+	if _, err := WriteMessage(conn, &msg); err != nil {
+		return err
+	}
+
+	var sh shake
+	if _, err := ReadMessage(conn, &sh); err != nil {
+		return err
+	}
+
+	node.Version = sh.Version
+	node.Capabilities = sh.Capabilities
+	node.UserAgent = sh.UserAgent
+	node.TotalDifficulty = sh.TotalDifficulty
+
+	if sh.Genesis != c.genesis {
+		return errOtherChain
+	}
+
+	return nil
+}
+
+// request pings the node & requests its peers, the other messages are
+// skipped until both are answered
+func (c *Crawler) request(conn net.Conn, node *CrawledNode) error {
+	sent := time.Now()
+	if _, err := WriteMessage(conn, &Ping{TotalDifficulty: consensus.Difficulty(1)}); err != nil {
+		return err
+	}
+	if _, err := WriteMessage(conn, &GetPeerAddrs{Capabilities: consensus.CapUnknown}); err != nil {
+		return err
+	}
+
+	pong, peers := false, false
+	for !pong || !peers {
+		var header Header
+		if err := header.Read(conn); err != nil {
+			return err
+		}
+		body := io.LimitReader(conn, int64(header.Len))
+
+		switch header.Type {
+		case consensus.MsgTypePong:
+			var msg Pong
+			if err := msg.Read(body); err != nil {
+				return err
+			}
+			node.Latency = time.Since(sent)
+			node.Height = msg.Height
+			node.TotalDifficulty = msg.TotalDifficulty
+			pong = true
+
+		case consensus.MsgTypePeerAddrs:
+			var msg PeerAddrs
+			if err := msg.Read(body); err != nil {
+				return err
+			}
+			for _, addr := range msg.peers {
+				node.Peers = append(node.Peers, addr.String())
+			}
+			peers = true
+		}
+
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
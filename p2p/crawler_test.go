@@ -0,0 +1,106 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// crawledNode serves the handshake of the genesis, the pong of the height &
+// the peer addrs on the listener until it's closed
+func crawledNode(t *testing.T, genesis consensus.Hash, height uint64, peers []*net.TCPAddr) net.Listener {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				var h hand
+				if _, err := ReadMessage(conn, &h); err != nil || h.SenderAddr.Port != 0 {
+					t.Errorf("invalid crawler hand %+v: %v", h, err)
+					return
+				}
+				WriteMessage(conn, &shake{Version: consensus.ProtocolVersion, Capabilities: consensus.CapFullNode,
+					UserAgent: "MW/Grin 1.0.0", Genesis: genesis})
+
+				// the unrequested message is skipped by the crawler
+				WriteMessage(conn, &GetPeerAddrs{})
+				for {
+					var header Header
+					if err := header.Read(conn); err != nil {
+						return
+					}
+					io.CopyN(ioutil.Discard, conn, int64(header.Len))
+
+					switch header.Type {
+					case consensus.MsgTypePing:
+						WriteMessage(conn, &Pong{Ping{TotalDifficulty: 1000, Height: height}})
+					case consensus.MsgTypeGetPeerAddrs:
+						WriteMessage(conn, &PeerAddrs{peers: peers})
+					}
+				}
+			}()
+		}
+	}()
+
+	return lis
+}
+
+func TestCrawl(t *testing.T) {
+	genesis := chain.Testnet4.Hash()
+
+	// the closed listener addr is unreachable
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed.Close()
+	other := crawledNode(t, consensus.Hash{1}, 5, nil)
+	defer other.Close()
+
+	leaf := crawledNode(t, genesis, 10, []*net.TCPAddr{closed.Addr().(*net.TCPAddr), other.Addr().(*net.TCPAddr)})
+	defer leaf.Close()
+	seed := crawledNode(t, genesis, 11, []*net.TCPAddr{leaf.Addr().(*net.TCPAddr)})
+	defer seed.Close()
+
+	snapshot := NewCrawler(&consensus.TestnetParams, genesis).Crawl(context.Background(), []string{seed.Addr().String()})
+	if len(snapshot.Nodes) != 4 || snapshot.Reachable != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+
+	nodes := make(map[string]CrawledNode)
+	for _, node := range snapshot.Nodes {
+		nodes[node.Addr] = node
+	}
+
+	if node := nodes[seed.Addr().String()]; !node.Reachable || node.Height != 11 || node.UserAgent != "MW/Grin 1.0.0" ||
+		node.Latency <= 0 || len(node.Peers) != 1 || node.Peers[0] != leaf.Addr().String() {
+		t.Errorf("unexpected seed node: %+v", node)
+	}
+
+	if node := nodes[leaf.Addr().String()]; !node.Reachable || node.Height != 10 || len(node.Peers) != 2 {
+		t.Errorf("unexpected leaf node: %+v", node)
+	}
+
+	if node := nodes[other.Addr().String()]; node.Reachable || node.Error != errOtherChain.Error() {
+		t.Errorf("node of the other chain was %+v", node)
+	}
+
+	if node := nodes[closed.Addr().String()]; node.Reachable || node.Error == "" {
+		t.Errorf("closed node was %+v", node)
+	}
+}
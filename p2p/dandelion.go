@@ -0,0 +1,202 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DandelionConfig is the relay settings of the stem transactions: the node
+// is the stem relay or the fluff point by the epoch. The stem relay forwards
+// the stem transactions to a random peer, the fluff point aggregates them &
+// fluffs the aggregated transaction
+type DandelionConfig struct {
+	// Epoch is the interval the node keeps the stem or the fluff mode for
+	Epoch time.Duration
+	// StemProbability is the percent probability of the stem epoch
+	StemProbability int
+	// Aggregation is the patience interval the stem transactions of the
+	// fluff epoch are collected for before aggregated
+	Aggregation time.Duration
+	// Embargo is the max stem time, the forwarded stem transaction still
+	// missing in the pool after it is fluffed by the node
+	Embargo time.Duration
+}
+
+// DefaultDandelionConfig is the Dandelion settings of grin
+var DefaultDandelionConfig = DandelionConfig{
+	Epoch:           10 * time.Minute,
+	StemProbability: 90,
+	Aggregation:     30 * time.Second,
+	Embargo:         3 * time.Minute,
+}
+
+// dandelion is the Dandelion relay state
+type dandelion struct {
+	sync.Mutex
+	config DandelionConfig
+
+	// stem is the mode of the epoch ending at epochEnd
+	stem     bool
+	epochEnd time.Time
+
+	// stempool is the stem transactions of the fluff epochs waiting for the
+	// aggregation
+	stempool []*consensus.Transaction
+	// embargo is the fluff deadlines of the forwarded stem transactions
+	embargo map[*consensus.Transaction]time.Time
+
+	// validate checks the stem transaction by the consensus rules, its
+	// inputs are checked by the fluff point pool
+	validate func(ctx context.Context, tx *consensus.Transaction) error
+}
+
+// SetDandelion relays the stem transactions by the Dandelion config,
+// otherwise the node is the fluff point of every stem transaction. Must be
+// called before Run
+func (s *Syncer) SetDandelion(config DandelionConfig) {
+	s.dandelion = &dandelion{
+		config:   config,
+		embargo:  make(map[*consensus.Transaction]time.Time),
+		validate: func(ctx context.Context, tx *consensus.Transaction) error { return tx.Validate(ctx) },
+	}
+}
+
+// stemEpoch returns true if the epoch of now is the stem one, the mode of
+// the new epoch is random by the stem probability
+func (d *dandelion) stemEpoch(now time.Time) bool {
+	if !now.Before(d.epochEnd) {
+		d.stem = rand.Intn(100) < d.config.StemProbability
+		d.epochEnd = now.Add(d.config.Epoch)
+	}
+
+	return d.stem
+}
+
+// processStemTx forwards the stem transaction to a random peer in the stem
+// epoch or collects it for the aggregation in the fluff one
+func (s *Syncer) processStemTx(ctx context.Context, peer *Peer, tx *consensus.Transaction) {
+	d := s.dandelion
+	if err := d.validate(ctx, tx); err != nil {
+		if misbehaving(err) {
+			s.Pool.Ban(peer.banAddr())
+		}
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	now := time.Now()
+	if !d.stemEpoch(now) {
+		d.stempool = append(d.stempool, tx)
+		return
+	}
+
+	d.embargo[tx] = now.Add(d.config.Embargo)
+	s.Pool.PropagateTx(tx, false)
+}
+
+// runDandelion fluffs the aggregated stem transactions & the embargoed ones
+// every aggregation interval until Stop
+func (s *Syncer) runDandelion() {
+	ticker := time.NewTicker(s.dandelion.config.Aggregation)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			s.fluffStempool()
+			s.fluffEmbargoed(now)
+		}
+	}
+}
+
+// fluffStempool aggregates the collected stem transactions & fluffs the
+// aggregated one, the transactions are fluffed one by one if it's rejected
+func (s *Syncer) fluffStempool() {
+	d := s.dandelion
+	d.Lock()
+	txs := d.stempool
+	d.stempool = nil
+	d.Unlock()
+
+	if len(txs) == 0 {
+		return
+	}
+
+	if len(txs) > 1 {
+		aggregated := consensus.Aggregate(txs...)
+		err := s.fluff(aggregated)
+		if err == nil {
+			s.log.Debugf("fluffed %d aggregated stem transactions", len(txs))
+			return
+		}
+		s.log.Debugf("aggregated stem transaction rejected: %v", err)
+	}
+
+	for _, tx := range txs {
+		if err := s.fluff(tx); err != nil {
+			s.log.Debugf("stem transaction rejected: %v", err)
+		}
+	}
+}
+
+// fluffEmbargoed fluffs the forwarded stem transactions of the passed
+// embargo, the ones of the kernels in the pool are fluffed by the peers
+func (s *Syncer) fluffEmbargoed(now time.Time) {
+	d := s.dandelion
+	d.Lock()
+	var txs []*consensus.Transaction
+	for tx, deadline := range d.embargo {
+		if now.After(deadline) {
+			delete(d.embargo, tx)
+			txs = append(txs, tx)
+		}
+	}
+	d.Unlock()
+
+	if len(txs) == 0 {
+		return
+	}
+
+	pooled := make(map[consensus.Hash]bool)
+	for _, tx := range s.Mempool.Transactions() {
+		for i := range tx.Kernels {
+			pooled[tx.Kernels[i].Hash()] = true
+		}
+	}
+
+	for _, tx := range txs {
+		if len(tx.Kernels) == 0 || pooled[tx.Kernels[0].Hash()] {
+			continue
+		}
+
+		s.log.Debugf("stem transaction embargo expired, fluffing %s", tx.Hash())
+		if err := s.fluff(tx); err != nil {
+			s.log.Debugf("embargoed stem transaction rejected: %v", err)
+		}
+	}
+}
+
+// fluff adds the transaction to the pool & relays it to the connected peers
+func (s *Syncer) fluff(tx *consensus.Transaction) error {
+	ctx, cancel := context.WithTimeout(s.ctx, ProcessTimeout)
+	defer cancel()
+
+	if err := s.Mempool.ProcessTx(ctx, tx); err != nil {
+		return err
+	}
+
+	s.Pool.PropagateTx(tx, true)
+	return nil
+}
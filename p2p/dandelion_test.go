@@ -0,0 +1,105 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+	"time"
+)
+
+// testDandelionSyncer returns the syncer of the Dandelion stem probability &
+// the connected peer, the stem transactions are valid
+func testDandelionSyncer(stemProbability int, pooled ...*consensus.Transaction) (*Syncer, *Peer) {
+	s, peer := testTxPoolSyncer(consensus.CapTxPool, pooled...)
+
+	config := DefaultDandelionConfig
+	config.StemProbability = stemProbability
+	s.SetDandelion(config)
+	s.dandelion.validate = func(context.Context, *consensus.Transaction) error { return nil }
+
+	return s, peer
+}
+
+func TestDandelionAggregation(t *testing.T) {
+	s, peer := testDandelionSyncer(0)
+	pool := s.Pool.(*mockPool)
+	mempool := s.Mempool.(*mockMempool)
+
+	s.ProcessMessage(peer, &StemTransaction{Transaction: *testTx(1)})
+	s.ProcessMessage(peer, &StemTransaction{Transaction: *testTx(2, 3)})
+
+	if len(pool.txs)+len(pool.stems)+len(mempool.txs) != 0 {
+		t.Fatalf("stem transactions were relayed before the aggregation")
+	}
+
+	s.fluffStempool()
+	if len(mempool.txs) != 1 || len(pool.txs) != 1 || len(pool.stems) != 0 {
+		t.Fatalf("%d txs processed, %d fluffed & %d stemmed, want 1, 1 & 0", len(mempool.txs), len(pool.txs), len(pool.stems))
+	}
+
+	if kernels := len(pool.txs[0].Kernels); kernels != 3 {
+		t.Errorf("aggregated transaction of %d kernels, want 3", kernels)
+	}
+
+	// the empty stempool fluffs nothing
+	s.fluffStempool()
+	if len(pool.txs) != 1 {
+		t.Errorf("%d txs fluffed, want 1", len(pool.txs))
+	}
+}
+
+func TestDandelionEmbargo(t *testing.T) {
+	pooled := testTx(2)
+
+	s, peer := testDandelionSyncer(100, pooled)
+	pool := s.Pool.(*mockPool)
+	mempool := s.Mempool.(*mockMempool)
+
+	// the stem transaction of the pooled kernel is fluffed by the peers
+	s.ProcessMessage(peer, &StemTransaction{Transaction: *testTx(1)})
+	s.ProcessMessage(peer, &StemTransaction{Transaction: *pooled})
+
+	if len(pool.stems) != 2 || len(pool.txs) != 0 {
+		t.Fatalf("%d txs stemmed & %d fluffed, want 2 & 0", len(pool.stems), len(pool.txs))
+	}
+
+	s.fluffEmbargoed(time.Now())
+	if len(pool.txs) != 0 {
+		t.Errorf("%d txs fluffed before the embargo, want 0", len(pool.txs))
+	}
+
+	s.fluffEmbargoed(time.Now().Add(DefaultDandelionConfig.Embargo + time.Second))
+	if len(mempool.txs) != 1 || len(pool.txs) != 1 || pool.txs[0] != pool.stems[0] {
+		t.Errorf("%d txs processed & %d fluffed after the embargo, want 1 & 1", len(mempool.txs), len(pool.txs))
+	}
+
+	if len(s.dandelion.embargo) != 0 {
+		t.Errorf("%d txs embargoed, want 0", len(s.dandelion.embargo))
+	}
+}
+
+func TestDandelionEpoch(t *testing.T) {
+	for _, probability := range []int{0, 100} {
+		s, _ := testDandelionSyncer(probability)
+		d := s.dandelion
+
+		now := time.Now()
+		if stem := d.stemEpoch(now); stem != (probability == 100) {
+			t.Errorf("%d%%: stem epoch was %v", probability, stem)
+		}
+
+		// the mode is kept for the epoch
+		d.stem = !d.stem
+		if stem := d.stemEpoch(now.Add(d.config.Epoch - time.Second)); stem == (probability == 100) {
+			t.Errorf("%d%%: stem mode was changed within the epoch", probability)
+		}
+
+		if stem := d.stemEpoch(now.Add(d.config.Epoch)); stem != (probability == 100) {
+			t.Errorf("%d%%: stem mode of the next epoch was %v", probability, stem)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"io"
+	"sort"
+)
+
+// messageNames is the names of the decodable message types
+var messageNames = map[uint8]string{
+	consensus.MsgTypeError:           "error",
+	consensus.MsgTypeHand:            "hand",
+	consensus.MsgTypeShake:           "shake",
+	consensus.MsgTypePing:            "ping",
+	consensus.MsgTypePong:            "pong",
+	consensus.MsgTypeGetPeerAddrs:    "get_peer_addrs",
+	consensus.MsgTypePeerAddrs:       "peer_addrs",
+	consensus.MsgTypeGetHeaders:      "get_headers",
+	consensus.MsgTypeHeader:          "header",
+	consensus.MsgTypeHeaders:         "headers",
+	consensus.MsgTypeGetBlock:        "get_block",
+	consensus.MsgTypeBlock:           "block",
+	consensus.MsgTypeGetCompactBlock: "get_compact_block",
+	consensus.MsgTypeCompactBlock:    "compact_block",
+	consensus.MsgTypeStemTransaction: "stem_transaction",
+	consensus.MsgTypeTransaction:     "transaction",
+	consensus.MsgTypePoolKernels:     "pool_kernels",
+	consensus.MsgTypeGetPoolTxs:      "get_pool_txs",
+}
+
+// MessageNames returns the sorted names of the decodable message types
+func MessageNames() []string {
+	names := make([]string, 0, len(messageNames))
+	for _, name := range messageNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// MessageTypeName returns the name of the message type, the number of the
+// unknown one
+func MessageTypeName(msgType uint8) string {
+	if name, ok := messageNames[msgType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown(%d)", msgType)
+}
+
+// ParseMessageType returns the message type of the name, e.g. block
+func ParseMessageType(name string) (uint8, error) {
+	for msgType, n := range messageNames {
+		if n == name {
+			return msgType, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown message type: %s", name)
+}
+
+// NewMessage returns the empty message of the type to read, nil if the type
+// isn't decodable
+func NewMessage(msgType uint8) Message {
+	switch msgType {
+	case consensus.MsgTypeError:
+		return new(PeerError)
+	case consensus.MsgTypeHand:
+		return new(hand)
+	case consensus.MsgTypeShake:
+		return new(shake)
+	case consensus.MsgTypePing:
+		return new(Ping)
+	case consensus.MsgTypePong:
+		return new(Pong)
+	case consensus.MsgTypeGetPeerAddrs:
+		return new(GetPeerAddrs)
+	case consensus.MsgTypePeerAddrs:
+		return new(PeerAddrs)
+	case consensus.MsgTypeGetHeaders:
+		return new(GetBlockHeaders)
+	case consensus.MsgTypeHeader:
+		return new(BlockHeader)
+	case consensus.MsgTypeHeaders:
+		return new(BlockHeaders)
+	case consensus.MsgTypeGetBlock:
+		return new(GetBlock)
+	case consensus.MsgTypeBlock:
+		return new(consensus.Block)
+	case consensus.MsgTypeGetCompactBlock:
+		return new(GetCompactBlock)
+	case consensus.MsgTypeCompactBlock:
+		return new(consensus.CompactBlock)
+	case consensus.MsgTypeStemTransaction:
+		return new(StemTransaction)
+	case consensus.MsgTypeTransaction:
+		return new(consensus.Transaction)
+	case consensus.MsgTypePoolKernels:
+		return new(PoolKernels)
+	case consensus.MsgTypeGetPoolTxs:
+		return new(GetPoolTxs)
+	}
+
+	return nil
+}
+
+// DecodeMessage decodes the message of the header & the body of the network
+// of the params, as captured off the wire. The block isn't validated
+func DecodeMessage(data []byte, params *consensus.Params) (*Header, Message, error) {
+	magicCode = params.MagicCode
+
+	r := bytes.NewReader(data)
+	var header Header
+	if err := header.Read(r); err != nil {
+		return nil, nil, err
+	}
+
+	if uint64(r.Len()) < header.Len {
+		return &header, nil, fmt.Errorf("%s message of %d bytes is truncated to %d", MessageTypeName(header.Type), header.Len, r.Len())
+	}
+
+	body := data[len(data)-r.Len():][:header.Len]
+	msg, err := DecodeBody(header.Type, body)
+	if err != nil {
+		return &header, nil, err
+	}
+
+	if rest := r.Len() - int(header.Len); rest > 0 {
+		return &header, msg, fmt.Errorf("%d bytes after the %s message", rest, MessageTypeName(header.Type))
+	}
+
+	return &header, msg, nil
+}
+
+// DecodeBody decodes the message body of the type, the body must be read
+// entirely
+func DecodeBody(msgType uint8, body []byte) (Message, error) {
+	msg := NewMessage(msgType)
+	if msg == nil {
+		return nil, fmt.Errorf("message type %s isn't decodable", MessageTypeName(msgType))
+	}
+
+	r := bytes.NewReader(body)
+	if err := msg.Read(r); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("invalid %s message: %v", MessageTypeName(msgType), err)
+	}
+
+	if r.Len() > 0 {
+		return msg, fmt.Errorf("%d bytes of the %s message body are not read", r.Len(), MessageTypeName(msgType))
+	}
+
+	return msg, nil
+}
+
+// MarshalJSON implements json.Marshaler interface, the addrs are host:port
+func (p *PeerAddrs) MarshalJSON() ([]byte, error) {
+	peers := make([]string, len(p.peers))
+	for i, addr := range p.peers {
+		peers[i] = addr.String()
+	}
+
+	return json.Marshal(struct {
+		Peers []string
+	}{peers})
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"sync"
+	"time"
+)
+
+// forkCheckInterval is the period of the fork alarm checks
+const forkCheckInterval = 10 * time.Second
+
+// ForkAlarmConfig is the condition of the fork alarm: PeersPercent of the
+// connected peers report the total difficulty ahead of the chain by more than
+// the work of Blocks head blocks for Duration after the initial block
+// download. The chain is likely on the minority fork or stalled
+type ForkAlarmConfig struct {
+	// PeersPercent is the percent of the connected peers ahead, 0 disables
+	// the alarm
+	PeersPercent int
+	Blocks       uint64
+	Duration     time.Duration
+}
+
+// ForkAlarm is the state of the fork alarm
+type ForkAlarm struct {
+	Raised bool
+	// Since is the time the peers are ahead since, zero if they aren't
+	Since time.Time
+	// PeersAhead of the Peers connected at the last check
+	PeersAhead int
+	Peers      int
+}
+
+// forkAlarm is the fork alarm config & state
+type forkAlarm struct {
+	sync.Mutex
+	config ForkAlarmConfig
+	state  ForkAlarm
+}
+
+// SetForkAlarm sets the condition of the fork alarm, the alarm is logged,
+// exported by the metrics & flagged by the sync status. Must be called
+// before Run
+func (s *Syncer) SetForkAlarm(config ForkAlarmConfig) {
+	s.forkAlarm.config = config
+}
+
+// ForkAlarm returns the state of the fork alarm of the last check
+func (s *Syncer) ForkAlarm() ForkAlarm {
+	s.forkAlarm.Lock()
+	defer s.forkAlarm.Unlock()
+
+	return s.forkAlarm.state
+}
+
+// runForkAlarm checks the fork alarm every forkCheckInterval until Stop
+func (s *Syncer) runForkAlarm() {
+	ticker := time.NewTicker(forkCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.checkFork(now)
+		}
+	}
+}
+
+// checkFork counts the connected peers ahead of the chain, the alarm is
+// raised once they are ahead for the config duration & cleared once they
+// aren't. The alarm isn't checked during the initial block download
+func (s *Syncer) checkFork(now time.Time) {
+	a := &s.forkAlarm
+	peers := s.Pool.Connected()
+	ibd := s.InitialBlockDownload()
+
+	// the lead is the work of the config blocks of the head difficulty
+	header := s.Chain.HeaderHead()
+	threshold := s.Chain.TotalDifficulty().Add(header.POW.ToDifficulty().MulDiv(a.config.Blocks, 1))
+
+	ahead := 0
+	for _, peer := range peers {
+		if peer.TotalDifficulty > threshold {
+			ahead++
+		}
+	}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.state.PeersAhead, a.state.Peers = ahead, len(peers)
+
+	if len(peers) == 0 || ahead*100 < a.config.PeersPercent*len(peers) || ibd {
+		if a.state.Raised {
+			s.log.WithFields(logging.Fields{"ahead": ahead, "peers": len(peers)}).Info("fork alarm cleared")
+		}
+		a.state.Raised = false
+		a.state.Since = time.Time{}
+		return
+	}
+
+	if a.state.Since.IsZero() {
+		a.state.Since = now
+	}
+
+	if !a.state.Raised && now.Sub(a.state.Since) >= a.config.Duration {
+		a.state.Raised = true
+		s.log.WithFields(logging.Fields{
+			"height": s.Chain.Height(),
+			"ahead":  ahead,
+			"peers":  len(peers),
+			"since":  a.state.Since,
+		}).Warn("most peers are ahead of the chain, it may be on a minority fork or stalled")
+	}
+}
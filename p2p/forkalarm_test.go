@@ -0,0 +1,87 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"testing"
+	"time"
+)
+
+func TestForkAlarm(t *testing.T) {
+	chain := &stubChain{height: 90, totalDifficulty: 90}
+	sync := NewSyncer(nil, chain, nil)
+	sync.SetLogger(logging.Nop)
+	sync.SetForkAlarm(ForkAlarmConfig{PeersPercent: 50, Duration: time.Minute})
+	pp := sync.Pool.(*peersPool)
+
+	ahead := &peerInfo{totalDifficulty: 200, status: psConnected}
+	ahead.SetChain(200, 200)
+	pp.ConnectedPeers["127.0.0.1:13414"] = ahead
+
+	// the alarm isn't checked during the initial block download
+	now := time.Now()
+	sync.checkFork(now)
+	if alarm := sync.ForkAlarm(); alarm.Raised || !alarm.Since.IsZero() {
+		t.Errorf("alarm was %+v during ibd", alarm)
+	}
+
+	ahead.SetChain(200, 90+DefaultIBDDistance)
+	sync.InitialBlockDownload()
+	ahead.SetChain(200, 200)
+
+	behind := &peerInfo{totalDifficulty: 90, status: psConnected}
+	pp.ConnectedPeers["127.0.0.1:13415"] = behind
+
+	sync.checkFork(now)
+	if alarm := sync.ForkAlarm(); alarm.Raised || alarm.Since != now || alarm.PeersAhead != 1 || alarm.Peers != 2 {
+		t.Errorf("alarm was %+v, want pending of 1/2 peers", alarm)
+	}
+
+	sync.checkFork(now.Add(time.Minute - time.Second))
+	if sync.ForkAlarm().Raised {
+		t.Error("alarm was raised before the duration")
+	}
+
+	sync.checkFork(now.Add(time.Minute))
+	if !sync.Status().ForkAlarm {
+		t.Error("alarm wasn't raised after the duration")
+	}
+
+	// the peers ahead are below the percent
+	pp.ConnectedPeers["127.0.0.1:13416"] = &peerInfo{totalDifficulty: 90, status: psConnected}
+	sync.checkFork(now.Add(2 * time.Minute))
+	if alarm := sync.ForkAlarm(); alarm.Raised || !alarm.Since.IsZero() {
+		t.Errorf("alarm was %+v of 1/3 peers ahead", alarm)
+	}
+}
+
+func TestForkAlarmBlocks(t *testing.T) {
+	chain := &stubChain{height: 90, totalDifficulty: 90}
+	sync := NewSyncer(nil, chain, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	header := chain.HeaderHead()
+	lead := header.POW.ToDifficulty().MulDiv(3, 1)
+	sync.SetForkAlarm(ForkAlarmConfig{PeersPercent: 100, Blocks: 3})
+
+	peer := &peerInfo{totalDifficulty: chain.totalDifficulty.Add(lead), status: psConnected}
+	peer.SetChain(peer.totalDifficulty, 90)
+	pp.ConnectedPeers["127.0.0.1:13414"] = peer
+	sync.InitialBlockDownload()
+
+	// the peer of the exact lead isn't ahead
+	sync.checkFork(time.Now())
+	if alarm := sync.ForkAlarm(); alarm.Raised || alarm.PeersAhead != 0 {
+		t.Errorf("alarm was %+v of the peer within the lead", alarm)
+	}
+
+	peer.SetChain(peer.totalDifficulty.Add(1), 93)
+	sync.checkFork(time.Now())
+	if alarm := sync.ForkAlarm(); !alarm.Raised || alarm.PeersAhead != 1 {
+		t.Errorf("alarm was %+v of the peer ahead", alarm)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestGoldenShake ensures the shake message captured from the grin node is
+// read entirely & written with the header to the identical bytes
+func TestGoldenShake(t *testing.T) {
+	message, err := hex.DecodeString(testShake)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg shake
+	if read, err := ReadMessage(bytes.NewReader(message), &msg); err != nil || read != uint64(len(message)) {
+		t.Fatalf("read %d of %d bytes: %v", read, len(message), err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := WriteMessage(&buf, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), message) {
+		t.Errorf("written\n%x\nwant\n%x", buf.Bytes(), message)
+	}
+}
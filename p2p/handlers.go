@@ -0,0 +1,39 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+// MessageHandler is the extension handler of the message received from the
+// peer: the crawlers, the statistics collectors & the research tools. The
+// handlers run on the read loop of the peer before the syncer processes the
+// message, so they must not block or modify the message
+type MessageHandler func(peer *Peer, msg Message)
+
+// Handle registers the handler of the messages of the type, one of
+// consensus.MsgType*, the handlers run in the order of registration. The
+// handshake messages are not handled. Must be called before Run
+func (s *Syncer) Handle(msgType uint8, handler MessageHandler) {
+	if s.handlers == nil {
+		s.handlers = make(map[uint8][]MessageHandler)
+	}
+
+	s.handlers[msgType] = append(s.handlers[msgType], handler)
+}
+
+// Observe registers the handler of all messages, the observers run before
+// the handlers of the message type. Must be called before Run
+func (s *Syncer) Observe(handler MessageHandler) {
+	s.observers = append(s.observers, handler)
+}
+
+// dispatch runs the observers & the handlers of the message type
+func (s *Syncer) dispatch(peer *Peer, msg Message) {
+	for _, handler := range s.observers {
+		handler(peer, msg)
+	}
+
+	for _, handler := range s.handlers[msg.Type()] {
+		handler(peer, msg)
+	}
+}
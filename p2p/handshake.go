@@ -6,15 +6,24 @@ package p2p
 
 import (
 	"bytes"
-	"encoding/binary"
 	"errors"
+	"fmt"
 	"github.com/dblokhin/gringo/chain"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/wire"
 	"io"
+	"io/ioutil"
 	"net"
+	"time"
 )
 
+// ErrUnsupportedVersion is the handshake of the other protocol version, the
+// peer is sent the PeerError of NetUnsupportedVersion before the disconnect
+var ErrUnsupportedVersion = errors.New("unsupported protocol version")
+
+// rejectTimeout is the deadline of the PeerError of the unsupported version
+const rejectTimeout = 5 * time.Second
+
 // First part of a handshake, sender advertises its version and
 // characteristics.
 type hand struct {
@@ -37,42 +46,26 @@ type hand struct {
 
 	// name of version of the software
 	UserAgent string
+
+	// NodeID is the identity of the sender of CapNodeID
+	NodeID NodeID
 }
 
 func (h *hand) Bytes() []byte {
-	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, h.Version); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint32(h.Capabilities)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, h.Nonce); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(h.TotalDifficulty)); err != nil {
-		logrus.Fatal(err)
-	}
-
 	if (h.SenderAddr == nil) || (h.ReceiverAddr == nil) {
-		logrus.Fatal("invalid netaddr (SenderAddr/ReceiverAddr)")
+		panic("invalid netaddr (SenderAddr/ReceiverAddr)")
 	}
 
-	// Write Sender addr
-	serializeTCPAddr(buff, h.SenderAddr)
-
-	// Write Recv addr
-	serializeTCPAddr(buff, h.ReceiverAddr)
-
-	// Write user agent [len][string]
-	binary.Write(buff, binary.BigEndian, uint64(len(h.UserAgent)))
-	buff.WriteString(h.UserAgent)
-
-	binary.Write(buff, binary.BigEndian, h.Genesis)
+	buff := new(bytes.Buffer)
+	wire.WriteUint32(buff, h.Version)
+	wire.WriteUint32(buff, uint32(h.Capabilities))
+	wire.WriteUint64(buff, h.Nonce)
+	wire.WriteUint64(buff, uint64(h.TotalDifficulty))
+	wire.WriteAddr(buff, h.SenderAddr)
+	wire.WriteAddr(buff, h.ReceiverAddr)
+	wire.WriteString(buff, h.UserAgent)
+	buff.Write(h.Genesis[:])
+	writeNodeID(buff, h.Capabilities, h.NodeID)
 
 	return buff.Bytes()
 }
@@ -82,62 +75,48 @@ func (h *hand) Type() uint8 {
 }
 
 func (h *hand) Read(r io.Reader) error {
-
-	if err := binary.Read(r, binary.BigEndian, &h.Version); err != nil {
+	var err error
+	if h.Version, err = wire.ReadUint32(r); err != nil {
 		return err
 	}
 
 	if h.Version != consensus.ProtocolVersion {
-		return errors.New("incompatibility protocol version")
+		return unsupportedVersion(r, h.Version)
 	}
 
-	if err := binary.Read(r, binary.BigEndian, (*uint32)(&h.Capabilities)); err != nil {
-		return err
-	}
-
-	if err := binary.Read(r, binary.BigEndian, &h.Nonce); err != nil {
+	capabilities, err := wire.ReadUint32(r)
+	if err != nil {
 		return err
 	}
+	h.Capabilities = consensus.Capabilities(capabilities)
 
-	if err := binary.Read(r, binary.BigEndian, (*uint64)(&h.TotalDifficulty)); err != nil {
+	if h.Nonce, err = wire.ReadUint64(r); err != nil {
 		return err
 	}
 
-	// read Sender addr
-	addr, err := deserializeTCPAddr(r)
+	totalDifficulty, err := wire.ReadUint64(r)
 	if err != nil {
 		return err
 	}
-	h.SenderAddr = addr
+	h.TotalDifficulty = consensus.Difficulty(totalDifficulty)
 
-	// read Recv addr
-	addr, err = deserializeTCPAddr(r)
-	if err != nil {
+	if h.SenderAddr, err = wire.ReadAddr(r); err != nil {
 		return err
 	}
-	h.ReceiverAddr = addr
 
-	// read user agent
-	var userAgentLen uint64
-	if err := binary.Read(r, binary.BigEndian, &userAgentLen); err != nil {
+	if h.ReceiverAddr, err = wire.ReadAddr(r); err != nil {
 		return err
 	}
 
-	buff := make([]byte, userAgentLen)
-	if _, err := io.ReadFull(r, buff); err != nil {
+	if h.UserAgent, err = wire.ReadString(r); err != nil {
 		return err
 	}
 
-	h.UserAgent = string(buff)
-
-	genesisBuf := make([]byte, 32)
-	if _, err := io.ReadFull(r, genesisBuf); err != nil {
+	if err := wire.ReadBytes(r, h.Genesis[:]); err != nil {
 		return err
 	}
 
-	h.Genesis = genesisBuf
-
-	return nil
+	return readNodeID(r, h.Capabilities, &h.NodeID)
 }
 
 // Second part of a handshake, receiver of the first part replies with its own
@@ -157,28 +136,19 @@ type shake struct {
 	// Genesis is the initial block hash on our chain. Used to prevent
 	// connections to distinct chains.
 	Genesis consensus.Hash
+
+	// NodeID is the identity of the sender of CapNodeID
+	NodeID NodeID
 }
 
 func (h *shake) Bytes() []byte {
 	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, h.Version); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint32(h.Capabilities)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(h.TotalDifficulty)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	// Write user agent [len][string]
-	binary.Write(buff, binary.BigEndian, uint64(len(h.UserAgent)))
-	buff.WriteString(h.UserAgent)
-
-	binary.Write(buff, binary.BigEndian, h.Genesis)
+	wire.WriteUint32(buff, h.Version)
+	wire.WriteUint32(buff, uint32(h.Capabilities))
+	wire.WriteUint64(buff, uint64(h.TotalDifficulty))
+	wire.WriteString(buff, h.UserAgent)
+	buff.Write(h.Genesis[:])
+	writeNodeID(buff, h.Capabilities, h.NodeID)
 
 	return buff.Bytes()
 }
@@ -188,65 +158,72 @@ func (h *shake) Type() uint8 {
 }
 
 func (h *shake) Read(r io.Reader) error {
-
-	if err := binary.Read(r, binary.BigEndian, &h.Version); err != nil {
+	var err error
+	if h.Version, err = wire.ReadUint32(r); err != nil {
 		return err
 	}
 
 	if h.Version != consensus.ProtocolVersion {
-		return errors.New("incompatibility protocol version")
+		return unsupportedVersion(r, h.Version)
 	}
 
-	if err := binary.Read(r, binary.BigEndian, (*uint32)(&h.Capabilities)); err != nil {
+	capabilities, err := wire.ReadUint32(r)
+	if err != nil {
 		return err
 	}
+	h.Capabilities = consensus.Capabilities(capabilities)
 
-	if err := binary.Read(r, binary.BigEndian, (*uint64)(&h.TotalDifficulty)); err != nil {
+	totalDifficulty, err := wire.ReadUint64(r)
+	if err != nil {
 		return err
 	}
+	h.TotalDifficulty = consensus.Difficulty(totalDifficulty)
 
-	var userAgentLen uint64
-	if err := binary.Read(r, binary.BigEndian, &userAgentLen); err != nil {
+	if h.UserAgent, err = wire.ReadString(r); err != nil {
 		return err
 	}
 
-	buff := make([]byte, userAgentLen)
-	if _, err := io.ReadFull(r, buff); err != nil {
+	if err := wire.ReadBytes(r, h.Genesis[:]); err != nil {
 		return err
 	}
 
-	h.UserAgent = string(buff)
+	return readNodeID(r, h.Capabilities, &h.NodeID)
+}
 
-	genesisBuf := make([]byte, 32)
-	if _, err := io.ReadFull(r, genesisBuf); err != nil {
-		return err
+// writeNodeID appends the node id to the handshake of CapNodeID, the grin
+// peers don't read past the genesis
+func writeNodeID(buff *bytes.Buffer, capabilities consensus.Capabilities, id NodeID) {
+	if capabilities&consensus.CapNodeID != 0 {
+		buff.Write(id[:])
 	}
-
-	h.Genesis = genesisBuf
-
-	return nil
 }
 
-// shakeByHand sends hand to receive shake
-func shakeByHand(conn net.Conn) (*shake, error) {
-	// create hand
-	// TODO: use the server listen addr
-	sender, err := net.ResolveTCPAddr("tcp", "0.0.0.0:0")
-	if err != nil {
-		logrus.Fatal(err)
+// readNodeID reads the node id of the handshake of CapNodeID
+func readNodeID(r io.Reader, capabilities consensus.Capabilities, id *NodeID) error {
+	if capabilities&consensus.CapNodeID == 0 {
+		return nil
 	}
 
+	return wire.ReadBytes(r, id[:])
+}
+
+// shakeByHand sends hand of the sync capabilities with the next of its nonces
+// to receive shake, the sender addr advertises the listen port
+func shakeByHand(conn net.Conn, sync *Syncer) (*shake, error) {
+	// create hand, the peer knows our IP
+	sender := &net.TCPAddr{IP: net.IPv4zero, Port: int(sync.listenPort)}
 	receiver := conn.RemoteAddr().(*net.TCPAddr)
 
 	msg := hand{
 		Version:         consensus.ProtocolVersion,
-		Capabilities:    consensus.CapFullNode,
-		Nonce:           serverNonces.NextNonce(),
+		Capabilities:    sync.capabilities(),
+		Nonce:           sync.nextNonce(),
 		TotalDifficulty: consensus.Difficulty(1),
 		SenderAddr:      sender,
 		ReceiverAddr:    receiver,
-		UserAgent:       userAgent,
+		UserAgent:       UserAgent,
 		Genesis:         chain.Testnet4.Hash(),
+		NodeID:          sync.NodeID(),
 	}
 
 	// Send own hand
@@ -257,33 +234,38 @@ func shakeByHand(conn net.Conn) (*shake, error) {
 	// Read peer shake
 	sh := new(shake)
 	if _, err := ReadMessage(conn, sh); err != nil {
+		rejectVersion(conn, err)
 		return nil, err
 	}
 
 	return sh, nil
 }
 
-// handByShake sends shake and return received hand
-func handByShake(conn net.Conn) (*hand, error) {
+// handByShake sends shake of the sync capabilities and return received hand,
+// the hand of our own nonces is rejected
+func handByShake(conn net.Conn, sync *Syncer) (*hand, error) {
 
 	var h hand
 
 	// Recv remote hand
 	if _, err := ReadMessage(conn, &h); err != nil {
+		rejectVersion(conn, err)
 		return nil, err
 	}
 
 	// Check nonce to detect connection to ourselves
-	if serverNonces.Consist(h.Nonce) {
+	if sync.nonces.Has(h.Nonce) {
 		return &h, errors.New("detect connection to ourselves by nonce")
 	}
 
 	// Send shake
 	msg := shake{
 		Version:         consensus.ProtocolVersion,
-		Capabilities:    consensus.CapFullNode,
+		Capabilities:    sync.capabilities(),
 		TotalDifficulty: consensus.Difficulty(1),
-		UserAgent:       userAgent,
+		UserAgent:       UserAgent,
+		Genesis:         chain.Testnet4.Hash(),
+		NodeID:          sync.NodeID(),
 	}
 	if _, err := WriteMessage(conn, &msg); err != nil {
 		return nil, err
@@ -291,3 +273,26 @@ func handByShake(conn net.Conn) (*hand, error) {
 
 	return &h, nil
 }
+
+// unsupportedVersion skips the rest of the handshake of the other version,
+// so the peer writing it reads the PeerError of the version
+func unsupportedVersion(r io.Reader, version uint32) error {
+	io.Copy(ioutil.Discard, r)
+	return fmt.Errorf("%w %d", ErrUnsupportedVersion, version)
+}
+
+// rejectVersion sends the PeerError of NetUnsupportedVersion if the handshake
+// of the peer is of the other protocol version, the peer is disconnected
+// anyway so the write error is ignored
+func rejectVersion(conn net.Conn, err error) {
+	var peerErr *PeerError
+	if !errors.Is(err, ErrUnsupportedVersion) || errors.As(err, &peerErr) {
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(rejectTimeout))
+	WriteMessage(conn, &PeerError{
+		Code:    uint32(consensus.NetUnsupportedVersion),
+		Message: fmt.Sprintf("unsupported protocol version, want %d", consensus.ProtocolVersion),
+	})
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"sync"
+)
+
+// DefaultIBDDistance is the count of blocks behind the best peer the initial
+// block download ends at
+const DefaultIBDDistance = 5
+
+// ibd is the initial block download state, it ends once & is never resumed
+type ibd struct {
+	sync.Mutex
+	done bool
+	// distance is the count of blocks behind the best peer it ends at
+	distance uint64
+}
+
+// SetIBDDistance sets the count of blocks behind the best peer the initial
+// block download ends at. Must be called before Run
+func (s *Syncer) SetIBDDistance(distance uint64) {
+	s.ibd.distance = distance
+}
+
+// InitialBlockDownload returns true until the chain is within the IBD
+// distance of the best connected peer or no peer has more work. The node
+// doesn't relay the transactions & the blocks during it, the headers are
+// synced in batches & the blocks of the batch are requested, the miner
+// shouldn't mine on the stale head
+func (s *Syncer) InitialBlockDownload() bool {
+	s.ibd.Lock()
+	defer s.ibd.Unlock()
+
+	if s.ibd.done {
+		return false
+	}
+
+	peers := s.Pool.Connected()
+	if len(peers) == 0 {
+		return true
+	}
+
+	// the peer heights are unknown until the pings, the total difficulty
+	// is of the handshake
+	totalDifficulty, height := s.Chain.TotalDifficulty(), s.Chain.Height()
+	ahead := false
+	var best uint64
+	for _, peer := range peers {
+		ahead = ahead || peer.TotalDifficulty > totalDifficulty
+		if peer.Height > best {
+			best = peer.Height
+		}
+	}
+
+	if ahead && (best == 0 || height+s.ibd.distance < best) {
+		return true
+	}
+
+	s.ibd.done = true
+	s.log.WithFields(logging.Fields{"height": height}).Info("initial block download is done")
+
+	return false
+}
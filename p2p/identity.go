@@ -0,0 +1,65 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"encoding/hex"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"golang.org/x/crypto/blake2b"
+	"math/big"
+)
+
+// nodeIDLen is the size of the serialized node id
+const nodeIDLen = 33
+
+// NodeID is the compressed public key of the node identity, the peers know
+// the node by it across the addresses & the restarts
+type NodeID [nodeIDLen]byte
+
+// String returns the hex of the id
+func (id NodeID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if the node has no identity
+func (id NodeID) IsZero() bool {
+	return id == NodeID{}
+}
+
+// Verify returns true if sig is the signature of data by the node
+func (id NodeID) Verify(data []byte, sig [64]byte) bool {
+	publicKey, err := secp256k1zkp.DecompressPubkey(id)
+	if err != nil {
+		return false
+	}
+
+	return secp256k1zkp.VerifySignature(*publicKey, blake2b.Sum256(data), secp256k1zkp.DecodeSignature(sig))
+}
+
+// Identity is the keypair of the node identity, it signs the messages of the
+// node verified by its ID
+type Identity struct {
+	ID NodeID
+
+	key       *big.Int
+	publicKey *bulletproofs.Point
+}
+
+// NewIdentity returns the identity of the secret key
+func NewIdentity(key *big.Int) *Identity {
+	publicKey := bulletproofs.ScalarMulPoint(&secp256k1zkp.G, key)
+
+	return &Identity{
+		ID:        secp256k1zkp.CompressPubkey(*publicKey),
+		key:       key,
+		publicKey: publicKey,
+	}
+}
+
+// Sign returns the schnorr signature of the blake2b-256 hash of data
+func (i *Identity) Sign(data []byte) [64]byte {
+	return secp256k1zkp.SignMessage(*i.publicKey, *i.key, blake2b.Sum256(data)).Bytes()
+}
@@ -0,0 +1,35 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"testing"
+)
+
+func TestIdentity(t *testing.T) {
+	identity := NewIdentity(secp256k1zkp.RandomInt())
+	if identity.ID.IsZero() || len(identity.ID.String()) != 2*nodeIDLen {
+		t.Fatalf("node id was %s", identity.ID)
+	}
+
+	sig := identity.Sign([]byte("checkpoint"))
+	if !identity.ID.Verify([]byte("checkpoint"), sig) {
+		t.Error("signature isn't verified")
+	}
+
+	if identity.ID.Verify([]byte("checkpoint2"), sig) {
+		t.Error("signature of the other data is verified")
+	}
+
+	other := NewIdentity(secp256k1zkp.RandomInt())
+	if other.ID.Verify([]byte("checkpoint"), sig) {
+		t.Error("signature of the other node is verified")
+	}
+
+	if (NodeID{}).Verify([]byte("checkpoint"), sig) {
+		t.Error("signature of the zero id is verified")
+	}
+}
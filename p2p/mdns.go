@@ -0,0 +1,275 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// mdnsService is the DNS-SD service of the grin peers
+	mdnsService = "_grin._tcp.local."
+
+	// mdnsInterval is the interval of the peers queries
+	mdnsInterval = time.Minute
+
+	// mdnsTTL is the TTL of the advertised records, seconds
+	mdnsTTL = 120
+
+	// mdnsMaxPacket is the max size of the mDNS packet
+	mdnsMaxPacket = 9000
+)
+
+// mdnsGroup is the mDNS multicast group
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdns advertises the node & discovers the peers of the local network by the
+// DNS-SD records of the grin service: PTR of the service to the instance, SRV
+// of the instance to the p2p port & TXT of the instance to the network magic.
+// The peer addr is the source IP of the response & the SRV port
+type mdns struct {
+	sync *Syncer
+
+	service  dnsmessage.Name
+	instance dnsmessage.Name
+	target   dnsmessage.Name
+
+	// port is the advertised p2p port, 0 doesn't advertise the node
+	port uint16
+	// magic is the TXT of the network magic code
+	magic string
+}
+
+// newMDNS returns the mDNS of the node listening on port, the instance name
+// is random to tell the own responses
+func newMDNS(sync *Syncer, port uint16) *mdns {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+	id := hex.EncodeToString(nonce)
+
+	return &mdns{
+		sync:     sync,
+		service:  dnsmessage.MustNewName(mdnsService),
+		instance: dnsmessage.MustNewName("gringo-" + id + "." + mdnsService),
+		target:   dnsmessage.MustNewName("gringo-" + id + ".local."),
+		port:     port,
+		magic:    "magic=" + hex.EncodeToString(sync.params.MagicCode[:]),
+	}
+}
+
+// query returns the query of the grin service instances
+func (m *mdns) query() ([]byte, error) {
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: m.service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+
+	return msg.Pack()
+}
+
+// announcement returns the response advertising the node
+func (m *mdns) announcement() ([]byte, error) {
+	header := func(name dnsmessage.Name, t dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: t, Class: dnsmessage.ClassINET, TTL: mdnsTTL}
+	}
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header(m.service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: m.instance}},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: header(m.instance, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: m.target, Port: m.port}},
+			{Header: header(m.instance, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{m.magic}}},
+		},
+	}
+
+	return msg.Pack()
+}
+
+// handle handles the mDNS packet of from, the query of the grin service is
+// replied by the announcement, the peers of the response are added to the
+// pool. The nil reply is not sent
+func (m *mdns) handle(packet []byte, from *net.UDPAddr) []byte {
+	var p dnsmessage.Parser
+	header, err := p.Start(packet)
+	if err != nil {
+		return nil
+	}
+
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil
+	}
+
+	if !header.Response {
+		if m.port == 0 {
+			return nil
+		}
+
+		for _, q := range questions {
+			if sameName(q.Name, m.service) && (q.Type == dnsmessage.TypePTR || q.Type == dnsmessage.TypeALL) {
+				reply, err := m.announcement()
+				if err != nil {
+					m.sync.log.Errorf("failed to pack mdns announcement: %v", err)
+					return nil
+				}
+				return reply
+			}
+		}
+
+		return nil
+	}
+
+	for _, addr := range m.peers(&p, from) {
+		m.sync.log.Debugf("mdns found peer %s", addr)
+		m.sync.Pool.Add(addr)
+	}
+
+	return nil
+}
+
+// peers returns the addrs of the other instances of the response records
+func (m *mdns) peers(p *dnsmessage.Parser, from *net.UDPAddr) []string {
+	var instances []dnsmessage.Name
+	ports := make(map[string]uint16)
+	network := make(map[string]bool)
+
+	// the records may be in any section, the names are compared lower case
+	sections := []struct {
+		header func() (dnsmessage.ResourceHeader, error)
+		skip   func() error
+	}{
+		{p.AnswerHeader, p.SkipAnswer},
+		{p.AuthorityHeader, p.SkipAuthority},
+		{p.AdditionalHeader, p.SkipAdditional},
+	}
+
+	for _, section := range sections {
+		for {
+			header, err := section.header()
+			if err == dnsmessage.ErrSectionDone {
+				break
+			}
+			if err != nil {
+				return nil
+			}
+
+			name := strings.ToLower(header.Name.String())
+			switch header.Type {
+			case dnsmessage.TypePTR:
+				var ptr dnsmessage.PTRResource
+				if ptr, err = p.PTRResource(); err == nil && sameName(header.Name, m.service) {
+					instances = append(instances, ptr.PTR)
+				}
+
+			case dnsmessage.TypeSRV:
+				var srv dnsmessage.SRVResource
+				if srv, err = p.SRVResource(); err == nil {
+					ports[name] = srv.Port
+				}
+
+			case dnsmessage.TypeTXT:
+				var txt dnsmessage.TXTResource
+				if txt, err = p.TXTResource(); err == nil {
+					for _, entry := range txt.TXT {
+						network[name] = network[name] || entry == m.magic
+					}
+				}
+
+			default:
+				err = section.skip()
+			}
+
+			if err != nil {
+				return nil
+			}
+		}
+	}
+
+	var result []string
+	for _, instance := range instances {
+		name := strings.ToLower(instance.String())
+		port := ports[name]
+		if port == 0 || !network[name] || sameName(instance, m.instance) {
+			continue
+		}
+
+		result = append(result, net.JoinHostPort(from.IP.String(), strconv.Itoa(int(port))))
+	}
+
+	return result
+}
+
+// sameName returns true if the names are equal ignoring the case
+func sameName(a, b dnsmessage.Name) bool {
+	return strings.EqualFold(a.String(), b.String())
+}
+
+// runMDNS advertises the node & queries the peers of the local network until
+// Stop
+func (s *Syncer) runMDNS() {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		s.log.Errorf("mdns is disabled: %v", err)
+		return
+	}
+
+	m := newMDNS(s, s.mdnsPort)
+
+	go func() {
+		<-s.ctx.Done()
+		conn.Close()
+	}()
+
+	go func() {
+		if m.port != 0 {
+			if announcement, err := m.announcement(); err == nil {
+				conn.WriteToUDP(announcement, mdnsGroup)
+			}
+		}
+
+		query, err := m.query()
+		if err != nil {
+			s.log.Errorf("failed to pack mdns query: %v", err)
+			return
+		}
+
+		ticker := time.NewTicker(mdnsInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+				s.log.Infof("failed to send mdns query: %v", err)
+			}
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	buf := make([]byte, mdnsMaxPacket)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.log.Errorf("mdns is stopped: %v", err)
+			}
+			return
+		}
+
+		if reply := m.handle(buf[:n], from); reply != nil {
+			conn.WriteToUDP(reply, mdnsGroup)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/logging"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"testing"
+)
+
+// parsed returns the parser of the packet records
+func parsed(t *testing.T, packet []byte) *dnsmessage.Parser {
+	var p dnsmessage.Parser
+	if _, err := p.Start(packet); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatal(err)
+	}
+
+	return &p
+}
+
+func TestMDNS(t *testing.T) {
+	newNode := func(port uint16) (*mdns, *peersPool) {
+		sync := NewSyncer(nil, nil, nil)
+		sync.SetLogger(logging.Nop)
+		return newMDNS(sync, port), sync.Pool.(*peersPool)
+	}
+
+	node, _ := newNode(13414)
+	browser, pool := newNode(0)
+	from := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 7), Port: 5353}
+
+	query, err := browser.query()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reply := browser.handle(query, from); reply != nil {
+		t.Errorf("not advertised node replied the query")
+	}
+
+	reply := node.handle(query, from)
+	if reply == nil {
+		t.Fatal("advertised node didn't reply the query")
+	}
+
+	// the own announcement is ignored
+	node.handle(reply, from)
+
+	browser.handle(reply, from)
+	if _, ok := pool.PeersTable["192.168.1.7:13414"]; !ok || len(pool.PeersTable) != 1 {
+		t.Errorf("peers table was %v, want 192.168.1.7:13414", pool.PeersTable)
+	}
+
+	if peers := node.peers(parsed(t, reply), from); len(peers) != 0 {
+		t.Errorf("own announcement returned peers %v", peers)
+	}
+
+	other, _ := newNode(13414)
+	other.magic = "magic=0000"
+	announcement, err := other.announcement()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if peers := browser.peers(parsed(t, announcement), from); len(peers) != 0 {
+		t.Errorf("peers of the other network were %v", peers)
+	}
+
+	if reply := node.handle([]byte{1, 2, 3}, from); reply != nil {
+		t.Errorf("malformed packet was replied")
+	}
+}
@@ -3,13 +3,23 @@ package p2p
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"github.com/dblokhin/gringo/chain"
 	"github.com/dblokhin/gringo/consensus"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"strings"
 	"testing"
 )
 
+// testShake is the shake message of the grin 0.3.0 node
+const testShake = "543402000000000000004500000001000000060000000000007530000000000000000d4d572f4772696e20302e332e30006642e037a073b89c00f48c93cf1b701b335dab84b538136f63b6feadaa50d6"
+
 // TestShakeParsing ensures a shake message is parsed correctly.
 func TestShakeParsing(t *testing.T) {
-	message, _ := hex.DecodeString("543402000000000000004500000001000000060000000000007530000000000000000d4d572f4772696e20302e332e30006642e037a073b89c00f48c93cf1b701b335dab84b538136f63b6feadaa50d6")
+	message, _ := hex.DecodeString(testShake)
 
 	sh := new(shake)
 	read, err := ReadMessage(bytes.NewReader(message), sh)
@@ -37,3 +47,347 @@ func TestShakeParsing(t *testing.T) {
 		t.Errorf("Version differs")
 	}
 }
+
+func TestUserAgent(t *testing.T) {
+	if ua := userAgent("1.0.0", ""); ua != "gringo v1.0.0" {
+		t.Errorf("user agent was %q, want gringo v1.0.0", ua)
+	}
+
+	if ua := userAgent("1.0.0", "abc1234"); ua != "gringo v1.0.0+abc1234" {
+		t.Errorf("user agent was %q, want gringo v1.0.0+abc1234", ua)
+	}
+}
+
+// testHeaders returns the message of n testnet4 genesis headers
+func testHeaders(n int) *BlockHeaders {
+	header := chain.Testnet4.Header
+	header.PreviousRoot = consensus.ZeroHash
+
+	msg := &BlockHeaders{}
+	for i := 0; i < n; i++ {
+		msg.Headers = append(msg.Headers, header)
+	}
+
+	return msg
+}
+
+func TestHeaderReadMaxLen(t *testing.T) {
+	for _, test := range []struct {
+		msgType uint8
+		len     uint64
+		err     error
+	}{
+		{consensus.MsgTypePing, 16, nil},
+		{consensus.MsgTypePing, 17, consensus.ErrTooLargeRead},
+		{consensus.MsgTypeHeaders, 2 + uint64(consensus.MaxBlockHeaderLen)*uint64(consensus.MaxBlockHeaders), nil},
+		{consensus.MsgTypeHeaders, 3 + uint64(consensus.MaxBlockHeaderLen)*uint64(consensus.MaxBlockHeaders), consensus.ErrTooLargeRead},
+		{consensus.MsgTypeBlock, consensus.MaxMsgLen, nil},
+		{consensus.MsgTypeBlock, consensus.MaxMsgLen + 1, consensus.ErrTooLargeRead},
+	} {
+		data := make([]byte, consensus.HeaderLen)
+		header := Header{magic: magicCode, Type: test.msgType, Len: test.len}
+		header.encode(data)
+
+		if err := new(Header).Read(bytes.NewReader(data)); !errors.Is(err, test.err) {
+			t.Errorf("message %d of %d bytes: error was %v, want %v", test.msgType, test.len, err, test.err)
+		}
+	}
+}
+
+func TestHeaderReadLimits(t *testing.T) {
+	headers := testHeaders(9).Bytes()
+
+	limits := consensus.DefaultLimits
+	limits.MaxBlockHeaders = 8
+	consensus.SetLimits(limits)
+	defer consensus.SetLimits(consensus.DefaultLimits)
+
+	data := make([]byte, consensus.HeaderLen)
+	header := Header{magic: magicCode, Type: consensus.MsgTypeHeaders, Len: 3 + uint64(consensus.MaxBlockHeaderLen)*8}
+	header.encode(data)
+
+	if err := new(Header).Read(bytes.NewReader(data)); !errors.Is(err, consensus.ErrTooLargeRead) {
+		t.Errorf("error was %v, want %v", err, consensus.ErrTooLargeRead)
+	}
+
+	if err := new(BlockHeaders).Read(bytes.NewReader(headers)); !errors.Is(err, consensus.ErrTooLargeRead) {
+		t.Errorf("error was %v, want %v", err, consensus.ErrTooLargeRead)
+	}
+}
+
+func TestPeerErrorReadMaxLen(t *testing.T) {
+	msg := PeerError{Code: 1, Message: strings.Repeat("a", consensus.MaxStringLen+1)}
+
+	// the len is checked before the string is read
+	data := msg.Bytes()[:4+8]
+	if err := new(PeerError).Read(bytes.NewReader(data)); !errors.Is(err, consensus.ErrTooLargeRead) {
+		t.Errorf("error was %v, want %v", err, consensus.ErrTooLargeRead)
+	}
+}
+
+func TestWriteMessageHeaders(t *testing.T) {
+	msg := testHeaders(consensus.MaxBlockHeaders)
+
+	var buf bytes.Buffer
+	written, err := WriteMessage(&buf, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if written != uint64(buf.Len()) || written != consensus.HeaderLen+uint64(len(msg.Bytes())) {
+		t.Errorf("written %d of %d bytes", written, buf.Len())
+	}
+
+	var received BlockHeaders
+	if _, err := ReadMessage(&buf, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received.Headers) != len(msg.Headers) || received.Headers[0].Hash() != msg.Headers[0].Hash() {
+		t.Errorf("received %d headers, want %d", len(received.Headers), len(msg.Headers))
+	}
+}
+
+func BenchmarkWriteMessageHeaders(b *testing.B) {
+	msg := testHeaders(consensus.MaxBlockHeaders)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := WriteMessage(ioutil.Discard, msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadMessageHeaders(b *testing.B) {
+	var buf bytes.Buffer
+	if _, err := WriteMessage(&buf, testHeaders(consensus.MaxBlockHeaders)); err != nil {
+		b.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	r := bytes.NewReader(data)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+
+		var msg BlockHeaders
+		if _, err := ReadMessage(r, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// wireMessage is the message decoded by the fuzz targets
+type wireMessage interface {
+	Read(r io.Reader) error
+	Bytes() []byte
+}
+
+// fuzzMessage fuzzes the decoder of the message returned by newMsg: the
+// decoded message must be encoded to the bytes decoded to the same message
+func fuzzMessage(f *testing.F, newMsg func() wireMessage, seeds ...[]byte) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg := newMsg()
+		if err := msg.Read(bytes.NewReader(data)); err != nil {
+			return
+		}
+
+		encoded := msg.Bytes()
+
+		decoded := newMsg()
+		if err := decoded.Read(bytes.NewReader(encoded)); err != nil {
+			t.Fatalf("failed to read the encoded message %x: %v", encoded, err)
+		}
+
+		if again := decoded.Bytes(); !bytes.Equal(again, encoded) {
+			t.Errorf("message was encoded to %x, want %x", again, encoded)
+		}
+	})
+}
+
+// testAddrs returns the ipv4 & ipv6 addrs
+func testAddrs() []*net.TCPAddr {
+	return []*net.TCPAddr{
+		{IP: net.IPv4(10, 0, 0, 1), Port: 13414},
+		{IP: net.ParseIP("2001:db8::68"), Port: 13414},
+	}
+}
+
+func TestHandshakeNodeID(t *testing.T) {
+	addrs := testAddrs()
+	id := NewIdentity(big.NewInt(8)).ID
+
+	for _, capabilities := range []consensus.Capabilities{consensus.CapFullNode, consensus.CapFullNode | consensus.CapNodeID} {
+		h := hand{
+			Version:      consensus.ProtocolVersion,
+			Capabilities: capabilities,
+			SenderAddr:   addrs[0],
+			ReceiverAddr: addrs[1],
+			NodeID:       id,
+		}
+		s := shake{Version: consensus.ProtocolVersion, Capabilities: capabilities, NodeID: id}
+
+		// the id is sent by the node of CapNodeID only
+		want := NodeID{}
+		if capabilities&consensus.CapNodeID != 0 {
+			want = id
+		}
+
+		var buf bytes.Buffer
+		WriteMessage(&buf, &h)
+		WriteMessage(&buf, &s)
+
+		var readHand hand
+		var readShake shake
+		if _, err := ReadMessage(&buf, &readHand); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadMessage(&buf, &readShake); err != nil {
+			t.Fatal(err)
+		}
+
+		if readHand.NodeID != want || readShake.NodeID != want {
+			t.Errorf("capabilities %d: node ids were %s & %s, want %s", capabilities, readHand.NodeID, readShake.NodeID, want)
+		}
+	}
+}
+
+func FuzzHeaderRead(f *testing.F) {
+	message, _ := hex.DecodeString(testShake)
+
+	fuzzMessage(f, func() wireMessage { return new(Header) }, message[:consensus.HeaderLen])
+}
+
+func FuzzHandRead(f *testing.F) {
+	addrs := testAddrs()
+	msg := hand{
+		Version:         consensus.ProtocolVersion,
+		Capabilities:    consensus.CapFullNode,
+		Nonce:           1,
+		TotalDifficulty: 30000,
+		SenderAddr:      addrs[0],
+		ReceiverAddr:    addrs[1],
+		UserAgent:       userAgent("1.0.0", ""),
+		Genesis:         chain.Testnet4.Hash(),
+	}
+
+	fuzzMessage(f, func() wireMessage { return new(hand) }, msg.Bytes())
+}
+
+func FuzzShakeRead(f *testing.F) {
+	message, _ := hex.DecodeString(testShake)
+
+	fuzzMessage(f, func() wireMessage { return new(shake) }, message[consensus.HeaderLen:])
+}
+
+func FuzzPeerAddrsRead(f *testing.F) {
+	msg := PeerAddrs{peers: testAddrs()}
+
+	fuzzMessage(f, func() wireMessage { return new(PeerAddrs) }, msg.Bytes())
+}
+
+func FuzzPeerErrorRead(f *testing.F) {
+	msg := PeerError{Code: 1, Message: "banned"}
+
+	fuzzMessage(f, func() wireMessage { return new(PeerError) }, msg.Bytes())
+}
+
+func TestPeerAddrsRead(t *testing.T) {
+	msg := PeerAddrs{peers: testAddrs()}
+
+	var received PeerAddrs
+	if err := received.Read(bytes.NewReader(msg.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, addr := range msg.peers {
+		if received.peers[i].String() != addr.String() {
+			t.Errorf("peer addr was %s, want %s", received.peers[i], addr)
+		}
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	defer func(magic [2]byte) { magicCode = magic }(magicCode)
+
+	message, _ := hex.DecodeString(testShake)
+	params := consensus.TestnetParams
+
+	header, msg, err := DecodeMessage(message, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Type != consensus.MsgTypeShake || MessageTypeName(header.Type) != "shake" {
+		t.Errorf("message type was %d", header.Type)
+	}
+	if sh, ok := msg.(*shake); !ok || sh.UserAgent != "MW/Grin 0.3.0" {
+		t.Errorf("message was %#v", msg)
+	}
+
+	if _, _, err := DecodeMessage(message[:len(message)-1], &params); err == nil {
+		t.Error("truncated message decoded")
+	}
+	if _, msg, err := DecodeMessage(append(message, 0), &params); err == nil || msg == nil {
+		t.Errorf("message of the trailing byte: %v, %v", msg, err)
+	}
+
+	peers := PeerAddrs{peers: testAddrs()}
+	msgType, err := ParseMessageType("peer_addrs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = DecodeBody(msgType, peers.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.(*PeerAddrs).peers) != len(peers.peers) {
+		t.Errorf("decoded %d peers, want %d", len(msg.(*PeerAddrs).peers), len(peers.peers))
+	}
+
+	if _, err := DecodeBody(consensus.MsgTypeTxHashSetArchive, nil); err == nil {
+		t.Error("txhashset archive decoded")
+	}
+}
+
+func TestBlockHeadersReadValidate(t *testing.T) {
+	headers := make([]consensus.BlockHeader, 5)
+	for i := range headers {
+		headers[i] = chain.Testnet4.Header
+		headers[i].Height = uint64(i)
+	}
+
+	data := (&BlockHeaders{Headers: headers}).Bytes()
+	headerLen := len(headers[0].Bytes())
+
+	// the decoding is stopped on the invalid header of height 2
+	validate := func(prev, header *consensus.BlockHeader) error {
+		if (prev == nil) != (header.Height == 0) || prev != nil && prev.Height+1 != header.Height {
+			t.Errorf("header %d was validated after %v", header.Height, prev)
+		}
+
+		if header.Height == 2 {
+			return consensus.ErrInvalidPow
+		}
+		return nil
+	}
+
+	r := bytes.NewReader(data)
+	var msg BlockHeaders
+	if err := msg.ReadValidate(r, validate); !errors.Is(err, consensus.ErrInvalidPow) {
+		t.Fatalf("error was %v, want %v", err, consensus.ErrInvalidPow)
+	}
+
+	if len(msg.Headers) != 2 {
+		t.Errorf("%d headers were kept, want 2", len(msg.Headers))
+	}
+
+	if want := 2 * headerLen; r.Len() != want {
+		t.Errorf("%d bytes were left, want %d", r.Len(), want)
+	}
+}
@@ -10,11 +10,46 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/wire"
 	"io"
 	"net"
 )
 
+// maxMsgLens is the max sizes of the messages by type, the body is never read
+// beyond the size. The messages of the counts limited by the consensus limits
+// are sized by maxMsgLen, the other types are limited by MaxMsgLen
+var maxMsgLens = map[uint8]uint64{
+	consensus.MsgTypeError:           4 + 8 + wire.MaxStringLen,
+	consensus.MsgTypeHand:            4 + 4 + 8 + 8 + 2*wire.MaxAddrLen + 8 + wire.MaxStringLen + consensus.BlockHashSize + nodeIDLen,
+	consensus.MsgTypeShake:           4 + 4 + 8 + 8 + wire.MaxStringLen + consensus.BlockHashSize + nodeIDLen,
+	consensus.MsgTypePing:            16,
+	consensus.MsgTypePong:            16,
+	consensus.MsgTypeGetPeerAddrs:    4,
+	consensus.MsgTypeHeader:          uint64(consensus.MaxBlockHeaderLen),
+	consensus.MsgTypeGetBlock:        consensus.BlockHashSize,
+	consensus.MsgTypeGetCompactBlock: consensus.BlockHashSize,
+	consensus.MsgTypePoolKernels:     consensus.BlockHashSize + 4 + consensus.ShortIDSize*consensus.MaxPoolKernels,
+	consensus.MsgTypeGetPoolTxs:      consensus.BlockHashSize + 4 + consensus.ShortIDSize*consensus.MaxPoolKernels,
+}
+
+// maxMsgLen returns the max size of the message of the type
+func maxMsgLen(msgType uint8) uint64 {
+	switch msgType {
+	case consensus.MsgTypePeerAddrs:
+		return 4 + wire.MaxAddrLen*uint64(consensus.MaxPeerAddrs)
+	case consensus.MsgTypeGetHeaders:
+		return 1 + consensus.BlockHashSize*uint64(consensus.MaxLocators)
+	case consensus.MsgTypeHeaders:
+		return 2 + uint64(consensus.MaxBlockHeaderLen)*uint64(consensus.MaxBlockHeaders)
+	}
+
+	if max, ok := maxMsgLens[msgType]; ok {
+		return max
+	}
+
+	return consensus.MaxMsgLen
+}
+
 // Header is header of any protocol message, used to identify incoming messages
 type Header struct {
 	// magic number
@@ -27,56 +62,53 @@ type Header struct {
 
 // Bytes serialises the header to its on-the-wire representation.
 func (h *Header) Bytes() []byte {
-	buff := new(bytes.Buffer)
+	var data [consensus.HeaderLen]byte
+	h.encode(data[:])
 
-	if _, err := buff.Write(h.magic[:]); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, h.Type); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, h.Len); err != nil {
-		logrus.Fatal(err)
-	}
-
-	return buff.Bytes()
+	return data[:]
 }
 
 // Write writes header as binary data to writer
 func (h *Header) Write(wr io.Writer) error {
-	if _, err := wr.Write(h.magic[:]); err != nil {
-		return err
-	}
-	if err := binary.Write(wr, binary.BigEndian, h.Type); err != nil {
-		return err
-	}
+	var data [consensus.HeaderLen]byte
+	h.encode(data[:])
 
-	return binary.Write(wr, binary.BigEndian, h.Len)
+	_, err := wr.Write(data[:])
+	return err
 }
 
 // Read reads from reader & fill struct
 func (h *Header) Read(r io.Reader) error {
-	if _, err := io.ReadFull(r, h.magic[:]); err != nil {
+	var data [consensus.HeaderLen]byte
+	if _, err := io.ReadFull(r, data[:]); err != nil {
 		return err
 	}
 
+	copy(h.magic[:], data[:2])
 	if !h.validateMagic() {
-		logrus.Debug("got magic: ", h.magic[:])
-		return errors.New("invalid magic code")
+		return fmt.Errorf("invalid magic code: %x", h.magic[:])
 	}
 
-	if err := binary.Read(r, binary.BigEndian, &h.Type); err != nil {
-		return err
-	}
+	h.Type = data[2]
+	h.Len = binary.BigEndian.Uint64(data[3:])
+
+	return wire.CheckLen(fmt.Sprintf("message %d len", h.Type), h.Len, maxMsgLen(h.Type))
+}
 
-	return binary.Read(r, binary.BigEndian, &h.Len)
+// encode writes the header to data of HeaderLen
+func (h *Header) encode(data []byte) {
+	copy(data, h.magic[:])
+	data[2] = h.Type
+	binary.BigEndian.PutUint64(data[3:], h.Len)
 }
 
+// magicCode is expected in the header of every message, set by
+// Syncer.SetParams
+var magicCode = consensus.TestnetParams.MagicCode
+
 // validateMagic verifies magic code
 func (h Header) validateMagic() bool {
-	return bytes.Equal(h.magic[:], consensus.MagicCode[:])
+	return bytes.Equal(h.magic[:], magicCode[:])
 }
 
 // Ping request
@@ -91,14 +123,8 @@ type Ping struct {
 // Bytes implements Message interface
 func (p *Ping) Bytes() []byte {
 	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(p.TotalDifficulty)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint64(p.Height)); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint64(buff, uint64(p.TotalDifficulty))
+	wire.WriteUint64(buff, p.Height)
 
 	return buff.Bytes()
 }
@@ -110,12 +136,14 @@ func (p *Ping) Type() uint8 {
 
 // Read implements Message interface
 func (p *Ping) Read(r io.Reader) error {
-
-	if err := binary.Read(r, binary.BigEndian, (*uint64)(&p.TotalDifficulty)); err != nil {
+	totalDifficulty, err := wire.ReadUint64(r)
+	if err != nil {
 		return err
 	}
+	p.TotalDifficulty = consensus.Difficulty(totalDifficulty)
 
-	return binary.Read(r, binary.BigEndian, (*uint64)(&p.Height))
+	p.Height, err = wire.ReadUint64(r)
+	return err
 }
 
 // String implements String() interface
@@ -142,10 +170,7 @@ type GetPeerAddrs struct {
 // Bytes implements Message interface
 func (p *GetPeerAddrs) Bytes() []byte {
 	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, uint32(p.Capabilities)); err != nil {
-		logrus.Fatal(err)
-	}
+	wire.WriteUint32(buff, uint32(p.Capabilities))
 
 	return buff.Bytes()
 }
@@ -157,8 +182,10 @@ func (p *GetPeerAddrs) Type() uint8 {
 
 // Read implements Message interface
 func (p *GetPeerAddrs) Read(r io.Reader) error {
+	capabilities, err := wire.ReadUint32(r)
+	p.Capabilities = consensus.Capabilities(capabilities)
 
-	return binary.Read(r, binary.BigEndian, (*uint32)(&p.Capabilities))
+	return err
 }
 
 // String implements String() interface
@@ -177,16 +204,9 @@ type PeerError struct {
 // Bytes implements Message interface
 func (p *PeerError) Bytes() []byte {
 	buff := new(bytes.Buffer)
+	wire.WriteUint32(buff, p.Code)
+	wire.WriteString(buff, p.Message)
 
-	if err := binary.Write(buff, binary.BigEndian, uint32(p.Code)); err != nil {
-		logrus.Fatal(err)
-	}
-
-	// Write user agent [len][string]
-	if err := binary.Write(buff, binary.BigEndian, uint64(len(p.Message))); err != nil {
-		logrus.Fatal(err)
-	}
-	buff.WriteString(p.Message)
 	return buff.Bytes()
 }
 
@@ -197,22 +217,28 @@ func (p *PeerError) Type() uint8 {
 
 // Read implements Message interface
 func (p *PeerError) Read(r io.Reader) error {
-
-	if err := binary.Read(r, binary.BigEndian, (*uint32)(&p.Code)); err != nil {
+	code, err := wire.ReadUint32(r)
+	if err != nil {
 		return err
 	}
+	p.Code = code
 
-	var messageLen uint64
-	if err := binary.Read(r, binary.BigEndian, &messageLen); err != nil {
-		return err
-	}
+	p.Message, err = wire.ReadString(r)
+	return err
+}
 
-	buff := make([]byte, messageLen)
-	if _, err := io.ReadFull(r, buff); err != nil {
-		return err
+// Error implements error interface, the PeerError received instead of the
+// expected message is returned by ReadMessage
+func (p *PeerError) Error() string {
+	return fmt.Sprintf("peer error %d: %s", p.Code, p.Message)
+}
+
+// Unwrap returns ErrUnsupportedVersion of the NetUnsupportedVersion code
+func (p *PeerError) Unwrap() error {
+	if p.Code == uint32(consensus.NetUnsupportedVersion) {
+		return ErrUnsupportedVersion
 	}
 
-	p.Message = string(buff)
 	return nil
 }
 
@@ -231,16 +257,12 @@ func (p *PeerAddrs) Bytes() []byte {
 	buff := new(bytes.Buffer)
 
 	if len(p.peers) > consensus.MaxPeerAddrs {
-		logrus.Debug("length peers: ", len(p.peers))
-		logrus.Fatal(errors.New("too big peer addrs count for sending"))
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint32(len(p.peers))); err != nil {
-		logrus.Fatal(err)
+		panic(fmt.Errorf("too big peer addrs count for sending: %d", len(p.peers)))
 	}
 
+	wire.WriteUint32(buff, uint32(len(p.peers)))
 	for _, peerAddr := range p.peers {
-		serializeTCPAddr(buff, peerAddr)
+		wire.WriteAddr(buff, peerAddr)
 	}
 
 	return buff.Bytes()
@@ -253,18 +275,17 @@ func (p *PeerAddrs) Type() uint8 {
 
 // Read implements Message interface
 func (p *PeerAddrs) Read(r io.Reader) error {
-	var peersCount uint32
-
-	if err := binary.Read(r, binary.BigEndian, &peersCount); err != nil {
+	peersCount, err := wire.ReadUint32(r)
+	if err != nil {
 		return err
 	}
 
-	if peersCount > consensus.MaxPeerAddrs {
-		return errors.New("too big peer addrs count")
+	if err := wire.CheckLen("peer addrs count", uint64(peersCount), uint64(consensus.MaxPeerAddrs)); err != nil {
+		return err
 	}
 
 	for i := uint32(0); i < peersCount; i++ {
-		addr, err := deserializeTCPAddr(r)
+		addr, err := wire.ReadAddr(r)
 		if err != nil {
 			return err
 		}
@@ -287,11 +308,7 @@ type GetBlock struct {
 
 // Bytes implements Message interface
 func (h *GetBlock) Bytes() []byte {
-	if len(h.Hash) != consensus.BlockHashSize {
-		logrus.Fatal(errors.New("invalid block hash len"))
-	}
-
-	return h.Hash
+	return h.Hash.Bytes()
 }
 
 // Type implements Message interface
@@ -301,12 +318,7 @@ func (h *GetBlock) Type() uint8 {
 
 // Read implements Message interface
 func (h *GetBlock) Read(r io.Reader) error {
-
-	hash := make([]byte, consensus.BlockHashSize)
-	_, err := io.ReadFull(r, hash)
-
-	h.Hash = hash
-	return err
+	return wire.ReadBytes(r, h.Hash[:])
 }
 
 // String implements String() interface
@@ -325,13 +337,12 @@ type BlockHeader struct {
 
 // Bytes implements Message interface
 func (h *BlockHeader) Bytes() []byte {
-	buff := new(bytes.Buffer)
-
-	if _, err := buff.Write(h.Header.Bytes()); err != nil {
-		logrus.Fatal(err)
-	}
+	return h.Header.Bytes()
+}
 
-	return buff.Bytes()
+// WriteTo implements io.WriterTo
+func (h *BlockHeader) WriteTo(w io.Writer) (int64, error) {
+	return h.Header.WriteTo(w)
 }
 
 // Type implements Message interface
@@ -356,24 +367,25 @@ type BlockHeaders struct {
 
 // Bytes implements Message interface
 func (h *BlockHeaders) Bytes() []byte {
-	buff := new(bytes.Buffer)
+	return wire.Bytes(h.write)
+}
 
+// write writes the headers to buf
+func (h *BlockHeaders) write(buf *bytes.Buffer) {
 	// check the bounds of h.Headers & set the limits
 	if len(h.Headers) > consensus.MaxBlockHeaders {
-		logrus.Fatal(errors.New("invalid headers len in BlockHeaders"))
-	}
-
-	if err := binary.Write(buff, binary.BigEndian, uint16(len(h.Headers))); err != nil {
-		logrus.Fatal(err)
+		panic(errors.New("invalid headers len in BlockHeaders"))
 	}
 
-	for _, header := range h.Headers {
-		if _, err := buff.Write(header.Bytes()); err != nil {
-			logrus.Fatal(err)
-		}
+	wire.WriteUint16(buf, uint16(len(h.Headers)))
+	for i := range h.Headers {
+		h.Headers[i].WriteTo(buf)
 	}
+}
 
-	return buff.Bytes()
+// WriteTo implements io.WriterTo
+func (h *BlockHeaders) WriteTo(w io.Writer) (int64, error) {
+	return wire.WriteTo(w, h.write)
 }
 
 // Type implements Message interface
@@ -383,21 +395,39 @@ func (h *BlockHeaders) Type() uint8 {
 
 // Read implements Message interface
 func (h *BlockHeaders) Read(r io.Reader) error {
+	return h.ReadValidate(r, nil)
+}
 
-	var count uint16
-	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+// ReadValidate reads the headers checking each one by validate while decoded,
+// prev is the previous header of the message, nil for the first one. The
+// headers are appended as validated, so the decoding is stopped on the first
+// invalid header & the memory of the declared count isn't allocated ahead
+func (h *BlockHeaders) ReadValidate(r io.Reader, validate func(prev, header *consensus.BlockHeader) error) error {
+	count, err := wire.ReadUint16(r)
+	if err != nil {
 		return err
 	}
 
-	if int(count) > consensus.MaxBlockHeaders {
-		return errors.New("too big block headers len from peer")
+	if err := wire.CheckLen("block headers count", uint64(count), uint64(consensus.MaxBlockHeaders)); err != nil {
+		return err
 	}
 
-	h.Headers = make([]consensus.BlockHeader, count)
+	h.Headers = make([]consensus.BlockHeader, 0)
+	var prev *consensus.BlockHeader
 	for i := 0; i < int(count); i++ {
-		if err := h.Headers[i].Read(r); err != nil {
+		var header consensus.BlockHeader
+		if err := header.Read(r); err != nil {
 			return err
 		}
+
+		if validate != nil {
+			if err := validate(prev, &header); err != nil {
+				return err
+			}
+		}
+
+		h.Headers = append(h.Headers, header)
+		prev = &h.Headers[len(h.Headers)-1]
 	}
 
 	return nil
@@ -432,3 +462,90 @@ func (h *GetBlockHeaders) Read(r io.Reader) error {
 func (h GetBlockHeaders) String() string {
 	return fmt.Sprintf("%#v", h)
 }
+
+// GetCompactBlock message for requesting compact block by hash
+type GetCompactBlock struct {
+	GetBlock
+}
+
+// Type implements Message interface
+func (h *GetCompactBlock) Type() uint8 {
+	return consensus.MsgTypeGetCompactBlock
+}
+
+// StemTransaction is the transaction of the Dandelion stem phase, relayed to
+// a single peer before it is fluffed
+type StemTransaction struct {
+	consensus.Transaction
+}
+
+// Type implements Message interface
+func (t *StemTransaction) Type() uint8 {
+	return consensus.MsgTypeStemTransaction
+}
+
+// PoolKernels is the short ids of the pool transaction kernels sent on
+// connect, the ids are keyed by the random Key of the message
+type PoolKernels struct {
+	Key consensus.Hash
+	IDs consensus.ShortIDList
+}
+
+// Bytes implements Message interface
+func (p *PoolKernels) Bytes() []byte {
+	// check the bounds of p.IDs & set the limits
+	if len(p.IDs) > consensus.MaxPoolKernels {
+		panic(errors.New("invalid ids len in PoolKernels"))
+	}
+
+	buff := new(bytes.Buffer)
+	buff.Write(p.Key[:])
+	wire.WriteUint32(buff, uint32(len(p.IDs)))
+	for _, id := range p.IDs {
+		buff.Write(id)
+	}
+
+	return buff.Bytes()
+}
+
+// Type implements Message interface
+func (p *PoolKernels) Type() uint8 {
+	return consensus.MsgTypePoolKernels
+}
+
+// Read implements Message interface
+func (p *PoolKernels) Read(r io.Reader) error {
+	if err := wire.ReadBytes(r, p.Key[:]); err != nil {
+		return err
+	}
+
+	count, err := wire.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+
+	if err := wire.CheckLen("pool kernels count", uint64(count), consensus.MaxPoolKernels); err != nil {
+		return err
+	}
+
+	p.IDs = make(consensus.ShortIDList, count)
+	for i := range p.IDs {
+		p.IDs[i] = make(consensus.ShortID, consensus.ShortIDSize)
+		if err := wire.ReadBytes(r, p.IDs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetPoolTxs requests the pool transactions of the kernel short ids, the
+// transactions are sent as the Transaction messages
+type GetPoolTxs struct {
+	PoolKernels
+}
+
+// Type implements Message interface
+func (p *GetPoolTxs) Type() uint8 {
+	return consensus.MsgTypeGetPoolTxs
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Metrics is a prometheus collector of the p2p statistics
+type Metrics struct {
+	// count of the connected peers
+	peers prometheus.GaugeFunc
+
+	// max height of the connected peers
+	bestPeerHeight prometheus.GaugeFunc
+
+	// count of blocks the chain is behind the best peer
+	syncLag prometheus.GaugeFunc
+
+	// fork alarm & the count of the peers ahead of the chain
+	forkAlarm  prometheus.GaugeFunc
+	peersAhead prometheus.GaugeFunc
+
+	// latency of the decoding & the handling of the peer messages by type,
+	// the blocks & the headers are validated while decoded
+	decodeLatency *prometheus.HistogramVec
+	handleLatency *prometheus.HistogramVec
+}
+
+// newMetrics returns metrics of the syncer
+func newMetrics(s *Syncer) *Metrics {
+	return &Metrics{
+		peers: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "peers_connected",
+			Help:      "Count of the connected peers.",
+		}, func() float64 {
+			return float64(len(s.Pool.Connected()))
+		}),
+
+		bestPeerHeight: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "best_peer_height",
+			Help:      "Max height of the connected peers.",
+		}, func() float64 {
+			return float64(s.BestPeerHeight())
+		}),
+
+		syncLag: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "sync_lag_blocks",
+			Help:      "Count of blocks the chain is behind the best peer.",
+		}, func() float64 {
+			return float64(s.SyncLag())
+		}),
+
+		forkAlarm: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "fork_alarm",
+			Help:      "1 if the most peers are ahead of the synced chain, 0 otherwise.",
+		}, func() float64 {
+			if s.ForkAlarm().Raised {
+				return 1
+			}
+			return 0
+		}),
+
+		peersAhead: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "peers_ahead",
+			Help:      "Count of the connected peers ahead of the chain by the fork alarm lead.",
+		}, func() float64 {
+			return float64(s.ForkAlarm().PeersAhead)
+		}),
+
+		decodeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "message_decode_duration_seconds",
+			Help:      "Latency of the peer message decoding by type.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, []string{"type"}),
+
+		handleLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gringo",
+			Subsystem: "p2p",
+			Name:      "message_handle_duration_seconds",
+			Help:      "Latency of the peer message handling by type.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, []string{"type"}),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.peers.Describe(ch)
+	m.bestPeerHeight.Describe(ch)
+	m.syncLag.Describe(ch)
+	m.forkAlarm.Describe(ch)
+	m.peersAhead.Describe(ch)
+	m.decodeLatency.Describe(ch)
+	m.handleLatency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.peers.Collect(ch)
+	m.bestPeerHeight.Collect(ch)
+	m.syncLag.Collect(ch)
+	m.forkAlarm.Collect(ch)
+	m.peersAhead.Collect(ch)
+	m.decodeLatency.Collect(ch)
+	m.handleLatency.Collect(ch)
+}
+
+// observeMessage records the decoding of the message of the type from start
+// to decoded & its handling from decoded to now
+func (m *Metrics) observeMessage(msgType uint8, start, decoded time.Time) {
+	name := MessageTypeName(msgType)
+	m.decodeLatency.WithLabelValues(name).Observe(decoded.Sub(start).Seconds())
+	m.handleLatency.WithLabelValues(name).Observe(time.Since(decoded).Seconds())
+}
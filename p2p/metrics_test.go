@@ -0,0 +1,62 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMessageMetrics(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 3414}
+
+	s := NewSyncer(nil, &mockChain{}, &mockMempool{})
+	s.SetLogger(logging.Nop)
+	s.Pool = &mockPool{info: &peerInfo{status: psConnected}, peers: &PeerAddrs{}}
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(s.Metrics()); err != nil {
+		t.Fatalf("failed to register p2p metrics: %v", err)
+	}
+
+	peer := &Peer{
+		conn:      &mockConn{addr: addr},
+		sync:      s,
+		quit:      make(chan struct{}),
+		sendQueue: make(chan Message, 16),
+		Addr:      addr.String(),
+	}
+	peer.process(consensus.MsgTypePing, time.Now().Add(-time.Second), &Ping{})
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			histogram := metric.GetHistogram()
+			if histogram == nil || metric.GetLabel()[0].GetValue() != "ping" {
+				continue
+			}
+
+			found[family.GetName()] = histogram.GetSampleCount() == 1
+			if family.GetName() == "gringo_p2p_message_decode_duration_seconds" && histogram.GetSampleSum() < 1 {
+				t.Errorf("decode latency was %v, want 1s at least", histogram.GetSampleSum())
+			}
+		}
+	}
+
+	for _, name := range []string{"gringo_p2p_message_decode_duration_seconds", "gringo_p2p_message_handle_duration_seconds"} {
+		if !found[name] {
+			t.Errorf("ping latency of %s is not exposed", name)
+		}
+	}
+}
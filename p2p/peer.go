@@ -7,15 +7,18 @@ package p2p
 import (
 	"bufio"
 	"bytes"
-	"encoding/hex"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/wire"
 	"io"
+	"io/ioutil"
 	"net"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Peer is a participant of p2p network
@@ -36,9 +39,17 @@ type Peer struct {
 	// disconnect flag
 	disconnect int32
 
+	// connected is the time of the handshake, reason is the disconnect
+	// reason set before quit is closed
+	connected time.Time
+	reason    error
+
 	// Network addr
 	Addr string
 
+	// Inbound is true for peers connected to our listener
+	Inbound bool
+
 	// Info connected peer
 	Info struct {
 		// protocol version of the sender
@@ -52,13 +63,19 @@ type Peer struct {
 		UserAgent string
 		// Height
 		Height uint64
+		// ListenPort is the port advertised by the inbound peer, 0 if it
+		// isn't listening
+		ListenPort int
+		// NodeID is the identity of the peer of CapNodeID, zero otherwise
+		NodeID NodeID
 	}
 }
 
 // NewPeer connects to peer
 func NewPeer(sync *Syncer, addr string) (*Peer, error) {
 
-	logrus.Infof("starting new peer (%s)", addr)
+	log := sync.log.WithFields(logging.Fields{"peer": addr})
+	log.Debug("connecting to peer")
 	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
 	if err != nil {
 		return nil, err
@@ -70,8 +87,8 @@ func NewPeer(sync *Syncer, addr string) (*Peer, error) {
 		return nil, err
 	}
 
-	logrus.Infof("connected to peer (%s)", addr)
-	shake, err := shakeByHand(conn)
+	log.Info("connected to peer")
+	shake, err := shakeByHand(conn, sync)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +98,7 @@ func NewPeer(sync *Syncer, addr string) (*Peer, error) {
 	p.sync = sync
 	p.quit = make(chan struct{})
 	p.sendQueue = make(chan Message)
+	p.connected = time.Now()
 
 	// Store the network addr
 	p.Addr = addr
@@ -89,28 +107,37 @@ func NewPeer(sync *Syncer, addr string) (*Peer, error) {
 	p.Info.Capabilities = shake.Capabilities
 	p.Info.TotalDifficulty = shake.TotalDifficulty
 	p.Info.UserAgent = shake.UserAgent
+	p.Info.NodeID = shake.NodeID
 
 	return p, nil
 }
 
 // AcceptNewPeer creates peer accepting listening server conn
-func AcceptNewPeer(conn net.Conn) (*Peer, error) {
+func AcceptNewPeer(sync *Syncer, conn net.Conn) (*Peer, error) {
 
-	logrus.Info("accept new peer")
-	hand, err := handByShake(conn)
+	sync.log.WithFields(logging.Fields{"peer": conn.RemoteAddr().String()}).Info("accepted peer")
+	hand, err := handByShake(conn, sync)
 	if err != nil {
 		return nil, err
 	}
 
 	p := new(Peer)
 	p.conn = conn
+	p.sync = sync
 	p.quit = make(chan struct{})
 	p.sendQueue = make(chan Message)
+	p.connected = time.Now()
+	p.Inbound = true
+
+	// Store the network addr
+	p.Addr = conn.RemoteAddr().String()
 
 	p.Info.Version = hand.Version
 	p.Info.Capabilities = hand.Capabilities
 	p.Info.TotalDifficulty = hand.TotalDifficulty
 	p.Info.UserAgent = hand.UserAgent
+	p.Info.ListenPort = hand.SenderAddr.Port
+	p.Info.NodeID = hand.NodeID
 
 	return p, nil
 }
@@ -144,6 +171,7 @@ out:
 			}
 
 			atomic.AddUint64(&p.bytesSent, written)
+			atomic.AddUint64(&p.sync.bytesSent, written)
 
 		case <-p.quit:
 			exitError = errors.New("peer exiting")
@@ -159,7 +187,7 @@ out:
 func (p *Peer) WriteMessage(msg Message) {
 	select {
 	case <-p.quit:
-		logrus.Info("cannot send message, peer is shutting down")
+		p.logger().Debug("cannot send message, peer is shutting down")
 	case p.sendQueue <- msg:
 	}
 }
@@ -170,32 +198,54 @@ func (p *Peer) WriteMessage(msg Message) {
 // NOTE: This method MUST be run as a goroutine.
 func (p *Peer) readHandler() {
 	var exitError error
+	log := p.logger()
 	input := bufio.NewReader(p.conn)
 	header := new(Header)
 
+	// the messages are read to the pooled buffer, the message is decoded
+	// before the next one is read
+	buf := wire.GetBuffer()
+	defer func() {
+		wire.PutBuffer(buf)
+	}()
+
 out:
 	for atomic.LoadInt32(&p.disconnect) == 0 {
 		if exitError = header.Read(input); exitError != nil {
-			logrus.Debugf("Failed to read message from peer %v", p.conn.RemoteAddr())
+			log.Debugf("failed to read message header: %v", exitError)
 			break out
 		}
 
-		if header.Len > consensus.MaxMsgLen {
-			exitError = errors.New("too big message size")
-			break out
+		// the headers are validated while streamed, the big message isn't
+		// buffered
+		if header.Type == consensus.MsgTypeHeaders {
+			if exitError = p.readHeaders(input, header.Len); exitError != nil {
+				break out
+			}
+			continue
+		}
+
+		// the buffer grown by the big message is left to GC
+		if buf.Cap() > wire.MaxPooledBuffer {
+			buf = wire.GetBuffer()
 		}
 
 		// Read the whole message. If the peer disconnects mid-way through
 		// ReadFull will return an error.
-		readBuffer := make([]byte, header.Len)
+		buf.Reset()
+		buf.Grow(int(header.Len))
+		readBuffer := buf.Bytes()[:header.Len]
 		_, err := io.ReadFull(input, readBuffer)
 		if err != nil {
-			logrus.Infof("Failed to read message: %v", err)
+			log.Debugf("failed to read message: %v", err)
 			break out
 		}
 
 		// Print the message for debugging purposes.
-		logrus.Debugf("Received message from %s: %02x%02x", p.conn.RemoteAddr(), header.Bytes(), readBuffer)
+		log.Debugf("received message: %02x%02x", header.Bytes(), readBuffer)
+
+		// the message is decoded from the buffer read
+		start := time.Now()
 
 		rl := bytes.NewReader(readBuffer)
 
@@ -207,8 +257,8 @@ out:
 				break out
 			}
 
-			logrus.Debugf("Received Ping from %s", p.conn.RemoteAddr().String())
-			p.sync.ProcessMessage(p, &msg)
+			log.Debug("received ping")
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypePong:
 			// update peer info
@@ -217,116 +267,156 @@ out:
 				break out
 			}
 
-			p.sync.ProcessMessage(p, &msg)
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeGetPeerAddrs:
-			logrus.Infof("receiving peer request (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving peer request")
 
 			var msg GetPeerAddrs
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			p.sync.ProcessMessage(p, &msg)
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypePeerAddrs:
-			logrus.Infof("receiving peer addrs (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving peer addrs")
 
 			var msg PeerAddrs
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			logrus.Infof("received %d peers", len(msg.peers))
-			p.sync.ProcessMessage(p, &msg)
+			log.Debugf("received %d peers", len(msg.peers))
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeGetHeaders:
-			logrus.Infof("receiving header request (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving header request")
 
 			var msg GetBlockHeaders
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			p.sync.ProcessMessage(p, &msg)
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeHeader:
-			logrus.Infof("header notification from peer %s", p.conn.RemoteAddr().String())
+			log.Debug("receiving header notification")
 
 			var msg BlockHeader
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			p.sync.ProcessMessage(p, &msg)
-
-		case consensus.MsgTypeHeaders:
-			logrus.Infof("receiving headers (%s)", p.conn.RemoteAddr().String())
-
-			var msg BlockHeaders
-			if exitError = msg.Read(rl); exitError != nil {
-				break out
-			}
-
-			logrus.Debug("headers: ", msg.Headers)
-			p.sync.ProcessMessage(p, &msg)
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeGetBlock:
-			logrus.Infof("receiving block request (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving block request")
 
 			var msg GetBlock
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			p.sync.ProcessMessage(p, &msg)
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeBlock:
-			logrus.Infof("receiving block (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving block")
 
+			// the block is validated while decoded
 			var msg consensus.Block
-			if exitError = msg.Read(rl); exitError != nil {
+			if exitError = p.readBlock(rl, &msg); exitError != nil {
 				break out
 			}
 
-			logrus.Info("block hash: ", hex.EncodeToString(msg.Header.Hash()))
-			p.sync.ProcessMessage(p, &msg)
+			log.WithFields(logging.Fields{
+				"height": msg.Header.Height,
+				"hash":   msg.Header.Hash().String(),
+			}).Debug("received block")
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeGetCompactBlock:
-			logrus.Infof("receiving compact block request (%s)", p.conn.RemoteAddr().String())
-			// TODO: impl it
+			log.Debug("receiving compact block request")
+
+			var msg GetCompactBlock
+			if exitError = msg.Read(rl); exitError != nil {
+				break out
+			}
+
+			p.process(header.Type, start, &msg)
 
 		case consensus.MsgTypeCompactBlock:
-			logrus.Infof("receiving compact block (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving compact block")
 
 			var msg consensus.CompactBlock
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			// TODO: process compact block
-			logrus.Info("compact block hash: ", hex.EncodeToString(msg.Header.Hash()))
+			log.WithFields(logging.Fields{
+				"height": msg.Header.Height,
+				"hash":   msg.Header.Hash().String(),
+			}).Debug("received compact block")
+
+			// the header is all the headers only need, the block of the
+			// coinbase only is hydrated, the other blocks are requested in
+			// full after the header is processed
+			if p.sync.headersOnly || len(msg.KernelIDs) > 0 {
+				p.process(header.Type, start, &BlockHeader{Header: msg.Header})
+			} else {
+				p.process(header.Type, start, &consensus.Block{Header: msg.Header, Outputs: msg.Outputs, Kernels: msg.Kernels})
+			}
 
 		case consensus.MsgTypeTransaction:
-			logrus.Infof("receiving transaction (%s)", p.conn.RemoteAddr().String())
+			log.Debug("receiving transaction")
 
 			var msg consensus.Transaction
 			if exitError = msg.Read(rl); exitError != nil {
 				break out
 			}
 
-			logrus.Debug("transaction: ", msg)
-			p.sync.ProcessMessage(p, &msg)
+			log.Debug("received transaction")
+			p.process(header.Type, start, &msg)
+
+		case consensus.MsgTypeStemTransaction:
+			log.Debug("receiving stem transaction")
+
+			var msg StemTransaction
+			if exitError = msg.Read(rl); exitError != nil {
+				break out
+			}
+
+			p.process(header.Type, start, &msg)
+
+		case consensus.MsgTypePoolKernels:
+			log.Debug("receiving pool kernels")
+
+			var msg PoolKernels
+			if exitError = msg.Read(rl); exitError != nil {
+				break out
+			}
+
+			p.process(header.Type, start, &msg)
+
+		case consensus.MsgTypeGetPoolTxs:
+			log.Debug("receiving pool transactions request")
+
+			var msg GetPoolTxs
+			if exitError = msg.Read(rl); exitError != nil {
+				break out
+			}
+
+			p.process(header.Type, start, &msg)
 
 		default:
 			// Print the content of the unknown message.
 			buff := make([]byte, header.Len)
 			if _, err := io.ReadFull(rl, buff); err != nil {
-				logrus.Debugf("failed to read message body: %v", err)
+				log.Debugf("failed to read message body: %v", err)
 				break out
 			}
 
-			logrus.Debugf("received unexpected message: %02x%02x", header.Bytes(), buff)
+			log.Debugf("received unexpected message: %02x%02x", header.Bytes(), buff)
 
 			exitError = fmt.Errorf("received unexpected message from peer: %v", header)
 			break out
@@ -334,20 +424,72 @@ out:
 
 		// update recv bytes counter
 		atomic.AddUint64(&p.bytesReceived, header.Len+consensus.HeaderLen)
+		atomic.AddUint64(&p.sync.bytesReceived, header.Len+consensus.HeaderLen)
+	}
+
+	// the peer is banned for the invalid block or the too large read
+	if misbehaving(exitError) {
+		p.sync.Pool.Ban(p.banAddr())
 	}
 
 	p.wg.Done()
 	p.Disconnect(exitError)
 }
 
+// readHeaders reads the headers message of n bytes from r, the headers are
+// validated by the chain one by one while decoded. The trailing bytes of the
+// message are skipped
+func (p *Peer) readHeaders(r io.Reader, n uint64) error {
+	p.logger().Debug("receiving headers")
+
+	lr := &io.LimitedReader{R: r, N: int64(n)}
+	start := time.Now()
+
+	var msg BlockHeaders
+	if err := msg.ReadValidate(lr, p.sync.Chain.ValidateHeader); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(ioutil.Discard, lr); err != nil {
+		return err
+	}
+
+	p.logger().WithFields(logging.Fields{"count": len(msg.Headers)}).Debug("received headers")
+	p.process(consensus.MsgTypeHeaders, start, &msg)
+
+	return nil
+}
+
+// process processes the message of the type decoded since start, the
+// decoding & the handling latencies are recorded
+func (p *Peer) process(msgType uint8, start time.Time, msg Message) {
+	decoded := time.Now()
+	p.sync.ProcessMessage(p, msg)
+	p.sync.metrics.observeMessage(msgType, start, decoded)
+}
+
+// readBlock reads the block by the chain within ProcessTimeout
+func (p *Peer) readBlock(r io.Reader, block *consensus.Block) error {
+	ctx, cancel := context.WithTimeout(p.sync.ctx, ProcessTimeout)
+	defer cancel()
+
+	return p.sync.Chain.ReadBlock(ctx, r, block)
+}
+
+// logger returns the sync logger of the peer messages
+func (p *Peer) logger() logging.Logger {
+	return p.sync.log.WithFields(logging.Fields{"peer": p.Addr})
+}
+
 // Disconnect closes peer connection
 func (p *Peer) Disconnect(reason error) {
 	if !atomic.CompareAndSwapInt32(&p.disconnect, 0, 1) {
 		return
 	}
 
-	logrus.Info("Disconnect peer: ", reason)
+	p.logger().Infof("disconnected peer: %v", reason)
 
+	p.reason = reason
 	close(p.quit)
 	p.conn.Close()
 	p.wg.Wait()
@@ -366,7 +508,7 @@ func (p *Peer) WaitForDisconnect() {
 
 // SendPing sends Ping request to peer
 func (p *Peer) SendPing() {
-	logrus.Infof("Sending Ping to %s", p.conn.RemoteAddr())
+	p.logger().Debug("sending ping")
 
 	var request Ping
 	request.TotalDifficulty = consensus.Difficulty(1)
@@ -377,7 +519,7 @@ func (p *Peer) SendPing() {
 
 // SendBlockRequest sends request block by hash
 func (p *Peer) SendBlockRequest(hash consensus.Hash) {
-	logrus.Infof("sending block request (%s)", hex.EncodeToString(hash[:6]))
+	p.logger().WithFields(logging.Fields{"hash": hash.String()}).Debug("sending block request")
 
 	var request GetBlock
 	request.Hash = hash
@@ -387,13 +529,16 @@ func (p *Peer) SendBlockRequest(hash consensus.Hash) {
 
 // SendBlock sends Block to peer
 func (p *Peer) SendBlock(block *consensus.Block) {
-	logrus.Info("sending block, height: ", block.Header.Height)
+	p.logger().WithFields(logging.Fields{
+		"height": block.Header.Height,
+		"hash":   block.Hash().String(),
+	}).Debug("sending block")
 	p.WriteMessage(block)
 }
 
 // SendPeerRequest sends peer request
 func (p *Peer) SendPeerRequest(capabilities consensus.Capabilities) {
-	logrus.Infof("Sending GetPeerAddrs to %s", p.conn.RemoteAddr())
+	p.logger().Debug("sending peer request")
 	var request GetPeerAddrs
 
 	request.Capabilities = capabilities
@@ -403,11 +548,11 @@ func (p *Peer) SendPeerRequest(capabilities consensus.Capabilities) {
 
 // SendHeaderRequest sends request headers
 func (p *Peer) SendHeaderRequest(locator consensus.Locator) {
-	logrus.Info("sending headers request")
+	p.logger().Debug("sending headers request")
 
 	if len(locator.Hashes) > consensus.MaxLocators {
-		logrus.Debug("locator hashes count: ", len(locator.Hashes))
-		logrus.Fatal(errors.New("too big locator hashes"))
+		p.logger().Errorf("too big locator hashes: %d", len(locator.Hashes))
+		return
 	}
 
 	var request GetBlockHeaders
@@ -418,6 +563,6 @@ func (p *Peer) SendHeaderRequest(locator consensus.Locator) {
 
 // SendTransaction sends tx to peer
 func (p *Peer) SendTransaction(tx consensus.Transaction) {
-	logrus.Info("sending transaction")
+	p.logger().Debug("sending transaction")
 	p.WriteMessage(&tx)
 }
@@ -0,0 +1,212 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"github.com/dblokhin/gringo/consensus"
+	"sync"
+	"time"
+)
+
+// peerInfo is the state of the known or connected peer, shared by the peers
+// pool & the message handlers of the peer. The fields are accessed under the
+// lock of the methods only
+type peerInfo struct {
+	mu sync.Mutex
+
+	status peerStatus
+	peer   *Peer
+
+	version         uint32
+	height          uint64
+	totalDifficulty consensus.Difficulty
+	capabilities    consensus.Capabilities
+
+	// lastConn is the time the peer was last seen connected, the unix zero
+	// if it was never connected
+	lastConn time.Time
+
+	// lastBlock is the time the peer delivered the new block, zero if never
+	lastBlock time.Time
+}
+
+// newPeerInfo returns the info of the new peer never connected
+func newPeerInfo() *peerInfo {
+	return &peerInfo{
+		status:          psNew,
+		totalDifficulty: consensus.ZeroDifficulty,
+		capabilities:    consensus.CapUnknown,
+		lastConn:        time.Unix(0, 0),
+	}
+}
+
+// newConnectedPeerInfo returns the info of the peer connected now
+func newConnectedPeerInfo(peer *Peer) *peerInfo {
+	pi := newPeerInfo()
+	pi.setConnected(peer)
+
+	return pi
+}
+
+// Status returns the peer status
+func (pi *peerInfo) Status() peerStatus {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.status
+}
+
+// Peer returns the connection of the peer, nil if it was never connected
+func (pi *peerInfo) Peer() *Peer {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.peer
+}
+
+// Dialable returns true if the peer is neither banned nor connected
+func (pi *peerInfo) Dialable() bool {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.status != psBanned && pi.status != psConnected
+}
+
+// Connect records the connection of the peer, false if the peer was banned
+// or connected while dialed
+func (pi *peerInfo) Connect(peer *Peer) bool {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if pi.status == psBanned || pi.status == psConnected {
+		return false
+	}
+	pi.setConnected(peer)
+
+	return true
+}
+
+// setConnected records the connection & the handshake info of the peer,
+// must be called with the lock held
+func (pi *peerInfo) setConnected(peer *Peer) {
+	pi.peer = peer
+	pi.status = psConnected
+	pi.lastConn = time.Now()
+
+	pi.version = peer.Info.Version
+	pi.height = peer.Info.Height
+	pi.totalDifficulty = peer.Info.TotalDifficulty
+	pi.capabilities = peer.Info.Capabilities
+}
+
+// Fail records the failed connection, the banned peer stays banned
+func (pi *peerInfo) Fail() {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if pi.status != psBanned {
+		pi.status = psFailedConn
+	}
+}
+
+// Disconnect records the disconnect of the peer, the peer was verified until
+// now. The banned peer stays banned
+func (pi *peerInfo) Disconnect() {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if pi.status != psBanned {
+		pi.status = psDisconnected
+	}
+	pi.lastConn = time.Now()
+}
+
+// Ban marks the peer banned, returns the connection of the peer & true if it
+// was connected
+func (pi *peerInfo) Ban() (*Peer, bool) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	connected := pi.status == psConnected
+	pi.status = psBanned
+
+	return pi.peer, connected
+}
+
+// Chain returns the total difficulty & the height of the peer chain
+func (pi *peerInfo) Chain() (consensus.Difficulty, uint64) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.totalDifficulty, pi.height
+}
+
+// SetChain sets the total difficulty & the height of the peer chain of the
+// ping or the pong
+func (pi *peerInfo) SetChain(totalDifficulty consensus.Difficulty, height uint64) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	pi.totalDifficulty = totalDifficulty
+	pi.height = height
+}
+
+// DeliverBlock records the block of the peer: the peer chain is at least of
+// the header, the accepted new block makes the peer the candidate of the
+// high bandwidth peers
+func (pi *peerInfo) DeliverBlock(header *consensus.BlockHeader, accepted bool) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if pi.totalDifficulty < header.TotalDifficulty || pi.height < header.Height {
+		pi.totalDifficulty = header.TotalDifficulty
+		pi.height = header.Height
+	}
+
+	if accepted {
+		pi.lastBlock = time.Now()
+	}
+}
+
+// LastBlock returns the time the peer delivered the new block, zero if never
+func (pi *peerInfo) LastBlock() time.Time {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.lastBlock
+}
+
+// Gossip returns the status, the capabilities & the last connection time to
+// choose the peers advertised to the others
+func (pi *peerInfo) Gossip() (peerStatus, consensus.Capabilities, time.Time) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	return pi.status, pi.capabilities, pi.lastConn
+}
+
+// Stats returns the public info of the peer of addr, the user agent & the
+// identity are of the connected peer
+func (pi *peerInfo) Stats(addr string) PeerStats {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	stats := PeerStats{
+		Addr:            addr,
+		Status:          pi.status.String(),
+		Version:         pi.version,
+		Capabilities:    pi.capabilities,
+		TotalDifficulty: pi.totalDifficulty,
+		Height:          pi.height,
+	}
+
+	if pi.peer != nil && pi.status == psConnected {
+		stats.UserAgent = pi.peer.Info.UserAgent
+		stats.Inbound = pi.peer.Inbound
+		stats.NodeID = pi.peer.Info.NodeID
+	}
+
+	return stats
+}
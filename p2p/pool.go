@@ -8,28 +8,53 @@ import (
 	"errors"
 	"fmt"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/logging"
 	"net"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// maxOnlineConnections should be override
-// TODO: setting up by config
+// maxOnlineConnections should be override by SetMaxOnlineConnections
 var (
 	maxOnlineConnections = 15
 	maxPeersTableSize    = 10000
 )
 
+// maxPeersLimit is the capacity of the connection slots, the bound of the
+// max peers changed at runtime
+const maxPeersLimit = 1024
+
+// highBandwidthPeers is the count of the peers the new blocks are pushed to
+// in full, the other peers get the header announcement & request the block
+const highBandwidthPeers = 3
+
+// peerAddrsFreshness is the max age of the last connection to the gossiped
+// peer, the never connected peers are not gossiped
+const peerAddrsFreshness = 24 * time.Hour
+
+// SetMaxOnlineConnections sets the limit of connected peers, must be called
+// before NewSyncer
+func SetMaxOnlineConnections(n int) {
+	if n > 0 {
+		maxOnlineConnections = n
+	}
+}
+
 // newPeersPool returns peers pool instance
 func newPeersPool(sync *Syncer) *peersPool {
 	pp := &peersPool{
 		connected:      0,
 		sync:           sync,
-		pool:           make(chan struct{}, maxOnlineConnections),
+		log:            sync.log,
+		pool:           make(chan struct{}, maxPeersLimit),
+		maxPeers:       int32(maxOnlineConnections),
 		quit:           make(chan int),
 		PeersTable:     make(map[string]*peerInfo),
 		ConnectedPeers: make(map[string]*peerInfo),
+		BannedPeers:    make(map[string]struct{}),
+		BannedNets:     make(map[string]*net.IPNet),
 	}
 
 	return pp
@@ -39,13 +64,16 @@ func newPeersPool(sync *Syncer) *peersPool {
 type peersPool struct {
 	ptmu sync.Mutex // mutex for PeersTable
 	cpmu sync.Mutex // mutex for ConnectedPeers
-	bnmu sync.Mutex // mutex for BannedPeers
+	bnmu sync.Mutex // mutex for BannedPeers & BannedNets
 
 	connected int32
 	sync      *Syncer
+	log       logging.Logger
 
-	pool chan struct{}
-	quit chan int
+	// pool is the connection slots, maxPeers of them are taken at most
+	pool     chan struct{}
+	maxPeers int32
+	quit     chan int
 
 	// all peers
 	PeersTable map[string]*peerInfo
@@ -55,40 +83,153 @@ type peersPool struct {
 
 	// banned peers
 	BannedPeers map[string]struct{}
+
+	// banned ranges by CIDR
+	BannedNets map[string]*net.IPNet
+
+	// banFile persists the ban list, the empty one isn't persisted
+	banFile string
+
+	// connLog is the audit log of the latest connections
+	connLog connLog
 }
 
-// Ban closes connection & ban peer
-func (pp *peersPool) Ban(addr string) {
-	pp.ptmu.Lock()
-	peerInfo, ok := pp.PeersTable[addr]
-	pp.ptmu.Unlock()
+// full returns true if the taken slots exceed the max peers, the lowered
+// limit keeps the connected peers & refuses the new ones
+func (pp *peersPool) full() bool {
+	return len(pp.pool) > int(atomic.LoadInt32(&pp.maxPeers))
+}
 
-	if !ok {
-		return
+// setMaxPeers changes the max count of the connected peers
+func (pp *peersPool) setMaxPeers(n int) {
+	if n > maxPeersLimit {
+		n = maxPeersLimit
+	}
+
+	if n > 0 {
+		atomic.StoreInt32(&pp.maxPeers, int32(n))
 	}
+}
 
-	// Mark banned & Close connection
-	peerInfo.Status = psBanned
-	peerInfo.Peer.Close()
+// Ban closes connection & ban peer, the unknown addr is banned too. The CIDR
+// range bans all its peers: the connected, the known & the future ones
+func (pp *peersPool) Ban(addr string) {
+	addr, ipNet, err := parseBan(addr)
+	if err != nil {
+		pp.log.Infof("not banned: %v", err)
+		return
+	}
 
 	// Add to ban list
 	pp.bnmu.Lock()
-	pp.BannedPeers[addr] = struct{}{}
+	if ipNet != nil {
+		pp.BannedNets[addr] = ipNet
+	} else {
+		pp.BannedPeers[addr] = struct{}{}
+	}
+	pp.saveBans()
 	pp.bnmu.Unlock()
 
-	// Clear the peers table
+	addrs := []string{addr}
+	if ipNet != nil {
+		addrs = pp.addrsInNet(ipNet)
+	}
+
+	// the ban is recorded for the range & every banned connected peer
+	banned := 0
+	for _, addr := range addrs {
+		// Mark banned & Close connection
+		if peerInfo := pp.PeerInfo(addr); peerInfo != nil {
+			if peer, connected := peerInfo.Ban(); peer != nil {
+				if connected {
+					pp.connLog.add(peer.connEvent(ConnBan))
+					banned++
+				}
+				peer.Disconnect(errors.New("banned"))
+			}
+		}
+
+		// Clear the peers table
+		pp.ptmu.Lock()
+		delete(pp.PeersTable, addr)
+		pp.ptmu.Unlock()
+	}
+
+	if ipNet != nil || banned == 0 {
+		pp.connLog.add(ConnEvent{Time: time.Now(), Event: ConnBan, Addr: addr})
+	}
+}
+
+// addrsInNet returns the known & connected addrs of the range
+func (pp *peersPool) addrsInNet(ipNet *net.IPNet) []string {
+	var result []string
+
 	pp.ptmu.Lock()
-	delete(pp.PeersTable, addr)
+	for addr := range pp.PeersTable {
+		if ip := hostIP(addr); ip != nil && ipNet.Contains(ip) {
+			result = append(result, addr)
+		}
+	}
 	pp.ptmu.Unlock()
+
+	pp.cpmu.Lock()
+	for addr := range pp.ConnectedPeers {
+		if ip := hostIP(addr); ip != nil && ipNet.Contains(ip) {
+			result = append(result, addr)
+		}
+	}
+	pp.cpmu.Unlock()
+
+	return result
+}
+
+// Unban removes addr or CIDR range from the ban list, the addr is added to
+// the peers table
+func (pp *peersPool) Unban(addr string) {
+	addr, ipNet, err := parseBan(addr)
+	if err != nil {
+		return
+	}
+
+	pp.bnmu.Lock()
+	if ipNet != nil {
+		delete(pp.BannedNets, addr)
+	} else {
+		delete(pp.BannedPeers, addr)
+	}
+	pp.saveBans()
+	pp.bnmu.Unlock()
+
+	if ipNet == nil {
+		pp.Add(addr)
+	}
 }
 
-// IsBan returns true if addr is banned
+// IsBan returns true if addr or its range is banned
 func (pp *peersPool) IsBan(addr string) bool {
 	pp.bnmu.Lock()
 	defer pp.bnmu.Unlock()
 
-	_, ok := pp.BannedPeers[addr]
-	return ok
+	if _, ok := pp.BannedPeers[addr]; ok {
+		return true
+	}
+
+	if len(pp.BannedNets) == 0 {
+		return false
+	}
+
+	ip := hostIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range pp.BannedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // AddPeer adds new peer addr to pm
@@ -104,7 +245,7 @@ func (pp *peersPool) Add(addr string) {
 		return
 	}
 
-	if netAddr.Port == 0 {
+	if netAddr.Port == 0 || pp.IsBan(addr) {
 		return
 	}
 
@@ -122,44 +263,73 @@ func (pp *peersPool) Add(addr string) {
 	}
 
 	// Adds new
-	pp.PeersTable[addr] = &peerInfo{
-		Status:          psNew,
-		Peer:            nil,
-		ProtocolVersion: 0,
-		Height:          0,
-		TotalDifficulty: consensus.ZeroDifficulty,
-		Capabilities:    consensus.CapUnknown,
-		LastConn:        time.Unix(0, 0),
-	}
+	pp.PeersTable[addr] = newPeerInfo()
 }
 
-// PeerAddrs returns peer list (no banned)
-func (pp *peersPool) Peers(capabilities consensus.Capabilities) *PeerAddrs {
-	addrs := make([]*net.TCPAddr, 0)
+// Peers returns the peers dialed within peerAddrsFreshness for the requester
+// addr, the connected & the recently connected peers first (no banned, no
+// failed, no requester host). The addrs of the local networks are returned
+// only to the requester of the local network
+func (pp *peersPool) Peers(capabilities consensus.Capabilities, requester string) *PeerAddrs {
+	type verified struct {
+		addr     string
+		lastConn time.Time
+	}
 
-	// Getting peers randomly
-	pp.ptmu.Lock()
-	defer pp.ptmu.Unlock()
+	requesterIP := hostIP(requester)
+	local := requesterIP != nil && isLocal(requesterIP)
+
+	var candidates []verified
+	now := time.Now()
 
+	pp.ptmu.Lock()
 	for addr, peerInfo := range pp.PeersTable {
-		if peerInfo.Status == psBanned || peerInfo.Status == psFailedConn {
+		// the host names are gossiped as they are
+		if ip := hostIP(addr); ip != nil {
+			if ip.Equal(requesterIP) || ip.IsUnspecified() || ip.IsMulticast() || (isLocal(ip) && !local) {
+				continue
+			}
+		}
+
+		status, caps, lastConn := peerInfo.Gossip()
+
+		if status == psBanned || status == psFailedConn {
 			continue
 		}
 
 		// filter by capabilities
-		if (peerInfo.Capabilities & capabilities) != capabilities {
+		if (caps & capabilities) != capabilities {
 			continue
 		}
 
-		if netAddr, err := net.ResolveTCPAddr("tcp", addr); err == nil {
-			addrs = append(addrs, netAddr)
-		} else {
-			logrus.Error(err)
+		// the connected peer is verified now
+		if status == psConnected {
+			lastConn = now
+		}
+
+		if now.Sub(lastConn) > peerAddrsFreshness {
+			continue
 		}
 
+		candidates = append(candidates, verified{addr, lastConn})
+	}
+	pp.ptmu.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastConn.After(candidates[j].lastConn)
+	})
+
+	addrs := make([]*net.TCPAddr, 0)
+	for _, candidate := range candidates {
 		if len(addrs) == consensus.MaxPeerAddrs {
 			break
 		}
+
+		if netAddr, err := net.ResolveTCPAddr("tcp", candidate.addr); err == nil {
+			addrs = append(addrs, netAddr)
+		} else {
+			pp.log.Error(err)
+		}
 	}
 
 	return &PeerAddrs{
@@ -167,7 +337,8 @@ func (pp *peersPool) Peers(capabilities consensus.Capabilities) *PeerAddrs {
 	}
 }
 
-// PeerInfo returns peer structure
+// PeerInfo returns peer structure, inbound peers are found only while
+// connected
 func (pp *peersPool) PeerInfo(addr string) *peerInfo {
 	pp.ptmu.Lock()
 	peerInfo, ok := pp.PeersTable[addr]
@@ -176,23 +347,137 @@ func (pp *peersPool) PeerInfo(addr string) *peerInfo {
 		return peerInfo
 	}
 
+	pp.cpmu.Lock()
+	peerInfo, ok = pp.ConnectedPeers[addr]
+	pp.cpmu.Unlock()
+	if ok {
+		return peerInfo
+	}
+
 	return nil
 }
 
-// PropagateBlock propagates block to connected peers
+// Connected returns stats of the connected peers
+func (pp *peersPool) Connected() []PeerStats {
+	pp.cpmu.Lock()
+	defer pp.cpmu.Unlock()
+
+	result := make([]PeerStats, 0, len(pp.ConnectedPeers))
+	for addr, peerInfo := range pp.ConnectedPeers {
+		result = append(result, peerInfo.Stats(addr))
+	}
+
+	return result
+}
+
+// All returns stats of the known, connected & banned peers
+func (pp *peersPool) All() []PeerStats {
+	result := pp.Connected()
+
+	connected := make(map[string]bool)
+	for _, stats := range result {
+		connected[stats.Addr] = true
+	}
+
+	pp.ptmu.Lock()
+	for addr, peerInfo := range pp.PeersTable {
+		if connected[addr] {
+			continue
+		}
+
+		result = append(result, peerInfo.Stats(addr))
+	}
+	pp.ptmu.Unlock()
+
+	pp.bnmu.Lock()
+	for addr := range pp.BannedPeers {
+		if !connected[addr] {
+			result = append(result, PeerStats{Addr: addr, Status: psBanned.String()})
+		}
+	}
+	for cidr := range pp.BannedNets {
+		result = append(result, PeerStats{Addr: cidr, Status: psBanned.String()})
+	}
+	pp.bnmu.Unlock()
+
+	return result
+}
+
+// PropagateBlock propagates block to connected peers: the high bandwidth
+// peers get the block, the others the header announcement
 func (pp *peersPool) PropagateBlock(block *consensus.Block) {
 	pp.cpmu.Lock()
 	defer pp.cpmu.Unlock()
 
+	full := pp.highBandwidth()
+	header := &BlockHeader{Header: block.Header}
+
 	for _, pi := range pp.ConnectedPeers {
-		go func(peerInfo *peerInfo) {
+		go func(peerInfo *peerInfo, full bool) {
 			// propagate if peer height or totalDiff less than newest block
-			if peerInfo.Height < block.Header.Height || peerInfo.TotalDifficulty < block.Header.TotalDifficulty {
-				if peer := peerInfo.Peer; peer != nil {
-					peer.SendBlock(block)
-				}
+			totalDifficulty, height := peerInfo.Chain()
+			behind := height < block.Header.Height || totalDifficulty < block.Header.TotalDifficulty
+			peer := peerInfo.Peer()
+
+			if !behind || peer == nil {
+				return
 			}
-		}(pi)
+
+			if full {
+				peer.SendBlock(block)
+			} else {
+				peer.WriteMessage(header)
+			}
+		}(pi, full[pi])
+	}
+}
+
+// highBandwidth returns the connected peers delivered the new blocks most
+// recently, up to highBandwidthPeers. Must be called with cpmu locked
+func (pp *peersPool) highBandwidth() map[*peerInfo]bool {
+	type delivery struct {
+		peerInfo  *peerInfo
+		lastBlock time.Time
+	}
+
+	var deliveries []delivery
+	for _, peerInfo := range pp.ConnectedPeers {
+		if lastBlock := peerInfo.LastBlock(); !lastBlock.IsZero() {
+			deliveries = append(deliveries, delivery{peerInfo, lastBlock})
+		}
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].lastBlock.After(deliveries[j].lastBlock)
+	})
+
+	result := make(map[*peerInfo]bool)
+	for i := 0; i < len(deliveries) && i < highBandwidthPeers; i++ {
+		result[deliveries[i].peerInfo] = true
+	}
+
+	return result
+}
+
+// PropagateTx sends transaction to the connected peers if fluff is set,
+// otherwise to the random peer as the stem transaction
+func (pp *peersPool) PropagateTx(tx *consensus.Transaction, fluff bool) {
+	pp.cpmu.Lock()
+	defer pp.cpmu.Unlock()
+
+	// the map order is random
+	for _, peerInfo := range pp.ConnectedPeers {
+		peer := peerInfo.Peer()
+		if peer == nil {
+			continue
+		}
+
+		if !fluff {
+			go peer.WriteMessage(&StemTransaction{Transaction: *tx})
+			return
+		}
+
+		go peer.SendTransaction(*tx)
 	}
 }
 
@@ -203,7 +488,7 @@ func (pp *peersPool) connectPeer(addr string) error {
 		return nil
 	}
 
-	if pp.connected > int32(maxOnlineConnections) {
+	if atomic.LoadInt32(&pp.connected) > atomic.LoadInt32(&pp.maxPeers) {
 		return errors.New("too big online peers connections")
 	}
 
@@ -215,36 +500,32 @@ func (pp *peersPool) connectPeer(addr string) error {
 		return errors.New("peer doesn't exists at peersTable")
 	}
 
-	peerInfo.Lock()
-	defer peerInfo.Unlock()
-
-	if peerInfo.Status == psBanned || peerInfo.Status == psConnected {
-		logrus.Debug("dont connect to banned host (or already connected)")
+	if !peerInfo.Dialable() || pp.IsBan(addr) {
+		pp.log.Debug("dont connect to banned host (or already connected)")
 		return nil
 	}
 
+	// the peer info isn't locked while dialing, the peer banned meanwhile
+	// isn't connected
 	peerConn, err := NewPeer(pp.sync, addr)
 	if err != nil {
-		peerInfo.Status = psFailedConn
+		peerInfo.Fail()
 		return err
 	}
 
 	// Check the Protocol version
 	if peerConn.Info.Version != consensus.ProtocolVersion {
+		peerConn.Close()
+		peerInfo.Fail()
 		return fmt.Errorf("unexpected protocolVersion: %d", peerConn.Info.Version)
 	}
 
-	pp.connected++
-
 	// update peers table
-	peerInfo.Peer = peerConn
-	peerInfo.Status = psConnected
-	peerInfo.LastConn = time.Now()
-
-	peerInfo.ProtocolVersion = peerConn.Info.Version
-	peerInfo.Height = peerConn.Info.Height
-	peerInfo.TotalDifficulty = peerConn.Info.TotalDifficulty
-	peerInfo.Capabilities = peerConn.Info.Capabilities
+	if !peerInfo.Connect(peerConn) {
+		peerConn.Close()
+		return errors.New("peer is banned or connected while dialing")
+	}
+	atomic.AddInt32(&pp.connected, 1)
 
 	// update connected peers
 	pp.cpmu.Lock()
@@ -252,22 +533,136 @@ func (pp *peersPool) connectPeer(addr string) error {
 	pp.cpmu.Unlock()
 
 	// And send ping / peers request
+	pp.logConnect(peerConn)
 	peerConn.Start()
 	peerConn.SendPing()
 	peerConn.SendPeerRequest(consensus.CapFullNode)
+	pp.sync.sendPoolKernels(peerConn)
 
 	// on disconnect update info
 	go func() {
 		peerConn.WaitForDisconnect()
-		logrus.Infof("closed peer connection (%s)", addr)
+		pp.log.WithFields(logging.Fields{"peer": addr}).Debug("closed peer connection")
+		pp.logDisconnect(peerConn)
 
-		// update peers & connected peers tables
-		peerInfo.Lock()
-		peerInfo.Status = psDisconnected
-		peerInfo.Unlock()
+		// update peers & connected peers tables, the peer was verified
+		// until the disconnect
+		peerInfo.Disconnect()
 
 		// clean connected peers
-		pp.connected--
+		atomic.AddInt32(&pp.connected, -1)
+		pp.cpmu.Lock()
+		delete(pp.ConnectedPeers, addr)
+		pp.cpmu.Unlock()
+
+		<-pp.pool
+	}()
+
+	return nil
+}
+
+// Listen accepts inbound connections on addr until the pool is stopped
+func (pp *peersPool) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	pp.Serve(listener)
+	return nil
+}
+
+// Serve accepts inbound connections on listener until the pool is stopped,
+// the listener port is advertised by the handshakes
+func (pp *peersPool) Serve(listener net.Listener) {
+	pp.log.Infof("listening for peers on %s", listener.Addr())
+
+	if addr, ok := listener.Addr().(*net.TCPAddr); ok {
+		pp.sync.listenPort = uint16(addr.Port)
+	}
+
+	go func() {
+		<-pp.quit
+		listener.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				select {
+				case <-pp.quit:
+					return
+				default:
+				}
+
+				pp.log.Error(err)
+				continue
+			}
+
+			go func() {
+				if err := pp.acceptPeer(conn); err != nil {
+					pp.log.WithFields(logging.Fields{"peer": conn.RemoteAddr().String()}).Infof("failed to accept peer: %v", err)
+					conn.Close()
+				}
+			}()
+		}
+	}()
+}
+
+// acceptPeer makes handshake with inbound conn & adds peer to the connected
+// peers table
+func (pp *peersPool) acceptPeer(conn net.Conn) error {
+	addr := conn.RemoteAddr().String()
+
+	if pp.IsBan(addr) {
+		return errors.New("peer is banned")
+	}
+
+	// the pool slot is taken until the peer disconnects
+	select {
+	case pp.pool <- struct{}{}:
+	default:
+		return errors.New("too big online peers connections")
+	}
+
+	if pp.full() {
+		<-pp.pool
+		return errors.New("too big online peers connections")
+	}
+
+	peerConn, err := AcceptNewPeer(pp.sync, conn)
+	if err != nil {
+		<-pp.pool
+		return err
+	}
+
+	// Check the Protocol version
+	if peerConn.Info.Version != consensus.ProtocolVersion {
+		<-pp.pool
+		return fmt.Errorf("unexpected protocolVersion: %d", peerConn.Info.Version)
+	}
+
+	peerInfo := newConnectedPeerInfo(peerConn)
+
+	// inbound addrs have ephemeral ports, so the peer is kept only in the
+	// connected peers table
+	atomic.AddInt32(&pp.connected, 1)
+	pp.cpmu.Lock()
+	pp.ConnectedPeers[addr] = peerInfo
+	pp.cpmu.Unlock()
+
+	pp.logConnect(peerConn)
+	peerConn.Start()
+	peerConn.SendPing()
+	pp.sync.sendPoolKernels(peerConn)
+
+	go func() {
+		peerConn.WaitForDisconnect()
+		pp.log.WithFields(logging.Fields{"peer": addr}).Debug("closed inbound peer connection")
+		pp.logDisconnect(peerConn)
+
+		atomic.AddInt32(&pp.connected, -1)
 		pp.cpmu.Lock()
 		delete(pp.ConnectedPeers, addr)
 		pp.cpmu.Unlock()
@@ -288,8 +683,13 @@ out:
 			break out
 
 		case pp.pool <- struct{}{}:
-			if err := pp.connectPeer(pp.notConnected()); err != nil {
-				logrus.Error(err)
+			// the slot is released if there is no peer to connect
+			if pp.full() {
+				<-pp.pool
+			} else if addr := pp.notConnected(); addr == "" {
+				<-pp.pool
+			} else if err := pp.connectPeer(addr); err != nil {
+				pp.log.Error(err)
 				<-pp.pool
 			}
 
@@ -297,18 +697,19 @@ out:
 		}
 	}
 
-	// Close all connections
-	pp.ptmu.Lock()
-	defer pp.ptmu.Unlock()
+	// Close all connections, inbound peers are only in the connected peers
+	pp.cpmu.Lock()
+	defer pp.cpmu.Unlock()
 
-	for _, pi := range pp.PeersTable {
+	for _, pi := range pp.ConnectedPeers {
 		go func(peerInfo *peerInfo) {
-			peerInfo.Lock()
-			peerInfo.Peer.Close()
-			peerInfo.Status = psDisconnected
-			peerInfo.Unlock()
-		}(pi)
+			peerInfo.Disconnect()
 
+			// the peer handlers may wait for the peers pool
+			if peer := peerInfo.Peer(); peer != nil {
+				peer.Close()
+			}
+		}(pi)
 	}
 }
 
@@ -324,14 +725,14 @@ func (pp *peersPool) notConnected() string {
 
 	// first, find good peers
 	for addr, peerInfo := range pp.PeersTable {
-		if peerInfo.Status == psNew || peerInfo.Status == psDisconnected {
+		if status := peerInfo.Status(); status == psNew || status == psDisconnected {
 			return addr
 		}
 	}
 
 	// second, try to open conn with failed nodes
 	for addr, peer := range pp.PeersTable {
-		if peer.Status == psFailedConn {
+		if peer.Status() == psFailedConn {
 			return addr
 		}
 	}
@@ -339,18 +740,19 @@ func (pp *peersPool) notConnected() string {
 	return ""
 }
 
-type peerInfo struct {
-	sync.Mutex
-
-	Status peerStatus
-	Peer   *Peer
-
-	ProtocolVersion uint32
-	Height          uint64
-	TotalDifficulty consensus.Difficulty
+// PeerStats is the public info about peer
+type PeerStats struct {
+	Addr string
+	// Status is one of new, connected, banned, disconnected or failed
+	Status          string
+	Version         uint32
+	UserAgent       string
 	Capabilities    consensus.Capabilities
-
-	LastConn time.Time
+	TotalDifficulty consensus.Difficulty
+	Height          uint64
+	Inbound         bool
+	// NodeID is the identity of the connected peer, zero if it has none
+	NodeID NodeID
 }
 
 type peerStatus int
@@ -362,3 +764,21 @@ const (
 	psDisconnected
 	psFailedConn
 )
+
+// String returns the status name
+func (s peerStatus) String() string {
+	switch s {
+	case psNew:
+		return "new"
+	case psConnected:
+		return "connected"
+	case psBanned:
+		return "banned"
+	case psDisconnected:
+		return "disconnected"
+	case psFailedConn:
+		return "failed"
+	}
+
+	return "unknown"
+}
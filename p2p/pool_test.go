@@ -0,0 +1,864 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dialInbound connects to ln & makes the handshake of the given protocol version
+func dialInbound(t *testing.T, ln net.Listener, version uint32) net.Conn {
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := hand{
+		Version:         version,
+		Capabilities:    consensus.CapFullNode,
+		Nonce:           1,
+		TotalDifficulty: consensus.Difficulty(1),
+		SenderAddr:      conn.LocalAddr().(*net.TCPAddr),
+		ReceiverAddr:    conn.RemoteAddr().(*net.TCPAddr),
+		UserAgent:       "test",
+	}
+
+	if _, err := WriteMessage(conn, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func TestAcceptPeer(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client := dialInbound(t, ln, consensus.ProtocolVersion)
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pp.acceptPeer(conn); err != nil {
+		t.Fatalf("failed to accept peer: %v", err)
+	}
+
+	addr := conn.RemoteAddr().String()
+	if len(pp.PeersTable) != 0 {
+		t.Errorf("inbound peer is added to the peers table")
+	}
+	if pp.PeerInfo(addr) == nil {
+		t.Errorf("inbound peer is not found among the connected peers")
+	}
+	if n := atomic.LoadInt32(&pp.connected); n != 1 {
+		t.Errorf("connected was %d, want 1", n)
+	}
+
+	client.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pp.connected) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("inbound peer is not released on disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if pp.PeerInfo(addr) != nil {
+		t.Errorf("disconnected inbound peer is still connected")
+	}
+}
+
+func TestAcceptPeerHeadersOnly(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	sync.SetHeadersOnly()
+	pp := sync.Pool.(*peersPool)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client := dialInbound(t, ln, consensus.ProtocolVersion)
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pp.acceptPeer(conn); err != nil {
+		t.Fatalf("failed to accept peer: %v", err)
+	}
+
+	var sh shake
+	if _, err := ReadMessage(client, &sh); err != nil {
+		t.Fatal(err)
+	}
+
+	if sh.Capabilities != consensus.CapPeerList {
+		t.Errorf("capabilities were %d, want %d", sh.Capabilities, consensus.CapPeerList)
+	}
+}
+
+func TestAcceptPeerVersion(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client := dialInbound(t, ln, consensus.ProtocolVersion+1)
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := pp.acceptPeer(conn); err == nil {
+		t.Errorf("peer of the unexpected protocol version is accepted")
+	}
+
+	if len(pp.ConnectedPeers) != 0 || atomic.LoadInt32(&pp.connected) != 0 {
+		t.Errorf("rejected peer is counted as connected")
+	}
+
+	if len(pp.pool) != 0 {
+		t.Errorf("rejected peer holds the pool slot")
+	}
+}
+
+// stubChain is the chain of the given block & header heights
+type stubChain struct {
+	Blockchain
+	height, header  uint64
+	totalDifficulty consensus.Difficulty
+}
+
+func (c *stubChain) Height() uint64                        { return c.height }
+func (c *stubChain) TotalDifficulty() consensus.Difficulty { return c.totalDifficulty }
+func (c *stubChain) HeaderHead() consensus.BlockHeader {
+	return consensus.BlockHeader{Height: c.header}
+}
+
+func TestSyncStatus(t *testing.T) {
+	tests := []struct {
+		height, header, peer uint64
+		stage                string
+		percent              int
+	}{
+		{height: 10, header: 10, stage: SyncAwaitingPeers, percent: 100},
+		{height: 10, header: 20, peer: 40, stage: SyncHeaders, percent: 25},
+		{height: 10, header: 40, peer: 40, stage: SyncBlocks, percent: 25},
+		{height: 40, header: 40, peer: 30, stage: SyncDone, percent: 100},
+	}
+
+	for _, test := range tests {
+		sync := NewSyncer(nil, &stubChain{height: test.height, header: test.header}, nil)
+		sync.SetLogger(logging.Nop)
+
+		if test.peer != 0 {
+			pp := sync.Pool.(*peersPool)
+			pp.ConnectedPeers["127.0.0.1:13414"] = &peerInfo{height: test.peer, status: psConnected}
+		}
+
+		status := sync.Status()
+		if status.Stage != test.stage || status.Percent != test.percent {
+			t.Errorf("%d/%d/%d: status was %s %d%%, want %s %d%%", test.height, test.header, test.peer,
+				status.Stage, status.Percent, test.stage, test.percent)
+		}
+	}
+}
+
+func TestInitialBlockDownload(t *testing.T) {
+	chain := &stubChain{height: 90, totalDifficulty: 90}
+	sync := NewSyncer(nil, chain, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	if !sync.InitialBlockDownload() {
+		t.Error("ibd was done without peers")
+	}
+
+	// the height of the peer ahead is unknown until the ping
+	peer := &peerInfo{totalDifficulty: 200, status: psConnected}
+	pp.ConnectedPeers["127.0.0.1:13414"] = peer
+	if !sync.InitialBlockDownload() {
+		t.Error("ibd was done behind the peer of the unknown height")
+	}
+
+	peer.SetChain(200, 100)
+	if !sync.InitialBlockDownload() {
+		t.Errorf("ibd was done %d blocks behind the peer", 100-chain.height)
+	}
+
+	peer.SetChain(200, 90+DefaultIBDDistance)
+	if sync.InitialBlockDownload() {
+		t.Errorf("ibd wasn't done %d blocks behind the peer", DefaultIBDDistance)
+	}
+
+	// the done ibd isn't resumed
+	peer.SetChain(200, 200)
+	if sync.InitialBlockDownload() {
+		t.Error("ibd was resumed")
+	}
+
+	// no peer has more work
+	sync = NewSyncer(nil, chain, nil)
+	sync.SetLogger(logging.Nop)
+	sync.Pool.(*peersPool).ConnectedPeers["127.0.0.1:13414"] = &peerInfo{totalDifficulty: 90, status: psConnected}
+	if sync.InitialBlockDownload() {
+		t.Error("ibd wasn't done without the peers ahead")
+	}
+}
+
+func TestResolveSeeds(t *testing.T) {
+	defer func(lookup func(string) ([]string, error)) { lookupHost = lookup }(lookupHost)
+	lookupHost = func(host string) ([]string, error) {
+		if host != "seed.example.org" {
+			return nil, errors.New("no such host")
+		}
+		return []string{"10.0.0.1", "10.0.0.2"}, nil
+	}
+
+	sync := NewSyncer([]string{"10.0.0.3:13414"}, nil, nil)
+	sync.SetLogger(logging.Nop)
+	sync.SetDNSSeeds([]string{"seed.example.org:3414", "unknown.example.org:3414", "invalid"})
+	sync.resolveSeeds()
+
+	pp := sync.Pool.(*peersPool)
+	for _, addr := range []string{"10.0.0.1:3414", "10.0.0.2:3414", "10.0.0.3:13414"} {
+		if _, ok := pp.PeersTable[addr]; !ok {
+			t.Errorf("peer %s is not added", addr)
+		}
+	}
+
+	if len(pp.PeersTable) != 3 {
+		t.Errorf("peers table has %d peers, want 3", len(pp.PeersTable))
+	}
+}
+
+func TestBan(t *testing.T) {
+	sync := NewSyncer([]string{"10.0.0.1:13414"}, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	// the known & the unknown peers are banned
+	pp.Ban("10.0.0.1:13414")
+	pp.Ban("10.0.0.2:13414")
+
+	if _, ok := pp.PeersTable["10.0.0.1:13414"]; ok {
+		t.Errorf("banned peer is in the peers table")
+	}
+
+	pp.Add("10.0.0.2:13414")
+	if _, ok := pp.PeersTable["10.0.0.2:13414"]; ok {
+		t.Errorf("banned peer is added to the peers table")
+	}
+
+	banned := 0
+	for _, stats := range pp.All() {
+		if stats.Status == "banned" {
+			banned++
+		}
+	}
+	if banned != 2 {
+		t.Errorf("banned peers count was %d, want 2", banned)
+	}
+
+	if bans := pp.Bans(); len(bans) != 2 || bans[0] != "10.0.0.1:13414" || bans[1] != "10.0.0.2:13414" {
+		t.Errorf("ban list was %v", bans)
+	}
+
+	pp.Unban("10.0.0.1:13414")
+	if pp.IsBan("10.0.0.1:13414") || pp.PeerInfo("10.0.0.1:13414") == nil {
+		t.Errorf("unbanned peer is not restored")
+	}
+}
+
+func TestBanRange(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client := dialInbound(t, ln, consensus.ProtocolVersion)
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pp.acceptPeer(conn); err != nil {
+		t.Fatalf("failed to accept peer: %v", err)
+	}
+
+	pp.Add("10.0.0.1:13414")
+	pp.Add("10.0.1.1:13414")
+	pp.Ban("127.0.0.0/8")
+	pp.Ban("10.0.0.7/24")
+
+	addr := conn.RemoteAddr().String()
+	if peerInfo := pp.PeerInfo(addr); peerInfo != nil && peerInfo.Status() != psBanned {
+		t.Errorf("connected peer of the banned range was %s", peerInfo.Status())
+	}
+
+	if _, ok := pp.PeersTable["10.0.0.1:13414"]; ok {
+		t.Errorf("known peer of the banned range is in the peers table")
+	}
+
+	for addr, banned := range map[string]bool{
+		"127.0.0.1:1":    true,
+		"10.0.0.200:1":   true,
+		"10.0.1.1:13414": false,
+		"[::1]:13414":    false,
+	} {
+		if pp.IsBan(addr) != banned {
+			t.Errorf("%s: banned was %v, want %v", addr, !banned, banned)
+		}
+	}
+
+	pp.Add("10.0.0.2:13414")
+	if _, ok := pp.PeersTable["10.0.0.2:13414"]; ok {
+		t.Errorf("peer of the banned range is added to the peers table")
+	}
+
+	banned := 0
+	for _, stats := range pp.All() {
+		if stats.Addr == "10.0.0.0/24" && stats.Status == psBanned.String() {
+			banned++
+		}
+	}
+	if banned != 1 {
+		t.Errorf("banned range is listed %d times, want 1", banned)
+	}
+
+	pp.Unban("10.0.0.0/24")
+	if pp.IsBan("10.0.0.200:1") {
+		t.Errorf("peer of the unbanned range is banned")
+	}
+}
+
+func TestBanInbound(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	for _, test := range []struct {
+		addr    *net.TCPAddr
+		inbound bool
+		ban     string
+	}{
+		{&net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 13414}, false, "10.0.0.1:13414"},
+		{&net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 51234}, true, "10.0.0.2/32"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 51234}, true, "2001:db8::1/128"},
+	} {
+		peer := &Peer{conn: &mockConn{addr: test.addr}, Inbound: test.inbound}
+		if ban := peer.banAddr(); ban != test.ban {
+			t.Errorf("%s: ban addr was %s, want %s", test.addr, ban, test.ban)
+		}
+		pp.Ban(peer.banAddr())
+	}
+
+	// the banned inbound host is refused from any port
+	if !pp.IsBan("10.0.0.2:51235") || !pp.IsBan("[2001:db8::1]:1") || pp.IsBan("10.0.0.1:51235") {
+		t.Errorf("bans were %v", pp.Bans())
+	}
+}
+
+func TestBanFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "banned_peers")
+
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	if err := sync.SetBanFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	sync.Pool.Ban("10.0.0.1:13414")
+	sync.Pool.Ban("192.168.1.0/24")
+	sync.Pool.Ban("10.0.0.2:13414")
+	sync.Pool.Unban("10.0.0.2:13414")
+
+	restarted := NewSyncer(nil, nil, nil)
+	restarted.SetLogger(logging.Nop)
+	if err := restarted.SetBanFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	pp := restarted.Pool.(*peersPool)
+	for addr, banned := range map[string]bool{
+		"10.0.0.1:13414":   true,
+		"192.168.1.9:3414": true,
+		"10.0.0.2:13414":   false,
+	} {
+		if pp.IsBan(addr) != banned {
+			t.Errorf("%s: banned was %v, want %v", addr, !banned, banned)
+		}
+	}
+
+	if err := ioutil.WriteFile(file, []byte("# comment\n\n10.0.0.1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSyncer(nil, nil, nil).SetBanFile(file); err == nil {
+		t.Errorf("ban file of the invalid entry is loaded")
+	}
+}
+
+func TestReloadBans(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "banned_peers")
+	if err := ioutil.WriteFile(file, []byte("10.0.0.1:13414\n10.0.0.2:13414\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	if err := sync.SetBanFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	// the edited file of the running node
+	if err := ioutil.WriteFile(file, []byte("# edited\n10.0.0.2:13414\n192.168.1.0/24\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sync.ReloadBans(); err != nil {
+		t.Fatal(err)
+	}
+
+	if bans := sync.Pool.Bans(); !reflect.DeepEqual(bans, []string{"10.0.0.2:13414", "192.168.1.0/24"}) {
+		t.Errorf("reloaded bans were %v", bans)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("10.0.0.1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := sync.ReloadBans(); err == nil || len(sync.Pool.Bans()) != 2 {
+		t.Errorf("invalid ban file was reloaded: %v", err)
+	}
+}
+
+func TestSetMaxPeers(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	sync.SetMaxPeers(2)
+	for i := 0; i < 2; i++ {
+		pp.pool <- struct{}{}
+	}
+	if pp.full() {
+		t.Errorf("pool of max peers is full")
+	}
+
+	// the connected peers are kept, the new ones are refused
+	sync.SetMaxPeers(1)
+	if !pp.full() {
+		t.Errorf("pool above the lowered max peers is not full")
+	}
+
+	sync.SetMaxPeers(0)
+	if atomic.LoadInt32(&pp.maxPeers) != 1 {
+		t.Errorf("zero max peers was set")
+	}
+}
+
+func TestPeersFreshness(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	now := time.Now()
+	for addr, info := range map[string]*peerInfo{
+		"1.0.0.1:13414": {status: psNew, lastConn: time.Unix(0, 0)},
+		"1.0.0.2:13414": {status: psDisconnected, lastConn: now.Add(-2 * time.Hour)},
+		"1.0.0.3:13414": {status: psDisconnected, lastConn: now.Add(-2 * peerAddrsFreshness)},
+		"1.0.0.4:13414": {status: psConnected, lastConn: now.Add(-2 * peerAddrsFreshness)},
+		"1.0.0.5:13414": {status: psDisconnected, lastConn: now.Add(-time.Hour)},
+		"1.0.0.6:13414": {status: psFailedConn, lastConn: now.Add(-time.Hour)},
+	} {
+		info.capabilities = consensus.CapFullNode
+		pp.PeersTable[addr] = info
+	}
+
+	// the inbound peers are not gossiped, they were never dialed
+	pp.ConnectedPeers["1.0.0.7:50001"] = &peerInfo{status: psConnected, capabilities: consensus.CapFullNode}
+
+	var got []string
+	for _, addr := range pp.Peers(consensus.CapFullNode, "8.8.8.8:13414").peers {
+		got = append(got, addr.String())
+	}
+
+	want := []string{"1.0.0.4:13414", "1.0.0.5:13414", "1.0.0.2:13414"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("peers were %v, want %v", got, want)
+	}
+}
+
+func TestPeersRoutable(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	now := time.Now()
+	for i, addr := range []string{
+		"1.0.0.1:13414",
+		"1.0.0.2:13414",
+		"127.0.0.1:13414",
+		"10.0.0.1:13414",
+		"192.168.1.1:13414",
+		"100.64.0.1:13414",
+		"[fd00::1]:13414",
+		"[2001:db8::1]:13414",
+		"0.0.0.0:13414",
+		"224.0.0.1:13414",
+	} {
+		// the recently connected first in the listed order
+		pp.PeersTable[addr] = &peerInfo{
+			status:       psDisconnected,
+			capabilities: consensus.CapFullNode,
+			lastConn:     now.Add(-time.Duration(i+1) * time.Minute),
+		}
+	}
+
+	for _, test := range []struct {
+		requester string
+		expected  []string
+	}{
+		{"1.0.0.1:50000", []string{"1.0.0.2:13414", "[2001:db8::1]:13414"}},
+		{"", []string{"1.0.0.1:13414", "1.0.0.2:13414", "[2001:db8::1]:13414"}},
+		{"192.168.1.1:50000", []string{"1.0.0.1:13414", "1.0.0.2:13414", "127.0.0.1:13414", "10.0.0.1:13414", "100.64.0.1:13414", "[fd00::1]:13414", "[2001:db8::1]:13414"}},
+	} {
+		var got []string
+		for _, addr := range pp.Peers(consensus.CapFullNode, test.requester).peers {
+			got = append(got, addr.String())
+		}
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: peers were %v, want %v", test.requester, got, test.expected)
+		}
+	}
+}
+
+func TestHandshakeListenPort(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	sync.Pool.Serve(ln)
+	defer sync.Pool.Stop()
+
+	if sync.listenPort != uint16(ln.Addr().(*net.TCPAddr).Port) {
+		t.Fatalf("listen port was %d, want %s", sync.listenPort, ln.Addr())
+	}
+
+	peer, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	go func() {
+		conn, err := peer.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var h hand
+		if _, err := ReadMessage(conn, &h); err != nil {
+			return
+		}
+		WriteMessage(conn, &shake{
+			Version:         h.Version,
+			TotalDifficulty: consensus.Difficulty(1),
+			UserAgent:       fmt.Sprint(h.SenderAddr.Port),
+		})
+	}()
+
+	conn, err := net.Dial("tcp", peer.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sh, err := shakeByHand(conn, sync)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sh.UserAgent != fmt.Sprint(sync.listenPort) {
+		t.Errorf("advertised port was %s, want %d", sh.UserAgent, sync.listenPort)
+	}
+}
+
+func TestHighBandwidth(t *testing.T) {
+	sync := NewSyncer(nil, nil, nil)
+	sync.SetLogger(logging.Nop)
+	pp := sync.Pool.(*peersPool)
+
+	now := time.Now()
+	for i := 0; i < highBandwidthPeers+2; i++ {
+		addr := fmt.Sprintf("10.0.0.%d:13414", i)
+		pp.ConnectedPeers[addr] = &peerInfo{status: psConnected, lastBlock: now.Add(-time.Duration(i) * time.Minute)}
+	}
+	pp.ConnectedPeers["10.0.1.1:13414"] = &peerInfo{status: psConnected}
+
+	full := pp.highBandwidth()
+	if len(full) != highBandwidthPeers {
+		t.Fatalf("%d high bandwidth peers, want %d", len(full), highBandwidthPeers)
+	}
+
+	for i := 0; i < highBandwidthPeers; i++ {
+		if !full[pp.ConnectedPeers[fmt.Sprintf("10.0.0.%d:13414", i)]] {
+			t.Errorf("peer %d delivering the recent block is not high bandwidth", i)
+		}
+	}
+}
+
+func TestPeerInfoConcurrent(t *testing.T) {
+	pi := newPeerInfo()
+	peer := &Peer{Addr: "127.0.0.1:13414"}
+	peer.Info.Version = consensus.ProtocolVersion
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				switch (i + j) % 8 {
+				case 0:
+					pi.Connect(peer)
+				case 1:
+					pi.Disconnect()
+				case 2:
+					pi.SetChain(consensus.Difficulty(j), uint64(j))
+				case 3:
+					pi.DeliverBlock(&consensus.BlockHeader{Height: uint64(j)}, true)
+				case 4:
+					pi.Stats(peer.Addr)
+				case 5:
+					pi.Gossip()
+				case 6:
+					pi.Fail()
+				case 7:
+					pi.Chain()
+					pi.LastBlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the banned peer stays banned
+	pi.Ban()
+	pi.Disconnect()
+	pi.Fail()
+	if pi.Connect(peer) || pi.Status() != psBanned {
+		t.Errorf("banned peer was %s", pi.Status())
+	}
+}
+
+// TestPoolConcurrentPeers connects the outbound & the inbound peers while
+// they're banned, listed & gossiped, go test -race checks the pool & the
+// peer infos
+func TestPoolConcurrentPeers(t *testing.T) {
+	s := NewSyncer(nil, &stubChain{totalDifficulty: 1000}, nil)
+	s.SetLogger(logging.Nop)
+	pp := s.Pool.(*peersPool)
+
+	const n = 4
+	var nodes []string
+	for i := 0; i < n; i++ {
+		node := crawledNode(t, chain.Testnet4.Hash(), uint64(i), nil)
+		defer node.Close()
+
+		nodes = append(nodes, node.Addr().String())
+		pp.Add(node.Addr().String())
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		clients []net.Conn
+	)
+
+	for _, addr := range nodes {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+
+			// the slot is taken as by Run
+			pp.pool <- struct{}{}
+			if err := pp.connectPeer(addr); err != nil {
+				<-pp.pool
+			}
+		}(addr)
+	}
+
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := pp.acceptPeer(conn); err != nil {
+				conn.Close()
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			clients = append(clients, conn)
+			mu.Unlock()
+
+			WriteMessage(conn, &hand{
+				Version:         consensus.ProtocolVersion,
+				Capabilities:    consensus.CapFullNode,
+				Nonce:           1,
+				TotalDifficulty: consensus.Difficulty(1),
+				SenderAddr:      conn.LocalAddr().(*net.TCPAddr),
+				ReceiverAddr:    conn.RemoteAddr().(*net.TCPAddr),
+				UserAgent:       "test",
+			})
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 50; i++ {
+			pp.All()
+			pp.Connected()
+			pp.Peers(consensus.CapUnknown, "")
+			pp.PropagateBlock(&consensus.Block{})
+			s.BestPeerHeight()
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		pp.Ban(nodes[0])
+		pp.Unban(nodes[0])
+		pp.Ban(nodes[1])
+	}()
+	wg.Wait()
+
+	// the peers left are disconnected
+	for _, conn := range clients {
+		conn.Close()
+	}
+	for _, stats := range pp.Connected() {
+		if peerInfo := pp.PeerInfo(stats.Addr); peerInfo != nil && peerInfo.Peer() != nil {
+			peerInfo.Peer().Close()
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pp.connected) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d peers are not released on disconnect", atomic.LoadInt32(&pp.connected))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !pp.IsBan(nodes[1]) || pp.PeerInfo(nodes[1]) != nil {
+		t.Errorf("banned peer %s is known", nodes[1])
+	}
+
+	for _, stats := range pp.All() {
+		if stats.Status == psConnected.String() {
+			t.Errorf("peer %s is connected after the disconnect", stats.Addr)
+		}
+	}
+}
+
+func TestProgressRates(t *testing.T) {
+	var p progress
+	start := time.Now()
+
+	if headers, blocks := p.rates(start, 100, 10); headers != 0 || blocks != 0 {
+		t.Errorf("rates of the single sample were %v, %v", headers, blocks)
+	}
+
+	// the sample of the same second is skipped
+	p.rates(start.Add(500*time.Millisecond), 5000, 5000)
+
+	if headers, blocks := p.rates(start.Add(10*time.Second), 1100, 60); headers != 100 || blocks != 5 {
+		t.Errorf("rates were %v, %v, want 100, 5", headers, blocks)
+	}
+
+	// the samples out of the window are dropped
+	if headers, blocks := p.rates(start.Add(progressWindow+20*time.Second), 1100, 60); headers != 0 || blocks != 0 {
+		t.Errorf("rates of the stalled sync were %v, %v", headers, blocks)
+	}
+
+	if d := eta(100, 4); d != 25*time.Second {
+		t.Errorf("eta was %v, want 25s", d)
+	}
+	if d := eta(100, 0); d != 0 {
+		t.Errorf("eta of the unknown rate was %v", d)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"sync"
+	"time"
+)
+
+// progressWindow is the window of the header & block sync rates
+const progressWindow = time.Minute
+
+// progressSample is the header head & chain heights sampled at the time
+type progressSample struct {
+	at              time.Time
+	headers, blocks uint64
+}
+
+// progress samples the header head & chain heights for the sync rates
+type progress struct {
+	sync.Mutex
+	samples []progressSample
+}
+
+// rates adds the sample of the heights at most once a second & returns the
+// header & block rates per second within progressWindow
+func (p *progress) rates(now time.Time, headers, blocks uint64) (headerRate, blockRate float64) {
+	p.Lock()
+	defer p.Unlock()
+
+	if n := len(p.samples); n == 0 || now.Sub(p.samples[n-1].at) >= time.Second {
+		p.samples = append(p.samples, progressSample{now, headers, blocks})
+	}
+
+	// the oldest sample within the window is kept
+	i := 0
+	for i < len(p.samples)-1 && now.Sub(p.samples[i+1].at) >= progressWindow {
+		i++
+	}
+	p.samples = p.samples[i:]
+
+	first, last := p.samples[0], p.samples[len(p.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+
+	// the heights may go back on the fork
+	if last.headers > first.headers {
+		headerRate = float64(last.headers-first.headers) / elapsed
+	}
+	if last.blocks > first.blocks {
+		blockRate = float64(last.blocks-first.blocks) / elapsed
+	}
+
+	return headerRate, blockRate
+}
+
+// eta returns the time to sync the remaining items at the rate, zero if the
+// rate is unknown
+func eta(remaining uint64, rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(remaining) / rate * float64(time.Second))
+}
+
+// OnProgress calls fn with the sync status every interval until Stop
+func (s *Syncer) OnProgress(interval time.Duration, fn func(status SyncStatus)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				fn(s.Status())
+			}
+		}
+	}()
+}
@@ -5,17 +5,51 @@
 package p2p
 
 import (
-	"bufio"
 	"errors"
 	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/wire"
 	"io"
 )
 
-const (
-	// userAgent is name of version of the software
-	userAgent = "gringo v0.0.1"
+// The build info is set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/dblokhin/gringo/p2p.Commit=$(git rev-parse --short HEAD)"
+var (
+	// Version is the version of the software
+	Version = "0.0.1"
+
+	// Commit is the git commit of the build
+	Commit string
+
+	// BuildDate is the build date of the software
+	BuildDate string
+
+	// UserAgent is name of version of the software
+	UserAgent string
 )
 
+func init() {
+	UserAgent = userAgent(Version, Commit)
+}
+
+// SetUserAgent sets the user agent sent by the handshakes, the empty one is
+// the default name & version
+func SetUserAgent(ua string) {
+	if ua != "" {
+		UserAgent = ua
+	}
+}
+
+// userAgent returns the user agent of the version, the commit is appended
+// as the semver build metadata
+func userAgent(version, commit string) string {
+	if commit == "" {
+		return "gringo v" + version
+	}
+
+	return "gringo v" + version + "+" + commit
+}
+
 // Message defines methods for WriteMessage/ReadMessage functions
 type Message interface {
 	// Read reads from reader and fit self struct
@@ -28,31 +62,39 @@ type Message interface {
 	Type() uint8
 }
 
-// WriteMessage writes to wr (net.conn) protocol message
+// WriteMessage writes to wr (net.conn) protocol message. The message is
+// serialized to the pooled buffer, by WriteTo if the message implements
+// io.WriterTo, and written at once
 func WriteMessage(w io.Writer, msg Message) (uint64, error) {
-	data := msg.Bytes()
+	buf := wire.GetBuffer()
+	defer wire.PutBuffer(buf)
+
+	// the header is filled after the body of the unknown length
+	var reserved [consensus.HeaderLen]byte
+	buf.Write(reserved[:])
+
+	if wt, ok := msg.(io.WriterTo); ok {
+		if _, err := wt.WriteTo(buf); err != nil {
+			return 0, err
+		}
+	} else {
+		buf.Write(msg.Bytes())
+	}
 
+	data := buf.Bytes()
 	header := Header{
-		magic: consensus.MagicCode,
+		magic: magicCode,
 		Type:  msg.Type(),
-		Len:   uint64(len(data)),
-	}
-
-	// use the buffered writer
-	wr := bufio.NewWriter(w)
-	if err := header.Write(wr); err != nil {
-		return 0, err
-	}
-
-	n, err := wr.Write(data)
-	if err != nil {
-		return uint64(n) + consensus.HeaderLen, err
+		Len:   uint64(len(data)) - consensus.HeaderLen,
 	}
+	header.encode(data)
 
-	return uint64(n) + consensus.HeaderLen, wr.Flush()
+	n, err := w.Write(data)
+	return uint64(n), err
 }
 
-// ReadMessage reads from r (net.conn) protocol message
+// ReadMessage reads from r (net.conn) protocol message, the PeerError sent
+// instead of the message is returned as the error
 func ReadMessage(r io.Reader, msg Message) (uint64, error) {
 	var header Header
 
@@ -62,12 +104,17 @@ func ReadMessage(r io.Reader, msg Message) (uint64, error) {
 		return 0, err
 	}
 
-	if header.Type != msg.Type() {
-		return uint64(consensus.HeaderLen), errors.New("receive unexpected message type")
+	if header.Type == consensus.MsgTypeError && msg.Type() != consensus.MsgTypeError {
+		peerErr := new(PeerError)
+		if err := peerErr.Read(io.LimitReader(r, int64(header.Len))); err != nil {
+			return uint64(consensus.HeaderLen), err
+		}
+
+		return uint64(consensus.HeaderLen) + uint64(header.Len), peerErr
 	}
 
-	if header.Len > consensus.MaxMsgLen {
-		return uint64(consensus.HeaderLen), errors.New("too big message size")
+	if header.Type != msg.Type() {
+		return uint64(consensus.HeaderLen), errors.New("receive unexpected message type")
 	}
 
 	rb := io.LimitReader(r, int64(header.Len))
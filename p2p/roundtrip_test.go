@@ -0,0 +1,221 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/testutil"
+	"math/rand"
+	"net"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// checkRoundTrip checks the message read from the bytes of the message reads
+// the bytes entirely, equals the message & is written to the same bytes
+func checkRoundTrip(msg, read wireMessage) error {
+	data := msg.Bytes()
+
+	r := bytes.NewReader(data)
+	if err := read.Read(r); err != nil {
+		return fmt.Errorf("failed to read %x: %v", data, err)
+	}
+
+	if r.Len() > 0 {
+		return fmt.Errorf("%d of %d bytes are not read", r.Len(), len(data))
+	}
+
+	if written := read.Bytes(); !bytes.Equal(written, data) {
+		return fmt.Errorf("read message is written to\n%x, want\n%x", written, data)
+	}
+
+	if !reflect.DeepEqual(read, msg) {
+		return fmt.Errorf("read %+v, want %+v", read, msg)
+	}
+
+	return nil
+}
+
+// testRoundTrip checks the round trip of the random messages of the random
+// seeds, random returns the message & the empty message to read
+func testRoundTrip(t *testing.T, random func(r *rand.Rand) (msg, read wireMessage)) {
+	property := func(seed int64) bool {
+		msg, read := random(rand.New(rand.NewSource(seed)))
+		if err := checkRoundTrip(msg, read); err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 100}); err != nil {
+		t.Error(err)
+	}
+}
+
+// randomString returns the random string up to n bytes
+func randomString(r *rand.Rand, n int) string {
+	data := make([]byte, r.Intn(n+1))
+	r.Read(data)
+
+	return string(data)
+}
+
+// randomAddr returns the random addr as read: the ipv4 of 4 bytes or the
+// ipv6 of 16 bytes that isn't the mapped ipv4
+func randomAddr(r *rand.Rand) *net.TCPAddr {
+	ip := make(net.IP, net.IPv4len)
+	if r.Intn(2) == 0 {
+		ip = make(net.IP, net.IPv6len)
+		ip[0] = 0x20
+	}
+	r.Read(ip[len(ip)-net.IPv4len:])
+
+	return &net.TCPAddr{IP: ip, Port: r.Intn(1 << 16)}
+}
+
+// randomCapabilities returns the random capabilities, the node id is set of
+// CapNodeID only as read
+func randomCapabilities(r *rand.Rand, id *NodeID) consensus.Capabilities {
+	capabilities := consensus.Capabilities(r.Uint32())
+	if capabilities&consensus.CapNodeID != 0 {
+		r.Read(id[:])
+	}
+
+	return capabilities
+}
+
+func TestHeaderRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		msgType := uint8(r.Intn(int(consensus.MsgTypeGetPoolTxs) + 1))
+		header := &Header{
+			magic: magicCode,
+			Type:  msgType,
+			Len:   uint64(r.Int63n(int64(maxMsgLen(msgType)) + 1)),
+		}
+
+		return header, new(Header)
+	})
+}
+
+func TestHandRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		msg := &hand{
+			Version:         consensus.ProtocolVersion,
+			Nonce:           r.Uint64(),
+			Genesis:         testutil.RandomHash(r),
+			TotalDifficulty: consensus.Difficulty(r.Uint64()),
+			SenderAddr:      randomAddr(r),
+			ReceiverAddr:    randomAddr(r),
+			UserAgent:       randomString(r, 64),
+		}
+		msg.Capabilities = randomCapabilities(r, &msg.NodeID)
+
+		return msg, new(hand)
+	})
+}
+
+func TestShakeRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		msg := &shake{
+			Version:         consensus.ProtocolVersion,
+			TotalDifficulty: consensus.Difficulty(r.Uint64()),
+			UserAgent:       randomString(r, 64),
+			Genesis:         testutil.RandomHash(r),
+		}
+		msg.Capabilities = randomCapabilities(r, &msg.NodeID)
+
+		return msg, new(shake)
+	})
+}
+
+func TestPingRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		ping := Ping{TotalDifficulty: consensus.Difficulty(r.Uint64()), Height: r.Uint64()}
+		if r.Intn(2) == 0 {
+			return &ping, new(Ping)
+		}
+
+		return &Pong{ping}, new(Pong)
+	})
+}
+
+func TestGetPeerAddrsRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		return &GetPeerAddrs{Capabilities: consensus.Capabilities(r.Uint32())}, new(GetPeerAddrs)
+	})
+}
+
+func TestPeerErrorRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		return &PeerError{Code: r.Uint32(), Message: randomString(r, 64)}, new(PeerError)
+	})
+}
+
+func TestPeerAddrsRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		// the addrs of no peers are nil as read
+		msg := new(PeerAddrs)
+		for i := r.Intn(consensus.MaxPeerAddrs + 1); i > 0; i-- {
+			msg.peers = append(msg.peers, randomAddr(r))
+		}
+
+		return msg, new(PeerAddrs)
+	})
+}
+
+func TestGetBlockRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		hash := testutil.RandomHash(r)
+		if r.Intn(2) == 0 {
+			return &GetBlock{Hash: hash}, new(GetBlock)
+		}
+
+		return &GetCompactBlock{GetBlock{Hash: hash}}, new(GetCompactBlock)
+	})
+}
+
+func TestBlockHeadersRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		if r.Intn(2) == 0 {
+			return &BlockHeader{Header: testutil.RandomHeader(r)}, new(BlockHeader)
+		}
+
+		msg := &BlockHeaders{Headers: make([]consensus.BlockHeader, r.Intn(16))}
+		for i := range msg.Headers {
+			msg.Headers[i] = testutil.RandomHeader(r)
+		}
+
+		return msg, new(BlockHeaders)
+	})
+}
+
+func TestGetBlockHeadersRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		msg := &GetBlockHeaders{Locator: consensus.Locator{Hashes: testutil.RandomHashes(r, consensus.MaxLocators)}}
+		return msg, new(GetBlockHeaders)
+	})
+}
+
+func TestStemTransactionRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		return &StemTransaction{*testutil.RandomTransaction(r)}, new(StemTransaction)
+	})
+}
+
+func TestPoolKernelsRoundTrip(t *testing.T) {
+	testRoundTrip(t, func(r *rand.Rand) (wireMessage, wireMessage) {
+		msg := PoolKernels{Key: testutil.RandomHash(r), IDs: testutil.RandomShortIDs(r)}
+		if r.Intn(2) == 0 {
+			return &msg, new(PoolKernels)
+		}
+
+		return &GetPoolTxs{msg}, new(GetPoolTxs)
+	})
+}
@@ -5,50 +5,34 @@
 package p2p
 
 import (
+	"github.com/dblokhin/gringo/timecache"
 	"math/rand"
 	"time"
 )
 
 const (
 	noncesCap = 100
-)
-
-type nonceList struct {
-	idx  int
-	list []uint64
-}
-
-// Init nonce list
-func (n *nonceList) Init() {
-	n.list = make([]uint64, noncesCap)
 
-	for i := 0; i < noncesCap; i++ {
-		n.list[i] = rand.Uint64()
-	}
-}
+	// nonceTTL is the time the nonce of our handshake is remembered, the
+	// handshake with ourselves is done long before
+	nonceTTL = 10 * time.Minute
+)
 
-// NextNonce returns next nonce from list
-func (n *nonceList) NextNonce() uint64 {
-	n.idx = (n.idx + 1) % noncesCap
-	return n.list[n.idx]
+// newNonces returns the cache of the nonces of our handshakes
+func newNonces() *timecache.Cache {
+	return timecache.New(nonceTTL, noncesCap)
 }
 
-func (n *nonceList) Consist(nonce uint64) bool {
-	for _, v := range n.list {
-		if nonce == v {
-			return true
-		}
-	}
+// nextNonce returns the random nonce of our handshake remembered by the
+// nonces
+func (s *Syncer) nextNonce() uint64 {
+	nonce := rand.Uint64()
+	s.nonces.Add(nonce)
 
-	return false
+	return nonce
 }
 
-var serverNonces nonceList
-
 func init() {
 	// init rand
 	rand.Seed(time.Now().UnixNano())
-
-	// init nonce
-	serverNonces.Init()
 }
@@ -5,10 +5,22 @@
 package p2p
 
 import (
+	"context"
+	"errors"
+	"github.com/dblokhin/gringo/chain"
 	"github.com/dblokhin/gringo/consensus"
-	"github.com/sirupsen/logrus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/timecache"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
 )
 
+// ProcessTimeout is the deadline of the validation of the block, headers or
+// transaction received from the peer
+const ProcessTimeout = time.Minute
+
 type Blockchain interface {
 	// Requite a mutex
 	Lock()
@@ -19,13 +31,23 @@ type Blockchain interface {
 	Genesis() consensus.Block
 	TotalDifficulty() consensus.Difficulty
 	Height() uint64
+	HeaderHead() consensus.BlockHeader
+	Locator() consensus.Locator
 	GetBlockHeaders(loc consensus.Locator) []consensus.BlockHeader
 	GetBlock(hash consensus.Hash) *consensus.Block
 
 	// ProcessHeaders processing block headers
 	// Validate blockchain rules
 	// ban peer with consensus error
-	ProcessHeaders(headers []consensus.BlockHeader) error
+	ProcessHeaders(ctx context.Context, headers []consensus.BlockHeader) error
+
+	// ValidateHeader checks the header of the peer while decoded, the
+	// header follows prev unless prev is nil
+	ValidateHeader(prev, header *consensus.BlockHeader) error
+
+	// ReadBlock reads the block of the peer, the block may be validated
+	// while decoded
+	ReadBlock(ctx context.Context, r io.Reader, block *consensus.Block) error
 
 	// ProcessBlock processing block
 	// Validate blockchain rules
@@ -34,45 +56,174 @@ type Blockchain interface {
 
 	// propagate block on new block to connected peer with less Height
 	// clear tx's from pool on new block
-	ProcessBlock(block *consensus.Block) error
+	ProcessBlock(ctx context.Context, block *consensus.Block) error
+
+	// Subscribe registers ch to receive the new head blocks, Unsubscribe
+	// removes it
+	Subscribe(ch chan<- *consensus.Block)
+	Unsubscribe(ch chan<- *consensus.Block)
 }
 
 type Mempool interface {
 	// ProcessTx processing transaction
 	// Validate blockchain rules
 	// ban peer with consensus error
-	ProcessTx(transaction *consensus.Transaction) error
+	ProcessTx(ctx context.Context, transaction *consensus.Transaction) error
+
+	// Transactions returns the pool transactions, exchanged with the peers
+	// on connect
+	Transactions() []*consensus.Transaction
 }
 
 type PeersPool interface {
 	// PropagateBlock block to connected peer with less Height
 	PropagateBlock(block *consensus.Block)
 
-	// Peers returns live peers list (without banned)
-	Peers(capabilities consensus.Capabilities) *PeerAddrs
+	// PropagateTx sends transaction to the connected peers if fluff is set,
+	// otherwise to the random peer for the stem phase
+	PropagateTx(tx *consensus.Transaction, fluff bool)
+
+	// Peers returns live peers list for the requester addr (without banned)
+	Peers(capabilities consensus.Capabilities, requester string) *PeerAddrs
 
 	// PeerInfo returns peer structure
 	PeerInfo(addr string) *peerInfo
 
+	// Connected returns stats of the connected peers
+	Connected() []PeerStats
+
+	// All returns stats of the known, connected & banned peers
+	All() []PeerStats
+
 	// Add peer
 	Add(addr string)
 
+	// Listen accepts inbound connections on addr
+	Listen(addr string) error
+
+	// Serve accepts inbound connections on listener
+	Serve(listener net.Listener)
+
 	// Ban peer & ensure closed connection
 	Ban(addr string)
 
+	// Unban removes peer from the ban list
+	Unban(addr string)
+
+	// Bans returns the banned addrs & CIDR ranges
+	Bans() []string
+
+	// ConnLog returns the latest connection events selected by the query
+	ConnLog(query ConnLogQuery) []ConnEvent
+
 	// Run & stop
 	Run()
 	Stop()
 }
 
+// The sync stages, named as in grin
+const (
+	SyncAwaitingPeers = "awaiting_peers"
+	SyncHeaders       = "header_sync"
+	SyncBlocks        = "body_sync"
+	SyncDone          = "no_sync"
+)
+
+// SyncStatus is the sync progress of the node
+type SyncStatus struct {
+	Stage string
+	// Percent is the chain height to the best known height ratio, 0-100
+	Percent int
+
+	// Headers is the header head height, Blocks is the chain height, both
+	// are synced up to Target: the best peer or the header head height
+	Headers uint64
+	Blocks  uint64
+	Target  uint64
+
+	// HeadersPerSecond & BlocksPerSecond are the sync rates of the last
+	// minute
+	HeadersPerSecond float64
+	BlocksPerSecond  float64
+
+	// ETA is the estimated time to complete the stage, zero if unknown
+	ETA time.Duration
+
+	// ForkAlarm is set if the most peers are ahead of the synced chain, see
+	// ForkAlarmConfig
+	ForkAlarm bool
+}
+
+// lookupHost resolves the DNS seeds, replaced by tests
+var lookupHost = net.LookupHost
+
 // Syncer synchronize blockchain & mempool via peers pool
 type Syncer struct {
+	// bytes sent & received by all the peers, the following fields are only
+	// meant to be used *atomically*
+	bytesSent     uint64
+	bytesReceived uint64
+
 	// Chain is a grin blockchain
 	Chain   Blockchain
 	Mempool Mempool
 
 	// Pool of peers (peers manager)
 	Pool PeersPool
+
+	// sync statistics
+	metrics *Metrics
+
+	// nonces of our handshakes to detect the connections to ourselves
+	nonces *timecache.Cache
+
+	// dnsSeeds is the host:port list resolved to the initial peers on Run
+	dnsSeeds []string
+
+	// params is the consensus parameters of the network
+	params *consensus.Params
+
+	// headersOnly is set if the headers are synced without the block bodies
+	// & the transactions
+	headersOnly bool
+	// archive is set if the chain keeps all the blocks
+	archive bool
+	// identity is the node identity announced by the handshake, nil if the
+	// node has none
+	identity *Identity
+
+	// listenPort is the port of the inbound connections advertised by the
+	// handshake, 0 if the node isn't listening
+	listenPort uint16
+
+	// mdns is set if the peers of the local network are discovered by mDNS,
+	// the node listening on mdnsPort is advertised
+	mdns     bool
+	mdnsPort uint16
+
+	// progress samples the heights for the sync rates
+	progress progress
+
+	// ibd is the initial block download state
+	ibd ibd
+
+	// forkAlarm is the alarm of the most peers ahead of the synced chain
+	forkAlarm forkAlarm
+
+	// dandelion is the relay state of the stem transactions, nil if the
+	// node is the fluff point of all of them
+	dandelion *dandelion
+
+	// handlers are the extension handlers of the message types & observers
+	// the ones of all messages, see Handle & Observe
+	handlers  map[uint8][]MessageHandler
+	observers []MessageHandler
+
+	// ctx is cancelled on Stop to cancel the validation of the peer messages
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	log logging.Logger
 }
 
 // Start starts sync proccess with initial peer addrs
@@ -81,7 +232,13 @@ func NewSyncer(addrs []string, chain Blockchain, mempool Mempool) *Syncer {
 	sync := new(Syncer)
 	sync.Chain = chain
 	sync.Mempool = mempool
+	sync.params = &consensus.TestnetParams
+	sync.ibd.distance = DefaultIBDDistance
+	sync.ctx, sync.cancel = context.WithCancel(context.Background())
+	sync.log = logging.Default(logging.P2P)
+	sync.nonces = newNonces()
 	sync.Pool = newPeersPool(sync)
+	sync.metrics = newMetrics(sync)
 
 	for _, addr := range addrs {
 		sync.Pool.Add(addr)
@@ -90,62 +247,327 @@ func NewSyncer(addrs []string, chain Blockchain, mempool Mempool) *Syncer {
 	return sync
 }
 
-// Run begins syncing with peers.
+// SetLogger replaces the logger of the syncer, its peers pool & peers,
+// logging.Nop disables the logging. Must be called before Run
+func (s *Syncer) SetLogger(logger logging.Logger) {
+	s.log = logger
+
+	if pool, ok := s.Pool.(*peersPool); ok {
+		pool.log = logger
+	}
+}
+
+// SetDNSSeeds sets the host:port list of the DNS seeds, the resolved peers
+// are added to the pool on Run in addition to the initial addrs
+func (s *Syncer) SetDNSSeeds(seeds []string) {
+	s.dnsSeeds = seeds
+}
+
+// SetParams sets the consensus parameters of the network, the messages are
+// sent & expected with the magic code of the params. The magic code is
+// shared by the syncers of the process, so it must be called before Run
+func (s *Syncer) SetParams(params *consensus.Params) {
+	s.params = params
+	magicCode = params.MagicCode
+}
+
+// SetBanFile loads the ban list of the file & persists the bans & the unbans
+// to it, the missing file is created on the first ban. Must be called before
+// Run
+func (s *Syncer) SetBanFile(file string) error {
+	pool, ok := s.Pool.(*peersPool)
+	if !ok {
+		return nil
+	}
+
+	if err := pool.loadBans(file); err != nil {
+		return err
+	}
+
+	pool.banFile = file
+	return nil
+}
+
+// ReloadBans bans & unbans the peers by the edited ban file at runtime
+func (s *Syncer) ReloadBans() error {
+	if pool, ok := s.Pool.(*peersPool); ok {
+		return pool.reloadBans()
+	}
+
+	return nil
+}
+
+// SetMaxPeers changes the max count of the connected peers at runtime, the
+// connected peers above the lowered limit are kept
+func (s *Syncer) SetMaxPeers(n int) {
+	if pool, ok := s.Pool.(*peersPool); ok {
+		pool.setMaxPeers(n)
+	}
+}
+
+// SetHeadersOnly syncs the headers only: the headers are requested from the
+// peers ahead, the blocks & transactions are not relayed & the node is
+// announced as the peer list provider. Must be called before Run
+func (s *Syncer) SetHeadersOnly() {
+	s.headersOnly = true
+}
+
+// SetArchive announces the node keeping all the blocks, the peers request the
+// historical blocks from it. Must be called before Run
+func (s *Syncer) SetArchive() {
+	s.archive = true
+}
+
+// SetMDNS discovers the peers of the local network by mDNS & advertises the
+// node listening on port, 0 doesn't advertise it. Must be called before Run
+func (s *Syncer) SetMDNS(port uint16) {
+	s.mdns = true
+	s.mdnsPort = port
+}
+
+// SetIdentity announces the node identity by the handshake. Must be called
+// before Run
+func (s *Syncer) SetIdentity(identity *Identity) {
+	s.identity = identity
+}
+
+// NodeID returns the node identity announced by the handshake, zero if the
+// node has none
+func (s *Syncer) NodeID() NodeID {
+	if s.identity == nil {
+		return NodeID{}
+	}
+
+	return s.identity.ID
+}
+
+// capabilities returns the capabilities announced by the handshake, the
+// txhashset isn't served, so the UTXO history is never announced
+func (s *Syncer) capabilities() consensus.Capabilities {
+	var capabilities consensus.Capabilities = consensus.CapPeerList
+	if s.archive && !s.headersOnly {
+		capabilities |= consensus.CapFullHist
+	}
+
+	if s.identity != nil {
+		capabilities |= consensus.CapNodeID
+	}
+
+	if !s.headersOnly {
+		capabilities |= consensus.CapTxPool
+	}
+
+	return capabilities
+}
+
+// requestHeaders requests the headers after the chain head from the peer of
+// the more total difficulty, the headers only & the initial block download
+// are synced actively
+func (s *Syncer) requestHeaders(peer *Peer, totalDifficulty consensus.Difficulty) {
+	if totalDifficulty <= s.Chain.TotalDifficulty() || !(s.headersOnly || s.InitialBlockDownload()) {
+		return
+	}
+
+	peer.WriteMessage(&GetBlockHeaders{Locator: s.Chain.Locator()})
+}
+
+// Metrics returns the prometheus collector of the sync statistics
+func (s *Syncer) Metrics() *Metrics {
+	return s.metrics
+}
+
+// Traffic returns the bytes sent & received by all the peers since the start
+func (s *Syncer) Traffic() (sent, received uint64) {
+	return atomic.LoadUint64(&s.bytesSent), atomic.LoadUint64(&s.bytesReceived)
+}
+
+// BestPeerHeight returns max height of the connected peers
+func (s *Syncer) BestPeerHeight() uint64 {
+	var height uint64
+	for _, peer := range s.Pool.Connected() {
+		if peer.Height > height {
+			height = peer.Height
+		}
+	}
+
+	return height
+}
+
+// SyncLag returns count of blocks the chain is behind the best peer
+func (s *Syncer) SyncLag() uint64 {
+	best, height := s.BestPeerHeight(), s.Chain.Height()
+	if best <= height {
+		return 0
+	}
+
+	return best - height
+}
+
+// Status returns the sync stage & progress. The headers are synced up to the
+// best peer height first, then the blocks up to the header head
+func (s *Syncer) Status() SyncStatus {
+	height, header := s.Chain.Height(), s.Chain.HeaderHead().Height
+
+	target := s.BestPeerHeight()
+	if header > target {
+		target = header
+	}
+
+	status := SyncStatus{
+		Stage:   SyncDone,
+		Percent: 100,
+		Headers: header,
+		Blocks:  height,
+		Target:  target,
+	}
+	status.HeadersPerSecond, status.BlocksPerSecond = s.progress.rates(time.Now(), header, height)
+	status.ForkAlarm = s.ForkAlarm().Raised
+
+	if height < target {
+		status.Percent = int(height * 100 / target)
+	}
+
+	switch {
+	case len(s.Pool.Connected()) == 0:
+		status.Stage = SyncAwaitingPeers
+	case header < target:
+		status.Stage = SyncHeaders
+		status.ETA = eta(target-header, status.HeadersPerSecond)
+	case height < target:
+		status.Stage = SyncBlocks
+		status.ETA = eta(target-height, status.BlocksPerSecond)
+	}
+
+	return status
+}
+
+// Run begins syncing with peers, the new head blocks of the chain are
+// announced to the peers until Stop
 func (s *Syncer) Run() {
+	go s.announce(s.subscribe())
+	go s.resolveSeeds()
+	if s.mdns {
+		go s.runMDNS()
+	}
+	if s.dandelion != nil && !s.headersOnly {
+		go s.runDandelion()
+	}
+	if s.forkAlarm.config.PeersPercent > 0 {
+		go s.runForkAlarm()
+	}
 	s.Pool.Run()
 }
 
-// Stop stops activity
+// resolveSeeds adds the peers of the DNS seeds to the pool
+func (s *Syncer) resolveSeeds() {
+	for _, seed := range s.dnsSeeds {
+		host, port, err := net.SplitHostPort(seed)
+		if err != nil {
+			s.log.Errorf("invalid dns seed %s: %v", seed, err)
+			continue
+		}
+
+		addrs, err := lookupHost(host)
+		if err != nil {
+			s.log.Infof("failed to resolve dns seed %s: %v", host, err)
+			continue
+		}
+
+		for _, addr := range addrs {
+			s.Pool.Add(net.JoinHostPort(addr, port))
+		}
+		s.log.Debugf("dns seed %s resolved to %d peers", host, len(addrs))
+	}
+}
+
+// Stop stops activity, the validation of the peer messages is cancelled
 func (s *Syncer) Stop() {
+	s.cancel()
 	s.Pool.Stop()
 }
 
+// banErrors is the consensus failures, the blocks marked invalid by the
+// operator & the too large reads of the peer data the peer is banned for.
+// The peer isn't banned for the orphans, the cancelled processing or the
+// transactions conflicting with the pool
+var banErrors = []error{
+	consensus.ErrInvalidBlockVersion,
+	consensus.ErrInvalidBlockTime,
+	consensus.ErrInvalidPow,
+	consensus.ErrTooHeavy,
+	consensus.ErrNotSorted,
+	consensus.ErrInvalidCoinbase,
+	consensus.ErrNoKernels,
+	consensus.ErrDuplicate,
+	consensus.ErrCutThrough,
+	consensus.ErrInvalidSignature,
+	consensus.ErrInvalidKernelSum,
+	consensus.ErrInvalidRangeProof,
+	chain.ErrInvalidTotalDifficulty,
+	chain.ErrDifficultyTooLow,
+	chain.ErrInvalidPrevious,
+	chain.ErrInvalidHeaderRoot,
+	chain.ErrInvalidBlock,
+	consensus.ErrTooLargeRead,
+}
+
+// misbehaving returns true if err is the consensus failure the peer is
+// banned for
+func misbehaving(err error) bool {
+	for _, target := range banErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *Syncer) ProcessMessage(peer *Peer, message Message) {
 
 	peerInfo := s.Pool.PeerInfo(peer.Addr)
 	if peerInfo == nil {
 		// should never rich
-		logrus.Fatal("unexpected error")
+		s.log.Errorf("message from unknown peer %s", peer.Addr)
+		return
 	}
+	log := peer.logger()
+	s.dispatch(peer, message)
+
+	// the validation of the peer work has the deadline
+	ctx, cancel := context.WithTimeout(s.ctx, ProcessTimeout)
+	defer cancel()
 
 	switch msg := message.(type) {
 	case *Ping:
 		// MUST be answered
 		// update peer info
-		peerInfo.Lock()
-		peerInfo.TotalDifficulty = msg.TotalDifficulty
-		peerInfo.Height = msg.Height
-		peerInfo.Unlock()
+		peerInfo.SetChain(msg.TotalDifficulty, msg.Height)
 
 		// send answer
 		var resp Pong
 
-		// Lock the chain before getting various params
-		s.Chain.RLock()
+		// the getters lock the chain themselves
 		resp.TotalDifficulty = s.Chain.TotalDifficulty()
 		resp.Height = s.Chain.Height()
-		s.Chain.RUnlock()
 
 		peer.WriteMessage(&resp)
-		logrus.Debugf("Sent Pong to %s", peer.conn.RemoteAddr())
+		log.Debug("sent pong")
 
 	case *Pong:
 		// update peer info
-		peerInfo.Lock()
-		peerInfo.TotalDifficulty = msg.TotalDifficulty
-		peerInfo.Height = msg.Height
-		peerInfo.Unlock()
+		peerInfo.SetChain(msg.TotalDifficulty, msg.Height)
 
-		logrus.Debugf("Received Pong from %s", peer.conn.RemoteAddr())
+		log.Debug("received pong")
+		s.requestHeaders(peer, msg.TotalDifficulty)
 
 	case *GetPeerAddrs:
 		// MUST NOT be answered
 		// Send answer
-		peers := s.Pool.Peers(msg.Capabilities)
-		if peers != nil {
+		if peers := s.Pool.Peers(msg.Capabilities, peer.Addr); peers != nil {
 			peer.WriteMessage(peers)
+			log.Debugf("sent %d peers", len(peers.peers))
 		}
-		logrus.Debugf("Sent %d PeerAddrs to %s", len(peers.peers), peer.conn.RemoteAddr())
 
 	case *PeerAddrs:
 		// Adding peer to pool
@@ -164,19 +586,53 @@ func (s *Syncer) ProcessMessage(peer *Peer, message Message) {
 		peer.WriteMessage(&resp)
 
 	case *BlockHeader:
+		log := log.WithFields(logging.Fields{
+			"height": msg.Header.Height,
+			"hash":   msg.Header.Hash().String(),
+		})
+
 		headers := []consensus.BlockHeader{msg.Header}
-		if err := s.Chain.ProcessHeaders(headers); err != nil {
-			// ban peer ?
-			//s.Pool.Ban(peer.conn.RemoteAddr().String())
-			logrus.Infof("Failed to process header: %v", err)
+		err := s.Chain.ProcessHeaders(ctx, headers)
+		if err != nil {
+			log.Infof("header was not added: %v", err)
+			if misbehaving(err) {
+				s.Pool.Ban(peer.banAddr())
+			}
+
+			// the headers up to the announced one are missing
+			if errors.Is(err, chain.ErrOrphan) {
+				s.requestHeaders(peer, msg.Header.TotalDifficulty)
+			}
 		}
 
-		logrus.Debugf("Received BlockHeader from %s for height %d: %v:", peer.conn.RemoteAddr(), msg.Header.Height, msg.Header.Hash())
+		log.Debug("processed header")
+
+		// the announced block is requested from the announcer
+		if err == nil && !s.headersOnly && s.Chain.GetBlock(msg.Header.Hash()) == nil {
+			peer.SendBlockRequest(msg.Header.Hash())
+		}
 
 	case *BlockHeaders:
-		if err := s.Chain.ProcessHeaders(msg.Headers); err != nil {
-			// ban peer ?
-			s.Pool.Ban(peer.conn.RemoteAddr().String())
+		err := s.Chain.ProcessHeaders(ctx, msg.Headers)
+		if misbehaving(err) {
+			s.Pool.Ban(peer.banAddr())
+			return
+		}
+
+		// the blocks of the batch are downloaded from the peer during the
+		// initial block download
+		if err == nil && !s.headersOnly && s.InitialBlockDownload() {
+			for i := range msg.Headers {
+				if hash := msg.Headers[i].Hash(); s.Chain.GetBlock(hash) == nil {
+					peer.SendBlockRequest(hash)
+				}
+			}
+		}
+
+		// the full batch is followed by the next one
+		if err == nil && len(msg.Headers) == consensus.MaxBlockHeaders {
+			totalDifficulty, _ := peerInfo.Chain()
+			s.requestHeaders(peer, totalDifficulty)
 		}
 
 	case *GetBlock:
@@ -185,37 +641,95 @@ func (s *Syncer) ProcessMessage(peer *Peer, message Message) {
 			peer.WriteMessage(block)
 		}
 
+	case *GetCompactBlock:
+		// MUST NOT be answered
+		if block := s.Chain.GetBlock(msg.Hash); block != nil {
+			peer.WriteMessage(block.Compact())
+		}
+
 	case *consensus.Block:
 		// ProcessBlock puts block into blockchain
 		// if block on the top of chain than propagate it
 		// to others nodes with less TotalDifficulty
-		if err := s.Chain.ProcessBlock(msg); err != nil {
-			logrus.Info(err)
-			// TODO: maybe smarter ban peer ?
-			s.Pool.Ban(peer.conn.RemoteAddr().String())
+		log := log.WithFields(logging.Fields{
+			"height": msg.Header.Height,
+			"hash":   msg.Header.Hash().String(),
+		})
+
+		err := s.Chain.ProcessBlock(ctx, msg)
+		if misbehaving(err) {
+			log.Warnf("invalid block: %v", err)
+			s.Pool.Ban(peer.banAddr())
+			return
 		}
 
-		// update peer info
-		peerInfo.Lock()
+		// the peer is ahead of the orphan block
+		if err != nil && !errors.Is(err, chain.ErrOrphan) {
+			log.Infof("block was not added: %v", err)
+			return
+		}
 
-		if peerInfo.TotalDifficulty < msg.Header.TotalDifficulty || peerInfo.Height < msg.Header.Height {
-			peerInfo.TotalDifficulty = msg.Header.TotalDifficulty
-			peerInfo.Height = msg.Header.Height
+		if errors.Is(err, chain.ErrOrphan) {
+			s.requestHeaders(peer, msg.Header.TotalDifficulty)
 		}
 
-		peerInfo.Unlock()
+		// update peer info, the peer delivering the new block is the
+		// candidate of the high bandwidth peers
+		peerInfo.DeliverBlock(&msg.Header, err == nil)
 
-		// propagate if it is top block
-		if msg.Header.Height == s.Chain.Height() {
-			s.Pool.PropagateBlock(msg)
-		}
+		// the accepted head is propagated by announce with the mined ones
 
 	case *consensus.Transaction:
-		if err := s.Mempool.ProcessTx(msg); err != nil {
-			// ban peer ?
-			s.Pool.Ban(peer.conn.RemoteAddr().String())
+		// the transactions are not validated without the outputs & are
+		// not relayed during the initial block download
+		if s.headersOnly || s.InitialBlockDownload() {
+			return
+		}
+
+		if err := s.Mempool.ProcessTx(ctx, msg); err != nil {
+			if misbehaving(err) {
+				s.Pool.Ban(peer.banAddr())
+			}
+			return
+		}
+
+		// the transaction accepted by the pool passed the relay policy, the
+		// fluffed one is relayed on
+		s.Pool.PropagateTx(msg, true)
+
+	case *StemTransaction:
+		if s.headersOnly || s.InitialBlockDownload() {
+			return
+		}
+
+		if s.dandelion != nil {
+			s.processStemTx(ctx, peer, &msg.Transaction)
+			return
+		}
+
+		if err := s.Mempool.ProcessTx(ctx, &msg.Transaction); err != nil {
+			if misbehaving(err) {
+				s.Pool.Ban(peer.banAddr())
+			}
+			return
+		}
+
+		// without the Dandelion relay the node is the fluff point
+		s.Pool.PropagateTx(&msg.Transaction, true)
+
+	case *PoolKernels:
+		// the pool isn't repopulated during the initial block download
+		if !s.syncTxPool(peer) || s.InitialBlockDownload() {
+			return
+		}
+
+		s.requestPoolTxs(peer, msg)
+
+	case *GetPoolTxs:
+		if !s.syncTxPool(peer) {
+			return
 		}
 
-		// TODO: propagate tx?
+		s.sendPoolTxs(peer, msg)
 	}
 }
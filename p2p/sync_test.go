@@ -0,0 +1,440 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestMisbehaving(t *testing.T) {
+	for _, test := range []struct {
+		err error
+		ban bool
+	}{
+		{nil, false},
+		{chain.ErrOrphan, false},
+		{context.Canceled, false},
+		{context.DeadlineExceeded, false},
+		{errors.New("transaction pool is full"), false},
+		{consensus.ErrInvalidPow, true},
+		{fmt.Errorf("%w: cuckoo size too small: 10", consensus.ErrInvalidPow), true},
+		{consensus.ErrInvalidKernelSum, true},
+		{chain.ErrDifficultyTooLow, true},
+		{fmt.Errorf("%w: string len 20000", consensus.ErrTooLargeRead), true},
+		{chain.ErrInvalidHeaderRoot, true},
+		{chain.ErrInvalidBlock, true},
+	} {
+		if got := misbehaving(test.err); got != test.ban {
+			t.Errorf("%v: misbehaving was %v, want %v", test.err, got, test.ban)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	for _, test := range []struct {
+		headersOnly, archive, identity bool
+		capabilities                   consensus.Capabilities
+	}{
+		{false, false, false, consensus.CapPeerList | consensus.CapTxPool},
+		{false, true, false, consensus.CapFullHist | consensus.CapPeerList | consensus.CapTxPool},
+		{true, false, false, consensus.CapPeerList},
+		{true, true, false, consensus.CapPeerList},
+		{false, false, true, consensus.CapPeerList | consensus.CapNodeID | consensus.CapTxPool},
+	} {
+		s := Syncer{headersOnly: test.headersOnly, archive: test.archive}
+		if test.identity {
+			s.SetIdentity(NewIdentity(big.NewInt(8)))
+		}
+		if got := s.capabilities(); got != test.capabilities {
+			t.Errorf("headers only %v, archive %v: capabilities were %d, want %d", test.headersOnly, test.archive, got, test.capabilities)
+		}
+	}
+}
+
+// mockChain is the chain of the given height & blocks, the processed headers
+// & blocks are recorded
+type mockChain struct {
+	sync.RWMutex
+
+	height          uint64
+	totalDifficulty consensus.Difficulty
+	blocks          map[consensus.Hash]*consensus.Block
+	// err is returned by the headers & the block processing
+	err error
+
+	headers   [][]consensus.BlockHeader
+	processed []*consensus.Block
+
+	// subscribed is the new head blocks channel, nil if unsubscribed
+	subscribed chan<- *consensus.Block
+}
+
+func (c *mockChain) Genesis() consensus.Block              { return chain.Testnet4 }
+func (c *mockChain) TotalDifficulty() consensus.Difficulty { return c.totalDifficulty }
+func (c *mockChain) Height() uint64                        { return c.height }
+func (c *mockChain) HeaderHead() consensus.BlockHeader {
+	return consensus.BlockHeader{Height: c.height}
+}
+func (c *mockChain) Locator() consensus.Locator                    { return consensus.Locator{} }
+func (c *mockChain) GetBlock(hash consensus.Hash) *consensus.Block { return c.blocks[hash] }
+
+func (c *mockChain) GetBlockHeaders(loc consensus.Locator) []consensus.BlockHeader {
+	return []consensus.BlockHeader{chain.Testnet4.Header}
+}
+
+func (c *mockChain) ProcessHeaders(ctx context.Context, headers []consensus.BlockHeader) error {
+	c.headers = append(c.headers, headers)
+	return c.err
+}
+
+func (c *mockChain) ValidateHeader(prev, header *consensus.BlockHeader) error {
+	return nil
+}
+
+func (c *mockChain) ReadBlock(ctx context.Context, r io.Reader, block *consensus.Block) error {
+	return block.Read(r)
+}
+
+func (c *mockChain) ProcessBlock(ctx context.Context, block *consensus.Block) error {
+	c.processed = append(c.processed, block)
+	return c.err
+}
+
+func (c *mockChain) Subscribe(ch chan<- *consensus.Block) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.subscribed = ch
+}
+
+func (c *mockChain) Unsubscribe(ch chan<- *consensus.Block) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.subscribed == ch {
+		c.subscribed = nil
+	}
+}
+
+// mockMempool records the processed transactions, pool is the pool
+// transactions
+type mockMempool struct {
+	err  error
+	txs  []*consensus.Transaction
+	pool []*consensus.Transaction
+}
+
+func (m *mockMempool) ProcessTx(ctx context.Context, tx *consensus.Transaction) error {
+	m.txs = append(m.txs, tx)
+	return m.err
+}
+
+func (m *mockMempool) Transactions() []*consensus.Transaction { return m.pool }
+
+// mockPool is the pool of the single peer info, the added, banned & the
+// propagated items are recorded
+type mockPool struct {
+	info  *peerInfo
+	peers *PeerAddrs
+
+	added     []string
+	banned    []string
+	blocks    []*consensus.Block
+	txs       []*consensus.Transaction
+	stems     []*consensus.Transaction
+	connected []PeerStats
+}
+
+func (pp *mockPool) PropagateBlock(block *consensus.Block) { pp.blocks = append(pp.blocks, block) }
+func (pp *mockPool) PropagateTx(tx *consensus.Transaction, fluff bool) {
+	if fluff {
+		pp.txs = append(pp.txs, tx)
+	} else {
+		pp.stems = append(pp.stems, tx)
+	}
+}
+func (pp *mockPool) Peers(consensus.Capabilities, string) *PeerAddrs { return pp.peers }
+func (pp *mockPool) PeerInfo(addr string) *peerInfo                  { return pp.info }
+func (pp *mockPool) Connected() []PeerStats                          { return pp.connected }
+func (pp *mockPool) All() []PeerStats                                { return pp.connected }
+func (pp *mockPool) Add(addr string)                                 { pp.added = append(pp.added, addr) }
+func (pp *mockPool) Listen(addr string) error                        { return nil }
+func (pp *mockPool) Serve(listener net.Listener)                     {}
+func (pp *mockPool) Ban(addr string)                                 { pp.banned = append(pp.banned, addr) }
+func (pp *mockPool) Unban(addr string)                               {}
+func (pp *mockPool) Bans() []string                                  { return pp.banned }
+func (pp *mockPool) ConnLog(ConnLogQuery) []ConnEvent                { return nil }
+func (pp *mockPool) Run()                                            {}
+func (pp *mockPool) Stop()                                           {}
+
+// mockConn is the connection of the remote addr only
+type mockConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *mockConn) RemoteAddr() net.Addr { return c.addr }
+
+// sentMessages returns the types of the messages queued to the peer
+func sentMessages(peer *Peer) []string {
+	var result []string
+	for {
+		select {
+		case msg := <-peer.sendQueue:
+			result = append(result, fmt.Sprintf("%T", msg))
+		default:
+			return result
+		}
+	}
+}
+
+func TestProcessMessage(t *testing.T) {
+	// the block hash is the hash of the proof
+	known := consensus.Block{Header: consensus.BlockHeader{Height: 7, POW: consensus.Proof{EdgeBits: 16, Nonces: []uint32{7}}}}
+	unknown := consensus.BlockHeader{Height: 12, TotalDifficulty: 300, POW: consensus.Proof{EdgeBits: 16, Nonces: []uint32{12}}}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 3414}
+
+	batch := make([]consensus.BlockHeader, consensus.MaxBlockHeaders)
+	for i := range batch {
+		batch[i].Height = uint64(i + 1)
+	}
+
+	tests := []struct {
+		name    string
+		msg     Message
+		chain   error
+		mempool error
+		// headersOnly syncs the headers only, ibd is the initial block
+		// download
+		headersOnly, ibd bool
+		// unknown is the message of the peer missing in the pool
+		unknown bool
+
+		sent       []string
+		ban        bool
+		added      int
+		processed  int
+		txs        int
+		propagated int
+		// height & totalDifficulty are of the peer info after the message
+		height          uint64
+		totalDifficulty consensus.Difficulty
+	}{
+		{name: "ping", msg: &Ping{TotalDifficulty: 150, Height: 11}, sent: []string{"*p2p.Pong"}, height: 11, totalDifficulty: 150},
+		{name: "pong ahead", msg: &Pong{Ping{TotalDifficulty: 300, Height: 12}}, ibd: true, sent: []string{"*p2p.GetBlockHeaders"}, height: 12, totalDifficulty: 300},
+		{name: "pong ahead after ibd", msg: &Pong{Ping{TotalDifficulty: 300, Height: 12}}, height: 12, totalDifficulty: 300},
+		{name: "pong ahead of headers only", msg: &Pong{Ping{TotalDifficulty: 300, Height: 12}}, headersOnly: true, sent: []string{"*p2p.GetBlockHeaders"}, height: 12, totalDifficulty: 300},
+		{name: "pong behind", msg: &Pong{Ping{TotalDifficulty: 50, Height: 5}}, ibd: true, height: 5, totalDifficulty: 50},
+		{name: "get peer addrs", msg: &GetPeerAddrs{}, sent: []string{"*p2p.PeerAddrs"}},
+		{name: "peer addrs", msg: &PeerAddrs{peers: []*net.TCPAddr{addr, addr}}, added: 2},
+		{name: "get block headers", msg: &GetBlockHeaders{}, sent: []string{"*p2p.BlockHeaders"}},
+		{name: "header", msg: &BlockHeader{Header: unknown}, sent: []string{"*p2p.GetBlock"}},
+		{name: "known header", msg: &BlockHeader{Header: known.Header}},
+		{name: "header of headers only", msg: &BlockHeader{Header: unknown}, headersOnly: true},
+		{name: "invalid header", msg: &BlockHeader{Header: unknown}, chain: consensus.ErrInvalidPow, ban: true},
+		{name: "orphan header", msg: &BlockHeader{Header: unknown}, chain: chain.ErrOrphan, ibd: true, sent: []string{"*p2p.GetBlockHeaders"}},
+		{name: "headers", msg: &BlockHeaders{Headers: []consensus.BlockHeader{known.Header, unknown}}},
+		{name: "headers of ibd", msg: &BlockHeaders{Headers: []consensus.BlockHeader{known.Header, unknown}}, ibd: true, sent: []string{"*p2p.GetBlock"}},
+		{name: "invalid headers", msg: &BlockHeaders{Headers: []consensus.BlockHeader{unknown}}, chain: chain.ErrInvalidPrevious, ibd: true, ban: true},
+		{name: "orphan headers", msg: &BlockHeaders{Headers: []consensus.BlockHeader{unknown}}, chain: chain.ErrOrphan, ibd: true},
+		{name: "full headers batch", msg: &BlockHeaders{Headers: batch}, headersOnly: true, sent: []string{"*p2p.GetBlockHeaders"}},
+		{name: "get block", msg: &GetBlock{Hash: known.Hash()}, sent: []string{"*consensus.Block"}},
+		{name: "get unknown block", msg: &GetBlock{Hash: unknown.Hash()}},
+		{name: "get compact block", msg: &GetCompactBlock{GetBlock{Hash: known.Hash()}}, sent: []string{"*consensus.CompactBlock"}},
+		{name: "block", msg: &consensus.Block{Header: unknown}, processed: 1, height: 12, totalDifficulty: 300},
+		{name: "block of ibd", msg: &consensus.Block{Header: unknown}, ibd: true, processed: 1, height: 12, totalDifficulty: 300},
+		{name: "block of headers only", msg: &consensus.Block{Header: unknown}, headersOnly: true, processed: 1, height: 12, totalDifficulty: 300},
+		{name: "invalid block", msg: &consensus.Block{Header: unknown}, chain: consensus.ErrInvalidKernelSum, processed: 1, ban: true, height: 10, totalDifficulty: 100},
+		{name: "orphan block", msg: &consensus.Block{Header: unknown}, chain: chain.ErrOrphan, ibd: true, processed: 1, sent: []string{"*p2p.GetBlockHeaders"}, height: 12, totalDifficulty: 300},
+		{name: "fork block", msg: &consensus.Block{Header: known.Header}, chain: context.DeadlineExceeded, processed: 1, height: 10, totalDifficulty: 100},
+		{name: "transaction", msg: &consensus.Transaction{}, txs: 1, propagated: 1},
+		{name: "transaction of full pool", msg: &consensus.Transaction{}, mempool: errors.New("transaction pool is full"), txs: 1},
+		{name: "transaction of ibd", msg: &consensus.Transaction{}, ibd: true},
+		{name: "transaction of headers only", msg: &consensus.Transaction{}, headersOnly: true},
+		{name: "invalid transaction", msg: &consensus.Transaction{}, mempool: consensus.ErrInvalidSignature, txs: 1, ban: true},
+		{name: "stem transaction", msg: &StemTransaction{}, txs: 1, propagated: 1},
+		{name: "stem transaction of full pool", msg: &StemTransaction{}, mempool: errors.New("transaction pool is full"), txs: 1},
+		{name: "invalid stem transaction", msg: &StemTransaction{}, mempool: consensus.ErrNoKernels, txs: 1, ban: true},
+		{name: "unknown peer", msg: &Ping{TotalDifficulty: 150, Height: 11}, unknown: true, height: 10, totalDifficulty: 100},
+	}
+
+	for _, test := range tests {
+		c := &mockChain{
+			height:          12,
+			totalDifficulty: 50,
+			blocks:          map[consensus.Hash]*consensus.Block{known.Hash(): &known},
+			err:             test.chain,
+		}
+		mempool := &mockMempool{err: test.mempool}
+
+		s := NewSyncer(nil, c, mempool)
+		s.SetLogger(logging.Nop)
+		s.headersOnly = test.headersOnly
+		s.ibd.done = !test.ibd
+
+		info := &peerInfo{height: 10, totalDifficulty: 100, status: psConnected}
+		pool := &mockPool{info: info, peers: &PeerAddrs{peers: []*net.TCPAddr{addr}}}
+		if test.unknown {
+			pool.info = nil
+		}
+		s.Pool = pool
+
+		peer := &Peer{
+			conn:      &mockConn{addr: addr},
+			sync:      s,
+			quit:      make(chan struct{}),
+			sendQueue: make(chan Message, 16),
+			Addr:      addr.String(),
+		}
+
+		s.ProcessMessage(peer, test.msg)
+
+		if sent := sentMessages(peer); fmt.Sprint(sent) != fmt.Sprint(test.sent) {
+			t.Errorf("%s: sent %v, want %v", test.name, sent, test.sent)
+		}
+
+		if banned := len(pool.banned) > 0; banned != test.ban {
+			t.Errorf("%s: peer banned %v, want %v", test.name, banned, test.ban)
+		}
+
+		if len(pool.added) != test.added {
+			t.Errorf("%s: %d peers added, want %d", test.name, len(pool.added), test.added)
+		}
+
+		if len(c.processed) != test.processed || len(mempool.txs) != test.txs {
+			t.Errorf("%s: %d blocks & %d txs processed, want %d & %d", test.name, len(c.processed), len(mempool.txs), test.processed, test.txs)
+		}
+
+		if propagated := len(pool.blocks) + len(pool.txs); propagated != test.propagated {
+			t.Errorf("%s: %d items propagated, want %d", test.name, propagated, test.propagated)
+		}
+
+		totalDifficulty, height := info.Chain()
+		if test.height != 0 && (height != test.height || totalDifficulty != test.totalDifficulty) {
+			t.Errorf("%s: peer info was %d/%d, want %d/%d", test.name, height, totalDifficulty, test.height, test.totalDifficulty)
+		}
+	}
+}
+
+func TestMessageHandlers(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 3414}
+
+	s := NewSyncer(nil, &mockChain{height: 12, totalDifficulty: 50}, &mockMempool{})
+	s.SetLogger(logging.Nop)
+	s.Pool = &mockPool{info: &peerInfo{status: psConnected}, peers: &PeerAddrs{}}
+
+	var calls []string
+	s.Observe(func(peer *Peer, msg Message) {
+		calls = append(calls, fmt.Sprintf("observe %T", msg))
+	})
+	for _, name := range []string{"first", "second"} {
+		name := name
+		s.Handle(consensus.MsgTypePing, func(peer *Peer, msg Message) {
+			calls = append(calls, name+" "+peer.Addr)
+		})
+	}
+
+	peer := &Peer{
+		conn:      &mockConn{addr: addr},
+		sync:      s,
+		quit:      make(chan struct{}),
+		sendQueue: make(chan Message, 16),
+		Addr:      addr.String(),
+	}
+	s.ProcessMessage(peer, &Ping{TotalDifficulty: 150, Height: 11})
+	s.ProcessMessage(peer, &GetPeerAddrs{})
+
+	want := []string{"observe *p2p.Ping", "first " + addr.String(), "second " + addr.String(), "observe *p2p.GetPeerAddrs"}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("handlers were called %v, want %v", calls, want)
+	}
+
+	// the messages are processed by the syncer too
+	if sent := sentMessages(peer); fmt.Sprint(sent) != "[*p2p.Pong *p2p.PeerAddrs]" {
+		t.Errorf("sent %v", sent)
+	}
+}
+
+func TestHandshakeSelfConnection(t *testing.T) {
+	s := NewSyncer(nil, nil, nil)
+	s.SetLogger(logging.Nop)
+
+	for _, test := range []struct {
+		name  string
+		nonce uint64
+		self  bool
+	}{
+		{"peer", 1, false},
+		{"ourselves", s.nextNonce(), true},
+	} {
+		local, remote := net.Pipe()
+
+		go func() {
+			WriteMessage(remote, &hand{
+				Version:         consensus.ProtocolVersion,
+				Nonce:           test.nonce,
+				TotalDifficulty: consensus.Difficulty(1),
+				SenderAddr:      &net.TCPAddr{IP: net.IPv4zero},
+				ReceiverAddr:    &net.TCPAddr{IP: net.IPv4zero},
+				UserAgent:       "test",
+			})
+			ReadMessage(remote, new(shake))
+		}()
+
+		h, err := handByShake(local, s)
+		if (err != nil) != test.self {
+			t.Errorf("%s: handshake error was %v", test.name, err)
+		}
+		if h == nil || h.Nonce != test.nonce {
+			t.Errorf("%s: hand wasn't received", test.name)
+		}
+
+		local.Close()
+		remote.Close()
+	}
+}
+
+func TestHandshakeUnsupportedVersion(t *testing.T) {
+	s := NewSyncer(nil, nil, nil)
+	s.SetLogger(logging.Nop)
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		WriteMessage(remote, &hand{
+			Version:         consensus.ProtocolVersion + 1,
+			TotalDifficulty: consensus.Difficulty(1),
+			SenderAddr:      &net.TCPAddr{IP: net.IPv4zero},
+			ReceiverAddr:    &net.TCPAddr{IP: net.IPv4zero},
+			UserAgent:       "test",
+		})
+		_, err := ReadMessage(remote, new(shake))
+		errs <- err
+	}()
+
+	if _, err := handByShake(local, s); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("handshake error was %v, want %v", err, ErrUnsupportedVersion)
+	}
+
+	// the peer reads the PeerError instead of the shake
+	err := <-errs
+	var peerErr *PeerError
+	if !errors.As(err, &peerErr) || peerErr.Code != uint32(consensus.NetUnsupportedVersion) || !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("peer error was %v, want the unsupported version", err)
+	}
+}
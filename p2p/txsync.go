@@ -0,0 +1,89 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"crypto/rand"
+	"github.com/dblokhin/gringo/consensus"
+)
+
+// syncTxPool returns true if the pool transactions are exchanged with the
+// peer: both nodes relay the transactions & the peer has CapTxPool
+func (s *Syncer) syncTxPool(peer *Peer) bool {
+	return s.Mempool != nil && !s.headersOnly && peer.Info.Capabilities&consensus.CapTxPool != 0
+}
+
+// sendPoolKernels sends the kernel short ids of the pool transactions to the
+// connected peer, the peer requests the transactions it misses. The ids are
+// keyed by the random key of the message
+func (s *Syncer) sendPoolKernels(peer *Peer) {
+	if !s.syncTxPool(peer) {
+		return
+	}
+
+	msg := &PoolKernels{}
+	if _, err := rand.Read(msg.Key[:]); err != nil {
+		s.log.Errorf("failed to generate pool kernels key: %v", err)
+		return
+	}
+
+	for id := range s.poolKernels(msg.Key) {
+		if len(msg.IDs) == consensus.MaxPoolKernels {
+			break
+		}
+		msg.IDs = append(msg.IDs, consensus.ShortID(id))
+	}
+
+	peer.logger().Debugf("sending %d pool kernels", len(msg.IDs))
+	peer.WriteMessage(msg)
+}
+
+// poolKernels returns the pool transactions by the kernel short ids of key
+func (s *Syncer) poolKernels(key consensus.Hash) map[string]*consensus.Transaction {
+	result := make(map[string]*consensus.Transaction)
+	for _, tx := range s.Mempool.Transactions() {
+		for i := range tx.Kernels {
+			result[string(tx.Kernels[i].Hash().ShortID(key))] = tx
+		}
+	}
+
+	return result
+}
+
+// requestPoolTxs requests the transactions of the peer kernels missing in
+// the pool
+func (s *Syncer) requestPoolTxs(peer *Peer, msg *PoolKernels) {
+	known := s.poolKernels(msg.Key)
+
+	request := &GetPoolTxs{PoolKernels{Key: msg.Key}}
+	for _, id := range msg.IDs {
+		if _, ok := known[string(id)]; !ok {
+			request.IDs = append(request.IDs, id)
+		}
+	}
+
+	if len(request.IDs) == 0 {
+		return
+	}
+
+	peer.logger().Debugf("requesting %d pool transactions", len(request.IDs))
+	peer.WriteMessage(request)
+}
+
+// sendPoolTxs sends the pool transactions of the requested kernels, the
+// transaction of the several requested kernels is sent once
+func (s *Syncer) sendPoolTxs(peer *Peer, msg *GetPoolTxs) {
+	txs := s.poolKernels(msg.Key)
+
+	sent := make(map[*consensus.Transaction]bool)
+	for _, id := range msg.IDs {
+		if tx, ok := txs[string(id)]; ok && !sent[tx] {
+			sent[tx] = true
+			peer.WriteMessage(tx)
+		}
+	}
+
+	peer.logger().Debugf("sent %d pool transactions", len(sent))
+}
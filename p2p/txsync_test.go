@@ -0,0 +1,151 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"math/big"
+	"net"
+	"testing"
+)
+
+// testTx returns the transaction of the kernels of the excess keys
+func testTx(keys ...int64) *consensus.Transaction {
+	tx := new(consensus.Transaction)
+	for _, key := range keys {
+		tx.Kernels = append(tx.Kernels, consensus.TxKernel{Excess: *secp256k1zkp.CommitValue(big.NewInt(key), big.NewInt(0))})
+	}
+
+	return tx
+}
+
+// testTxPoolSyncer returns the syncer of the pool txs & the connected peer of
+// the capabilities
+func testTxPoolSyncer(capabilities consensus.Capabilities, txs ...*consensus.Transaction) (*Syncer, *Peer) {
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 3414}
+
+	s := NewSyncer(nil, &mockChain{}, &mockMempool{pool: txs})
+	s.SetLogger(logging.Nop)
+	s.ibd.done = true
+	s.Pool = &mockPool{info: &peerInfo{status: psConnected}}
+
+	peer := &Peer{
+		conn:      &mockConn{addr: addr},
+		sync:      s,
+		quit:      make(chan struct{}),
+		sendQueue: make(chan Message, 16),
+		Addr:      addr.String(),
+	}
+	peer.Info.Capabilities = capabilities
+
+	return s, peer
+}
+
+// sent returns the messages queued to the peer
+func sent(peer *Peer) []Message {
+	var result []Message
+	for {
+		select {
+		case msg := <-peer.sendQueue:
+			result = append(result, msg)
+		default:
+			return result
+		}
+	}
+}
+
+func TestTxPoolSync(t *testing.T) {
+	shared, missing := testTx(1, 2), testTx(3)
+
+	// the restarted node misses the transaction of the peer
+	s, peer := testTxPoolSyncer(consensus.CapTxPool, shared, missing)
+	s.sendPoolKernels(peer)
+
+	msgs := sent(peer)
+	if len(msgs) != 1 || len(msgs[0].(*PoolKernels).IDs) != 3 {
+		t.Fatalf("pool kernels were %v", msgs)
+	}
+	kernels := msgs[0].(*PoolKernels)
+
+	restarted, restartedPeer := testTxPoolSyncer(consensus.CapTxPool, shared)
+	restarted.ProcessMessage(restartedPeer, kernels)
+
+	msgs = sent(restartedPeer)
+	if len(msgs) != 1 || len(msgs[0].(*GetPoolTxs).IDs) != 1 {
+		t.Fatalf("pool txs request was %v", msgs)
+	}
+	request := msgs[0].(*GetPoolTxs)
+
+	if id := missing.Kernels[0].Hash().ShortID(kernels.Key); !bytes.Equal(request.IDs[0], id) {
+		t.Errorf("requested kernel was %s, want %s", request.IDs[0], id)
+	}
+
+	s.ProcessMessage(peer, request)
+	if msgs := sent(peer); len(msgs) != 1 || msgs[0] != missing {
+		t.Errorf("sent pool txs were %v", msgs)
+	}
+
+	// the transaction of the several requested kernels is sent once
+	s.ProcessMessage(peer, &GetPoolTxs{PoolKernels{Key: kernels.Key, IDs: kernels.IDs}})
+	if msgs := sent(peer); len(msgs) != 2 {
+		t.Errorf("%d pool txs were sent, want 2", len(msgs))
+	}
+}
+
+func TestTxPoolSyncSkipped(t *testing.T) {
+	tx := testTx(1)
+	kernels := &PoolKernels{IDs: consensus.ShortIDList{tx.Kernels[0].Hash().ShortID(consensus.ZeroHash)}}
+
+	for _, test := range []struct {
+		name         string
+		capabilities consensus.Capabilities
+		headersOnly  bool
+		ibd          bool
+	}{
+		{name: "grin peer", capabilities: consensus.CapFullNode},
+		{name: "headers only", capabilities: consensus.CapTxPool, headersOnly: true},
+		{name: "initial block download", capabilities: consensus.CapTxPool, ibd: true},
+	} {
+		s, peer := testTxPoolSyncer(test.capabilities, tx)
+		s.headersOnly = test.headersOnly
+		s.ibd.done = !test.ibd
+
+		if !test.ibd {
+			s.sendPoolKernels(peer)
+			s.ProcessMessage(peer, &GetPoolTxs{*kernels})
+		}
+
+		empty, emptyPeer := testTxPoolSyncer(test.capabilities)
+		empty.headersOnly = test.headersOnly
+		empty.ibd.done = !test.ibd
+		empty.ProcessMessage(emptyPeer, kernels)
+
+		if msgs := append(sent(peer), sent(emptyPeer)...); len(msgs) != 0 {
+			t.Errorf("%s: sent %v", test.name, msgs)
+		}
+	}
+}
+
+func TestPoolKernelsRead(t *testing.T) {
+	msg := &PoolKernels{Key: consensus.Hash{1}, IDs: consensus.ShortIDList{{1, 2, 3, 4, 5, 6}, {6, 5, 4, 3, 2, 1}}}
+
+	read := new(PoolKernels)
+	if err := read.Read(bytes.NewReader(msg.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	if read.Key != msg.Key || len(read.IDs) != 2 || !bytes.Equal(read.IDs[1], msg.IDs[1]) {
+		t.Errorf("pool kernels were read as %v", read)
+	}
+
+	data := append(make([]byte, consensus.BlockHashSize), 0, 0x01, 0x86, 0xa1)
+	if err := read.Read(bytes.NewReader(data)); !errors.Is(err, consensus.ErrTooLargeRead) {
+		t.Errorf("ids over the limit: %v, want ErrTooLargeRead", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"github.com/btcsuite/btcd/btcec"
 	. "github.com/yoss22/bulletproofs"
 	"math/big"
@@ -74,11 +75,25 @@ func (s Signature) Bytes() [64]byte {
 //   s*G == R + e*P
 func SignMessage(publicKey Point, privateKey big.Int, message [32]byte) Signature {
 	// Compute a random nonce, k.
-	k := RandomInt()
+	return SignMessageNonce(publicKey, privateKey, message, RandomInt())
+}
+
+// SignMessageNonce signs the message by the nonce k of SignMessage, the
+// nonce must be secret & never reused for the other message. The
+// deterministic signatures derive it from the private key & the message
+func SignMessageNonce(publicKey Point, privateKey big.Int, message [32]byte, nonce *big.Int) Signature {
+	k := new(big.Int).Set(nonce)
 
 	// R is the public key for k.
 	R := ScalarMulPoint(&G, k)
 
+	// The signature keeps only R.x and DecodeSignature recovers the y that
+	// is a quadratic residue, so negate k if R.y is not one.
+	if big.Jacobi(R.Y, btcec.S256().P) != 1 {
+		k.Sub(btcec.S256().N, k)
+		R = ScalarMulPoint(&G, k)
+	}
+
 	// Compute a non-interactive challenge.
 	Rx := GetB32(R.X)
 	compressedPubkey := CompressPubkey(publicKey)
@@ -134,6 +149,33 @@ func CompressPubkey(p Point) [33]byte {
 	return buf
 }
 
+// DecompressPubkey returns the point of the 33-byte compressed pubkey.
+func DecompressPubkey(pubkey [33]byte) (*Point, error) {
+	if pubkey[0] != TagPubkeyEven && pubkey[0] != TagPubkeyOdd {
+		return nil, errors.New("invalid pubkey tag")
+	}
+
+	p := btcec.S256().Params().P
+	x := new(big.Int).SetBytes(pubkey[1:])
+	if x.Cmp(p) >= 0 {
+		return nil, errors.New("invalid pubkey x coordinate")
+	}
+
+	// the root of the non residue is not on the curve
+	y := decompressPoint(pubkey[1:])
+	x3 := new(big.Int).Exp(x, big.NewInt(3), p)
+	x3.Add(x3, btcec.S256().Params().B)
+	if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(x3.Mod(x3, p)) != 0 {
+		return nil, errors.New("pubkey is not on the curve")
+	}
+
+	if y.Bit(0) != uint(pubkey[0]&1) {
+		y.Sub(p, y)
+	}
+
+	return &Point{X: x, Y: y}, nil
+}
+
 // decompressPoint returns the y-coordinate for the given x coordinate.
 func decompressPoint(xBytes []byte) *big.Int {
 	x := new(big.Int).SetBytes(xBytes)
@@ -57,3 +57,46 @@ func TestVerifyKernel(t *testing.T) {
 		t.Errorf("verify failed")
 	}
 }
+
+func TestSignatureRoundTrip(t *testing.T) {
+	x := big.NewInt(8)
+	P := ScalarMulPoint(&G, x)
+	msg := ComputeMessage(2, 0)
+
+	// every signature must survive the encoding, whatever the random nonce
+	for i := 0; i < 32; i++ {
+		sig := DecodeSignature(SignMessage(*P, *x, msg).Bytes())
+
+		if !VerifySignature(*P, msg, sig) {
+			t.Fatalf("failed to verify decoded signature %d", i)
+		}
+	}
+}
+
+func TestDecompressPubkey(t *testing.T) {
+	for _, x := range []int64{1, 2, 3, 8} {
+		P := ScalarMulPoint(&G, big.NewInt(x))
+
+		point, err := DecompressPubkey(CompressPubkey(*P))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if point.X.Cmp(P.X) != 0 || point.Y.Cmp(P.Y) != 0 {
+			t.Errorf("pubkey of %d was decompressed to the other point", x)
+		}
+	}
+
+	invalid := CompressPubkey(G)
+	invalid[0] = 0x08
+	if _, err := DecompressPubkey(invalid); err == nil {
+		t.Error("pubkey of the invalid tag was decompressed")
+	}
+
+	// x = 5 isn't on the curve: 5^3 + 7 isn't a square
+	var offCurve [33]byte
+	offCurve[0], offCurve[32] = TagPubkeyEven, 5
+	if _, err := DecompressPubkey(offCurve); err == nil {
+		t.Error("pubkey off the curve was decompressed")
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package simnet runs the network of the in-process nodes for the end-to-end
+// tests of the p2p & chain layers. The nodes keep the chain in memory, listen
+// on the random localhost ports and accept the blocks of the minimum
+// difficulty without the proof of work.
+//
+// The p2p layer doesn't sync the lagging nodes yet and drops the orphan
+// blocks, the blocks reach only the connected nodes & may be reordered on the
+// way. So the network should be connected before mining, and Network.Mine
+// waits for the block to reach all the nodes.
+package simnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/mempool"
+	"github.com/dblokhin/gringo/p2p"
+	"github.com/dblokhin/gringo/storage"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// pollInterval is the period of checking the wait conditions
+const pollInterval = 10 * time.Millisecond
+
+// seq makes the proofs & so the hashes of the mined blocks unique
+var seq uint32
+
+// Node is the in-process node
+type Node struct {
+	Chain   *chain.Chain
+	Storage *storage.MemStorage
+	Mempool *mempool.Pool
+	Syncer  *p2p.Syncer
+
+	// Addr is the p2p listen addr
+	Addr string
+}
+
+// Network is the set of the in-process nodes
+type Network struct {
+	Nodes []*Node
+}
+
+// New returns the network of n nodes of the genesis chain listening on the
+// localhost ports, the nodes are not connected & not started
+func New(n int, genesis *consensus.Block) (*Network, error) {
+	network := new(Network)
+
+	for i := 0; i < n; i++ {
+		node, err := newNode(genesis)
+		if err != nil {
+			network.Stop()
+			return nil, err
+		}
+
+		network.Nodes = append(network.Nodes, node)
+	}
+
+	return network, nil
+}
+
+// newNode returns the node listening on the random localhost port
+func newNode(genesis *consensus.Block) (*Node, error) {
+	store := storage.NewMemStorage()
+	store.AddBlock(genesis)
+
+	node := &Node{
+		Storage: store,
+		Chain:   chain.New(genesis, store),
+	}
+	node.Chain.SetLogger(logging.Nop)
+	node.Chain.SetValidator(validate)
+	node.Chain.SetHeaderValidator(validateHeader)
+
+	node.Mempool = mempool.New(node.Chain)
+	node.Mempool.SetLogger(logging.Nop)
+
+	node.Syncer = p2p.NewSyncer(nil, node.Chain, node.Mempool)
+	node.Syncer.SetLogger(logging.Nop)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	node.Addr = listener.Addr().String()
+	node.Syncer.Pool.Serve(listener)
+
+	return node, nil
+}
+
+// validate checks the block version skipping the proof of work & the block
+// body rules, the difficulty is checked by the chain
+func validate(ctx context.Context, block *consensus.Block) error {
+	return validateHeader(&block.Header)
+}
+
+// validateHeader checks the header version skipping the proof of work
+func validateHeader(header *consensus.BlockHeader) error {
+	if !consensus.TestnetParams.ValidateBlockVersion(header.Height, header.Version) {
+		return fmt.Errorf("%w %d", consensus.ErrInvalidBlockVersion, header.Version)
+	}
+
+	return nil
+}
+
+// Connect makes the node from dial the node to
+func (n *Network) Connect(from, to int) {
+	n.Nodes[from].Syncer.Pool.Add(n.Nodes[to].Addr)
+}
+
+// Start runs the nodes
+func (n *Network) Start() {
+	for _, node := range n.Nodes {
+		go node.Syncer.Run()
+	}
+}
+
+// Stop stops the nodes & closes their connections
+func (n *Network) Stop() {
+	for _, node := range n.Nodes {
+		node.Syncer.Stop()
+	}
+}
+
+// Wait waits for cond to be true
+func (n *Network) Wait(cond func() bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return errors.New("timeout")
+		}
+
+		time.Sleep(pollInterval)
+	}
+
+	return nil
+}
+
+// WaitPeers waits for every node to have at least min connected peers
+func (n *Network) WaitPeers(min int, timeout time.Duration) error {
+	return n.Wait(func() bool {
+		for _, node := range n.Nodes {
+			if node.Peers() < min {
+				return false
+			}
+		}
+		return true
+	}, timeout)
+}
+
+// WaitHeight waits for every node to reach height
+func (n *Network) WaitHeight(height uint64, timeout time.Duration) error {
+	err := n.Wait(func() bool {
+		for _, node := range n.Nodes {
+			if node.Chain.Height() < height {
+				return false
+			}
+		}
+		return true
+	}, timeout)
+
+	if err != nil {
+		heights := make([]uint64, len(n.Nodes))
+		for i, node := range n.Nodes {
+			heights[i] = node.Chain.Height()
+		}
+		return fmt.Errorf("nodes heights are %v, want %d", heights, height)
+	}
+
+	return nil
+}
+
+// Mine mines the block by the node i & waits for all the nodes to reach it
+func (n *Network) Mine(i int, timeout time.Duration) (*consensus.Block, error) {
+	block, err := n.Nodes[i].Mine()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.WaitHeight(block.Header.Height, timeout); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// Peers returns count of the connected peers
+func (node *Node) Peers() int {
+	return len(node.Syncer.Pool.Connected())
+}
+
+// Mine adds the new block on top of the node chain, the syncer announces it
+// to the connected peers
+func (node *Node) Mine() (*consensus.Block, error) {
+	head := node.Chain.Head()
+	block := nextBlock(&head)
+
+	if err := node.Chain.ProcessBlock(context.Background(), block); err != nil {
+		return nil, err
+	}
+
+	if node.Chain.Height() != block.Header.Height {
+		return nil, fmt.Errorf("mined block %d is not accepted", block.Header.Height)
+	}
+
+	return block, nil
+}
+
+// nextBlock returns the empty block of the minimum difficulty after parent
+func nextBlock(parent *consensus.Block) *consensus.Block {
+	header := parent.Header
+	header.Height = parent.Header.Height + 1
+	header.Previous = parent.Hash()
+	// the header MMR is not maintained
+	header.PreviousRoot = consensus.ZeroHash
+	header.Timestamp = parent.Header.Timestamp.Add(consensus.TestnetParams.BlockTime)
+	header.TotalDifficulty = parent.Header.TotalDifficulty.Add(parent.Header.POW.ToDifficulty())
+
+	// the proof is not a cuckoo cycle, the nonce is searched for the proof
+	// hash of the minimum difficulty only. The block hash is the hash of the
+	// proof, so the unique nonces make the blocks of the nodes different
+	header.POW.Nonces = append([]uint32(nil), parent.Header.POW.Nonces...)
+	for {
+		header.POW.Nonces[0] = atomic.AddUint32(&seq, 1)
+		if header.Difficulty = header.POW.ToDifficulty(); header.Difficulty >= consensus.MinimumDifficulty {
+			break
+		}
+	}
+	header.Nonce = uint64(header.POW.Nonces[0])
+
+	return &consensus.Block{Header: header}
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package simnet
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+	"time"
+)
+
+const timeout = 10 * time.Second
+
+// newLine returns the started network of n nodes, each dialing the next one
+func newLine(t *testing.T, n int) *Network {
+	network, err := New(n, &chain.Testnet4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i+1 < n; i++ {
+		network.Connect(i, i+1)
+	}
+
+	network.Start()
+	if err := network.WaitPeers(1, timeout); err != nil {
+		network.Stop()
+		t.Fatalf("nodes are not connected: %v", err)
+	}
+
+	return network
+}
+
+func TestPropagation(t *testing.T) {
+	network := newLine(t, 3)
+	defer network.Stop()
+
+	// the blocks are relayed by the middle node to the last one
+	for i := 0; i < 5; i++ {
+		if _, err := network.Mine(0, timeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	head := network.Nodes[0].Chain.Head()
+	for i, node := range network.Nodes {
+		nodeHead := node.Chain.Head()
+		if nodeHead.Hash() != head.Hash() {
+			t.Errorf("node %d head was %s, want %s", i, nodeHead.Hash(), head.Hash())
+		}
+
+		if node.Chain.TotalDifficulty() != head.Header.TotalDifficulty {
+			t.Errorf("node %d total difficulty was %d, want %d", i, node.Chain.TotalDifficulty(), head.Header.TotalDifficulty)
+		}
+	}
+}
+
+func TestMiningNodes(t *testing.T) {
+	network := newLine(t, 3)
+	defer network.Stop()
+
+	// the nodes take turns mining on top of the common chain
+	for i := 0; i < 6; i++ {
+		if _, err := network.Mine(i%len(network.Nodes), timeout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every node stores the same linked chain
+	genesis := uint64(0)
+	for i, node := range network.Nodes {
+		blocks := node.Storage.From(consensus.BlockID{Height: &genesis}, 10)
+		if len(blocks) != 7 {
+			t.Fatalf("node %d stores %d blocks, want 7", i, len(blocks))
+		}
+
+		for j := 1; j < len(blocks); j++ {
+			if blocks[j].Header.Previous != blocks[j-1].Hash() {
+				t.Errorf("node %d block %d is not linked to the previous one", i, j)
+			}
+		}
+	}
+}
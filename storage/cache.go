@@ -0,0 +1,85 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"container/list"
+	"github.com/dblokhin/gringo/consensus"
+	"sync"
+)
+
+// blockCacheSize is the number of recently used blocks kept in memory
+const blockCacheSize = 1024
+
+// blockCache is LRU cache of blocks by hash
+type blockCache struct {
+	sync.Mutex
+
+	size  int
+	order *list.List
+	items map[consensus.Hash]*list.Element
+}
+
+// newBlockCache returns cache holding up to size blocks, all the blocks if
+// size isn't positive
+func newBlockCache(size int) *blockCache {
+	return &blockCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[consensus.Hash]*list.Element),
+	}
+}
+
+// get returns cached block by hash or nil
+func (c *blockCache) get(hash consensus.Hash) *consensus.Block {
+	c.Lock()
+	defer c.Unlock()
+
+	elem, ok := c.items[hash]
+	if !ok {
+		return nil
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*consensus.Block)
+}
+
+// put adds block to the cache evicting the least recently used one
+func (c *blockCache) put(block *consensus.Block) {
+	c.Lock()
+	defer c.Unlock()
+
+	key := block.Hash()
+	if elem, ok := c.items[key]; ok {
+		elem.Value = block
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(block)
+
+	if c.size > 0 && c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.items, last.Value.(*consensus.Block).Hash())
+	}
+}
+
+// purge drops all the cached blocks
+func (c *blockCache) purge() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.order.Init()
+	c.items = make(map[consensus.Hash]*list.Element)
+}
+
+// len returns number of cached blocks
+func (c *blockCache) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,216 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"sync"
+)
+
+// MemStorage is the in-memory storage backend, used by tests & simnet
+type MemStorage struct {
+	sync.RWMutex
+
+	// blocks by hash
+	blocks map[consensus.Hash]*consensus.Block
+	// chain is the blocks of the main chain by height
+	chain []*consensus.Block
+	// kernels is the blocks by kernel excess
+	kernels map[string][]*consensus.Block
+}
+
+// NewMemStorage returns empty in-memory storage
+func NewMemStorage() *MemStorage {
+	return &MemStorage{
+		blocks:  make(map[consensus.Hash]*consensus.Block),
+		kernels: make(map[string][]*consensus.Block),
+	}
+}
+
+// AddBlock adds block on top of its parent, the main chain blocks above the
+// parent are replaced
+func (s *MemStorage) AddBlock(block *consensus.Block) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.blocks[block.Hash()] = block
+	for i := range block.Kernels {
+		excess := string(block.Kernels[i].Excess.Bytes())
+		s.kernels[excess] = append(s.kernels[excess], block)
+	}
+
+	height := int(block.Header.Height)
+	if height > len(s.chain) {
+		// the gap below is unknown, the block starts the chain
+		s.chain = s.chain[:0]
+		for i := 0; i < height; i++ {
+			s.chain = append(s.chain, nil)
+		}
+	}
+
+	s.chain = append(s.chain[:height], block)
+}
+
+// DelBlock deletes blocks from id and all of child
+func (s *MemStorage) DelBlock(id consensus.BlockID) {
+	s.Lock()
+	defer s.Unlock()
+
+	block := s.get(id)
+	if block == nil {
+		return
+	}
+
+	s.del(block)
+
+	height := int(block.Header.Height)
+	if height < len(s.chain) && s.chain[height] == block {
+		for _, child := range s.chain[height+1:] {
+			if child != nil {
+				s.del(child)
+			}
+		}
+		s.chain = s.chain[:height]
+	}
+}
+
+// del deletes the block & its kernels from the index, must be called under
+// lock
+func (s *MemStorage) del(block *consensus.Block) {
+	delete(s.blocks, block.Hash())
+
+	for i := range block.Kernels {
+		excess := string(block.Kernels[i].Excess.Bytes())
+
+		blocks := s.kernels[excess][:0]
+		for _, b := range s.kernels[excess] {
+			if b != block {
+				blocks = append(blocks, b)
+			}
+		}
+
+		if len(blocks) == 0 {
+			delete(s.kernels, excess)
+		} else {
+			s.kernels[excess] = blocks
+		}
+	}
+}
+
+// GetBlock returns full block by hash or height (or both)
+// if not found return nil
+func (s *MemStorage) GetBlock(id consensus.BlockID) *consensus.Block {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.get(id)
+}
+
+// get returns block by id, must be called under lock
+func (s *MemStorage) get(id consensus.BlockID) *consensus.Block {
+	if !id.Hash.IsZero() {
+		block := s.blocks[id.Hash]
+		if block != nil && id.Height != nil && block.Header.Height != *id.Height {
+			return nil
+		}
+
+		return block
+	}
+
+	if id.Height != nil && *id.Height < uint64(len(s.chain)) {
+		return s.chain[*id.Height]
+	}
+
+	return nil
+}
+
+// GetLastBlock returns head of blockchain
+func (s *MemStorage) GetLastBlock() *consensus.Block {
+	s.RLock()
+	defer s.RUnlock()
+
+	if len(s.chain) == 0 {
+		return nil
+	}
+
+	return s.chain[len(s.chain)-1]
+}
+
+// From returns up to limit blocks of the main chain starting from id
+func (s *MemStorage) From(id consensus.BlockID, limit int) consensus.BlockList {
+	s.RLock()
+	defer s.RUnlock()
+
+	block := s.get(id)
+	if block == nil {
+		return nil
+	}
+
+	var list consensus.BlockList
+	for _, b := range s.chain[block.Header.Height:] {
+		if len(list) == limit {
+			break
+		}
+
+		if b != nil {
+			list = append(list, *b)
+		}
+	}
+
+	return list
+}
+
+// GetUnspentOutput returns id of the main chain block with the unspent
+// output by commitment, if not found returns nil
+func (s *MemStorage) GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID {
+	s.RLock()
+	defer s.RUnlock()
+
+	// the spending input is above the output
+	for i := len(s.chain) - 1; i >= 0; i-- {
+		block := s.chain[i]
+		if block == nil {
+			continue
+		}
+
+		for _, input := range block.Inputs {
+			if bytes.Equal(input.Commit, commit) {
+				return nil
+			}
+		}
+
+		for _, output := range block.Outputs {
+			if bytes.Equal(output.Commit.Bytes(), commit) {
+				height := block.Header.Height
+				return &consensus.BlockID{Hash: block.Hash(), Height: &height}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetKernel returns id of the main chain block with the kernel by excess
+// within the height range, if not found returns nil
+func (s *MemStorage) GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, block := range s.kernels[string(excess)] {
+		height := block.Header.Height
+		if height < minHeight || height > maxHeight {
+			continue
+		}
+
+		// the blocks of the forks are indexed too
+		if height < uint64(len(s.chain)) && s.chain[height] == block {
+			return &consensus.BlockID{Hash: block.Hash(), Height: &height}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/dblokhin/gringo/testutil"
+	"math/big"
+	"testing"
+)
+
+// child returns the next block after parent with the kernel of the excess k,
+// nonce makes the siblings different
+func child(parent *consensus.Block, nonce uint32, k int64) *consensus.Block {
+	header := parent.Header
+	header.Height++
+	header.Previous = parent.Hash()
+	header.POW.Nonces = append([]uint32(nil), parent.Header.POW.Nonces...)
+	header.POW.Nonces[0] = nonce
+
+	excess := secp256k1zkp.CommitValue(big.NewInt(k), big.NewInt(0))
+	return &consensus.Block{Header: header, Kernels: consensus.TxKernelList{{Excess: *excess}}}
+}
+
+func TestGetUnspentOutput(t *testing.T) {
+	s := NewMemStorage()
+	genesis := chain.Testnet4
+	s.AddBlock(&genesis)
+
+	g := testutil.New(&genesis, 1)
+	blocks, err := g.Chain(&genesis, 2, testutil.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend, err := g.Block(blocks[1], testutil.Options{Spends: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range append(blocks, spend) {
+		s.AddBlock(block)
+	}
+
+	spent := g.Unspent(blocks[1])[0]
+	if id := s.GetUnspentOutput(spent.Output.Commit.Bytes()); id != nil {
+		t.Errorf("spent output was found in %s", id.Hash)
+	}
+
+	for _, coin := range g.Unspent(spend) {
+		id := s.GetUnspentOutput(coin.Output.Commit.Bytes())
+		if id == nil || *id.Height != coin.Height {
+			t.Errorf("unspent output of the block %d was found in %v", coin.Height, id)
+		}
+	}
+}
+
+func TestGetKernel(t *testing.T) {
+	s := NewMemStorage()
+	genesis := chain.Testnet4
+	s.AddBlock(&genesis)
+
+	block := child(&genesis, 1, 1)
+	s.AddBlock(block)
+
+	excess := block.Kernels[0].Excess.Bytes()
+	if id := s.GetKernel(excess, 0, 10); id == nil || id.Hash != block.Hash() {
+		t.Errorf("kernel block was %v, want %s", id, block.Hash())
+	}
+
+	if id := s.GetKernel(excess, 2, 10); id != nil {
+		t.Errorf("kernel out of the height range was found at %d", *id.Height)
+	}
+
+	// the fork block replaces the block in the main chain
+	fork := child(&genesis, 2, 2)
+	s.AddBlock(fork)
+	if id := s.GetKernel(excess, 0, 10); id != nil {
+		t.Errorf("kernel of the fork was found in %s", id.Hash)
+	}
+
+	s.DelBlock(consensus.BlockID{Hash: fork.Hash()})
+	if id := s.GetKernel(fork.Kernels[0].Excess.Bytes(), 0, 10); id != nil || len(s.kernels[string(fork.Kernels[0].Excess.Bytes())]) != 0 {
+		t.Errorf("kernel of the deleted block was found in %v", id)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Metrics is a prometheus collector of the storage backend statistics
+type Metrics struct {
+	// latency of storage operations by op name
+	latency *prometheus.HistogramVec
+
+	// number of blocks returned by the range queries
+	batchSize prometheus.Histogram
+
+	// block cache requests by result (hit or miss) and the cache size
+	cacheRequests *prometheus.CounterVec
+	cacheSize     prometheus.GaugeFunc
+
+	// on-disk size of the backend and the number of open cursors
+	diskSize    prometheus.GaugeFunc
+	openCursors prometheus.GaugeFunc
+}
+
+// NewMetrics returns storage metrics for backend. cacheSize, diskSize and
+// openCursors are sampled on every collect.
+func NewMetrics(backend string, cacheSize, diskSize, openCursors func() float64) *Metrics {
+	labels := prometheus.Labels{"backend": backend}
+
+	return &Metrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "operation_duration_seconds",
+			Help:        "Latency of the storage operations.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"op"}),
+
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "batch_size_blocks",
+			Help:        "Number of blocks returned by the range queries.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "block_cache_requests_total",
+			Help:        "Block cache requests by result, hit rate is hit / (hit + miss).",
+			ConstLabels: labels,
+		}, []string{"result"}),
+
+		cacheSize: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "block_cache_blocks",
+			Help:        "Number of blocks in the block cache.",
+			ConstLabels: labels,
+		}, cacheSize),
+
+		diskSize: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "disk_size_bytes",
+			Help:        "On-disk size of the storage.",
+			ConstLabels: labels,
+		}, diskSize),
+
+		openCursors: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "gringo",
+			Subsystem:   "storage",
+			Name:        "open_cursors",
+			Help:        "Number of currently open cursors (connections in use).",
+			ConstLabels: labels,
+		}, openCursors),
+	}
+}
+
+// Describe implements prometheus.Collector interface
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.latency.Describe(ch)
+	m.batchSize.Describe(ch)
+	m.cacheRequests.Describe(ch)
+	m.cacheSize.Describe(ch)
+	m.diskSize.Describe(ch)
+	m.openCursors.Describe(ch)
+}
+
+// Collect implements prometheus.Collector interface
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.latency.Collect(ch)
+	m.batchSize.Collect(ch)
+	m.cacheRequests.Collect(ch)
+	m.cacheSize.Collect(ch)
+	m.diskSize.Collect(ch)
+	m.openCursors.Collect(ch)
+}
+
+// observe records latency of op started at start, used with defer
+func (m *Metrics) observe(op string, start time.Time) {
+	m.latency.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// observeBatch records size of the range query result
+func (m *Metrics) observeBatch(n int) {
+	m.batchSize.Observe(float64(n))
+}
+
+// observeCache records block cache request result
+func (m *Metrics) observeCache(hit bool) {
+	if hit {
+		m.cacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		m.cacheRequests.WithLabelValues("miss").Inc()
+	}
+}
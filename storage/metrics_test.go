@@ -0,0 +1,110 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"github.com/dblokhin/gringo/chain"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+)
+
+func TestSqlStorageMetrics(t *testing.T) {
+	s := NewSqlStorage(nil)
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(s.Metrics()); err != nil {
+		t.Fatalf("failed to register storage metrics: %v", err)
+	}
+
+	height := uint64(1)
+	s.AddBlock(&chain.Testnet3)
+	s.GetBlock(consensus.BlockID{Hash: chain.Testnet3.Hash()})
+	s.GetBlock(consensus.BlockID{Hash: chain.Testnet4.Hash()})
+	s.GetBlock(consensus.BlockID{Height: &height})
+	s.From(consensus.BlockID{Height: &height}, 10)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, family := range families {
+		found[family.GetName()] = true
+
+		if family.GetName() == "gringo_storage_block_cache_requests_total" {
+			for _, metric := range family.GetMetric() {
+				if metric.GetCounter().GetValue() != 1 {
+					t.Errorf("%v cache requests counter was %v, want 1", metric.GetLabel(), metric.GetCounter().GetValue())
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{
+		"gringo_storage_operation_duration_seconds",
+		"gringo_storage_batch_size_blocks",
+		"gringo_storage_block_cache_requests_total",
+		"gringo_storage_block_cache_blocks",
+		"gringo_storage_disk_size_bytes",
+		"gringo_storage_open_cursors",
+	} {
+		if !found[name] {
+			t.Errorf("metric %s is not exposed", name)
+		}
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	c := newBlockCache(1)
+
+	c.put(&chain.Testnet3)
+	if c.get(chain.Testnet3.Hash()) != &chain.Testnet3 {
+		t.Errorf("cached block is not found")
+	}
+
+	// evicts testnet3
+	c.put(&chain.Testnet4)
+	if c.get(chain.Testnet3.Hash()) != nil {
+		t.Errorf("least recently used block is not evicted")
+	}
+
+	if c.len() != 1 {
+		t.Errorf("cache size was %d, want 1", c.len())
+	}
+
+	c.purge()
+	if c.get(chain.Testnet4.Hash()) != nil || c.len() != 0 {
+		t.Errorf("cache is not purged")
+	}
+}
+
+func TestBlockCacheArchive(t *testing.T) {
+	s := NewSqlStorage(nil)
+	s.SetArchive()
+
+	// more blocks than the cache of the recently used ones holds
+	var blocks []*consensus.Block
+	for i := 0; i < blockCacheSize+10; i++ {
+		block := chain.Testnet4
+		block.Header.POW.Nonces = append([]uint32{uint32(i)}, block.Header.POW.Nonces[1:]...)
+
+		blocks = append(blocks, &block)
+		s.AddBlock(&block)
+	}
+
+	for _, block := range blocks {
+		if s.GetBlock(consensus.BlockID{Hash: block.Hash()}) != block {
+			t.Fatalf("block %s is not kept", block.Hash())
+		}
+	}
+}
+
+func TestPingWithoutDatabase(t *testing.T) {
+	if err := NewSqlStorage(nil).Ping(); err == nil {
+		t.Errorf("storage without database is reachable")
+	}
+}
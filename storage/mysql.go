@@ -8,16 +8,25 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/logging"
+	"github.com/dblokhin/gringo/secp256k1zkp"
 	_ "github.com/go-sql-driver/mysql"
 	"sync"
+	"time"
 )
 
 // NewSqlStorage returns blockchain Storage defined in /src/chain
 func NewSqlStorage(db *sql.DB) *SqlStorage {
-	return &SqlStorage{
-		db: db,
+	s := &SqlStorage{
+		db:    db,
+		cache: newBlockCache(blockCacheSize),
+		log:   logging.Default(logging.Storage),
 	}
+	s.metrics = NewMetrics("mysql", s.cacheSize, s.diskSize, s.openCursors)
+
+	return s
 }
 
 // SqlStorage sql storage backend for blockchain
@@ -26,30 +35,145 @@ type SqlStorage struct {
 
 	// database instance
 	db *sql.DB
+
+	// recently used blocks
+	cache *blockCache
+
+	// backend statistics
+	metrics *Metrics
+
+	log logging.Logger
+}
+
+// SetLogger replaces the storage logger, logging.Nop disables the logging
+func (s *SqlStorage) SetLogger(logger logging.Logger) {
+	s.log = logger
+}
+
+// SetArchive keeps all the blocks instead of the recently used ones, the
+// archive node serves the whole history. Must be called before the blocks
+// are added
+func (s *SqlStorage) SetArchive() {
+	s.cache = newBlockCache(0)
+}
+
+// Metrics returns the prometheus collector of the storage statistics
+func (s *SqlStorage) Metrics() *Metrics {
+	return s.metrics
 }
 
 // AddBlock adds block to storage
 func (s *SqlStorage) AddBlock(block *consensus.Block) {
+	defer s.metrics.observe("add_block", time.Now())
 
+	s.cache.put(block)
 }
 
 // DelBlock deletes blocks from id and all of child
 func (s *SqlStorage) DelBlock(id consensus.BlockID) {
+	defer s.metrics.observe("del_block", time.Now())
 
+	// the children are deleted too, so drop the whole cache
+	s.cache.purge()
 }
 
 // GetBlock returns full block by hash or height (or both)
 // if not found return nil
 func (s *SqlStorage) GetBlock(id consensus.BlockID) *consensus.Block {
-	return nil
+	defer s.metrics.observe("get_block", time.Now())
+
+	if !id.Hash.IsZero() {
+		block := s.cache.get(id.Hash)
+		s.metrics.observeCache(block != nil)
+
+		if block != nil {
+			return block
+		}
+	}
+
+	var block *consensus.Block
+
+	return block
 }
 
 // Returns list of blocks from id
 func (s *SqlStorage) From(id consensus.BlockID, limit int) consensus.BlockList {
-	return nil
+	defer s.metrics.observe("from", time.Now())
+
+	var list consensus.BlockList
+
+	s.metrics.observeBatch(len(list))
+	return list
+}
+
+// GetUnspentOutput returns id of the block with the unspent output by
+// commitment, if not found returns nil
+func (s *SqlStorage) GetUnspentOutput(commit secp256k1zkp.Commitment) *consensus.BlockID {
+	defer s.metrics.observe("get_unspent_output", time.Now())
+
+	var id *consensus.BlockID
+
+	return id
+}
+
+// GetKernel returns id of the block with the kernel by excess within the
+// height range, if not found returns nil
+func (s *SqlStorage) GetKernel(excess secp256k1zkp.Commitment, minHeight, maxHeight uint64) *consensus.BlockID {
+	defer s.metrics.observe("get_kernel", time.Now())
+
+	var id *consensus.BlockID
+
+	return id
 }
 
 // GetLastBlock returns head of blockchain
 func (s *SqlStorage) GetLastBlock() *consensus.Block {
+	defer s.metrics.observe("get_last_block", time.Now())
+
 	return nil
 }
+
+// Ping returns error if the database is unreachable
+func (s *SqlStorage) Ping() error {
+	if s.db == nil {
+		return errors.New("database is not configured")
+	}
+
+	return s.db.Ping()
+}
+
+// cacheSize returns number of cached blocks
+func (s *SqlStorage) cacheSize() float64 {
+	return float64(s.cache.len())
+}
+
+// DiskSize returns the size of data & indexes of the current database in
+// bytes, 0 without the database
+func (s *SqlStorage) DiskSize() uint64 {
+	return uint64(s.diskSize())
+}
+
+// diskSize returns size of data & indexes of the current database
+func (s *SqlStorage) diskSize() float64 {
+	if s.db == nil {
+		return 0
+	}
+
+	var size sql.NullFloat64
+	row := s.db.QueryRow("SELECT SUM(data_length + index_length) FROM information_schema.tables WHERE table_schema = DATABASE()")
+	if err := row.Scan(&size); err != nil {
+		s.log.Error(err)
+		return 0
+	}
+
+	return size.Float64
+}
+
+// openCursors returns number of db connections in use
+func (s *SqlStorage) openCursors() float64 {
+	if s.db == nil {
+		return 0
+	}
+
+	return float64(s.db.Stats().InUse)
+}
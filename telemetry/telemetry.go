@@ -0,0 +1,122 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package telemetry reports the anonymized node stats to the monitoring
+// endpoint of the network health, the nodes opt in to it
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dblokhin/gringo/logging"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// reportTimeout is the deadline of the report request
+const reportTimeout = 30 * time.Second
+
+// Report is the anonymized node stats: no addrs, keys or node ids are sent
+type Report struct {
+	Version   string `json:"version"`
+	UserAgent string `json:"user_agent"`
+	Network   string `json:"network"`
+	Height    uint64 `json:"height"`
+	Peers     int    `json:"peers"`
+	Syncing   bool   `json:"syncing"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// Uptime is the node uptime in seconds, rounded to the hours
+	Uptime int64 `json:"uptime"`
+}
+
+// Stats returns the stats of the report, the os & the arch are filled by
+// the reporter
+type Stats func() Report
+
+// Reporter posts the report of the node stats to the endpoint url as json
+// every interval
+type Reporter struct {
+	url      string
+	interval time.Duration
+	stats    Stats
+	started  time.Time
+
+	client *http.Client
+	log    logging.Logger
+}
+
+// NewReporter returns the reporter of the stats to url every interval
+func NewReporter(url string, interval time.Duration, stats Stats) *Reporter {
+	return &Reporter{
+		url:      url,
+		interval: interval,
+		stats:    stats,
+		started:  time.Now(),
+		client:   &http.Client{Timeout: reportTimeout},
+		log:      logging.Default("telemetry"),
+	}
+}
+
+// SetLogger sets the logger of the failed reports
+func (r *Reporter) SetLogger(logger logging.Logger) {
+	r.log = logger
+}
+
+// Report returns the current report
+func (r *Reporter) Report() Report {
+	report := r.stats()
+	report.OS = runtime.GOOS
+	report.Arch = runtime.GOARCH
+	report.Uptime = int64(time.Since(r.started).Truncate(time.Hour) / time.Second)
+
+	return report
+}
+
+// Send posts the current report to the endpoint
+func (r *Reporter) Send(ctx context.Context) error {
+	body, err := json.Marshal(r.Report())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("telemetry endpoint responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Run sends the reports every interval until ctx is done, the first one
+// after the first interval so the report is of the started node
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Send(ctx); err != nil {
+				r.log.Warnf("telemetry report failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestReporter(t *testing.T) {
+	reports := make(chan Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil || r.Method != http.MethodPost {
+			t.Errorf("invalid report %s: %v", r.Method, err)
+		}
+		select {
+		case reports <- report:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	stats := func() Report {
+		return Report{Version: "1.0.0", Network: "testnet4", Height: 42, Peers: 8}
+	}
+	r := NewReporter(server.URL, time.Millisecond, stats)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go r.Run(ctx)
+	report := <-reports
+	cancel()
+
+	if report.Height != 42 || report.Peers != 8 || report.Network != "testnet4" ||
+		report.OS != runtime.GOOS || report.Arch != runtime.GOARCH || report.Uptime != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	if err := NewReporter(failing.URL, time.Hour, stats).Send(context.Background()); err == nil {
+		t.Errorf("failed report returned no error")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"bytes"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The Random functions return the random values of the wire types for the
+// property tests: the values are valid as decoded, so every field is read
+// back from the serialization. The lists are short, up to maxRandomItems.
+
+// maxRandomItems is the max count of the items of the random lists
+const maxRandomItems = 4
+
+var (
+	// rangeProofs is the pool of the valid range proofs of the random
+	// outputs, created once
+	rangeProofsOnce sync.Once
+	rangeProofs     []bulletproofs.BulletProof
+)
+
+// RandomHash returns the random hash
+func RandomHash(r *rand.Rand) consensus.Hash {
+	var hash consensus.Hash
+	r.Read(hash[:])
+
+	return hash
+}
+
+// RandomKey returns the random secret key
+func RandomKey(r *rand.Rand) *big.Int {
+	var data [32]byte
+	r.Read(data[:])
+
+	key := new(big.Int).SetBytes(data[:])
+	key.Mod(key, btcec.S256().N)
+	if key.Sign() == 0 {
+		key.SetInt64(1)
+	}
+
+	return key
+}
+
+// RandomPoint returns the point of the random key
+func RandomPoint(r *rand.Rand) *bulletproofs.Point {
+	return bulletproofs.ScalarMulPoint(&secp256k1zkp.G, RandomKey(r))
+}
+
+// RandomProof returns the random proof of work of the cuckoo graph size
+// up to 32 edge bits, the nonces fit the size
+func RandomProof(r *rand.Rand) consensus.Proof {
+	proof := consensus.Proof{
+		EdgeBits: uint8(r.Intn(32) + 1),
+		Nonces:   make([]uint32, consensus.ProofSize),
+	}
+
+	mask := uint32(1<<proof.EdgeBits - 1)
+	for i := range proof.Nonces {
+		proof.Nonces[i] = r.Uint32() & mask
+	}
+
+	return proof
+}
+
+// RandomHeader returns the random header of the serialized fields & the
+// difficulty of the proof, the total kernel sum is not serialized
+func RandomHeader(r *rand.Rand) consensus.BlockHeader {
+	header := consensus.BlockHeader{
+		Version:           uint16(r.Uint32()),
+		Height:            r.Uint64(),
+		Previous:          RandomHash(r),
+		PreviousRoot:      RandomHash(r),
+		Timestamp:         time.Unix(r.Int63n(1<<40), 0).UTC(),
+		UTXORoot:          RandomHash(r),
+		RangeProofRoot:    RandomHash(r),
+		KernelRoot:        RandomHash(r),
+		Nonce:             r.Uint64(),
+		TotalKernelOffset: RandomHash(r),
+		OutputMmrSize:     r.Uint64(),
+		KernelMmrSize:     r.Uint64(),
+		POW:               RandomProof(r),
+		TotalDifficulty:   consensus.Difficulty(r.Uint64()),
+		ScalingDifficulty: r.Uint32(),
+	}
+	header.Difficulty = header.POW.ToDifficulty()
+
+	return header
+}
+
+// RandomInput returns the random input
+func RandomInput(r *rand.Rand) consensus.Input {
+	return consensus.Input{
+		Features: consensus.OutputFeatures(r.Intn(2)),
+		Commit:   RandomPoint(r).Bytes(),
+	}
+}
+
+// RandomOutput returns the random output, the range proof is of the other
+// commitment of the pool of the proofs
+func RandomOutput(r *rand.Rand) consensus.Output {
+	rangeProofsOnce.Do(func() {
+		proverOnce.Do(func() { prover = bulletproofs.NewProver(64) })
+
+		for i := int64(1); i <= maxRandomItems; i++ {
+			blind, value := big.NewInt(i), big.NewInt(i*1000)
+			commit := secp256k1zkp.CommitValue(blind, value)
+			proof, err := prover.CreateRangeProof(commit, value, blind, secp256k1zkp.ComputeHash(blind.Bytes()), [16]byte{})
+			if err != nil {
+				panic(err)
+			}
+
+			// the proofs are read back to be of the decoded fields only
+			var read bulletproofs.BulletProof
+			if err := read.Read(bytes.NewReader(proof.Bytes())); err != nil {
+				panic(err)
+			}
+			rangeProofs = append(rangeProofs, read)
+		}
+	})
+
+	return consensus.Output{
+		Features:   consensus.OutputFeatures(r.Intn(2)),
+		Commit:     RandomPoint(r),
+		RangeProof: rangeProofs[r.Intn(len(rangeProofs))],
+	}
+}
+
+// RandomKernel returns the random kernel, the signature isn't valid
+func RandomKernel(r *rand.Rand) consensus.TxKernel {
+	kernel := consensus.TxKernel{
+		Features:   consensus.KernelFeatures(r.Intn(2)),
+		Fee:        r.Uint64(),
+		LockHeight: r.Uint64(),
+		Excess:     *RandomPoint(r),
+	}
+	r.Read(kernel.ExcessSig[:])
+
+	return kernel
+}
+
+// randomBody returns the random sorted inputs, outputs & kernels, the empty
+// lists are not nil as read
+func randomBody(r *rand.Rand) (consensus.InputList, consensus.OutputList, consensus.TxKernelList) {
+	inputs := make(consensus.InputList, r.Intn(maxRandomItems+1))
+	for i := range inputs {
+		inputs[i] = RandomInput(r)
+	}
+
+	outputs := make(consensus.OutputList, r.Intn(maxRandomItems+1))
+	for i := range outputs {
+		outputs[i] = RandomOutput(r)
+	}
+
+	kernels := make(consensus.TxKernelList, r.Intn(maxRandomItems+1))
+	for i := range kernels {
+		kernels[i] = RandomKernel(r)
+	}
+
+	sort.Sort(inputs)
+	sort.Sort(outputs)
+	sort.Sort(kernels)
+
+	return inputs, outputs, kernels
+}
+
+// RandomTransaction returns the random transaction of the sorted lists, the
+// kernel sums aren't valid
+func RandomTransaction(r *rand.Rand) *consensus.Transaction {
+	tx := new(consensus.Transaction)
+	r.Read(tx.KernelOffset[:])
+	tx.Inputs, tx.Outputs, tx.Kernels = randomBody(r)
+
+	return tx
+}
+
+// RandomBlock returns the random block of the sorted lists, the proof of
+// work isn't valid
+func RandomBlock(r *rand.Rand) *consensus.Block {
+	block := &consensus.Block{Header: RandomHeader(r)}
+	block.Inputs, block.Outputs, block.Kernels = randomBody(r)
+
+	return block
+}
+
+// RandomShortIDs returns the random sorted short ids
+func RandomShortIDs(r *rand.Rand) consensus.ShortIDList {
+	ids := make(consensus.ShortIDList, r.Intn(maxRandomItems+1))
+	for i := range ids {
+		ids[i] = make(consensus.ShortID, consensus.ShortIDSize)
+		r.Read(ids[i])
+	}
+	sort.Sort(ids)
+
+	return ids
+}
+
+// RandomHashes returns up to n random hashes, the empty list is not nil
+func RandomHashes(r *rand.Rand, n int) []consensus.Hash {
+	hashes := make([]consensus.Hash, r.Intn(n+1))
+	for i := range hashes {
+		hashes[i] = RandomHash(r)
+	}
+
+	return hashes
+}
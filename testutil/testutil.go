@@ -0,0 +1,332 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package testutil generates the deterministic chains of the valid blocks for
+// the tests & the benchmarks: the generators of the same seed return the same
+// blocks byte for byte. The blocks pay the coinbases to the keys derived from
+// the seed, spend the outputs of the chain on request & fork at any block.
+//
+// The proof of work is not a cuckoo cycle, the nonces are searched for the
+// proof hash of the minimum difficulty only. So the chain must skip the proof
+// of work validation: Validate checks the block version & the body rules. The
+// header commits to the header MMR, the other roots are of the genesis.
+package testutil
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dblokhin/gringo/consensus"
+	"github.com/dblokhin/gringo/secp256k1zkp"
+	"github.com/yoss22/bulletproofs"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+var (
+	// prover is created once, the generators are expensive
+	proverOnce sync.Once
+	prover     *bulletproofs.Prover
+)
+
+// Coin is the generated output & its keys
+type Coin struct {
+	Output consensus.Output
+	// Blind is the blinding factor of the commitment
+	Blind *big.Int
+	Value uint64
+	// Height is the height of the block of the output
+	Height uint64
+}
+
+// Input returns the input spending the coin
+func (c *Coin) Input() consensus.Input {
+	return consensus.Input{Features: c.Output.Features, Commit: c.Output.Commit.Bytes()}
+}
+
+// Options are the contents of the generated block
+type Options struct {
+	// Spends is the count of the oldest unspent outputs of the chain spent by
+	// the block transaction, the change output is of the inputs value less
+	// the fee
+	Spends int
+	Fee    uint64
+}
+
+// state is the chain of the generated block: the header MMR including the
+// block & the unspent outputs in the creation order
+type state struct {
+	mmr     consensus.MMR
+	unspent []Coin
+	// children is the count of the blocks generated on top of the block
+	children uint32
+}
+
+// Generator generates the blocks on top of the genesis & the generated
+// blocks, it isn't safe for the concurrent use
+type Generator struct {
+	seed   [8]byte
+	states map[consensus.Hash]*state
+}
+
+// New returns the generator of the chains of the genesis, the seed derives
+// the keys
+func New(genesis *consensus.Block, seed uint64) *Generator {
+	g := &Generator{states: make(map[consensus.Hash]*state)}
+	binary.BigEndian.PutUint64(g.seed[:], seed)
+
+	// the genesis outputs are not spendable, their keys are unknown
+	genesisState := new(state)
+	genesisState.mmr.Append(genesis.Hash().Bytes())
+	g.states[genesis.Hash()] = genesisState
+
+	return g
+}
+
+// Chain returns n blocks on top of parent, the blocks of every call on the
+// same parent fork the chain
+func (g *Generator) Chain(parent *consensus.Block, n int, opts Options) ([]*consensus.Block, error) {
+	blocks := make([]*consensus.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := g.Block(parent, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		blocks = append(blocks, block)
+		parent = block
+	}
+
+	return blocks, nil
+}
+
+// Block returns the next block after parent, the genesis or the generated
+// block: the coinbase of the reward & the fee and the transaction of
+// opts.Spends outputs. The siblings differ by the proof of work
+func (g *Generator) Block(parent *consensus.Block, opts Options) (*consensus.Block, error) {
+	parentState, ok := g.states[parent.Hash()]
+	if !ok {
+		return nil, fmt.Errorf("block %s isn't generated", parent.Hash())
+	}
+
+	if opts.Spends > len(parentState.unspent) {
+		return nil, fmt.Errorf("%d outputs to spend, %d unspent", opts.Spends, len(parentState.unspent))
+	}
+
+	sibling := parentState.children
+	parentState.children++
+
+	block := &consensus.Block{Header: nextHeader(&parent.Header, parentState.mmr.Root(), sibling)}
+	height := block.Header.Height
+
+	unspent := append([]Coin(nil), parentState.unspent[opts.Spends:]...)
+
+	var fee uint64
+	if opts.Spends > 0 {
+		tx, change, err := g.spend(parentState.unspent[:opts.Spends], opts.Fee)
+		if err != nil {
+			return nil, err
+		}
+
+		block.Inputs = tx.Inputs
+		block.Outputs = tx.Outputs
+		block.Kernels = tx.Kernels
+		fee = opts.Fee
+
+		change.Height = height
+		unspent = append(unspent, *change)
+	}
+
+	coinbase, err := g.coinbase(block.Hash(), height, fee)
+	if err != nil {
+		return nil, err
+	}
+	unspent = append(unspent, *coinbase)
+
+	block.Outputs = append(block.Outputs, coinbase.Output)
+	block.Kernels = append(block.Kernels, g.kernel(consensus.CoinbaseKernel, 0, coinbase.Blind))
+	sort.Sort(block.Inputs)
+	sort.Sort(block.Outputs)
+	sort.Sort(block.Kernels)
+
+	blockState := &state{
+		mmr:     parentState.mmr.Copy(),
+		unspent: unspent,
+	}
+	blockState.mmr.Append(block.Hash().Bytes())
+	g.states[block.Hash()] = blockState
+
+	return block, nil
+}
+
+// nextHeader returns the header after parent committing to the header MMR
+// root, the proof nonces are of the height & the sibling index
+func nextHeader(parent *consensus.BlockHeader, root consensus.Hash, sibling uint32) consensus.BlockHeader {
+	header := *parent
+	header.Height = parent.Height + 1
+	header.Previous = parent.Hash()
+	header.PreviousRoot = root
+	header.Timestamp = parent.Timestamp.Add(consensus.TestnetParams.BlockTime)
+	header.TotalDifficulty = parent.TotalDifficulty.Add(parent.POW.ToDifficulty())
+
+	header.POW.Nonces = append([]uint32(nil), parent.POW.Nonces...)
+	header.POW.Nonces[0] = uint32(header.Height)
+	header.POW.Nonces[1] = sibling
+	header.POW.Nonces[2] = 0
+	for header.POW.ToDifficulty() < consensus.MinimumDifficulty {
+		header.POW.Nonces[2]++
+	}
+	header.Difficulty = header.POW.ToDifficulty()
+	header.Nonce = uint64(header.POW.Nonces[2])
+
+	return header
+}
+
+// Unspent returns the unspent outputs of the chain of the generated tip in
+// the creation order, nil for the unknown tip
+func (g *Generator) Unspent(tip *consensus.Block) []Coin {
+	tipState, ok := g.states[tip.Hash()]
+	if !ok {
+		return nil
+	}
+
+	return append([]Coin(nil), tipState.unspent...)
+}
+
+// Transaction returns the transaction spending the coins to the change
+// output of their value less the fee, as the pool transactions
+func (g *Generator) Transaction(coins []Coin, fee uint64) (*consensus.Transaction, error) {
+	tx, _, err := g.spend(coins, fee)
+	return tx, err
+}
+
+// spend returns the transaction of the coins & its change
+func (g *Generator) spend(coins []Coin, fee uint64) (*consensus.Transaction, *Coin, error) {
+	if len(coins) == 0 {
+		return nil, nil, fmt.Errorf("no coins to spend")
+	}
+
+	var value uint64
+	tx := new(consensus.Transaction)
+	inputs := make([][]byte, 0, len(coins))
+	excess := new(big.Int)
+	for i := range coins {
+		value += coins[i].Value
+		input := coins[i].Input()
+		tx.Inputs = append(tx.Inputs, input)
+		inputs = append(inputs, input.Commit)
+		excess.Sub(excess, coins[i].Blind)
+	}
+
+	if fee >= value {
+		return nil, nil, fmt.Errorf("fee %d exceeds the spent value %d", fee, value)
+	}
+
+	// the change of the same inputs is the same
+	change, err := g.output(consensus.DefaultOutput, value-fee, "change", inputs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the kernel excess is the change blind less the input blinds:
+	// change + fee*H - inputs = excess*G
+	excess.Add(excess, change.Blind)
+	excess.Mod(excess, btcec.S256().N)
+	if excess.Sign() == 0 {
+		return nil, nil, fmt.Errorf("zero kernel excess")
+	}
+
+	tx.Outputs = consensus.OutputList{change.Output}
+	tx.Kernels = consensus.TxKernelList{g.kernel(consensus.DefaultKernel, fee, excess)}
+	sort.Sort(tx.Inputs)
+
+	return tx, change, nil
+}
+
+// coinbase returns the coinbase output of the reward & fee of the block
+func (g *Generator) coinbase(block consensus.Hash, height, fee uint64) (*Coin, error) {
+	coin, err := g.output(consensus.CoinbaseOutput, consensus.Reward+fee, "coinbase", block[:])
+	if err != nil {
+		return nil, err
+	}
+	coin.Height = height
+
+	return coin, nil
+}
+
+// output returns the output of the value committed to the blind derived
+// from the label & the data, the range proof is of the blind nonce
+func (g *Generator) output(features consensus.OutputFeatures, value uint64, label string, data ...[]byte) (*Coin, error) {
+	proverOnce.Do(func() { prover = bulletproofs.NewProver(64) })
+
+	blind := g.key(label, data...)
+	v := new(big.Int).SetUint64(value)
+	commit := secp256k1zkp.CommitValue(blind, v)
+
+	nonce := secp256k1zkp.ComputeHash(blind.Bytes())
+	proof, err := prover.CreateRangeProof(commit, v, blind, nonce, [16]byte{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create range proof: %v", err)
+	}
+
+	return &Coin{
+		Output: consensus.Output{
+			Features:   features,
+			Commit:     commit,
+			RangeProof: proof,
+		},
+		Blind: blind,
+		Value: value,
+	}, nil
+}
+
+// kernel returns the kernel of the excess key signed by the nonce derived
+// from the key & the message
+func (g *Generator) kernel(features consensus.KernelFeatures, fee uint64, key *big.Int) consensus.TxKernel {
+	excess := bulletproofs.ScalarMulPoint(&secp256k1zkp.G, key)
+	msg := secp256k1zkp.ComputeMessage(fee, 0)
+	nonce := g.key("nonce", key.Bytes(), msg[:])
+
+	return consensus.TxKernel{
+		Features:  features,
+		Fee:       fee,
+		Excess:    *excess,
+		ExcessSig: secp256k1zkp.SignMessageNonce(*excess, *key, msg, nonce).Bytes(),
+	}
+}
+
+// key returns the secret key of the hash of the seed, the label & the data
+func (g *Generator) key(label string, data ...[]byte) *big.Int {
+	hash := secp256k1zkp.ComputeHash(append([][]byte{g.seed[:], []byte(label)}, data...)...)
+
+	key := new(big.Int).SetBytes(hash[:])
+	key.Mod(key, btcec.S256().N)
+	if key.Sign() == 0 {
+		key.SetInt64(1)
+	}
+
+	return key
+}
+
+// Validate checks the block version & the body rules of the generated block
+// skipping the proof of work, as the block validator of the chain
+func Validate(ctx context.Context, block *consensus.Block) error {
+	if err := ValidateHeader(&block.Header); err != nil {
+		return err
+	}
+
+	return block.ValidateBody(ctx)
+}
+
+// ValidateHeader checks the header version skipping the proof of work, as
+// the header validator of the chain
+func ValidateHeader(header *consensus.BlockHeader) error {
+	if !consensus.TestnetParams.ValidateBlockVersion(header.Height, header.Version) {
+		return fmt.Errorf("%w %d", consensus.ErrInvalidBlockVersion, header.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"github.com/dblokhin/gringo/consensus"
+	"testing"
+	"time"
+)
+
+// testGenesis is the genesis of the zero proof, as of the testnet 4
+var testGenesis = consensus.Block{
+	Header: consensus.BlockHeader{
+		Version:   1,
+		Timestamp: time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC),
+		POW: consensus.Proof{
+			EdgeBits: 29,
+			Nonces:   make([]uint32, consensus.ProofSize),
+		},
+		TotalKernelSum:    make([]byte, 33),
+		TotalDifficulty:   consensus.MinimumDifficulty,
+		ScalingDifficulty: 1,
+	},
+}
+
+// generate returns the blocks of the seed: 3 blocks, the 3rd spends 2
+// outputs
+func generate(t *testing.T, seed uint64) (*Generator, []*consensus.Block) {
+	g := New(&testGenesis, seed)
+
+	blocks, err := g.Chain(&testGenesis, 2, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := g.Block(blocks[1], Options{Spends: 2, Fee: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g, append(blocks, block)
+}
+
+func TestGeneratorDeterministic(t *testing.T) {
+	_, first := generate(t, 1)
+	_, second := generate(t, 1)
+	_, other := generate(t, 2)
+
+	for i := range first {
+		if !bytes.Equal(first[i].Bytes(), second[i].Bytes()) {
+			t.Errorf("block %d of the same seed differs", i)
+		}
+
+		if bytes.Equal(first[i].Bytes(), other[i].Bytes()) {
+			t.Errorf("block %d of the other seed is the same", i)
+		}
+	}
+}
+
+func TestGeneratorValid(t *testing.T) {
+	g, blocks := generate(t, 1)
+
+	parent := &testGenesis
+	for i, block := range blocks {
+		if err := Validate(context.Background(), block); err != nil {
+			t.Errorf("block %d is invalid: %v", i, err)
+		}
+
+		if block.Header.Previous != parent.Hash() || block.Header.Height != uint64(i+1) {
+			t.Errorf("block %d isn't linked to the parent", i)
+		}
+		parent = block
+	}
+
+	spend := blocks[2]
+	if len(spend.Inputs) != 2 || len(spend.Outputs) != 2 || len(spend.Kernels) != 2 {
+		t.Errorf("spending block has %d inputs, %d outputs & %d kernels", len(spend.Inputs), len(spend.Outputs), len(spend.Kernels))
+	}
+
+	// the coinbase of the block & the change of the spent coinbases
+	unspent := g.Unspent(spend)
+	if len(unspent) != 2 || unspent[0].Value != 2*consensus.Reward-1000 || unspent[1].Value != consensus.Reward+1000 {
+		t.Errorf("unspent outputs are %+v", unspent)
+	}
+
+	tx, err := g.Transaction(unspent, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Validate(context.Background()); err != nil {
+		t.Errorf("transaction is invalid: %v", err)
+	}
+}
+
+func TestGeneratorFork(t *testing.T) {
+	g, blocks := generate(t, 1)
+
+	fork, err := g.Chain(blocks[0], 3, Options{Spends: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fork[0].Hash() == blocks[1].Hash() || fork[0].Header.Previous != blocks[0].Hash() {
+		t.Error("fork block isn't the sibling")
+	}
+
+	if err := Validate(context.Background(), fork[2]); err != nil {
+		t.Errorf("fork block is invalid: %v", err)
+	}
+
+	if _, err := g.Block(&consensus.Block{}, Options{}); err == nil {
+		t.Error("block of the unknown parent generated")
+	}
+
+	if _, err := g.Block(blocks[0], Options{Spends: 2}); err == nil {
+		t.Error("block spending the missing outputs generated")
+	}
+}
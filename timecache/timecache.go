@@ -0,0 +1,116 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package timecache remembers the keys for the time to live: the seen &
+// rejected items, the own nonces & so on
+package timecache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache remembers the keys for the ttl, up to size keys: the oldest key is
+// evicted by the new one. The keys are any comparable values like the map
+// keys. Cache is safe for concurrent use
+type Cache struct {
+	sync.Mutex
+
+	ttl  time.Duration
+	size int
+
+	// order is the entries by the expiration, the soonest first
+	order *list.List
+	items map[interface{}]*list.Element
+
+	// now returns the current time, replaced by tests
+	now func() time.Time
+}
+
+// entry is the key & its expiration time
+type entry struct {
+	key     interface{}
+	expires time.Time
+}
+
+// New returns the cache remembering up to size keys for ttl, the cache of the
+// non positive size is not bounded
+func New(ttl time.Duration, size int) *Cache {
+	return &Cache{
+		ttl:   ttl,
+		size:  size,
+		order: list.New(),
+		items: make(map[interface{}]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Add remembers the key for the ttl from now, the known key is refreshed.
+// Returns true if the key wasn't remembered
+func (c *Cache) Add(key interface{}) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	now := c.now()
+	c.expire(now)
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*entry).expires = now.Add(c.ttl)
+		c.order.MoveToBack(elem)
+		return false
+	}
+
+	c.items[key] = c.order.PushBack(&entry{key: key, expires: now.Add(c.ttl)})
+
+	if c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Front())
+	}
+
+	return true
+}
+
+// Has returns true if the key is remembered & not expired
+func (c *Cache) Has(key interface{}) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	c.expire(c.now())
+
+	_, ok := c.items[key]
+	return ok
+}
+
+// Remove forgets the key
+func (c *Cache) Remove(key interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the count of the remembered keys
+func (c *Cache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	c.expire(c.now())
+	return c.order.Len()
+}
+
+// expire forgets the keys expired by now, must be called with the cache
+// locked
+func (c *Cache) expire(now time.Time) {
+	for elem := c.order.Front(); elem != nil && !now.Before(elem.Value.(*entry).expires); elem = c.order.Front() {
+		c.remove(elem)
+	}
+}
+
+// remove forgets the entry, must be called with the cache locked
+func (c *Cache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
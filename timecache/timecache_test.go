@@ -0,0 +1,95 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package timecache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCache returns the cache of the manual clock advanced by the returned
+// func
+func testCache(ttl time.Duration, size int) (*Cache, func(time.Duration)) {
+	now := time.Date(2018, 10, 17, 20, 0, 0, 0, time.UTC)
+
+	c := New(ttl, size)
+	c.now = func() time.Time { return now }
+
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheExpire(t *testing.T) {
+	c, advance := testCache(time.Minute, 0)
+
+	if !c.Add("a") || c.Add("a") {
+		t.Error("Add didn't report the new key only")
+	}
+
+	advance(30 * time.Second)
+	c.Add(uint64(1))
+	if !c.Has("a") || !c.Has(uint64(1)) || c.Has("b") {
+		t.Error("keys weren't remembered")
+	}
+
+	advance(30 * time.Second)
+	if c.Has("a") || !c.Has(uint64(1)) || c.Len() != 1 {
+		t.Errorf("expired key is remembered, %d keys", c.Len())
+	}
+
+	// the refreshed key lives the ttl from the refresh
+	advance(20 * time.Second)
+	c.Add(uint64(1))
+	advance(50 * time.Second)
+	if !c.Has(uint64(1)) {
+		t.Error("refreshed key is expired")
+	}
+
+	c.Remove(uint64(1))
+	if c.Has(uint64(1)) || c.Len() != 0 {
+		t.Error("removed key is remembered")
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	c, advance := testCache(time.Hour, 3)
+
+	for i := 0; i < 5; i++ {
+		c.Add(i)
+		advance(time.Second)
+	}
+
+	if c.Len() != 3 || c.Has(0) || c.Has(1) || !c.Has(2) || !c.Has(4) {
+		t.Errorf("cache of %d keys didn't evict the oldest ones", c.Len())
+	}
+
+	// the refreshed key is the newest
+	c.Add(2)
+	c.Add(5)
+	if c.Has(3) || !c.Has(2) {
+		t.Error("refreshed key was evicted")
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	c := New(time.Minute, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Add(i*1000 + j)
+				c.Has(j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if c.Len() != 100 {
+		t.Errorf("cache of %d keys, want 100", c.Len())
+	}
+}
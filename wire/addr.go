@@ -0,0 +1,68 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+)
+
+// the ip families of the serialized addr
+const (
+	addrIPv4 = 0
+	addrIPv6 = 1
+)
+
+// MaxAddrLen is the max size of the serialized addr: the family, the IPv6 &
+// the port
+const MaxAddrLen = 1 + net.IPv6len + 2
+
+// WriteAddr writes the ip family, the ip & the big endian port of addr to
+// buf, the addr without the valid ip is written as 0.0.0.0
+func WriteAddr(buf *bytes.Buffer, addr *net.TCPAddr) {
+	if ip := addr.IP.To4(); ip != nil {
+		WriteUint8(buf, addrIPv4)
+		buf.Write(ip)
+	} else if ip := addr.IP.To16(); ip != nil {
+		WriteUint8(buf, addrIPv6)
+		buf.Write(ip)
+	} else {
+		WriteUint8(buf, addrIPv4)
+		buf.Write(net.IPv4zero.To4())
+	}
+
+	WriteUint16(buf, uint16(addr.Port))
+}
+
+// ReadAddr reads the addr written by WriteAddr
+func ReadAddr(r io.Reader) (*net.TCPAddr, error) {
+	family, err := ReadUint8(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip net.IP
+	switch family {
+	case addrIPv4:
+		ip = make(net.IP, net.IPv4len)
+	case addrIPv6:
+		ip = make(net.IP, net.IPv6len)
+	default:
+		return nil, fmt.Errorf("invalid ip family: %d", family)
+	}
+
+	if err := ReadBytes(r, ip); err != nil {
+		return nil, err
+	}
+
+	port, err := ReadUint16(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MaxPooledBuffer is the max capacity of the buffer returned to the pool, it
+// fits the headers message of MaxBlockHeaders, the buffers of the rare big
+// messages are left to GC
+const MaxPooledBuffer = 1 << 20
+
+// bufferPool is the pool of the serialization buffers
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// GetBuffer returns the empty buffer from the pool
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer returns the buffer to the pool, the buffer must not be used
+// after
+func PutBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > MaxPooledBuffer {
+		return
+	}
+
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// WriteTo writes the data serialized by write to w, the buffer w is written
+// directly
+func WriteTo(w io.Writer, write func(buf *bytes.Buffer)) (int64, error) {
+	if buf, ok := w.(*bytes.Buffer); ok {
+		n := buf.Len()
+		write(buf)
+		return int64(buf.Len() - n), nil
+	}
+
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+
+	write(buf)
+
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the data serialized by write
+func Bytes(write func(buf *bytes.Buffer)) []byte {
+	buf := GetBuffer()
+	defer PutBuffer(buf)
+
+	write(buf)
+
+	return append([]byte(nil), buf.Bytes()...)
+}
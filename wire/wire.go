@@ -0,0 +1,151 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+// Package wire is the binary codec of the grin messages & types: the big
+// endian integers, the length prefixed bytes & strings, the peer addrs. The
+// writes to the buffer never fail, the reads return the io errors & the
+// lengths over the limits are ErrTooLargeRead before anything is allocated
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTooLargeRead is the message, string or list of the peer exceeding its
+// maximum, it's returned before the buffer of the size is allocated
+var ErrTooLargeRead = errors.New("too large read")
+
+// MaxStringLen is the max length of the user agent or error message a peer
+// should ever send
+const MaxStringLen = 10000
+
+// WriteUint8 writes v to buf
+func WriteUint8(buf *bytes.Buffer, v uint8) {
+	buf.WriteByte(v)
+}
+
+// WriteUint16 writes the big endian v to buf
+func WriteUint16(buf *bytes.Buffer, v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	buf.Write(b[:])
+}
+
+// WriteUint32 writes the big endian v to buf
+func WriteUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+// WriteUint64 writes the big endian v to buf
+func WriteUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
+
+// WriteBytesWithLen writes the uint64 length of data & data to buf
+func WriteBytesWithLen(buf *bytes.Buffer, data []byte) {
+	WriteUint64(buf, uint64(len(data)))
+	buf.Write(data)
+}
+
+// WriteString writes the uint64 length of s & s to buf
+func WriteString(buf *bytes.Buffer, s string) {
+	WriteUint64(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// ReadUint8 reads the byte of r
+func ReadUint8(r io.Reader) (uint8, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+// ReadUint16 reads the big endian uint16 of r
+func ReadUint16(r io.Reader) (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint16(b[:]), nil
+}
+
+// ReadUint32 reads the big endian uint32 of r
+func ReadUint32(r io.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(b[:]), nil
+}
+
+// ReadUint64 reads the big endian uint64 of r
+func ReadUint64(r io.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(b[:]), nil
+}
+
+// ReadBytes fills data by r
+func ReadBytes(r io.Reader, data []byte) error {
+	_, err := io.ReadFull(r, data)
+	return err
+}
+
+// CheckLen returns ErrTooLargeRead of what if n exceeds max
+func CheckLen(what string, n, max uint64) error {
+	if n > max {
+		return fmt.Errorf("%w: %s %d, the max is %d", ErrTooLargeRead, what, n, max)
+	}
+
+	return nil
+}
+
+// ReadLen reads the uint64 length or count of what, limited by max
+func ReadLen(r io.Reader, what string, max uint64) (uint64, error) {
+	n, err := ReadUint64(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, CheckLen(what, n, max)
+}
+
+// ReadLimitedBytes reads the uint64 length & the bytes of r, the length is
+// limited by max
+func ReadLimitedBytes(r io.Reader, what string, max uint64) ([]byte, error) {
+	n, err := ReadLen(r, what, max)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ReadString reads the uint64 length & the string of r, the length is
+// limited by MaxStringLen
+func ReadString(r io.Reader) (string, error) {
+	data, err := ReadLimitedBytes(r, "string len", MaxStringLen)
+	return string(data), err
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 The Gringo Developers. All rights reserved.
+// Use of this source code is governed by a GNU GENERAL PUBLIC LICENSE v3
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestUintRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteUint8(buf, 0x01)
+	WriteUint16(buf, 0x0203)
+	WriteUint32(buf, 0x04050607)
+	WriteUint64(buf, 0x08090a0b0c0d0e0f)
+
+	if s := hex.EncodeToString(buf.Bytes()); s != "0102030405060708090a0b0c0d0e0f" {
+		t.Fatalf("big endian encoding was %s", s)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	if v, err := ReadUint8(r); err != nil || v != 0x01 {
+		t.Errorf("uint8 was %x, %v", v, err)
+	}
+	if v, err := ReadUint16(r); err != nil || v != 0x0203 {
+		t.Errorf("uint16 was %x, %v", v, err)
+	}
+	if v, err := ReadUint32(r); err != nil || v != 0x04050607 {
+		t.Errorf("uint32 was %x, %v", v, err)
+	}
+	if v, err := ReadUint64(r); err != nil || v != 0x08090a0b0c0d0e0f {
+		t.Errorf("uint64 was %x, %v", v, err)
+	}
+
+	if _, err := ReadUint64(r); err != io.EOF {
+		t.Errorf("read of the empty input: %v, want EOF", err)
+	}
+
+	if _, err := ReadUint32(bytes.NewReader([]byte{1, 2})); err != io.ErrUnexpectedEOF {
+		t.Errorf("read of the short input: %v, want unexpected EOF", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	buf := new(bytes.Buffer)
+	WriteBytesWithLen(buf, []byte("abc"))
+
+	if data, err := ReadLimitedBytes(bytes.NewReader(buf.Bytes()), "data len", 3); err != nil || string(data) != "abc" {
+		t.Errorf("data was %q, %v", data, err)
+	}
+
+	if _, err := ReadLimitedBytes(bytes.NewReader(buf.Bytes()), "data len", 2); !errors.Is(err, ErrTooLargeRead) {
+		t.Errorf("data over the limit: %v, want ErrTooLargeRead", err)
+	}
+
+	buf.Reset()
+	WriteString(buf, strings.Repeat("a", MaxStringLen+1))
+	if _, err := ReadString(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrTooLargeRead) {
+		t.Errorf("string over the limit: %v, want ErrTooLargeRead", err)
+	}
+
+	if err := CheckLen("count", 5, 5); err != nil {
+		t.Errorf("len of the max was rejected: %v", err)
+	}
+
+	if err := CheckLen("count", 6, 5); err == nil || err.Error() != "too large read: count 6, the max is 5" {
+		t.Errorf("error was %v", err)
+	}
+}
+
+func TestAddrRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		addr *net.TCPAddr
+		hex  string
+	}{
+		{&net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 13414}, "00010203043466"},
+		{&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 80}, "0120010db80000000000000000000000010050"},
+	} {
+		buf := new(bytes.Buffer)
+		WriteAddr(buf, test.addr)
+
+		if s := hex.EncodeToString(buf.Bytes()); s != test.hex {
+			t.Errorf("%v was encoded as %s, want %s", test.addr, s, test.hex)
+		}
+
+		addr, err := ReadAddr(bytes.NewReader(buf.Bytes()))
+		if err != nil || !addr.IP.Equal(test.addr.IP) || addr.Port != test.addr.Port {
+			t.Errorf("%v was decoded as %v, %v", test.addr, addr, err)
+		}
+	}
+
+	if _, err := ReadAddr(bytes.NewReader([]byte{2})); err == nil {
+		t.Error("addr of the unknown ip family was read")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	write := func(buf *bytes.Buffer) { WriteUint16(buf, 0xabcd) }
+
+	var out bytes.Buffer
+	out.WriteByte(1)
+	if n, err := WriteTo(&out, write); err != nil || n != 2 || out.Len() != 3 {
+		t.Errorf("write to the buffer: %d, %v", n, err)
+	}
+
+	var w strings.Builder
+	if n, err := WriteTo(&w, write); err != nil || n != 2 || w.String() != "\xab\xcd" {
+		t.Errorf("write to the writer: %d, %v", n, err)
+	}
+
+	if data := Bytes(write); !bytes.Equal(data, []byte{0xab, 0xcd}) {
+		t.Errorf("bytes were %x", data)
+	}
+}